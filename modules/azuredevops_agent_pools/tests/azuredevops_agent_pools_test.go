@@ -7,6 +7,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/importtest"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
@@ -22,15 +25,15 @@ func TestBasicAzuredevopsAgentPools(t *testing.T) {
 	terraformOptions := getTerraformOptions(t, testFolder)
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		if _, err := os.Stat(filepath.Join(testFolder, ".test-data", "TerraformOptions.json")); err == nil {
-			terraform.Destroy(t, test_structure.LoadTerraformOptions(t, testFolder))
+			destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 			return
 		}
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -40,6 +43,11 @@ func TestBasicAzuredevopsAgentPools(t *testing.T) {
 
 		assert.NotEmpty(t, agentPoolID)
 	})
+
+	// Adopting the deployed module resources into an empty state must plan no changes
+	test_structure.RunTestStage(t, "import", func() {
+		importtest.RequireStateImportRoundTrip(t, test_structure.LoadTerraformOptions(t, testFolder))
+	})
 }
 
 // Test complete azuredevops_agent_pools configuration
@@ -51,15 +59,15 @@ func TestCompleteAzuredevopsAgentPools(t *testing.T) {
 	terraformOptions := getTerraformOptions(t, testFolder)
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		if _, err := os.Stat(filepath.Join(testFolder, ".test-data", "TerraformOptions.json")); err == nil {
-			terraform.Destroy(t, test_structure.LoadTerraformOptions(t, testFolder))
+			destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 			return
 		}
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -80,15 +88,15 @@ func TestSecureAzuredevopsAgentPools(t *testing.T) {
 	terraformOptions := getTerraformOptions(t, testFolder)
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		if _, err := os.Stat(filepath.Join(testFolder, ".test-data", "TerraformOptions.json")); err == nil {
-			terraform.Destroy(t, test_structure.LoadTerraformOptions(t, testFolder))
+			destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 			return
 		}
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -111,15 +119,10 @@ func getTerraformOptions(t testing.TB, terraformDir string) *terraform.Options {
 		Vars: map[string]interface{}{
 			"pool_name_prefix": fmt.Sprintf("ado-agent-pool-%s", uniqueID),
 		},
-		NoColor: true,
-		Upgrade: true,
-		RetryableTerraformErrors: map[string]string{
-			".*timeout.*":                  "Timeout error - retrying",
-			".*TooManyRequests.*":          "Too many requests - retrying",
-			".*connection reset by peer.*": "Azure DevOps connection reset - retrying",
-			".*invalid character '<' looking for beginning of value.*": "Azure DevOps returned HTML instead of JSON - retrying",
-		},
-		MaxRetries:         3,
-		TimeBetweenRetries: 10 * time.Second,
+		NoColor:                  true,
+		Upgrade:                  true,
+		RetryableTerraformErrors: tfretry.ClassifiedRetryableErrors(),
+		MaxRetries:               3,
+		TimeBetweenRetries:       10 * time.Second,
 	}
 }
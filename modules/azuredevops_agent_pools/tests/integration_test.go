@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/assert"
@@ -22,15 +24,15 @@ func TestAzuredevopsAgentPoolsFullIntegration(t *testing.T) {
 	terraformOptions := getTerraformOptions(t, testFolder)
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		if _, err := os.Stat(filepath.Join(testFolder, ".test-data", "TerraformOptions.json")); err == nil {
-			terraform.Destroy(t, test_structure.LoadTerraformOptions(t, testFolder))
+			destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 			return
 		}
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -2,9 +2,15 @@ package test
 
 import (
 	"fmt"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adoacl"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/importtest"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
@@ -19,13 +25,13 @@ func TestBasicAzuredevopsArtifactsFeed(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/basic")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -34,6 +40,20 @@ func TestBasicAzuredevopsArtifactsFeed(t *testing.T) {
 		feedID := terraform.Output(t, terraformOptions, "feed_id")
 
 		assert.NotEmpty(t, feedID)
+
+		helper := NewAzureDevOpsHelper(t)
+		projectID := getProjectID(t)
+		feed := helper.GetFeed(t, projectID, feedID)
+		require.NotNil(t, feed.Name)
+		assert.Equal(t, fmt.Sprintf("%s-basic", terraformOptions.Vars["feed_name_prefix"]), *feed.Name)
+		require.NotNil(t, feed.Project)
+		require.NotNil(t, feed.Project.Id)
+		assert.Equal(t, projectID, feed.Project.Id.String())
+	})
+
+	// Adopting the deployed module resources into an empty state must plan no changes
+	test_structure.RunTestStage(t, "import", func() {
+		importtest.RequireStateImportRoundTrip(t, test_structure.LoadTerraformOptions(t, testFolder))
 	})
 }
 
@@ -44,13 +64,13 @@ func TestCompleteAzuredevopsArtifactsFeed(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/complete")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -59,6 +79,17 @@ func TestCompleteAzuredevopsArtifactsFeed(t *testing.T) {
 		feedID := terraform.Output(t, terraformOptions, "feed_id")
 
 		assert.NotEmpty(t, feedID)
+
+		// The module does not configure upstreams, so the feed must have none
+		RequireFeed(t, NewAzureDevOpsHelper(t), feedID, ExpectedFeed{
+			Name:            terraform.Output(t, terraformOptions, "feed_name"),
+			ProjectID:       getProjectID(t),
+			UpstreamSources: []ExpectedUpstreamSource{},
+			Retention:       &ExpectedFeedRetention{CountLimit: 20, DaysToKeepRecentlyDownloadedPackages: 30},
+			Permissions: []ExpectedFeedPermission{
+				{IdentityDescriptor: terraform.Output(t, terraformOptions, "readers_descriptor"), Role: "contributor"},
+			},
+		})
 	})
 }
 
@@ -69,13 +100,13 @@ func TestSecureAzuredevopsArtifactsFeed(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/secure")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -84,6 +115,82 @@ func TestSecureAzuredevopsArtifactsFeed(t *testing.T) {
 		feedID := terraform.Output(t, terraformOptions, "feed_id")
 
 		assert.NotEmpty(t, feedID)
+
+		// The module does not configure upstreams, so the feed must have none
+		RequireFeed(t, NewAzureDevOpsHelper(t), feedID, ExpectedFeed{
+			Name:            terraform.Output(t, terraformOptions, "feed_name"),
+			ProjectID:       getProjectID(t),
+			UpstreamSources: []ExpectedUpstreamSource{},
+			Retention:       &ExpectedFeedRetention{CountLimit: 10, DaysToKeepRecentlyDownloadedPackages: 14},
+			Permissions: []ExpectedFeedPermission{
+				{IdentityDescriptor: terraform.Output(t, terraformOptions, "readers_descriptor"), Role: "reader"},
+			},
+		})
+
+		// Readers must not reach a higher role through inherited feed permissions either
+		helper := NewAzureDevOpsHelper(t)
+		readers := adoacl.RequireIdentityDescriptor(t, helper.Connection(), terraform.Output(t, terraformOptions, "readers_descriptor"))
+		adoacl.RequireEffectiveFeedRole(t, helper.Connection(), getProjectID(t), feedID, readers, "reader")
+	})
+}
+
+// Publish more package versions than the retention count_limit allows and wait for retention to prune them.
+// Retention runs asynchronously in Azure Artifacts, so this test is opt-in via AZDO_FEED_RETENTION_TEST.
+func TestAzuredevopsArtifactsFeedRetention(t *testing.T) {
+	t.Parallel()
+	requireADOEnv(t)
+	if os.Getenv("AZDO_FEED_RETENTION_TEST") == "" {
+		t.Skip("Skipping feed retention test: set AZDO_FEED_RETENTION_TEST=true to publish packages and wait for retention")
+	}
+
+	countLimit := 2
+	timeout := 30 * time.Minute
+	if value := os.Getenv("AZDO_FEED_RETENTION_TIMEOUT"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		require.NoError(t, err, "AZDO_FEED_RETENTION_TIMEOUT must be a duration such as 45m")
+		timeout = parsed
+	}
+
+	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/retention")
+	defer test_structure.RunTestStage(t, "cleanup", func() {
+		destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
+	})
+
+	test_structure.RunTestStage(t, "deploy", func() {
+		terraformOptions := getTerraformOptions(t, testFolder)
+		terraformOptions.Vars["count_limit"] = countLimit
+		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
+	})
+
+	test_structure.RunTestStage(t, "validate", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+		feedID := terraform.Output(t, terraformOptions, "feed_id")
+
+		RequireFeed(t, NewAzureDevOpsHelper(t), feedID, ExpectedFeed{
+			ProjectID: getProjectID(t),
+			Retention: &ExpectedFeedRetention{CountLimit: countLimit, DaysToKeepRecentlyDownloadedPackages: 1},
+		})
+	})
+
+	test_structure.RunTestStage(t, "publish", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+		feedID := terraform.Output(t, terraformOptions, "feed_id")
+		feedName := terraform.Output(t, terraformOptions, "feed_name")
+		projectID := getProjectID(t)
+		packageName := strings.ToLower(fmt.Sprintf("terratest.retention.%s", random.UniqueId()))
+
+		published := countLimit + 3
+		for patch := 0; patch < published; patch++ {
+			version := fmt.Sprintf("1.0.%d", patch)
+			require.NoError(t, PublishNuGetPackageE(projectID, feedName, packageName, version), "Failed to publish %s %s", packageName, version)
+		}
+
+		versions, err := NewAzureDevOpsHelper(t).WaitForRetentionE(projectID, feedID, packageName, countLimit, timeout, time.Minute)
+		require.NoError(t, err)
+		// Retention removes the oldest versions first
+		assert.Contains(t, versions, fmt.Sprintf("1.0.%d", published-1))
+		assert.NotContains(t, versions, "1.0.0")
 	})
 }
 
@@ -119,13 +226,10 @@ func getTerraformOptions(t testing.TB, terraformDir string) *terraform.Options {
 			"project_id":      getProjectID(t),
 			"feed_name_prefix": fmt.Sprintf("ado-feed-%s", uniqueID),
 		},
-		NoColor: true,
-		Upgrade: true,
-		RetryableTerraformErrors: map[string]string{
-			".*timeout.*":         "Timeout error - retrying",
-			".*TooManyRequests.*": "Too many requests - retrying",
-		},
-		MaxRetries:         3,
-		TimeBetweenRetries: 10 * time.Second,
+		NoColor:                  true,
+		Upgrade:                  true,
+		RetryableTerraformErrors: tfretry.ClassifiedRetryableErrors(),
+		MaxRetries:               3,
+		TimeBetweenRetries:       10 * time.Second,
 	}
 }
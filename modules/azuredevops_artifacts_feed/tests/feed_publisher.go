@@ -0,0 +1,176 @@
+package test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adohelper"
+)
+
+// nuspecPackage is the minimal .nuspec manifest accepted by the Azure Artifacts NuGet endpoint
+type nuspecPackage struct {
+	XMLName  xml.Name       `xml:"package"`
+	Xmlns    string         `xml:"xmlns,attr"`
+	Metadata nuspecMetadata `xml:"metadata"`
+}
+
+type nuspecMetadata struct {
+	ID          string `xml:"id"`
+	Version     string `xml:"version"`
+	Authors     string `xml:"authors"`
+	Description string `xml:"description"`
+}
+
+const nupkgContentTypes = `<?xml version="1.0" encoding="utf-8"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+  <Default Extension="nuspec" ContentType="application/octet" />
+  <Default Extension="txt" ContentType="application/octet" />
+</Types>`
+
+// BuildNuGetPackage returns a dummy .nupkg containing only a manifest and a readme
+func BuildNuGetPackage(id, version string) ([]byte, error) {
+	manifest, err := xml.MarshalIndent(nuspecPackage{
+		Xmlns: "http://schemas.microsoft.com/packaging/2013/05/nuspec.xsd",
+		Metadata: nuspecMetadata{
+			ID:          id,
+			Version:     version,
+			Authors:     "terratest",
+			Description: "Dummy package published by the artifacts feed retention test",
+		},
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+	files := []struct {
+		name    string
+		content []byte
+	}{
+		{id + ".nuspec", append([]byte(xml.Header), manifest...)},
+		{"[Content_Types].xml", []byte(nupkgContentTypes)},
+		{"readme.txt", []byte(fmt.Sprintf("%s %s\n", id, version))},
+	}
+	for _, file := range files {
+		writer, err := archive.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := writer.Write(file.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// PackagingBaseURL maps an organization URL to its Azure Artifacts host
+// (https://dev.azure.com/org -> https://pkgs.dev.azure.com/org, https://org.visualstudio.com -> https://org.pkgs.visualstudio.com)
+func PackagingBaseURL(organizationURL string) (string, error) {
+	parsed, err := url.Parse(strings.TrimRight(organizationURL, "/"))
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case strings.EqualFold(parsed.Host, "dev.azure.com"):
+		parsed.Host = "pkgs.dev.azure.com"
+	case strings.HasSuffix(strings.ToLower(parsed.Host), ".visualstudio.com"):
+		organization := strings.TrimSuffix(strings.ToLower(parsed.Host), ".visualstudio.com")
+		parsed.Host = organization + ".pkgs.visualstudio.com"
+	default:
+		return "", fmt.Errorf("unsupported organization URL %q", organizationURL)
+	}
+	return parsed.String(), nil
+}
+
+// NuGetPushURL returns the NuGet v2 push endpoint of a feed; projectID is empty for organization-scoped feeds
+func NuGetPushURL(organizationURL, projectID, feedName string) (string, error) {
+	base, err := PackagingBaseURL(organizationURL)
+	if err != nil {
+		return "", err
+	}
+	if projectID != "" {
+		base += "/" + url.PathEscape(projectID)
+	}
+	return fmt.Sprintf("%s/_packaging/%s/nuget/v2/", base, url.PathEscape(feedName)), nil
+}
+
+// PublishNuGetPackageE pushes a dummy package version to the feed with the PAT from AZDO_PERSONAL_ACCESS_TOKEN
+func PublishNuGetPackageE(projectID, feedName, id, version string) error {
+	pushURL, err := NuGetPushURL(os.Getenv("AZDO_ORG_SERVICE_URL"), projectID, feedName)
+	if err != nil {
+		return err
+	}
+	content, err := BuildNuGetPackage(id, version)
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("package", fmt.Sprintf("%s.%s.nupkg", id, version))
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(content); err != nil {
+		return err
+	}
+	if err := form.Close(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), adohelper.RequestTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, pushURL, &body)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	// Azure Artifacts ignores the API key value but NuGet clients always send one
+	request.Header.Set("X-NuGet-ApiKey", "AzureDevOps")
+	request.SetBasicAuth("terratest", os.Getenv("AZDO_PERSONAL_ACCESS_TOKEN"))
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 2048))
+		return fmt.Errorf("push of %s %s returned %s: %s", id, version, response.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// WaitForRetentionE polls the feed until at most countLimit versions of the package remain
+func (h *AzureDevOpsHelper) WaitForRetentionE(projectID, feedID, packageName string, countLimit int, timeout, interval time.Duration) ([]string, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		versions, err := h.ListPackageVersionsE(projectID, feedID, "NuGet", packageName)
+		if err != nil {
+			return nil, err
+		}
+		if len(versions) <= countLimit {
+			return versions, nil
+		}
+		if time.Now().After(deadline) {
+			return versions, fmt.Errorf("%d versions of %s still active after %s, retention count_limit is %d", len(versions), packageName, timeout, countLimit)
+		}
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,302 @@
+package test
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adohelper"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/feed"
+	"github.com/stretchr/testify/require"
+)
+
+// ExpectedFeed describes the feed state the fixture inputs should produce
+type ExpectedFeed struct {
+	Name string
+	// ProjectID is empty for organization-scoped feeds
+	ProjectID string
+	// UpstreamSources nil skips the check, an empty slice requires a feed without upstreams
+	UpstreamSources []ExpectedUpstreamSource
+	// Retention is nil when no retention policy is configured
+	Retention   *ExpectedFeedRetention
+	Permissions []ExpectedFeedPermission
+}
+
+// ExpectedUpstreamSource identifies an upstream by name and protocol; Location is optional
+type ExpectedUpstreamSource struct {
+	Name     string
+	Protocol string
+	Location string
+}
+
+// ExpectedFeedRetention mirrors azuredevops_feed_retention_policy
+type ExpectedFeedRetention struct {
+	CountLimit                           int
+	DaysToKeepRecentlyDownloadedPackages int
+}
+
+// ExpectedFeedPermission mirrors azuredevops_feed_permission; IdentityDescriptor is the subject descriptor passed to Terraform
+type ExpectedFeedPermission struct {
+	IdentityDescriptor string
+	Role               string
+}
+
+// GetFeedPermissionsE lists the explicit (non-inherited) permissions of a feed
+func (h *AzureDevOpsHelper) GetFeedPermissionsE(projectID, feedID string) ([]feed.FeedPermission, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), adohelper.RequestTimeout)
+	defer cancel()
+
+	client, err := h.Feed(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	excludeInherited := true
+	args := feed.GetFeedPermissionsArgs{FeedId: &feedID, ExcludeInheritedPermissions: &excludeInherited}
+	if projectID != "" {
+		args.Project = &projectID
+	}
+	permissions, err := client.GetFeedPermissions(ctx, args)
+	if err != nil || permissions == nil {
+		return nil, err
+	}
+	return *permissions, nil
+}
+
+// GetFeedRetentionPolicyE retrieves the retention policy of a feed
+func (h *AzureDevOpsHelper) GetFeedRetentionPolicyE(projectID, feedID string) (*feed.FeedRetentionPolicy, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), adohelper.RequestTimeout)
+	defer cancel()
+
+	client, err := h.Feed(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	args := feed.GetFeedRetentionPoliciesArgs{FeedId: &feedID}
+	if projectID != "" {
+		args.Project = &projectID
+	}
+	return client.GetFeedRetentionPolicies(ctx, args)
+}
+
+// ListPackageVersionsE returns the versions of a package that have not been deleted
+func (h *AzureDevOpsHelper) ListPackageVersionsE(projectID, feedID, protocolType, packageName string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), adohelper.RequestTimeout)
+	defer cancel()
+
+	client, err := h.Feed(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	includeAllVersions := true
+	args := feed.GetPackagesArgs{
+		FeedId:                &feedID,
+		ProtocolType:          &protocolType,
+		NormalizedPackageName: &packageName,
+		IncludeAllVersions:    &includeAllVersions,
+	}
+	if projectID != "" {
+		args.Project = &projectID
+	}
+	packages, err := client.GetPackages(ctx, args)
+	if err != nil || packages == nil {
+		return nil, err
+	}
+	return activePackageVersions(*packages), nil
+}
+
+// RequireFeed reads the feed, its permissions and retention policy back and compares them with the inputs
+func RequireFeed(t testing.TB, helper *AzureDevOpsHelper, feedID string, expected ExpectedFeed) {
+	t.Helper()
+
+	actual, err := helper.GetFeedE(expected.ProjectID, feedID)
+	require.NoError(t, err, "Failed to get feed %s", feedID)
+	require.NotNil(t, actual, "Feed %s not found", feedID)
+	problems := CompareFeed(actual, expected)
+
+	if expected.Retention != nil {
+		policy, err := helper.GetFeedRetentionPolicyE(expected.ProjectID, feedID)
+		require.NoError(t, err, "Failed to get retention policy of feed %s", feedID)
+		problems = append(problems, CompareFeedRetention(policy, *expected.Retention)...)
+	}
+
+	if len(expected.Permissions) > 0 {
+		permissions, err := helper.GetFeedPermissionsE(expected.ProjectID, feedID)
+		require.NoError(t, err, "Failed to get permissions of feed %s", feedID)
+		problems = append(problems, CompareFeedPermissions(permissions, expected.Permissions)...)
+	}
+
+	if len(problems) > 0 {
+		require.FailNow(t, "Feed does not match the fixture", "feed %s:\n  %s", feedID, strings.Join(problems, "\n  "))
+	}
+}
+
+// CompareFeed reports differences in name, scope and upstream sources
+func CompareFeed(actual *feed.Feed, expected ExpectedFeed) []string {
+	var problems []string
+	if expected.Name != "" && (actual.Name == nil || *actual.Name != expected.Name) {
+		problems = append(problems, fmt.Sprintf("name is %q, expected %q", feedString(actual.Name), expected.Name))
+	}
+
+	switch {
+	case expected.ProjectID == "" && actual.Project != nil:
+		problems = append(problems, fmt.Sprintf("feed is scoped to project %s, expected organization scope", feedProjectID(actual)))
+	case expected.ProjectID != "" && !strings.EqualFold(feedProjectID(actual), expected.ProjectID):
+		problems = append(problems, fmt.Sprintf("feed is scoped to project %q, expected %q", feedProjectID(actual), expected.ProjectID))
+	}
+
+	if expected.UpstreamSources != nil {
+		problems = append(problems, compareUpstreamSources(actual.UpstreamSources, expected.UpstreamSources)...)
+	}
+	return problems
+}
+
+// CompareFeedRetention reports differences in count limit and days to keep recently downloaded packages
+func CompareFeedRetention(policy *feed.FeedRetentionPolicy, expected ExpectedFeedRetention) []string {
+	if policy == nil {
+		return []string{"retention policy is not set"}
+	}
+
+	var problems []string
+	if policy.CountLimit == nil || *policy.CountLimit != expected.CountLimit {
+		problems = append(problems, fmt.Sprintf("retention count_limit is %s, expected %d", feedInt(policy.CountLimit), expected.CountLimit))
+	}
+	if policy.DaysToKeepRecentlyDownloadedPackages == nil || *policy.DaysToKeepRecentlyDownloadedPackages != expected.DaysToKeepRecentlyDownloadedPackages {
+		problems = append(problems, fmt.Sprintf("retention days_to_keep_recently_downloaded_packages is %s, expected %d",
+			feedInt(policy.DaysToKeepRecentlyDownloadedPackages), expected.DaysToKeepRecentlyDownloadedPackages))
+	}
+	return problems
+}
+
+// CompareFeedPermissions reports expected identities that are missing or hold another role.
+// The feed creator and build service keep their own explicit roles, so extra entries are not reported.
+func CompareFeedPermissions(permissions []feed.FeedPermission, expected []ExpectedFeedPermission) []string {
+	var problems []string
+	for _, want := range expected {
+		permission := findFeedPermission(permissions, want.IdentityDescriptor)
+		switch {
+		case permission == nil:
+			problems = append(problems, fmt.Sprintf("permission for %s is missing", want.IdentityDescriptor))
+		case permission.Role == nil || !strings.EqualFold(string(*permission.Role), want.Role):
+			role := ""
+			if permission.Role != nil {
+				role = string(*permission.Role)
+			}
+			problems = append(problems, fmt.Sprintf("%s has role %q, expected %q", want.IdentityDescriptor, role, want.Role))
+		}
+	}
+	return problems
+}
+
+// SubjectDescriptorIdentifier decodes the identifier part of a graph subject descriptor
+// (vssgp.<base64 SID>, aad.<base64 identifier>), which is the suffix of the legacy identity descriptor
+func SubjectDescriptorIdentifier(subjectDescriptor string) string {
+	_, encoded, found := strings.Cut(subjectDescriptor, ".")
+	if !found {
+		return ""
+	}
+	for _, encoding := range []*base64.Encoding{base64.RawURLEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.StdEncoding} {
+		if decoded, err := encoding.DecodeString(encoded); err == nil {
+			return string(decoded)
+		}
+	}
+	return ""
+}
+
+func findFeedPermission(permissions []feed.FeedPermission, subjectDescriptor string) *feed.FeedPermission {
+	identifier := SubjectDescriptorIdentifier(subjectDescriptor)
+	for i := range permissions {
+		descriptor := feedString(permissions[i].IdentityDescriptor)
+		if strings.EqualFold(descriptor, subjectDescriptor) {
+			return &permissions[i]
+		}
+		if _, actualIdentifier, found := strings.Cut(descriptor, ";"); found && identifier != "" && strings.EqualFold(actualIdentifier, identifier) {
+			return &permissions[i]
+		}
+	}
+	return nil
+}
+
+func compareUpstreamSources(actual *[]feed.UpstreamSource, expected []ExpectedUpstreamSource) []string {
+	remaining := map[string]feed.UpstreamSource{}
+	if actual != nil {
+		for _, source := range *actual {
+			if source.DeletedDate != nil {
+				continue
+			}
+			remaining[upstreamKey(feedString(source.Name), feedString(source.Protocol))] = source
+		}
+	}
+
+	var problems []string
+	for _, want := range expected {
+		key := upstreamKey(want.Name, want.Protocol)
+		source, ok := remaining[key]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("upstream %s (%s) is missing", want.Name, want.Protocol))
+			continue
+		}
+		delete(remaining, key)
+		if want.Location != "" && !strings.EqualFold(strings.TrimRight(feedString(source.Location), "/"), strings.TrimRight(want.Location, "/")) {
+			problems = append(problems, fmt.Sprintf("upstream %s location is %q, expected %q", want.Name, feedString(source.Location), want.Location))
+		}
+	}
+
+	var unexpected []string
+	for _, source := range remaining {
+		unexpected = append(unexpected, fmt.Sprintf("unexpected upstream %s (%s)", feedString(source.Name), feedString(source.Protocol)))
+	}
+	sort.Strings(unexpected)
+	return append(problems, unexpected...)
+}
+
+func activePackageVersions(packages []feed.Package) []string {
+	var versions []string
+	for _, pkg := range packages {
+		if pkg.Versions == nil {
+			continue
+		}
+		for _, version := range *pkg.Versions {
+			if version.IsDeleted != nil && *version.IsDeleted {
+				continue
+			}
+			if version.NormalizedVersion != nil {
+				versions = append(versions, *version.NormalizedVersion)
+			} else if version.Version != nil {
+				versions = append(versions, *version.Version)
+			}
+		}
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+func upstreamKey(name, protocol string) string {
+	return strings.ToLower(name) + "|" + strings.ToLower(protocol)
+}
+
+func feedProjectID(actual *feed.Feed) string {
+	if actual.Project == nil || actual.Project.Id == nil {
+		return ""
+	}
+	return actual.Project.Id.String()
+}
+
+func feedString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func feedInt(value *int) string {
+	if value == nil {
+		return "unset"
+	}
+	return fmt.Sprintf("%d", *value)
+}
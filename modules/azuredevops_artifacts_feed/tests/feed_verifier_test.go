@@ -0,0 +1,155 @@
+package test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/feed"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testFeedProjectID = "0b6a2c47-8f3e-4d1a-b5c9-7e2f1a3d4c58"
+	// vssgp descriptor of S-1-9-1551374245-1204400969-2402986413-2179408616-0-0-0-0-2
+	testReadersDescriptor = "vssgp.Uy0xLTktMTU1MTM3NDI0NS0xMjA0NDAwOTY5LTI0MDI5ODY0MTMtMjE3OTQwODYxNi0wLTAtMC0wLTI"
+)
+
+func loadTestFeed(t *testing.T) *feed.Feed {
+	content, err := os.ReadFile(filepath.Join("testdata", "feed.json"))
+	require.NoError(t, err)
+
+	var actual feed.Feed
+	require.NoError(t, json.Unmarshal(content, &actual))
+	return &actual
+}
+
+func loadTestFeedPermissions(t *testing.T) []feed.FeedPermission {
+	content, err := os.ReadFile(filepath.Join("testdata", "feed_permissions.json"))
+	require.NoError(t, err)
+
+	var response struct {
+		Value []feed.FeedPermission `json:"value"`
+	}
+	require.NoError(t, json.Unmarshal(content, &response))
+	return response.Value
+}
+
+func TestCompareFeedMatches(t *testing.T) {
+	assert.Empty(t, CompareFeed(loadTestFeed(t), ExpectedFeed{
+		Name:      "ado-feed-abc123-complete",
+		ProjectID: testFeedProjectID,
+		UpstreamSources: []ExpectedUpstreamSource{
+			{Name: "NuGet Gallery", Protocol: "nuget", Location: "https://api.nuget.org/v3/index.json"},
+		},
+	}))
+}
+
+func TestCompareFeedDifferences(t *testing.T) {
+	actual := loadTestFeed(t)
+
+	problems := CompareFeed(actual, ExpectedFeed{
+		Name:            "ado-feed-abc123-secure",
+		ProjectID:       "9d4e3b21-6a7c-4f58-8e1d-3c2b5a6f7e90",
+		UpstreamSources: []ExpectedUpstreamSource{{Name: "PyPI", Protocol: "pypi"}},
+	})
+	assert.Equal(t, []string{
+		`name is "ado-feed-abc123-complete", expected "ado-feed-abc123-secure"`,
+		`feed is scoped to project "0b6a2c47-8f3e-4d1a-b5c9-7e2f1a3d4c58", expected "9d4e3b21-6a7c-4f58-8e1d-3c2b5a6f7e90"`,
+		"upstream PyPI (pypi) is missing",
+		"unexpected upstream NuGet Gallery (nuget)",
+	}, problems)
+
+	assert.Equal(t, []string{"feed is scoped to project 0b6a2c47-8f3e-4d1a-b5c9-7e2f1a3d4c58, expected organization scope"},
+		CompareFeed(actual, ExpectedFeed{}))
+	assert.Equal(t, []string{"unexpected upstream NuGet Gallery (nuget)"},
+		CompareFeed(actual, ExpectedFeed{ProjectID: testFeedProjectID, UpstreamSources: []ExpectedUpstreamSource{}}))
+}
+
+func TestCompareFeedRetention(t *testing.T) {
+	countLimit, days := 20, 30
+	policy := &feed.FeedRetentionPolicy{CountLimit: &countLimit, DaysToKeepRecentlyDownloadedPackages: &days}
+
+	assert.Empty(t, CompareFeedRetention(policy, ExpectedFeedRetention{CountLimit: 20, DaysToKeepRecentlyDownloadedPackages: 30}))
+	assert.Equal(t, []string{
+		"retention count_limit is 20, expected 10",
+		"retention days_to_keep_recently_downloaded_packages is 30, expected 14",
+	}, CompareFeedRetention(policy, ExpectedFeedRetention{CountLimit: 10, DaysToKeepRecentlyDownloadedPackages: 14}))
+	assert.Equal(t, []string{"retention days_to_keep_recently_downloaded_packages is unset, expected 30"},
+		CompareFeedRetention(&feed.FeedRetentionPolicy{CountLimit: &countLimit}, ExpectedFeedRetention{CountLimit: 20, DaysToKeepRecentlyDownloadedPackages: 30}))
+}
+
+func TestCompareFeedPermissionsMatchesSubjectDescriptors(t *testing.T) {
+	permissions := loadTestFeedPermissions(t)
+
+	assert.Equal(t, "S-1-9-1551374245-1204400969-2402986413-2179408616-0-0-0-0-2", SubjectDescriptorIdentifier(testReadersDescriptor))
+	assert.Empty(t, CompareFeedPermissions(permissions, []ExpectedFeedPermission{
+		{IdentityDescriptor: testReadersDescriptor, Role: "contributor"},
+	}))
+
+	problems := CompareFeedPermissions(permissions, []ExpectedFeedPermission{
+		{IdentityDescriptor: testReadersDescriptor, Role: "reader"},
+		{IdentityDescriptor: "vssgp.Uy0xLTktOTk5", Role: "reader"},
+	})
+	assert.Equal(t, []string{
+		testReadersDescriptor + ` has role "contributor", expected "reader"`,
+		"permission for vssgp.Uy0xLTktOTk5 is missing",
+	}, problems)
+}
+
+func TestActivePackageVersionsSkipsDeleted(t *testing.T) {
+	deleted := true
+	versions := []feed.MinimalPackageVersion{
+		{NormalizedVersion: stringPtr("1.0.0"), IsDeleted: &deleted},
+		{NormalizedVersion: stringPtr("1.0.2")},
+		{Version: stringPtr("1.0.1")},
+	}
+
+	assert.Equal(t, []string{"1.0.1", "1.0.2"}, activePackageVersions([]feed.Package{{Versions: &versions}}))
+}
+
+func TestNuGetPushURL(t *testing.T) {
+	pushURL, err := NuGetPushURL("https://dev.azure.com/contoso/", testFeedProjectID, "ado-feed-retention")
+	require.NoError(t, err)
+	assert.Equal(t, "https://pkgs.dev.azure.com/contoso/"+testFeedProjectID+"/_packaging/ado-feed-retention/nuget/v2/", pushURL)
+
+	pushURL, err = NuGetPushURL("https://contoso.visualstudio.com", "", "org-feed")
+	require.NoError(t, err)
+	assert.Equal(t, "https://contoso.pkgs.visualstudio.com/_packaging/org-feed/nuget/v2/", pushURL)
+
+	_, err = NuGetPushURL("https://tfs.contoso.local/DefaultCollection", "", "feed")
+	assert.Error(t, err)
+}
+
+func TestBuildNuGetPackage(t *testing.T) {
+	content, err := BuildNuGetPackage("terratest.retention", "1.0.3")
+	require.NoError(t, err)
+
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	require.NoError(t, err)
+
+	var manifest nuspecPackage
+	for _, file := range archive.File {
+		if file.Name != "terratest.retention.nuspec" {
+			continue
+		}
+		reader, err := file.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		require.NoError(t, err)
+		require.NoError(t, xml.Unmarshal(data, &manifest))
+	}
+	assert.Equal(t, "terratest.retention", manifest.Metadata.ID)
+	assert.Equal(t, "1.0.3", manifest.Metadata.Version)
+}
+
+func stringPtr(value string) *string {
+	return &value
+}
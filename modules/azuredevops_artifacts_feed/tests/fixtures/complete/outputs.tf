@@ -2,3 +2,13 @@ output "feed_id" {
   description = "Feed ID created in this fixture."
   value       = module.azuredevops_artifacts_feed.feed_id
 }
+
+output "feed_name" {
+  description = "Feed name created in this fixture."
+  value       = module.azuredevops_artifacts_feed.feed_name
+}
+
+output "readers_descriptor" {
+  description = "Descriptor of the Readers group granted a feed role."
+  value       = data.azuredevops_group.readers.descriptor
+}
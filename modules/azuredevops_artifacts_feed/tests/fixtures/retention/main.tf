@@ -0,0 +1,27 @@
+terraform {
+  required_version = ">= 1.12.2"
+  required_providers {
+
+    azuredevops = {
+      source  = "microsoft/azuredevops"
+      version = "1.12.2"
+    }
+  }
+}
+
+provider "azuredevops" {}
+
+module "azuredevops_artifacts_feed" {
+  source = "../../../"
+
+  name       = "${var.feed_name_prefix}-retention"
+  project_id = var.project_id
+
+  feed_retention_policies = [
+    {
+      key                                       = "retention"
+      count_limit                               = var.count_limit
+      days_to_keep_recently_downloaded_packages = var.days_to_keep_recently_downloaded_packages
+    }
+  ]
+}
@@ -0,0 +1,9 @@
+output "feed_id" {
+  description = "Feed ID created in this fixture."
+  value       = module.azuredevops_artifacts_feed.feed_id
+}
+
+output "feed_name" {
+  description = "Feed name used for package publishing."
+  value       = module.azuredevops_artifacts_feed.feed_name
+}
@@ -0,0 +1,22 @@
+variable "project_id" {
+  description = "Azure DevOps project ID."
+  type        = string
+}
+
+variable "feed_name_prefix" {
+  description = "Prefix for feed names."
+  type        = string
+  default     = "ado-feed-retention"
+}
+
+variable "count_limit" {
+  description = "Maximum number of versions kept per package."
+  type        = number
+  default     = 2
+}
+
+variable "days_to_keep_recently_downloaded_packages" {
+  description = "Days a downloaded version is protected from retention."
+  type        = number
+  default     = 1
+}
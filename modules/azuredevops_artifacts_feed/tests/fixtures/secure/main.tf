@@ -21,6 +21,9 @@ module "azuredevops_artifacts_feed" {
 
   name       = "${var.feed_name_prefix}-secure"
   project_id = var.project_id
+  features = {
+    permanent_delete = true
+  }
 
   feed_permissions = [
     {
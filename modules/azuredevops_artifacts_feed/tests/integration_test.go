@@ -3,6 +3,8 @@ package test
 import (
 	"testing"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/assert"
@@ -18,13 +20,13 @@ func TestAzuredevopsArtifactsFeedFullIntegration(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/complete")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -3,6 +3,8 @@ package test
 import (
 	"os"
 	"testing"
+
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adohelper"
 )
 
 func requireADOEnv(t testing.TB) {
@@ -22,3 +24,15 @@ func getProjectID(t testing.TB) string {
 
 	return os.Getenv("AZDO_PROJECT_ID")
 }
+
+// AzureDevOpsHelper adds the feed verification calls of this suite to the shared helper
+type AzureDevOpsHelper struct {
+	*adohelper.Helper
+}
+
+// NewAzureDevOpsHelper creates a helper authenticated with AZDO_ORG_SERVICE_URL and AZDO_PERSONAL_ACCESS_TOKEN
+func NewAzureDevOpsHelper(t testing.TB) *AzureDevOpsHelper {
+	t.Helper()
+
+	return &AzureDevOpsHelper{Helper: adohelper.New(t)}
+}
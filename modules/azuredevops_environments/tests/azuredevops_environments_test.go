@@ -7,6 +7,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adohelper"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/importtest"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
@@ -23,15 +27,15 @@ func TestBasicAzuredevopsEnvironments(t *testing.T) {
 	terraformOptions := getTerraformOptions(t, testFolder)
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		if _, err := os.Stat(filepath.Join(testFolder, ".test-data", "TerraformOptions.json")); err == nil {
-			terraform.Destroy(t, test_structure.LoadTerraformOptions(t, testFolder))
+			destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 			return
 		}
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -40,6 +44,18 @@ func TestBasicAzuredevopsEnvironments(t *testing.T) {
 		environmentID := terraform.Output(t, terraformOptions, "environment_id")
 
 		assert.NotEmpty(t, environmentID)
+
+		helper := NewAzureDevOpsHelper(t)
+		environment := helper.GetEnvironment(t, getProjectID(t), adohelper.ParseIntID(t, environmentID))
+		require.NotNil(t, environment.Name)
+		assert.Equal(t, terraformOptions.Vars["environment_name"], *environment.Name)
+		require.NotNil(t, environment.Description)
+		assert.Equal(t, "Basic environment", *environment.Description)
+	})
+
+	// Adopting the deployed module resources into an empty state must plan no changes
+	test_structure.RunTestStage(t, "import", func() {
+		importtest.RequireStateImportRoundTrip(t, test_structure.LoadTerraformOptions(t, testFolder))
 	})
 }
 
@@ -52,15 +68,15 @@ func TestCompleteAzuredevopsEnvironments(t *testing.T) {
 	terraformOptions := getTerraformOptions(t, testFolder)
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		if _, err := os.Stat(filepath.Join(testFolder, ".test-data", "TerraformOptions.json")); err == nil {
-			terraform.Destroy(t, test_structure.LoadTerraformOptions(t, testFolder))
+			destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 			return
 		}
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -75,6 +91,65 @@ func TestCompleteAzuredevopsEnvironments(t *testing.T) {
 		assert.NotEmpty(t, approvalCheckIDs)
 		assert.Contains(t, approvalCheckIDs, "integration-approval")
 		assert.NotEmpty(t, approvalCheckIDs["integration-approval"])
+
+		helper := NewAzureDevOpsHelper(t)
+		projectID := getProjectID(t)
+		checks := RequireChecks(t, helper, projectID, "environment", environmentID, ExpectedChecks{
+			Approvals: []ExpectedApprovalCheck{
+				{Approvers: []string{terraform.Output(t, terraformOptions, "approver_id")}},
+			},
+			BranchControls: []ExpectedBranchControlCheck{
+				{DisplayName: "environment-branch-control", AllowedBranches: "refs/heads/main", VerifyBranchProtection: true},
+			},
+			BusinessHours: []ExpectedBusinessHoursCheck{
+				{
+					DisplayName: "environment-business-hours",
+					Days:        []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+					StartTime:   "09:00",
+					EndTime:     "17:00",
+					TimeZone:    "UTC",
+				},
+			},
+			ExclusiveLocks: 1,
+			RequiredTemplates: [][]ExpectedRequiredTemplate{
+				{
+					{
+						RepositoryType: "azuregit",
+						RepositoryName: fmt.Sprintf("%s/%s", terraform.Output(t, terraformOptions, "project_name"), terraform.Output(t, terraformOptions, "templates_repository_name")),
+						RepositoryRef:  "refs/heads/main",
+						TemplatePath:   "pipelines/deploy.yml",
+					},
+				},
+			},
+			RESTAPIs: []ExpectedRESTAPICheck{
+				{
+					DisplayName:          "environment-rest-api",
+					ConnectedServiceName: terraform.Output(t, terraformOptions, "rest_check_service_endpoint_name"),
+					Method:               "GET",
+					URLSuffix:            "/health",
+					CompletionEvent:      "ApiResponse",
+				},
+			},
+		})
+
+		RequireChecks(t, helper, projectID, "endpoint", terraform.Output(t, terraformOptions, "kubernetes_service_endpoint_id"), ExpectedChecks{
+			BranchControls: []ExpectedBranchControlCheck{
+				{DisplayName: "k8s-endpoint-branch-control", AllowedBranches: "refs/heads/main", VerifyBranchProtection: true},
+			},
+		})
+
+		businessHours := time.Date(2026, time.March, 4, 10, 0, 0, 0, time.UTC)
+		simulation, err := SimulateChecks(checks, "main", businessHours)
+		require.NoError(t, err)
+		assert.False(t, simulation.Blocked(), "main during business hours should pass: %+v", simulation.Results)
+
+		simulation, err = SimulateChecks(checks, "feature/unreviewed", businessHours)
+		require.NoError(t, err)
+		assert.True(t, simulation.Blocked(), "feature branches should be blocked by branch control")
+
+		simulation, err = SimulateChecks(checks, "main", businessHours.AddDate(0, 0, 3))
+		require.NoError(t, err)
+		assert.True(t, simulation.Blocked(), "weekend runs should be blocked by business hours")
 	})
 }
 
@@ -87,15 +162,15 @@ func TestSecureAzuredevopsEnvironments(t *testing.T) {
 	terraformOptions := getTerraformOptions(t, testFolder)
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		if _, err := os.Stat(filepath.Join(testFolder, ".test-data", "TerraformOptions.json")); err == nil {
-			terraform.Destroy(t, test_structure.LoadTerraformOptions(t, testFolder))
+			destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 			return
 		}
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -136,15 +211,10 @@ func getTerraformOptions(t testing.TB, terraformDir string) *terraform.Options {
 			"project_id":       getProjectID(t),
 			"environment_name": fmt.Sprintf("ado-env-%s", uniqueID),
 		},
-		NoColor: true,
-		Upgrade: true,
-		RetryableTerraformErrors: map[string]string{
-			".*timeout.*":                  "Timeout error - retrying",
-			".*TooManyRequests.*":          "Too many requests - retrying",
-			".*connection reset by peer.*": "Azure DevOps connection reset - retrying",
-			".*invalid character '<' looking for beginning of value.*": "Azure DevOps returned HTML instead of JSON - retrying",
-		},
-		MaxRetries:         3,
-		TimeBetweenRetries: 10 * time.Second,
+		NoColor:                  true,
+		Upgrade:                  true,
+		RetryableTerraformErrors: tfretry.ClassifiedRetryableErrors(),
+		MaxRetries:               3,
+		TimeBetweenRetries:       10 * time.Second,
 	}
 }
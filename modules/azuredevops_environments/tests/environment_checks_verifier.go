@@ -0,0 +1,593 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adohelper"
+	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/pipelineschecks"
+	"github.com/stretchr/testify/require"
+)
+
+// Check kinds as named by the module's check_* inputs
+const (
+	CheckKindApproval         = "approval"
+	CheckKindBranchControl    = "branch_control"
+	CheckKindBusinessHours    = "business_hours"
+	CheckKindExclusiveLock    = "exclusive_lock"
+	CheckKindRequiredTemplate = "required_template"
+	CheckKindRESTAPI          = "rest_api"
+)
+
+// Simulation outcomes; external checks depend on people or services and cannot be decided offline
+const (
+	CheckOutcomePass     = "pass"
+	CheckOutcomeFail     = "fail"
+	CheckOutcomeExternal = "external"
+)
+
+// checkConfigurationsLocationID is the pipelines checks "configurations" route
+var checkConfigurationsLocationID = uuid.MustParse("86c8381e-5aee-4cde-8ae4-25c0c7f5eaea")
+
+// windowsTimeZones maps the Windows time zone IDs used by business hours checks to IANA names
+var windowsTimeZones = map[string]string{
+	"UTC":                            "UTC",
+	"GMT Standard Time":              "Europe/London",
+	"W. Europe Standard Time":        "Europe/Berlin",
+	"Central European Standard Time": "Europe/Warsaw",
+	"Romance Standard Time":          "Europe/Paris",
+	"Eastern Standard Time":          "America/New_York",
+	"Central Standard Time":          "America/Chicago",
+	"Mountain Standard Time":         "America/Denver",
+	"Pacific Standard Time":          "America/Los_Angeles",
+	"India Standard Time":            "Asia/Kolkata",
+	"Tokyo Standard Time":            "Asia/Tokyo",
+	"AUS Eastern Standard Time":      "Australia/Sydney",
+}
+
+// checkConfiguration mirrors the REST payload; the SDK model does not expose settings
+type checkConfiguration struct {
+	ID   *int `json:"id"`
+	Type *struct {
+		Name *string `json:"name"`
+	} `json:"type"`
+	Timeout  *int                   `json:"timeout"`
+	Settings map[string]interface{} `json:"settings"`
+}
+
+// PipelineCheck is a check configured on an environment or service endpoint
+type PipelineCheck struct {
+	ID       int
+	Kind     string
+	Timeout  int
+	Settings map[string]interface{}
+}
+
+// DisplayName returns the display name stored for task based checks
+func (c PipelineCheck) DisplayName() string {
+	name, _ := c.Settings["displayName"].(string)
+	return name
+}
+
+// Input returns a task check input as a string
+func (c PipelineCheck) Input(name string) string {
+	inputs, _ := c.Settings["inputs"].(map[string]interface{})
+	if value, ok := inputs[name]; ok && value != nil {
+		return fmt.Sprint(value)
+	}
+	return ""
+}
+
+// ExpectedApprovalCheck mirrors a check_approvals entry; approvers are identity IDs
+type ExpectedApprovalCheck struct {
+	Approvers                []string
+	MinimumRequiredApprovers int
+	RequesterCanApprove      bool
+	Instructions             string
+}
+
+// ExpectedBranchControlCheck mirrors a check_branch_controls entry
+type ExpectedBranchControlCheck struct {
+	DisplayName                   string
+	AllowedBranches               string
+	VerifyBranchProtection        bool
+	IgnoreUnknownProtectionStatus bool
+}
+
+// ExpectedBusinessHoursCheck mirrors a check_business_hours entry
+type ExpectedBusinessHoursCheck struct {
+	DisplayName string
+	Days        []time.Weekday
+	StartTime   string
+	EndTime     string
+	TimeZone    string
+}
+
+// ExpectedRequiredTemplate mirrors a required_templates entry
+type ExpectedRequiredTemplate struct {
+	RepositoryType string
+	RepositoryName string
+	RepositoryRef  string
+	TemplatePath   string
+}
+
+// ExpectedRESTAPICheck mirrors a check_rest_apis entry
+type ExpectedRESTAPICheck struct {
+	DisplayName          string
+	ConnectedServiceName string
+	Method               string
+	URLSuffix            string
+	SuccessCriteria      string
+	CompletionEvent      string
+}
+
+// ExpectedChecks is the full set of checks expected on one resource
+type ExpectedChecks struct {
+	Approvals         []ExpectedApprovalCheck
+	BranchControls    []ExpectedBranchControlCheck
+	BusinessHours     []ExpectedBusinessHoursCheck
+	ExclusiveLocks    int
+	RequiredTemplates [][]ExpectedRequiredTemplate
+	RESTAPIs          []ExpectedRESTAPICheck
+}
+
+// GetPipelineChecksE retrieves the checks and their settings configured on a resource
+func (h *AzureDevOpsHelper) GetPipelineChecksE(projectID, resourceType, resourceID string) ([]PipelineCheck, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), adohelper.RequestTimeout)
+	defer cancel()
+
+	client, err := h.Connection().GetClientByResourceAreaId(ctx, pipelineschecks.ResourceAreaId)
+	if err != nil {
+		return nil, err
+	}
+
+	queryParams := url.Values{}
+	queryParams.Add("resourceType", resourceType)
+	queryParams.Add("resourceId", resourceID)
+	queryParams.Add("$expand", string(pipelineschecks.CheckConfigurationExpandParameterValues.Settings))
+	resp, err := client.Send(ctx, http.MethodGet, checkConfigurationsLocationID, "7.1-preview.1", map[string]string{"project": projectID}, queryParams, nil, "", "application/json", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var configurations []checkConfiguration
+	if err := client.UnmarshalCollectionBody(resp, &configurations); err != nil {
+		return nil, err
+	}
+	return toPipelineChecks(configurations), nil
+}
+
+// RequireChecks fetches the checks on a resource and fails the test when they differ from the expected set
+func RequireChecks(t testing.TB, helper *AzureDevOpsHelper, projectID, resourceType, resourceID string, expected ExpectedChecks) []PipelineCheck {
+	t.Helper()
+
+	checks, err := helper.GetPipelineChecksE(projectID, resourceType, resourceID)
+	require.NoError(t, err, "Failed to get checks on %s %s", resourceType, resourceID)
+
+	if problems := CompareChecks(checks, expected); len(problems) > 0 {
+		require.FailNow(t, "Checks do not match the fixture", "%s %s:\n  %s", resourceType, resourceID, strings.Join(problems, "\n  "))
+	}
+	return checks
+}
+
+// CompareChecks reports missing, extra and mismatched checks per kind
+func CompareChecks(checks []PipelineCheck, expected ExpectedChecks) []string {
+	byKind := map[string][]PipelineCheck{}
+	for _, check := range checks {
+		byKind[check.Kind] = append(byKind[check.Kind], check)
+	}
+
+	var problems []string
+	problems = append(problems, matchChecks(CheckKindApproval, byKind[CheckKindApproval], len(expected.Approvals), func(i int, check PipelineCheck) []string {
+		return compareApproval(expected.Approvals[i], check)
+	})...)
+	problems = append(problems, matchChecks(CheckKindBranchControl, byKind[CheckKindBranchControl], len(expected.BranchControls), func(i int, check PipelineCheck) []string {
+		return compareBranchControl(expected.BranchControls[i], check)
+	})...)
+	problems = append(problems, matchChecks(CheckKindBusinessHours, byKind[CheckKindBusinessHours], len(expected.BusinessHours), func(i int, check PipelineCheck) []string {
+		return compareBusinessHours(expected.BusinessHours[i], check)
+	})...)
+	problems = append(problems, matchChecks(CheckKindExclusiveLock, byKind[CheckKindExclusiveLock], expected.ExclusiveLocks, func(int, PipelineCheck) []string {
+		return nil
+	})...)
+	problems = append(problems, matchChecks(CheckKindRequiredTemplate, byKind[CheckKindRequiredTemplate], len(expected.RequiredTemplates), func(i int, check PipelineCheck) []string {
+		return compareRequiredTemplates(expected.RequiredTemplates[i], check)
+	})...)
+	problems = append(problems, matchChecks(CheckKindRESTAPI, byKind[CheckKindRESTAPI], len(expected.RESTAPIs), func(i int, check PipelineCheck) []string {
+		return compareRESTAPI(expected.RESTAPIs[i], check)
+	})...)
+
+	for kind, kindChecks := range byKind {
+		if !knownCheckKind(kind) {
+			for _, check := range kindChecks {
+				problems = append(problems, fmt.Sprintf("extra %s check (id %d)", kind, check.ID))
+			}
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// CheckSimulationResult is the simulated outcome of one check
+type CheckSimulationResult struct {
+	CheckID     int
+	Kind        string
+	DisplayName string
+	Outcome     string
+	Reason      string
+}
+
+// CheckSimulation holds the simulated outcome of every check on a resource
+type CheckSimulation struct {
+	Results []CheckSimulationResult
+}
+
+// Blocked reports whether any check would fail; external checks do not block the simulation
+func (s CheckSimulation) Blocked() bool {
+	for _, result := range s.Results {
+		if result.Outcome == CheckOutcomeFail {
+			return true
+		}
+	}
+	return false
+}
+
+// SimulateChecks evaluates whether a run from the branch at the given time would pass the configured checks.
+// Branch controls and business hours are decided locally; approvals, REST API and template checks are external.
+// Branch protection status is not evaluated.
+func SimulateChecks(checks []PipelineCheck, branch string, at time.Time) (CheckSimulation, error) {
+	ref := branch
+	if !strings.HasPrefix(ref, "refs/") {
+		ref = "refs/heads/" + ref
+	}
+
+	var simulation CheckSimulation
+	for _, check := range checks {
+		result := CheckSimulationResult{CheckID: check.ID, Kind: check.Kind, DisplayName: check.DisplayName()}
+		switch check.Kind {
+		case CheckKindBranchControl:
+			allowed := check.Input("allowedBranches")
+			if branchAllowed(allowed, ref) {
+				result.Outcome, result.Reason = CheckOutcomePass, fmt.Sprintf("%s matches %q", ref, allowed)
+			} else {
+				result.Outcome, result.Reason = CheckOutcomeFail, fmt.Sprintf("%s does not match %q", ref, allowed)
+			}
+		case CheckKindBusinessHours:
+			inside, reason, err := withinBusinessHours(check, at)
+			if err != nil {
+				return CheckSimulation{}, fmt.Errorf("check %d: %w", check.ID, err)
+			}
+			result.Outcome, result.Reason = CheckOutcomeFail, reason
+			if inside {
+				result.Outcome = CheckOutcomePass
+			}
+		case CheckKindExclusiveLock:
+			result.Outcome, result.Reason = CheckOutcomePass, "lock is granted when no other run holds it"
+		case CheckKindApproval:
+			result.Outcome, result.Reason = CheckOutcomeExternal, "waits for approvers"
+		default:
+			result.Outcome, result.Reason = CheckOutcomeExternal, "depends on the pipeline or an external service"
+		}
+		simulation.Results = append(simulation.Results, result)
+	}
+	return simulation, nil
+}
+
+func toPipelineChecks(configurations []checkConfiguration) []PipelineCheck {
+	checks := make([]PipelineCheck, 0, len(configurations))
+	for _, configuration := range configurations {
+		check := PipelineCheck{Settings: configuration.Settings}
+		if configuration.ID != nil {
+			check.ID = *configuration.ID
+		}
+		if configuration.Timeout != nil {
+			check.Timeout = *configuration.Timeout
+		}
+		if check.Settings == nil {
+			check.Settings = map[string]interface{}{}
+		}
+
+		typeName := ""
+		if configuration.Type != nil && configuration.Type.Name != nil {
+			typeName = *configuration.Type.Name
+		}
+		check.Kind = checkKind(typeName, check.Settings)
+		checks = append(checks, check)
+	}
+	sort.SliceStable(checks, func(i, j int) bool { return checks[i].ID < checks[j].ID })
+	return checks
+}
+
+// checkKind maps the check type and, for task checks, the task definition to the module's check kinds
+func checkKind(typeName string, settings map[string]interface{}) string {
+	switch strings.ToLower(typeName) {
+	case "approval":
+		return CheckKindApproval
+	case "exclusivelock":
+		return CheckKindExclusiveLock
+	case "extendscheck":
+		return CheckKindRequiredTemplate
+	case "task check":
+		definition, _ := settings["definitionRef"].(map[string]interface{})
+		name, _ := definition["name"].(string)
+		switch strings.ToLower(name) {
+		case "evaluatebranchprotection":
+			return CheckKindBranchControl
+		case "evaluatebusinesshours":
+			return CheckKindBusinessHours
+		case "invokerestapi":
+			return CheckKindRESTAPI
+		}
+		return "task:" + name
+	}
+	return typeName
+}
+
+func knownCheckKind(kind string) bool {
+	switch kind {
+	case CheckKindApproval, CheckKindBranchControl, CheckKindBusinessHours, CheckKindExclusiveLock, CheckKindRequiredTemplate, CheckKindRESTAPI:
+		return true
+	}
+	return false
+}
+
+// matchChecks pairs each expected check with an unused actual check, preferring exact matches
+func matchChecks(kind string, actual []PipelineCheck, expectedCount int, compare func(int, PipelineCheck) []string) []string {
+	var problems []string
+	used := make([]bool, len(actual))
+	for i := 0; i < expectedCount; i++ {
+		index := -1
+		var diffs []string
+		for j, check := range actual {
+			if used[j] {
+				continue
+			}
+			checkDiffs := compare(i, check)
+			if index == -1 || (len(checkDiffs) == 0 && len(diffs) > 0) {
+				index, diffs = j, checkDiffs
+			}
+			if len(diffs) == 0 {
+				break
+			}
+		}
+		if index == -1 {
+			problems = append(problems, fmt.Sprintf("missing %s check #%d", kind, i+1))
+			continue
+		}
+		used[index] = true
+		if len(diffs) > 0 {
+			problems = append(problems, fmt.Sprintf("mismatched %s check (id %d): %s", kind, actual[index].ID, strings.Join(diffs, "; ")))
+		}
+	}
+	for j, check := range actual {
+		if !used[j] {
+			problems = append(problems, fmt.Sprintf("extra %s check (id %d)", kind, check.ID))
+		}
+	}
+	return problems
+}
+
+func compareApproval(expected ExpectedApprovalCheck, check PipelineCheck) []string {
+	var diffs []string
+
+	var approvers []string
+	rawApprovers, _ := check.Settings["approvers"].([]interface{})
+	for _, raw := range rawApprovers {
+		approver, _ := raw.(map[string]interface{})
+		if id, ok := approver["id"].(string); ok {
+			approvers = append(approvers, strings.ToLower(id))
+		}
+	}
+	if actual, want := sortedJoin(approvers), sortedJoin(lowerStrings(expected.Approvers)); actual != want {
+		diffs = append(diffs, fmt.Sprintf("approvers are [%s], expected [%s]", actual, want))
+	}
+
+	if minimum := settingInt(check.Settings, "minRequiredApprovers"); minimum != expected.MinimumRequiredApprovers {
+		diffs = append(diffs, fmt.Sprintf("minimum approvers is %d, expected %d", minimum, expected.MinimumRequiredApprovers))
+	}
+	requesterCannotApprove, _ := check.Settings["requesterCannotBeApprover"].(bool)
+	if requesterCannotApprove == expected.RequesterCanApprove {
+		diffs = append(diffs, fmt.Sprintf("requester can approve is %t, expected %t", !requesterCannotApprove, expected.RequesterCanApprove))
+	}
+	instructions, _ := check.Settings["instructions"].(string)
+	if instructions != expected.Instructions {
+		diffs = append(diffs, fmt.Sprintf("instructions are %q, expected %q", instructions, expected.Instructions))
+	}
+	return diffs
+}
+
+func compareBranchControl(expected ExpectedBranchControlCheck, check PipelineCheck) []string {
+	var diffs []string
+	diffs = appendStringDiff(diffs, "display name", check.DisplayName(), expected.DisplayName)
+	diffs = appendStringDiff(diffs, "allowed branches", check.Input("allowedBranches"), expected.AllowedBranches)
+	diffs = appendBoolDiff(diffs, "verify branch protection", check.Input("ensureProtectionOfBranch"), expected.VerifyBranchProtection)
+	diffs = appendBoolDiff(diffs, "ignore unknown protection status", check.Input("allowUnknownStatusBranch"), expected.IgnoreUnknownProtectionStatus)
+	return diffs
+}
+
+func compareBusinessHours(expected ExpectedBusinessHoursCheck, check PipelineCheck) []string {
+	var diffs []string
+	diffs = appendStringDiff(diffs, "display name", check.DisplayName(), expected.DisplayName)
+	diffs = appendStringDiff(diffs, "start time", check.Input("startTime"), expected.StartTime)
+	diffs = appendStringDiff(diffs, "end time", check.Input("endTime"), expected.EndTime)
+	diffs = appendStringDiff(diffs, "time zone", check.Input("timeZone"), expected.TimeZone)
+
+	days := make([]string, 0, len(expected.Days))
+	for _, day := range expected.Days {
+		days = append(days, day.String())
+	}
+	actualDays := splitList(check.Input("businessDays"))
+	if actual, want := sortedJoin(lowerStrings(actualDays)), sortedJoin(lowerStrings(days)); actual != want {
+		diffs = append(diffs, fmt.Sprintf("business days are [%s], expected [%s]", actual, want))
+	}
+	return diffs
+}
+
+func compareRequiredTemplates(expected []ExpectedRequiredTemplate, check PipelineCheck) []string {
+	var actual []string
+	rawTemplates, _ := check.Settings["extendsChecks"].([]interface{})
+	for _, raw := range rawTemplates {
+		template, _ := raw.(map[string]interface{})
+		actual = append(actual, strings.ToLower(fmt.Sprintf("%v|%v|%v|%v", template["repositoryType"], template["repositoryName"], template["repositoryRef"], template["templatePath"])))
+	}
+
+	want := make([]string, 0, len(expected))
+	for _, template := range expected {
+		want = append(want, strings.ToLower(fmt.Sprintf("%s|%s|%s|%s", template.RepositoryType, template.RepositoryName, template.RepositoryRef, template.TemplatePath)))
+	}
+	if sortedJoin(actual) != sortedJoin(want) {
+		return []string{fmt.Sprintf("templates are [%s], expected [%s]", sortedJoin(actual), sortedJoin(want))}
+	}
+	return nil
+}
+
+func compareRESTAPI(expected ExpectedRESTAPICheck, check PipelineCheck) []string {
+	var diffs []string
+	diffs = appendStringDiff(diffs, "display name", check.DisplayName(), expected.DisplayName)
+	diffs = appendStringDiff(diffs, "method", check.Input("method"), expected.Method)
+	diffs = appendStringDiff(diffs, "url suffix", check.Input("urlSuffix"), expected.URLSuffix)
+	diffs = appendStringDiff(diffs, "success criteria", check.Input("successCriteria"), expected.SuccessCriteria)
+
+	selector := check.Input("connectedServiceNameSelector")
+	connection := check.Input(selector)
+	if connection == "" {
+		connection = check.Input("connectedServiceName")
+	}
+	diffs = appendStringDiff(diffs, "service connection", connection, expected.ConnectedServiceName)
+
+	if expected.CompletionEvent != "" {
+		waitForCompletion := strings.EqualFold(expected.CompletionEvent, "Callback")
+		diffs = appendBoolDiff(diffs, "wait for completion", check.Input("waitForCompletion"), waitForCompletion)
+	}
+	return diffs
+}
+
+// branchAllowed supports "*" and wildcard refs in the comma-separated allowed branches list
+func branchAllowed(allowed, ref string) bool {
+	for _, pattern := range splitList(allowed) {
+		if pattern == "*" {
+			return true
+		}
+		expression := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+		if matched, _ := regexp.MatchString("(?i)"+expression, ref); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func withinBusinessHours(check PipelineCheck, at time.Time) (bool, string, error) {
+	location, err := businessHoursLocation(check.Input("timeZone"))
+	if err != nil {
+		return false, "", err
+	}
+	start, err := time.Parse("15:04", check.Input("startTime"))
+	if err != nil {
+		return false, "", fmt.Errorf("invalid start time %q: %w", check.Input("startTime"), err)
+	}
+	end, err := time.Parse("15:04", check.Input("endTime"))
+	if err != nil {
+		return false, "", fmt.Errorf("invalid end time %q: %w", check.Input("endTime"), err)
+	}
+
+	local := at.In(location)
+	dayAllowed := false
+	for _, day := range splitList(check.Input("businessDays")) {
+		if strings.EqualFold(day, local.Weekday().String()) {
+			dayAllowed = true
+			break
+		}
+	}
+	if !dayAllowed {
+		return false, fmt.Sprintf("%s is not a business day", local.Weekday()), nil
+	}
+
+	minute := local.Hour()*60 + local.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+	inside := minute >= startMinute && minute < endMinute
+	if endMinute <= startMinute {
+		inside = minute >= startMinute || minute < endMinute
+	}
+	window := fmt.Sprintf("%s %s-%s %s", local.Format("15:04"), check.Input("startTime"), check.Input("endTime"), check.Input("timeZone"))
+	if inside {
+		return true, "within " + window, nil
+	}
+	return false, "outside " + window, nil
+}
+
+func businessHoursLocation(timeZone string) (*time.Location, error) {
+	if name, ok := windowsTimeZones[timeZone]; ok {
+		timeZone = name
+	}
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported time zone %q: %w", timeZone, err)
+	}
+	return location, nil
+}
+
+func appendStringDiff(diffs []string, field, actual, expected string) []string {
+	if actual != expected {
+		diffs = append(diffs, fmt.Sprintf("%s is %q, expected %q", field, actual, expected))
+	}
+	return diffs
+}
+
+func appendBoolDiff(diffs []string, field, actual string, expected bool) []string {
+	value, err := strconv.ParseBool(actual)
+	if actual == "" {
+		value, err = false, nil
+	}
+	if err != nil || value != expected {
+		diffs = append(diffs, fmt.Sprintf("%s is %q, expected %t", field, actual, expected))
+	}
+	return diffs
+}
+
+func settingInt(settings map[string]interface{}, key string) int {
+	switch value := settings[key].(type) {
+	case float64:
+		return int(value)
+	case int:
+		return value
+	case string:
+		parsed, _ := strconv.Atoi(value)
+		return parsed
+	}
+	return 0
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func lowerStrings(values []string) []string {
+	lowered := make([]string, 0, len(values))
+	for _, value := range values {
+		lowered = append(lowered, strings.ToLower(value))
+	}
+	return lowered
+}
+
+func sortedJoin(values []string) string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ", ")
+}
@@ -0,0 +1,118 @@
+package test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadTestChecks(t *testing.T) []PipelineCheck {
+	body, err := os.ReadFile(filepath.Join("testdata", "check_configurations.json"))
+	require.NoError(t, err)
+
+	var configurations []checkConfiguration
+	require.NoError(t, json.Unmarshal(body, &configurations))
+	return toPipelineChecks(configurations)
+}
+
+func testExpectedChecks() ExpectedChecks {
+	return ExpectedChecks{
+		Approvals: []ExpectedApprovalCheck{
+			{Approvers: []string{"9a2e4e6a-0f1f-4c6e-9e0b-1b2c3d4e5f60"}},
+		},
+		BranchControls: []ExpectedBranchControlCheck{
+			{DisplayName: "environment-branch-control", AllowedBranches: "refs/heads/main,refs/heads/release/*", VerifyBranchProtection: true},
+		},
+		BusinessHours: []ExpectedBusinessHoursCheck{
+			{
+				DisplayName: "environment-business-hours",
+				Days:        []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+				StartTime:   "09:00",
+				EndTime:     "17:00",
+				TimeZone:    "Central European Standard Time",
+			},
+		},
+		ExclusiveLocks: 1,
+		RequiredTemplates: [][]ExpectedRequiredTemplate{
+			{{RepositoryType: "git", RepositoryName: "project/templates", RepositoryRef: "refs/heads/main", TemplatePath: "pipelines/deploy.yml"}},
+		},
+		RESTAPIs: []ExpectedRESTAPICheck{
+			{
+				DisplayName:          "environment-rest-api",
+				ConnectedServiceName: "generic-check-endpoint",
+				Method:               "GET",
+				URLSuffix:            "/health",
+				SuccessCriteria:      "eq(root['status'], 'ok')",
+				CompletionEvent:      "ApiResponse",
+			},
+		},
+	}
+}
+
+func TestCompareChecksMatches(t *testing.T) {
+	checks := loadTestChecks(t)
+	require.Len(t, checks, 6)
+	assert.Empty(t, CompareChecks(checks, testExpectedChecks()))
+}
+
+func TestCompareChecksReportsDifferences(t *testing.T) {
+	expected := testExpectedChecks()
+	expected.Approvals[0].RequesterCanApprove = true
+	expected.BusinessHours[0].TimeZone = "UTC"
+	expected.ExclusiveLocks = 0
+	expected.RESTAPIs = append(expected.RESTAPIs, ExpectedRESTAPICheck{DisplayName: "second"})
+
+	problems := CompareChecks(loadTestChecks(t), expected)
+	require.Len(t, problems, 4, "Unexpected problems: %v", problems)
+	assert.Contains(t, problems[0], "extra exclusive_lock check (id 14)")
+	assert.Contains(t, problems[1], "mismatched approval check (id 11): requester can approve is false, expected true")
+	assert.Contains(t, problems[2], `mismatched business_hours check (id 13): time zone is "Central European Standard Time", expected "UTC"`)
+	assert.Contains(t, problems[3], "missing rest_api check #2")
+}
+
+func TestSimulateChecks(t *testing.T) {
+	checks := loadTestChecks(t)
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	require.NoError(t, err)
+
+	cases := []struct {
+		name    string
+		branch  string
+		at      time.Time
+		blocked bool
+	}{
+		{"main during business hours", "main", time.Date(2026, time.March, 4, 10, 30, 0, 0, warsaw), false},
+		{"release wildcard", "refs/heads/release/1.2", time.Date(2026, time.March, 4, 16, 59, 0, 0, warsaw), false},
+		{"feature branch", "feature/x", time.Date(2026, time.March, 4, 10, 30, 0, 0, warsaw), true},
+		{"after hours", "main", time.Date(2026, time.March, 4, 17, 0, 0, 0, warsaw), true},
+		{"weekend", "main", time.Date(2026, time.March, 7, 10, 30, 0, 0, warsaw), true},
+		{"utc input converted to zone", "main", time.Date(2026, time.March, 4, 8, 30, 0, 0, time.UTC), false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			simulation, err := SimulateChecks(checks, tc.branch, tc.at)
+			require.NoError(t, err)
+			assert.Equal(t, tc.blocked, simulation.Blocked(), "Results: %+v", simulation.Results)
+		})
+	}
+
+	simulation, err := SimulateChecks(checks, "main", time.Date(2026, time.March, 4, 10, 30, 0, 0, warsaw))
+	require.NoError(t, err)
+	outcomes := map[string]string{}
+	for _, result := range simulation.Results {
+		outcomes[result.Kind] = result.Outcome
+	}
+	assert.Equal(t, map[string]string{
+		CheckKindApproval:         CheckOutcomeExternal,
+		CheckKindBranchControl:    CheckOutcomePass,
+		CheckKindBusinessHours:    CheckOutcomePass,
+		CheckKindExclusiveLock:    CheckOutcomePass,
+		CheckKindRequiredTemplate: CheckOutcomeExternal,
+		CheckKindRESTAPI:          CheckOutcomeExternal,
+	}, outcomes)
+}
@@ -15,6 +15,26 @@ data "azuredevops_group" "project_collection_admins" {
   name = "Project Collection Administrators"
 }
 
+data "azuredevops_project" "project" {
+  project_id = var.project_id
+}
+
+resource "azuredevops_git_repository" "templates" {
+  project_id = var.project_id
+  name       = "${var.environment_name}-templates"
+
+  initialization {
+    init_type = "Clean"
+  }
+}
+
+resource "azuredevops_serviceendpoint_generic" "rest_check" {
+  project_id            = var.project_id
+  service_endpoint_name = "${var.environment_name}-rest"
+  server_url            = var.rest_check_url
+  description           = "Managed by Terraform"
+}
+
 resource "azuredevops_serviceendpoint_kubernetes" "example" {
   project_id            = var.project_id
   service_endpoint_name = "${var.environment_name}-k8s"
@@ -89,4 +109,48 @@ module "azuredevops_environments" {
       verify_branch_protection = true
     }
   ]
+
+  check_business_hours = [
+    {
+      name       = "environment-business-hours"
+      start_time = "09:00"
+      end_time   = "17:00"
+      time_zone  = "UTC"
+      monday     = true
+      tuesday    = true
+      wednesday  = true
+      thursday   = true
+      friday     = true
+    }
+  ]
+
+  check_exclusive_locks = [
+    {
+      name = "environment-lock"
+    }
+  ]
+
+  check_required_templates = [
+    {
+      name = "environment-required-template"
+      required_templates = [
+        {
+          template_path   = "pipelines/deploy.yml"
+          repository_name = "${data.azuredevops_project.project.name}/${azuredevops_git_repository.templates.name}"
+          repository_ref  = "refs/heads/main"
+        }
+      ]
+    }
+  ]
+
+  check_rest_apis = [
+    {
+      name                            = "environment-rest-api"
+      connected_service_name_selector = "connectedServiceName"
+      connected_service_name          = azuredevops_serviceendpoint_generic.rest_check.service_endpoint_name
+      method                          = "GET"
+      url_suffix                      = "/health"
+      completion_event                = "ApiResponse"
+    }
+  ]
 }
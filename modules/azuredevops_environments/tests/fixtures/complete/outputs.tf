@@ -17,3 +17,28 @@ output "approval_check_ids" {
   description = "Approval check IDs created by the module."
   value       = module.azuredevops_environments.check_ids.environment.approvals
 }
+
+output "approver_id" {
+  description = "Identity ID of the approval check approver."
+  value       = data.azuredevops_group.project_collection_admins.origin_id
+}
+
+output "kubernetes_service_endpoint_id" {
+  description = "Service endpoint backing the Kubernetes resource."
+  value       = azuredevops_serviceendpoint_kubernetes.example.id
+}
+
+output "project_name" {
+  description = "Name of the project hosting the environment."
+  value       = data.azuredevops_project.project.name
+}
+
+output "templates_repository_name" {
+  description = "Repository holding the required template."
+  value       = azuredevops_git_repository.templates.name
+}
+
+output "rest_check_service_endpoint_name" {
+  description = "Service connection used by the REST API check."
+  value       = azuredevops_serviceendpoint_generic.rest_check.service_endpoint_name
+}
@@ -10,3 +10,8 @@ variable "kubernetes_api_url" {
   type    = string
   default = "https://example.kubernetes.local"
 }
+
+variable "rest_check_url" {
+  type    = string
+  default = "https://example.endpoint.local"
+}
@@ -6,6 +6,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/importtest"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/assert"
@@ -24,10 +27,10 @@ func TestBasicAzuredevopsExtension(t *testing.T) {
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		if shouldCleanup {
 			if _, err := os.Stat(filepath.Join(testFolder, ".test-data", "TerraformOptions.json")); err == nil {
-				terraform.Destroy(t, test_structure.LoadTerraformOptions(t, testFolder))
+				destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 				return
 			}
-			terraform.Destroy(t, terraformOptions)
+			destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
 			return
 		}
 
@@ -50,6 +53,11 @@ func TestBasicAzuredevopsExtension(t *testing.T) {
 
 		assert.NotEmpty(t, extensionID)
 	})
+
+	// Adopting the deployed module resources into an empty state must plan no changes
+	test_structure.RunTestStage(t, "import", func() {
+		importtest.RequireStateImportRoundTrip(t, test_structure.LoadTerraformOptions(t, testFolder))
+	})
 }
 
 // Test complete azuredevops_extension with multiple extensions
@@ -67,10 +75,10 @@ func TestCompleteAzuredevopsExtension(t *testing.T) {
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		if shouldCleanup {
 			if _, err := os.Stat(filepath.Join(testFolder, ".test-data", "TerraformOptions.json")); err == nil {
-				terraform.Destroy(t, test_structure.LoadTerraformOptions(t, testFolder))
+				destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 				return
 			}
-			terraform.Destroy(t, terraformOptions)
+			destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
 			return
 		}
 
@@ -111,10 +119,10 @@ func TestSecureAzuredevopsExtension(t *testing.T) {
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		if shouldCleanup {
 			if _, err := os.Stat(filepath.Join(testFolder, ".test-data", "TerraformOptions.json")); err == nil {
-				terraform.Destroy(t, test_structure.LoadTerraformOptions(t, testFolder))
+				destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 				return
 			}
-			terraform.Destroy(t, terraformOptions)
+			destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
 			return
 		}
 
@@ -158,16 +166,11 @@ func TestAzuredevopsExtensionValidationRules(t *testing.T) {
 // Helper function to get terraform options
 func getTerraformOptions(terraformDir string, vars map[string]interface{}) *terraform.Options {
 	return &terraform.Options{
-		TerraformDir: terraformDir,
-		Vars:         vars,
-		NoColor:      true,
-		RetryableTerraformErrors: map[string]string{
-			".*timeout.*":                  "Timeout error - retrying",
-			".*TooManyRequests.*":          "Too many requests - retrying",
-			".*connection reset by peer.*": "Azure DevOps connection reset - retrying",
-			".*invalid character '<' looking for beginning of value.*": "Azure DevOps returned HTML instead of JSON - retrying",
-		},
-		MaxRetries:         3,
-		TimeBetweenRetries: 10 * time.Second,
+		TerraformDir:             terraformDir,
+		Vars:                     vars,
+		NoColor:                  true,
+		RetryableTerraformErrors: tfretry.ClassifiedRetryableErrors(),
+		MaxRetries:               3,
+		TimeBetweenRetries:       10 * time.Second,
 	}
 }
@@ -6,6 +6,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/importtest"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/require"
 )
@@ -61,15 +63,10 @@ func getExtensionsFromEnv() []map[string]interface{} {
 	return extensions
 }
 
-type importTarget struct {
-	address string
-	id      string
-}
-
-func applyWithImportIfInstalled(t testing.TB, options *terraform.Options, targets []importTarget) (bool, error) {
+func applyWithImportIfInstalled(t testing.TB, options *terraform.Options, targets []importtest.ImportTarget) (bool, error) {
 	t.Helper()
 
-	if _, err := terraform.InitAndApplyE(t, options); err != nil {
+	if _, err := tfretry.InitAndApplyWithRetryE(t, options); err != nil {
 		if !isAlreadyInstalledError(err) {
 			return false, err
 		}
@@ -81,7 +78,7 @@ func applyWithImportIfInstalled(t testing.TB, options *terraform.Options, target
 		t.Logf("Extension already installed; importing existing resource(s) into state")
 		terraform.Init(t, options)
 		for _, target := range targets {
-			if importErr := importTerraformResourceE(t, options, target); importErr != nil {
+			if importErr := importtest.ImportResourceE(t, options, target); importErr != nil {
 				return false, importErr
 			}
 		}
@@ -101,30 +98,30 @@ func isAlreadyInstalledError(err error) bool {
 	return strings.Contains(message, "already installed") || strings.Contains(message, "tf1590010")
 }
 
-func buildBasicImportTargets(t testing.TB, vars map[string]interface{}) []importTarget {
+func buildBasicImportTargets(t testing.TB, vars map[string]interface{}) []importtest.ImportTarget {
 	t.Helper()
 
 	publisherID := stringFromVar(t, vars, "publisher_id")
 	extensionID := stringFromVar(t, vars, "extension_id")
-	return []importTarget{
+	return []importtest.ImportTarget{
 		{
-			address: "module.azuredevops_extension.azuredevops_extension.extension",
-			id:      fmt.Sprintf("%s/%s", publisherID, extensionID),
+			Address: importtest.ModuleResourceAddress("azuredevops_extension", "azuredevops_extension", "extension"),
+			ID:      fmt.Sprintf("%s/%s", publisherID, extensionID),
 		},
 	}
 }
 
-func buildForEachImportTargets(t testing.TB, extensions []map[string]interface{}) []importTarget {
+func buildForEachImportTargets(t testing.TB, extensions []map[string]interface{}) []importtest.ImportTarget {
 	t.Helper()
 
-	targets := make([]importTarget, 0, len(extensions))
+	targets := make([]importtest.ImportTarget, 0, len(extensions))
 	for _, extension := range extensions {
 		publisherID := stringFromVar(t, extension, "publisher_id")
 		extensionID := stringFromVar(t, extension, "extension_id")
 		key := fmt.Sprintf("%s/%s", publisherID, extensionID)
-		targets = append(targets, importTarget{
-			address: fmt.Sprintf("module.azuredevops_extension[%q].azuredevops_extension.extension", key),
-			id:      key,
+		targets = append(targets, importtest.ImportTarget{
+			Address: importtest.InstanceAddress("module.azuredevops_extension", key) + ".azuredevops_extension.extension",
+			ID:      key,
 		})
 	}
 
@@ -140,32 +137,3 @@ func stringFromVar(t testing.TB, vars map[string]interface{}, key string) string
 	require.Truef(t, ok, "expected %q to be a string, got %T", key, value)
 	return stringValue
 }
-
-func importTerraformResourceE(t testing.TB, options *terraform.Options, target importTarget) error {
-	t.Helper()
-
-	args := buildImportArgs(options, target.address, target.id)
-	_, err := terraform.RunTerraformCommandE(t, options, args...)
-	return err
-}
-
-func buildImportArgs(options *terraform.Options, address, id string) []string {
-	args := []string{"import", "-input=false"}
-
-	if options.NoColor {
-		args = append(args, "-no-color")
-	}
-
-	args = append(args, terraform.FormatTerraformLockAsArgs(options.Lock, options.LockTimeout)...)
-
-	if options.SetVarsAfterVarFiles {
-		args = append(args, terraform.FormatTerraformArgs("-var-file", options.VarFiles)...)
-		args = append(args, terraform.FormatTerraformVarsAsArgs(options.Vars)...)
-	} else {
-		args = append(args, terraform.FormatTerraformVarsAsArgs(options.Vars)...)
-		args = append(args, terraform.FormatTerraformArgs("-var-file", options.VarFiles)...)
-	}
-
-	args = append(args, address, id)
-	return args
-}
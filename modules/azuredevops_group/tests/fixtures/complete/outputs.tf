@@ -12,3 +12,8 @@ output "group_membership_ids" {
   description = "Map of group membership IDs keyed by membership key."
   value       = module.azuredevops_group.group_membership_ids
 }
+
+output "member_descriptor" {
+  description = "The descriptor of the group added as a member."
+  value       = azuredevops_group.member.descriptor
+}
@@ -5,6 +5,11 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adoentitlement"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adohelper"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/importtest"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/assert"
@@ -20,10 +25,10 @@ func TestBasicAzuredevopsGroupEntitlement(t *testing.T) {
 	terraformOptions := getTerraformOptions(t, testFolder, fixtureName)
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		if _, err := os.Stat(filepath.Join(testFolder, ".test-data", "TerraformOptions.json")); err == nil {
-			terraform.Destroy(t, test_structure.LoadTerraformOptions(t, testFolder))
+			destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 			return
 		}
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "setup", func() {
@@ -32,7 +37,7 @@ func TestBasicAzuredevopsGroupEntitlement(t *testing.T) {
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -45,6 +50,16 @@ func TestBasicAzuredevopsGroupEntitlement(t *testing.T) {
 		assert.NotEmpty(t, entitlementID)
 		assert.NotEmpty(t, entitlementDescriptor)
 		assert.Equal(t, "fixture-basic-group", entitlementKey)
+
+		adoentitlement.RequireGroupEntitlement(t, adohelper.New(t).Connection(), entitlementID, adoentitlement.ExpectedEntitlement{
+			AccountLicenseType: "express",
+			LicensingSource:    "account",
+		})
+	})
+
+	// Adopting the deployed module resources into an empty state must plan no changes
+	test_structure.RunTestStage(t, "import", func() {
+		importtest.RequireStateImportRoundTrip(t, test_structure.LoadTerraformOptions(t, testFolder))
 	})
 }
 
@@ -57,10 +72,10 @@ func TestCompleteAzuredevopsGroupEntitlement(t *testing.T) {
 	terraformOptions := getTerraformOptions(t, testFolder, fixtureName)
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		if _, err := os.Stat(filepath.Join(testFolder, ".test-data", "TerraformOptions.json")); err == nil {
-			terraform.Destroy(t, test_structure.LoadTerraformOptions(t, testFolder))
+			destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 			return
 		}
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "setup", func() {
@@ -69,7 +84,7 @@ func TestCompleteAzuredevopsGroupEntitlement(t *testing.T) {
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -82,6 +97,12 @@ func TestCompleteAzuredevopsGroupEntitlement(t *testing.T) {
 		assert.NotEmpty(t, entitlementID)
 		assert.NotEmpty(t, entitlementDescriptor)
 		assert.Equal(t, "fixture-complete-group", entitlementKey)
+
+		adoentitlement.RequireGroupEntitlement(t, adohelper.New(t).Connection(), entitlementID, adoentitlement.ExpectedEntitlement{
+			AccountLicenseType: "professional",
+			LicensingSource:    "account",
+			OriginID:           optionalVar(terraformOptions, "group_origin_id"),
+		})
 	})
 }
 
@@ -94,10 +115,10 @@ func TestSecureAzuredevopsGroupEntitlement(t *testing.T) {
 	terraformOptions := getTerraformOptions(t, testFolder, fixtureName)
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		if _, err := os.Stat(filepath.Join(testFolder, ".test-data", "TerraformOptions.json")); err == nil {
-			terraform.Destroy(t, test_structure.LoadTerraformOptions(t, testFolder))
+			destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 			return
 		}
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "setup", func() {
@@ -106,7 +127,7 @@ func TestSecureAzuredevopsGroupEntitlement(t *testing.T) {
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -119,6 +140,11 @@ func TestSecureAzuredevopsGroupEntitlement(t *testing.T) {
 		assert.NotEmpty(t, entitlementID)
 		assert.NotEmpty(t, entitlementDescriptor)
 		assert.Equal(t, "fixture-secure-group", entitlementKey)
+
+		adoentitlement.RequireGroupEntitlement(t, adohelper.New(t).Connection(), entitlementID, adoentitlement.ExpectedEntitlement{
+			AccountLicenseType: "stakeholder",
+			LicensingSource:    "account",
+		})
 	})
 }
 
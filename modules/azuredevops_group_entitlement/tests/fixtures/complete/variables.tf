@@ -8,3 +8,9 @@ variable "group_origin_id" {
   description = "Origin ID for the group."
   type        = string
 }
+
+variable "project_id" {
+  description = "Project whose Contributors group the group joins, so the entitlement carries a project entitlement. Null skips the membership."
+  type        = string
+  default     = null
+}
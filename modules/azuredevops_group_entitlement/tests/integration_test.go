@@ -5,6 +5,10 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adoentitlement"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adohelper"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/assert"
@@ -18,15 +22,20 @@ func TestAzuredevopsGroupEntitlementFullIntegration(t *testing.T) {
 
 	fixtureName := "complete"
 	requireADOGroupEntitlementEnv(t, fixtureName)
+	projectID := os.Getenv("AZDO_PROJECT_ID")
+	if projectID == "" {
+		t.Skip("Skipping Azure DevOps tests: AZDO_PROJECT_ID is required to check project entitlements")
+	}
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/complete")
 	terraformOptions := getTerraformOptions(t, testFolder, fixtureName)
+	terraformOptions.Vars["project_id"] = projectID
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		if _, err := os.Stat(filepath.Join(testFolder, ".test-data", "TerraformOptions.json")); err == nil {
-			terraform.Destroy(t, test_structure.LoadTerraformOptions(t, testFolder))
+			destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 			return
 		}
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "setup", func() {
@@ -35,7 +44,7 @@ func TestAzuredevopsGroupEntitlementFullIntegration(t *testing.T) {
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -43,5 +52,14 @@ func TestAzuredevopsGroupEntitlementFullIntegration(t *testing.T) {
 
 		entitlementID := terraform.Output(t, terraformOptions, "group_entitlement_id")
 		assert.NotEmpty(t, entitlementID)
+
+		// The group rule must be applied with the license and reach the project through its Contributors membership
+		adoentitlement.RequireGroupEntitlement(t, adohelper.New(t).Connection(), entitlementID, adoentitlement.ExpectedEntitlement{
+			AccountLicenseType:  "professional",
+			LicensingSource:     "account",
+			OriginID:            optionalVar(terraformOptions, "group_origin_id"),
+			ProjectEntitlements: map[string]string{projectID: "projectContributor"},
+			AllowOtherProjects:  true,
+		})
 	})
 }
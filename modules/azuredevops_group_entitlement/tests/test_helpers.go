@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 )
 
@@ -72,17 +73,12 @@ func getTerraformOptions(t testing.TB, terraformDir string, fixtureName string)
 	}
 
 	return &terraform.Options{
-		TerraformDir: terraformDir,
-		Vars:         vars,
-		NoColor:      true,
-		RetryableTerraformErrors: map[string]string{
-			".*timeout.*":                  "Timeout error - retrying",
-			".*TooManyRequests.*":          "Too many requests - retrying",
-			".*connection reset by peer.*": "Azure DevOps connection reset - retrying",
-			".*invalid character '<' looking for beginning of value.*": "Azure DevOps returned HTML instead of JSON - retrying",
-		},
-		MaxRetries:         3,
-		TimeBetweenRetries: 10 * time.Second,
+		TerraformDir:             terraformDir,
+		Vars:                     vars,
+		NoColor:                  true,
+		RetryableTerraformErrors: tfretry.ClassifiedRetryableErrors(),
+		MaxRetries:               3,
+		TimeBetweenRetries:       10 * time.Second,
 	}
 }
 
@@ -102,3 +98,11 @@ func formatFixtureEnv(baseName string, fixtureName string) string {
 	}
 	return fmt.Sprintf("%s or %s_%s", baseName, baseName, fixtureName)
 }
+
+// optionalVar returns a Terraform variable passed to the fixture, or "" when it was not set
+func optionalVar(terraformOptions *terraform.Options, name string) string {
+	if value, ok := terraformOptions.Vars[name]; ok && value != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return ""
+}
@@ -1,11 +1,17 @@
 package test
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"testing"
 	"time"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adoacl"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adohelper"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/importtest"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
@@ -30,7 +36,7 @@ func TestBasicAzuredevopsPipelines(t *testing.T) {
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -39,6 +45,22 @@ func TestBasicAzuredevopsPipelines(t *testing.T) {
 		buildDefinitionID := terraform.Output(t, terraformOptions, "build_definition_id")
 
 		assert.NotEmpty(t, buildDefinitionID)
+
+		helper := NewAzureDevOpsHelper(t)
+		definition := helper.GetBuildDefinition(t, getProjectID(t), adohelper.ParseIntID(t, buildDefinitionID))
+		require.NotNil(t, definition.Name)
+		assert.Equal(t, fmt.Sprintf("pip-ado-bas-%s", terraformOptions.Vars["random_suffix"]), *definition.Name)
+		require.NotNil(t, definition.Repository)
+		require.NotNil(t, definition.Repository.Type)
+		assert.Equal(t, "TfsGit", *definition.Repository.Type)
+		process, ok := definition.Process.(map[string]interface{})
+		require.True(t, ok, "Build definition process should be a YAML process")
+		assert.Equal(t, "azure-pipelines.yml", process["yamlFilename"])
+	})
+
+	// Adopting the deployed module resources into an empty state must plan no changes
+	test_structure.RunTestStage(t, "import", func() {
+		importtest.RequireStateImportRoundTrip(t, test_structure.LoadTerraformOptions(t, testFolder))
 	})
 }
 
@@ -59,7 +81,7 @@ func TestCompleteAzuredevopsPipelines(t *testing.T) {
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -67,6 +89,62 @@ func TestCompleteAzuredevopsPipelines(t *testing.T) {
 
 		buildDefinitionIDs := terraform.OutputMap(t, terraformOptions, "build_definition_ids")
 		assert.NotEmpty(t, buildDefinitionIDs)
+		repositoryID := terraform.Output(t, terraformOptions, "repository_id")
+		serviceEndpointID := terraform.Output(t, terraformOptions, "service_endpoint_id")
+		suffix := fmt.Sprintf("%v", terraformOptions.Vars["random_suffix"])
+
+		expected := map[string]ExpectedPipeline{
+			"app": {
+				Name:           fmt.Sprintf("pip-ado-cmp-app-%s", suffix),
+				Path:           fmt.Sprintf("\\Pipelines-app-%s", suffix),
+				RepositoryID:   repositoryID,
+				RepositoryType: "TfsGit",
+				Branch:         "refs/heads/main",
+				YAMLPath:       "azure-pipelines.yml",
+				Variables:      map[string]string{"ENV": "dev"},
+				Schedules: []ExpectedSchedule{
+					{
+						BranchInclude:   []string{"main"},
+						BranchExclude:   []string{"experimental"},
+						DaysToBuild:     []string{"Mon", "Wed", "Fri"},
+						StartHours:      8,
+						StartMinutes:    30,
+						OnlyWithChanges: true,
+					},
+				},
+			},
+			"release": {
+				Name:           fmt.Sprintf("pip-ado-cmp-rel-%s", suffix),
+				Path:           fmt.Sprintf("\\Pipelines-release-%s", suffix),
+				RepositoryID:   repositoryID,
+				RepositoryType: "TfsGit",
+				Branch:         "refs/heads/main",
+				YAMLPath:       "azure-pipelines-release.yml",
+				CIUseYAML:      true,
+			},
+		}
+		require.Len(t, buildDefinitionIDs, len(expected))
+
+		helper := NewAzureDevOpsHelper(t)
+		projectID := getProjectID(t)
+		for key, pipeline := range expected {
+			definitionID := adohelper.ParseIntID(t, buildDefinitionIDs[key])
+			RequirePipelineDefinition(t, helper, projectID, definitionID, pipeline)
+			RequirePipelineAuthorizations(t, helper, projectID, definitionID, repositoryID, []ExpectedAuthorization{
+				{Type: "endpoint", ResourceID: serviceEndpointID},
+			})
+		}
+	})
+
+	test_structure.RunTestStage(t, "run", func() {
+		if os.Getenv("AZDO_SKIP_PIPELINE_RUN") != "" {
+			t.Skip("AZDO_SKIP_PIPELINE_RUN is set")
+		}
+		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+
+		buildDefinitionIDs := terraform.OutputMap(t, terraformOptions, "build_definition_ids")
+		helper := NewAzureDevOpsHelper(t)
+		RunPipelineAndWait(t, helper, getProjectID(t), adohelper.ParseIntID(t, buildDefinitionIDs["app"]), "refs/heads/main", 20*time.Minute)
 	})
 }
 
@@ -87,7 +165,7 @@ func TestSecureAzuredevopsPipelines(t *testing.T) {
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -96,6 +174,16 @@ func TestSecureAzuredevopsPipelines(t *testing.T) {
 		buildDefinitionID := terraform.Output(t, terraformOptions, "build_definition_id")
 
 		assert.NotEmpty(t, buildDefinitionID)
+
+		definitionID, err := strconv.Atoi(buildDefinitionID)
+		require.NoError(t, err)
+		helper := NewAzureDevOpsHelper(t)
+		collectionAdmins := adoacl.RequireIdentityDescriptor(t, helper.Connection(), terraform.Output(t, terraformOptions, "collection_admins_descriptor"))
+		// The fixture keeps the default root folder, so the token is <project>/<definition>
+		token := adoacl.BuildDefinitionPermissionToken(getProjectID(t), `\`, definitionID)
+		adoacl.RequireEffectivePermission(t, helper.Connection(), adoacl.SecurityNamespaceBuild, token, collectionAdmins, "ViewBuildDefinition", adoacl.PermissionAllow)
+		// The explicit Deny on the definition wins over the Allow collection administrators inherit
+		adoacl.RequireEffectivePermission(t, helper.Connection(), adoacl.SecurityNamespaceBuild, token, collectionAdmins, "EditBuildDefinition", adoacl.PermissionDeny)
 	})
 }
 
@@ -127,12 +215,9 @@ func getTerraformOptions(t testing.TB, terraformDir string) *terraform.Options {
 			"project_id":    getProjectID(t),
 			"random_suffix": uniqueID,
 		},
-		NoColor: true,
-		RetryableTerraformErrors: map[string]string{
-			".*timeout.*":         "Timeout error - retrying",
-			".*TooManyRequests.*": "Too many requests - retrying",
-		},
-		MaxRetries:         3,
-		TimeBetweenRetries: 10 * time.Second,
+		NoColor:                  true,
+		RetryableTerraformErrors: tfretry.ClassifiedRetryableErrors(),
+		MaxRetries:               3,
+		TimeBetweenRetries:       10 * time.Second,
 	}
 }
@@ -20,6 +20,27 @@ resource "azuredevops_git_repository" "example" {
   }
 }
 
+locals {
+  pipeline_yaml = <<-EOT
+    trigger: none
+    pool:
+      vmImage: ubuntu-latest
+    steps:
+      - script: echo "Terratest pipeline run"
+  EOT
+}
+
+resource "azuredevops_git_repository_file" "pipeline" {
+  for_each = toset([var.yaml_path, "azure-pipelines-release.yml"])
+
+  repository_id       = azuredevops_git_repository.example.id
+  file                = each.value
+  content             = local.pipeline_yaml
+  branch              = "refs/heads/main"
+  commit_message      = "Add ${each.value}"
+  overwrite_on_create = true
+}
+
 resource "azuredevops_serviceendpoint_generic" "example" {
   project_id            = var.project_id
   service_endpoint_name = "se-ado-cmp-${var.random_suffix}"
@@ -92,9 +113,10 @@ module "azuredevops_pipelines" {
   path       = each.value.path
 
   repository = {
-    repo_type = "TfsGit"
-    repo_id   = azuredevops_git_repository.example.id
-    yml_path  = each.value.yml_path
+    repo_type   = "TfsGit"
+    repo_id     = azuredevops_git_repository.example.id
+    branch_name = "refs/heads/main"
+    yml_path    = each.value.yml_path
   }
 
   ci_trigger = try(each.value.ci_trigger, null)
@@ -103,4 +125,6 @@ module "azuredevops_pipelines" {
   variables = each.value.variables
 
   pipeline_authorizations = each.value.pipeline_authorizations
+
+  depends_on = [azuredevops_git_repository_file.pipeline]
 }
@@ -2,3 +2,13 @@ output "build_definition_ids" {
   description = "Build definition IDs created by the module."
   value       = { for key, mod in module.azuredevops_pipelines : key => mod.build_definition_id }
 }
+
+output "repository_id" {
+  description = "Repository hosting the pipeline YAML files."
+  value       = azuredevops_git_repository.example.id
+}
+
+output "service_endpoint_id" {
+  description = "Service endpoint authorized for the pipelines."
+  value       = azuredevops_serviceendpoint_generic.example.id
+}
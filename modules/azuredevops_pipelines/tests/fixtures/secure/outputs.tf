@@ -2,3 +2,8 @@ output "build_definition_id" {
   description = "Build definition ID created by the module."
   value       = module.azuredevops_pipelines.build_definition_id
 }
+
+output "collection_admins_descriptor" {
+  description = "Descriptor of the Project Collection Administrators group given build definition permissions."
+  value       = data.azuredevops_group.project_collection_admins.descriptor
+}
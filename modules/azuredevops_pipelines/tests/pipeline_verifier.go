@@ -0,0 +1,403 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adohelper"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/build"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/pipelinepermissions"
+	"github.com/stretchr/testify/require"
+)
+
+// scheduleDayFlags follows the ScheduleDays flags used by build definition schedules
+var scheduleDayFlags = []struct {
+	flag int
+	name string
+}{
+	{1, "mon"}, {2, "tue"}, {4, "wed"}, {8, "thu"}, {16, "fri"}, {32, "sat"}, {64, "sun"},
+}
+
+// ExpectedSchedule mirrors a schedules entry of the module
+type ExpectedSchedule struct {
+	BranchInclude   []string
+	BranchExclude   []string
+	DaysToBuild     []string
+	StartHours      int
+	StartMinutes    int
+	OnlyWithChanges bool
+}
+
+// ExpectedPipeline is the build definition the fixture should have created
+type ExpectedPipeline struct {
+	Name           string
+	Path           string
+	RepositoryID   string
+	RepositoryType string
+	Branch         string
+	YAMLPath       string
+	Variables      map[string]string
+	CIUseYAML      bool
+	Schedules      []ExpectedSchedule
+}
+
+// ExpectedAuthorization is a pipeline_authorizations entry
+type ExpectedAuthorization struct {
+	Type       string
+	ResourceID string
+}
+
+// GetDefinitionResourcesE retrieves the resources authorized for a build definition
+func (h *AzureDevOpsHelper) GetDefinitionResourcesE(projectID string, definitionID int) ([]build.DefinitionResourceReference, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), adohelper.RequestTimeout)
+	defer cancel()
+
+	client, err := h.Build(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resources, err := client.GetDefinitionResources(ctx, build.GetDefinitionResourcesArgs{
+		Project:      &projectID,
+		DefinitionId: &definitionID,
+	})
+	if err != nil || resources == nil {
+		return nil, err
+	}
+	return *resources, nil
+}
+
+// GetPipelinePermissionsE retrieves which pipelines may use a protected resource
+func (h *AzureDevOpsHelper) GetPipelinePermissionsE(projectID, resourceType, resourceID string) (*pipelinepermissions.ResourcePipelinePermissions, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), adohelper.RequestTimeout)
+	defer cancel()
+
+	client, err := pipelinepermissions.NewClient(ctx, h.Connection())
+	if err != nil {
+		return nil, err
+	}
+	return client.GetPipelinePermissionsForResource(ctx, pipelinepermissions.GetPipelinePermissionsForResourceArgs{
+		Project:      &projectID,
+		ResourceType: &resourceType,
+		ResourceId:   &resourceID,
+	})
+}
+
+// QueueBuildE queues a run of the definition on the given branch
+func (h *AzureDevOpsHelper) QueueBuildE(projectID string, definitionID int, branch string) (*build.Build, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), adohelper.RequestTimeout)
+	defer cancel()
+
+	client, err := h.Build(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.QueueBuild(ctx, build.QueueBuildArgs{
+		Project: &projectID,
+		Build: &build.Build{
+			Definition:   &build.DefinitionReference{Id: &definitionID},
+			SourceBranch: &branch,
+		},
+	})
+}
+
+// GetBuildE retrieves a build by ID
+func (h *AzureDevOpsHelper) GetBuildE(projectID string, buildID int) (*build.Build, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), adohelper.RequestTimeout)
+	defer cancel()
+
+	client, err := h.Build(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetBuild(ctx, build.GetBuildArgs{Project: &projectID, BuildId: &buildID})
+}
+
+// RequirePipelineDefinition fetches the definition and fails the test when it differs from the expected pipeline
+func RequirePipelineDefinition(t testing.TB, helper *AzureDevOpsHelper, projectID string, definitionID int, expected ExpectedPipeline) *build.BuildDefinition {
+	t.Helper()
+
+	definition := helper.GetBuildDefinition(t, projectID, definitionID)
+	if problems := ComparePipelineDefinition(definition, expected); len(problems) > 0 {
+		require.FailNow(t, "Build definition does not match the fixture", "definition %d:\n  %s", definitionID, strings.Join(problems, "\n  "))
+	}
+	return definition
+}
+
+// RequirePipelineAuthorizations asserts the definition is authorized on exactly the expected resources.
+// The definition's own repository is implicitly authorized and is not reported as extra.
+func RequirePipelineAuthorizations(t testing.TB, helper *AzureDevOpsHelper, projectID string, definitionID int, repositoryID string, expected []ExpectedAuthorization) {
+	t.Helper()
+
+	var problems []string
+	for _, authorization := range expected {
+		permissions, err := helper.GetPipelinePermissionsE(projectID, strings.ToLower(authorization.Type), authorization.ResourceID)
+		require.NoError(t, err, "Failed to get pipeline permissions for %s %s", authorization.Type, authorization.ResourceID)
+		problems = append(problems, comparePipelinePermissions(definitionID, authorization, permissions)...)
+	}
+
+	resources, err := helper.GetDefinitionResourcesE(projectID, definitionID)
+	require.NoError(t, err, "Failed to get authorized resources for definition %d", definitionID)
+	problems = append(problems, CompareAuthorizedResources(resources, repositoryID, expected)...)
+
+	if len(problems) > 0 {
+		require.FailNow(t, "Pipeline authorizations do not match the fixture", "definition %d:\n  %s", definitionID, strings.Join(problems, "\n  "))
+	}
+}
+
+// RunPipelineAndWait queues the definition and waits for the run to succeed, proving the YAML path is valid
+func RunPipelineAndWait(t testing.TB, helper *AzureDevOpsHelper, projectID string, definitionID int, branch string, timeout time.Duration) *build.Build {
+	t.Helper()
+
+	queued, err := helper.QueueBuildE(projectID, definitionID, branch)
+	require.NoError(t, err, "Failed to queue definition %d", definitionID)
+	require.NotNil(t, queued.Id)
+
+	pollInterval := 15 * time.Second
+	maxRetries := int(timeout / pollInterval)
+	var completed *build.Build
+	retry.DoWithRetry(t, fmt.Sprintf("Waiting for build %d of definition %d", *queued.Id, definitionID), maxRetries, pollInterval, func() (string, error) {
+		current, err := helper.GetBuildE(projectID, *queued.Id)
+		if err != nil {
+			return "", err
+		}
+		if current.Status == nil || *current.Status != build.BuildStatusValues.Completed {
+			return "", fmt.Errorf("build %d status is %v", *queued.Id, derefValue(current.Status))
+		}
+		completed = current
+		return "completed", nil
+	})
+
+	require.NotNil(t, completed.Result)
+	require.Equal(t, build.BuildResultValues.Succeeded, *completed.Result, "Build %d of definition %d did not succeed", *queued.Id, definitionID)
+	return completed
+}
+
+// ComparePipelineDefinition reports differences in repository, branch, YAML path, variables and triggers
+func ComparePipelineDefinition(definition *build.BuildDefinition, expected ExpectedPipeline) []string {
+	var problems []string
+	problems = appendValueDiff(problems, "name", derefValue(definition.Name), expected.Name)
+	if expected.Path != "" {
+		problems = appendValueDiff(problems, "path", derefValue(definition.Path), expected.Path)
+	}
+
+	if definition.Repository == nil {
+		problems = append(problems, "repository is not set")
+	} else {
+		problems = appendValueDiff(problems, "repository id", strings.ToLower(derefValue(definition.Repository.Id)), strings.ToLower(expected.RepositoryID))
+		problems = appendValueDiff(problems, "repository type", derefValue(definition.Repository.Type), expected.RepositoryType)
+		if expected.Branch != "" {
+			problems = appendValueDiff(problems, "branch", derefValue(definition.Repository.DefaultBranch), expected.Branch)
+		}
+	}
+
+	process, _ := definition.Process.(map[string]interface{})
+	yamlPath, _ := process["yamlFilename"].(string)
+	problems = appendValueDiff(problems, "yaml path", yamlPath, expected.YAMLPath)
+
+	problems = append(problems, compareVariables(definition.Variables, expected.Variables)...)
+	problems = append(problems, compareTriggers(definition.Triggers, expected)...)
+	return problems
+}
+
+// CompareAuthorizedResources reports resources authorized for the definition that were not listed and listed ones that are missing
+func CompareAuthorizedResources(resources []build.DefinitionResourceReference, repositoryID string, expected []ExpectedAuthorization) []string {
+	want := map[string]bool{}
+	for _, authorization := range expected {
+		want[authorizationKey(authorization.Type, authorization.ResourceID)] = true
+	}
+
+	var problems []string
+	found := map[string]bool{}
+	for _, resource := range resources {
+		if resource.Authorized == nil || !*resource.Authorized {
+			continue
+		}
+		resourceType, resourceID := derefValue(resource.Type), derefValue(resource.Id)
+		key := authorizationKey(resourceType, resourceID)
+		if want[key] {
+			found[key] = true
+			continue
+		}
+		if strings.EqualFold(resourceType, "repository") && repositoryID != "" && strings.HasSuffix(strings.ToLower(resourceID), strings.ToLower(repositoryID)) {
+			continue
+		}
+		problems = append(problems, fmt.Sprintf("extra authorization for %s %s (%s)", resourceType, resourceID, derefValue(resource.Name)))
+	}
+	for _, authorization := range expected {
+		if !found[authorizationKey(authorization.Type, authorization.ResourceID)] {
+			problems = append(problems, fmt.Sprintf("definition resources do not list %s %s", authorization.Type, authorization.ResourceID))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+func comparePipelinePermissions(definitionID int, authorization ExpectedAuthorization, permissions *pipelinepermissions.ResourcePipelinePermissions) []string {
+	subject := fmt.Sprintf("%s %s", authorization.Type, authorization.ResourceID)
+	if permissions == nil {
+		return []string{fmt.Sprintf("no pipeline permissions returned for %s", subject)}
+	}
+
+	var problems []string
+	if permissions.AllPipelines != nil && permissions.AllPipelines.Authorized != nil && *permissions.AllPipelines.Authorized {
+		problems = append(problems, fmt.Sprintf("%s is open to all pipelines instead of the listed pipeline", subject))
+	}
+	if permissions.Pipelines != nil {
+		for _, pipeline := range *permissions.Pipelines {
+			if pipeline.Id != nil && *pipeline.Id == definitionID && pipeline.Authorized != nil && *pipeline.Authorized {
+				return problems
+			}
+		}
+	}
+	return append(problems, fmt.Sprintf("%s does not authorize definition %d", subject, definitionID))
+}
+
+func compareVariables(variables *map[string]build.BuildDefinitionVariable, expected map[string]string) []string {
+	actual := map[string]string{}
+	if variables != nil {
+		for name, variable := range *variables {
+			actual[name] = derefValue(variable.Value)
+		}
+	}
+
+	var problems []string
+	for name, value := range expected {
+		actualValue, ok := actual[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("variable %s is missing", name))
+			continue
+		}
+		problems = appendValueDiff(problems, "variable "+name, actualValue, value)
+	}
+	for name := range actual {
+		if _, ok := expected[name]; !ok {
+			problems = append(problems, fmt.Sprintf("unexpected variable %s", name))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+func compareTriggers(triggers *[]interface{}, expected ExpectedPipeline) []string {
+	var ciUseYAML bool
+	var schedules []map[string]interface{}
+	if triggers != nil {
+		for _, raw := range *triggers {
+			trigger, _ := raw.(map[string]interface{})
+			switch strings.ToLower(fmt.Sprint(trigger["triggerType"])) {
+			case "continuousintegration", "2":
+				ciUseYAML = fmt.Sprint(trigger["settingsSourceType"]) == "2"
+			case "schedule", "8":
+				rawSchedules, _ := trigger["schedules"].([]interface{})
+				for _, rawSchedule := range rawSchedules {
+					if schedule, ok := rawSchedule.(map[string]interface{}); ok {
+						schedules = append(schedules, schedule)
+					}
+				}
+			}
+		}
+	}
+
+	var problems []string
+	if ciUseYAML != expected.CIUseYAML {
+		problems = append(problems, fmt.Sprintf("CI trigger from YAML is %t, expected %t", ciUseYAML, expected.CIUseYAML))
+	}
+	if len(schedules) != len(expected.Schedules) {
+		return append(problems, fmt.Sprintf("definition has %d schedules, expected %d", len(schedules), len(expected.Schedules)))
+	}
+	for i, want := range expected.Schedules {
+		schedule := schedules[i]
+		prefix := fmt.Sprintf("schedule %d ", i+1)
+
+		var filters []string
+		rawFilters, _ := schedule["branchFilters"].([]interface{})
+		for _, filter := range rawFilters {
+			filters = append(filters, normalizeBranchFilter(fmt.Sprint(filter)))
+		}
+		var wantFilters []string
+		for _, branch := range want.BranchInclude {
+			wantFilters = append(wantFilters, normalizeBranchFilter("+"+branch))
+		}
+		for _, branch := range want.BranchExclude {
+			wantFilters = append(wantFilters, normalizeBranchFilter("-"+branch))
+		}
+		problems = appendValueDiff(problems, prefix+"branch filters", joinSorted(filters), joinSorted(wantFilters))
+		problems = appendValueDiff(problems, prefix+"days", joinSorted(scheduleDays(schedule["daysToBuild"])), joinSorted(lowerPrefixes(want.DaysToBuild)))
+		problems = appendValueDiff(problems, prefix+"start", fmt.Sprintf("%v:%v", schedule["startHours"], schedule["startMinutes"]), fmt.Sprintf("%d:%d", want.StartHours, want.StartMinutes))
+		problems = appendValueDiff(problems, prefix+"only with changes", fmt.Sprint(schedule["scheduleOnlyWithChanges"]), strconv.FormatBool(want.OnlyWithChanges))
+	}
+	return problems
+}
+
+// scheduleDays accepts either the numeric flags or the comma separated enum names
+func scheduleDays(value interface{}) []string {
+	var days []string
+	switch typed := value.(type) {
+	case float64:
+		for _, day := range scheduleDayFlags {
+			if int(typed)&day.flag != 0 {
+				days = append(days, day.name)
+			}
+		}
+	case string:
+		days = lowerPrefixes(strings.Split(typed, ","))
+	}
+	return days
+}
+
+// normalizeBranchFilter drops the refs/heads/ prefix so "+main" and "+refs/heads/main" compare equal
+func normalizeBranchFilter(filter string) string {
+	if filter == "" {
+		return filter
+	}
+	sign, branch := filter[:1], filter[1:]
+	if sign != "+" && sign != "-" {
+		sign, branch = "+", filter
+	}
+	return sign + strings.TrimPrefix(branch, "refs/heads/")
+}
+
+func lowerPrefixes(values []string) []string {
+	var prefixes []string
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if len(value) > 3 {
+			value = value[:3]
+		}
+		if value != "" {
+			prefixes = append(prefixes, value)
+		}
+	}
+	return prefixes
+}
+
+func authorizationKey(resourceType, resourceID string) string {
+	return strings.ToLower(resourceType) + ":" + strings.ToLower(resourceID)
+}
+
+func appendValueDiff(problems []string, field, actual, expected string) []string {
+	if actual != expected {
+		problems = append(problems, fmt.Sprintf("%s is %q, expected %q", field, actual, expected))
+	}
+	return problems
+}
+
+func joinSorted(values []string) string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+func derefValue[T any](value *T) T {
+	var zero T
+	if value == nil {
+		return zero
+	}
+	return *value
+}
@@ -0,0 +1,118 @@
+package test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/build"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/pipelinepermissions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPipelineRepositoryID = "5febef5a-833d-4e14-b9c0-14cb638f91e6"
+
+func loadTestBuildDefinition(t *testing.T) *build.BuildDefinition {
+	content, err := os.ReadFile(filepath.Join("testdata", "build_definition.json"))
+	require.NoError(t, err)
+
+	var definition build.BuildDefinition
+	require.NoError(t, json.Unmarshal(content, &definition))
+	return &definition
+}
+
+func testExpectedPipeline() ExpectedPipeline {
+	return ExpectedPipeline{
+		Name:           "pip-ado-cmp-app-abc123",
+		Path:           "\\Pipelines-app-abc123",
+		RepositoryID:   testPipelineRepositoryID,
+		RepositoryType: "TfsGit",
+		Branch:         "refs/heads/main",
+		YAMLPath:       "azure-pipelines.yml",
+		Variables:      map[string]string{"ENV": "dev"},
+		Schedules: []ExpectedSchedule{
+			{
+				BranchInclude:   []string{"main"},
+				BranchExclude:   []string{"experimental"},
+				DaysToBuild:     []string{"Mon", "Wed", "Fri"},
+				StartHours:      8,
+				StartMinutes:    30,
+				OnlyWithChanges: true,
+			},
+		},
+	}
+}
+
+func TestComparePipelineDefinitionMatches(t *testing.T) {
+	assert.Empty(t, ComparePipelineDefinition(loadTestBuildDefinition(t), testExpectedPipeline()))
+}
+
+func TestComparePipelineDefinitionDifferences(t *testing.T) {
+	expected := testExpectedPipeline()
+	expected.YAMLPath = "pipelines/app.yml"
+	expected.Branch = "refs/heads/develop"
+	expected.Variables = map[string]string{"ENV": "prod", "REGION": "westeurope"}
+	expected.CIUseYAML = true
+	expected.Schedules[0].DaysToBuild = []string{"Mon", "Tue"}
+
+	problems := ComparePipelineDefinition(loadTestBuildDefinition(t), expected)
+	assert.ElementsMatch(t, []string{
+		`branch is "refs/heads/main", expected "refs/heads/develop"`,
+		`yaml path is "azure-pipelines.yml", expected "pipelines/app.yml"`,
+		`variable ENV is "dev", expected "prod"`,
+		"variable REGION is missing",
+		"CI trigger from YAML is false, expected true",
+		`schedule 1 days is "fri,mon,wed", expected "mon,tue"`,
+	}, problems)
+}
+
+func TestScheduleDaysAcceptsFlagsAndNames(t *testing.T) {
+	assert.Equal(t, []string{"mon", "wed", "fri"}, scheduleDays(float64(21)))
+	assert.Equal(t, []string{"sat", "sun"}, scheduleDays("saturday, sunday"))
+	assert.Empty(t, scheduleDays(nil))
+}
+
+func TestCompareAuthorizedResources(t *testing.T) {
+	authorized := true
+	reference := func(resourceType, id, name string) build.DefinitionResourceReference {
+		return build.DefinitionResourceReference{Authorized: &authorized, Type: &resourceType, Id: &id, Name: &name}
+	}
+	expected := []ExpectedAuthorization{{Type: "endpoint", ResourceID: "ENDPOINT-1"}}
+
+	// The definition's own repository is reported as "<project>.<repository>" and is ignored
+	resources := []build.DefinitionResourceReference{
+		reference("endpoint", "endpoint-1", "se-generic"),
+		reference("repository", "8d1a9c54."+testPipelineRepositoryID, "repo"),
+	}
+	assert.Empty(t, CompareAuthorizedResources(resources, testPipelineRepositoryID, expected))
+
+	resources = []build.DefinitionResourceReference{
+		reference("queue", "17", "Azure Pipelines"),
+	}
+	assert.Equal(t, []string{
+		"definition resources do not list endpoint ENDPOINT-1",
+		"extra authorization for queue 17 (Azure Pipelines)",
+	}, CompareAuthorizedResources(resources, testPipelineRepositoryID, expected))
+}
+
+func TestComparePipelinePermissions(t *testing.T) {
+	authorized, allowed := true, 42
+	other := 7
+	authorization := ExpectedAuthorization{Type: "endpoint", ResourceID: "endpoint-1"}
+
+	permissions := &pipelinepermissions.ResourcePipelinePermissions{
+		Pipelines: &[]pipelinepermissions.PipelinePermission{{Id: &allowed, Authorized: &authorized}},
+	}
+	assert.Empty(t, comparePipelinePermissions(42, authorization, permissions))
+
+	permissions = &pipelinepermissions.ResourcePipelinePermissions{
+		AllPipelines: &pipelinepermissions.Permission{Authorized: &authorized},
+		Pipelines:    &[]pipelinepermissions.PipelinePermission{{Id: &other, Authorized: &authorized}},
+	}
+	assert.Equal(t, []string{
+		"endpoint endpoint-1 is open to all pipelines instead of the listed pipeline",
+		"endpoint endpoint-1 does not authorize definition 42",
+	}, comparePipelinePermissions(42, authorization, permissions))
+}
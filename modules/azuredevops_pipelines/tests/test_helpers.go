@@ -5,6 +5,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adohelper"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/require"
 )
@@ -30,7 +32,7 @@ func getProjectID(t testing.TB) string {
 func destroyAllowMissingPipeline(t testing.TB, options *terraform.Options) {
 	t.Helper()
 
-	_, err := terraform.DestroyE(t, options)
+	_, err := tfretry.DestroyWithRetryE(t, options)
 	if err == nil {
 		return
 	}
@@ -44,3 +46,15 @@ func destroyAllowMissingPipeline(t testing.TB, options *terraform.Options) {
 
 	require.NoError(t, err)
 }
+
+// AzureDevOpsHelper adds the pipeline verification calls of this suite to the shared helper
+type AzureDevOpsHelper struct {
+	*adohelper.Helper
+}
+
+// NewAzureDevOpsHelper creates a helper authenticated with AZDO_ORG_SERVICE_URL and AZDO_PERSONAL_ACCESS_TOKEN
+func NewAzureDevOpsHelper(t testing.TB) *AzureDevOpsHelper {
+	t.Helper()
+
+	return &AzureDevOpsHelper{Helper: adohelper.New(t)}
+}
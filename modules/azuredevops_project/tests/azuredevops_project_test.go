@@ -5,9 +5,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adohelper"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/importtest"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/core"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -19,13 +24,13 @@ func TestBasicAzuredevopsProject(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/basic")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -36,6 +41,24 @@ func TestBasicAzuredevopsProject(t *testing.T) {
 
 		assert.NotEmpty(t, projectID)
 		assert.NotEmpty(t, projectName)
+
+		helper := adohelper.New(t)
+		project := helper.GetProject(t, projectID)
+		require.NotNil(t, project.Name)
+		assert.Equal(t, projectName, *project.Name)
+		require.NotNil(t, project.Description)
+		assert.Equal(t, "Test basic Azure DevOps project", *project.Description)
+		require.NotNil(t, project.Visibility)
+		assert.Equal(t, core.ProjectVisibilityValues.Private, *project.Visibility)
+		require.NotNil(t, project.Capabilities)
+		capabilities := *project.Capabilities
+		assert.Equal(t, "Git", capabilities["versioncontrol"]["sourceControlType"])
+		assert.Equal(t, "Agile", capabilities["processTemplate"]["templateName"])
+	})
+
+	// Adopting the deployed module resources into an empty state must plan no changes
+	test_structure.RunTestStage(t, "import", func() {
+		importtest.RequireStateImportRoundTrip(t, test_structure.LoadTerraformOptions(t, testFolder))
 	})
 }
 
@@ -46,13 +69,13 @@ func TestCompleteAzuredevopsProject(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/complete")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -73,13 +96,13 @@ func TestSecureAzuredevopsProject(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/secure")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -117,12 +140,9 @@ func getTerraformOptions(t testing.TB, terraformDir string) *terraform.Options {
 		Vars: map[string]interface{}{
 			"project_name": fmt.Sprintf("ado-test-%s", uniqueID),
 		},
-		NoColor: true,
-		RetryableTerraformErrors: map[string]string{
-			".*timeout.*":         "Timeout error - retrying",
-			".*TooManyRequests.*": "Too many requests - retrying",
-		},
-		MaxRetries:         3,
-		TimeBetweenRetries: 10 * time.Second,
+		NoColor:                  true,
+		RetryableTerraformErrors: tfretry.ClassifiedRetryableErrors(),
+		MaxRetries:               3,
+		TimeBetweenRetries:       10 * time.Second,
 	}
 }
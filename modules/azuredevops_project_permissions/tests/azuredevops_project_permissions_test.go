@@ -1,11 +1,17 @@
 package test
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adoacl"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adohelper"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/importtest"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/assert"
@@ -21,15 +27,15 @@ func TestBasicAzuredevopsProjectPermissions(t *testing.T) {
 	terraformOptions := getTerraformOptions(t, testFolder)
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		if _, err := os.Stat(filepath.Join(testFolder, ".test-data", "TerraformOptions.json")); err == nil {
-			terraform.Destroy(t, test_structure.LoadTerraformOptions(t, testFolder))
+			destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 			return
 		}
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -38,6 +44,11 @@ func TestBasicAzuredevopsProjectPermissions(t *testing.T) {
 		permissionIDs := terraform.OutputMap(t, terraformOptions, "permission_ids")
 		assert.NotEmpty(t, permissionIDs)
 	})
+
+	// Adopting the deployed module resources into an empty state must plan no changes
+	test_structure.RunTestStage(t, "import", func() {
+		importtest.RequireStateImportRoundTrip(t, test_structure.LoadTerraformOptions(t, testFolder))
+	})
 }
 
 // Test complete azuredevops_project_permissions configuration
@@ -49,15 +60,15 @@ func TestCompleteAzuredevopsProjectPermissions(t *testing.T) {
 	terraformOptions := getTerraformOptions(t, testFolder)
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		if _, err := os.Stat(filepath.Join(testFolder, ".test-data", "TerraformOptions.json")); err == nil {
-			terraform.Destroy(t, test_structure.LoadTerraformOptions(t, testFolder))
+			destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 			return
 		}
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -65,6 +76,18 @@ func TestCompleteAzuredevopsProjectPermissions(t *testing.T) {
 
 		permissionIDs := terraform.OutputMap(t, terraformOptions, "permission_ids")
 		assert.NotEmpty(t, permissionIDs)
+
+		helper := adohelper.New(t)
+		project := helper.GetProject(t, getProjectID(t))
+		require.NotNil(t, project.Name)
+		token := adoacl.ProjectPermissionToken(getProjectID(t))
+
+		readers := adoacl.RequireIdentityDescriptor(t, helper.Connection(), fmt.Sprintf("[%s]\\Readers", *project.Name))
+		adoacl.RequireEffectivePermission(t, helper.Connection(), adoacl.SecurityNamespaceProject, token, readers, "GENERIC_READ", adoacl.PermissionAllow)
+		adoacl.RequirePermissionNotAllowed(t, helper.Connection(), adoacl.SecurityNamespaceProject, token, readers, "GENERIC_WRITE")
+
+		collectionAdmins := adoacl.RequireIdentityDescriptor(t, helper.Connection(), terraform.Output(t, terraformOptions, "collection_admins_descriptor"))
+		adoacl.RequireEffectivePermission(t, helper.Connection(), adoacl.SecurityNamespaceProject, token, collectionAdmins, "GENERIC_WRITE", adoacl.PermissionAllow)
 	})
 }
 
@@ -77,15 +100,15 @@ func TestSecureAzuredevopsProjectPermissions(t *testing.T) {
 	terraformOptions := getTerraformOptions(t, testFolder)
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		if _, err := os.Stat(filepath.Join(testFolder, ".test-data", "TerraformOptions.json")); err == nil {
-			terraform.Destroy(t, test_structure.LoadTerraformOptions(t, testFolder))
+			destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 			return
 		}
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -124,14 +147,9 @@ func getTerraformOptions(t testing.TB, terraformDir string) *terraform.Options {
 		Vars: map[string]interface{}{
 			"project_id": getProjectID(t),
 		},
-		NoColor: true,
-		RetryableTerraformErrors: map[string]string{
-			".*timeout.*":         "Timeout error - retrying",
-			".*TooManyRequests.*": "Too many requests - retrying",
-			".*waiting for permission update.*connection reset by peer.*": "Azure DevOps ACL readback connection reset - retrying",
-			".*Read.*connection reset by peer.*":                          "Azure DevOps connection reset - retrying",
-		},
-		MaxRetries:         3,
-		TimeBetweenRetries: 10 * time.Second,
+		NoColor:                  true,
+		RetryableTerraformErrors: tfretry.ClassifiedRetryableErrors(),
+		MaxRetries:               3,
+		TimeBetweenRetries:       10 * time.Second,
 	}
 }
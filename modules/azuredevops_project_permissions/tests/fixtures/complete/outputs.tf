@@ -1,3 +1,7 @@
 output "permission_ids" {
   value = module.azuredevops_project_permissions.permission_ids
 }
+
+output "collection_admins_descriptor" {
+  value = data.azuredevops_group.project_collection_admins.descriptor
+}
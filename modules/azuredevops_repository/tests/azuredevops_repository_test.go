@@ -5,6 +5,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adoacl"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/importtest"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
@@ -19,13 +23,13 @@ func TestBasicAzuredevopsRepository(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/basic")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -34,6 +38,22 @@ func TestBasicAzuredevopsRepository(t *testing.T) {
 		repositoryID := terraform.Output(t, terraformOptions, "repository_id")
 
 		assert.NotEmpty(t, repositoryID)
+
+		helper := NewAzureDevOpsHelper(t)
+		projectID := getProjectID(t)
+		repository := helper.GetRepository(t, projectID, repositoryID)
+		require.NotNil(t, repository.Name)
+		assert.Equal(t, fmt.Sprintf("%s-basic", terraformOptions.Vars["repo_name_prefix"]), *repository.Name)
+		require.NotNil(t, repository.Project)
+		require.NotNil(t, repository.Project.Id)
+		assert.Equal(t, projectID, repository.Project.Id.String())
+		require.NotNil(t, repository.RemoteUrl)
+		assert.Equal(t, terraform.Output(t, terraformOptions, "repository_url"), *repository.RemoteUrl)
+	})
+
+	// Adopting the deployed module resources into an empty state must plan no changes
+	test_structure.RunTestStage(t, "import", func() {
+		importtest.RequireStateImportRoundTrip(t, test_structure.LoadTerraformOptions(t, testFolder))
 	})
 }
 
@@ -44,13 +64,13 @@ func TestCompleteAzuredevopsRepository(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/complete")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -61,6 +81,94 @@ func TestCompleteAzuredevopsRepository(t *testing.T) {
 
 		assert.NotEmpty(t, repositoryID)
 		assert.NotEmpty(t, branchIDs)
+
+		// Auto reviewers, build validation and author email policies need external identities,
+		// pipelines or commit authors, so the complete fixture does not create them.
+		helper := NewAzureDevOpsHelper(t)
+		RequireRepositoryPolicies(t, helper, getProjectID(t), repositoryID, []ExpectedPolicy{
+			{Type: PolicyTypeMinReviewers, Branch: "develop", Enabled: true, Blocking: true, Settings: map[string]interface{}{
+				"minimumApproverCount": 1,
+				"creatorVoteCounts":    false,
+			}},
+			{Type: PolicyTypeCommentResolution, Branch: "develop", Enabled: true, Blocking: true},
+			{Type: PolicyTypeWorkItemLinking, Branch: "develop", Enabled: true, Blocking: false},
+			{Type: PolicyTypeMergeTypes, Branch: "develop", Enabled: true, Blocking: true, Settings: map[string]interface{}{
+				"allowSquash":        true,
+				"allowRebase":        false,
+				"allowNoFastForward": true,
+				"allowRebaseMerge":   false,
+			}},
+			{Type: PolicyTypeStatusCheck, Branch: "develop", Enabled: true, Blocking: true, Settings: map[string]interface{}{
+				"statusName":         "terratest/verify",
+				"statusGenre":        "terratest",
+				"defaultDisplayName": "Terratest verification",
+			}},
+			{Type: PolicyTypeReservedNames, Enabled: true, Blocking: true},
+			{Type: PolicyTypeCaseEnforcement, Enabled: true, Blocking: true, Settings: map[string]interface{}{
+				"enforceConsistentCase": true,
+			}},
+			{Type: PolicyTypeFilePathPattern, Enabled: true, Blocking: true, Settings: map[string]interface{}{
+				"filenamePatterns": []string{"*.exe", "*.dll"},
+			}},
+			{Type: PolicyTypeMaxFileSize, Enabled: true, Blocking: true, Settings: map[string]interface{}{
+				"maximumGitBlobSizeInBytes": 10 * 1024 * 1024,
+			}},
+			{Type: PolicyTypeMaxPathLength, Enabled: true, Blocking: false, Settings: map[string]interface{}{
+				"maxPathLength": 500,
+			}},
+		})
+
+		contributors := adoacl.RequireIdentityDescriptor(t, helper.Connection(), terraform.Output(t, terraformOptions, "contributors_descriptor"))
+		token := adoacl.GitRepositoryPermissionToken(getProjectID(t), repositoryID)
+		adoacl.RequireEffectivePermission(t, helper.Connection(), adoacl.SecurityNamespaceGitRepositories, token, contributors, "Administer", adoacl.PermissionDeny)
+		adoacl.RequireEffectivePermission(t, helper.Connection(), adoacl.SecurityNamespaceGitRepositories, token, contributors, "ForcePush", adoacl.PermissionDeny)
+		adoacl.RequirePermissionNotAllowed(t, helper.Connection(), adoacl.SecurityNamespaceGitRepositories, token, contributors, "ManagePermissions")
+		// Contribute is not managed by the fixture and stays inherited from the project defaults
+		permission := adoacl.RequireEffectivePermission(t, helper.Connection(), adoacl.SecurityNamespaceGitRepositories, token, contributors, "GenericContribute", adoacl.PermissionAllow)
+		assert.True(t, permission.Inherited)
+	})
+}
+
+// Test adopting a repository created outside Terraform with import blocks
+func TestAzuredevopsRepositoryImport(t *testing.T) {
+	t.Parallel()
+	requireADOEnv(t)
+
+	helper := NewAzureDevOpsHelper(t)
+	projectID := getProjectID(t)
+	readme := "# Repository\n\nCreated outside Terraform."
+
+	repository, err := helper.CreateRepositoryWithFilesE(projectID, fmt.Sprintf("ado-repo-%s-import", random.UniqueId()), "refs/heads/main", map[string]string{"README.md": readme})
+	if repository != nil {
+		defer func() {
+			require.NoError(t, helper.DeleteRepositoryE(projectID, repository.Id.String()))
+		}()
+	}
+	require.NoError(t, err, "Failed to create repository outside Terraform")
+
+	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/import")
+	defer test_structure.RunTestStage(t, "cleanup", func() {
+		destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
+	})
+
+	test_structure.RunTestStage(t, "import", func() {
+		terraformOptions := getTerraformOptions(t, testFolder)
+		terraformOptions.Vars = map[string]interface{}{
+			"project_id":      projectID,
+			"repository_name": *repository.Name,
+			"readme_content":  readme,
+		}
+		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
+
+		targets := RepositoryImportTargets(projectID, repository.Id.String(), "refs/heads/main", []string{"README.md"})
+		importtest.ImportRoundTrip(t, terraformOptions, targets, importtest.ImportWithBlocks)
+	})
+
+	test_structure.RunTestStage(t, "validate", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+
+		assert.Equal(t, repository.Id.String(), terraform.Output(t, terraformOptions, "repository_id"))
+		assert.Contains(t, terraform.OutputMap(t, terraformOptions, "file_ids"), "README.md:default")
 	})
 }
 
@@ -71,13 +179,13 @@ func TestSecureAzuredevopsRepository(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/secure")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -117,12 +225,9 @@ func getTerraformOptions(t testing.TB, terraformDir string) *terraform.Options {
 			"project_id":       getProjectID(t),
 			"repo_name_prefix": fmt.Sprintf("ado-repo-%s", uniqueID),
 		},
-		NoColor: true,
-		RetryableTerraformErrors: map[string]string{
-			".*timeout.*":         "Timeout error - retrying",
-			".*TooManyRequests.*": "Too many requests - retrying",
-		},
-		MaxRetries:         3,
-		TimeBetweenRetries: 10 * time.Second,
+		NoColor:                  true,
+		RetryableTerraformErrors: tfretry.ClassifiedRetryableErrors(),
+		MaxRetries:               3,
+		TimeBetweenRetries:       10 * time.Second,
 	}
 }
@@ -10,6 +10,11 @@ terraform {
 
 provider "azuredevops" {}
 
+data "azuredevops_group" "contributors" {
+  project_id = var.project_id
+  name       = "Contributors"
+}
+
 module "azuredevops_repository" {
   source = "../../../"
 
@@ -26,8 +31,26 @@ module "azuredevops_repository" {
       ref_branch = "refs/heads/main"
       policies = {
         min_reviewers = {
-          reviewer_count = 1
+          reviewer_count     = 1
+          submitter_can_vote = false
+        }
+        comment_resolution = {}
+        work_item_linking = {
+          blocking = false
         }
+        merge_types = {
+          allow_squash                  = true
+          allow_rebase_and_fast_forward = false
+          allow_basic_no_fast_forward   = true
+          allow_rebase_with_merge       = false
+        }
+        status_check = [
+          {
+            name         = "terratest/verify"
+            genre        = "terratest"
+            display_name = "Terratest verification"
+          }
+        ]
       }
     }
   ]
@@ -41,9 +64,34 @@ module "azuredevops_repository" {
     }
   ]
 
+  git_permissions = [
+    {
+      principal = data.azuredevops_group.contributors.id
+      permissions = {
+        Administer        = "Deny"
+        ForcePush         = "Deny"
+        ManagePermissions = "Deny"
+      }
+      replace = false
+    }
+  ]
+
   policies = {
     reserved_names = {
       blocking = true
     }
+    case_enforcement = {
+      enforce_consistent_case = true
+    }
+    file_path_pattern = {
+      filepath_patterns = ["*.exe", "*.dll"]
+    }
+    maximum_file_size = {
+      max_file_size = 10
+    }
+    maximum_path_length = {
+      blocking        = false
+      max_path_length = 500
+    }
   }
 }
@@ -17,3 +17,8 @@ output "policy_ids" {
   description = "Map of policy IDs grouped by policy type."
   value       = module.azuredevops_repository.policy_ids
 }
+
+output "contributors_descriptor" {
+  description = "Descriptor of the project Contributors group restricted by git_permissions."
+  value       = data.azuredevops_group.contributors.descriptor
+}
@@ -0,0 +1,50 @@
+terraform {
+  required_version = ">= 1.12.2"
+  required_providers {
+    azuredevops = {
+      source  = "microsoft/azuredevops"
+      version = "1.12.2"
+    }
+  }
+}
+
+provider "azuredevops" {}
+
+module "azuredevops_repository" {
+  source = "../../../"
+
+  project_id = var.project_id
+  name       = "${var.repo_name_prefix}-enforcement"
+
+  initialization = {
+    init_type = "Clean"
+  }
+
+  branches = [
+    {
+      name       = var.protected_branch
+      ref_branch = "refs/heads/main"
+      policies = {
+        min_reviewers = {
+          reviewer_count = 1
+          blocking       = true
+        }
+      }
+    }
+  ]
+
+  policies = {
+    author_email_pattern = {
+      blocking              = true
+      author_email_patterns = var.author_email_patterns
+    }
+    file_path_pattern = {
+      blocking          = true
+      filepath_patterns = var.blocked_filepath_patterns
+    }
+    maximum_file_size = {
+      blocking      = true
+      max_file_size = var.max_file_size
+    }
+  }
+}
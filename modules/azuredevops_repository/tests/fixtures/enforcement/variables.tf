@@ -0,0 +1,34 @@
+variable "project_id" {
+  description = "Azure DevOps project ID."
+  type        = string
+}
+
+variable "repo_name_prefix" {
+  description = "Prefix for the repository name."
+  type        = string
+  default     = "ado-repo-enforcement-fixture"
+}
+
+variable "protected_branch" {
+  description = "Branch protected by the minimum reviewers policy."
+  type        = string
+  default     = "develop"
+}
+
+variable "author_email_patterns" {
+  description = "Commit author email patterns allowed by the repository."
+  type        = list(string)
+  default     = ["*@example.com"]
+}
+
+variable "blocked_filepath_patterns" {
+  description = "File path patterns rejected by the repository."
+  type        = list(string)
+  default     = ["*.exe"]
+}
+
+variable "max_file_size" {
+  description = "Maximum file size in MB."
+  type        = number
+  default     = 1
+}
@@ -0,0 +1,30 @@
+terraform {
+  required_version = ">= 1.12.2"
+  required_providers {
+    azuredevops = {
+      source  = "microsoft/azuredevops"
+      version = "1.12.2"
+    }
+  }
+}
+
+provider "azuredevops" {}
+
+# The repository and README are created outside Terraform by the test and adopted with import blocks.
+module "azuredevops_repository" {
+  source = "../../../"
+
+  project_id = var.project_id
+  name       = var.repository_name
+
+  initialization = {
+    init_type = "Clean"
+  }
+
+  files = [
+    {
+      file    = "README.md"
+      content = var.readme_content
+    }
+  ]
+}
@@ -0,0 +1,9 @@
+output "repository_id" {
+  description = "Repository ID."
+  value       = module.azuredevops_repository.repository_id
+}
+
+output "file_ids" {
+  description = "Map of file IDs keyed by file path and branch."
+  value       = module.azuredevops_repository.file_ids
+}
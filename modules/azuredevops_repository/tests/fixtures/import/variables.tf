@@ -0,0 +1,14 @@
+variable "project_id" {
+  description = "Azure DevOps project ID."
+  type        = string
+}
+
+variable "repository_name" {
+  description = "Name of the repository created outside Terraform."
+  type        = string
+}
+
+variable "readme_content" {
+  description = "README.md content pushed outside Terraform; must match for the plan after import to be empty."
+  type        = string
+}
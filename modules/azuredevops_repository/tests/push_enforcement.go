@@ -0,0 +1,195 @@
+package test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+// Default author used for pushes that should satisfy the author email policy
+const (
+	compliantAuthorName  = "Terratest"
+	compliantAuthorEmail = "terratest@example.com"
+)
+
+// PushTarget is the git endpoint and credentials used for enforcement pushes
+type PushTarget struct {
+	URL        string
+	Username   string
+	Token      string
+	BaseBranch string
+}
+
+// PushViolation is a commit that a repository policy is expected to reject
+type PushViolation struct {
+	Name          string
+	Branch        string
+	Files         map[string][]byte
+	AuthorEmail   string
+	ExpectedError *regexp.Regexp
+}
+
+// PushTargetFromEnv builds a target from AZDO_GIT_REMOTE_URL, AZDO_GIT_USERNAME and AZDO_GIT_TOKEN.
+// repositoryURL is used when AZDO_GIT_REMOTE_URL is not set; the token falls back to AZDO_PERSONAL_ACCESS_TOKEN.
+func PushTargetFromEnv(repositoryURL string) PushTarget {
+	target := PushTarget{
+		URL:        repositoryURL,
+		Username:   os.Getenv("AZDO_GIT_USERNAME"),
+		Token:      os.Getenv("AZDO_GIT_TOKEN"),
+		BaseBranch: os.Getenv("AZDO_GIT_BASE_BRANCH"),
+	}
+	if override := os.Getenv("AZDO_GIT_REMOTE_URL"); override != "" {
+		target.URL = override
+	}
+	if target.Username == "" {
+		target.Username = "terratest"
+	}
+	if target.Token == "" {
+		target.Token = os.Getenv("AZDO_PERSONAL_ACCESS_TOKEN")
+	}
+	if target.BaseBranch == "" {
+		target.BaseBranch = "main"
+	}
+	return target
+}
+
+// policyRejectionCode matches the error code that starts an Azure DevOps policy rejection, e.g. TF402455:
+const policyRejectionCode = `\b(?:TF|VS)40\d{4}:`
+
+// policyRejection matches a policy rejection line that names the offending file path or author email,
+// so a push rejected by another policy or for another file does not count
+func policyRejection(subject string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)` + policyRejectionCode + `[^\n]*` + regexp.QuoteMeta(subject))
+}
+
+// DefaultPushViolations returns one violation per policy created by the enforcement fixture.
+// ExpectedError matches the Azure DevOps rejection messages; a local stand-in must return the same error codes.
+func DefaultPushViolations(t testing.TB, protectedBranch string, maxFileSizeMB int) []PushViolation {
+	t.Helper()
+
+	// Random content keeps the blob from compressing below the limit
+	oversized := make([]byte, (maxFileSizeMB+1)*1024*1024)
+	_, err := rand.Read(oversized)
+	require.NoError(t, err)
+
+	return []PushViolation{
+		{
+			Name:          "max_file_size",
+			Branch:        "terratest/max-file-size",
+			Files:         map[string][]byte{"large.bin": oversized},
+			ExpectedError: policyRejection("large.bin"),
+		},
+		{
+			Name:          "file_path_pattern",
+			Branch:        "terratest/file-path",
+			Files:         map[string][]byte{"tools/setup.exe": []byte("not a binary")},
+			ExpectedError: policyRejection("tools/setup.exe"),
+		},
+		{
+			Name:          "author_email_pattern",
+			Branch:        "terratest/author-email",
+			Files:         map[string][]byte{"notes/author.txt": []byte("author email check")},
+			AuthorEmail:   "terratest@invalid.test",
+			ExpectedError: policyRejection("terratest@invalid.test"),
+		},
+		{
+			Name:          "protected_branch",
+			Branch:        protectedBranch,
+			Files:         map[string][]byte{"notes/direct.txt": []byte("direct push")},
+			ExpectedError: regexp.MustCompile(`\bTF402455:`),
+		},
+	}
+}
+
+// PushCommitE clones the base branch, commits the files and pushes the commit to the target branch.
+// The returned output holds both the push error and the remote progress messages.
+func PushCommitE(target PushTarget, branch string, files map[string][]byte, authorEmail string) (string, error) {
+	auth := &githttp.BasicAuth{Username: target.Username, Password: target.Token}
+	repository, err := git.Clone(memory.NewStorage(), memfs.New(), &git.CloneOptions{
+		URL:           target.URL,
+		Auth:          auth,
+		ReferenceName: plumbing.NewBranchReferenceName(target.BaseBranch),
+		SingleBranch:  true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to clone %s: %w", target.URL, err)
+	}
+
+	worktree, err := repository.Worktree()
+	if err != nil {
+		return "", err
+	}
+	for path, content := range files {
+		file, err := worktree.Filesystem.Create(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to create %s: %w", path, err)
+		}
+		_, writeErr := file.Write(content)
+		closeErr := file.Close()
+		if err := errors.Join(writeErr, closeErr); err != nil {
+			return "", fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		if _, err := worktree.Add(path); err != nil {
+			return "", fmt.Errorf("failed to stage %s: %w", path, err)
+		}
+	}
+
+	if authorEmail == "" {
+		authorEmail = compliantAuthorEmail
+	}
+	signature := &object.Signature{Name: compliantAuthorName, Email: authorEmail, When: time.Now()}
+	if _, err := worktree.Commit(fmt.Sprintf("Terratest push to %s", branch), &git.CommitOptions{
+		Author:    signature,
+		Committer: signature,
+	}); err != nil {
+		return "", fmt.Errorf("failed to commit: %w", err)
+	}
+
+	var progress bytes.Buffer
+	err = repository.Push(&git.PushOptions{
+		RemoteName: "origin",
+		Auth:       auth,
+		Progress:   &progress,
+		RefSpecs: []config.RefSpec{
+			config.RefSpec(fmt.Sprintf("%s:%s", plumbing.NewBranchReferenceName(target.BaseBranch), plumbing.NewBranchReferenceName(branch))),
+		},
+	})
+	output := strings.TrimSpace(progress.String())
+	if err != nil {
+		output = strings.TrimSpace(err.Error() + "\n" + output)
+	}
+	return output, err
+}
+
+// RequirePushRejected pushes the violation and asserts it is rejected with the matching policy error
+func RequirePushRejected(t testing.TB, target PushTarget, violation PushViolation) {
+	t.Helper()
+
+	output, err := PushCommitE(target, violation.Branch, violation.Files, violation.AuthorEmail)
+	require.Error(t, err, "Push violating %s was accepted", violation.Name)
+	require.NotContains(t, err.Error(), "failed to clone", "Could not prepare push for %s", violation.Name)
+	require.Regexp(t, violation.ExpectedError, output, "Push violating %s was rejected for another reason", violation.Name)
+}
+
+// RequirePushAccepted pushes a compliant commit to prove rejections come from the policies
+func RequirePushAccepted(t testing.TB, target PushTarget, branch string) {
+	t.Helper()
+
+	output, err := PushCommitE(target, branch, map[string][]byte{"notes/compliant.txt": []byte("compliant push")}, "")
+	require.NoError(t, err, "Compliant push to %s was rejected: %s", branch, output)
+}
@@ -0,0 +1,188 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test that repository policies reject real pushes, not only that they are configured.
+// Runs against the enforcement fixture when AZDO_RUN_PUSH_ENFORCEMENT=true, or directly
+// against a local stand-in git endpoint when AZDO_GIT_REMOTE_URL is set.
+func TestAzuredevopsRepositoryPushEnforcement(t *testing.T) {
+	if os.Getenv("AZDO_GIT_REMOTE_URL") != "" {
+		protectedBranch := os.Getenv("AZDO_GIT_PROTECTED_BRANCH")
+		if protectedBranch == "" {
+			protectedBranch = "develop"
+		}
+		runPushEnforcement(t, PushTargetFromEnv(""), protectedBranch)
+		return
+	}
+	if os.Getenv("AZDO_RUN_PUSH_ENFORCEMENT") != "true" {
+		t.Skip("Skipping push enforcement test; set AZDO_RUN_PUSH_ENFORCEMENT=true or AZDO_GIT_REMOTE_URL")
+	}
+
+	t.Parallel()
+	requireADOEnv(t)
+
+	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/enforcement")
+	defer test_structure.RunTestStage(t, "cleanup", func() {
+		destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
+	})
+
+	test_structure.RunTestStage(t, "deploy", func() {
+		terraformOptions := getTerraformOptions(t, testFolder)
+		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
+	})
+
+	test_structure.RunTestStage(t, "enforce", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+
+		repositoryURL := terraform.Output(t, terraformOptions, "repository_url")
+		runPushEnforcement(t, PushTargetFromEnv(repositoryURL), "develop")
+	})
+}
+
+func runPushEnforcement(t *testing.T, target PushTarget, protectedBranch string) {
+	RequirePushAccepted(t, target, "terratest/compliant-"+random.UniqueId())
+
+	for _, violation := range DefaultPushViolations(t, protectedBranch, 1) {
+		violation := violation
+		t.Run(violation.Name, func(t *testing.T) {
+			RequirePushRejected(t, target, violation)
+		})
+	}
+}
+
+func TestPushCommitLocalRemote(t *testing.T) {
+	target, remote := newLocalPushTarget(t, "")
+	_, err := PushCommitE(target, "terratest/compliant", map[string][]byte{"notes/compliant.txt": []byte("ok")}, "")
+	require.NoError(t, err)
+
+	ref, err := remote.Reference(plumbing.NewBranchReferenceName("terratest/compliant"), true)
+	require.NoError(t, err)
+	commit, err := remote.CommitObject(ref.Hash())
+	require.NoError(t, err)
+	assert.Equal(t, compliantAuthorEmail, commit.Author.Email)
+	_, err = commit.File("notes/compliant.txt")
+	assert.NoError(t, err)
+}
+
+// Test that RequirePushRejected only accepts a rejection carrying the policy error of the violation
+func TestPushRejectedLocalRemote(t *testing.T) {
+	violations := map[string]PushViolation{}
+	for _, violation := range DefaultPushViolations(t, "develop", 1) {
+		violations[violation.Name] = violation
+	}
+
+	testCases := []struct {
+		name      string
+		violation string
+		rejection string
+		passes    bool
+	}{
+		{"file_path_pattern", "file_path_pattern", "TF402484: The push was rejected because tools/setup.exe matches a blocked file path.", true},
+		{"author_email_pattern", "author_email_pattern", "VS403422: Commit author email terratest@invalid.test does not match the allowed patterns.", true},
+		{"protected_branch", "protected_branch", "TF402455: Pushes to this branch are not permitted; you must use a pull request to update this branch.", true},
+		{"other_policy", "file_path_pattern", "TF402455: Pushes to this branch are not permitted; you must use a pull request to update this branch.", false},
+		{"other_file", "file_path_pattern", "TF402484: The push was rejected because tools/other.exe matches a blocked file path.", false},
+		{"no_error_code", "author_email_pattern", "error: commit author email terratest@invalid.test is blocked", false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			target, _ := newLocalPushTarget(t, fmt.Sprintf("#!/bin/sh\necho %q >&2\nexit 1\n", tc.rejection))
+			recorder := &recordingT{TB: t}
+			recorder.run(func() { RequirePushRejected(recorder, target, violations[tc.violation]) })
+			assert.Equal(t, !tc.passes, recorder.failed, "RequirePushRejected failures: %v", recorder.errors)
+			if !tc.passes {
+				assert.Contains(t, strings.Join(recorder.errors, "\n"), "was rejected for another reason")
+			}
+		})
+	}
+}
+
+// newLocalPushTarget creates a bare repository with a seeded main branch and an optional pre-receive hook script
+func newLocalPushTarget(t *testing.T, preReceive string) (PushTarget, *git.Repository) {
+	t.Helper()
+
+	remoteDir := t.TempDir()
+	remote, err := git.PlainInit(remoteDir, true)
+	require.NoError(t, err)
+
+	seedDir := t.TempDir()
+	seed, err := git.PlainInit(seedDir, false)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(seedDir, "README.md"), []byte("seed"), 0o600))
+	worktree, err := seed.Worktree()
+	require.NoError(t, err)
+	_, err = worktree.Add("README.md")
+	require.NoError(t, err)
+	_, err = worktree.Commit("Seed", &git.CommitOptions{Author: &object.Signature{Name: "Seed", Email: compliantAuthorEmail, When: time.Now()}})
+	require.NoError(t, err)
+	_, err = seed.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{remoteDir}})
+	require.NoError(t, err)
+	head, err := seed.Head()
+	require.NoError(t, err)
+	require.NoError(t, seed.Push(&git.PushOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{config.RefSpec(fmt.Sprintf("%s:refs/heads/main", head.Name()))},
+	}))
+
+	// The hook is installed after seeding so it only sees the pushes of the test
+	if preReceive != "" {
+		hooksDir := filepath.Join(remoteDir, "hooks")
+		require.NoError(t, os.MkdirAll(hooksDir, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(hooksDir, "pre-receive"), []byte(preReceive), 0o755))
+	}
+	return PushTarget{URL: remoteDir, BaseBranch: "main"}, remote
+}
+
+// recordingT records assertion failures instead of failing the test, so a failing check can be asserted on
+type recordingT struct {
+	testing.TB
+	failed bool
+	errors []string
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.failed = true
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingT) FailNow() {
+	r.failed = true
+	runtime.Goexit()
+}
+
+// run calls check on its own goroutine so FailNow stops the check and not the test
+func (r *recordingT) run(check func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		check()
+	}()
+	<-done
+}
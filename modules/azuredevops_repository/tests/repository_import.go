@@ -0,0 +1,115 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adohelper"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/importtest"
+	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/core"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+)
+
+const (
+	repositoryModuleName = "azuredevops_repository"
+	emptyObjectID        = "0000000000000000000000000000000000000000"
+)
+
+// CreateRepositoryWithFilesE creates a repository outside Terraform and pushes the files as the first commit on branch
+func (h *AzureDevOpsHelper) CreateRepositoryWithFilesE(projectID, name, branch string, files map[string]string) (*git.GitRepository, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), adohelper.RequestTimeout)
+	defer cancel()
+
+	client, err := h.Git(ctx)
+	if err != nil {
+		return nil, err
+	}
+	projectUUID, err := uuid.Parse(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("project ID %q: %w", projectID, err)
+	}
+	repository, err := client.CreateRepository(ctx, git.CreateRepositoryArgs{
+		Project: &projectID,
+		GitRepositoryToCreate: &git.GitRepositoryCreateOptions{
+			Name:    &name,
+			Project: &core.TeamProjectReference{Id: &projectUUID},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	changes := make([]interface{}, 0, len(paths))
+	for _, path := range paths {
+		changes = append(changes, git.GitChange{
+			ChangeType: &git.VersionControlChangeTypeValues.Add,
+			Item:       map[string]string{"path": "/" + strings.TrimPrefix(path, "/")},
+			NewContent: &git.ItemContent{Content: stringPointer(files[path]), ContentType: &git.ItemContentTypeValues.RawText},
+		})
+	}
+
+	repositoryID := repository.Id.String()
+	comment := "Initial commit created outside Terraform"
+	_, err = client.CreatePush(ctx, git.CreatePushArgs{
+		Project:      &projectID,
+		RepositoryId: &repositoryID,
+		Push: &git.GitPush{
+			RefUpdates: &[]git.GitRefUpdate{{Name: &branch, OldObjectId: stringPointer(emptyObjectID)}},
+			Commits:    &[]git.GitCommitRef{{Comment: &comment, Changes: &changes}},
+		},
+	})
+	if err != nil {
+		return repository, err
+	}
+	// The default branch is only set once the first branch exists
+	return client.GetRepository(ctx, git.GetRepositoryArgs{Project: &projectID, RepositoryId: &repositoryID})
+}
+
+// DeleteRepositoryE deletes a repository; a repository that is already gone is not an error
+func (h *AzureDevOpsHelper) DeleteRepositoryE(projectID, repositoryID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), adohelper.RequestTimeout)
+	defer cancel()
+
+	client, err := h.Git(ctx)
+	if err != nil {
+		return err
+	}
+	id, err := uuid.Parse(repositoryID)
+	if err != nil {
+		return err
+	}
+	if err := client.DeleteRepository(ctx, git.DeleteRepositoryArgs{Project: &projectID, RepositoryId: &id}); err != nil && !destroyverify.IsADONotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// RepositoryImportTargets maps an existing repository and its files to the module addresses.
+// File keys follow the module's files_by_key ("<file>:default" when the file uses the default branch).
+func RepositoryImportTargets(projectID, repositoryID, defaultBranch string, files []string) []importtest.ImportTarget {
+	targets := []importtest.ImportTarget{{
+		Address: importtest.ModuleResourceAddress(repositoryModuleName, "azuredevops_git_repository", "git_repository"),
+		ID:      fmt.Sprintf("%s/%s", projectID, repositoryID),
+	}}
+	fileAddress := importtest.ModuleResourceAddress(repositoryModuleName, "azuredevops_git_repository_file", "git_repository_file")
+	for _, file := range files {
+		targets = append(targets, importtest.ImportTarget{
+			Address: importtest.InstanceAddress(fileAddress, file+":default"),
+			ID:      fmt.Sprintf("%s/%s:%s", repositoryID, file, defaultBranch),
+		})
+	}
+	return targets
+}
+
+func stringPointer(value string) *string {
+	return &value
+}
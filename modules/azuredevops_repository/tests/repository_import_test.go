@@ -0,0 +1,18 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/importtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryImportTargets(t *testing.T) {
+	targets := RepositoryImportTargets("project-id", "repository-id", "refs/heads/main", []string{"README.md", "docs/guide.md"})
+
+	assert.Equal(t, []importtest.ImportTarget{
+		{Address: "module.azuredevops_repository.azuredevops_git_repository.git_repository", ID: "project-id/repository-id"},
+		{Address: `module.azuredevops_repository.azuredevops_git_repository_file.git_repository_file["README.md:default"]`, ID: "repository-id/README.md:refs/heads/main"},
+		{Address: `module.azuredevops_repository.azuredevops_git_repository_file.git_repository_file["docs/guide.md:default"]`, ID: "repository-id/docs/guide.md:refs/heads/main"},
+	}, targets)
+}
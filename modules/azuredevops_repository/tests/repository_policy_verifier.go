@@ -0,0 +1,380 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adohelper"
+	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/policy"
+	"github.com/stretchr/testify/require"
+)
+
+// Policy type IDs used by the azuredevops_branch_policy_* and azuredevops_repository_policy_* resources
+const (
+	PolicyTypeMinReviewers       = "fa4e907d-c16b-4a4c-9dfa-4906e5d171dd"
+	PolicyTypeBuildValidation    = "0609b952-1397-4640-95ec-e00a01b2c241"
+	PolicyTypeStatusCheck        = "cbdc66da-9728-4af8-aada-9a5a32e4a226"
+	PolicyTypeMergeTypes         = "fa4e907d-c16b-4a4c-9dfa-4916e5d171ab"
+	PolicyTypeWorkItemLinking    = "40e92b44-2fe1-4dd6-b3d8-74a9c21d0c6e"
+	PolicyTypeCommentResolution  = "c6a1889d-b943-4856-b76f-9e46bb6b0df2"
+	PolicyTypeAutoReviewers      = "fd2167ab-b0be-447a-8ec8-39368250530e"
+	PolicyTypeAuthorEmailPattern = "77ed4bd3-b063-4689-934a-175e4d0a78d7"
+	PolicyTypeCaseEnforcement    = "7ed39669-655c-494e-b4a0-a08b4da0fcce"
+	PolicyTypeFilePathPattern    = "51c78909-e838-41a2-9496-c647091e3c61"
+	PolicyTypeMaxFileSize        = "2e26e725-8201-4edd-8bf5-978563c34a80"
+	PolicyTypeMaxPathLength      = "001a79cf-fda1-4c4e-9e7c-bac40ee5ead8"
+	PolicyTypeReservedNames      = "db2b9b4c-180d-4529-9701-01541d19f36b"
+)
+
+var policyTypeNames = map[string]string{
+	PolicyTypeMinReviewers:       "min_reviewers",
+	PolicyTypeBuildValidation:    "build_validation",
+	PolicyTypeStatusCheck:        "status_check",
+	PolicyTypeMergeTypes:         "merge_types",
+	PolicyTypeWorkItemLinking:    "work_item_linking",
+	PolicyTypeCommentResolution:  "comment_resolution",
+	PolicyTypeAutoReviewers:      "auto_reviewers",
+	PolicyTypeAuthorEmailPattern: "author_email_pattern",
+	PolicyTypeCaseEnforcement:    "case_enforcement",
+	PolicyTypeFilePathPattern:    "file_path_pattern",
+	PolicyTypeMaxFileSize:        "max_file_size",
+	PolicyTypeMaxPathLength:      "max_path_length",
+	PolicyTypeReservedNames:      "reserved_names",
+}
+
+// ExpectedPolicy describes a policy the fixture should have created.
+// Branch is empty for repository policies; Settings only lists the keys that must match.
+type ExpectedPolicy struct {
+	Type     string
+	Branch   string
+	Enabled  bool
+	Blocking bool
+	Settings map[string]interface{}
+}
+
+// PolicyFinding is a single difference between the fixture and the applied policies
+type PolicyFinding struct {
+	Kind       string
+	PolicyType string
+	Scope      string
+	PolicyID   int
+	Detail     string
+}
+
+// String formats the finding for test failure output
+func (f PolicyFinding) String() string {
+	message := fmt.Sprintf("%s %s policy at %s", f.Kind, policyTypeName(f.PolicyType), f.Scope)
+	if f.PolicyID != 0 {
+		message += fmt.Sprintf(" (id %d)", f.PolicyID)
+	}
+	if f.Detail != "" {
+		message += ": " + f.Detail
+	}
+	return message
+}
+
+// PolicyReport lists missing, extra, mis-scoped and mismatched policies for a repository
+type PolicyReport struct {
+	Findings []PolicyFinding
+}
+
+// Err returns nil when every expected policy matched and nothing extra was found
+func (r PolicyReport) Err() error {
+	if len(r.Findings) == 0 {
+		return nil
+	}
+	lines := make([]string, 0, len(r.Findings))
+	for _, finding := range r.Findings {
+		lines = append(lines, finding.String())
+	}
+	return fmt.Errorf("repository policies do not match the fixture:\n  %s", strings.Join(lines, "\n  "))
+}
+
+// Policy finding kinds
+const (
+	PolicyFindingMissing    = "missing"
+	PolicyFindingExtra      = "extra"
+	PolicyFindingMisScoped  = "mis-scoped"
+	PolicyFindingMismatched = "mismatched"
+)
+
+type policyScope struct {
+	RepositoryID string `json:"repositoryId"`
+	RefName      string `json:"refName"`
+	MatchKind    string `json:"matchKind"`
+}
+
+type appliedPolicy struct {
+	id       int
+	typeID   string
+	enabled  bool
+	blocking bool
+	scope    policyScope
+	settings map[string]interface{}
+}
+
+// GetRepositoryPolicyConfigurationsE retrieves every policy configuration that applies to the repository
+func (h *AzureDevOpsHelper) GetRepositoryPolicyConfigurationsE(projectID, repositoryID string) ([]policy.PolicyConfiguration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), adohelper.RequestTimeout)
+	defer cancel()
+
+	repositoryUUID, err := uuid.Parse(repositoryID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid repository ID %q: %w", repositoryID, err)
+	}
+	client, err := h.Git(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var configurations []policy.PolicyConfiguration
+	var continuationToken *string
+	for {
+		response, err := client.GetPolicyConfigurations(ctx, git.GetPolicyConfigurationsArgs{
+			Project:           &projectID,
+			RepositoryId:      &repositoryUUID,
+			ContinuationToken: continuationToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if response.PolicyConfigurations != nil {
+			configurations = append(configurations, *response.PolicyConfigurations...)
+		}
+		if response.ContinuationToken == nil || *response.ContinuationToken == "" {
+			return configurations, nil
+		}
+		continuationToken = response.ContinuationToken
+	}
+}
+
+// RequireRepositoryPolicies fetches the repository policies and fails the test on any finding
+func RequireRepositoryPolicies(t testing.TB, helper *AzureDevOpsHelper, projectID, repositoryID string, expected []ExpectedPolicy) {
+	t.Helper()
+
+	configurations, err := helper.GetRepositoryPolicyConfigurationsE(projectID, repositoryID)
+	require.NoError(t, err, "Failed to get policy configurations for repository %s", repositoryID)
+
+	report, err := CompareRepositoryPolicies(repositoryID, expected, configurations)
+	require.NoError(t, err, "Failed to read policy configurations for repository %s", repositoryID)
+	require.NoError(t, report.Err())
+}
+
+// CompareRepositoryPolicies matches the applied policies scoped to the repository against the expected ones.
+// Policies scoped to other repositories or to the whole project are ignored.
+func CompareRepositoryPolicies(repositoryID string, expected []ExpectedPolicy, configurations []policy.PolicyConfiguration) (PolicyReport, error) {
+	applied, err := repositoryPolicies(repositoryID, configurations)
+	if err != nil {
+		return PolicyReport{}, err
+	}
+
+	var report PolicyReport
+	used := make([]bool, len(applied))
+	pending := make([]ExpectedPolicy, 0, len(expected))
+
+	// Exact scope matches first, preferring a policy whose settings also match
+	for _, want := range expected {
+		index := -1
+		var diffs []string
+		for i, candidate := range applied {
+			if used[i] || !strings.EqualFold(candidate.typeID, want.Type) || !scopeMatches(candidate.scope, repositoryID, want.Branch) {
+				continue
+			}
+			policyDiffs := comparePolicy(want, candidate)
+			if index == -1 || (len(policyDiffs) == 0 && len(diffs) > 0) {
+				index, diffs = i, policyDiffs
+			}
+			if len(diffs) == 0 {
+				break
+			}
+		}
+		if index == -1 {
+			pending = append(pending, want)
+			continue
+		}
+		used[index] = true
+		if len(diffs) > 0 {
+			report.Findings = append(report.Findings, PolicyFinding{
+				Kind:       PolicyFindingMismatched,
+				PolicyType: want.Type,
+				Scope:      expectedScope(want.Branch),
+				PolicyID:   applied[index].id,
+				Detail:     strings.Join(diffs, "; "),
+			})
+		}
+	}
+
+	// Remaining policies of the expected type on this repository are on the wrong scope
+	for _, want := range pending {
+		index := -1
+		for i, candidate := range applied {
+			if !used[i] && strings.EqualFold(candidate.typeID, want.Type) {
+				index = i
+				break
+			}
+		}
+		if index == -1 {
+			report.Findings = append(report.Findings, PolicyFinding{
+				Kind:       PolicyFindingMissing,
+				PolicyType: want.Type,
+				Scope:      expectedScope(want.Branch),
+			})
+			continue
+		}
+		used[index] = true
+		report.Findings = append(report.Findings, PolicyFinding{
+			Kind:       PolicyFindingMisScoped,
+			PolicyType: want.Type,
+			Scope:      expectedScope(want.Branch),
+			PolicyID:   applied[index].id,
+			Detail:     fmt.Sprintf("applied at %s", appliedScope(applied[index].scope)),
+		})
+	}
+
+	for i, candidate := range applied {
+		if used[i] {
+			continue
+		}
+		report.Findings = append(report.Findings, PolicyFinding{
+			Kind:       PolicyFindingExtra,
+			PolicyType: candidate.typeID,
+			Scope:      appliedScope(candidate.scope),
+			PolicyID:   candidate.id,
+		})
+	}
+	return report, nil
+}
+
+// repositoryPolicies decodes non-deleted policies whose scope targets the repository
+func repositoryPolicies(repositoryID string, configurations []policy.PolicyConfiguration) ([]appliedPolicy, error) {
+	var applied []appliedPolicy
+	for _, configuration := range configurations {
+		if configuration.IsDeleted != nil && *configuration.IsDeleted {
+			continue
+		}
+		if configuration.Type == nil || configuration.Type.Id == nil {
+			continue
+		}
+
+		settings, err := toJSONMap(configuration.Settings)
+		if err != nil {
+			return nil, fmt.Errorf("policy %d settings: %w", derefInt(configuration.Id), err)
+		}
+
+		var scopes []policyScope
+		if raw, ok := settings["scope"]; ok {
+			body, err := json.Marshal(raw)
+			if err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal(body, &scopes); err != nil {
+				return nil, fmt.Errorf("policy %d scope: %w", derefInt(configuration.Id), err)
+			}
+		}
+
+		for _, scope := range scopes {
+			if !strings.EqualFold(scope.RepositoryID, repositoryID) {
+				continue
+			}
+			applied = append(applied, appliedPolicy{
+				id:       derefInt(configuration.Id),
+				typeID:   configuration.Type.Id.String(),
+				enabled:  configuration.IsEnabled != nil && *configuration.IsEnabled,
+				blocking: configuration.IsBlocking != nil && *configuration.IsBlocking,
+				scope:    scope,
+				settings: settings,
+			})
+			break
+		}
+	}
+
+	sort.SliceStable(applied, func(i, j int) bool { return applied[i].id < applied[j].id })
+	return applied, nil
+}
+
+func comparePolicy(want ExpectedPolicy, applied appliedPolicy) []string {
+	var diffs []string
+	if applied.enabled != want.Enabled {
+		diffs = append(diffs, fmt.Sprintf("enabled is %t, expected %t", applied.enabled, want.Enabled))
+	}
+	if applied.blocking != want.Blocking {
+		diffs = append(diffs, fmt.Sprintf("blocking is %t, expected %t", applied.blocking, want.Blocking))
+	}
+
+	keys := make([]string, 0, len(want.Settings))
+	for key := range want.Settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		expected, _ := json.Marshal(want.Settings[key])
+		actual, _ := json.Marshal(applied.settings[key])
+		if string(expected) != string(actual) {
+			diffs = append(diffs, fmt.Sprintf("%s is %s, expected %s", key, actual, expected))
+		}
+	}
+	return diffs
+}
+
+// scopeMatches follows the module: branch policies use an Exact refs/heads scope, repository policies have no ref
+func scopeMatches(scope policyScope, repositoryID, branch string) bool {
+	if !strings.EqualFold(scope.RepositoryID, repositoryID) {
+		return false
+	}
+	if branch == "" {
+		return scope.RefName == ""
+	}
+	return scope.RefName == "refs/heads/"+branch && strings.EqualFold(scope.MatchKind, "Exact")
+}
+
+func expectedScope(branch string) string {
+	if branch == "" {
+		return "repository"
+	}
+	return fmt.Sprintf("refs/heads/%s (Exact)", branch)
+}
+
+func appliedScope(scope policyScope) string {
+	if scope.RefName == "" {
+		return "repository"
+	}
+	return fmt.Sprintf("%s (%s)", scope.RefName, scope.MatchKind)
+}
+
+func policyTypeName(typeID string) string {
+	if name, ok := policyTypeNames[strings.ToLower(typeID)]; ok {
+		return name
+	}
+	return typeID
+}
+
+func toJSONMap(value interface{}) (map[string]interface{}, error) {
+	if value == nil {
+		return map[string]interface{}{}, nil
+	}
+	if settings, ok := value.(map[string]interface{}); ok {
+		return settings, nil
+	}
+	body, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	settings := map[string]interface{}{}
+	if err := json.Unmarshal(body, &settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func derefInt(value *int) int {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
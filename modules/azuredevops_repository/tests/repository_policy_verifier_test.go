@@ -0,0 +1,113 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPolicyRepositoryID = "5febef5a-833d-4e14-b9c0-14cb638f91e6"
+
+func testPolicyConfiguration(id int, typeID string, blocking bool, scope map[string]interface{}, settings map[string]interface{}) policy.PolicyConfiguration {
+	policyType := uuid.MustParse(typeID)
+	enabled := true
+	if settings == nil {
+		settings = map[string]interface{}{}
+	}
+	settings["scope"] = []interface{}{scope}
+	return policy.PolicyConfiguration{
+		Id:         &id,
+		Type:       &policy.PolicyTypeRef{Id: &policyType},
+		IsEnabled:  &enabled,
+		IsBlocking: &blocking,
+		Settings:   settings,
+	}
+}
+
+func branchScope(repositoryID, branch string) map[string]interface{} {
+	return map[string]interface{}{"repositoryId": repositoryID, "refName": "refs/heads/" + branch, "matchKind": "Exact"}
+}
+
+func repositoryScope(repositoryID string) map[string]interface{} {
+	return map[string]interface{}{"repositoryId": repositoryID, "refName": nil, "matchKind": "Exact"}
+}
+
+func TestCompareRepositoryPoliciesMatches(t *testing.T) {
+	configurations := []policy.PolicyConfiguration{
+		testPolicyConfiguration(1, PolicyTypeMinReviewers, true, branchScope(testPolicyRepositoryID, "develop"), map[string]interface{}{
+			"minimumApproverCount": float64(2),
+			"creatorVoteCounts":    false,
+		}),
+		testPolicyConfiguration(2, PolicyTypeMaxPathLength, false, repositoryScope(testPolicyRepositoryID), map[string]interface{}{
+			"maxPathLength": float64(500),
+		}),
+		// Policies on other repositories and project-wide policies are ignored
+		testPolicyConfiguration(3, PolicyTypeReservedNames, true, repositoryScope("0cb8e5b9-5d1c-4c4f-8b6e-6a0f1c1f0c43"), nil),
+		testPolicyConfiguration(4, PolicyTypeReservedNames, true, map[string]interface{}{"repositoryId": nil}, nil),
+	}
+
+	report, err := CompareRepositoryPolicies(testPolicyRepositoryID, []ExpectedPolicy{
+		{Type: PolicyTypeMinReviewers, Branch: "develop", Enabled: true, Blocking: true, Settings: map[string]interface{}{"minimumApproverCount": 2}},
+		{Type: PolicyTypeMaxPathLength, Enabled: true, Blocking: false, Settings: map[string]interface{}{"maxPathLength": 500}},
+	}, configurations)
+	require.NoError(t, err)
+	assert.Empty(t, report.Findings)
+	assert.NoError(t, report.Err())
+}
+
+func TestCompareRepositoryPoliciesFindings(t *testing.T) {
+	configurations := []policy.PolicyConfiguration{
+		testPolicyConfiguration(10, PolicyTypeMinReviewers, true, branchScope(testPolicyRepositoryID, "develop"), map[string]interface{}{
+			"minimumApproverCount": float64(1),
+		}),
+		testPolicyConfiguration(11, PolicyTypeCommentResolution, true, branchScope(testPolicyRepositoryID, "main"), nil),
+		testPolicyConfiguration(12, PolicyTypeCaseEnforcement, true, repositoryScope(testPolicyRepositoryID), map[string]interface{}{
+			"enforceConsistentCase": true,
+		}),
+	}
+
+	report, err := CompareRepositoryPolicies(testPolicyRepositoryID, []ExpectedPolicy{
+		{Type: PolicyTypeMinReviewers, Branch: "develop", Enabled: true, Blocking: true, Settings: map[string]interface{}{"minimumApproverCount": 2}},
+		{Type: PolicyTypeCommentResolution, Branch: "develop", Enabled: true, Blocking: true},
+		{Type: PolicyTypeReservedNames, Enabled: true, Blocking: true},
+	}, configurations)
+	require.NoError(t, err)
+
+	findings := map[string]PolicyFinding{}
+	for _, finding := range report.Findings {
+		findings[finding.Kind+":"+policyTypeName(finding.PolicyType)] = finding
+	}
+	require.Len(t, report.Findings, 4, "Unexpected findings: %v", report.Findings)
+
+	mismatched := findings["mismatched:min_reviewers"]
+	assert.Equal(t, 10, mismatched.PolicyID)
+	assert.Contains(t, mismatched.Detail, "minimumApproverCount is 1, expected 2")
+
+	misScoped := findings["mis-scoped:comment_resolution"]
+	assert.Equal(t, 11, misScoped.PolicyID)
+	assert.Contains(t, misScoped.Detail, "refs/heads/main")
+
+	assert.Contains(t, findings, "missing:reserved_names")
+
+	extra := findings["extra:case_enforcement"]
+	assert.Equal(t, 12, extra.PolicyID)
+	assert.Equal(t, "repository", extra.Scope)
+
+	assert.Error(t, report.Err())
+}
+
+func TestCompareRepositoryPoliciesSkipsDeleted(t *testing.T) {
+	deleted := testPolicyConfiguration(20, PolicyTypeWorkItemLinking, true, branchScope(testPolicyRepositoryID, "develop"), nil)
+	isDeleted := true
+	deleted.IsDeleted = &isDeleted
+
+	report, err := CompareRepositoryPolicies(testPolicyRepositoryID, []ExpectedPolicy{
+		{Type: PolicyTypeWorkItemLinking, Branch: "develop", Enabled: true, Blocking: true},
+	}, []policy.PolicyConfiguration{deleted})
+	require.NoError(t, err)
+	require.Len(t, report.Findings, 1)
+	assert.Equal(t, PolicyFindingMissing, report.Findings[0].Kind)
+}
@@ -3,6 +3,9 @@ package test
 import (
 	"testing"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/importtest"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/assert"
@@ -16,13 +19,13 @@ func TestBasicAzuredevopsSecurityroleAssignment(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/basic")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder, getScopeIDBasic(t), getResourceIDBasic(t), getIdentityIDBasic(t)))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder, getScopeIDBasic(t), getResourceIDBasic(t), getIdentityIDBasic(t)), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder, getScopeIDBasic(t), getResourceIDBasic(t), getIdentityIDBasic(t))
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -31,6 +34,11 @@ func TestBasicAzuredevopsSecurityroleAssignment(t *testing.T) {
 
 		assert.NotEmpty(t, assignmentID)
 	})
+
+	// Adopting the deployed module resources into an empty state must plan no changes
+	test_structure.RunTestStage(t, "import", func() {
+		importtest.RequireStateImportRoundTrip(t, test_structure.LoadTerraformOptions(t, testFolder))
+	})
 }
 
 // Test complete azuredevops_securityrole_assignment configuration
@@ -40,13 +48,13 @@ func TestCompleteAzuredevopsSecurityroleAssignment(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/complete")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder, getScopeIDComplete(t), getResourceIDComplete(t), getIdentityIDComplete(t)))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder, getScopeIDComplete(t), getResourceIDComplete(t), getIdentityIDComplete(t)), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder, getScopeIDComplete(t), getResourceIDComplete(t), getIdentityIDComplete(t))
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -64,13 +72,13 @@ func TestSecureAzuredevopsSecurityroleAssignment(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/secure")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder, getScopeIDSecure(t), getResourceIDSecure(t), getIdentityIDSecure(t)))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder, getScopeIDSecure(t), getResourceIDSecure(t), getIdentityIDSecure(t)), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder, getScopeIDSecure(t), getResourceIDSecure(t), getIdentityIDSecure(t))
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
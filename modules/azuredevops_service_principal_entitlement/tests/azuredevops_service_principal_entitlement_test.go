@@ -5,6 +5,11 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adoentitlement"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adohelper"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/importtest"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/assert"
@@ -21,15 +26,15 @@ func TestBasicAzuredevopsServicePrincipalEntitlement(t *testing.T) {
 	terraformOptions := getTerraformOptions(t, testFolder, originID)
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		if _, err := os.Stat(filepath.Join(testFolder, ".test-data", "TerraformOptions.json")); err == nil {
-			terraform.Destroy(t, test_structure.LoadTerraformOptions(t, testFolder))
+			destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 			return
 		}
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -40,6 +45,17 @@ func TestBasicAzuredevopsServicePrincipalEntitlement(t *testing.T) {
 
 		assert.NotEmpty(t, entitlementID)
 		assert.NotEmpty(t, descriptor)
+
+		adoentitlement.RequireServicePrincipalEntitlement(t, adohelper.New(t).Connection(), entitlementID, adoentitlement.ExpectedEntitlement{
+			AccountLicenseType: "stakeholder",
+			LicensingSource:    "account",
+			OriginID:           originID,
+		})
+	})
+
+	// Adopting the deployed module resources into an empty state must plan no changes
+	test_structure.RunTestStage(t, "import", func() {
+		importtest.RequireStateImportRoundTrip(t, test_structure.LoadTerraformOptions(t, testFolder))
 	})
 }
 
@@ -53,15 +69,15 @@ func TestCompleteAzuredevopsServicePrincipalEntitlement(t *testing.T) {
 	terraformOptions := getTerraformOptions(t, testFolder, originID)
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		if _, err := os.Stat(filepath.Join(testFolder, ".test-data", "TerraformOptions.json")); err == nil {
-			terraform.Destroy(t, test_structure.LoadTerraformOptions(t, testFolder))
+			destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 			return
 		}
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -72,6 +88,12 @@ func TestCompleteAzuredevopsServicePrincipalEntitlement(t *testing.T) {
 
 		assert.NotEmpty(t, entitlementID)
 		assert.NotEmpty(t, descriptor)
+
+		adoentitlement.RequireServicePrincipalEntitlement(t, adohelper.New(t).Connection(), entitlementID, adoentitlement.ExpectedEntitlement{
+			AccountLicenseType: "stakeholder",
+			LicensingSource:    "account",
+			OriginID:           originID,
+		})
 	})
 }
 
@@ -85,15 +107,15 @@ func TestSecureAzuredevopsServicePrincipalEntitlement(t *testing.T) {
 	terraformOptions := getTerraformOptions(t, testFolder, originID)
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		if _, err := os.Stat(filepath.Join(testFolder, ".test-data", "TerraformOptions.json")); err == nil {
-			terraform.Destroy(t, test_structure.LoadTerraformOptions(t, testFolder))
+			destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 			return
 		}
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -104,6 +126,12 @@ func TestSecureAzuredevopsServicePrincipalEntitlement(t *testing.T) {
 
 		assert.NotEmpty(t, entitlementID)
 		assert.NotEmpty(t, descriptor)
+
+		adoentitlement.RequireServicePrincipalEntitlement(t, adohelper.New(t).Connection(), entitlementID, adoentitlement.ExpectedEntitlement{
+			AccountLicenseType: "stakeholder",
+			LicensingSource:    "account",
+			OriginID:           originID,
+		})
 	})
 }
 
@@ -18,3 +18,18 @@ module "azuredevops_service_principal_entitlement" {
   account_license_type = "stakeholder"
   licensing_source     = "account"
 }
+
+data "azuredevops_group" "project_contributors" {
+  count = var.project_id == null ? 0 : 1
+
+  project_id = var.project_id
+  name       = "Contributors"
+}
+
+resource "azuredevops_group_membership" "project_contributor" {
+  count = var.project_id == null ? 0 : 1
+
+  group   = data.azuredevops_group.project_contributors[0].descriptor
+  members = [module.azuredevops_service_principal_entitlement.service_principal_entitlement_descriptor]
+  mode    = "add"
+}
@@ -2,3 +2,9 @@ variable "service_principal_origin_id" {
   description = "Service principal object ID for the entitlement."
   type        = string
 }
+
+variable "project_id" {
+  description = "Project whose Contributors group the service principal joins, so the entitlement carries a project entitlement. Null skips the membership."
+  type        = string
+  default     = null
+}
@@ -5,6 +5,10 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adoentitlement"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adohelper"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/assert"
@@ -18,20 +22,25 @@ func TestAzuredevopsServicePrincipalEntitlementFullIntegration(t *testing.T) {
 
 	requireADOEnv(t)
 	originID := requireServicePrincipalOriginID(t, "AZDO_SERVICE_PRINCIPAL_ORIGIN_ID_COMPLETE")
+	projectID := os.Getenv("AZDO_PROJECT_ID")
+	if projectID == "" {
+		t.Skip("Skipping Azure DevOps tests: AZDO_PROJECT_ID is required to check project entitlements")
+	}
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/complete")
 	terraformOptions := getTerraformOptions(t, testFolder, originID)
+	terraformOptions.Vars["project_id"] = projectID
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		if _, err := os.Stat(filepath.Join(testFolder, ".test-data", "TerraformOptions.json")); err == nil {
-			terraform.Destroy(t, test_structure.LoadTerraformOptions(t, testFolder))
+			destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 			return
 		}
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -42,5 +51,14 @@ func TestAzuredevopsServicePrincipalEntitlementFullIntegration(t *testing.T) {
 
 		assert.NotEmpty(t, entitlementID)
 		assert.NotEmpty(t, descriptor)
+
+		// The service principal may already belong to other projects of the organization
+		adoentitlement.RequireServicePrincipalEntitlement(t, adohelper.New(t).Connection(), entitlementID, adoentitlement.ExpectedEntitlement{
+			AccountLicenseType:  "stakeholder",
+			LicensingSource:     "account",
+			OriginID:            originID,
+			ProjectEntitlements: map[string]string{projectID: "projectContributor"},
+			AllowOtherProjects:  true,
+		})
 	})
 }
@@ -7,6 +7,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adoacl"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adohelper"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/importtest"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
@@ -23,15 +28,15 @@ func TestBasicAzuredevopsServiceendpoint(t *testing.T) {
 	terraformOptions := getTerraformOptions(t, testFolder)
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		if _, err := os.Stat(filepath.Join(testFolder, ".test-data", "TerraformOptions.json")); err == nil {
-			terraform.Destroy(t, test_structure.LoadTerraformOptions(t, testFolder))
+			destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 			return
 		}
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -40,6 +45,22 @@ func TestBasicAzuredevopsServiceendpoint(t *testing.T) {
 		serviceendpointID := terraform.Output(t, terraformOptions, "serviceendpoint_id")
 
 		assert.NotEmpty(t, serviceendpointID)
+
+		helper := adohelper.New(t)
+		endpoint := helper.GetServiceEndpoint(t, getProjectID(t), serviceendpointID)
+		require.NotNil(t, endpoint.Name)
+		assert.Equal(t, terraformOptions.Vars["generic_endpoint_name_prefix"], *endpoint.Name)
+		require.NotNil(t, endpoint.Type)
+		assert.Equal(t, "generic", *endpoint.Type)
+		require.NotNil(t, endpoint.Url)
+		assert.Equal(t, "https://example.endpoint.local", *endpoint.Url)
+		require.NotNil(t, endpoint.Description)
+		assert.Equal(t, "Managed by Terraform", *endpoint.Description)
+	})
+
+	// Adopting the deployed module resources into an empty state must plan no changes
+	test_structure.RunTestStage(t, "import", func() {
+		importtest.RequireStateImportRoundTrip(t, test_structure.LoadTerraformOptions(t, testFolder))
 	})
 }
 
@@ -52,15 +73,15 @@ func TestCompleteAzuredevopsServiceendpoint(t *testing.T) {
 	terraformOptions := getTerraformOptions(t, testFolder)
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		if _, err := os.Stat(filepath.Join(testFolder, ".test-data", "TerraformOptions.json")); err == nil {
-			terraform.Destroy(t, test_structure.LoadTerraformOptions(t, testFolder))
+			destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 			return
 		}
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -73,6 +94,17 @@ func TestCompleteAzuredevopsServiceendpoint(t *testing.T) {
 		assert.NotEmpty(t, primaryEndpointID)
 		assert.NotEmpty(t, secondaryEndpointID)
 		assert.NotEmpty(t, primaryPermissions)
+
+		helper := adohelper.New(t)
+		for endpointID, suffix := range map[string]string{primaryEndpointID: "primary", secondaryEndpointID: "secondary"} {
+			RequireGenericEndpointRoundTrip(t, helper, endpointID, ExpectedGenericEndpoint{
+				Name:        fmt.Sprintf("%s-%s", terraformOptions.Vars["generic_endpoint_name_prefix"], suffix),
+				URL:         "https://example.endpoint.local",
+				Username:    "example-user",
+				Description: "Managed by Terraform",
+				ProjectID:   getProjectID(t),
+			})
+		}
 	})
 }
 
@@ -85,15 +117,15 @@ func TestSecureAzuredevopsServiceendpoint(t *testing.T) {
 	terraformOptions := getTerraformOptions(t, testFolder)
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		if _, err := os.Stat(filepath.Join(testFolder, ".test-data", "TerraformOptions.json")); err == nil {
-			terraform.Destroy(t, test_structure.LoadTerraformOptions(t, testFolder))
+			destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 			return
 		}
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -104,6 +136,13 @@ func TestSecureAzuredevopsServiceendpoint(t *testing.T) {
 
 		assert.NotEmpty(t, serviceendpointID)
 		assert.NotEmpty(t, permissions)
+
+		helper := adohelper.New(t)
+		collectionAdmins := adoacl.RequireIdentityDescriptor(t, helper.Connection(), terraform.Output(t, terraformOptions, "collection_admins_descriptor"))
+		token := adoacl.ServiceEndpointPermissionToken(getProjectID(t), serviceendpointID)
+		adoacl.RequireEffectivePermission(t, helper.Connection(), adoacl.SecurityNamespaceServiceEndpoints, token, collectionAdmins, "Use", adoacl.PermissionAllow)
+		// The explicit Deny on the endpoint wins over the Allow collection administrators inherit
+		adoacl.RequireEffectivePermission(t, helper.Connection(), adoacl.SecurityNamespaceServiceEndpoints, token, collectionAdmins, "Administer", adoacl.PermissionDeny)
 	})
 }
 
@@ -135,13 +174,9 @@ func getTerraformOptions(t testing.TB, terraformDir string) *terraform.Options {
 			"project_id":                   getProjectID(t),
 			"generic_endpoint_name_prefix": fmt.Sprintf("ado-endpoint-%s", uniqueID),
 		},
-		NoColor: true,
-		RetryableTerraformErrors: map[string]string{
-			".*timeout.*":         "Timeout error - retrying",
-			".*TooManyRequests.*": "Too many requests - retrying",
-			".*invalid character '<' looking for beginning of value.*": "Azure DevOps returned HTML instead of JSON - retrying",
-		},
-		MaxRetries:         3,
-		TimeBetweenRetries: 10 * time.Second,
+		NoColor:                  true,
+		RetryableTerraformErrors: tfretry.ClassifiedRetryableErrors(),
+		MaxRetries:               3,
+		TimeBetweenRetries:       10 * time.Second,
 	}
 }
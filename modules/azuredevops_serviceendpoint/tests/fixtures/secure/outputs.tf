@@ -7,3 +7,8 @@ output "permissions" {
   description = "Service endpoint permission IDs created by the module."
   value       = module.azuredevops_serviceendpoint.permissions
 }
+
+output "collection_admins_descriptor" {
+  description = "Descriptor of the Project Collection Administrators group given service endpoint permissions."
+  value       = data.azuredevops_group.project_collection_admins.descriptor
+}
@@ -0,0 +1,55 @@
+package test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/serviceendpoint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadTestGenericEndpoint(t *testing.T) *serviceendpoint.ServiceEndpoint {
+	content, err := os.ReadFile(filepath.Join("testdata", "generic_endpoint.json"))
+	require.NoError(t, err)
+
+	var endpoint serviceendpoint.ServiceEndpoint
+	require.NoError(t, json.Unmarshal(content, &endpoint))
+	return &endpoint
+}
+
+func testExpectedGenericEndpoint() ExpectedGenericEndpoint {
+	return ExpectedGenericEndpoint{
+		Name:        "ado-endpoint-abc123-primary",
+		URL:         "https://example.endpoint.local",
+		Username:    "example-user",
+		Description: "Managed by Terraform",
+		ProjectID:   "0f3c8a7e-2b7d-4c6a-9a53-1f0e6d2b8c11",
+	}
+}
+
+func TestCompareGenericEndpointRoundTrips(t *testing.T) {
+	assert.Empty(t, CompareGenericEndpoint(loadTestGenericEndpoint(t), testExpectedGenericEndpoint()))
+}
+
+func TestCompareGenericEndpointDifferences(t *testing.T) {
+	endpoint := loadTestGenericEndpoint(t)
+	scheme := "Token"
+	endpoint.Authorization.Scheme = &scheme
+	(*endpoint.Authorization.Parameters)["password"] = "example-password"
+
+	expected := testExpectedGenericEndpoint()
+	expected.URL = "https://other.endpoint.local"
+	expected.Username = "other-user"
+	expected.ProjectID = "a1d6e1f4-7c0e-4a9b-8f3d-2e5c9b7a6d10"
+
+	assert.ElementsMatch(t, []string{
+		`url is "https://example.endpoint.local", expected "https://other.endpoint.local"`,
+		`authorization scheme is "Token", expected "UsernamePassword"`,
+		`authorization username is "example-user", expected "other-user"`,
+		"authorization password is returned in clear text",
+		"endpoint is not referenced by project a1d6e1f4-7c0e-4a9b-8f3d-2e5c9b7a6d10",
+	}, CompareGenericEndpoint(endpoint, expected))
+}
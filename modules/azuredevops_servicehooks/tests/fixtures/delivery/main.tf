@@ -0,0 +1,42 @@
+terraform {
+  required_version = ">= 1.12.2"
+  required_providers {
+    azuredevops = {
+      source  = "microsoft/azuredevops"
+      version = "1.12.2"
+    }
+  }
+}
+
+provider "azuredevops" {}
+
+resource "azuredevops_git_repository" "delivery" {
+  project_id = var.project_id
+  name       = "repo-ado-hook-${var.random_suffix}"
+
+  initialization {
+    init_type = "Clean"
+  }
+}
+
+module "azuredevops_servicehooks" {
+  source = "../../../"
+
+  project_id = var.project_id
+
+  webhook = {
+    url                      = var.webhook_url
+    accept_untrusted_certs   = true
+    basic_auth_username      = var.webhook_username
+    basic_auth_password      = var.webhook_password
+    resource_details_to_send = "all"
+    messages_to_send         = "text"
+    http_headers = {
+      "X-Terratest-Run" = var.random_suffix
+    }
+    git_push = {
+      repository_id = azuredevops_git_repository.delivery.id
+      branch        = "main"
+    }
+  }
+}
@@ -0,0 +1,9 @@
+output "webhook_id" {
+  description = "Webhook ID created in this fixture."
+  value       = module.azuredevops_servicehooks.webhook_id
+}
+
+output "repository_id" {
+  description = "Repository whose pushes trigger the webhook."
+  value       = azuredevops_git_repository.delivery.id
+}
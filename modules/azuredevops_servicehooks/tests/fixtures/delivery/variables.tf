@@ -0,0 +1,26 @@
+variable "project_id" {
+  description = "Azure DevOps project ID."
+  type        = string
+}
+
+variable "random_suffix" {
+  description = "A random suffix to ensure unique resource names."
+  type        = string
+}
+
+variable "webhook_url" {
+  description = "Public HTTPS URL that forwards to the local webhook receiver."
+  type        = string
+}
+
+variable "webhook_username" {
+  description = "Basic auth username sent with each delivery."
+  type        = string
+  default     = "terratest"
+}
+
+variable "webhook_password" {
+  description = "Basic auth password sent with each delivery."
+  type        = string
+  sensitive   = true
+}
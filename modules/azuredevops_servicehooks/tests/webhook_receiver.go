@@ -0,0 +1,297 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adohelper"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/stretchr/testify/require"
+)
+
+// ServiceHookEvent is the body Azure DevOps posts to webhook consumers
+type ServiceHookEvent struct {
+	ID                 string                         `json:"id"`
+	EventType          string                         `json:"eventType"`
+	PublisherID        string                         `json:"publisherId"`
+	SubscriptionID     string                         `json:"subscriptionId"`
+	NotificationID     int                            `json:"notificationId"`
+	Message            *ServiceHookMessage            `json:"message"`
+	DetailedMessage    *ServiceHookMessage            `json:"detailedMessage"`
+	Resource           map[string]interface{}         `json:"resource"`
+	ResourceVersion    string                         `json:"resourceVersion"`
+	ResourceContainers map[string]ServiceHookResource `json:"resourceContainers"`
+	CreatedDate        string                         `json:"createdDate"`
+}
+
+// ServiceHookMessage is the text/html/markdown rendering of an event
+type ServiceHookMessage struct {
+	Text     string `json:"text"`
+	HTML     string `json:"html"`
+	Markdown string `json:"markdown"`
+}
+
+// ServiceHookResource identifies a container (collection, account, project) of the event
+type ServiceHookResource struct {
+	ID      string `json:"id"`
+	BaseURL string `json:"baseUrl"`
+}
+
+// WebhookDelivery is one request received by the WebhookReceiver
+type WebhookDelivery struct {
+	Method       string
+	Path         string
+	Header       http.Header
+	Body         []byte
+	HasBasicAuth bool
+	Username     string
+	Password     string
+	Event        ServiceHookEvent
+	DecodeError  error
+	ReceivedAt   time.Time
+}
+
+// ExpectedDelivery describes what a webhook delivery must contain
+type ExpectedDelivery struct {
+	EventType      string
+	SubscriptionID string
+	ProjectID      string
+	Username       string
+	Password       string
+	Headers        map[string]string
+	// ResourceFields are top-level keys that must be present in the event resource
+	ResourceFields []string
+}
+
+// WebhookReceiver is a local HTTPS endpoint that records service hook deliveries
+type WebhookReceiver struct {
+	server     *httptest.Server
+	mu         sync.Mutex
+	deliveries []WebhookDelivery
+	received   chan struct{}
+}
+
+// NewWebhookReceiver starts an HTTPS receiver with a self-signed certificate.
+// listenAddr may be empty to listen on a random loopback port.
+func NewWebhookReceiver(t testing.TB, listenAddr string) *WebhookReceiver {
+	t.Helper()
+
+	receiver := &WebhookReceiver{received: make(chan struct{}, 1)}
+	receiver.server = httptest.NewUnstartedServer(http.HandlerFunc(receiver.handle))
+	if listenAddr != "" {
+		listener, err := net.Listen("tcp", listenAddr)
+		require.NoError(t, err, "Failed to listen on %s", listenAddr)
+		receiver.server.Listener.Close()
+		receiver.server.Listener = listener
+	}
+	receiver.server.StartTLS()
+	t.Cleanup(receiver.server.Close)
+	return receiver
+}
+
+// URL returns the local base URL of the receiver
+func (r *WebhookReceiver) URL() string {
+	return r.server.URL
+}
+
+// Client returns an HTTP client that trusts the receiver certificate
+func (r *WebhookReceiver) Client() *http.Client {
+	return r.server.Client()
+}
+
+// Deliveries returns a copy of everything received so far
+func (r *WebhookReceiver) Deliveries() []WebhookDelivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]WebhookDelivery(nil), r.deliveries...)
+}
+
+// WaitForDeliveryE waits until a delivery with the event type arrives
+func (r *WebhookReceiver) WaitForDeliveryE(eventType string, timeout time.Duration) (WebhookDelivery, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		for _, delivery := range r.Deliveries() {
+			if delivery.Event.EventType == eventType {
+				return delivery, nil
+			}
+		}
+		select {
+		case <-r.received:
+		case <-deadline.C:
+			return WebhookDelivery{}, fmt.Errorf("no %s delivery within %s (received %d other deliveries)", eventType, timeout, len(r.Deliveries()))
+		}
+	}
+}
+
+// WaitForDelivery waits until a delivery with the event type arrives
+func (r *WebhookReceiver) WaitForDelivery(t testing.TB, eventType string, timeout time.Duration) WebhookDelivery {
+	t.Helper()
+
+	delivery, err := r.WaitForDeliveryE(eventType, timeout)
+	require.NoError(t, err)
+	return delivery
+}
+
+func (r *WebhookReceiver) handle(w http.ResponseWriter, request *http.Request) {
+	body, err := io.ReadAll(request.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	delivery := WebhookDelivery{
+		Method:     request.Method,
+		Path:       request.URL.Path,
+		Header:     request.Header.Clone(),
+		Body:       body,
+		ReceivedAt: time.Now(),
+	}
+	delivery.Username, delivery.Password, delivery.HasBasicAuth = request.BasicAuth()
+	delivery.DecodeError = json.Unmarshal(body, &delivery.Event)
+
+	r.mu.Lock()
+	r.deliveries = append(r.deliveries, delivery)
+	r.mu.Unlock()
+	select {
+	case r.received <- struct{}{}:
+	default:
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// RequireDelivery fails the test when the delivery does not match the expectation
+func RequireDelivery(t testing.TB, delivery WebhookDelivery, expected ExpectedDelivery) {
+	t.Helper()
+
+	if problems := CompareDelivery(delivery, expected); len(problems) > 0 {
+		require.FailNow(t, "Webhook delivery does not match the fixture", "%s delivery:\n  %s", expected.EventType, strings.Join(problems, "\n  "))
+	}
+}
+
+// CompareDelivery reports differences in method, headers, credentials and payload shape
+func CompareDelivery(delivery WebhookDelivery, expected ExpectedDelivery) []string {
+	var problems []string
+	if delivery.Method != http.MethodPost {
+		problems = append(problems, fmt.Sprintf("method is %s, expected POST", delivery.Method))
+	}
+	if contentType := delivery.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+		problems = append(problems, fmt.Sprintf("content type is %q, expected application/json", contentType))
+	}
+
+	if expected.Username != "" || expected.Password != "" {
+		switch {
+		case !delivery.HasBasicAuth:
+			problems = append(problems, "basic auth credentials are missing")
+		case delivery.Username != expected.Username || delivery.Password != expected.Password:
+			problems = append(problems, fmt.Sprintf("basic auth user is %q with a different password than configured, expected %q", delivery.Username, expected.Username))
+		}
+	}
+
+	headerNames := make([]string, 0, len(expected.Headers))
+	for name := range expected.Headers {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+	for _, name := range headerNames {
+		if actual := delivery.Header.Get(name); actual != expected.Headers[name] {
+			problems = append(problems, fmt.Sprintf("header %s is %q, expected %q", name, actual, expected.Headers[name]))
+		}
+	}
+
+	if delivery.DecodeError != nil {
+		return append(problems, fmt.Sprintf("payload is not a service hook event: %v", delivery.DecodeError))
+	}
+	event := delivery.Event
+	if event.EventType != expected.EventType {
+		problems = append(problems, fmt.Sprintf("event type is %q, expected %q", event.EventType, expected.EventType))
+	}
+	if event.PublisherID != "tfs" {
+		problems = append(problems, fmt.Sprintf("publisher is %q, expected tfs", event.PublisherID))
+	}
+	if event.ID == "" || event.CreatedDate == "" {
+		problems = append(problems, "event id or createdDate is missing")
+	}
+	if event.Message == nil || event.Message.Text == "" {
+		problems = append(problems, "message text is missing")
+	}
+	if expected.SubscriptionID != "" && !strings.EqualFold(event.SubscriptionID, expected.SubscriptionID) {
+		problems = append(problems, fmt.Sprintf("subscription is %q, expected %q", event.SubscriptionID, expected.SubscriptionID))
+	}
+	if expected.ProjectID != "" {
+		if project, ok := event.ResourceContainers["project"]; !ok || !strings.EqualFold(project.ID, expected.ProjectID) {
+			problems = append(problems, fmt.Sprintf("project container is %q, expected %q", project.ID, expected.ProjectID))
+		}
+	}
+	for _, field := range expected.ResourceFields {
+		if _, ok := event.Resource[field]; !ok {
+			problems = append(problems, fmt.Sprintf("resource field %s is missing", field))
+		}
+	}
+	return problems
+}
+
+// PushFileE commits a file to the branch through the Git REST API and returns the push ID
+func (h *AzureDevOpsHelper) PushFileE(projectID, repositoryID, branch, path, content string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), adohelper.RequestTimeout)
+	defer cancel()
+
+	client, err := h.Git(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	branch = strings.TrimPrefix(branch, "refs/heads/")
+	filter := "heads/" + branch
+	refs, err := client.GetRefs(ctx, git.GetRefsArgs{RepositoryId: &repositoryID, Project: &projectID, Filter: &filter})
+	if err != nil {
+		return 0, err
+	}
+	var oldObjectID string
+	if refs != nil {
+		for _, ref := range refs.Value {
+			if ref.Name != nil && *ref.Name == "refs/heads/"+branch && ref.ObjectId != nil {
+				oldObjectID = *ref.ObjectId
+			}
+		}
+	}
+	if oldObjectID == "" {
+		return 0, fmt.Errorf("branch %s not found in repository %s", branch, repositoryID)
+	}
+
+	refName := "refs/heads/" + branch
+	comment := fmt.Sprintf("Terratest webhook delivery %s", path)
+	changes := []interface{}{
+		git.GitChange{
+			ChangeType: &git.VersionControlChangeTypeValues.Add,
+			Item:       map[string]string{"path": "/" + strings.TrimPrefix(path, "/")},
+			NewContent: &git.ItemContent{Content: &content, ContentType: &git.ItemContentTypeValues.RawText},
+		},
+	}
+	push, err := client.CreatePush(ctx, git.CreatePushArgs{
+		RepositoryId: &repositoryID,
+		Project:      &projectID,
+		Push: &git.GitPush{
+			RefUpdates: &[]git.GitRefUpdate{{Name: &refName, OldObjectId: &oldObjectID}},
+			Commits:    &[]git.GitCommitRef{{Comment: &comment, Changes: &changes}},
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	if push == nil || push.PushId == nil {
+		return 0, fmt.Errorf("push to %s returned no push ID", refName)
+	}
+	return *push.PushId, nil
+}
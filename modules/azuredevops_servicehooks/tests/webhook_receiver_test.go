@@ -0,0 +1,133 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test that the webhook actually delivers a git.push event with the configured headers and credentials.
+// Azure DevOps must reach the local receiver, so AZDO_WEBHOOK_PUBLIC_URL has to be an HTTPS URL
+// (for example a tunnel) forwarding to AZDO_WEBHOOK_LISTEN_ADDR (default 127.0.0.1:8443).
+func TestAzuredevopsServicehooksDelivery(t *testing.T) {
+	publicURL := os.Getenv("AZDO_WEBHOOK_PUBLIC_URL")
+	if publicURL == "" {
+		t.Skip("Skipping webhook delivery test; set AZDO_WEBHOOK_PUBLIC_URL to a URL forwarding to the local receiver")
+	}
+	requireADOEnv(t)
+
+	listenAddr := os.Getenv("AZDO_WEBHOOK_LISTEN_ADDR")
+	if listenAddr == "" {
+		listenAddr = "127.0.0.1:8443"
+	}
+	receiver := NewWebhookReceiver(t, listenAddr)
+
+	uniqueID := random.UniqueId()
+	password := fmt.Sprintf("hook-%s-%s", uniqueID, random.UniqueId())
+	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/delivery")
+	terraformOptions := getTerraformOptions(t, testFolder)
+	terraformOptions.Vars["random_suffix"] = uniqueID
+	terraformOptions.Vars["webhook_url"] = publicURL
+	terraformOptions.Vars["webhook_password"] = password
+	defer destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
+
+	tfretry.InitAndApplyWithRetry(t, terraformOptions)
+	webhookID := terraform.Output(t, terraformOptions, "webhook_id")
+	repositoryID := terraform.Output(t, terraformOptions, "repository_id")
+
+	helper := NewAzureDevOpsHelper(t)
+	_, err := helper.PushFileE(getProjectID(t), repositoryID, "main", "notes/delivery.txt", "Terratest webhook delivery "+uniqueID)
+	require.NoError(t, err)
+
+	delivery := receiver.WaitForDelivery(t, "git.push", 5*time.Minute)
+	RequireDelivery(t, delivery, ExpectedDelivery{
+		EventType:      "git.push",
+		SubscriptionID: webhookID,
+		ProjectID:      getProjectID(t),
+		Username:       "terratest",
+		Password:       password,
+		Headers:        map[string]string{"X-Terratest-Run": uniqueID},
+		ResourceFields: []string{"commits", "refUpdates", "repository", "pushId"},
+	})
+	repository, ok := delivery.Event.Resource["repository"].(map[string]interface{})
+	require.True(t, ok, "git.push resource should contain the repository")
+	assert.Equal(t, repositoryID, repository["id"])
+}
+
+func testExpectedPushDelivery() ExpectedDelivery {
+	return ExpectedDelivery{
+		EventType:      "git.push",
+		SubscriptionID: "7d1f6a3e-5c2b-4f0e-9b8a-1c2d3e4f5a6b",
+		ProjectID:      "0f3c8a7e-2b7d-4c6a-9a53-1f0e6d2b8c11",
+		Username:       "terratest",
+		Password:       "webhook-secret",
+		Headers:        map[string]string{"X-Terratest-Run": "abc123"},
+		ResourceFields: []string{"commits", "refUpdates", "repository", "pushId"},
+	}
+}
+
+func postTestEvent(t *testing.T, receiver *WebhookReceiver, username, password string, headers map[string]string) {
+	body, err := os.ReadFile(filepath.Join("testdata", "git_push_event.json"))
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, receiver.URL()+"/hooks/git-push", bytes.NewReader(body))
+	require.NoError(t, err)
+	request.Header.Set("Content-Type", "application/json; charset=utf-8")
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	if username != "" {
+		request.SetBasicAuth(username, password)
+	}
+
+	response, err := receiver.Client().Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+}
+
+func TestWebhookReceiverRecordsDelivery(t *testing.T) {
+	receiver := NewWebhookReceiver(t, "")
+	require.Contains(t, receiver.URL(), "https://")
+
+	postTestEvent(t, receiver, "terratest", "webhook-secret", map[string]string{"X-Terratest-Run": "abc123"})
+
+	delivery := receiver.WaitForDelivery(t, "git.push", 5*time.Second)
+	assert.Equal(t, "/hooks/git-push", delivery.Path)
+	assert.Empty(t, CompareDelivery(delivery, testExpectedPushDelivery()))
+}
+
+func TestWebhookReceiverReportsMismatches(t *testing.T) {
+	receiver := NewWebhookReceiver(t, "")
+	postTestEvent(t, receiver, "terratest", "wrong-secret", nil)
+
+	delivery := receiver.WaitForDelivery(t, "git.push", 5*time.Second)
+	expected := testExpectedPushDelivery()
+	expected.ResourceFields = append(expected.ResourceFields, "pullRequestId")
+	assert.ElementsMatch(t, []string{
+		`basic auth user is "terratest" with a different password than configured, expected "terratest"`,
+		`header X-Terratest-Run is "", expected "abc123"`,
+		"resource field pullRequestId is missing",
+	}, CompareDelivery(delivery, expected))
+}
+
+func TestWebhookReceiverTimesOut(t *testing.T) {
+	receiver := NewWebhookReceiver(t, "")
+	postTestEvent(t, receiver, "", "", nil)
+
+	_, err := receiver.WaitForDeliveryE("build.complete", 200*time.Millisecond)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "received 1 other deliveries")
+}
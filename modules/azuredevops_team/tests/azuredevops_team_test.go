@@ -7,6 +7,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adohelper"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adomembership"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/importtest"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
@@ -23,15 +28,15 @@ func TestBasicAzuredevopsTeam(t *testing.T) {
 	terraformOptions := getTerraformOptions(t, testFolder)
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		if _, err := os.Stat(filepath.Join(testFolder, ".test-data", "TerraformOptions.json")); err == nil {
-			terraform.Destroy(t, test_structure.LoadTerraformOptions(t, testFolder))
+			destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 			return
 		}
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -42,6 +47,21 @@ func TestBasicAzuredevopsTeam(t *testing.T) {
 
 		assert.NotEmpty(t, teamID)
 		assert.NotEmpty(t, teamDescriptor)
+
+		helper := adohelper.New(t)
+		projectID := getProjectID(t)
+		team := helper.GetTeam(t, projectID, teamID)
+		require.NotNil(t, team.Name)
+		assert.Equal(t, fmt.Sprintf("ado-team-bas-%s", terraformOptions.Vars["random_suffix"]), *team.Name)
+		require.NotNil(t, team.Description)
+		assert.Equal(t, "Test core team", *team.Description)
+		require.NotNil(t, team.ProjectId)
+		assert.Equal(t, projectID, team.ProjectId.String())
+	})
+
+	// Adopting the deployed module resources into an empty state must plan no changes
+	test_structure.RunTestStage(t, "import", func() {
+		importtest.RequireStateImportRoundTrip(t, test_structure.LoadTerraformOptions(t, testFolder))
 	})
 }
 
@@ -54,15 +74,15 @@ func TestCompleteAzuredevopsTeam(t *testing.T) {
 	terraformOptions := getTerraformOptions(t, testFolder)
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		if _, err := os.Stat(filepath.Join(testFolder, ".test-data", "TerraformOptions.json")); err == nil {
-			terraform.Destroy(t, test_structure.LoadTerraformOptions(t, testFolder))
+			destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 			return
 		}
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -78,6 +98,27 @@ func TestCompleteAzuredevopsTeam(t *testing.T) {
 		assert.True(t, ok)
 		_, ok = teamAdministratorIDs["team-admins"]
 		assert.True(t, ok)
+
+		adomembership.RequireTeamMembership(t, adohelper.New(t).Connection(), getProjectID(t), teamID,
+			terraform.OutputList(t, terraformOptions, "member_descriptors"),
+			terraform.OutputList(t, terraformOptions, "administrator_descriptors"))
+	})
+
+	// Shrink both lists and re-apply: overwrite mode must remove the dropped groups
+	test_structure.RunTestStage(t, "update", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+		terraformOptions.Vars["member_group_names"] = []string{"Readers"}
+		terraformOptions.Vars["administrator_group_names"] = []string{"Project Administrators"}
+		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
+		tfretry.ApplyWithRetry(t, terraformOptions)
+
+		memberDescriptors := terraform.OutputList(t, terraformOptions, "member_descriptors")
+		administratorDescriptors := terraform.OutputList(t, terraformOptions, "administrator_descriptors")
+		require.Len(t, memberDescriptors, 1)
+		require.Len(t, administratorDescriptors, 1)
+
+		adomembership.RequireTeamMembership(t, adohelper.New(t).Connection(), getProjectID(t),
+			terraform.Output(t, terraformOptions, "team_id"), memberDescriptors, administratorDescriptors)
 	})
 }
 
@@ -90,15 +131,15 @@ func TestSecureAzuredevopsTeam(t *testing.T) {
 	terraformOptions := getTerraformOptions(t, testFolder)
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		if _, err := os.Stat(filepath.Join(testFolder, ".test-data", "TerraformOptions.json")); err == nil {
-			terraform.Destroy(t, test_structure.LoadTerraformOptions(t, testFolder))
+			destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 			return
 		}
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -139,14 +180,9 @@ func getTerraformOptions(t testing.TB, terraformDir string) *terraform.Options {
 			"project_id":    getProjectID(t),
 			"random_suffix": fmt.Sprintf("ado-%s", uniqueID),
 		},
-		NoColor: true,
-		RetryableTerraformErrors: map[string]string{
-			".*timeout.*":                  "Timeout error - retrying",
-			".*TooManyRequests.*":          "Too many requests - retrying",
-			".*connection reset by peer.*": "Azure DevOps connection reset - retrying",
-			".*invalid character '<' looking for beginning of value.*": "Azure DevOps returned HTML instead of JSON - retrying",
-		},
-		MaxRetries:         3,
-		TimeBetweenRetries: 10 * time.Second,
+		NoColor:                  true,
+		RetryableTerraformErrors: tfretry.ClassifiedRetryableErrors(),
+		MaxRetries:               3,
+		TimeBetweenRetries:       10 * time.Second,
 	}
 }
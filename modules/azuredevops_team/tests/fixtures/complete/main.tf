@@ -10,13 +10,18 @@ terraform {
 
 provider "azuredevops" {}
 
-data "azuredevops_group" "project_collection_admins" {
-  name = "Project Collection Administrators"
+data "azuredevops_group" "members" {
+  for_each = toset(var.member_group_names)
+
+  project_id = var.project_id
+  name       = each.value
 }
 
-data "azuredevops_group" "project_readers" {
+data "azuredevops_group" "administrators" {
+  for_each = toset(var.administrator_group_names)
+
   project_id = var.project_id
-  name       = "Readers"
+  name       = each.value
 }
 
 module "azuredevops_team" {
@@ -26,17 +31,20 @@ module "azuredevops_team" {
   name        = "ado-team-cmp-${var.random_suffix}"
   description = "Platform engineering team"
 
+  # overwrite removes the team creator and anything dropped from the lists on update
   team_members = [
     {
       key                = "team-members"
-      member_descriptors = [data.azuredevops_group.project_readers.descriptor]
+      member_descriptors = [for group in data.azuredevops_group.members : group.descriptor]
+      mode               = "overwrite"
     }
   ]
 
   team_administrators = [
     {
       key               = "team-admins"
-      admin_descriptors = [data.azuredevops_group.project_collection_admins.descriptor]
+      admin_descriptors = [for group in data.azuredevops_group.administrators : group.descriptor]
+      mode              = "overwrite"
     }
   ]
 }
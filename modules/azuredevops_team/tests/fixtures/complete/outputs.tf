@@ -17,3 +17,13 @@ output "team_administrator_ids" {
   description = "Map of team administrator assignment IDs keyed by admin key."
   value       = module.azuredevops_team.team_administrator_ids
 }
+
+output "member_descriptors" {
+  description = "Descriptors of the groups configured as team members."
+  value       = [for group in data.azuredevops_group.members : group.descriptor]
+}
+
+output "administrator_descriptors" {
+  description = "Descriptors of the groups configured as team administrators."
+  value       = [for group in data.azuredevops_group.administrators : group.descriptor]
+}
@@ -8,3 +8,15 @@ variable "random_suffix" {
   type        = string
   default     = "local"
 }
+
+variable "member_group_names" {
+  description = "Project groups added as team members."
+  type        = list(string)
+  default     = ["Readers", "Contributors"]
+}
+
+variable "administrator_group_names" {
+  description = "Project groups assigned as team administrators."
+  type        = list(string)
+  default     = ["Project Administrators", "Build Administrators"]
+}
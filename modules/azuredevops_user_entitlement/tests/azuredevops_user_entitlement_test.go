@@ -3,6 +3,11 @@ package test
 import (
 	"testing"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adoentitlement"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adohelper"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/importtest"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/assert"
@@ -17,7 +22,7 @@ func TestBasicAzuredevopsUserEntitlement(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/basic")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder, fixtureName))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder, fixtureName), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "setup", func() {
@@ -27,7 +32,7 @@ func TestBasicAzuredevopsUserEntitlement(t *testing.T) {
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -41,6 +46,11 @@ func TestBasicAzuredevopsUserEntitlement(t *testing.T) {
 		assert.NotEmpty(t, userDescriptor)
 		assert.Equal(t, "fixture-basic-user", userKey)
 	})
+
+	// Adopting the deployed module resources into an empty state must plan no changes
+	test_structure.RunTestStage(t, "import", func() {
+		importtest.RequireStateImportRoundTrip(t, test_structure.LoadTerraformOptions(t, testFolder))
+	})
 }
 
 // Test complete user entitlement configuration
@@ -51,7 +61,7 @@ func TestCompleteAzuredevopsUserEntitlement(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/complete")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder, fixtureName))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder, fixtureName), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "setup", func() {
@@ -61,7 +71,7 @@ func TestCompleteAzuredevopsUserEntitlement(t *testing.T) {
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -74,6 +84,14 @@ func TestCompleteAzuredevopsUserEntitlement(t *testing.T) {
 		assert.NotEmpty(t, userID)
 		assert.NotEmpty(t, userDescriptor)
 		assert.Equal(t, "fixture-complete-user", userKey)
+
+		helper := adohelper.New(t)
+		adoentitlement.RequireUserEntitlement(t, helper.Connection(), userID, adoentitlement.ExpectedEntitlement{
+			AccountLicenseType: "basic",
+			LicensingSource:    "account",
+			PrincipalName:      optionalVar(terraformOptions, "user_principal_name"),
+			OriginID:           optionalVar(terraformOptions, "user_origin_id"),
+		})
 	})
 }
 
@@ -85,7 +103,7 @@ func TestSecureAzuredevopsUserEntitlement(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/secure")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder, fixtureName))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder, fixtureName), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "setup", func() {
@@ -95,7 +113,7 @@ func TestSecureAzuredevopsUserEntitlement(t *testing.T) {
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -108,6 +126,14 @@ func TestSecureAzuredevopsUserEntitlement(t *testing.T) {
 		assert.NotEmpty(t, userID)
 		assert.NotEmpty(t, userDescriptor)
 		assert.Equal(t, "fixture-secure-user", userKey)
+
+		helper := adohelper.New(t)
+		adoentitlement.RequireUserEntitlement(t, helper.Connection(), userID, adoentitlement.ExpectedEntitlement{
+			AccountLicenseType: "stakeholder",
+			LicensingSource:    "account",
+			PrincipalName:      optionalVar(terraformOptions, "user_principal_name"),
+			OriginID:           optionalVar(terraformOptions, "user_origin_id"),
+		})
 	})
 }
 
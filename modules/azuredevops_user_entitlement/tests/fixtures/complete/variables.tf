@@ -33,3 +33,9 @@ variable "user_origin" {
   type        = string
   default     = "aad"
 }
+
+variable "project_id" {
+  description = "Project whose Contributors group the user joins, so the entitlement carries a project entitlement. Null skips the membership."
+  type        = string
+  default     = null
+}
@@ -1,8 +1,13 @@
 package test
 
 import (
+	"os"
 	"testing"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adoentitlement"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adohelper"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/assert"
@@ -16,20 +21,25 @@ func TestAzuredevopsUserEntitlementFullIntegration(t *testing.T) {
 
 	fixtureName := "complete"
 	requireADOEnv(t, fixtureName)
+	projectID := os.Getenv("AZDO_PROJECT_ID")
+	if projectID == "" {
+		t.Skip("Skipping Azure DevOps tests: AZDO_PROJECT_ID is required to check project entitlements")
+	}
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/complete")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder, fixtureName))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder, fixtureName), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "setup", func() {
 		terraformOptions := getTerraformOptions(t, testFolder, fixtureName)
+		terraformOptions.Vars["project_id"] = projectID
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -42,5 +52,15 @@ func TestAzuredevopsUserEntitlementFullIntegration(t *testing.T) {
 		assert.NotEmpty(t, userID)
 		assert.NotEmpty(t, userDescriptor)
 		assert.Equal(t, "fixture-complete-user", userKey)
+
+		// The user may already belong to other projects of the organization
+		adoentitlement.RequireUserEntitlement(t, adohelper.New(t).Connection(), userID, adoentitlement.ExpectedEntitlement{
+			AccountLicenseType:  "basic",
+			LicensingSource:     "account",
+			PrincipalName:       optionalVar(terraformOptions, "user_principal_name"),
+			OriginID:            optionalVar(terraformOptions, "user_origin_id"),
+			ProjectEntitlements: map[string]string{projectID: "projectContributor"},
+			AllowOtherProjects:  true,
+		})
 	})
 }
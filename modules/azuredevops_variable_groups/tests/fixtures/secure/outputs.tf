@@ -7,3 +7,8 @@ output "variable_group_name" {
   description = "Variable group name created in this fixture."
   value       = module.azuredevops_variable_groups.variable_group_name
 }
+
+output "readers_descriptor" {
+  description = "Descriptor of the Readers group given variable group permissions."
+  value       = data.azuredevops_group.readers.descriptor
+}
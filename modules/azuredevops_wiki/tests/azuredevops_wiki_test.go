@@ -2,9 +2,14 @@ package test
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/importtest"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
@@ -19,13 +24,13 @@ func TestBasicAzuredevopsWiki(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/basic")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -34,6 +39,31 @@ func TestBasicAzuredevopsWiki(t *testing.T) {
 		wikiIDs := terraform.OutputMap(t, terraformOptions, "wiki_ids")
 
 		assert.NotEmpty(t, wikiIDs)
+
+		helper := NewAzureDevOpsHelper(t)
+		RequireWiki(t, helper, getProjectID(t), wikiIDs["wiki"], ExpectedWiki{
+			Name: fmt.Sprintf("%v-basic", terraformOptions.Vars["wiki_name_prefix"]),
+			Type: "projectWiki",
+		})
+		RequireWikiPages(t, helper, getProjectID(t), wikiIDs["wiki"], loadWikiPageMarkdown(t, testFolder, terraformOptions))
+	})
+
+	// Adopting the deployed module resources into an empty state must plan no changes
+	test_structure.RunTestStage(t, "import", func() {
+		importtest.RequireStateImportRoundTrip(t, test_structure.LoadTerraformOptions(t, testFolder))
+	})
+
+	// Re-applying unchanged markdown must not rewrite the pages
+	test_structure.RunTestStage(t, "idempotency", func() {
+		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+		wikiID := terraform.OutputMap(t, terraformOptions, "wiki_ids")["wiki"]
+		expected := loadWikiPageMarkdown(t, testFolder, terraformOptions)
+
+		helper := NewAzureDevOpsHelper(t)
+		before := RequireWikiPages(t, helper, getProjectID(t), wikiID, expected)
+		tfretry.ApplyWithRetry(t, terraformOptions)
+		after := RequireWikiPages(t, helper, getProjectID(t), wikiID, expected)
+		assert.Equal(t, before, after, "Page ETags changed after re-applying the same content")
 	})
 }
 
@@ -44,13 +74,13 @@ func TestCompleteAzuredevopsWiki(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/complete")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -59,6 +89,14 @@ func TestCompleteAzuredevopsWiki(t *testing.T) {
 		wikiIDs := terraform.OutputMap(t, terraformOptions, "wiki_ids")
 
 		assert.NotEmpty(t, wikiIDs)
+
+		RequireWiki(t, NewAzureDevOpsHelper(t), getProjectID(t), wikiIDs["wiki"], ExpectedWiki{
+			Name:         terraform.Output(t, terraformOptions, "wiki_name"),
+			Type:         "codeWiki",
+			RepositoryID: terraform.Output(t, terraformOptions, "repository_id"),
+			Version:      "master",
+			MappedPath:   "/",
+		})
 	})
 }
 
@@ -69,13 +107,13 @@ func TestSecureAzuredevopsWiki(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/secure")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -84,6 +122,14 @@ func TestSecureAzuredevopsWiki(t *testing.T) {
 		wikiIDs := terraform.OutputMap(t, terraformOptions, "wiki_ids")
 
 		assert.NotEmpty(t, wikiIDs)
+
+		RequireWiki(t, NewAzureDevOpsHelper(t), getProjectID(t), wikiIDs["wiki"], ExpectedWiki{
+			Name:         terraform.Output(t, terraformOptions, "wiki_name"),
+			Type:         "codeWiki",
+			RepositoryID: terraform.Output(t, terraformOptions, "repository_id"),
+			Version:      "master",
+			MappedPath:   "/",
+		})
 	})
 }
 
@@ -103,6 +149,19 @@ func TestAzuredevopsWikiValidationRules(t *testing.T) {
 	assert.Contains(t, err.Error(), "unique path values")
 }
 
+// loadWikiPageMarkdown reads the fixture markdown of each page, keyed by page path
+func loadWikiPageMarkdown(t testing.TB, testFolder string, terraformOptions *terraform.Options) map[string]string {
+	t.Helper()
+
+	pages := map[string]string{}
+	for path, file := range terraform.OutputMap(t, terraformOptions, "wiki_page_files") {
+		content, err := os.ReadFile(filepath.Join(testFolder, file))
+		require.NoError(t, err, "Failed to read fixture markdown %s", file)
+		pages[path] = string(content)
+	}
+	return pages
+}
+
 // Helper function to get terraform options
 func getTerraformOptions(t testing.TB, terraformDir string) *terraform.Options {
 	t.Helper()
@@ -115,12 +174,9 @@ func getTerraformOptions(t testing.TB, terraformDir string) *terraform.Options {
 			"project_id":      getProjectID(t),
 			"wiki_name_prefix": fmt.Sprintf("ado-wiki-%s", uniqueID),
 		},
-		NoColor: true,
-		RetryableTerraformErrors: map[string]string{
-			".*timeout.*":         "Timeout error - retrying",
-			".*TooManyRequests.*": "Too many requests - retrying",
-		},
-		MaxRetries:         3,
-		TimeBetweenRetries: 10 * time.Second,
+		NoColor:                  true,
+		RetryableTerraformErrors: tfretry.ClassifiedRetryableErrors(),
+		MaxRetries:               3,
+		TimeBetweenRetries:       10 * time.Second,
 	}
 }
@@ -10,6 +10,20 @@ terraform {
 
 provider "azuredevops" {}
 
+locals {
+  # Page content lives in markdown files so tests can diff the published pages against them
+  wiki_pages = {
+    home = {
+      path = "/Home"
+      file = "pages/home.md"
+    }
+    runbook = {
+      path = "/Runbook"
+      file = "pages/runbook.md"
+    }
+  }
+}
+
 module "azuredevops_wiki" {
   source = "../../../"
 
@@ -21,9 +35,9 @@ module "azuredevops_wiki" {
   }
 
   wiki_pages = {
-    home = {
-      path    = "/Home"
-      content = "Basic wiki page"
+    for key, page in local.wiki_pages : key => {
+      path    = page.path
+      content = file("${path.module}/${page.file}")
     }
   }
 }
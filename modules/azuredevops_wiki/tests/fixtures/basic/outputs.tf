@@ -7,3 +7,8 @@ output "wiki_page_ids" {
   description = "Wiki page IDs created in this fixture."
   value       = module.azuredevops_wiki.wiki_page_ids
 }
+
+output "wiki_page_files" {
+  description = "Fixture markdown file of each wiki page, keyed by page path."
+  value       = { for page in local.wiki_pages : page.path => page.file }
+}
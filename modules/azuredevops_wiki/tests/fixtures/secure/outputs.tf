@@ -7,3 +7,13 @@ output "wiki_page_ids" {
   description = "Wiki page IDs created in this fixture."
   value       = module.azuredevops_wiki.wiki_page_ids
 }
+
+output "wiki_name" {
+  description = "Wiki name created in this fixture."
+  value       = module.azuredevops_wiki.wiki_name
+}
+
+output "repository_id" {
+  description = "Repository backing the code wiki."
+  value       = azuredevops_git_repository.wiki_repo.id
+}
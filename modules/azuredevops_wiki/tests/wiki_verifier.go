@@ -0,0 +1,197 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adohelper"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/wiki"
+	"github.com/stretchr/testify/require"
+)
+
+// ExpectedWiki describes the wiki inputs; repository fields only apply to code wikis
+type ExpectedWiki struct {
+	Name         string
+	Type         string
+	RepositoryID string
+	// Version is the branch name without refs/heads/
+	Version    string
+	MappedPath string
+}
+
+// WikiPageState is a page as read back from the wiki API
+type WikiPageState struct {
+	Path        string
+	Content     string
+	ETag        string
+	GitItemPath string
+}
+
+// GetWikiE retrieves a wiki by ID or name
+func (h *AzureDevOpsHelper) GetWikiE(projectID, wikiID string) (*wiki.WikiV2, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), adohelper.RequestTimeout)
+	defer cancel()
+
+	client, err := wiki.NewClient(ctx, h.Connection())
+	if err != nil {
+		return nil, err
+	}
+	return client.GetWiki(ctx, wiki.GetWikiArgs{WikiIdentifier: &wikiID, Project: &projectID})
+}
+
+// GetWikiPageE retrieves a page with its content and ETag
+func (h *AzureDevOpsHelper) GetWikiPageE(projectID, wikiID, path string) (*WikiPageState, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), adohelper.RequestTimeout)
+	defer cancel()
+
+	client, err := wiki.NewClient(ctx, h.Connection())
+	if err != nil {
+		return nil, err
+	}
+	includeContent := true
+	response, err := client.GetPage(ctx, wiki.GetPageArgs{
+		Project:        &projectID,
+		WikiIdentifier: &wikiID,
+		Path:           &path,
+		IncludeContent: &includeContent,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return wikiPageState(response), nil
+}
+
+// RequireWiki reads the wiki back and compares type, mapped repository, branch and path with the inputs
+func RequireWiki(t testing.TB, helper *AzureDevOpsHelper, projectID, wikiID string, expected ExpectedWiki) *wiki.WikiV2 {
+	t.Helper()
+
+	actual, err := helper.GetWikiE(projectID, wikiID)
+	require.NoError(t, err, "Failed to get wiki %s", wikiID)
+	require.NotNil(t, actual, "Wiki %s not found", wikiID)
+
+	if problems := CompareWiki(actual, expected); len(problems) > 0 {
+		require.FailNow(t, "Wiki does not match the fixture", "wiki %s:\n  %s", wikiID, strings.Join(problems, "\n  "))
+	}
+	return actual
+}
+
+// RequireWikiPages compares every page with the fixture markdown keyed by page path and returns the page ETags
+func RequireWikiPages(t testing.TB, helper *AzureDevOpsHelper, projectID, wikiID string, expected map[string]string) map[string]string {
+	t.Helper()
+
+	etags := make(map[string]string, len(expected))
+	var problems []string
+	for path, content := range expected {
+		page, err := helper.GetWikiPageE(projectID, wikiID, path)
+		require.NoError(t, err, "Failed to get wiki page %s", path)
+
+		if page.ETag == "" {
+			problems = append(problems, fmt.Sprintf("page %s has no ETag", path))
+		}
+		for _, difference := range DiffMarkdown(content, page.Content) {
+			problems = append(problems, fmt.Sprintf("page %s: %s", path, difference))
+		}
+		etags[path] = page.ETag
+	}
+
+	if len(problems) > 0 {
+		require.FailNow(t, "Wiki pages do not match the fixture markdown", "wiki %s:\n  %s", wikiID, strings.Join(problems, "\n  "))
+	}
+	return etags
+}
+
+// CompareWiki reports differences in name, type, repository, branch and mapped path
+func CompareWiki(actual *wiki.WikiV2, expected ExpectedWiki) []string {
+	var problems []string
+	if expected.Name != "" && wikiString(actual.Name) != expected.Name {
+		problems = append(problems, fmt.Sprintf("name is %q, expected %q", wikiString(actual.Name), expected.Name))
+	}
+	actualType := ""
+	if actual.Type != nil {
+		actualType = string(*actual.Type)
+	}
+	if expected.Type != "" && !strings.EqualFold(actualType, expected.Type) {
+		problems = append(problems, fmt.Sprintf("type is %q, expected %q", actualType, expected.Type))
+	}
+
+	if expected.RepositoryID != "" {
+		actualRepository := ""
+		if actual.RepositoryId != nil {
+			actualRepository = actual.RepositoryId.String()
+		}
+		if !strings.EqualFold(actualRepository, expected.RepositoryID) {
+			problems = append(problems, fmt.Sprintf("mapped repository is %q, expected %q", actualRepository, expected.RepositoryID))
+		}
+	}
+	if expected.Version != "" {
+		var versions []string
+		found := false
+		if actual.Versions != nil {
+			for _, version := range *actual.Versions {
+				name := strings.TrimPrefix(wikiString(version.Version), "refs/heads/")
+				versions = append(versions, name)
+				found = found || name == strings.TrimPrefix(expected.Version, "refs/heads/")
+			}
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("published branches are %v, expected %q", versions, expected.Version))
+		}
+	}
+	if expected.MappedPath != "" && wikiString(actual.MappedPath) != expected.MappedPath {
+		problems = append(problems, fmt.Sprintf("mapped path is %q, expected %q", wikiString(actual.MappedPath), expected.MappedPath))
+	}
+	return problems
+}
+
+// DiffMarkdown compares page content line by line, ignoring line endings and trailing newlines,
+// and reports the first differing line plus any difference in line count
+func DiffMarkdown(expected, actual string) []string {
+	expectedLines := markdownLines(expected)
+	actualLines := markdownLines(actual)
+
+	var problems []string
+	for i := 0; i < len(expectedLines) && i < len(actualLines); i++ {
+		if expectedLines[i] != actualLines[i] {
+			problems = append(problems, fmt.Sprintf("line %d is %q, expected %q", i+1, actualLines[i], expectedLines[i]))
+			break
+		}
+	}
+	if len(expectedLines) != len(actualLines) {
+		problems = append(problems, fmt.Sprintf("content has %d lines, expected %d", len(actualLines), len(expectedLines)))
+	}
+	return problems
+}
+
+func markdownLines(content string) []string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = strings.TrimRight(content, "\n")
+	if content == "" {
+		return nil
+	}
+	return strings.Split(content, "\n")
+}
+
+func wikiPageState(response *wiki.WikiPageResponse) *WikiPageState {
+	state := &WikiPageState{}
+	if response == nil {
+		return state
+	}
+	if response.ETag != nil && len(*response.ETag) > 0 {
+		state.ETag = strings.Trim((*response.ETag)[0], `"`)
+	}
+	if response.Page != nil {
+		state.Path = wikiString(response.Page.Path)
+		state.Content = wikiString(response.Page.Content)
+		state.GitItemPath = wikiString(response.Page.GitItemPath)
+	}
+	return state
+}
+
+func wikiString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
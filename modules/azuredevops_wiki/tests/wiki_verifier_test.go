@@ -0,0 +1,73 @@
+package test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/wiki"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadTestWiki(t *testing.T) *wiki.WikiV2 {
+	content, err := os.ReadFile(filepath.Join("testdata", "code_wiki.json"))
+	require.NoError(t, err)
+
+	var actual wiki.WikiV2
+	require.NoError(t, json.Unmarshal(content, &actual))
+	return &actual
+}
+
+func TestCompareWikiMatches(t *testing.T) {
+	assert.Empty(t, CompareWiki(loadTestWiki(t), ExpectedWiki{
+		Name:         "ado-wiki-abc123-complete",
+		Type:         "codeWiki",
+		RepositoryID: "7c2d4e6f-8a9b-4c1d-9e2f-3a4b5c6d7e8f",
+		Version:      "master",
+		MappedPath:   "/",
+	}))
+}
+
+func TestCompareWikiDifferences(t *testing.T) {
+	problems := CompareWiki(loadTestWiki(t), ExpectedWiki{
+		Type:         "projectWiki",
+		RepositoryID: "00000000-0000-0000-0000-000000000000",
+		Version:      "refs/heads/main",
+		MappedPath:   "/docs",
+	})
+	assert.Equal(t, []string{
+		`type is "codeWiki", expected "projectWiki"`,
+		`mapped repository is "7c2d4e6f-8a9b-4c1d-9e2f-3a4b5c6d7e8f", expected "00000000-0000-0000-0000-000000000000"`,
+		`published branches are [master], expected "refs/heads/main"`,
+		`mapped path is "/", expected "/docs"`,
+	}, problems)
+}
+
+func TestDiffMarkdownAgainstFixturePages(t *testing.T) {
+	expected, err := os.ReadFile(filepath.Join("fixtures", "basic", "pages", "runbook.md"))
+	require.NoError(t, err)
+
+	// The wiki API returns CRLF line endings and drops the trailing newline for some pages
+	published := "# Runbook\r\n\r\n1. Check the pipeline status.\r\n2. Review recent deployments.\r\n\r\n| Environment | Owner    |\r\n|-------------|----------|\r\n| dev         | platform |"
+	assert.Empty(t, DiffMarkdown(string(expected), published))
+
+	assert.Equal(t, []string{
+		`line 3 is "1. Check the build status.", expected "1. Check the pipeline status."`,
+	}, DiffMarkdown(string(expected), "# Runbook\n\n1. Check the build status.\n2. Review recent deployments.\n\n| Environment | Owner    |\n|-------------|----------|\n| dev         | platform |\n"))
+	assert.Equal(t, []string{"content has 1 lines, expected 8"}, DiffMarkdown(string(expected), "# Runbook"))
+}
+
+func TestWikiPageStateReadsETag(t *testing.T) {
+	path := "/Home"
+	content := "# Home"
+	state := wikiPageState(&wiki.WikiPageResponse{
+		ETag: &[]string{`"a1b2c3d4e5f60718293a4b5c6d7e8f9012345678"`},
+		Page: &wiki.WikiPage{Path: &path, Content: &content},
+	})
+
+	assert.Equal(t, "a1b2c3d4e5f60718293a4b5c6d7e8f9012345678", state.ETag)
+	assert.Equal(t, "/Home", state.Path)
+	assert.Equal(t, "# Home", state.Content)
+}
@@ -5,6 +5,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adohelper"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/importtest"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
@@ -19,13 +23,13 @@ func TestBasicAzuredevopsWorkItems(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/basic")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -34,6 +38,25 @@ func TestBasicAzuredevopsWorkItems(t *testing.T) {
 		workItemID := terraform.Output(t, terraformOptions, "work_item_id")
 
 		assert.NotEmpty(t, workItemID)
+
+		// Without inputs the work item starts in the initial state at the project root
+		helper := NewAzureDevOpsHelper(t)
+		projectName, initialState := requireWorkItemDefaults(t, helper, "Task")
+		RequireWorkItem(t, helper, getProjectID(t), adohelper.ParseIntID(t, workItemID), ExpectedWorkItem{
+			Type:          "Task",
+			Title:         fmt.Sprintf("%v-basic", terraformOptions.Vars["work_item_title_prefix"]),
+			State:         initialState,
+			AreaPath:      projectName,
+			IterationPath: projectName,
+			Tags:          []string{},
+			CustomFields:  map[string]string{},
+			ChildIDs:      []int{},
+		})
+	})
+
+	// Adopting the deployed module resources into an empty state must plan no changes
+	test_structure.RunTestStage(t, "import", func() {
+		importtest.RequireStateImportRoundTrip(t, test_structure.LoadTerraformOptions(t, testFolder))
 	})
 }
 
@@ -44,13 +67,13 @@ func TestCompleteAzuredevopsWorkItems(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/complete")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -61,6 +84,33 @@ func TestCompleteAzuredevopsWorkItems(t *testing.T) {
 		assert.NotEmpty(t, workItemIDs)
 		assert.Contains(t, workItemIDs, "parent")
 		assert.Contains(t, workItemIDs, "child")
+
+		helper := NewAzureDevOpsHelper(t)
+		prefix := terraformOptions.Vars["work_item_title_prefix"]
+		parentID := adohelper.ParseIntID(t, workItemIDs["parent"])
+		childID := adohelper.ParseIntID(t, workItemIDs["child"])
+		projectName, initialState := requireWorkItemDefaults(t, helper, "Task")
+		RequireWorkItem(t, helper, getProjectID(t), parentID, ExpectedWorkItem{
+			Type:          "Task",
+			Title:         fmt.Sprintf("%v-parent", prefix),
+			State:         initialState,
+			AreaPath:      projectName,
+			IterationPath: projectName,
+			CustomFields:  map[string]string{},
+			ChildIDs:      []int{childID},
+		})
+		// The child sets area_path and iteration_path to the project root explicitly
+		RequireWorkItem(t, helper, getProjectID(t), childID, ExpectedWorkItem{
+			Type:          "Task",
+			Title:         fmt.Sprintf("%v-child", prefix),
+			State:         initialState,
+			AreaPath:      projectName,
+			IterationPath: projectName,
+			Tags:          []string{"terraform", "child"},
+			CustomFields:  map[string]string{},
+			ParentID:      parentID,
+			ChildIDs:      []int{},
+		})
 	})
 }
 
@@ -71,13 +121,13 @@ func TestSecureAzuredevopsWorkItems(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/secure")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDevOpsDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -86,6 +136,18 @@ func TestSecureAzuredevopsWorkItems(t *testing.T) {
 		workItemID := terraform.Output(t, terraformOptions, "work_item_id")
 
 		assert.NotEmpty(t, workItemID)
+
+		helper := NewAzureDevOpsHelper(t)
+		projectName, initialState := requireWorkItemDefaults(t, helper, "Task")
+		RequireWorkItem(t, helper, getProjectID(t), adohelper.ParseIntID(t, workItemID), ExpectedWorkItem{
+			Type:          "Task",
+			Title:         fmt.Sprintf("%v-secure", terraformOptions.Vars["work_item_title_prefix"]),
+			State:         initialState,
+			AreaPath:      projectName,
+			IterationPath: projectName,
+			Tags:          []string{"terraform", "secure"},
+			CustomFields:  map[string]string{},
+		})
 	})
 }
 
@@ -117,12 +179,9 @@ func getTerraformOptions(t testing.TB, terraformDir string) *terraform.Options {
 			"project_id":             getProjectID(t),
 			"work_item_title_prefix": fmt.Sprintf("ado-work-item-%s", uniqueID),
 		},
-		NoColor: true,
-		RetryableTerraformErrors: map[string]string{
-			".*timeout.*":         "Timeout error - retrying",
-			".*TooManyRequests.*": "Too many requests - retrying",
-		},
-		MaxRetries:         3,
-		TimeBetweenRetries: 10 * time.Second,
+		NoColor:                  true,
+		RetryableTerraformErrors: tfretry.ClassifiedRetryableErrors(),
+		MaxRetries:               3,
+		TimeBetweenRetries:       10 * time.Second,
 	}
 }
@@ -10,6 +10,10 @@ terraform {
 
 provider "azuredevops" {}
 
+data "azuredevops_project" "project" {
+  project_id = var.project_id
+}
+
 module "work_item_parent" {
   source = "../../../"
 
@@ -25,4 +29,8 @@ module "work_item_child" {
   title      = "${var.work_item_title_prefix}-child"
   type       = "Task"
   parent_id  = tonumber(module.work_item_parent.work_item_id)
+  tags       = ["terraform", "child"]
+
+  area_path      = data.azuredevops_project.project.name
+  iteration_path = data.azuredevops_project.project.name
 }
@@ -10,6 +10,10 @@ terraform {
 
 provider "azuredevops" {}
 
+data "azuredevops_project" "project" {
+  project_id = var.project_id
+}
+
 module "azuredevops_work_items" {
   source = "../../../"
 
@@ -19,4 +23,7 @@ module "azuredevops_work_items" {
   type  = "Task"
 
   tags = ["terraform", "secure"]
+
+  area_path      = data.azuredevops_project.project.name
+  iteration_path = data.azuredevops_project.project.name
 }
@@ -0,0 +1,254 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/adohelper"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/workitemtracking"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	workItemParentRelation = "System.LinkTypes.Hierarchy-Reverse"
+	workItemChildRelation  = "System.LinkTypes.Hierarchy-Forward"
+)
+
+// ExpectedWorkItem mirrors the module inputs; empty values are not checked
+type ExpectedWorkItem struct {
+	Type          string
+	Title         string
+	State         string
+	AreaPath      string
+	IterationPath string
+	// Tags nil skips the check; tags compare as a set, case-insensitively
+	Tags []string
+	// CustomFields uses the module keys; the provider stores them as Custom.<key>.
+	// nil skips the check, otherwise Custom.* fields that are not listed are reported.
+	CustomFields map[string]string
+	ParentID     int
+	// ChildIDs nil skips the check, an empty slice requires no children
+	ChildIDs []int
+}
+
+// GetWorkItemE retrieves a work item with its fields and relations
+func (h *AzureDevOpsHelper) GetWorkItemE(projectID string, id int) (*workitemtracking.WorkItem, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), adohelper.RequestTimeout)
+	defer cancel()
+
+	client, err := workitemtracking.NewClient(ctx, h.Connection())
+	if err != nil {
+		return nil, err
+	}
+	return client.GetWorkItem(ctx, workitemtracking.GetWorkItemArgs{
+		Id:      &id,
+		Project: &projectID,
+		Expand:  &workitemtracking.WorkItemExpandValues.Relations,
+	})
+}
+
+// GetInitialStateE returns the state a new work item of the type starts in, which depends on the process
+func (h *AzureDevOpsHelper) GetInitialStateE(projectID, workItemType string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), adohelper.RequestTimeout)
+	defer cancel()
+
+	client, err := workitemtracking.NewClient(ctx, h.Connection())
+	if err != nil {
+		return "", err
+	}
+	states, err := client.GetWorkItemTypeStates(ctx, workitemtracking.GetWorkItemTypeStatesArgs{
+		Project: &projectID,
+		Type:    &workItemType,
+	})
+	if err != nil {
+		return "", err
+	}
+	if states == nil {
+		return "", fmt.Errorf("work item type %s has no states", workItemType)
+	}
+	return InitialWorkItemState(*states)
+}
+
+// InitialWorkItemState picks the first state in the Proposed category, e.g. "To Do" in the Basic
+// process or "New" in Agile
+func InitialWorkItemState(states []workitemtracking.WorkItemStateColor) (string, error) {
+	for _, state := range states {
+		if state.Name != nil && state.Category != nil && strings.EqualFold(*state.Category, "Proposed") {
+			return *state.Name, nil
+		}
+	}
+	return "", fmt.Errorf("no state in the Proposed category among %d states", len(states))
+}
+
+// RequireWorkItem reads the work item back and compares it with the module inputs
+func RequireWorkItem(t testing.TB, helper *AzureDevOpsHelper, projectID string, id int, expected ExpectedWorkItem) *workitemtracking.WorkItem {
+	t.Helper()
+
+	workItem, err := helper.GetWorkItemE(projectID, id)
+	require.NoError(t, err, "Failed to get work item %d", id)
+	require.NotNil(t, workItem, "Work item %d not found", id)
+
+	if problems := CompareWorkItem(workItem, expected); len(problems) > 0 {
+		require.FailNow(t, "Work item does not match the module inputs", "work item %d:\n  %s", id, strings.Join(problems, "\n  "))
+	}
+	return workItem
+}
+
+// requireWorkItemDefaults returns the project name, which new work items use as their area and
+// iteration path, and the initial state of the work item type
+func requireWorkItemDefaults(t testing.TB, helper *AzureDevOpsHelper, workItemType string) (string, string) {
+	t.Helper()
+
+	project := helper.GetProject(t, getProjectID(t))
+	require.NotNil(t, project.Name, "Project %s has no name", getProjectID(t))
+	state, err := helper.GetInitialStateE(getProjectID(t), workItemType)
+	require.NoError(t, err, "Failed to get the initial state of %s", workItemType)
+	return *project.Name, state
+}
+
+// CompareWorkItem reports differences in system fields, tags, custom fields and hierarchy links
+func CompareWorkItem(workItem *workitemtracking.WorkItem, expected ExpectedWorkItem) []string {
+	var problems []string
+	for _, field := range []struct {
+		name     string
+		expected string
+	}{
+		{"System.WorkItemType", expected.Type},
+		{"System.Title", expected.Title},
+		{"System.State", expected.State},
+		{"System.AreaPath", expected.AreaPath},
+		{"System.IterationPath", expected.IterationPath},
+	} {
+		if field.expected == "" {
+			continue
+		}
+		if actual := WorkItemField(workItem, field.name); actual != field.expected {
+			problems = append(problems, fmt.Sprintf("%s is %q, expected %q", field.name, actual, field.expected))
+		}
+	}
+
+	if expected.Tags != nil {
+		actual := WorkItemTags(workItem)
+		if !sameTags(actual, expected.Tags) {
+			problems = append(problems, fmt.Sprintf("tags are %v, expected %v", actual, normalizeTags(expected.Tags)))
+		}
+	}
+
+	keys := make([]string, 0, len(expected.CustomFields))
+	for key := range expected.CustomFields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name := "Custom." + key
+		if actual := WorkItemField(workItem, name); actual != expected.CustomFields[key] {
+			problems = append(problems, fmt.Sprintf("%s is %q, expected %q", name, actual, expected.CustomFields[key]))
+		}
+	}
+	if expected.CustomFields != nil {
+		for _, name := range customFieldNames(workItem) {
+			if _, ok := expected.CustomFields[strings.TrimPrefix(name, "Custom.")]; !ok {
+				problems = append(problems, fmt.Sprintf("%s is %q, expected it not to be set", name, WorkItemField(workItem, name)))
+			}
+		}
+	}
+
+	parents := WorkItemRelatedIDs(workItem, workItemParentRelation)
+	switch {
+	case expected.ParentID != 0 && (len(parents) != 1 || parents[0] != expected.ParentID):
+		problems = append(problems, fmt.Sprintf("parent links are %v, expected [%d]", parents, expected.ParentID))
+	case expected.ParentID == 0 && len(parents) > 0:
+		problems = append(problems, fmt.Sprintf("unexpected parent links %v", parents))
+	}
+
+	if expected.ChildIDs != nil {
+		children := WorkItemRelatedIDs(workItem, workItemChildRelation)
+		want := append([]int(nil), expected.ChildIDs...)
+		sort.Ints(want)
+		if fmt.Sprint(children) != fmt.Sprint(want) {
+			problems = append(problems, fmt.Sprintf("child links are %v, expected %v", children, want))
+		}
+	}
+	return problems
+}
+
+// WorkItemField returns a field value formatted as a string, or "" when the field is not set
+func WorkItemField(workItem *workitemtracking.WorkItem, name string) string {
+	if workItem.Fields == nil {
+		return ""
+	}
+	value, ok := (*workItem.Fields)[name]
+	if !ok || value == nil {
+		return ""
+	}
+	if text, ok := value.(string); ok {
+		return text
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// WorkItemTags splits System.Tags ("a; b") into a sorted list
+func WorkItemTags(workItem *workitemtracking.WorkItem) []string {
+	return normalizeTags(strings.Split(WorkItemField(workItem, "System.Tags"), ";"))
+}
+
+// WorkItemRelatedIDs returns the sorted IDs of work items linked with the relation type
+func WorkItemRelatedIDs(workItem *workitemtracking.WorkItem, relation string) []int {
+	var ids []int
+	if workItem.Relations == nil {
+		return ids
+	}
+	for _, link := range *workItem.Relations {
+		if link.Rel == nil || *link.Rel != relation || link.Url == nil {
+			continue
+		}
+		if id, err := strconv.Atoi(path.Base(*link.Url)); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func customFieldNames(workItem *workitemtracking.WorkItem) []string {
+	var names []string
+	if workItem.Fields == nil {
+		return names
+	}
+	for name, value := range *workItem.Fields {
+		if strings.HasPrefix(name, "Custom.") && value != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func normalizeTags(tags []string) []string {
+	normalized := []string{}
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			normalized = append(normalized, tag)
+		}
+	}
+	sort.Slice(normalized, func(i, j int) bool { return strings.ToLower(normalized[i]) < strings.ToLower(normalized[j]) })
+	return normalized
+}
+
+func sameTags(actual, expected []string) bool {
+	expected = normalizeTags(expected)
+	if len(actual) != len(expected) {
+		return false
+	}
+	for i := range actual {
+		if !strings.EqualFold(actual[i], expected[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+package test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/workitemtracking"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadTestWorkItem(t *testing.T) *workitemtracking.WorkItem {
+	content, err := os.ReadFile(filepath.Join("testdata", "work_item.json"))
+	require.NoError(t, err)
+
+	var workItem workitemtracking.WorkItem
+	require.NoError(t, json.Unmarshal(content, &workItem))
+	return &workItem
+}
+
+func testExpectedWorkItem() ExpectedWorkItem {
+	return ExpectedWorkItem{
+		Type:          "Task",
+		Title:         "ado-work-item-abc123-child",
+		State:         "To Do",
+		AreaPath:      "platform\\Infrastructure",
+		IterationPath: "platform\\Sprint 42",
+		Tags:          []string{"Terraform", "child"},
+		CustomFields:  map[string]string{"CostCenter": "CC-1042", "Estimate": "3.5"},
+		ParentID:      4811,
+		ChildIDs:      []int{4815},
+	}
+}
+
+func TestCompareWorkItemMatches(t *testing.T) {
+	assert.Empty(t, CompareWorkItem(loadTestWorkItem(t), testExpectedWorkItem()))
+}
+
+func TestCompareWorkItemDifferences(t *testing.T) {
+	expected := testExpectedWorkItem()
+	expected.State = "Done"
+	expected.IterationPath = "platform\\Sprint 43"
+	expected.Tags = []string{"terraform"}
+	expected.CustomFields = map[string]string{"CostCenter": "CC-2000", "Owner": "platform"}
+	expected.ParentID = 4810
+	expected.ChildIDs = []int{}
+
+	assert.Equal(t, []string{
+		`System.State is "To Do", expected "Done"`,
+		`System.IterationPath is "platform\\Sprint 42", expected "platform\\Sprint 43"`,
+		"tags are [child terraform], expected [terraform]",
+		`Custom.CostCenter is "CC-1042", expected "CC-2000"`,
+		`Custom.Owner is "", expected "platform"`,
+		`Custom.Estimate is "3.5", expected it not to be set`,
+		"parent links are [4811], expected [4810]",
+		"child links are [4815], expected []",
+	}, CompareWorkItem(loadTestWorkItem(t), expected))
+}
+
+func TestCompareWorkItemReportsUnexpectedParent(t *testing.T) {
+	expected := testExpectedWorkItem()
+	expected.ParentID = 0
+
+	assert.Equal(t, []string{"unexpected parent links [4811]"}, CompareWorkItem(loadTestWorkItem(t), expected))
+}
+
+func TestWorkItemRelatedIDsIgnoresOtherLinkTypes(t *testing.T) {
+	workItem := loadTestWorkItem(t)
+
+	assert.Equal(t, []int{4811}, WorkItemRelatedIDs(workItem, workItemParentRelation))
+	assert.Equal(t, []int{4815}, WorkItemRelatedIDs(workItem, workItemChildRelation))
+	assert.Equal(t, []string{"child", "terraform"}, WorkItemTags(workItem))
+	assert.Equal(t, "2", WorkItemField(workItem, "Microsoft.VSTS.Common.Priority"))
+}
+
+func TestCompareWorkItemWithoutCustomFields(t *testing.T) {
+	expected := testExpectedWorkItem()
+	expected.CustomFields = map[string]string{}
+
+	assert.Equal(t, []string{
+		`Custom.CostCenter is "CC-1042", expected it not to be set`,
+		`Custom.Estimate is "3.5", expected it not to be set`,
+	}, CompareWorkItem(loadTestWorkItem(t), expected))
+
+	expected.CustomFields = nil
+	assert.Empty(t, CompareWorkItem(loadTestWorkItem(t), expected))
+}
+
+func TestInitialWorkItemState(t *testing.T) {
+	state := func(name, category string) workitemtracking.WorkItemStateColor {
+		return workitemtracking.WorkItemStateColor{Name: &name, Category: &category}
+	}
+
+	initial, err := InitialWorkItemState([]workitemtracking.WorkItemStateColor{
+		state("Active", "InProgress"),
+		state("New", "Proposed"),
+		state("Closed", "Completed"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "New", initial)
+
+	_, err = InitialWorkItemState([]workitemtracking.WorkItemStateColor{state("Done", "Completed")})
+	assert.Error(t, err)
+}
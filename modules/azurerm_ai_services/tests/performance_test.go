@@ -5,7 +5,8 @@ import (
 	"testing"
 	"time"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/require"
 )
@@ -21,10 +22,10 @@ func BenchmarkAiServicesCreationSimple(b *testing.B) {
 		terraformOptions.Vars["random_suffix"] = fmt.Sprintf("bench%d%s", i, terraformOptions.Vars["random_suffix"].(string)[:5])
 		b.StartTimer()
 
-		terraform.InitAndApply(b, terraformOptions)
+		tfretry.InitAndApplyWithRetry(b, terraformOptions)
 
 		b.StopTimer()
-		terraform.Destroy(b, terraformOptions)
+		tfretry.DestroyWithRetry(b, terraformOptions)
 		b.StartTimer()
 	}
 }
@@ -39,10 +40,10 @@ func TestAiServicesCreationTime(t *testing.T) {
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/basic")
 	terraformOptions := getTerraformOptions(t, testFolder)
 
-	defer terraform.Destroy(t, terraformOptions)
+	defer destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 
 	start := time.Now()
-	terraform.InitAndApply(t, terraformOptions)
+	tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	duration := time.Since(start)
 
 	maxDuration := 30 * time.Minute
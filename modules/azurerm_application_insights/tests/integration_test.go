@@ -6,6 +6,9 @@ import (
 	// Azure SDK imports - add specific ones for your resource type
 	// Example: "github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/storage/armstorage"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/compliance"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/assert"
@@ -23,14 +26,14 @@ func TestApplicationInsightsFullIntegration(t *testing.T) {
 	// Setup stages
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	// Deploy infrastructure
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	// Validate all components
@@ -141,14 +144,14 @@ func TestApplicationInsightsSecurityConfiguration(t *testing.T) {
 	// Setup stages
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	// Deploy infrastructure
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	// Validate security settings
@@ -179,10 +182,10 @@ func TestApplicationInsightsLifecycle(t *testing.T) {
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/basic")
 	terraformOptions := getTerraformOptions(t, testFolder)
 
-	defer terraform.Destroy(t, terraformOptions)
+	defer destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 
 	// Initial deployment
-	terraform.InitAndApply(t, terraformOptions)
+	tfretry.InitAndApplyWithRetry(t, terraformOptions)
 
 	// Get initial state
 	resourceName := terraform.Output(t, terraformOptions, "application_insights_name")
@@ -205,7 +208,7 @@ func TestApplicationInsightsLifecycle(t *testing.T) {
 	assert.Equal(t, resourceID, updatedResourceID, "Resource ID should remain the same after update")
 
 	// Test idempotency - apply again without changes
-	terraform.Apply(t, terraformOptions)
+	tfretry.ApplyWithRetry(t, terraformOptions)
 }
 
 // TestApplicationInsightsCompliance tests compliance-related features
@@ -215,9 +218,13 @@ func TestApplicationInsightsCompliance(t *testing.T) {
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/secure")
 	terraformOptions := getTerraformOptions(t, testFolder)
 
-	defer terraform.Destroy(t, terraformOptions)
+	// Rules from security-policies/compliance are checked on the plan and on the applied state
+	compliance.AssertPlan(t, terraformOptions, "azurerm_application_insights")
 
-	terraform.InitAndApply(t, terraformOptions)
+	defer destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
+
+	tfretry.InitAndApplyWithRetry(t, terraformOptions)
+	compliance.AssertState(t, terraformOptions, "azurerm_application_insights")
 
 	resourceName := terraform.Output(t, terraformOptions, "application_insights_name")
 	resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
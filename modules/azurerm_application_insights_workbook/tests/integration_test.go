@@ -3,6 +3,8 @@ package test
 import (
 	"testing"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/assert"
@@ -18,13 +20,13 @@ func TestApplicationInsightsWorkbookFullIntegration(t *testing.T) {
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/complete")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -53,14 +55,14 @@ func TestApplicationInsightsWorkbookLifecycle(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/basic")
 	terraformOptions := getTerraformOptions(t, testFolder)
-	defer terraform.Destroy(t, terraformOptions)
+	defer destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 
-	terraform.InitAndApply(t, terraformOptions)
+	tfretry.InitAndApplyWithRetry(t, terraformOptions)
 
 	resourceID := terraform.Output(t, terraformOptions, "application_insights_workbook_id")
 	assert.NotEmpty(t, resourceID)
 
-	terraform.Apply(t, terraformOptions)
+	tfretry.ApplyWithRetry(t, terraformOptions)
 	updatedResourceID := terraform.Output(t, terraformOptions, "application_insights_workbook_id")
 	assert.Equal(t, resourceID, updatedResourceID)
 }
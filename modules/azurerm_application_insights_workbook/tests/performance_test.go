@@ -5,7 +5,8 @@ import (
 	"testing"
 	"time"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/require"
 )
@@ -21,14 +22,17 @@ func BenchmarkApplicationInsightsWorkbookCreationSimple(b *testing.B) {
 		b.StartTimer()
 
 		start := time.Now()
-		terraform.InitAndApply(b, terraformOptions)
+		tfretry.InitAndApplyWithRetry(b, terraformOptions)
 		creationTime := time.Since(start)
 
 		b.StopTimer()
-		terraform.Destroy(b, terraformOptions)
+		destroyStart := time.Now()
+		tfretry.DestroyWithRetry(b, terraformOptions)
+		destroyTime := time.Since(destroyStart)
 		b.StartTimer()
 
 		b.ReportMetric(float64(creationTime.Milliseconds()), "creation_ms")
+		b.ReportMetric(float64(destroyTime.Milliseconds()), "destroy_ms")
 	}
 }
 
@@ -46,12 +50,15 @@ func BenchmarkApplicationInsightsWorkbookParallelCreation(b *testing.B) {
 					terraformOptions.Vars["random_suffix"] = fmt.Sprintf("par%d%d%s", parallel, i, terraformOptions.Vars["random_suffix"].(string)[:5])
 
 					start := time.Now()
-					terraform.InitAndApply(b, terraformOptions)
+					tfretry.InitAndApplyWithRetry(b, terraformOptions)
 					creationTime := time.Since(start)
 
-					terraform.Destroy(b, terraformOptions)
+					destroyStart := time.Now()
+					tfretry.DestroyWithRetry(b, terraformOptions)
+					destroyTime := time.Since(destroyStart)
 
 					b.ReportMetric(float64(creationTime.Milliseconds()), "creation_ms")
+					b.ReportMetric(float64(destroyTime.Milliseconds()), "destroy_ms")
 					i++
 				}
 			})
@@ -68,10 +75,10 @@ func TestApplicationInsightsWorkbookCreationTime(t *testing.T) {
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/basic")
 	terraformOptions := getTerraformOptions(t, testFolder)
 
-	defer terraform.Destroy(t, terraformOptions)
+	defer destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 
 	start := time.Now()
-	terraform.InitAndApply(t, terraformOptions)
+	tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	duration := time.Since(start)
 
 	maxDuration := 5 * time.Minute
@@ -88,9 +95,9 @@ func TestApplicationInsightsWorkbookUpdatePerformance(t *testing.T) {
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/basic")
 	terraformOptions := getTerraformOptions(t, testFolder)
 
-	defer terraform.Destroy(t, terraformOptions)
+	defer destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 
-	terraform.InitAndApply(t, terraformOptions)
+	tfretry.InitAndApplyWithRetry(t, terraformOptions)
 
 	terraformOptions.Vars["tags"] = map[string]interface{}{
 		"Environment": "Test",
@@ -98,7 +105,7 @@ func TestApplicationInsightsWorkbookUpdatePerformance(t *testing.T) {
 	}
 
 	start := time.Now()
-	terraform.Apply(t, terraformOptions)
+	tfretry.ApplyWithRetry(t, terraformOptions)
 	updateTime := time.Since(start)
 
 	require.LessOrEqual(t, updateTime, 2*time.Minute,
@@ -114,10 +121,10 @@ func TestApplicationInsightsWorkbookDestroyPerformance(t *testing.T) {
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/basic")
 	terraformOptions := getTerraformOptions(t, testFolder)
 
-	terraform.InitAndApply(t, terraformOptions)
+	tfretry.InitAndApplyWithRetry(t, terraformOptions)
 
 	start := time.Now()
-	terraform.Destroy(t, terraformOptions)
+	destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	destroyTime := time.Since(start)
 
 	require.LessOrEqual(t, destroyTime, 3*time.Minute,
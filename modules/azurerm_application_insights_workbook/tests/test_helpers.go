@@ -93,15 +93,3 @@ func WaitForResourceDeletion(ctx context.Context, checkFunc func() (bool, error)
 		}
 	}
 }
-
-// GenerateResourceName generates a unique resource name for testing
-func GenerateResourceName(prefix string, uniqueID string) string {
-	// Ensure the name meets Azure naming requirements
-	name := fmt.Sprintf("%s%s", prefix, uniqueID)
-	// Remove any invalid characters and ensure length limits
-	name = strings.ReplaceAll(name, "-", "")
-	if len(name) > 24 {
-		name = name[:24]
-	}
-	return strings.ToLower(name)
-}
@@ -3,6 +3,7 @@ package test
 import (
 	"testing"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/assert"
@@ -24,7 +25,7 @@ func TestBastionHostFullIntegration(t *testing.T) {
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -48,11 +49,11 @@ func TestBastionHostLifecycle(t *testing.T) {
 
 	defer destroyWithRetry(t, terraformOptions)
 
-	terraform.InitAndApply(t, terraformOptions)
+	tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	initialID := terraform.Output(t, terraformOptions, "bastion_host_id")
 	assert.NotEmpty(t, initialID)
 
-	terraform.Apply(t, terraformOptions)
+	tfretry.ApplyWithRetry(t, terraformOptions)
 	updatedID := terraform.Output(t, terraformOptions, "bastion_host_id")
 	assert.Equal(t, initialID, updatedID)
 }
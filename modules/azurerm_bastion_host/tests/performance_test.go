@@ -5,7 +5,7 @@ import (
 	"testing"
 	"time"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/require"
 )
@@ -21,7 +21,7 @@ func BenchmarkBastionHostCreationSimple(b *testing.B) {
 		terraformOptions.Vars["random_suffix"] = fmt.Sprintf("bench%d%s", i, terraformOptions.Vars["random_suffix"].(string)[:5])
 		b.StartTimer()
 
-		terraform.InitAndApply(b, terraformOptions)
+		tfretry.InitAndApplyWithRetry(b, terraformOptions)
 
 		b.StopTimer()
 		destroyWithRetry(b, terraformOptions)
@@ -42,7 +42,7 @@ func TestBastionHostCreationTime(t *testing.T) {
 	defer destroyWithRetry(t, terraformOptions)
 
 	start := time.Now()
-	terraform.InitAndApply(t, terraformOptions)
+	tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	duration := time.Since(start)
 
 	maxDuration := 30 * time.Minute
@@ -10,6 +10,7 @@ import (
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/require"
@@ -88,18 +89,6 @@ func WaitForResourceDeletion(ctx context.Context, checkFunc func() (bool, error)
 	}
 }
 
-// GenerateResourceName generates a unique resource name for testing
-func GenerateResourceName(prefix string, uniqueID string) string {
-	// Ensure the name meets Azure naming requirements
-	name := fmt.Sprintf("%s%s", prefix, uniqueID)
-	// Remove any invalid characters and ensure length limits
-	name = strings.ReplaceAll(name, "-", "")
-	if len(name) > 24 {
-		name = name[:24]
-	}
-	return strings.ToLower(name)
-}
-
 // destroyWithRetry handles Azure eventual consistency when Bastion releases subnet/PIP references.
 func destroyWithRetry(t testing.TB, terraformOptions *terraform.Options) {
 	t.Helper()
@@ -110,7 +99,7 @@ func destroyWithRetry(t testing.TB, terraformOptions *terraform.Options) {
 	)
 
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		_, err := terraform.DestroyE(t, terraformOptions)
+		_, err := tfretry.DestroyWithRetryE(t, terraformOptions)
 		if err == nil {
 			return
 		}
@@ -5,7 +5,8 @@ import (
 	"testing"
 	"time"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/require"
 )
@@ -23,14 +24,17 @@ func BenchmarkCognitiveAccountCreationSimple(b *testing.B) {
 		b.StartTimer()
 
 		start := time.Now()
-		terraform.InitAndApply(b, terraformOptions)
+		tfretry.InitAndApplyWithRetry(b, terraformOptions)
 		creationTime := time.Since(start)
 
 		b.StopTimer()
-		terraform.Destroy(b, terraformOptions)
+		destroyStart := time.Now()
+		tfretry.DestroyWithRetry(b, terraformOptions)
+		destroyTime := time.Since(destroyStart)
 		b.StartTimer()
 
 		b.ReportMetric(float64(creationTime.Milliseconds()), "creation_ms")
+		b.ReportMetric(float64(destroyTime.Milliseconds()), "destroy_ms")
 	}
 }
 
@@ -75,14 +79,17 @@ func BenchmarkCognitiveAccountCreationWithFeatures(b *testing.B) {
 				b.StartTimer()
 
 				start := time.Now()
-				terraform.InitAndApply(b, terraformOptions)
+				tfretry.InitAndApplyWithRetry(b, terraformOptions)
 				creationTime := time.Since(start)
 
 				b.StopTimer()
-				terraform.Destroy(b, terraformOptions)
+				destroyStart := time.Now()
+				tfretry.DestroyWithRetry(b, terraformOptions)
+				destroyTime := time.Since(destroyStart)
 				b.StartTimer()
 
 				b.ReportMetric(float64(creationTime.Milliseconds()), "creation_ms")
+				b.ReportMetric(float64(destroyTime.Milliseconds()), "destroy_ms")
 			}
 		})
 	}
@@ -108,14 +115,17 @@ func BenchmarkCognitiveAccountCreationWithScale(b *testing.B) {
 				b.StartTimer()
 
 				start := time.Now()
-				terraform.InitAndApply(b, terraformOptions)
+				tfretry.InitAndApplyWithRetry(b, terraformOptions)
 				creationTime := time.Since(start)
 
 				b.StopTimer()
-				terraform.Destroy(b, terraformOptions)
+				destroyStart := time.Now()
+				tfretry.DestroyWithRetry(b, terraformOptions)
+				destroyTime := time.Since(destroyStart)
 				b.StartTimer()
 
 				b.ReportMetric(float64(creationTime.Milliseconds()), "creation_ms")
+				b.ReportMetric(float64(destroyTime.Milliseconds()), "destroy_ms")
 				b.ReportMetric(float64(count), "tag_count")
 			}
 		})
@@ -138,12 +148,15 @@ func BenchmarkCognitiveAccountParallelCreation(b *testing.B) {
 					terraformOptions.Vars["random_suffix"] = fmt.Sprintf("par%d%d%s", parallel, i, terraformOptions.Vars["random_suffix"].(string)[:5])
 
 					start := time.Now()
-					terraform.InitAndApply(b, terraformOptions)
+					tfretry.InitAndApplyWithRetry(b, terraformOptions)
 					creationTime := time.Since(start)
 
-					terraform.Destroy(b, terraformOptions)
+					destroyStart := time.Now()
+					tfretry.DestroyWithRetry(b, terraformOptions)
+					destroyTime := time.Since(destroyStart)
 
 					b.ReportMetric(float64(creationTime.Milliseconds()), "creation_ms")
+					b.ReportMetric(float64(destroyTime.Milliseconds()), "destroy_ms")
 					i++
 				}
 			})
@@ -161,10 +174,10 @@ func TestCognitiveAccountCreationTime(t *testing.T) {
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/openai-basic")
 	terraformOptions := getTerraformOptions(t, testFolder)
 
-	defer terraform.Destroy(t, terraformOptions)
+	defer destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 
 	start := time.Now()
-	terraform.InitAndApply(t, terraformOptions)
+	tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	duration := time.Since(start)
 
 	// cognitive_account creation should complete within 5 minutes
@@ -193,11 +206,11 @@ func TestCognitiveAccountScaling(t *testing.T) {
 		terraformOptions.Vars["random_suffix"] = fmt.Sprintf("scale%d%s", i, terraformOptions.Vars["random_suffix"].(string)[:5])
 
 		start := time.Now()
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 		creationTimes[i] = time.Since(start)
 
 		// Cleanup immediately to avoid hitting limits
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 
 		t.Logf("Cognitive Account instance %d created in %v", i, creationTimes[i])
 	}
@@ -227,10 +240,10 @@ func TestCognitiveAccountUpdatePerformance(t *testing.T) {
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/openai-basic")
 	terraformOptions := getTerraformOptions(t, testFolder)
 
-	defer terraform.Destroy(t, terraformOptions)
+	defer destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 
 	// Initial deployment
-	terraform.InitAndApply(t, terraformOptions)
+	tfretry.InitAndApplyWithRetry(t, terraformOptions)
 
 	// Measure update times for different changes
 	updateScenarios := []struct {
@@ -257,7 +270,7 @@ func TestCognitiveAccountUpdatePerformance(t *testing.T) {
 			}
 
 			start := time.Now()
-			terraform.Apply(t, terraformOptions)
+			tfretry.ApplyWithRetry(t, terraformOptions)
 			updateTime := time.Since(start)
 
 			t.Logf("%s completed in %v", scenario.name, updateTime)
@@ -280,11 +293,11 @@ func TestCognitiveAccountDestroyPerformance(t *testing.T) {
 	terraformOptions := getTerraformOptions(t, testFolder)
 
 	// Create resource
-	terraform.InitAndApply(t, terraformOptions)
+	tfretry.InitAndApplyWithRetry(t, terraformOptions)
 
 	// Measure destroy time
 	start := time.Now()
-	terraform.Destroy(t, terraformOptions)
+	destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	destroyTime := time.Since(start)
 
 	t.Logf("Cognitive Account destroyed in %v", destroyTime)
@@ -5,7 +5,8 @@ import (
 	"testing"
 	"time"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/require"
 )
@@ -23,14 +24,17 @@ func BenchmarkEventhubCreationSimple(b *testing.B) {
 		b.StartTimer()
 
 		start := time.Now()
-		terraform.InitAndApply(b, terraformOptions)
+		tfretry.InitAndApplyWithRetry(b, terraformOptions)
 		creationTime := time.Since(start)
 
 		b.StopTimer()
-		terraform.Destroy(b, terraformOptions)
+		destroyStart := time.Now()
+		tfretry.DestroyWithRetry(b, terraformOptions)
+		destroyTime := time.Since(destroyStart)
 		b.StartTimer()
 
 		b.ReportMetric(float64(creationTime.Milliseconds()), "creation_ms")
+		b.ReportMetric(float64(destroyTime.Milliseconds()), "destroy_ms")
 	}
 }
 
@@ -86,14 +90,17 @@ func BenchmarkEventhubCreationWithFeatures(b *testing.B) {
 				b.StartTimer()
 
 				start := time.Now()
-				terraform.InitAndApply(b, terraformOptions)
+				tfretry.InitAndApplyWithRetry(b, terraformOptions)
 				creationTime := time.Since(start)
 
 				b.StopTimer()
-				terraform.Destroy(b, terraformOptions)
+				destroyStart := time.Now()
+				tfretry.DestroyWithRetry(b, terraformOptions)
+				destroyTime := time.Since(destroyStart)
 				b.StartTimer()
 
 				b.ReportMetric(float64(creationTime.Milliseconds()), "creation_ms")
+				b.ReportMetric(float64(destroyTime.Milliseconds()), "destroy_ms")
 			}
 		})
 	}
@@ -121,14 +128,17 @@ func BenchmarkEventhubCreationWithScale(b *testing.B) {
 				b.StartTimer()
 
 				start := time.Now()
-				terraform.InitAndApply(b, terraformOptions)
+				tfretry.InitAndApplyWithRetry(b, terraformOptions)
 				creationTime := time.Since(start)
 
 				b.StopTimer()
-				terraform.Destroy(b, terraformOptions)
+				destroyStart := time.Now()
+				tfretry.DestroyWithRetry(b, terraformOptions)
+				destroyTime := time.Since(destroyStart)
 				b.StartTimer()
 
 				b.ReportMetric(float64(creationTime.Milliseconds()), "creation_ms")
+				b.ReportMetric(float64(destroyTime.Milliseconds()), "destroy_ms")
 				b.ReportMetric(float64(count), "scale_count")
 			}
 		})
@@ -151,12 +161,15 @@ func BenchmarkEventhubParallelCreation(b *testing.B) {
 					terraformOptions.Vars["random_suffix"] = fmt.Sprintf("par%d%d%s", parallel, i, terraformOptions.Vars["random_suffix"].(string)[:5])
 
 					start := time.Now()
-					terraform.InitAndApply(b, terraformOptions)
+					tfretry.InitAndApplyWithRetry(b, terraformOptions)
 					creationTime := time.Since(start)
 
-					terraform.Destroy(b, terraformOptions)
+					destroyStart := time.Now()
+					tfretry.DestroyWithRetry(b, terraformOptions)
+					destroyTime := time.Since(destroyStart)
 
 					b.ReportMetric(float64(creationTime.Milliseconds()), "creation_ms")
+					b.ReportMetric(float64(destroyTime.Milliseconds()), "destroy_ms")
 					i++
 				}
 			})
@@ -174,10 +187,10 @@ func TestEventhubCreationTime(t *testing.T) {
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "../..", "azurerm_eventhub/tests/fixtures/basic")
 	terraformOptions := getTerraformOptions(t, testFolder)
 
-	defer terraform.Destroy(t, terraformOptions)
+	defer destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 
 	start := time.Now()
-	terraform.InitAndApply(t, terraformOptions)
+	tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	duration := time.Since(start)
 
 	// eventhub creation should complete within 5 minutes
@@ -206,11 +219,11 @@ func TestEventhubScaling(t *testing.T) {
 		terraformOptions.Vars["random_suffix"] = fmt.Sprintf("scale%d%s", i, terraformOptions.Vars["random_suffix"].(string)[:5])
 
 		start := time.Now()
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 		creationTimes[i] = time.Since(start)
 
 		// Cleanup immediately to avoid hitting limits
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 
 		t.Logf("Event Hub instance %d created in %v", i, creationTimes[i])
 	}
@@ -240,10 +253,10 @@ func TestEventhubUpdatePerformance(t *testing.T) {
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "../..", "azurerm_eventhub/tests/fixtures/basic")
 	terraformOptions := getTerraformOptions(t, testFolder)
 
-	defer terraform.Destroy(t, terraformOptions)
+	defer destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 
 	// Initial deployment
-	terraform.InitAndApply(t, terraformOptions)
+	tfretry.InitAndApplyWithRetry(t, terraformOptions)
 
 	// Measure update times for different changes
 	updateScenarios := []struct {
@@ -273,7 +286,7 @@ func TestEventhubUpdatePerformance(t *testing.T) {
 			}
 
 			start := time.Now()
-			terraform.Apply(t, terraformOptions)
+			tfretry.ApplyWithRetry(t, terraformOptions)
 			updateTime := time.Since(start)
 
 			t.Logf("%s completed in %v", scenario.name, updateTime)
@@ -296,11 +309,11 @@ func TestEventhubDestroyPerformance(t *testing.T) {
 	terraformOptions := getTerraformOptions(t, testFolder)
 
 	// Create resource
-	terraform.InitAndApply(t, terraformOptions)
+	tfretry.InitAndApplyWithRetry(t, terraformOptions)
 
 	// Measure destroy time
 	start := time.Now()
-	terraform.Destroy(t, terraformOptions)
+	destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	destroyTime := time.Since(start)
 
 	t.Logf("Event Hub destroyed in %v", destroyTime)
@@ -5,7 +5,8 @@ import (
 	"testing"
 	"time"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/require"
 )
@@ -23,14 +24,17 @@ func BenchmarkEventhubNamespaceCreationSimple(b *testing.B) {
 		b.StartTimer()
 
 		start := time.Now()
-		terraform.InitAndApply(b, terraformOptions)
+		tfretry.InitAndApplyWithRetry(b, terraformOptions)
 		creationTime := time.Since(start)
 
 		b.StopTimer()
-		terraform.Destroy(b, terraformOptions)
+		destroyStart := time.Now()
+		tfretry.DestroyWithRetry(b, terraformOptions)
+		destroyTime := time.Since(destroyStart)
 		b.StartTimer()
 
 		b.ReportMetric(float64(creationTime.Milliseconds()), "creation_ms")
+		b.ReportMetric(float64(destroyTime.Milliseconds()), "destroy_ms")
 	}
 }
 
@@ -87,14 +91,17 @@ func BenchmarkEventhubNamespaceCreationWithFeatures(b *testing.B) {
 				b.StartTimer()
 
 				start := time.Now()
-				terraform.InitAndApply(b, terraformOptions)
+				tfretry.InitAndApplyWithRetry(b, terraformOptions)
 				creationTime := time.Since(start)
 
 				b.StopTimer()
-				terraform.Destroy(b, terraformOptions)
+				destroyStart := time.Now()
+				tfretry.DestroyWithRetry(b, terraformOptions)
+				destroyTime := time.Since(destroyStart)
 				b.StartTimer()
 
 				b.ReportMetric(float64(creationTime.Milliseconds()), "creation_ms")
+				b.ReportMetric(float64(destroyTime.Milliseconds()), "destroy_ms")
 			}
 		})
 	}
@@ -122,14 +129,17 @@ func BenchmarkEventhubNamespaceCreationWithScale(b *testing.B) {
 				b.StartTimer()
 
 				start := time.Now()
-				terraform.InitAndApply(b, terraformOptions)
+				tfretry.InitAndApplyWithRetry(b, terraformOptions)
 				creationTime := time.Since(start)
 
 				b.StopTimer()
-				terraform.Destroy(b, terraformOptions)
+				destroyStart := time.Now()
+				tfretry.DestroyWithRetry(b, terraformOptions)
+				destroyTime := time.Since(destroyStart)
 				b.StartTimer()
 
 				b.ReportMetric(float64(creationTime.Milliseconds()), "creation_ms")
+				b.ReportMetric(float64(destroyTime.Milliseconds()), "destroy_ms")
 				b.ReportMetric(float64(count), "scale_count")
 			}
 		})
@@ -152,12 +162,15 @@ func BenchmarkEventhubNamespaceParallelCreation(b *testing.B) {
 					terraformOptions.Vars["random_suffix"] = fmt.Sprintf("par%d%d%s", parallel, i, terraformOptions.Vars["random_suffix"].(string)[:5])
 
 					start := time.Now()
-					terraform.InitAndApply(b, terraformOptions)
+					tfretry.InitAndApplyWithRetry(b, terraformOptions)
 					creationTime := time.Since(start)
 
-					terraform.Destroy(b, terraformOptions)
+					destroyStart := time.Now()
+					tfretry.DestroyWithRetry(b, terraformOptions)
+					destroyTime := time.Since(destroyStart)
 
 					b.ReportMetric(float64(creationTime.Milliseconds()), "creation_ms")
+					b.ReportMetric(float64(destroyTime.Milliseconds()), "destroy_ms")
 					i++
 				}
 			})
@@ -175,10 +188,10 @@ func TestEventhubNamespaceCreationTime(t *testing.T) {
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/basic")
 	terraformOptions := getTerraformOptions(t, testFolder)
 
-	defer terraform.Destroy(t, terraformOptions)
+	defer destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 
 	start := time.Now()
-	terraform.InitAndApply(t, terraformOptions)
+	tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	duration := time.Since(start)
 
 	// eventhub_namespace creation should complete within 5 minutes
@@ -207,11 +220,11 @@ func TestEventhubNamespaceScaling(t *testing.T) {
 		terraformOptions.Vars["random_suffix"] = fmt.Sprintf("scale%d%s", i, terraformOptions.Vars["random_suffix"].(string)[:5])
 
 		start := time.Now()
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 		creationTimes[i] = time.Since(start)
 
 		// Cleanup immediately to avoid hitting limits
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 
 		t.Logf("Event Hub Namespace instance %d created in %v", i, creationTimes[i])
 	}
@@ -241,10 +254,10 @@ func TestEventhubNamespaceUpdatePerformance(t *testing.T) {
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/basic")
 	terraformOptions := getTerraformOptions(t, testFolder)
 
-	defer terraform.Destroy(t, terraformOptions)
+	defer destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 
 	// Initial deployment
-	terraform.InitAndApply(t, terraformOptions)
+	tfretry.InitAndApplyWithRetry(t, terraformOptions)
 
 	// Measure update times for different changes
 	updateScenarios := []struct {
@@ -277,7 +290,7 @@ func TestEventhubNamespaceUpdatePerformance(t *testing.T) {
 			}
 
 			start := time.Now()
-			terraform.Apply(t, terraformOptions)
+			tfretry.ApplyWithRetry(t, terraformOptions)
 			updateTime := time.Since(start)
 
 			t.Logf("%s completed in %v", scenario.name, updateTime)
@@ -300,11 +313,11 @@ func TestEventhubNamespaceDestroyPerformance(t *testing.T) {
 	terraformOptions := getTerraformOptions(t, testFolder)
 
 	// Create resource
-	terraform.InitAndApply(t, terraformOptions)
+	tfretry.InitAndApplyWithRetry(t, terraformOptions)
 
 	// Measure destroy time
 	start := time.Now()
-	terraform.Destroy(t, terraformOptions)
+	destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	destroyTime := time.Since(start)
 
 	t.Logf("Event Hub Namespace destroyed in %v", destroyTime)
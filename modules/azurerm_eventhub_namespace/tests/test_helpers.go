@@ -100,16 +100,6 @@ func WaitForResourceDeletion(ctx context.Context, checkFunc func() (bool, error)
 	}
 }
 
-// GenerateResourceName generates a unique resource name for testing
-func GenerateResourceName(prefix string, uniqueID string) string {
-	name := fmt.Sprintf("%s%s", prefix, uniqueID)
-	name = strings.ReplaceAll(name, "-", "")
-	if len(name) > 50 {
-		name = name[:50]
-	}
-	return strings.ToLower(name)
-}
-
 // OutputBool reads a Terraform output and parses it as a boolean.
 func OutputBool(t testing.TB, terraformOptions *terraform.Options, name string) bool {
 	value := terraform.Output(t, terraformOptions, name)
@@ -49,11 +49,29 @@ module "kubernetes_cluster" {
     vnet_subnet_id = azurerm_subnet.test.id
   }
 
+  node_pools = [
+    {
+      name                 = "user1"
+      vm_size              = "Standard_D2s_v3"
+      mode                 = "User"
+      auto_scaling_enabled = true
+      min_count            = 1
+      max_count            = 2
+      zones                = ["1", "2"]
+      vnet_subnet_id       = azurerm_subnet.test.id
+      node_labels = {
+        workload = "user"
+      }
+      node_taints = ["workload=user:NoSchedule"]
+    }
+  ]
+
   network_profile = {
     network_plugin = "azure"
     network_policy = "azure"
     service_cidr   = "172.16.0.0/16"
     dns_service_ip = "172.16.0.10"
+    outbound_type  = "loadBalancer"
   }
 
   tags = {
@@ -11,4 +11,10 @@ output "kubernetes_cluster_name" {
 output "resource_group_name" {
   description = "The name of the resource group in which the cluster was created."
   value       = azurerm_resource_group.test.name
-}
\ No newline at end of file
+}
+
+output "kube_config_raw" {
+  description = "Raw kubeconfig used by the tests to reach the cluster API."
+  value       = module.kubernetes_cluster.kube_config_raw
+  sensitive   = true
+}
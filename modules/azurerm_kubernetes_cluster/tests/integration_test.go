@@ -3,7 +3,9 @@ package test
 import (
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/cassette"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/assert"
 )
@@ -16,25 +18,25 @@ func TestKubernetesClusterLifecycle(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/basic")
 
-	defer test_structure.RunTestStage(t, "cleanup", func() {
+	defer cassette.RunLiveStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	// Deploy initial version
-	test_structure.RunTestStage(t, "deploy", func() {
+	cassette.RunLiveStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	// Validate initial deployment and then update
 	test_structure.RunTestStage(t, "validate_and_update", func() {
-		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+		terraformOptions := cassette.LoadTerraformOptions(t, testFolder)
 		helper := NewKubernetesClusterHelper(t)
 
-		resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
-		clusterName := terraform.Output(t, terraformOptions, "kubernetes_cluster_name")
+		resourceGroupName := cassette.Output(t, terraformOptions, "resource_group_name")
+		clusterName := cassette.Output(t, terraformOptions, "kubernetes_cluster_name")
 
 		// Validate initial node count
 		cluster := helper.GetKubernetesClusterProperties(t, resourceGroupName, clusterName)
@@ -42,13 +44,17 @@ func TestKubernetesClusterLifecycle(t *testing.T) {
 
 		// Update the node count
 		terraformOptions.Vars["node_count"] = 2
-		terraform.Apply(t, terraformOptions)
+		cassette.RunLiveStage(t, "update", func() {
+			tfretry.ApplyWithRetry(t, terraformOptions)
+		})
 
 		// Validate the updated node count
 		clusterAfterUpdate := helper.GetKubernetesClusterProperties(t, resourceGroupName, clusterName)
 		assert.Equal(t, int32(2), *(*clusterAfterUpdate.Properties.AgentPoolProfiles[0]).Count)
 
 		// Run apply again to check for idempotency
-		terraform.Apply(t, terraformOptions)
+		cassette.RunLiveStage(t, "idempotency", func() {
+			tfretry.ApplyWithRetry(t, terraformOptions)
+		})
 	})
 }
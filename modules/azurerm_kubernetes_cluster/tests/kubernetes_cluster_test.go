@@ -3,7 +3,13 @@ package test
 import (
 	"testing"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v4"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/cassette"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/importtest"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/leakscan"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/assert"
@@ -12,25 +18,29 @@ import (
 
 // Test basic AKS cluster creation
 func TestBasicKubernetesCluster(t *testing.T) {
+	scanner := leakscan.NewScanner(t)
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/basic")
 
-	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+	defer cassette.RunLiveStage(t, "cleanup", func() {
+		terraformOptions := scanner.Attach(test_structure.LoadTerraformOptions(t, testFolder))
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
-	test_structure.RunTestStage(t, "deploy", func() {
+	cassette.RunLiveStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, scanner.Attach(terraformOptions))
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
-		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+		terraformOptions := scanner.Attach(cassette.LoadTerraformOptions(t, testFolder))
+		cassette.RunLiveStage(t, "scan_outputs", func() {
+			scanner.ScanAfterApply(terraformOptions)
+		})
 		helper := NewKubernetesClusterHelper(t)
 
-		resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
-		clusterName := terraform.Output(t, terraformOptions, "kubernetes_cluster_name")
+		resourceGroupName := cassette.Output(t, terraformOptions, "resource_group_name")
+		clusterName := cassette.Output(t, terraformOptions, "kubernetes_cluster_name")
 
 		cluster := helper.GetKubernetesClusterProperties(t, resourceGroupName, clusterName)
 
@@ -38,6 +48,34 @@ func TestBasicKubernetesCluster(t *testing.T) {
 		assert.Equal(t, armcontainerservice.ResourceIdentityTypeSystemAssigned, *cluster.Identity.Type)
 		assert.Equal(t, "standard", string(*cluster.Properties.NetworkProfile.LoadBalancerSKU))
 		assert.Equal(t, int32(1), *(*cluster.Properties.AgentPoolProfiles[0]).Count)
+
+		ValidateAgentPools(t, cluster, []ExpectedAgentPool{
+			{
+				Name:   "default",
+				VMSize: "Standard_D2_v2",
+				Mode:   armcontainerservice.AgentPoolModeSystem,
+				Count:  to.Ptr(int32(1)),
+			},
+		})
+		ValidateNetworkProfile(t, cluster, ExpectedNetworkProfile{
+			NetworkPlugin: armcontainerservice.NetworkPluginAzure,
+			NetworkPolicy: armcontainerservice.NetworkPolicyAzure,
+			ServiceCIDR:   "172.16.0.0/16",
+			DNSServiceIP:  "172.16.0.10",
+			OutboundType:  armcontainerservice.OutboundTypeLoadBalancer,
+		})
+
+		// The Kubernetes API is not recorded, so workload checks only run against the live cluster
+		cassette.RunLiveStage(t, "validate_workloads", func() {
+			kubeClient := NewKubernetesClientFromKubeconfig(t, leakscan.SensitiveOutput(t, terraformOptions, "kube_config_raw"))
+			WaitForNodesReady(t, kubeClient, 1)
+			WaitForSystemPodsHealthy(t, kubeClient)
+		})
+	})
+
+	// Adopting the deployed module resources into an empty state must plan no changes
+	cassette.RunLiveStage(t, "import", func() {
+		importtest.RequireStateImportRoundTrip(t, test_structure.LoadTerraformOptions(t, testFolder))
 	})
 }
 
@@ -45,24 +83,24 @@ func TestBasicKubernetesCluster(t *testing.T) {
 func TestCompleteKubernetesCluster(t *testing.T) {
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/complete")
 
-	defer test_structure.RunTestStage(t, "cleanup", func() {
+	defer cassette.RunLiveStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
-	test_structure.RunTestStage(t, "deploy", func() {
+	cassette.RunLiveStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
-		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+		terraformOptions := cassette.LoadTerraformOptions(t, testFolder)
 		helper := NewKubernetesClusterHelper(t)
 
-		resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
-		clusterName := terraform.Output(t, terraformOptions, "kubernetes_cluster_name")
-		amplsResourceID := terraform.Output(t, terraformOptions, "ampls_resource_id")
+		resourceGroupName := cassette.Output(t, terraformOptions, "resource_group_name")
+		clusterName := cassette.Output(t, terraformOptions, "kubernetes_cluster_name")
+		amplsResourceID := cassette.Output(t, terraformOptions, "ampls_resource_id")
 
 		cluster := helper.GetKubernetesClusterProperties(t, resourceGroupName, clusterName)
 
@@ -95,23 +133,23 @@ func TestCompleteKubernetesCluster(t *testing.T) {
 func TestSecureKubernetesCluster(t *testing.T) {
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/secure")
 
-	defer test_structure.RunTestStage(t, "cleanup", func() {
+	defer cassette.RunLiveStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
-	test_structure.RunTestStage(t, "deploy", func() {
+	cassette.RunLiveStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
-		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+		terraformOptions := cassette.LoadTerraformOptions(t, testFolder)
 		helper := NewKubernetesClusterHelper(t)
 
-		resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
-		clusterName := terraform.Output(t, terraformOptions, "kubernetes_cluster_name")
+		resourceGroupName := cassette.Output(t, terraformOptions, "resource_group_name")
+		clusterName := cassette.Output(t, terraformOptions, "kubernetes_cluster_name")
 
 		cluster := helper.GetKubernetesClusterProperties(t, resourceGroupName, clusterName)
 
@@ -125,23 +163,23 @@ func TestSecureKubernetesCluster(t *testing.T) {
 func TestNetworkKubernetesCluster(t *testing.T) {
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/network")
 
-	defer test_structure.RunTestStage(t, "cleanup", func() {
+	defer cassette.RunLiveStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
-	test_structure.RunTestStage(t, "deploy", func() {
+	cassette.RunLiveStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
-		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+		terraformOptions := cassette.LoadTerraformOptions(t, testFolder)
 		helper := NewKubernetesClusterHelper(t)
 
-		resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
-		clusterName := terraform.Output(t, terraformOptions, "kubernetes_cluster_name")
+		resourceGroupName := cassette.Output(t, terraformOptions, "resource_group_name")
+		clusterName := cassette.Output(t, terraformOptions, "kubernetes_cluster_name")
 
 		cluster := helper.GetKubernetesClusterProperties(t, resourceGroupName, clusterName)
 
@@ -150,6 +188,42 @@ func TestNetworkKubernetesCluster(t *testing.T) {
 		assert.Equal(t, armcontainerservice.NetworkPolicyAzure, *cluster.Properties.NetworkProfile.NetworkPolicy)
 		assert.NotNil(t, cluster.Properties.NetworkProfile.ServiceCidr)
 		assert.NotNil(t, cluster.Properties.NetworkProfile.DNSServiceIP)
+
+		ValidateNetworkProfile(t, cluster, ExpectedNetworkProfile{
+			NetworkPlugin: armcontainerservice.NetworkPluginAzure,
+			NetworkPolicy: armcontainerservice.NetworkPolicyAzure,
+			ServiceCIDR:   "172.16.0.0/16",
+			DNSServiceIP:  "172.16.0.10",
+			OutboundType:  armcontainerservice.OutboundTypeLoadBalancer,
+		})
+
+		// Validate every agent pool, including the autoscaled user pool
+		ValidateAgentPools(t, cluster, []ExpectedAgentPool{
+			{
+				Name:   "default",
+				VMSize: "Standard_D2s_v3",
+				Mode:   armcontainerservice.AgentPoolModeSystem,
+				Count:  to.Ptr(int32(1)),
+			},
+			{
+				Name:        "user1",
+				VMSize:      "Standard_D2s_v3",
+				Mode:        armcontainerservice.AgentPoolModeUser,
+				Zones:       []string{"1", "2"},
+				AutoScaling: true,
+				MinCount:    to.Ptr(int32(1)),
+				MaxCount:    to.Ptr(int32(2)),
+				Taints:      []string{"workload=user:NoSchedule"},
+				Labels:      map[string]string{"workload": "user"},
+			},
+		})
+
+		// Validate workloads through the generated kubeconfig; the Kubernetes API is not recorded
+		cassette.RunLiveStage(t, "validate_workloads", func() {
+			kubeClient := NewKubernetesClientFromKubeconfig(t, leakscan.SensitiveOutput(t, terraformOptions, "kube_config_raw"))
+			WaitForNodesReady(t, kubeClient, 2)
+			WaitForSystemPodsHealthy(t, kubeClient)
+		})
 	})
 }
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -11,9 +12,16 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v4"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/cassette"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 // KubernetesClusterHelper provides helper methods for AKS testing
@@ -23,14 +31,18 @@ type KubernetesClusterHelper struct {
 	client         *armcontainerservice.ManagedClustersClient
 }
 
-// NewKubernetesClusterHelper creates a new helper instance for AKS
+// NewKubernetesClusterHelper creates a new helper instance for AKS.
+// SDK traffic goes through the test's HTTP cassette when AZURE_CASSETTE_MODE is set.
 func NewKubernetesClusterHelper(t *testing.T) *KubernetesClusterHelper {
-	subscriptionID := getRequiredEnvVar(t, "AZURE_SUBSCRIPTION_ID")
+	subscriptionID, credential, options := cassette.Session(t,
+		func() string { return getRequiredEnvVar(t, "AZURE_SUBSCRIPTION_ID") },
+		func() azcore.TokenCredential {
+			credential, err := azidentity.NewDefaultAzureCredential(nil)
+			require.NoError(t, err, "Failed to create default Azure credential")
+			return credential
+		})
 
-	credential, err := azidentity.NewDefaultAzureCredential(nil)
-	require.NoError(t, err, "Failed to create default Azure credential")
-
-	client, err := armcontainerservice.NewManagedClustersClient(subscriptionID, credential, nil)
+	client, err := armcontainerservice.NewManagedClustersClient(subscriptionID, credential, options)
 	require.NoError(t, err, "Failed to create AKS client")
 
 	return &KubernetesClusterHelper{
@@ -47,11 +59,231 @@ func (h *KubernetesClusterHelper) GetKubernetesClusterProperties(t *testing.T, r
 	return &resp.ManagedCluster
 }
 
+// ExpectedAgentPool describes the expected state of a single AKS agent pool.
+// Nil/empty fields are not asserted, except Taints and Labels which are compared exactly.
+type ExpectedAgentPool struct {
+	Name        string
+	VMSize      string
+	Mode        armcontainerservice.AgentPoolMode
+	Zones       []string
+	Count       *int32
+	AutoScaling bool
+	MinCount    *int32
+	MaxCount    *int32
+	Taints      []string
+	Labels      map[string]string
+}
+
+// ValidateAgentPools validates every agent pool on the cluster against the expected set
+func ValidateAgentPools(t *testing.T, cluster *armcontainerservice.ManagedCluster, expected []ExpectedAgentPool) {
+	require.NotNil(t, cluster.Properties, "Cluster properties should not be nil")
+
+	actual := make(map[string]*armcontainerservice.ManagedClusterAgentPoolProfile, len(cluster.Properties.AgentPoolProfiles))
+	for _, pool := range cluster.Properties.AgentPoolProfiles {
+		require.NotNil(t, pool.Name, "Agent pool name should not be nil")
+		actual[*pool.Name] = pool
+	}
+	require.Len(t, actual, len(expected), "Agent pool count mismatch")
+
+	for _, want := range expected {
+		pool, ok := actual[want.Name]
+		require.True(t, ok, "Expected agent pool %s to be present", want.Name)
+
+		if want.VMSize != "" {
+			require.NotNil(t, pool.VMSize, "Agent pool %s VM size should be set", want.Name)
+			require.True(t, strings.EqualFold(want.VMSize, *pool.VMSize), "Agent pool %s VM size should be %s, got %s", want.Name, want.VMSize, *pool.VMSize)
+		}
+		if want.Mode != "" {
+			require.NotNil(t, pool.Mode, "Agent pool %s mode should be set", want.Name)
+			require.Equal(t, want.Mode, *pool.Mode, "Agent pool %s mode should match", want.Name)
+		}
+		if want.Zones != nil {
+			require.ElementsMatch(t, want.Zones, derefStrings(pool.AvailabilityZones), "Agent pool %s zones should match", want.Name)
+		}
+		if want.Count != nil {
+			require.NotNil(t, pool.Count, "Agent pool %s count should be set", want.Name)
+			require.Equal(t, *want.Count, *pool.Count, "Agent pool %s count should match", want.Name)
+		}
+
+		autoScaling := pool.EnableAutoScaling != nil && *pool.EnableAutoScaling
+		require.Equal(t, want.AutoScaling, autoScaling, "Agent pool %s autoscaling flag should match", want.Name)
+		if want.AutoScaling {
+			require.NotNil(t, pool.MinCount, "Agent pool %s min count should be set", want.Name)
+			require.NotNil(t, pool.MaxCount, "Agent pool %s max count should be set", want.Name)
+			if want.MinCount != nil {
+				require.Equal(t, *want.MinCount, *pool.MinCount, "Agent pool %s min count should match", want.Name)
+			}
+			if want.MaxCount != nil {
+				require.Equal(t, *want.MaxCount, *pool.MaxCount, "Agent pool %s max count should match", want.Name)
+			}
+		}
+
+		require.ElementsMatch(t, want.Taints, derefStrings(pool.NodeTaints), "Agent pool %s taints should match", want.Name)
+
+		labels := make(map[string]string, len(pool.NodeLabels))
+		for key, value := range pool.NodeLabels {
+			if value != nil {
+				labels[key] = *value
+			}
+		}
+		if want.Labels == nil {
+			want.Labels = map[string]string{}
+		}
+		require.Equal(t, want.Labels, labels, "Agent pool %s labels should match", want.Name)
+	}
+}
+
+// ExpectedNetworkProfile describes the expected AKS network profile.
+// Empty CIDR fields assert that the value is unset (e.g. no pod CIDR with Azure CNI).
+type ExpectedNetworkProfile struct {
+	NetworkPlugin armcontainerservice.NetworkPlugin
+	NetworkPolicy armcontainerservice.NetworkPolicy
+	PodCIDR       string
+	ServiceCIDR   string
+	DNSServiceIP  string
+	OutboundType  armcontainerservice.OutboundType
+}
+
+// ValidateNetworkProfile validates plugin, policy, CIDRs and outbound type of the cluster
+func ValidateNetworkProfile(t *testing.T, cluster *armcontainerservice.ManagedCluster, expected ExpectedNetworkProfile) {
+	require.NotNil(t, cluster.Properties, "Cluster properties should not be nil")
+	profile := cluster.Properties.NetworkProfile
+	require.NotNil(t, profile, "Network profile should not be nil")
+
+	require.NotNil(t, profile.NetworkPlugin, "Network plugin should be set")
+	require.Equal(t, expected.NetworkPlugin, *profile.NetworkPlugin, "Network plugin should match")
+
+	if expected.NetworkPolicy != "" {
+		require.NotNil(t, profile.NetworkPolicy, "Network policy should be set")
+		require.Equal(t, expected.NetworkPolicy, *profile.NetworkPolicy, "Network policy should match")
+	}
+
+	require.Equal(t, expected.PodCIDR, derefString(profile.PodCidr), "Pod CIDR should match")
+	require.Equal(t, expected.ServiceCIDR, derefString(profile.ServiceCidr), "Service CIDR should match")
+	if expected.DNSServiceIP != "" {
+		require.Equal(t, expected.DNSServiceIP, derefString(profile.DNSServiceIP), "DNS service IP should match")
+	}
+
+	require.NotNil(t, profile.OutboundType, "Outbound type should be set")
+	require.Equal(t, expected.OutboundType, *profile.OutboundType, "Outbound type should match")
+}
+
+// NewKubernetesClientFromKubeconfig builds a client-go clientset from a raw kubeconfig
+func NewKubernetesClientFromKubeconfig(t *testing.T, kubeconfigRaw string) kubernetes.Interface {
+	require.NotEmpty(t, kubeconfigRaw, "Kubeconfig should not be empty")
+
+	restConfig, err := clientcmd.RESTConfigFromKubeConfig([]byte(kubeconfigRaw))
+	require.NoError(t, err, "Failed to parse kubeconfig")
+	restConfig.Timeout = 30 * time.Second
+
+	client, err := kubernetes.NewForConfig(restConfig)
+	require.NoError(t, err, "Failed to create Kubernetes client")
+	return client
+}
+
+// WaitForNodesReady waits until the expected number of nodes report Ready
+func WaitForNodesReady(t *testing.T, client kubernetes.Interface, expectedCount int) {
+	retry.DoWithRetry(t, "Waiting for AKS nodes to be Ready", 40, 15*time.Second, func() (string, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return "", err
+		}
+
+		notReady := make([]string, 0)
+		for _, node := range nodes.Items {
+			if !isNodeReady(node) {
+				notReady = append(notReady, node.Name)
+			}
+		}
+		if len(nodes.Items) != expectedCount {
+			return "", fmt.Errorf("expected %d nodes, found %d", expectedCount, len(nodes.Items))
+		}
+		if len(notReady) > 0 {
+			return "", fmt.Errorf("nodes not Ready: %s", strings.Join(notReady, ", "))
+		}
+		return fmt.Sprintf("%d nodes Ready", len(nodes.Items)), nil
+	})
+}
+
+// WaitForSystemPodsHealthy waits until every kube-system pod is Running with ready containers
+func WaitForSystemPodsHealthy(t *testing.T, client kubernetes.Interface) {
+	retry.DoWithRetry(t, "Waiting for kube-system pods to be healthy", 40, 15*time.Second, func() (string, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		pods, err := client.CoreV1().Pods("kube-system").List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return "", err
+		}
+		if len(pods.Items) == 0 {
+			return "", fmt.Errorf("no pods found in kube-system")
+		}
+
+		unhealthy := make([]string, 0)
+		for _, pod := range pods.Items {
+			if !isPodHealthy(pod) {
+				unhealthy = append(unhealthy, fmt.Sprintf("%s(%s)", pod.Name, pod.Status.Phase))
+			}
+		}
+		if len(unhealthy) > 0 {
+			sort.Strings(unhealthy)
+			return "", fmt.Errorf("unhealthy kube-system pods: %s", strings.Join(unhealthy, ", "))
+		}
+		return fmt.Sprintf("%d kube-system pods healthy", len(pods.Items)), nil
+	})
+}
+
+func isNodeReady(node corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func isPodHealthy(pod corev1.Pod) bool {
+	switch pod.Status.Phase {
+	case corev1.PodSucceeded:
+		return true
+	case corev1.PodRunning:
+		for _, status := range pod.Status.ContainerStatuses {
+			if !status.Ready {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+func derefString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func derefStrings(values []*string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if value != nil {
+			result = append(result, *value)
+		}
+	}
+	return result
+}
+
 // Shared test helper functions
 
 // getTerraformOptions creates a standard terraform.Options object for tests
 func getTerraformOptions(t testing.TB, terraformDir string) *terraform.Options {
-	randomSuffix := strings.ToLower(random.UniqueId())
+	// Replay reuses the suffix recorded in the test's cassette
+	randomSuffix := strings.ToLower(cassette.UniqueID(t, random.UniqueId))
 
 	return &terraform.Options{
 		TerraformDir: terraformDir,
@@ -59,14 +291,10 @@ func getTerraformOptions(t testing.TB, terraformDir string) *terraform.Options {
 			"random_suffix": randomSuffix,
 			"location":      "northeurope", // Standard test location
 		},
-		NoColor: true,
-		RetryableTerraformErrors: map[string]string{
-			".*timeout.*":               "Timeout error, retrying.",
-			".*ResourceGroupNotFound.*": "Resource group not found, retrying.",
-			".*Another operation is in progress.*": "Another operation is in progress, retrying.",
-		},
-		MaxRetries:         3,
-		TimeBetweenRetries: 10 * time.Second,
+		NoColor:                  true,
+		RetryableTerraformErrors: tfretry.ClassifiedRetryableErrors(),
+		MaxRetries:               3,
+		TimeBetweenRetries:       10 * time.Second,
 	}
 }
 
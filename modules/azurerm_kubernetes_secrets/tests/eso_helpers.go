@@ -1,93 +1,124 @@
 package test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
-	"os/exec"
-	"strings"
 	"testing"
+	"time"
 
+	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/dynamic"
 )
 
 const defaultESOCRDURL = "https://raw.githubusercontent.com/external-secrets/external-secrets/main/deploy/crds/bundle.yaml"
 
+var customResourceDefinitionGVR = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+
 func ensureESOCRDs(t testing.TB, terraformOptions *terraform.Options) {
-	kubeconfigPath := writeTempKubeconfig(t, terraformOptions)
-	defer os.Remove(kubeconfigPath)
+	dyn, err := dynamic.NewForConfig(restConfigFromTerraform(t, terraformOptions))
+	require.NoError(t, err, "Failed to create Kubernetes dynamic client")
 
-	if esoCRDsPresent(kubeconfigPath) {
+	if esoCRDsPresent(dyn) {
 		return
 	}
 
-	if !commandExists("kubectl") {
-		t.Skip("kubectl not found; install ESO CRDs manually or add kubectl to PATH")
-	}
-
 	crdURL := os.Getenv("ESO_CRD_URL")
 	if crdURL == "" {
 		crdURL = defaultESOCRDURL
 	}
 
-	err := runCommandCheck(kubeconfigPath, "kubectl", "apply", "--server-side", "--field-manager=terratest-eso", "-f", crdURL)
-	if err != nil {
-		if strings.Contains(err.Error(), "unknown flag: --server-side") {
-			t.Skip("kubectl is too old for server-side apply; upgrade kubectl to install ESO CRDs automatically")
-		}
-		require.NoError(t, err)
-	}
-	waitForCRD(t, kubeconfigPath, "secretstores.external-secrets.io")
-	waitForCRD(t, kubeconfigPath, "externalsecrets.external-secrets.io")
-}
+	bundle := downloadManifest(t, crdURL)
+	defer bundle.Close()
 
-func writeTempKubeconfig(t testing.TB, terraformOptions *terraform.Options) string {
-	kubeconfig := terraform.Output(t, terraformOptions, "kube_config_raw")
-	require.NotEmpty(t, kubeconfig, "kube_config_raw output is empty")
-
-	file, err := os.CreateTemp("", "kubeconfig-*")
-	require.NoError(t, err)
-	require.NoError(t, os.WriteFile(file.Name(), []byte(kubeconfig), 0600))
-	require.NoError(t, file.Close())
-
-	return file.Name()
+	applyCRDs(t, dyn, bundle)
+	waitForCRD(t, dyn, "secretstores.external-secrets.io")
+	waitForCRD(t, dyn, "externalsecrets.external-secrets.io")
 }
 
-func esoCRDsPresent(kubeconfigPath string) bool {
-	if !commandExists("kubectl") {
-		return false
-	}
-	if err := runCommandCheck(kubeconfigPath, "kubectl", "get", "crd", "secretstores.external-secrets.io"); err != nil {
-		return false
-	}
-	if err := runCommandCheck(kubeconfigPath, "kubectl", "get", "crd", "externalsecrets.external-secrets.io"); err != nil {
-		return false
+func esoCRDsPresent(dyn dynamic.Interface) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, name := range []string{"secretstores.external-secrets.io", "externalsecrets.external-secrets.io"} {
+		if _, err := dyn.Resource(customResourceDefinitionGVR).Get(ctx, name, metav1.GetOptions{}); err != nil {
+			return false
+		}
 	}
 	return true
 }
 
-func waitForCRD(t testing.TB, kubeconfigPath, crd string) {
-	runCommand(t, kubeconfigPath, "kubectl", "wait", "--for=condition=Established", "--timeout=120s", "crd/"+crd)
+func downloadManifest(t testing.TB, url string) io.ReadCloser {
+	client := &http.Client{Timeout: 2 * time.Minute}
+	resp, err := client.Get(url)
+	require.NoError(t, err, "Failed to download %s", url)
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		require.FailNow(t, "Failed to download manifest", "%s returned HTTP %d", url, resp.StatusCode)
+	}
+	return resp.Body
 }
 
-func commandExists(name string) bool {
-	_, err := exec.LookPath(name)
-	return err == nil
-}
+// applyCRDs server-side applies every CustomResourceDefinition found in a multi-document YAML stream
+func applyCRDs(t testing.TB, dyn dynamic.Interface, manifest io.Reader) {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(manifest, 4096)
+	for {
+		object := &unstructured.Unstructured{}
+		err := decoder.Decode(&object.Object)
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		require.NoError(t, err, "Failed to decode CRD manifest")
+		if len(object.Object) == 0 || object.GetKind() != "CustomResourceDefinition" {
+			continue
+		}
 
-func runCommand(t testing.TB, kubeconfigPath string, name string, args ...string) {
-	cmd := exec.Command(name, args...)
-	cmd.Env = append(os.Environ(), "KUBECONFIG="+kubeconfigPath)
-	output, err := cmd.CombinedOutput()
-	require.NoError(t, err, "command failed: %s %s\n%s", name, strings.Join(args, " "), strings.TrimSpace(string(output)))
-}
+		body, err := json.Marshal(object.Object)
+		require.NoError(t, err)
 
-func runCommandCheck(kubeconfigPath string, name string, args ...string) error {
-	cmd := exec.Command(name, args...)
-	cmd.Env = append(os.Environ(), "KUBECONFIG="+kubeconfigPath)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		force := true
+		_, err = dyn.Resource(customResourceDefinitionGVR).Patch(ctx, object.GetName(), types.ApplyPatchType, body, metav1.PatchOptions{
+			FieldManager: "terratest-eso",
+			Force:        &force,
+		})
+		cancel()
+		require.NoError(t, err, "Failed to apply CRD %s", object.GetName())
 	}
-	return nil
+}
+
+func waitForCRD(t testing.TB, dyn dynamic.Interface, crd string) {
+	retry.DoWithRetry(t, fmt.Sprintf("Waiting for CRD %s to be Established", crd), 24, 5*time.Second, func() (string, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		object, err := dyn.Resource(customResourceDefinitionGVR).Get(ctx, crd, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return "", fmt.Errorf("CRD %s not found yet", crd)
+		}
+		if err != nil {
+			return "", err
+		}
+
+		conditions, _, _ := unstructured.NestedSlice(object.Object, "status", "conditions")
+		for _, raw := range conditions {
+			condition, ok := raw.(map[string]interface{})
+			if ok && condition["type"] == "Established" && condition["status"] == "True" {
+				return "established", nil
+			}
+		}
+		return "", fmt.Errorf("CRD %s is not Established yet", crd)
+	})
 }
@@ -26,68 +26,14 @@ resource "azurerm_resource_group" "test" {
   location = var.location
 }
 
-resource "azurerm_virtual_network" "test" {
-  name                = "vnet-akssec-bas-${var.random_suffix}"
-  location            = azurerm_resource_group.test.location
-  resource_group_name = azurerm_resource_group.test.name
-  address_space       = ["10.0.0.0/16"]
-}
-
-resource "azurerm_subnet" "test" {
-  name                 = "snet-akssec-bas-${var.random_suffix}"
-  resource_group_name  = azurerm_resource_group.test.name
-  virtual_network_name = azurerm_virtual_network.test.name
-  address_prefixes     = ["10.0.1.0/24"]
-}
-
-module "kubernetes_cluster" {
-  source = "../../../../azurerm_kubernetes_cluster"
-
-  name                = "akssec-bas-${var.random_suffix}"
-  resource_group_name = azurerm_resource_group.test.name
-  location            = azurerm_resource_group.test.location
-
-  dns_config = {
-    dns_prefix = "akssec-bas-${var.random_suffix}"
-  }
-
-  identity = {
-    type = "SystemAssigned"
-  }
-
-  default_node_pool = {
-    name           = "default"
-    vm_size        = "Standard_D2_v4"
-    node_count     = var.node_count
-    vnet_subnet_id = azurerm_subnet.test.id
-  }
-
-  network_profile = {
-    network_plugin = "azure"
-    network_policy = "azure"
-    service_cidr   = "172.16.0.0/16"
-    dns_service_ip = "172.16.0.10"
-  }
-
-  tags = {
-    Environment = "Test"
-    Example     = "Basic"
-  }
-}
-
 provider "kubernetes" {
-  host                   = module.kubernetes_cluster.kube_config.host
-  client_certificate     = base64decode(module.kubernetes_cluster.kube_config.client_certificate)
-  client_key             = base64decode(module.kubernetes_cluster.kube_config.client_key)
-  cluster_ca_certificate = base64decode(module.kubernetes_cluster.kube_config.cluster_ca_certificate)
+  config_path = var.kubeconfig_path
 }
 
 resource "kubernetes_namespace_v1" "app" {
   metadata {
-    name = "app"
+    name = "app-${var.random_suffix}"
   }
-
-  depends_on = [module.kubernetes_cluster]
 }
 
 resource "azurerm_key_vault" "test" {
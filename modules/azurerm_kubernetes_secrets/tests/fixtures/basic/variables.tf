@@ -9,8 +9,7 @@ variable "random_suffix" {
   type        = string
 }
 
-variable "node_count" {
-  description = "Number of nodes in the default node pool"
-  type        = number
-  default     = 1
+variable "kubeconfig_path" {
+  description = "Kubeconfig of the pooled AKS cluster, written by the infra pool lease"
+  type        = string
 }
@@ -26,73 +26,14 @@ resource "azurerm_resource_group" "test" {
   location = var.location
 }
 
-resource "azurerm_virtual_network" "test" {
-  name                = "vnet-akssec-cmp-${var.random_suffix}"
-  location            = azurerm_resource_group.test.location
-  resource_group_name = azurerm_resource_group.test.name
-  address_space       = ["10.1.0.0/16"]
-}
-
-resource "azurerm_subnet" "test" {
-  name                 = "snet-akssec-cmp-${var.random_suffix}"
-  resource_group_name  = azurerm_resource_group.test.name
-  virtual_network_name = azurerm_virtual_network.test.name
-  address_prefixes     = ["10.1.1.0/24"]
-}
-
-module "kubernetes_cluster" {
-  source = "../../../../azurerm_kubernetes_cluster"
-
-  name                = "akssec-cmp-${var.random_suffix}"
-  resource_group_name = azurerm_resource_group.test.name
-  location            = azurerm_resource_group.test.location
-
-  dns_config = {
-    dns_prefix = "akssec-cmp-${var.random_suffix}"
-  }
-
-  identity = {
-    type = "SystemAssigned"
-  }
-
-  default_node_pool = {
-    name           = "default"
-    vm_size        = "Standard_D2s_v4"
-    node_count     = 2
-    vnet_subnet_id = azurerm_subnet.test.id
-  }
-
-  network_profile = {
-    network_plugin = "azure"
-    network_policy = "azure"
-    service_cidr   = "172.16.0.0/16"
-    dns_service_ip = "172.16.0.10"
-  }
-
-  key_vault_secrets_provider = {
-    secret_rotation_enabled  = true
-    secret_rotation_interval = "2m"
-  }
-
-  tags = {
-    Environment = "Test"
-    Example     = "Complete"
-  }
-}
-
 provider "kubernetes" {
-  host                   = module.kubernetes_cluster.kube_config.host
-  client_certificate     = base64decode(module.kubernetes_cluster.kube_config.client_certificate)
-  client_key             = base64decode(module.kubernetes_cluster.kube_config.client_key)
-  cluster_ca_certificate = base64decode(module.kubernetes_cluster.kube_config.cluster_ca_certificate)
+  config_path = var.kubeconfig_path
 }
 
 resource "kubernetes_namespace_v1" "app" {
   metadata {
-    name = "app"
+    name = "app-${var.random_suffix}"
   }
-
-  depends_on = [module.kubernetes_cluster]
 }
 
 resource "azurerm_key_vault" "test" {
@@ -113,7 +54,7 @@ resource "azurerm_role_assignment" "kv_admin" {
 resource "azurerm_role_assignment" "kv_csi" {
   scope                = azurerm_key_vault.test.id
   role_definition_name = "Key Vault Secrets User"
-  principal_id         = module.kubernetes_cluster.key_vault_secrets_provider.secret_identity.object_id
+  principal_id         = var.secrets_provider_object_id
 }
 
 resource "azurerm_key_vault_secret" "db_password" {
@@ -132,9 +73,9 @@ module "kubernetes_secrets" {
   name      = "app-spc"
 
   csi = {
-    tenant_id                        = module.kubernetes_cluster.identity.tenant_id
+    tenant_id                        = data.azurerm_client_config.current.tenant_id
     key_vault_name                   = azurerm_key_vault.test.name
-    user_assigned_identity_client_id = module.kubernetes_cluster.key_vault_secrets_provider.secret_identity.client_id
+    user_assigned_identity_client_id = var.secrets_provider_client_id
     sync_to_kubernetes_secret        = true
     kubernetes_secret_name           = "app-secrets"
     objects = [
@@ -149,3 +90,43 @@ module "kubernetes_secrets" {
 
   depends_on = [kubernetes_namespace_v1.app]
 }
+
+# Mounting the CSI volume is what triggers the sync into the Kubernetes Secret.
+resource "kubernetes_pod_v1" "consumer" {
+  metadata {
+    name      = "secrets-consumer"
+    namespace = kubernetes_namespace_v1.app.metadata[0].name
+  }
+
+  spec {
+    container {
+      name    = "consumer"
+      image   = "busybox:1.36"
+      command = ["sh", "-c", "sleep 3600"]
+
+      volume_mount {
+        name       = "secrets-store"
+        mount_path = "/mnt/secrets-store"
+        read_only  = true
+      }
+    }
+
+    volume {
+      name = "secrets-store"
+
+      csi {
+        driver    = "secrets-store.csi.k8s.io"
+        read_only = true
+        volume_attributes = {
+          secretProviderClass = module.kubernetes_secrets.secret_provider_class_name
+        }
+      }
+    }
+  }
+
+  depends_on = [
+    module.kubernetes_secrets,
+    azurerm_role_assignment.kv_csi,
+    azurerm_key_vault_secret.db_password
+  ]
+}
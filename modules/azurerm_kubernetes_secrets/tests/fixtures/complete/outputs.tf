@@ -13,7 +13,23 @@ output "kubernetes_secret_name" {
   value       = module.kubernetes_secrets.kubernetes_secret_name
 }
 
+output "namespace" {
+  description = "Namespace of the test workloads"
+  value       = kubernetes_namespace_v1.app.metadata[0].name
+}
+
 output "resource_group_name" {
   description = "Resource group name"
   value       = azurerm_resource_group.test.name
 }
+
+output "kube_config_raw" {
+  description = "Kubeconfig of the leased cluster for test automation"
+  value       = file(var.kubeconfig_path)
+  sensitive   = true
+}
+
+output "key_vault_name" {
+  description = "Key Vault name"
+  value       = azurerm_key_vault.test.name
+}
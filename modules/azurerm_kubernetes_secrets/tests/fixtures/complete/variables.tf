@@ -8,3 +8,18 @@ variable "random_suffix" {
   description = "Random suffix for unique resource naming"
   type        = string
 }
+
+variable "kubeconfig_path" {
+  description = "Kubeconfig of the pooled AKS cluster, written by the infra pool lease"
+  type        = string
+}
+
+variable "secrets_provider_client_id" {
+  description = "Client ID of the pooled cluster's Key Vault secrets provider identity"
+  type        = string
+}
+
+variable "secrets_provider_object_id" {
+  description = "Object ID of the pooled cluster's Key Vault secrets provider identity"
+  type        = string
+}
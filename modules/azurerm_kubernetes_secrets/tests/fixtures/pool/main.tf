@@ -0,0 +1,76 @@
+# Shared AKS cluster leased to the basic and complete fixtures through the infra pool
+
+terraform {
+  required_version = ">= 1.12.2"
+
+  required_providers {
+    azurerm = {
+      source  = "hashicorp/azurerm"
+      version = "4.57.0"
+    }
+  }
+}
+
+provider "azurerm" {
+  features {}
+}
+
+resource "azurerm_resource_group" "pool" {
+  name     = "rg-akssec-pool-${var.random_suffix}"
+  location = var.location
+}
+
+resource "azurerm_virtual_network" "pool" {
+  name                = "vnet-akssec-pool-${var.random_suffix}"
+  location            = azurerm_resource_group.pool.location
+  resource_group_name = azurerm_resource_group.pool.name
+  address_space       = ["10.30.0.0/16"]
+}
+
+resource "azurerm_subnet" "nodes" {
+  name                 = "snet-akssec-pool-${var.random_suffix}"
+  resource_group_name  = azurerm_resource_group.pool.name
+  virtual_network_name = azurerm_virtual_network.pool.name
+  address_prefixes     = ["10.30.1.0/24"]
+}
+
+module "kubernetes_cluster" {
+  source = "../../../../azurerm_kubernetes_cluster"
+
+  name                = "akssec-pool-${var.random_suffix}"
+  resource_group_name = azurerm_resource_group.pool.name
+  location            = azurerm_resource_group.pool.location
+
+  dns_config = {
+    dns_prefix = "akssec-pool-${var.random_suffix}"
+  }
+
+  identity = {
+    type = "SystemAssigned"
+  }
+
+  # Sized for the slots leased in parallel
+  default_node_pool = {
+    name           = "default"
+    vm_size        = "Standard_D2s_v4"
+    node_count     = var.node_count
+    vnet_subnet_id = azurerm_subnet.nodes.id
+  }
+
+  network_profile = {
+    network_plugin = "azure"
+    network_policy = "azure"
+    service_cidr   = "172.16.0.0/16"
+    dns_service_ip = "172.16.0.10"
+  }
+
+  key_vault_secrets_provider = {
+    secret_rotation_enabled  = true
+    secret_rotation_interval = "2m"
+  }
+
+  tags = {
+    Environment = "Test"
+    Example     = "Pool"
+  }
+}
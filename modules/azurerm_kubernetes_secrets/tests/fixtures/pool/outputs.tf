@@ -0,0 +1,30 @@
+output "resource_group_name" {
+  description = "Pool resource group name"
+  value       = azurerm_resource_group.pool.name
+}
+
+output "kubernetes_cluster_name" {
+  description = "Pooled AKS cluster name"
+  value       = module.kubernetes_cluster.name
+}
+
+output "tenant_id" {
+  description = "Tenant of the cluster identity"
+  value       = module.kubernetes_cluster.identity.tenant_id
+}
+
+output "secrets_provider_client_id" {
+  description = "Client ID of the Key Vault secrets provider identity"
+  value       = module.kubernetes_cluster.key_vault_secrets_provider.secret_identity.client_id
+}
+
+output "secrets_provider_object_id" {
+  description = "Object ID of the Key Vault secrets provider identity"
+  value       = module.kubernetes_cluster.key_vault_secrets_provider.secret_identity.object_id
+}
+
+output "kube_config_raw" {
+  description = "Kubeconfig written to each lease"
+  value       = module.kubernetes_cluster.kube_config_raw
+  sensitive   = true
+}
@@ -23,3 +23,8 @@ output "kube_config_raw" {
   value       = module.kubernetes_cluster.kube_config_raw
   sensitive   = true
 }
+
+output "key_vault_name" {
+  description = "Key Vault name"
+  value       = azurerm_key_vault.test.name
+}
@@ -3,6 +3,8 @@ package test
 import (
 	"testing"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/assert"
@@ -19,13 +21,13 @@ func TestKubernetesSecretsLifecycle(t *testing.T) {
 
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		deployOnPooledCluster(t, terraformOptions, "kubeconfig_path")
 	})
 
 	test_structure.RunTestStage(t, "validate_and_reapply", func() {
@@ -37,7 +39,7 @@ func TestKubernetesSecretsLifecycle(t *testing.T) {
 		assert.Equal(t, "manual", strategy)
 		assert.NotEmpty(t, secretName)
 
-		terraform.Apply(t, terraformOptions)
+		tfretry.ApplyWithRetry(t, terraformOptions)
 
 		secretNameAfter := terraform.Output(t, terraformOptions, "kubernetes_secret_name")
 		assert.Equal(t, secretName, secretNameAfter)
@@ -1,11 +1,18 @@
 package test
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/importtest"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/infrapool"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/leakscan"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
@@ -13,32 +20,62 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+// aksClusterPool shares one AKS cluster between the fixtures that only need a namespace on a cluster
+var aksClusterPool = &infrapool.InfraPool{
+	Name:          "azurerm_kubernetes_secrets-aks",
+	RootFolder:    "../..",
+	FixtureFolder: "azurerm_kubernetes_secrets/tests/fixtures/pool",
+	Slots:         4,
+	Options:       getTerraformOptions,
+	LeaseVars:     aksClusterLeaseVars,
+}
+
+func TestMain(m *testing.M) {
+	os.Exit(infrapool.RunWithInfraPools(m, aksClusterPool))
+}
+
 // Test basic Kubernetes Secrets configuration (manual strategy)
 func TestBasicKubernetesSecrets(t *testing.T) {
 	t.Parallel()
 
+	// Created before the lease so its final check runs after the lease kubeconfig is removed
+	scanner := leakscan.NewScanner(t)
+
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "../..", "azurerm_kubernetes_secrets/tests/fixtures/basic")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		terraformOptions := scanner.Attach(test_structure.LoadTerraformOptions(t, testFolder))
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		applyWithClusterFirst(t, terraformOptions)
+		lease := deployOnPooledCluster(t, scanner.Attach(terraformOptions), "kubeconfig_path")
+		scanner.TrackCredentialFile(fmt.Sprint(lease.Vars["kubeconfig_path"]))
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
-		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+		terraformOptions := scanner.Attach(test_structure.LoadTerraformOptions(t, testFolder))
+		scanner.ScanAfterApply(terraformOptions)
 
 		strategy := terraform.Output(t, terraformOptions, "strategy")
 		secretName := terraform.Output(t, terraformOptions, "kubernetes_secret_name")
+		namespace := terraform.Output(t, terraformOptions, "namespace")
 		resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
 
 		assert.Equal(t, "manual", strategy)
 		assert.NotEmpty(t, secretName)
 		assert.NotEmpty(t, resourceGroupName)
+
+		verifier := NewSecretsVerifierFromTerraform(t, terraformOptions)
+		WaitForVerification(t, "Manual secret keys", func(ctx context.Context) error {
+			return verifier.VerifySecret(ctx, namespace, secretName, "Opaque", []string{"DB_PASSWORD"})
+		})
+	})
+
+	// Adopting the deployed module resources into an empty state must plan no changes
+	test_structure.RunTestStage(t, "import", func() {
+		importtest.RequireStateImportRoundTrip(t, test_structure.LoadTerraformOptions(t, testFolder))
 	})
 }
 
@@ -49,25 +86,37 @@ func TestCompleteKubernetesSecrets(t *testing.T) {
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "../..", "azurerm_kubernetes_secrets/tests/fixtures/complete")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		applyWithClusterFirst(t, terraformOptions)
+		deployOnPooledCluster(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
 
 		strategy := terraform.Output(t, terraformOptions, "strategy")
+		namespace := terraform.Output(t, terraformOptions, "namespace")
 		secretProviderClassName := terraform.Output(t, terraformOptions, "secret_provider_class_name")
 		secretName := terraform.Output(t, terraformOptions, "kubernetes_secret_name")
 
 		assert.Equal(t, "csi", strategy)
 		assert.NotEmpty(t, secretProviderClassName)
 		assert.NotEmpty(t, secretName)
+
+		keyVaultName := terraform.Output(t, terraformOptions, "key_vault_name")
+		objects := []ExpectedSecretObject{{ObjectName: "db-password", SecretKey: "DB_PASSWORD"}}
+
+		verifier := NewSecretsVerifierFromTerraform(t, terraformOptions)
+		WaitForVerification(t, "SecretProviderClass spec", func(ctx context.Context) error {
+			return verifier.VerifySecretProviderClass(ctx, namespace, secretProviderClassName, keyVaultName, secretName, objects)
+		})
+		WaitForVerification(t, "CSI synced secret keys", func(ctx context.Context) error {
+			return verifier.VerifySecret(ctx, namespace, secretName, "Opaque", SecretKeys(objects))
+		})
 	})
 }
 
@@ -99,6 +148,13 @@ func TestSecureKubernetesSecrets(t *testing.T) {
 		assert.Equal(t, "eso", strategy)
 		assert.NotEmpty(t, secretStoreName)
 		assert.Greater(t, len(externalSecretNames), 0)
+
+		// Only the CRDs are installed here, so there is no controller to report sync status.
+		objects := []ExpectedSecretObject{{ObjectName: "db-password", SecretKey: "DB_PASSWORD"}}
+		verifier := NewSecretsVerifierFromTerraform(t, terraformOptions)
+		WaitForVerification(t, "ExternalSecret spec", func(ctx context.Context) error {
+			return verifier.VerifyExternalSecretSpec(ctx, "app", externalSecretNames[0], secretStoreName, "app-secrets", objects)
+		})
 	})
 }
 
@@ -118,7 +174,7 @@ func TestSecureKubernetesSecretsESOInstall(t *testing.T) {
 		applyWithClusterFirstAndSetup(t, terraformOptions, func() {
 			targetOptions := *terraformOptions
 			targetOptions.Targets = []string{"helm_release.external_secrets"}
-			terraform.Apply(t, &targetOptions)
+			tfretry.ApplyWithRetry(t, &targetOptions)
 		})
 	})
 
@@ -132,6 +188,21 @@ func TestSecureKubernetesSecretsESOInstall(t *testing.T) {
 		assert.Equal(t, "eso", strategy)
 		assert.NotEmpty(t, secretStoreName)
 		assert.Greater(t, len(externalSecretNames), 0)
+
+		objects := []ExpectedSecretObject{{ObjectName: "db-password", SecretKey: "DB_PASSWORD"}}
+		verifier := NewSecretsVerifierFromTerraform(t, terraformOptions)
+		WaitForVerification(t, "SecretStore Ready", func(ctx context.Context) error {
+			return verifier.VerifySecretStoreReady(ctx, "SecretStore", "app", secretStoreName)
+		})
+		WaitForVerification(t, "ExternalSecret spec", func(ctx context.Context) error {
+			return verifier.VerifyExternalSecretSpec(ctx, "app", externalSecretNames[0], secretStoreName, "app-secrets", objects)
+		})
+		WaitForVerification(t, "ExternalSecret synced", func(ctx context.Context) error {
+			return verifier.VerifyExternalSecretSynced(ctx, "app", externalSecretNames[0])
+		})
+		WaitForVerification(t, "ESO synced secret keys", func(ctx context.Context) error {
+			return verifier.VerifySecret(ctx, "app", "app-secrets", "", SecretKeys(objects))
+		})
 	})
 }
 
@@ -142,13 +213,13 @@ func TestNetworkKubernetesSecrets(t *testing.T) {
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "../..", "azurerm_kubernetes_secrets/tests/fixtures/network")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -193,19 +264,44 @@ func getTerraformOptions(t testing.TB, terraformDir string) *terraform.Options {
 		},
 		NoColor: true,
 		// Retry configuration
-		RetryableTerraformErrors: map[string]string{
-			".*timeout.*":               "Timeout error - retrying",
-			".*ResourceGroupNotFound.*": "Resource group not found - retrying",
-			".*AlreadyExists.*":         "Resource already exists - retrying",
-			".*TooManyRequests.*":       "Too many requests - retrying",
-		},
-		MaxRetries:         3,
-		TimeBetweenRetries: 10 * time.Second,
+		RetryableTerraformErrors: tfretry.ClassifiedRetryableErrors(),
+		MaxRetries:               3,
+		TimeBetweenRetries:       10 * time.Second,
 	}
 }
 
-func applyWithClusterFirst(t testing.TB, terraformOptions *terraform.Options) {
-	applyWithClusterFirstAndSetup(t, terraformOptions, nil)
+// aksClusterLeaseVars writes a private kubeconfig for the lease and passes the secrets provider identity
+func aksClusterLeaseVars(lease *infrapool.Lease) (map[string]interface{}, error) {
+	kubeconfig, err := lease.Output("kube_config_raw")
+	if err != nil {
+		return nil, err
+	}
+	kubeconfigPath, err := lease.WriteFileE("kubeconfig", kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	clientID, err := lease.Output("secrets_provider_client_id")
+	if err != nil {
+		return nil, err
+	}
+	objectID, err := lease.Output("secrets_provider_object_id")
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"kubeconfig_path":            kubeconfigPath,
+		"secrets_provider_client_id": clientID,
+		"secrets_provider_object_id": objectID,
+	}, nil
+}
+
+// deployOnPooledCluster leases the shared cluster until the test ends and applies the fixture against it
+func deployOnPooledCluster(t testing.TB, terraformOptions *terraform.Options, variables ...string) *infrapool.Lease {
+	lease := aksClusterPool.Acquire(t)
+	require.NoError(t, lease.WriteVarsFileE(terraformOptions.TerraformDir, variables...), "Failed to write lease variables")
+	tfretry.InitAndApplyWithRetry(t, terraformOptions)
+	return lease
 }
 
 func applyWithClusterFirstAndSetup(t testing.TB, terraformOptions *terraform.Options, setup func()) {
@@ -214,11 +310,11 @@ func applyWithClusterFirstAndSetup(t testing.TB, terraformOptions *terraform.Opt
 	targetOptions := *terraformOptions
 	targetOptions.Targets = []string{"module.kubernetes_cluster"}
 
-	terraform.Apply(t, &targetOptions)
+	tfretry.ApplyWithRetry(t, &targetOptions)
 	if setup != nil {
 		setup()
 	}
-	terraform.Apply(t, terraformOptions)
+	tfretry.ApplyWithRetry(t, terraformOptions)
 }
 
 func destroyWithoutRefresh(t testing.TB, terraformOptions *terraform.Options) {
@@ -4,7 +4,8 @@ import (
 	"testing"
 	"time"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/require"
 )
@@ -15,16 +16,19 @@ func BenchmarkKubernetesSecretsCreation(b *testing.B) {
 		b.Skip("Skipping benchmark in short mode")
 	}
 
+	lease := aksClusterPool.Acquire(b)
+
 	for i := 0; i < b.N; i++ {
 		b.StopTimer()
 		testFolder := test_structure.CopyTerraformFolderToTemp(b, "../..", "azurerm_kubernetes_secrets/tests/fixtures/basic")
 		terraformOptions := getTerraformOptions(b, testFolder)
+		require.NoError(b, lease.WriteVarsFileE(testFolder, "kubeconfig_path"))
 		b.StartTimer()
 
-		terraform.InitAndApply(b, terraformOptions)
+		tfretry.InitAndApplyWithRetry(b, terraformOptions)
 
 		b.StopTimer()
-		terraform.Destroy(b, terraformOptions)
+		tfretry.DestroyWithRetry(b, terraformOptions)
 	}
 }
 
@@ -37,14 +41,16 @@ func TestKubernetesSecretsCreationTime(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "../..", "azurerm_kubernetes_secrets/tests/fixtures/basic")
 	terraformOptions := getTerraformOptions(t, testFolder)
+	lease := aksClusterPool.Acquire(t)
+	require.NoError(t, lease.WriteVarsFileE(testFolder, "kubeconfig_path"))
 
-	defer terraform.Destroy(t, terraformOptions)
+	defer destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 
 	start := time.Now()
-	terraform.InitAndApply(t, terraformOptions)
+	tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	duration := time.Since(start)
 
-	// Creation includes AKS + Key Vault + K8s resources.
-	maxDuration := 25 * time.Minute
+	// Creation includes Key Vault + K8s resources; the pooled AKS cluster is provisioned before the timer starts.
+	maxDuration := 10 * time.Minute
 	require.LessOrEqual(t, duration, maxDuration, "Kubernetes secrets creation took %v, expected less than %v", duration, maxDuration)
 }
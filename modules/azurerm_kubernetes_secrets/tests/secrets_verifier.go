@@ -0,0 +1,262 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/leakscan"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+var (
+	secretProviderClassGVR = schema.GroupVersionResource{Group: "secrets-store.csi.x-k8s.io", Version: "v1", Resource: "secretproviderclasses"}
+	secretStoreGVR         = schema.GroupVersionResource{Group: "external-secrets.io", Version: "v1", Resource: "secretstores"}
+	clusterSecretStoreGVR  = schema.GroupVersionResource{Group: "external-secrets.io", Version: "v1", Resource: "clustersecretstores"}
+	externalSecretGVR      = schema.GroupVersionResource{Group: "external-secrets.io", Version: "v1", Resource: "externalsecrets"}
+)
+
+// ExpectedSecretObject maps a Key Vault object to the Kubernetes Secret key it populates
+type ExpectedSecretObject struct {
+	ObjectName string
+	SecretKey  string
+}
+
+// SecretsVerifier reads secrets-related objects straight from the Kubernetes API.
+// It works against AKS, kind or an envtest API server; kubectl is not required.
+type SecretsVerifier struct {
+	core    kubernetes.Interface
+	dynamic dynamic.Interface
+}
+
+// NewSecretsVerifier creates a verifier from existing clients
+func NewSecretsVerifier(core kubernetes.Interface, dyn dynamic.Interface) *SecretsVerifier {
+	return &SecretsVerifier{core: core, dynamic: dyn}
+}
+
+// NewSecretsVerifierForConfig creates a verifier from a REST config
+func NewSecretsVerifierForConfig(t testing.TB, config *rest.Config) *SecretsVerifier {
+	core, err := kubernetes.NewForConfig(config)
+	require.NoError(t, err, "Failed to create Kubernetes client")
+
+	dyn, err := dynamic.NewForConfig(config)
+	require.NoError(t, err, "Failed to create Kubernetes dynamic client")
+
+	return NewSecretsVerifier(core, dyn)
+}
+
+// NewSecretsVerifierFromTerraform creates a verifier from the fixture kube_config_raw output
+func NewSecretsVerifierFromTerraform(t testing.TB, terraformOptions *terraform.Options) *SecretsVerifier {
+	return NewSecretsVerifierForConfig(t, restConfigFromTerraform(t, terraformOptions))
+}
+
+func restConfigFromTerraform(t testing.TB, terraformOptions *terraform.Options) *rest.Config {
+	kubeconfig := leakscan.SensitiveOutput(t, terraformOptions, "kube_config_raw")
+	require.NotEmpty(t, kubeconfig, "kube_config_raw output is empty")
+
+	config, err := clientcmd.RESTConfigFromKubeConfig([]byte(kubeconfig))
+	require.NoError(t, err, "Failed to parse kube_config_raw")
+	config.Timeout = 30 * time.Second
+	return config
+}
+
+// VerifySecret checks that a Secret exists with the given type and exactly the expected keys
+func (v *SecretsVerifier) VerifySecret(ctx context.Context, namespace, name, secretType string, expectedKeys []string) error {
+	secret, err := v.core.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get secret %s/%s: %w", namespace, name, err)
+	}
+	if secretType != "" && string(secret.Type) != secretType {
+		return fmt.Errorf("secret %s/%s type is %s, expected %s", namespace, name, secret.Type, secretType)
+	}
+
+	actualKeys := make([]string, 0, len(secret.Data))
+	for key, value := range secret.Data {
+		if len(value) == 0 {
+			return fmt.Errorf("secret %s/%s key %s is empty", namespace, name, key)
+		}
+		actualKeys = append(actualKeys, key)
+	}
+	return compareKeys(fmt.Sprintf("secret %s/%s", namespace, name), expectedKeys, actualKeys)
+}
+
+// VerifySecretProviderClass checks the SecretProviderClass targets the Key Vault and maps objects to secret keys
+func (v *SecretsVerifier) VerifySecretProviderClass(ctx context.Context, namespace, name, keyVaultName, syncedSecretName string, objects []ExpectedSecretObject) error {
+	spc, err := v.dynamic.Resource(secretProviderClassGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get SecretProviderClass %s/%s: %w", namespace, name, err)
+	}
+
+	provider, _, _ := unstructured.NestedString(spc.Object, "spec", "provider")
+	if provider != "azure" {
+		return fmt.Errorf("SecretProviderClass %s/%s provider is %q, expected azure", namespace, name, provider)
+	}
+
+	vault, _, _ := unstructured.NestedString(spc.Object, "spec", "parameters", "keyvaultName")
+	if vault != keyVaultName {
+		return fmt.Errorf("SecretProviderClass %s/%s keyvaultName is %q, expected %q", namespace, name, vault, keyVaultName)
+	}
+
+	objectsYAML, _, _ := unstructured.NestedString(spc.Object, "spec", "parameters", "objects")
+	for _, object := range objects {
+		if !strings.Contains(objectsYAML, object.ObjectName) {
+			return fmt.Errorf("SecretProviderClass %s/%s does not reference Key Vault object %s", namespace, name, object.ObjectName)
+		}
+	}
+
+	if syncedSecretName == "" {
+		return nil
+	}
+
+	secretObjects, _, _ := unstructured.NestedSlice(spc.Object, "spec", "secretObjects")
+	for _, raw := range secretObjects {
+		secretObject, ok := raw.(map[string]interface{})
+		if !ok || secretObject["secretName"] != syncedSecretName {
+			continue
+		}
+		data, _, _ := unstructured.NestedSlice(secretObject, "data")
+		actual := make(map[string]string, len(data))
+		for _, rawEntry := range data {
+			entry, ok := rawEntry.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			key, _ := entry["key"].(string)
+			objectName, _ := entry["objectName"].(string)
+			actual[key] = objectName
+		}
+		return compareObjectMapping(fmt.Sprintf("SecretProviderClass %s/%s", namespace, name), objects, actual)
+	}
+	return fmt.Errorf("SecretProviderClass %s/%s does not sync secret %s", namespace, name, syncedSecretName)
+}
+
+// VerifySecretStoreReady checks the SecretStore/ClusterSecretStore reports Ready=True
+func (v *SecretsVerifier) VerifySecretStoreReady(ctx context.Context, kind, namespace, name string) error {
+	var store *unstructured.Unstructured
+	var err error
+	if kind == "ClusterSecretStore" {
+		store, err = v.dynamic.Resource(clusterSecretStoreGVR).Get(ctx, name, metav1.GetOptions{})
+	} else {
+		store, err = v.dynamic.Resource(secretStoreGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get %s %s: %w", kind, name, err)
+	}
+	return requireReadyCondition(fmt.Sprintf("%s %s", kind, name), store)
+}
+
+// VerifyExternalSecretSpec checks the ExternalSecret targets the store and maps remote keys to secret keys
+func (v *SecretsVerifier) VerifyExternalSecretSpec(ctx context.Context, namespace, name, storeName, targetSecretName string, objects []ExpectedSecretObject) error {
+	externalSecret, err := v.dynamic.Resource(externalSecretGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get ExternalSecret %s/%s: %w", namespace, name, err)
+	}
+
+	store, _, _ := unstructured.NestedString(externalSecret.Object, "spec", "secretStoreRef", "name")
+	if store != storeName {
+		return fmt.Errorf("ExternalSecret %s/%s references store %q, expected %q", namespace, name, store, storeName)
+	}
+	target, _, _ := unstructured.NestedString(externalSecret.Object, "spec", "target", "name")
+	if target != targetSecretName {
+		return fmt.Errorf("ExternalSecret %s/%s targets secret %q, expected %q", namespace, name, target, targetSecretName)
+	}
+
+	data, _, _ := unstructured.NestedSlice(externalSecret.Object, "spec", "data")
+	actual := make(map[string]string, len(data))
+	for _, rawEntry := range data {
+		entry, ok := rawEntry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		secretKey, _ := entry["secretKey"].(string)
+		remoteKey, _, _ := unstructured.NestedString(entry, "remoteRef", "key")
+		actual[secretKey] = remoteKey
+	}
+	return compareObjectMapping(fmt.Sprintf("ExternalSecret %s/%s", namespace, name), objects, actual)
+}
+
+// VerifyExternalSecretSynced checks the ExternalSecret reports Ready=True (SecretSynced)
+func (v *SecretsVerifier) VerifyExternalSecretSynced(ctx context.Context, namespace, name string) error {
+	externalSecret, err := v.dynamic.Resource(externalSecretGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get ExternalSecret %s/%s: %w", namespace, name, err)
+	}
+	return requireReadyCondition(fmt.Sprintf("ExternalSecret %s/%s", namespace, name), externalSecret)
+}
+
+// WaitForVerification retries a verifier check until it passes or the attempts run out
+func WaitForVerification(t testing.TB, description string, check func(ctx context.Context) error) {
+	retry.DoWithRetry(t, description, 30, 10*time.Second, func() (string, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := check(ctx); err != nil {
+			return "", err
+		}
+		return description + " passed", nil
+	})
+}
+
+// SecretKeys returns the Kubernetes Secret keys expected for the given objects
+func SecretKeys(objects []ExpectedSecretObject) []string {
+	keys := make([]string, 0, len(objects))
+	for _, object := range objects {
+		keys = append(keys, object.SecretKey)
+	}
+	return keys
+}
+
+func requireReadyCondition(subject string, object *unstructured.Unstructured) error {
+	conditions, _, _ := unstructured.NestedSlice(object.Object, "status", "conditions")
+	for _, raw := range conditions {
+		condition, ok := raw.(map[string]interface{})
+		if !ok || condition["type"] != "Ready" {
+			continue
+		}
+		if condition["status"] == "True" {
+			return nil
+		}
+		return fmt.Errorf("%s is not Ready: reason=%v message=%v", subject, condition["reason"], condition["message"])
+	}
+	return fmt.Errorf("%s has no Ready condition yet", subject)
+}
+
+func compareObjectMapping(subject string, expected []ExpectedSecretObject, actual map[string]string) error {
+	if len(actual) != len(expected) {
+		return fmt.Errorf("%s maps %d keys, expected %d", subject, len(actual), len(expected))
+	}
+	for _, object := range expected {
+		objectName, ok := actual[object.SecretKey]
+		if !ok {
+			return fmt.Errorf("%s is missing secret key %s", subject, object.SecretKey)
+		}
+		if objectName != object.ObjectName {
+			return fmt.Errorf("%s maps key %s to %q, expected %q", subject, object.SecretKey, objectName, object.ObjectName)
+		}
+	}
+	return nil
+}
+
+func compareKeys(subject string, expected, actual []string) error {
+	expectedSorted := append([]string(nil), expected...)
+	actualSorted := append([]string(nil), actual...)
+	sort.Strings(expectedSorted)
+	sort.Strings(actualSorted)
+
+	if strings.Join(expectedSorted, ",") != strings.Join(actualSorted, ",") {
+		return fmt.Errorf("%s keys are [%s], expected [%s]", subject, strings.Join(actualSorted, ", "), strings.Join(expectedSorted, ", "))
+	}
+	return nil
+}
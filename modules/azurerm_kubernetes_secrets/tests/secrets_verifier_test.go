@@ -0,0 +1,184 @@
+package test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+var testSecretObjects = []ExpectedSecretObject{{ObjectName: "db-password", SecretKey: "DB_PASSWORD"}}
+
+func newTestSecret() *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-secrets", Namespace: "app"},
+		Type:       corev1.SecretTypeOpaque,
+		Data:       map[string][]byte{"DB_PASSWORD": []byte("s3cret")},
+	}
+}
+
+func newTestSecretProviderClass() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "secrets-store.csi.x-k8s.io/v1",
+		"kind":       "SecretProviderClass",
+		"metadata":   map[string]interface{}{"name": "app-spc", "namespace": "app"},
+		"spec": map[string]interface{}{
+			"provider": "azure",
+			"parameters": map[string]interface{}{
+				"keyvaultName": "kv-test",
+				"objects":      "array:\n  - |\n    objectName: db-password\n    objectType: secret\n",
+			},
+			"secretObjects": []interface{}{
+				map[string]interface{}{
+					"secretName": "app-secrets",
+					"type":       "Opaque",
+					"data": []interface{}{
+						map[string]interface{}{"key": "DB_PASSWORD", "objectName": "db-password"},
+					},
+				},
+			},
+		},
+	}}
+}
+
+func newTestExternalSecret(ready string) *unstructured.Unstructured {
+	object := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "external-secrets.io/v1",
+		"kind":       "ExternalSecret",
+		"metadata":   map[string]interface{}{"name": "db-secret", "namespace": "app"},
+		"spec": map[string]interface{}{
+			"secretStoreRef": map[string]interface{}{"name": "kv-store", "kind": "SecretStore"},
+			"target":         map[string]interface{}{"name": "app-secrets"},
+			"data": []interface{}{
+				map[string]interface{}{"secretKey": "DB_PASSWORD", "remoteRef": map[string]interface{}{"key": "db-password"}},
+			},
+		},
+	}}
+	if ready != "" {
+		object.Object["status"] = map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Ready", "status": ready, "reason": "SecretSynced"},
+			},
+		}
+	}
+	return object
+}
+
+func newFakeSecretsVerifier(objects ...runtime.Object) *SecretsVerifier {
+	listKinds := map[schema.GroupVersionResource]string{
+		secretProviderClassGVR: "SecretProviderClassList",
+		secretStoreGVR:         "SecretStoreList",
+		clusterSecretStoreGVR:  "ClusterSecretStoreList",
+		externalSecretGVR:      "ExternalSecretList",
+	}
+	dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, objects...)
+	return NewSecretsVerifier(fake.NewSimpleClientset(newTestSecret()), dyn)
+}
+
+// Test the verifier against in-memory clients; no cluster is required
+func TestSecretsVerifierFakeClients(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	verifier := newFakeSecretsVerifier(newTestSecretProviderClass(), newTestExternalSecret("True"))
+
+	require.NoError(t, verifier.VerifySecret(ctx, "app", "app-secrets", "Opaque", []string{"DB_PASSWORD"}))
+	require.NoError(t, verifier.VerifySecretProviderClass(ctx, "app", "app-spc", "kv-test", "app-secrets", testSecretObjects))
+	require.NoError(t, verifier.VerifyExternalSecretSpec(ctx, "app", "db-secret", "kv-store", "app-secrets", testSecretObjects))
+	require.NoError(t, verifier.VerifyExternalSecretSynced(ctx, "app", "db-secret"))
+
+	err := verifier.VerifySecret(ctx, "app", "app-secrets", "Opaque", []string{"DB_PASSWORD", "API_KEY"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "keys are [DB_PASSWORD]")
+
+	err = verifier.VerifySecretProviderClass(ctx, "app", "app-spc", "kv-other", "app-secrets", testSecretObjects)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "keyvaultName")
+
+	err = verifier.VerifyExternalSecretSpec(ctx, "app", "db-secret", "other-store", "app-secrets", testSecretObjects)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "references store")
+
+	err = verifier.VerifySecretStoreReady(ctx, "SecretStore", "app", "kv-store")
+	require.Error(t, err)
+	assert.True(t, apierrors.IsNotFound(err), "missing store should surface NotFound, got %v", err)
+}
+
+// Test an ExternalSecret without a Ready=True condition is reported as not synced
+func TestSecretsVerifierExternalSecretNotSynced(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	err := newFakeSecretsVerifier(newTestExternalSecret("")).VerifyExternalSecretSynced(ctx, "app", "db-secret")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "has no Ready condition")
+
+	err = newFakeSecretsVerifier(newTestExternalSecret("False")).VerifyExternalSecretSynced(ctx, "app", "db-secret")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is not Ready")
+}
+
+// Test the verifier against a real API server (kind or envtest).
+// Set SECRETS_VERIFIER_KUBECONFIG to a kubeconfig for a disposable cluster.
+func TestSecretsVerifierLocalAPIServer(t *testing.T) {
+	kubeconfigPath := os.Getenv("SECRETS_VERIFIER_KUBECONFIG")
+	if kubeconfigPath == "" {
+		t.Skip("SECRETS_VERIFIER_KUBECONFIG is not set; point it at a kind or envtest cluster")
+	}
+
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+	require.NoError(t, err, "Failed to load %s", kubeconfigPath)
+
+	core, err := kubernetes.NewForConfig(config)
+	require.NoError(t, err)
+	dyn, err := dynamic.NewForConfig(config)
+	require.NoError(t, err)
+
+	manifest, err := os.Open("testdata/crds/secrets-crds.yaml")
+	require.NoError(t, err)
+	defer manifest.Close()
+
+	applyCRDs(t, dyn, manifest)
+	waitForCRD(t, dyn, "secretproviderclasses.secrets-store.csi.x-k8s.io")
+	waitForCRD(t, dyn, "externalsecrets.external-secrets.io")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "app"}}
+	if _, err := core.CoreV1().Namespaces().Create(ctx, namespace, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		require.NoError(t, err)
+	}
+
+	_, err = core.CoreV1().Secrets("app").Create(ctx, newTestSecret(), metav1.CreateOptions{})
+	require.NoError(t, err)
+	defer core.CoreV1().Secrets("app").Delete(context.Background(), "app-secrets", metav1.DeleteOptions{})
+
+	_, err = dyn.Resource(secretProviderClassGVR).Namespace("app").Create(ctx, newTestSecretProviderClass(), metav1.CreateOptions{})
+	require.NoError(t, err)
+	defer dyn.Resource(secretProviderClassGVR).Namespace("app").Delete(context.Background(), "app-spc", metav1.DeleteOptions{})
+
+	_, err = dyn.Resource(externalSecretGVR).Namespace("app").Create(ctx, newTestExternalSecret(""), metav1.CreateOptions{})
+	require.NoError(t, err)
+	defer dyn.Resource(externalSecretGVR).Namespace("app").Delete(context.Background(), "db-secret", metav1.DeleteOptions{})
+
+	verifier := NewSecretsVerifierForConfig(t, config)
+	require.NoError(t, verifier.VerifySecret(ctx, "app", "app-secrets", "Opaque", []string{"DB_PASSWORD"}))
+	require.NoError(t, verifier.VerifySecretProviderClass(ctx, "app", "app-spc", "kv-test", "app-secrets", testSecretObjects))
+	require.NoError(t, verifier.VerifyExternalSecretSpec(ctx, "app", "db-secret", "kv-store", "app-secrets", testSecretObjects))
+}
@@ -12,3 +12,9 @@ output "resource_group_name" {
   description = "The resource group name"
   value       = azurerm_resource_group.example.name
 }
+
+output "storage_account_access_key" {
+  description = "The access key of the Function App storage account."
+  value       = azurerm_storage_account.example.primary_access_key
+  sensitive   = true
+}
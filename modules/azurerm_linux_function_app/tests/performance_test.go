@@ -5,7 +5,8 @@ import (
 	"testing"
 	"time"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/require"
 )
@@ -23,14 +24,17 @@ func BenchmarkLinuxFunctionAppCreationSimple(b *testing.B) {
 		b.StartTimer()
 
 		start := time.Now()
-		terraform.InitAndApply(b, terraformOptions)
+		tfretry.InitAndApplyWithRetry(b, terraformOptions)
 		creationTime := time.Since(start)
 
 		b.StopTimer()
-		terraform.Destroy(b, terraformOptions)
+		destroyStart := time.Now()
+		tfretry.DestroyWithRetry(b, terraformOptions)
+		destroyTime := time.Since(destroyStart)
 		b.StartTimer()
 
 		b.ReportMetric(float64(creationTime.Milliseconds()), "creation_ms")
+		b.ReportMetric(float64(destroyTime.Milliseconds()), "destroy_ms")
 	}
 }
 
@@ -69,14 +73,17 @@ func BenchmarkLinuxFunctionAppCreationWithFeatures(b *testing.B) {
 				b.StartTimer()
 
 				start := time.Now()
-				terraform.InitAndApply(b, terraformOptions)
+				tfretry.InitAndApplyWithRetry(b, terraformOptions)
 				creationTime := time.Since(start)
 
 				b.StopTimer()
-				terraform.Destroy(b, terraformOptions)
+				destroyStart := time.Now()
+				tfretry.DestroyWithRetry(b, terraformOptions)
+				destroyTime := time.Since(destroyStart)
 				b.StartTimer()
 
 				b.ReportMetric(float64(creationTime.Milliseconds()), "creation_ms")
+				b.ReportMetric(float64(destroyTime.Milliseconds()), "destroy_ms")
 			}
 		})
 	}
@@ -101,14 +108,17 @@ func BenchmarkLinuxFunctionAppCreationWithScale(b *testing.B) {
 				b.StartTimer()
 
 				start := time.Now()
-				terraform.InitAndApply(b, terraformOptions)
+				tfretry.InitAndApplyWithRetry(b, terraformOptions)
 				creationTime := time.Since(start)
 
 				b.StopTimer()
-				terraform.Destroy(b, terraformOptions)
+				destroyStart := time.Now()
+				tfretry.DestroyWithRetry(b, terraformOptions)
+				destroyTime := time.Since(destroyStart)
 				b.StartTimer()
 
 				b.ReportMetric(float64(creationTime.Milliseconds()), "creation_ms")
+				b.ReportMetric(float64(destroyTime.Milliseconds()), "destroy_ms")
 				b.ReportMetric(float64(count), "scale_count")
 			}
 		})
@@ -131,12 +141,15 @@ func BenchmarkLinuxFunctionAppParallelCreation(b *testing.B) {
 					terraformOptions.Vars["random_suffix"] = fmt.Sprintf("par%d%d%s", parallel, i, terraformOptions.Vars["random_suffix"].(string)[:5])
 
 					start := time.Now()
-					terraform.InitAndApply(b, terraformOptions)
+					tfretry.InitAndApplyWithRetry(b, terraformOptions)
 					creationTime := time.Since(start)
 
-					terraform.Destroy(b, terraformOptions)
+					destroyStart := time.Now()
+					tfretry.DestroyWithRetry(b, terraformOptions)
+					destroyTime := time.Since(destroyStart)
 
 					b.ReportMetric(float64(creationTime.Milliseconds()), "creation_ms")
+					b.ReportMetric(float64(destroyTime.Milliseconds()), "destroy_ms")
 					i++
 				}
 			})
@@ -154,10 +167,10 @@ func TestLinuxFunctionAppCreationTime(t *testing.T) {
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/basic")
 	terraformOptions := getTerraformOptions(t, testFolder)
 
-	defer terraform.Destroy(t, terraformOptions)
+	defer destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 
 	start := time.Now()
-	terraform.InitAndApply(t, terraformOptions)
+	tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	duration := time.Since(start)
 
 	// linux_function_app creation should complete within 5 minutes
@@ -186,11 +199,11 @@ func TestLinuxFunctionAppScaling(t *testing.T) {
 		terraformOptions.Vars["random_suffix"] = fmt.Sprintf("scale%d%s", i, terraformOptions.Vars["random_suffix"].(string)[:5])
 
 		start := time.Now()
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 		creationTimes[i] = time.Since(start)
 
 		// Cleanup immediately to avoid hitting limits
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 
 		t.Logf("Linux Function App instance %d created in %v", i, creationTimes[i])
 	}
@@ -220,10 +233,10 @@ func TestLinuxFunctionAppUpdatePerformance(t *testing.T) {
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/basic")
 	terraformOptions := getTerraformOptions(t, testFolder)
 
-	defer terraform.Destroy(t, terraformOptions)
+	defer destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 
 	// Initial deployment
-	terraform.InitAndApply(t, terraformOptions)
+	tfretry.InitAndApplyWithRetry(t, terraformOptions)
 
 	// Measure re-apply time for idempotent updates.
 	updateScenarios := []string{
@@ -234,7 +247,7 @@ func TestLinuxFunctionAppUpdatePerformance(t *testing.T) {
 		t.Run(scenarioName, func(t *testing.T) {
 
 			start := time.Now()
-			terraform.Apply(t, terraformOptions)
+			tfretry.ApplyWithRetry(t, terraformOptions)
 			updateTime := time.Since(start)
 
 			t.Logf("%s completed in %v", scenarioName, updateTime)
@@ -257,11 +270,11 @@ func TestLinuxFunctionAppDestroyPerformance(t *testing.T) {
 	terraformOptions := getTerraformOptions(t, testFolder)
 
 	// Create resource
-	terraform.InitAndApply(t, terraformOptions)
+	tfretry.InitAndApplyWithRetry(t, terraformOptions)
 
 	// Measure destroy time
 	start := time.Now()
-	terraform.Destroy(t, terraformOptions)
+	destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	destroyTime := time.Since(start)
 
 	t.Logf("Linux Function App destroyed in %v", destroyTime)
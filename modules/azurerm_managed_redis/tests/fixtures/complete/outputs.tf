@@ -17,3 +17,9 @@ output "default_database_port" {
   description = "The default database port."
   value       = try(module.managed_redis.default_database.port, null)
 }
+
+output "default_database_primary_access_key" {
+  description = "The primary access key of the default database."
+  value       = module.managed_redis.default_database_primary_access_key
+  sensitive   = true
+}
@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/require"
@@ -94,16 +95,6 @@ func WaitForResourceDeletion(ctx context.Context, checkFunc func() (bool, error)
 	}
 }
 
-// GenerateResourceName generates a unique resource name for testing.
-func GenerateResourceName(prefix string, uniqueID string) string {
-	name := fmt.Sprintf("%s%s", prefix, uniqueID)
-	name = strings.ReplaceAll(name, "-", "")
-	if len(name) > 63 {
-		name = name[:63]
-	}
-	return strings.ToLower(name)
-}
-
 // PrepareTerraformWorkingDirs removes local Terraform artifacts from copied fixtures.
 // This prevents stale module snapshots in .terraform from previous runs.
 func PrepareTerraformWorkingDirs(t testing.TB, terraformDir string) {
@@ -220,24 +211,11 @@ func NewTerraformOptions(t testing.TB, terraformDir string) *terraform.Options {
 			"random_suffix": testConfig.UniqueID,
 			"location":      location,
 		},
-		EnvVars: NormalizedTerraformEnv(),
-		NoColor: true,
-		RetryableTerraformErrors: map[string]string{
-			".*timeout.*":                          "Timeout error - retrying",
-			".*ResourceGroupNotFound.*":            "Resource group not found - retrying",
-			".*AlreadyExists.*":                    "Resource already exists - retrying",
-			".*TooManyRequests.*":                  "Too many requests - retrying",
-			".*InternalServerError.*":              "Internal server error - retrying",
-			".*ServiceUnavailable.*":               "Service unavailable - retrying",
-			".*Conflict.*":                         "Conflict error - retrying",
-			".*another operation is in progress.*": "Concurrent Azure operation in progress - retrying",
-			".*operation.*in progress.*":           "Operation already in progress - retrying",
-			".*OperationTimedOut.*":                "Operation timed out - retrying",
-			".*context deadline exceeded.*":        "Context deadline exceeded - retrying",
-			".*transport is closing.*":             "Transient transport close - retrying",
-		},
-		MaxRetries:         8,
-		TimeBetweenRetries: 30 * time.Second,
+		EnvVars:                  NormalizedTerraformEnv(),
+		NoColor:                  true,
+		RetryableTerraformErrors: tfretry.ClassifiedRetryableErrors(),
+		MaxRetries:               8,
+		TimeBetweenRetries:       30 * time.Second,
 	}
 }
 
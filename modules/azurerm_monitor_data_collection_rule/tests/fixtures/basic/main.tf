@@ -13,25 +13,13 @@ provider "azurerm" {
   features {}
 }
 
-resource "azurerm_resource_group" "example" {
-  name     = "rg-dcr-basic-${var.random_suffix}"
-  location = var.location
-}
-
-resource "azurerm_log_analytics_workspace" "example" {
-  name                = "law-dcr-basic-${var.random_suffix}"
-  location            = var.location
-  resource_group_name = azurerm_resource_group.example.name
-  sku                 = "PerGB2018"
-  retention_in_days   = 30
-}
-
+# The resource group and Log Analytics workspace come from the shared network baseline leased by the tests
 module "monitor_data_collection_endpoint" {
   source = "../../../../azurerm_monitor_data_collection_endpoint"
 
   name                = "dcebasic${var.random_suffix}"
-  resource_group_name = azurerm_resource_group.example.name
-  location            = azurerm_resource_group.example.location
+  resource_group_name = var.baseline_resource_group_name
+  location            = var.location
   kind                = "Windows"
 
   tags = {
@@ -44,8 +32,8 @@ module "monitor_data_collection_rule" {
   source = "../../../"
 
   name                = "dcrbasic${var.random_suffix}"
-  resource_group_name = azurerm_resource_group.example.name
-  location            = azurerm_resource_group.example.location
+  resource_group_name = var.baseline_resource_group_name
+  location            = var.location
   kind                = "Windows"
 
   data_collection_endpoint_id = module.monitor_data_collection_endpoint.id
@@ -54,7 +42,7 @@ module "monitor_data_collection_rule" {
     log_analytics = [
       {
         name                  = "log-analytics"
-        workspace_resource_id = azurerm_log_analytics_workspace.example.id
+        workspace_resource_id = var.baseline_log_analytics_workspace_id
       }
     ]
   }
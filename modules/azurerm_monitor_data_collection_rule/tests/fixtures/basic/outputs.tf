@@ -10,5 +10,5 @@ output "monitor_data_collection_rule_name" {
 
 output "resource_group_name" {
   description = "The name of the resource group used for the Data Collection Rule"
-  value       = azurerm_resource_group.example.name
+  value       = var.baseline_resource_group_name
 }
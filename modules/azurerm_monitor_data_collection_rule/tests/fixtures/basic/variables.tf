@@ -17,3 +17,13 @@ variable "tags" {
     Example     = "Basic"
   }
 }
+
+variable "baseline_resource_group_name" {
+  description = "Resource group of the shared network baseline."
+  type        = string
+}
+
+variable "baseline_log_analytics_workspace_id" {
+  description = "Log Analytics workspace of the shared network baseline."
+  type        = string
+}
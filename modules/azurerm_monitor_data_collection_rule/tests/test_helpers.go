@@ -72,16 +72,6 @@ func WaitForResourceProvisioning(ctx context.Context, t *testing.T, maxWait time
 	return ctx
 }
 
-// GenerateResourceName generates a unique resource name for testing
-func GenerateResourceName(prefix string, uniqueID string) string {
-	name := fmt.Sprintf("%s%s", prefix, uniqueID)
-	name = strings.ReplaceAll(name, "-", "")
-	if len(name) > 63 {
-		name = name[:63]
-	}
-	return strings.ToLower(name)
-}
-
 // OutputBool reads a Terraform output and parses it as a boolean.
 func OutputBool(t testing.TB, terraformOptions *terraform.Options, name string) bool {
 	value := terraform.Output(t, terraformOptions, name)
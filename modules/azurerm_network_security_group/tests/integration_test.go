@@ -3,6 +3,9 @@ package test
 import (
 	"testing"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/assert"
@@ -19,13 +22,13 @@ func TestNetworkSecurityGroupLifecycle(t *testing.T) {
 
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate_initial", func() {
@@ -49,7 +52,7 @@ func TestNetworkSecurityGroupLifecycle(t *testing.T) {
 			"Update":      "true",
 		}
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.Apply(t, terraformOptions)
+		tfretry.ApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate_update", func() {
@@ -74,13 +77,13 @@ func TestSecureNetworkSecurityGroup(t *testing.T) {
 
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -105,5 +108,24 @@ func TestSecureNetworkSecurityGroup(t *testing.T) {
 			}
 		}
 		assert.True(t, denyRuleFound, "High-priority deny rule was not found.")
+
+		// Evaluate the intended behavior of the rules rather than their count.
+		evaluator := NewNsgFlowEvaluator("10.0.0.0/16")
+		RequireNsgFlow(t, evaluator, nsg, NsgFlow{
+			Direction:          armnetwork.SecurityRuleDirectionOutbound,
+			Protocol:           armnetwork.SecurityRuleProtocolTCP,
+			SourceAddress:      "10.0.1.4",
+			SourcePort:         50000,
+			DestinationAddress: "10.0.2.5",
+			DestinationPort:    443,
+		}, true, "allow_corp_outbound")
+		RequireNsgFlow(t, evaluator, nsg, NsgFlow{
+			Direction:          armnetwork.SecurityRuleDirectionInbound,
+			Protocol:           armnetwork.SecurityRuleProtocolTCP,
+			SourceAddress:      "203.0.113.10",
+			SourcePort:         50000,
+			DestinationAddress: "10.0.2.5",
+			DestinationPort:    443,
+		}, false, "deny_all_inbound")
 	})
 }
@@ -3,6 +3,10 @@ package test
 import (
 	"testing"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/importtest"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/assert"
@@ -17,13 +21,13 @@ func TestSimpleNetworkSecurityGroup(t *testing.T) {
 
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -36,6 +40,11 @@ func TestSimpleNetworkSecurityGroup(t *testing.T) {
 
 		assert.Equal(t, nsgName, *nsg.Name, "NSG name should match the output.")
 	})
+
+	// Adopting the deployed module resources into an empty state must plan no changes
+	test_structure.RunTestStage(t, "import", func() {
+		importtest.RequireStateImportRoundTrip(t, test_structure.LoadTerraformOptions(t, testFolder))
+	})
 }
 
 // TestCompleteNetworkSecurityGroup tests the complete NSG fixture.
@@ -46,13 +55,13 @@ func TestCompleteNetworkSecurityGroup(t *testing.T) {
 
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -78,13 +87,13 @@ func TestNetworkNetworkSecurityGroup(t *testing.T) {
 
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -98,6 +107,25 @@ func TestNetworkNetworkSecurityGroup(t *testing.T) {
 		assert.Equal(t, nsgName, *nsg.Name, "NSG name should match the output.")
 		// The network fixture defines 1 custom rule with multiple source prefixes and destination port ranges
 		helper.ValidateNsgSecurityRules(t, nsg, 1)
+
+		// Traffic from a listed source on a listed port is allowed; other sources fall through to DenyAllInBound.
+		evaluator := NewNsgFlowEvaluator("10.0.0.0/16")
+		RequireNsgFlow(t, evaluator, nsg, NsgFlow{
+			Direction:          armnetwork.SecurityRuleDirectionInbound,
+			Protocol:           armnetwork.SecurityRuleProtocolTCP,
+			SourceAddress:      "10.10.4.20",
+			SourcePort:         50000,
+			DestinationAddress: "10.0.2.5",
+			DestinationPort:    443,
+		}, true, "allow_multiple_ports_and_sources")
+		RequireNsgFlow(t, evaluator, nsg, NsgFlow{
+			Direction:          armnetwork.SecurityRuleDirectionInbound,
+			Protocol:           armnetwork.SecurityRuleProtocolTCP,
+			SourceAddress:      "192.168.2.10",
+			SourcePort:         50000,
+			DestinationAddress: "10.0.2.5",
+			DestinationPort:    443,
+		}, false, "DenyAllInBound")
 	})
 }
 
@@ -0,0 +1,314 @@
+package test
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork"
+	"github.com/stretchr/testify/require"
+)
+
+// azureLoadBalancerAddress is the platform address behind the AzureLoadBalancer service tag
+const azureLoadBalancerAddress = "168.63.129.16"
+
+// NsgFlow describes a single packet flow to evaluate against a Network Security Group
+type NsgFlow struct {
+	Direction          armnetwork.SecurityRuleDirection
+	Protocol           armnetwork.SecurityRuleProtocol
+	SourceAddress      string
+	SourcePort         int
+	DestinationAddress string
+	DestinationPort    int
+}
+
+// NsgFlowDecision is the outcome of an evaluation and the rule that decided it
+type NsgFlowDecision struct {
+	Allowed     bool
+	RuleName    string
+	Priority    int32
+	DefaultRule bool
+}
+
+// NsgFlowEvaluator answers flow questions for a fetched Network Security Group without calling Azure.
+// VirtualNetworkPrefixes defines what the VirtualNetwork service tag covers for the NSG under test.
+type NsgFlowEvaluator struct {
+	VirtualNetworkPrefixes []string
+}
+
+type evaluatedRule struct {
+	name        string
+	priority    int32
+	defaultRule bool
+	properties  *armnetwork.SecurityRulePropertiesFormat
+}
+
+// NewNsgFlowEvaluator creates an evaluator where the VirtualNetwork tag covers the given prefixes
+func NewNsgFlowEvaluator(virtualNetworkPrefixes ...string) *NsgFlowEvaluator {
+	return &NsgFlowEvaluator{VirtualNetworkPrefixes: virtualNetworkPrefixes}
+}
+
+// Evaluate processes custom and default rules in priority order; the first matching rule decides the flow
+func (e *NsgFlowEvaluator) Evaluate(nsg *armnetwork.SecurityGroup, flow NsgFlow) (NsgFlowDecision, error) {
+	if nsg == nil || nsg.Properties == nil {
+		return NsgFlowDecision{}, fmt.Errorf("network security group has no properties")
+	}
+	if flow.Protocol == "" {
+		flow.Protocol = armnetwork.SecurityRuleProtocolTCP
+	}
+
+	rules, err := collectRules(nsg, flow.Direction)
+	if err != nil {
+		return NsgFlowDecision{}, err
+	}
+
+	for _, rule := range rules {
+		matched, err := e.ruleMatches(rule, flow)
+		if err != nil {
+			return NsgFlowDecision{}, fmt.Errorf("rule %s: %w", rule.name, err)
+		}
+		if matched {
+			return NsgFlowDecision{
+				Allowed:     rule.properties.Access != nil && *rule.properties.Access == armnetwork.SecurityRuleAccessAllow,
+				RuleName:    rule.name,
+				Priority:    rule.priority,
+				DefaultRule: rule.defaultRule,
+			}, nil
+		}
+	}
+	return NsgFlowDecision{}, fmt.Errorf("no %s rule matched the flow", flow.Direction)
+}
+
+// RequireNsgFlow evaluates the flow and asserts both the decision and the deciding rule
+func RequireNsgFlow(t *testing.T, evaluator *NsgFlowEvaluator, nsg *armnetwork.SecurityGroup, flow NsgFlow, expectedAllowed bool, expectedRule string) {
+	decision, err := evaluator.Evaluate(nsg, flow)
+	require.NoError(t, err, "Failed to evaluate flow %+v", flow)
+	require.Equal(t, expectedRule, decision.RuleName, "Unexpected rule decided flow %+v", flow)
+	require.Equal(t, expectedAllowed, decision.Allowed, "Unexpected decision for flow %+v by rule %s", flow, decision.RuleName)
+}
+
+// collectRules returns the rules for one direction sorted by priority.
+// Azure's default rules are synthesised when the NSG payload does not include them.
+func collectRules(nsg *armnetwork.SecurityGroup, direction armnetwork.SecurityRuleDirection) ([]evaluatedRule, error) {
+	defaults := nsg.Properties.DefaultSecurityRules
+	if len(defaults) == 0 {
+		defaults = azureDefaultSecurityRules()
+	}
+
+	var rules []evaluatedRule
+	add := func(source []*armnetwork.SecurityRule, defaultRule bool) error {
+		for _, rule := range source {
+			if rule == nil || rule.Properties == nil {
+				continue
+			}
+			if rule.Properties.Direction == nil || *rule.Properties.Direction != direction {
+				continue
+			}
+			if rule.Properties.Priority == nil {
+				return fmt.Errorf("rule %s has no priority", derefString(rule.Name))
+			}
+			rules = append(rules, evaluatedRule{
+				name:        derefString(rule.Name),
+				priority:    *rule.Properties.Priority,
+				defaultRule: defaultRule,
+				properties:  rule.Properties,
+			})
+		}
+		return nil
+	}
+
+	if err := add(nsg.Properties.SecurityRules, false); err != nil {
+		return nil, err
+	}
+	if err := add(defaults, true); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].priority < rules[j].priority })
+	return rules, nil
+}
+
+func (e *NsgFlowEvaluator) ruleMatches(rule evaluatedRule, flow NsgFlow) (bool, error) {
+	p := rule.properties
+
+	if len(p.SourceApplicationSecurityGroups) > 0 || len(p.DestinationApplicationSecurityGroups) > 0 {
+		return false, fmt.Errorf("application security groups cannot be evaluated offline")
+	}
+
+	if p.Protocol != nil && *p.Protocol != armnetwork.SecurityRuleProtocolAsterisk &&
+		!strings.EqualFold(string(*p.Protocol), string(flow.Protocol)) {
+		return false, nil
+	}
+
+	for _, check := range []struct {
+		single   *string
+		multiple []*string
+		address  string
+	}{
+		{p.SourceAddressPrefix, p.SourceAddressPrefixes, flow.SourceAddress},
+		{p.DestinationAddressPrefix, p.DestinationAddressPrefixes, flow.DestinationAddress},
+	} {
+		matched, err := e.addressMatches(mergeValues(check.single, check.multiple), check.address)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+
+	for _, check := range []struct {
+		single   *string
+		multiple []*string
+		port     int
+	}{
+		{p.SourcePortRange, p.SourcePortRanges, flow.SourcePort},
+		{p.DestinationPortRange, p.DestinationPortRanges, flow.DestinationPort},
+	} {
+		matched, err := portMatches(mergeValues(check.single, check.multiple), check.port)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// addressMatches supports "*", CIDRs, single IPs and the VirtualNetwork, Internet and AzureLoadBalancer tags
+func (e *NsgFlowEvaluator) addressMatches(prefixes []string, address string) (bool, error) {
+	ip, err := netip.ParseAddr(address)
+	if err != nil {
+		return false, fmt.Errorf("invalid flow address %q: %w", address, err)
+	}
+
+	for _, prefix := range prefixes {
+		switch strings.ToLower(prefix) {
+		case "*", "any":
+			return true, nil
+		case "virtualnetwork":
+			if e.inVirtualNetwork(ip) {
+				return true, nil
+			}
+			continue
+		case "internet":
+			if !e.inVirtualNetwork(ip) && !ip.IsPrivate() && !ip.IsLoopback() {
+				return true, nil
+			}
+			continue
+		case "azureloadbalancer":
+			if ip == netip.MustParseAddr(azureLoadBalancerAddress) {
+				return true, nil
+			}
+			continue
+		}
+
+		matched, err := prefixContains(prefix, ip)
+		if err != nil {
+			return false, err
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (e *NsgFlowEvaluator) inVirtualNetwork(ip netip.Addr) bool {
+	for _, prefix := range e.VirtualNetworkPrefixes {
+		if matched, err := prefixContains(prefix, ip); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+func prefixContains(prefix string, ip netip.Addr) (bool, error) {
+	if strings.Contains(prefix, "/") {
+		network, err := netip.ParsePrefix(prefix)
+		if err != nil {
+			return false, fmt.Errorf("unsupported address prefix %q (service tags other than VirtualNetwork, Internet and AzureLoadBalancer are not evaluated)", prefix)
+		}
+		return network.Contains(ip), nil
+	}
+
+	single, err := netip.ParseAddr(prefix)
+	if err != nil {
+		return false, fmt.Errorf("unsupported address prefix %q (service tags other than VirtualNetwork, Internet and AzureLoadBalancer are not evaluated)", prefix)
+	}
+	return single == ip, nil
+}
+
+// portMatches supports "*", single ports and "low-high" ranges
+func portMatches(ranges []string, port int) (bool, error) {
+	for _, portRange := range ranges {
+		if portRange == "*" {
+			return true, nil
+		}
+
+		low, high, found := strings.Cut(portRange, "-")
+		if !found {
+			high = low
+		}
+		lowPort, err := strconv.Atoi(strings.TrimSpace(low))
+		if err != nil {
+			return false, fmt.Errorf("invalid port range %q", portRange)
+		}
+		highPort, err := strconv.Atoi(strings.TrimSpace(high))
+		if err != nil {
+			return false, fmt.Errorf("invalid port range %q", portRange)
+		}
+		if port >= lowPort && port <= highPort {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func mergeValues(single *string, multiple []*string) []string {
+	var values []string
+	if single != nil && *single != "" {
+		values = append(values, *single)
+	}
+	for _, value := range multiple {
+		if value != nil && *value != "" {
+			values = append(values, *value)
+		}
+	}
+	return values
+}
+
+func derefString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// azureDefaultSecurityRules mirrors the default rules Azure adds to every NSG
+func azureDefaultSecurityRules() []*armnetwork.SecurityRule {
+	rule := func(name string, priority int32, direction armnetwork.SecurityRuleDirection, access armnetwork.SecurityRuleAccess, source, destination string) *armnetwork.SecurityRule {
+		protocol := armnetwork.SecurityRuleProtocolAsterisk
+		wildcard := "*"
+		return &armnetwork.SecurityRule{
+			Name: &name,
+			Properties: &armnetwork.SecurityRulePropertiesFormat{
+				Priority:                 &priority,
+				Direction:                &direction,
+				Access:                   &access,
+				Protocol:                 &protocol,
+				SourceAddressPrefix:      &source,
+				SourcePortRange:          &wildcard,
+				DestinationAddressPrefix: &destination,
+				DestinationPortRange:     &wildcard,
+			},
+		}
+	}
+
+	return []*armnetwork.SecurityRule{
+		rule("AllowVnetInBound", 65000, armnetwork.SecurityRuleDirectionInbound, armnetwork.SecurityRuleAccessAllow, "VirtualNetwork", "VirtualNetwork"),
+		rule("AllowAzureLoadBalancerInBound", 65001, armnetwork.SecurityRuleDirectionInbound, armnetwork.SecurityRuleAccessAllow, "AzureLoadBalancer", "*"),
+		rule("DenyAllInBound", 65500, armnetwork.SecurityRuleDirectionInbound, armnetwork.SecurityRuleAccessDeny, "*", "*"),
+		rule("AllowVnetOutBound", 65000, armnetwork.SecurityRuleDirectionOutbound, armnetwork.SecurityRuleAccessAllow, "VirtualNetwork", "VirtualNetwork"),
+		rule("AllowInternetOutBound", 65001, armnetwork.SecurityRuleDirectionOutbound, armnetwork.SecurityRuleAccessAllow, "*", "Internet"),
+		rule("DenyAllOutBound", 65500, armnetwork.SecurityRuleDirectionOutbound, armnetwork.SecurityRuleAccessDeny, "*", "*"),
+	}
+}
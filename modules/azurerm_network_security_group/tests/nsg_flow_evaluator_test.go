@@ -0,0 +1,154 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// secureFixtureNsg mirrors the rules defined by fixtures/secure
+func secureFixtureNsg() *armnetwork.SecurityGroup {
+	return &armnetwork.SecurityGroup{
+		Name: to.Ptr("nsg-sec"),
+		Properties: &armnetwork.SecurityGroupPropertiesFormat{
+			SecurityRules: []*armnetwork.SecurityRule{
+				{
+					Name: to.Ptr("deny_all_inbound"),
+					Properties: &armnetwork.SecurityRulePropertiesFormat{
+						Priority:                 to.Ptr[int32](4000),
+						Direction:                to.Ptr(armnetwork.SecurityRuleDirectionInbound),
+						Access:                   to.Ptr(armnetwork.SecurityRuleAccessDeny),
+						Protocol:                 to.Ptr(armnetwork.SecurityRuleProtocolAsterisk),
+						SourcePortRange:          to.Ptr("*"),
+						DestinationPortRange:     to.Ptr("*"),
+						SourceAddressPrefix:      to.Ptr("Internet"),
+						DestinationAddressPrefix: to.Ptr("*"),
+					},
+				},
+				{
+					Name: to.Ptr("allow_corp_outbound"),
+					Properties: &armnetwork.SecurityRulePropertiesFormat{
+						Priority:                 to.Ptr[int32](100),
+						Direction:                to.Ptr(armnetwork.SecurityRuleDirectionOutbound),
+						Access:                   to.Ptr(armnetwork.SecurityRuleAccessAllow),
+						Protocol:                 to.Ptr(armnetwork.SecurityRuleProtocolTCP),
+						SourcePortRange:          to.Ptr("*"),
+						DestinationPortRange:     to.Ptr("443"),
+						SourceAddressPrefix:      to.Ptr("*"),
+						DestinationAddressPrefix: to.Ptr("10.0.0.0/8"),
+					},
+				},
+			},
+		},
+	}
+}
+
+// networkFixtureNsg mirrors the rules defined by fixtures/network
+func networkFixtureNsg() *armnetwork.SecurityGroup {
+	return &armnetwork.SecurityGroup{
+		Name: to.Ptr("nsg-net"),
+		Properties: &armnetwork.SecurityGroupPropertiesFormat{
+			SecurityRules: []*armnetwork.SecurityRule{
+				{
+					Name: to.Ptr("allow_multiple_ports_and_sources"),
+					Properties: &armnetwork.SecurityRulePropertiesFormat{
+						Priority:                 to.Ptr[int32](200),
+						Direction:                to.Ptr(armnetwork.SecurityRuleDirectionInbound),
+						Access:                   to.Ptr(armnetwork.SecurityRuleAccessAllow),
+						Protocol:                 to.Ptr(armnetwork.SecurityRuleProtocolTCP),
+						SourcePortRange:          to.Ptr("*"),
+						DestinationPortRanges:    []*string{to.Ptr("80"), to.Ptr("8080"), to.Ptr("443")},
+						SourceAddressPrefixes:    []*string{to.Ptr("192.168.1.0/24"), to.Ptr("10.10.0.0/16")},
+						DestinationAddressPrefix: to.Ptr("VirtualNetwork"),
+					},
+				},
+			},
+		},
+	}
+}
+
+// Test the evaluator answers flow questions for the secure fixture rules
+func TestNsgFlowEvaluatorSecureFixture(t *testing.T) {
+	t.Parallel()
+
+	nsg := secureFixtureNsg()
+	evaluator := NewNsgFlowEvaluator("10.0.0.0/16")
+
+	RequireNsgFlow(t, evaluator, nsg, secureFixtureFlow(armnetwork.SecurityRuleDirectionOutbound, "10.0.1.4", 443), true, "allow_corp_outbound")
+	RequireNsgFlow(t, evaluator, nsg, secureFixtureFlow(armnetwork.SecurityRuleDirectionInbound, "10.0.1.4", 443), true, "AllowVnetInBound")
+	RequireNsgFlow(t, evaluator, nsg, secureFixtureFlow(armnetwork.SecurityRuleDirectionInbound, "203.0.113.10", 443), false, "deny_all_inbound")
+
+	decision, err := evaluator.Evaluate(nsg, NsgFlow{
+		Direction:          armnetwork.SecurityRuleDirectionOutbound,
+		SourceAddress:      "10.0.1.4",
+		SourcePort:         50000,
+		DestinationAddress: "203.0.113.10",
+		DestinationPort:    443,
+	})
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+	assert.True(t, decision.DefaultRule)
+	assert.Equal(t, "AllowInternetOutBound", decision.RuleName)
+	assert.Equal(t, int32(65001), decision.Priority)
+}
+
+// Test the evaluator honours prefix lists and port lists from the network fixture
+func TestNsgFlowEvaluatorNetworkFixture(t *testing.T) {
+	t.Parallel()
+
+	nsg := networkFixtureNsg()
+	evaluator := NewNsgFlowEvaluator("10.0.0.0/16")
+
+	RequireNsgFlow(t, evaluator, nsg, NsgFlow{
+		Direction:          armnetwork.SecurityRuleDirectionInbound,
+		Protocol:           armnetwork.SecurityRuleProtocolTCP,
+		SourceAddress:      "10.10.4.20",
+		SourcePort:         50000,
+		DestinationAddress: "10.0.2.5",
+		DestinationPort:    8080,
+	}, true, "allow_multiple_ports_and_sources")
+
+	RequireNsgFlow(t, evaluator, nsg, NsgFlow{
+		Direction:          armnetwork.SecurityRuleDirectionInbound,
+		Protocol:           armnetwork.SecurityRuleProtocolTCP,
+		SourceAddress:      "192.168.2.10",
+		SourcePort:         50000,
+		DestinationAddress: "10.0.2.5",
+		DestinationPort:    443,
+	}, false, "DenyAllInBound")
+
+	RequireNsgFlow(t, evaluator, nsg, NsgFlow{
+		Direction:          armnetwork.SecurityRuleDirectionInbound,
+		Protocol:           armnetwork.SecurityRuleProtocolUDP,
+		SourceAddress:      "10.10.4.20",
+		SourcePort:         50000,
+		DestinationAddress: "10.0.2.5",
+		DestinationPort:    443,
+	}, false, "DenyAllInBound")
+}
+
+// Test the evaluator refuses rules it cannot evaluate offline
+func TestNsgFlowEvaluatorUnsupportedServiceTag(t *testing.T) {
+	t.Parallel()
+
+	nsg := secureFixtureNsg()
+	nsg.Properties.SecurityRules[1].Properties.DestinationAddressPrefix = to.Ptr("Storage")
+
+	_, err := NewNsgFlowEvaluator("10.0.0.0/16").Evaluate(nsg, secureFixtureFlow(armnetwork.SecurityRuleDirectionOutbound, "10.0.1.4", 443))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "allow_corp_outbound")
+}
+
+func secureFixtureFlow(direction armnetwork.SecurityRuleDirection, source string, port int) NsgFlow {
+	return NsgFlow{
+		Direction:          direction,
+		Protocol:           armnetwork.SecurityRuleProtocolTCP,
+		SourceAddress:      source,
+		SourcePort:         50000,
+		DestinationAddress: "10.0.2.5",
+		DestinationPort:    port,
+	}
+}
@@ -17,6 +17,8 @@ provider "azurerm" {
   features {}
 }
 
+data "azurerm_client_config" "current" {}
+
 resource "azurerm_resource_group" "example" {
   name     = "rg-pgfs-complete-${var.random_suffix}"
   location = var.location
@@ -68,21 +70,38 @@ module "postgresql_flexible_server" {
   }
 
   authentication = {
+    active_directory_auth_enabled = true
+    password_auth_enabled         = true
+    tenant_id                     = data.azurerm_client_config.current.tenant_id
     administrator = {
       login    = "pgfsadmin"
       password = random_password.admin.result
     }
+    active_directory_administrator = {
+      principal_name = "terratest-pgfs-admin"
+      object_id      = data.azurerm_client_config.current.object_id
+      principal_type = "ServicePrincipal"
+    }
   }
 
   network = {
     public_network_access_enabled = true
-    firewall_rules = [
-      {
-        name             = "office-range"
-        start_ip_address = "203.0.113.0"
-        end_ip_address   = "203.0.113.255"
-      }
-    ]
+    firewall_rules = concat(
+      [
+        {
+          name             = "office-range"
+          start_ip_address = "203.0.113.0"
+          end_ip_address   = "203.0.113.255"
+        }
+      ],
+      var.probe_client_ip == "" ? [] : [
+        {
+          name             = "probe-client"
+          start_ip_address = var.probe_client_ip
+          end_ip_address   = var.probe_client_ip
+        }
+      ]
+    )
   }
 
   features = {
@@ -22,3 +22,24 @@ output "public_network_access_enabled" {
   description = "Whether public network access is enabled."
   value       = module.postgresql_flexible_server.public_network_access_enabled
 }
+
+output "postgresql_flexible_server_fqdn" {
+  description = "The FQDN of the PostgreSQL Flexible Server."
+  value       = module.postgresql_flexible_server.fqdn
+}
+
+output "administrator_login" {
+  description = "The administrator login used by the SQL probe."
+  value       = "pgfsadmin"
+}
+
+output "administrator_password" {
+  description = "The administrator password used by the SQL probe."
+  value       = random_password.admin.result
+  sensitive   = true
+}
+
+output "active_directory_administrator_object_id" {
+  description = "The object ID of the Entra ID administrator."
+  value       = data.azurerm_client_config.current.object_id
+}
@@ -8,3 +8,9 @@ variable "location" {
   type        = string
   default     = "westeurope"
 }
+
+variable "probe_client_ip" {
+  description = "Public IP of the test runner allowed through the firewall for the SQL probe. Empty disables the rule."
+  type        = string
+  default     = ""
+}
@@ -3,7 +3,10 @@ package test
 import (
 	"testing"
 
-	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/cassette"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/compliance"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/assert"
 )
@@ -16,22 +19,22 @@ func TestPostgresqlFlexibleServerFullIntegration(t *testing.T) {
 	t.Parallel()
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/complete")
-	defer test_structure.RunTestStage(t, "cleanup", func() {
+	defer cassette.RunLiveStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
-	test_structure.RunTestStage(t, "deploy", func() {
+	cassette.RunLiveStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
-		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+		terraformOptions := cassette.LoadTerraformOptions(t, testFolder)
 
-		resourceName := terraform.Output(t, terraformOptions, "postgresql_flexible_server_name")
-		resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
+		resourceName := cassette.Output(t, terraformOptions, "postgresql_flexible_server_name")
+		resourceGroupName := cassette.Output(t, terraformOptions, "resource_group_name")
 		publicAccess := OutputBool(t, terraformOptions, "public_network_access_enabled")
 
 		assert.NotEmpty(t, resourceName)
@@ -55,20 +58,20 @@ func TestPostgresqlFlexibleServerWithNetworkRules(t *testing.T) {
 	t.Parallel()
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/network")
-	defer test_structure.RunTestStage(t, "cleanup", func() {
+	defer cassette.RunLiveStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
-	test_structure.RunTestStage(t, "deploy", func() {
+	cassette.RunLiveStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
-		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		firewallRules := terraform.OutputList(t, terraformOptions, "firewall_rule_names")
+		terraformOptions := cassette.LoadTerraformOptions(t, testFolder)
+		firewallRules := cassette.OutputList(t, terraformOptions, "firewall_rule_names")
 		assert.Len(t, firewallRules, 1)
 	})
 }
@@ -81,19 +84,19 @@ func TestPostgresqlFlexibleServerPrivateEndpointIntegration(t *testing.T) {
 	t.Parallel()
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/secure")
-	defer test_structure.RunTestStage(t, "cleanup", func() {
+	defer cassette.RunLiveStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
-	test_structure.RunTestStage(t, "deploy", func() {
+	cassette.RunLiveStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
-		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+		terraformOptions := cassette.LoadTerraformOptions(t, testFolder)
 		publicAccess := OutputBool(t, terraformOptions, "public_network_access_enabled")
 		assert.False(t, publicAccess)
 	})
@@ -107,22 +110,22 @@ func TestPostgresqlFlexibleServerSecurityConfiguration(t *testing.T) {
 	t.Parallel()
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/secure")
-	defer test_structure.RunTestStage(t, "cleanup", func() {
+	defer cassette.RunLiveStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
-	test_structure.RunTestStage(t, "deploy", func() {
+	cassette.RunLiveStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
-		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+		terraformOptions := cassette.LoadTerraformOptions(t, testFolder)
 
-		resourceName := terraform.Output(t, terraformOptions, "postgresql_flexible_server_name")
-		resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
+		resourceName := cassette.Output(t, terraformOptions, "postgresql_flexible_server_name")
+		resourceGroupName := cassette.Output(t, terraformOptions, "resource_group_name")
 		publicAccess := OutputBool(t, terraformOptions, "public_network_access_enabled")
 
 		assert.NotEmpty(t, resourceName)
@@ -140,15 +143,21 @@ func TestPostgresqlFlexibleServerLifecycle(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/basic")
 	terraformOptions := getTerraformOptions(t, testFolder)
-	defer terraform.Destroy(t, terraformOptions)
+	defer cassette.RunLiveStage(t, "cleanup", func() {
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
+	})
 
-	terraform.InitAndApply(t, terraformOptions)
+	cassette.RunLiveStage(t, "deploy", func() {
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
+	})
 
-	resourceID := terraform.Output(t, terraformOptions, "postgresql_flexible_server_id")
+	resourceID := cassette.Output(t, terraformOptions, "postgresql_flexible_server_id")
 	assert.NotEmpty(t, resourceID)
 
-	terraform.Apply(t, terraformOptions)
-	updatedResourceID := terraform.Output(t, terraformOptions, "postgresql_flexible_server_id")
+	cassette.RunLiveStage(t, "update", func() {
+		tfretry.ApplyWithRetry(t, terraformOptions)
+	})
+	updatedResourceID := cassette.Output(t, terraformOptions, "postgresql_flexible_server_id")
 	assert.Equal(t, resourceID, updatedResourceID)
 }
 
@@ -161,11 +170,21 @@ func TestPostgresqlFlexibleServerCompliance(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/secure")
 	terraformOptions := getTerraformOptions(t, testFolder)
-	defer terraform.Destroy(t, terraformOptions)
 
-	terraform.InitAndApply(t, terraformOptions)
+	// Rules from security-policies/compliance are checked on the plan and on the applied state
+	cassette.RunLiveStage(t, "plan_compliance", func() {
+		compliance.AssertPlan(t, terraformOptions, "azurerm_postgresql_flexible_server")
+	})
+	defer cassette.RunLiveStage(t, "cleanup", func() {
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
+	})
+
+	cassette.RunLiveStage(t, "deploy", func() {
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
+		compliance.AssertState(t, terraformOptions, "azurerm_postgresql_flexible_server")
+	})
 
-	resourceName := terraform.Output(t, terraformOptions, "postgresql_flexible_server_name")
+	resourceName := cassette.Output(t, terraformOptions, "postgresql_flexible_server_name")
 	publicAccess := OutputBool(t, terraformOptions, "public_network_access_enabled")
 
 	assert.NotEmpty(t, resourceName)
@@ -1,11 +1,19 @@
 package test
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/postgresql/armpostgresqlflexibleservers/v4"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/cassette"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/importtest"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/leakscan"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
@@ -18,23 +26,23 @@ func TestBasicPostgresqlFlexibleServer(t *testing.T) {
 	t.Parallel()
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/basic")
-	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+	defer cassette.RunLiveStage(t, "cleanup", func() {
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDestroyVerifier(t))
 	})
 
-	test_structure.RunTestStage(t, "deploy", func() {
+	cassette.RunLiveStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
-		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+		terraformOptions := cassette.LoadTerraformOptions(t, testFolder)
 
-		resourceID := terraform.Output(t, terraformOptions, "postgresql_flexible_server_id")
-		resourceName := terraform.Output(t, terraformOptions, "postgresql_flexible_server_name")
-		resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
-		databaseName := terraform.Output(t, terraformOptions, "database_name")
+		resourceID := cassette.Output(t, terraformOptions, "postgresql_flexible_server_id")
+		resourceName := cassette.Output(t, terraformOptions, "postgresql_flexible_server_name")
+		resourceGroupName := cassette.Output(t, terraformOptions, "resource_group_name")
+		databaseName := cassette.Output(t, terraformOptions, "database_name")
 
 		assert.NotEmpty(t, resourceID)
 		assert.NotEmpty(t, resourceName)
@@ -46,30 +54,43 @@ func TestBasicPostgresqlFlexibleServer(t *testing.T) {
 		assert.NotNil(t, server.ID)
 		assert.NotNil(t, server.Name)
 	})
+
+	// Adopting the deployed module resources into an empty state must plan no changes
+	cassette.RunLiveStage(t, "import", func() {
+		importtest.RequireStateImportRoundTrip(t, test_structure.LoadTerraformOptions(t, testFolder))
+	})
 }
 
 // Test complete postgresql_flexible_server with all features
 func TestCompletePostgresqlFlexibleServer(t *testing.T) {
 	t.Parallel()
 
+	scanner := leakscan.NewScanner(t)
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/complete")
-	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+	defer cassette.RunLiveStage(t, "cleanup", func() {
+		destroyverify.DestroyAndVerify(t, scanner.Attach(getTerraformOptions(t, testFolder)), destroyverify.NewAzureDestroyVerifier(t))
 	})
 
-	test_structure.RunTestStage(t, "deploy", func() {
+	probeClientIP := os.Getenv("PGPROBE_CLIENT_IP")
+
+	cassette.RunLiveStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
+		terraformOptions.Vars["probe_client_ip"] = probeClientIP
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, scanner.Attach(terraformOptions))
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
-		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+		terraformOptions := scanner.Attach(cassette.LoadTerraformOptions(t, testFolder))
+		cassette.RunLiveStage(t, "scan_outputs", func() {
+			scanner.ScanAfterApply(terraformOptions)
+		})
 
-		resourceID := terraform.Output(t, terraformOptions, "postgresql_flexible_server_id")
-		resourceName := terraform.Output(t, terraformOptions, "postgresql_flexible_server_name")
-		resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
+		resourceID := cassette.Output(t, terraformOptions, "postgresql_flexible_server_id")
+		resourceName := cassette.Output(t, terraformOptions, "postgresql_flexible_server_name")
+		resourceGroupName := cassette.Output(t, terraformOptions, "resource_group_name")
 		publicAccess := OutputBool(t, terraformOptions, "public_network_access_enabled")
+		adminObjectID := cassette.Output(t, terraformOptions, "active_directory_administrator_object_id")
 
 		assert.NotEmpty(t, resourceID)
 		assert.NotEmpty(t, resourceName)
@@ -82,7 +103,47 @@ func TestCompletePostgresqlFlexibleServer(t *testing.T) {
 			"Environment": "Test",
 			"Example":     "Complete",
 		})
+		ValidateHighAvailability(t, server, armpostgresqlflexibleservers.HighAvailabilityModeZoneRedundant)
+
+		helper.ValidateServerConfigurations(t, resourceGroupName, resourceName, map[string]string{
+			"log_checkpoints":          "on",
+			"log_connections":          "on",
+			"require_secure_transport": "on",
+		})
+
+		expectedRules := []ExpectedFirewallRule{
+			{Name: "office-range", StartIPAddress: "203.0.113.0", EndIPAddress: "203.0.113.255"},
+		}
+		if probeClientIP != "" {
+			expectedRules = append(expectedRules, ExpectedFirewallRule{Name: "probe-client", StartIPAddress: probeClientIP, EndIPAddress: probeClientIP})
+		}
+		ValidateFirewallRules(t, helper.ListFirewallRules(t, resourceGroupName, resourceName), expectedRules)
+
+		admins := helper.ListAdministrators(t, resourceGroupName, resourceName)
+		ValidateEntraAdministrator(t, admins, adminObjectID, armpostgresqlflexibleservers.PrincipalTypeServicePrincipal)
+
+		database := helper.GetDatabase(t, resourceGroupName, resourceName, "postgres")
+		ValidateDatabaseCharsetCollation(t, database, "UTF8", "")
 	})
+
+	if probeClientIP != "" {
+		cassette.RunLiveStage(t, "probe", func() {
+			terraformOptions := scanner.Attach(test_structure.LoadTerraformOptions(t, testFolder))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			defer cancel()
+
+			result, err := RunPostgresqlProbe(ctx, PostgresqlProbeConfig{
+				Host:     terraform.Output(t, terraformOptions, "postgresql_flexible_server_fqdn"),
+				Port:     5432,
+				User:     terraform.Output(t, terraformOptions, "administrator_login"),
+				Password: leakscan.SensitiveOutput(t, terraformOptions, "administrator_password"),
+				Database: "postgres",
+			})
+			require.NoError(t, err)
+			ValidatePostgresqlProbe(t, result, []string{"postgres"})
+		})
+	}
 }
 
 // Test security configurations
@@ -90,22 +151,22 @@ func TestSecurePostgresqlFlexibleServer(t *testing.T) {
 	t.Parallel()
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/secure")
-	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+	defer cassette.RunLiveStage(t, "cleanup", func() {
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDestroyVerifier(t))
 	})
 
-	test_structure.RunTestStage(t, "deploy", func() {
+	cassette.RunLiveStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
-		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+		terraformOptions := cassette.LoadTerraformOptions(t, testFolder)
 
-		resourceID := terraform.Output(t, terraformOptions, "postgresql_flexible_server_id")
-		resourceName := terraform.Output(t, terraformOptions, "postgresql_flexible_server_name")
-		resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
+		resourceID := cassette.Output(t, terraformOptions, "postgresql_flexible_server_id")
+		resourceName := cassette.Output(t, terraformOptions, "postgresql_flexible_server_name")
+		resourceGroupName := cassette.Output(t, terraformOptions, "resource_group_name")
 		publicAccess := OutputBool(t, terraformOptions, "public_network_access_enabled")
 
 		assert.NotEmpty(t, resourceID)
@@ -120,21 +181,21 @@ func TestNetworkPostgresqlFlexibleServer(t *testing.T) {
 	t.Parallel()
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/network")
-	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+	defer cassette.RunLiveStage(t, "cleanup", func() {
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDestroyVerifier(t))
 	})
 
-	test_structure.RunTestStage(t, "deploy", func() {
+	cassette.RunLiveStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
-		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+		terraformOptions := cassette.LoadTerraformOptions(t, testFolder)
 
-		resourceID := terraform.Output(t, terraformOptions, "postgresql_flexible_server_id")
-		firewallRules := terraform.OutputList(t, terraformOptions, "firewall_rule_names")
+		resourceID := cassette.Output(t, terraformOptions, "postgresql_flexible_server_id")
+		firewallRules := cassette.OutputList(t, terraformOptions, "firewall_rule_names")
 
 		assert.NotEmpty(t, resourceID)
 		assert.Len(t, firewallRules, 1)
@@ -185,21 +246,22 @@ func BenchmarkPostgresqlFlexibleServerCreation(b *testing.B) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(b, "..", "tests/fixtures/basic")
 	terraformOptions := getTerraformOptions(b, testFolder)
-	defer terraform.Destroy(b, terraformOptions)
+	defer tfretry.DestroyWithRetry(b, terraformOptions)
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		terraformOptions.Vars["random_suffix"] = fmt.Sprintf("%d%s", i, terraformOptions.Vars["random_suffix"].(string)[:5])
 
-		terraform.InitAndApply(b, terraformOptions)
-		terraform.Destroy(b, terraformOptions)
+		tfretry.InitAndApplyWithRetry(b, terraformOptions)
+		tfretry.DestroyWithRetry(b, terraformOptions)
 	}
 }
 
 // Helper function to get terraform options
 func getTerraformOptions(t testing.TB, terraformDir string) *terraform.Options {
 	timestamp := time.Now().UnixNano() % 1000
-	baseID := strings.ToLower(random.UniqueId())
+	// Replay reuses the suffix recorded in the test's cassette
+	baseID := strings.ToLower(cassette.UniqueID(t, random.UniqueId))
 	uniqueID := fmt.Sprintf("%s%03d", baseID[:5], timestamp)
 
 	return &terraform.Options{
@@ -211,18 +273,9 @@ func getTerraformOptions(t testing.TB, terraformDir string) *terraform.Options {
 		EnvVars: map[string]string{
 			"TF_CLI_ARGS_apply": "-parallelism=1",
 		},
-		NoColor: true,
-		RetryableTerraformErrors: map[string]string{
-			".*timeout.*":                           "Timeout error - retrying",
-			".*ResourceGroupNotFound.*":             "Resource group not found - retrying",
-			".*AlreadyExists.*":                     "Resource already exists - retrying",
-			".*TooManyRequests.*":                   "Too many requests - retrying",
-			".*ServerDropping.*":                    "Server is in dropping state - retrying",
-			".*ServerIsBusy.*":                      "Server is busy processing another operation - retrying",
-			".*busy processing another operation.*": "Server is busy processing another operation - retrying",
-			".*Cannot complete operation while server.*is busy processing another operation.*": "Server is busy processing another operation - retrying",
-		},
-		MaxRetries:         8,
-		TimeBetweenRetries: 30 * time.Second,
+		NoColor:                  true,
+		RetryableTerraformErrors: tfretry.ClassifiedRetryableErrors(),
+		MaxRetries:               8,
+		TimeBetweenRetries:       30 * time.Second,
 	}
 }
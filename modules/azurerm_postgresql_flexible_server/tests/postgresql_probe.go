@@ -0,0 +1,165 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+// PostgresqlProbeConfig holds connection settings for the live SQL probe.
+// NOTE: Keep env variable names in sync with test_env.sh and the Makefile probe targets.
+type PostgresqlProbeConfig struct {
+	Host           string
+	Port           int
+	User           string
+	Password       string
+	Database       string
+	SSLMode        string
+	SSLRootCert    string
+	ConnectTimeout time.Duration
+}
+
+// PostgresqlProbeResult captures what the probe observed on the server
+type PostgresqlProbeResult struct {
+	SSLInUse          bool
+	SSLVersion        string
+	PlaintextRejected bool
+	PlaintextError    string
+	Databases         []string
+}
+
+// PostgresqlProbeConfigFromEnv builds a probe configuration from PGPROBE_* variables.
+// The second return value is false when PGPROBE_HOST is not set.
+func PostgresqlProbeConfigFromEnv() (PostgresqlProbeConfig, bool) {
+	host := os.Getenv("PGPROBE_HOST")
+	if host == "" {
+		return PostgresqlProbeConfig{}, false
+	}
+
+	port := 5432
+	if value := os.Getenv("PGPROBE_PORT"); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			port = parsed
+		}
+	}
+
+	return PostgresqlProbeConfig{
+		Host:        host,
+		Port:        port,
+		User:        os.Getenv("PGPROBE_USER"),
+		Password:    os.Getenv("PGPROBE_PASSWORD"),
+		Database:    os.Getenv("PGPROBE_DATABASE"),
+		SSLMode:     os.Getenv("PGPROBE_SSLMODE"),
+		SSLRootCert: os.Getenv("PGPROBE_SSLROOTCERT"),
+	}, true
+}
+
+// connectionString builds a libpq URL for the given sslmode
+func (c PostgresqlProbeConfig) connectionString(sslMode string) string {
+	database := c.Database
+	if database == "" {
+		database = "postgres"
+	}
+	timeout := c.ConnectTimeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	query := url.Values{}
+	query.Set("sslmode", sslMode)
+	query.Set("connect_timeout", strconv.Itoa(int(timeout.Seconds())))
+	if c.SSLRootCert != "" && sslMode != "disable" {
+		query.Set("sslrootcert", c.SSLRootCert)
+	}
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		Path:     "/" + database,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
+
+// RunPostgresqlProbe connects over TLS, checks that plaintext sessions are refused
+// (require_secure_transport) and lists the databases visible to the probe user.
+func RunPostgresqlProbe(ctx context.Context, cfg PostgresqlProbeConfig) (*PostgresqlProbeResult, error) {
+	sslMode := cfg.SSLMode
+	if sslMode == "" {
+		sslMode = "require"
+	}
+	if sslMode == "disable" || sslMode == "allow" || sslMode == "prefer" {
+		return nil, fmt.Errorf("sslmode %q does not enforce TLS", sslMode)
+	}
+
+	conn, err := pgx.Connect(ctx, cfg.connectionString(sslMode))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect over TLS to %s: %w", cfg.Host, err)
+	}
+	defer conn.Close(ctx)
+
+	result := &PostgresqlProbeResult{}
+
+	var sslVersion *string
+	err = conn.QueryRow(ctx, "SELECT ssl, version FROM pg_stat_ssl WHERE pid = pg_backend_pid()").Scan(&result.SSLInUse, &sslVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pg_stat_ssl: %w", err)
+	}
+	if sslVersion != nil {
+		result.SSLVersion = *sslVersion
+	}
+
+	rows, err := conn.Query(ctx, "SELECT datname FROM pg_database WHERE NOT datistemplate ORDER BY datname")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list databases: %w", err)
+	}
+	result.Databases, err = pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read database names: %w", err)
+	}
+
+	plaintext, err := pgx.Connect(ctx, cfg.connectionString("disable"))
+	switch {
+	case err == nil:
+		plaintext.Close(ctx)
+	case IsPlaintextRejection(err):
+		result.PlaintextRejected = true
+		result.PlaintextError = err.Error()
+	default:
+		return nil, fmt.Errorf("plaintext connection to %s failed before the server could reject it: %w", cfg.Host, err)
+	}
+
+	return result, nil
+}
+
+// IsPlaintextRejection reports whether err is the server refusing a session without TLS:
+// SQLSTATE 28000 from pg_hba with the "no encryption" reason. DNS, timeout and firewall
+// errors do not show that require_secure_transport is enforced.
+func IsPlaintextRejection(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "28000" && strings.Contains(pgErr.Message, "no encryption")
+}
+
+// ValidatePostgresqlProbe validates TLS enforcement and the presence of expected databases
+func ValidatePostgresqlProbe(t *testing.T, result *PostgresqlProbeResult, expectedDatabases []string) {
+	require.NotNil(t, result, "Probe result should not be nil")
+	require.True(t, result.SSLInUse, "Probe session should use TLS")
+	require.NotEmpty(t, result.SSLVersion, "Probe session should report a TLS version")
+	require.True(t, result.PlaintextRejected, "Server should reject non-TLS connections (require_secure_transport)")
+
+	for _, database := range expectedDatabases {
+		require.Contains(t, result.Databases, database, "Expected database %s to be listed", database)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/postgresql/armpostgresqlflexibleservers/v4"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/cassette"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/require"
@@ -101,38 +102,52 @@ func WaitForResourceDeletion(ctx context.Context, checkFunc func() (bool, error)
 	}
 }
 
-// GenerateResourceName generates a unique resource name for testing
-func GenerateResourceName(prefix string, uniqueID string) string {
-	name := fmt.Sprintf("%s%s", prefix, uniqueID)
-	name = strings.ReplaceAll(name, "-", "")
-	if len(name) > 63 {
-		name = name[:63]
-	}
-	return strings.ToLower(name)
-}
-
 // PostgresqlFlexibleServerHelper provides helper methods for PostgreSQL Flexible Server testing
 // NOTE: Uses the new Azure SDK to query server state.
 type PostgresqlFlexibleServerHelper struct {
-	subscriptionID string
-	credential     azcore.TokenCredential
-	serversClient  *armpostgresqlflexibleservers.ServersClient
+	subscriptionID       string
+	credential           azcore.TokenCredential
+	serversClient        *armpostgresqlflexibleservers.ServersClient
+	databasesClient      *armpostgresqlflexibleservers.DatabasesClient
+	configurationsClient *armpostgresqlflexibleservers.ConfigurationsClient
+	firewallRulesClient  *armpostgresqlflexibleservers.FirewallRulesClient
+	administratorsClient *armpostgresqlflexibleservers.AdministratorsClient
 }
 
-// NewPostgresqlFlexibleServerHelper creates a new helper instance
+// NewPostgresqlFlexibleServerHelper creates a new helper instance.
+// SDK traffic goes through the test's HTTP cassette when AZURE_CASSETTE_MODE is set.
 func NewPostgresqlFlexibleServerHelper(t *testing.T) *PostgresqlFlexibleServerHelper {
-	subscriptionID := os.Getenv("ARM_SUBSCRIPTION_ID")
-	require.NotEmpty(t, subscriptionID, "ARM_SUBSCRIPTION_ID environment variable must be set")
+	subscriptionID, credential, options := cassette.Session(t,
+		func() string {
+			subscriptionID := os.Getenv("ARM_SUBSCRIPTION_ID")
+			require.NotEmpty(t, subscriptionID, "ARM_SUBSCRIPTION_ID environment variable must be set")
+			return subscriptionID
+		},
+		func() azcore.TokenCredential { return GetAzureCredential(t) })
 
-	credential := GetAzureCredential(t)
-
-	serversClient, err := armpostgresqlflexibleservers.NewServersClient(subscriptionID, credential, nil)
+	serversClient, err := armpostgresqlflexibleservers.NewServersClient(subscriptionID, credential, options)
 	require.NoError(t, err, "Failed to create PostgreSQL Flexible Servers client")
 
+	databasesClient, err := armpostgresqlflexibleservers.NewDatabasesClient(subscriptionID, credential, options)
+	require.NoError(t, err, "Failed to create PostgreSQL Flexible Server databases client")
+
+	configurationsClient, err := armpostgresqlflexibleservers.NewConfigurationsClient(subscriptionID, credential, options)
+	require.NoError(t, err, "Failed to create PostgreSQL Flexible Server configurations client")
+
+	firewallRulesClient, err := armpostgresqlflexibleservers.NewFirewallRulesClient(subscriptionID, credential, options)
+	require.NoError(t, err, "Failed to create PostgreSQL Flexible Server firewall rules client")
+
+	administratorsClient, err := armpostgresqlflexibleservers.NewAdministratorsClient(subscriptionID, credential, options)
+	require.NoError(t, err, "Failed to create PostgreSQL Flexible Server administrators client")
+
 	return &PostgresqlFlexibleServerHelper{
-		subscriptionID: subscriptionID,
-		credential:     credential,
-		serversClient:  serversClient,
+		subscriptionID:       subscriptionID,
+		credential:           credential,
+		serversClient:        serversClient,
+		databasesClient:      databasesClient,
+		configurationsClient: configurationsClient,
+		firewallRulesClient:  firewallRulesClient,
+		administratorsClient: administratorsClient,
 	}
 }
 
@@ -162,9 +177,154 @@ func ValidateServerTags(t *testing.T, server armpostgresqlflexibleservers.Server
 	}
 }
 
-// OutputBool reads a Terraform output and parses it as a boolean.
+// GetDatabase retrieves a database hosted on the PostgreSQL Flexible Server
+func (h *PostgresqlFlexibleServerHelper) GetDatabase(t *testing.T, resourceGroupName, serverName, databaseName string) armpostgresqlflexibleservers.Database {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	resp, err := h.databasesClient.Get(ctx, resourceGroupName, serverName, databaseName, nil)
+	require.NoError(t, err, "Failed to get PostgreSQL Flexible Server database %s", databaseName)
+
+	return resp.Database
+}
+
+// ValidateDatabaseCharsetCollation validates charset and collation of a database
+func ValidateDatabaseCharsetCollation(t *testing.T, database armpostgresqlflexibleservers.Database, expectedCharset, expectedCollation string) {
+	require.NotNil(t, database.Properties, "Database properties should not be nil")
+
+	if expectedCharset != "" {
+		require.NotNil(t, database.Properties.Charset, "Database charset should be set")
+		require.True(t, strings.EqualFold(expectedCharset, *database.Properties.Charset),
+			"Database charset should be %s, got %s", expectedCharset, *database.Properties.Charset)
+	}
+	if expectedCollation != "" {
+		require.NotNil(t, database.Properties.Collation, "Database collation should be set")
+		require.True(t, strings.EqualFold(expectedCollation, *database.Properties.Collation),
+			"Database collation should be %s, got %s", expectedCollation, *database.Properties.Collation)
+	}
+}
+
+// GetConfiguration retrieves a single server configuration parameter
+func (h *PostgresqlFlexibleServerHelper) GetConfiguration(t *testing.T, resourceGroupName, serverName, configurationName string) armpostgresqlflexibleservers.Configuration {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	resp, err := h.configurationsClient.Get(ctx, resourceGroupName, serverName, configurationName, nil)
+	require.NoError(t, err, "Failed to get PostgreSQL Flexible Server configuration %s", configurationName)
+
+	return resp.Configuration
+}
+
+// ValidateServerConfigurations validates server parameters against expected values.
+// Values are compared case-insensitively because Azure normalizes on/off flags.
+func (h *PostgresqlFlexibleServerHelper) ValidateServerConfigurations(t *testing.T, resourceGroupName, serverName string, expected map[string]string) {
+	for name, value := range expected {
+		configuration := h.GetConfiguration(t, resourceGroupName, serverName, name)
+		require.NotNil(t, configuration.Properties, "Configuration %s properties should not be nil", name)
+		require.NotNil(t, configuration.Properties.Value, "Configuration %s value should be set", name)
+		require.True(t, strings.EqualFold(value, *configuration.Properties.Value),
+			"Configuration %s should be %s, got %s", name, value, *configuration.Properties.Value)
+	}
+}
+
+// ListFirewallRules lists all firewall rules configured on the server
+func (h *PostgresqlFlexibleServerHelper) ListFirewallRules(t *testing.T, resourceGroupName, serverName string) []armpostgresqlflexibleservers.FirewallRule {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	rules := make([]armpostgresqlflexibleservers.FirewallRule, 0)
+	pager := h.firewallRulesClient.NewListByServerPager(resourceGroupName, serverName, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		require.NoError(t, err, "Failed to list PostgreSQL Flexible Server firewall rules")
+		for _, rule := range page.Value {
+			if rule != nil {
+				rules = append(rules, *rule)
+			}
+		}
+	}
+
+	return rules
+}
+
+// ExpectedFirewallRule describes a firewall rule the server should have
+type ExpectedFirewallRule struct {
+	Name           string
+	StartIPAddress string
+	EndIPAddress   string
+}
+
+// ValidateFirewallRules validates that the server has exactly the expected firewall rules
+func ValidateFirewallRules(t *testing.T, rules []armpostgresqlflexibleservers.FirewallRule, expected []ExpectedFirewallRule) {
+	actual := make(map[string]armpostgresqlflexibleservers.FirewallRule, len(rules))
+	for _, rule := range rules {
+		require.NotNil(t, rule.Name, "Firewall rule name should not be nil")
+		actual[*rule.Name] = rule
+	}
+	require.Len(t, actual, len(expected), "Firewall rule count mismatch")
+
+	for _, want := range expected {
+		rule, ok := actual[want.Name]
+		require.True(t, ok, "Expected firewall rule %s to be present", want.Name)
+		require.NotNil(t, rule.Properties, "Firewall rule %s properties should not be nil", want.Name)
+		require.Equal(t, want.StartIPAddress, *rule.Properties.StartIPAddress, "Firewall rule %s start IP should match", want.Name)
+		require.Equal(t, want.EndIPAddress, *rule.Properties.EndIPAddress, "Firewall rule %s end IP should match", want.Name)
+	}
+}
+
+// ListAdministrators lists the Entra ID administrators configured on the server
+func (h *PostgresqlFlexibleServerHelper) ListAdministrators(t *testing.T, resourceGroupName, serverName string) []armpostgresqlflexibleservers.ActiveDirectoryAdministrator {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	admins := make([]armpostgresqlflexibleservers.ActiveDirectoryAdministrator, 0)
+	pager := h.administratorsClient.NewListByServerPager(resourceGroupName, serverName, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		require.NoError(t, err, "Failed to list PostgreSQL Flexible Server administrators")
+		for _, admin := range page.Value {
+			if admin != nil {
+				admins = append(admins, *admin)
+			}
+		}
+	}
+
+	return admins
+}
+
+// ValidateEntraAdministrator validates that the given principal is an Entra ID administrator
+func ValidateEntraAdministrator(t *testing.T, admins []armpostgresqlflexibleservers.ActiveDirectoryAdministrator, objectID string, principalType armpostgresqlflexibleservers.PrincipalType) {
+	for _, admin := range admins {
+		if admin.Properties == nil || admin.Properties.ObjectID == nil {
+			continue
+		}
+		if !strings.EqualFold(*admin.Properties.ObjectID, objectID) {
+			continue
+		}
+		require.NotNil(t, admin.Properties.PrincipalType, "Administrator %s principal type should be set", objectID)
+		require.Equal(t, principalType, *admin.Properties.PrincipalType, "Administrator %s principal type should match", objectID)
+		return
+	}
+	require.Failf(t, "Entra administrator not found", "Expected administrator with object ID %s", objectID)
+}
+
+// ValidateHighAvailability validates the high availability mode of the server
+func ValidateHighAvailability(t *testing.T, server armpostgresqlflexibleservers.Server, expectedMode armpostgresqlflexibleservers.HighAvailabilityMode) {
+	require.NotNil(t, server.Properties, "Server properties should not be nil")
+
+	if expectedMode == armpostgresqlflexibleservers.HighAvailabilityModeDisabled {
+		if server.Properties.HighAvailability == nil || server.Properties.HighAvailability.Mode == nil {
+			return
+		}
+	}
+	require.NotNil(t, server.Properties.HighAvailability, "High availability should be configured")
+	require.NotNil(t, server.Properties.HighAvailability.Mode, "High availability mode should be set")
+	require.Equal(t, expectedMode, *server.Properties.HighAvailability.Mode, "High availability mode should match")
+}
+
+// OutputBool reads a Terraform output through the test's cassette and parses it as a boolean.
 func OutputBool(t testing.TB, terraformOptions *terraform.Options, name string) bool {
-	value := terraform.Output(t, terraformOptions, name)
+	value := cassette.Output(t, terraformOptions, name)
 	parsed, err := strconv.ParseBool(strings.TrimSpace(value))
 	require.NoError(t, err, "Failed to parse output %q as bool", name)
 	return parsed
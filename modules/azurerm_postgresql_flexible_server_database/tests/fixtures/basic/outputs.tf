@@ -13,6 +13,11 @@ output "postgresql_flexible_server_id" {
   value       = module.postgresql_flexible_server.id
 }
 
+output "postgresql_flexible_server_name" {
+  description = "The name of the PostgreSQL Flexible Server."
+  value       = module.postgresql_flexible_server.name
+}
+
 output "resource_group_name" {
   description = "The name of the resource group containing the server."
   value       = azurerm_resource_group.example.name
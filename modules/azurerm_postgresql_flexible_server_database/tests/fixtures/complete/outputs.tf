@@ -13,6 +13,11 @@ output "postgresql_flexible_server_id" {
   value       = module.postgresql_flexible_server.id
 }
 
+output "postgresql_flexible_server_name" {
+  description = "The name of the PostgreSQL Flexible Server."
+  value       = module.postgresql_flexible_server.name
+}
+
 output "database_charset" {
   description = "The charset configured for the database."
   value       = module.postgresql_flexible_server_database.charset
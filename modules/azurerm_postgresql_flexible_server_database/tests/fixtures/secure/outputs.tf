@@ -13,6 +13,11 @@ output "postgresql_flexible_server_id" {
   value       = module.postgresql_flexible_server.id
 }
 
+output "postgresql_flexible_server_name" {
+  description = "The name of the PostgreSQL Flexible Server."
+  value       = module.postgresql_flexible_server.name
+}
+
 output "public_network_access_enabled" {
   description = "Whether public network access is enabled."
   value       = module.postgresql_flexible_server.public_network_access_enabled
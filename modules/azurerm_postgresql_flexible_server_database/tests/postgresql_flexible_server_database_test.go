@@ -6,6 +6,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/importtest"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
@@ -19,13 +22,13 @@ func TestBasicPostgresqlFlexibleServerDatabase(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "../..", "azurerm_postgresql_flexible_server_database/tests/fixtures/basic")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -38,6 +41,16 @@ func TestBasicPostgresqlFlexibleServerDatabase(t *testing.T) {
 		assert.NotEmpty(t, resourceID)
 		assert.NotEmpty(t, resourceName)
 		assert.NotEmpty(t, resourceGroupName)
+
+		serverName := terraform.Output(t, terraformOptions, "postgresql_flexible_server_name")
+		helper := NewPostgresqlFlexibleServerDatabaseHelper(t)
+		database := helper.GetDatabase(t, resourceGroupName, serverName, resourceName)
+		assert.Equal(t, resourceID, *database.ID)
+	})
+
+	// Adopting the deployed module resources into an empty state must plan no changes
+	test_structure.RunTestStage(t, "import", func() {
+		importtest.RequireStateImportRoundTrip(t, test_structure.LoadTerraformOptions(t, testFolder))
 	})
 }
 
@@ -47,13 +60,13 @@ func TestCompletePostgresqlFlexibleServerDatabase(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "../..", "azurerm_postgresql_flexible_server_database/tests/fixtures/complete")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -68,6 +81,12 @@ func TestCompletePostgresqlFlexibleServerDatabase(t *testing.T) {
 		assert.NotEmpty(t, resourceName)
 		assert.Equal(t, "UTF8", charset)
 		assert.Equal(t, "en_US.utf8", collation)
+
+		resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
+		serverName := terraform.Output(t, terraformOptions, "postgresql_flexible_server_name")
+		helper := NewPostgresqlFlexibleServerDatabaseHelper(t)
+		database := helper.GetDatabase(t, resourceGroupName, serverName, resourceName)
+		ValidateDatabaseCharsetCollation(t, database, "UTF8", "en_US.utf8")
 	})
 }
 
@@ -77,13 +96,13 @@ func TestSecurePostgresqlFlexibleServerDatabase(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "../..", "azurerm_postgresql_flexible_server_database/tests/fixtures/secure")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -145,19 +164,9 @@ func getTerraformOptions(t testing.TB, terraformDir string) *terraform.Options {
 			"random_suffix": uniqueID,
 			"location":      "northeurope",
 		},
-		NoColor: true,
-		RetryableTerraformErrors: map[string]string{
-			".*timeout.*":               "Timeout error - retrying",
-			".*ResourceGroupNotFound.*": "Resource group not found - retrying",
-			".*AlreadyExists.*":         "Resource already exists - retrying",
-			".*TooManyRequests.*":       "Too many requests - retrying",
-			".*ServerDropping.*":        "Server is in dropping state - retrying",
-			".*InternalServerError.*":   "Azure internal server error - retrying",
-			".*unexpected error occured.*": "Azure service error - retrying",
-			".*ServerIsBusy.*":             "Server is busy processing another operation - retrying",
-			".*busy processing another operation.*": "Server is busy processing another operation - retrying",
-		},
-		MaxRetries:         3,
-		TimeBetweenRetries: 10 * time.Second,
+		NoColor:                  true,
+		RetryableTerraformErrors: tfretry.ClassifiedRetryableErrors(),
+		MaxRetries:               3,
+		TimeBetweenRetries:       10 * time.Second,
 	}
 }
@@ -1,14 +1,88 @@
 package test
 
 import (
+	"context"
+	"os"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/postgresql/armpostgresqlflexibleservers/v4"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/require"
 )
 
+// GetAzureCredential returns Azure credentials for SDK calls
+func GetAzureCredential(t *testing.T) azcore.TokenCredential {
+	tenantID := os.Getenv("ARM_TENANT_ID")
+	clientID := os.Getenv("ARM_CLIENT_ID")
+	clientSecret := os.Getenv("ARM_CLIENT_SECRET")
+
+	if tenantID != "" && clientID != "" && clientSecret != "" {
+		cred, err := azidentity.NewClientSecretCredential(tenantID, clientID, clientSecret, nil)
+		require.NoError(t, err, "Failed to create service principal credential")
+		return cred
+	}
+
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	require.NoError(t, err, "Failed to create default Azure credential")
+	return cred
+}
+
+// PostgresqlFlexibleServerDatabaseHelper provides helper methods for database testing
+type PostgresqlFlexibleServerDatabaseHelper struct {
+	subscriptionID  string
+	credential      azcore.TokenCredential
+	databasesClient *armpostgresqlflexibleservers.DatabasesClient
+}
+
+// NewPostgresqlFlexibleServerDatabaseHelper creates a new helper instance
+func NewPostgresqlFlexibleServerDatabaseHelper(t *testing.T) *PostgresqlFlexibleServerDatabaseHelper {
+	subscriptionID := os.Getenv("ARM_SUBSCRIPTION_ID")
+	require.NotEmpty(t, subscriptionID, "ARM_SUBSCRIPTION_ID environment variable must be set")
+
+	credential := GetAzureCredential(t)
+
+	databasesClient, err := armpostgresqlflexibleservers.NewDatabasesClient(subscriptionID, credential, nil)
+	require.NoError(t, err, "Failed to create PostgreSQL Flexible Server databases client")
+
+	return &PostgresqlFlexibleServerDatabaseHelper{
+		subscriptionID:  subscriptionID,
+		credential:      credential,
+		databasesClient: databasesClient,
+	}
+}
+
+// GetDatabase retrieves PostgreSQL Flexible Server database properties
+func (h *PostgresqlFlexibleServerDatabaseHelper) GetDatabase(t *testing.T, resourceGroupName, serverName, databaseName string) armpostgresqlflexibleservers.Database {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	resp, err := h.databasesClient.Get(ctx, resourceGroupName, serverName, databaseName, nil)
+	require.NoError(t, err, "Failed to get PostgreSQL Flexible Server database %s", databaseName)
+
+	return resp.Database
+}
+
+// ValidateDatabaseCharsetCollation validates charset and collation of a database
+func ValidateDatabaseCharsetCollation(t *testing.T, database armpostgresqlflexibleservers.Database, expectedCharset, expectedCollation string) {
+	require.NotNil(t, database.Properties, "Database properties should not be nil")
+
+	if expectedCharset != "" {
+		require.NotNil(t, database.Properties.Charset, "Database charset should be set")
+		require.True(t, strings.EqualFold(expectedCharset, *database.Properties.Charset),
+			"Database charset should be %s, got %s", expectedCharset, *database.Properties.Charset)
+	}
+	if expectedCollation != "" {
+		require.NotNil(t, database.Properties.Collation, "Database collation should be set")
+		require.True(t, strings.EqualFold(expectedCollation, *database.Properties.Collation),
+			"Database collation should be %s, got %s", expectedCollation, *database.Properties.Collation)
+	}
+}
+
 // OutputBool reads a Terraform output and parses it as a boolean.
 func OutputBool(t testing.TB, terraformOptions *terraform.Options, name string) bool {
 	value := terraform.Output(t, terraformOptions, name)
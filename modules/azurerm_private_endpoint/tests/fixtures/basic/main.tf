@@ -13,31 +13,20 @@ provider "azurerm" {
   features {}
 }
 
-resource "azurerm_resource_group" "example" {
-  name     = "rg-pe-basic-${var.random_suffix}"
-  location = var.location
-}
-
-resource "azurerm_virtual_network" "example" {
-  name                = "vnet-pe-basic-${var.random_suffix}"
-  location            = azurerm_resource_group.example.location
-  resource_group_name = azurerm_resource_group.example.name
-  address_space       = ["10.10.0.0/16"]
-}
-
+# The resource group and virtual network come from the shared network baseline leased by the tests
 resource "azurerm_subnet" "private_endpoints" {
   name                 = "snet-pe-basic-${var.random_suffix}"
-  resource_group_name  = azurerm_resource_group.example.name
-  virtual_network_name = azurerm_virtual_network.example.name
-  address_prefixes     = ["10.10.1.0/24"]
+  resource_group_name  = var.baseline_resource_group_name
+  virtual_network_name = var.baseline_virtual_network_name
+  address_prefixes     = [var.baseline_subnet_address_prefix]
 
   private_endpoint_network_policies = "Disabled"
 }
 
 resource "azurerm_storage_account" "example" {
   name                     = "stpebasic${var.random_suffix}"
-  resource_group_name      = azurerm_resource_group.example.name
-  location                 = azurerm_resource_group.example.location
+  resource_group_name      = var.baseline_resource_group_name
+  location                 = var.location
   account_tier             = "Standard"
   account_replication_type = "LRS"
 
@@ -50,8 +39,8 @@ module "private_endpoint" {
   source = "../../.."
 
   name                = "pe-basic-${var.random_suffix}"
-  resource_group_name = azurerm_resource_group.example.name
-  location            = azurerm_resource_group.example.location
+  resource_group_name = var.baseline_resource_group_name
+  location            = var.location
   subnet_id           = azurerm_subnet.private_endpoints.id
 
   private_service_connections = [
@@ -10,7 +10,7 @@ output "private_endpoint_name" {
 
 output "resource_group_name" {
   description = "The name of the resource group."
-  value       = azurerm_resource_group.example.name
+  value       = var.baseline_resource_group_name
 }
 
 output "private_endpoint_private_ip" {
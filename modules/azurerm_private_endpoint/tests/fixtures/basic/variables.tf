@@ -17,3 +17,18 @@ variable "tags" {
     Example     = "Basic"
   }
 }
+
+variable "baseline_resource_group_name" {
+  description = "Resource group of the shared network baseline."
+  type        = string
+}
+
+variable "baseline_virtual_network_name" {
+  description = "Virtual network of the shared network baseline."
+  type        = string
+}
+
+variable "baseline_subnet_address_prefix" {
+  description = "Address prefix leased to this fixture within the baseline virtual network."
+  type        = string
+}
@@ -12,3 +12,15 @@ output "resource_group_name" {
   description = "The resource group name."
   value       = azurerm_resource_group.example.name
 }
+
+output "primary_access_key" {
+  description = "The primary access key of the Redis Cache."
+  value       = module.redis_cache.primary_access_key
+  sensitive   = true
+}
+
+output "primary_connection_string" {
+  description = "The primary connection string of the Redis Cache."
+  value       = module.redis_cache.primary_connection_string
+  sensitive   = true
+}
@@ -13,6 +13,11 @@ output "spoke1_route_table_id" {
   value       = module.spoke1_route_table.id
 }
 
+output "spoke1_route_table_name" {
+  description = "The name of the spoke1 Route Table."
+  value       = module.spoke1_route_table.name
+}
+
 output "spoke2_route_table_id" {
   description = "The ID of the spoke2 Route Table."
   value       = module.spoke2_route_table.id
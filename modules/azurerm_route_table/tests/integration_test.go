@@ -3,6 +3,8 @@ package test
 import (
 	"testing"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/assert"
@@ -19,14 +21,14 @@ func TestRouteTableLifecycle(t *testing.T) {
 
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	// Deploy initial version
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	// Validate initial deployment and then update
@@ -49,7 +51,7 @@ func TestRouteTableLifecycle(t *testing.T) {
 				"next_hop_type":  "Internet",
 			},
 		}
-		terraform.Apply(t, terraformOptions)
+		tfretry.ApplyWithRetry(t, terraformOptions)
 
 		// Validate the updated route table
 		routeTableAfterUpdate := helper.GetRouteTableProperties(t, resourceGroupName, routeTableName)
@@ -59,7 +61,7 @@ func TestRouteTableLifecycle(t *testing.T) {
 		assert.Equal(t, "new-route", *routes[0].Name)
 
 		// Run apply again to check for idempotency
-		terraform.Apply(t, terraformOptions)
+		tfretry.ApplyWithRetry(t, terraformOptions)
 	})
 }
 
@@ -74,14 +76,14 @@ func TestRouteTableMultipleUpdates(t *testing.T) {
 
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	// Deploy initial version
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	// Perform multiple updates
@@ -94,7 +96,7 @@ func TestRouteTableMultipleUpdates(t *testing.T) {
 
 		// First update: Change BGP propagation
 		terraformOptions.Vars["bgp_route_propagation_enabled"] = false
-		terraform.Apply(t, terraformOptions)
+		tfretry.ApplyWithRetry(t, terraformOptions)
 
 		routeTable1 := helper.GetRouteTableProperties(t, resourceGroupName, routeTableName)
 		assert.True(t, *routeTable1.Properties.DisableBgpRoutePropagation)
@@ -107,7 +109,7 @@ func TestRouteTableMultipleUpdates(t *testing.T) {
 				"next_hop_type":  "VnetLocal",
 			},
 		}
-		terraform.Apply(t, terraformOptions)
+		tfretry.ApplyWithRetry(t, terraformOptions)
 
 		routes2 := helper.GetRoutes(t, resourceGroupName, routeTableName)
 		foundExtraRoute := false
@@ -124,7 +126,7 @@ func TestRouteTableMultipleUpdates(t *testing.T) {
 			"Environment": "Test-Updated",
 			"Managed":     "Terratest",
 		}
-		terraform.Apply(t, terraformOptions)
+		tfretry.ApplyWithRetry(t, terraformOptions)
 
 		routeTable3 := helper.GetRouteTableProperties(t, resourceGroupName, routeTableName)
 		assert.Equal(t, "Test-Updated", *routeTable3.Tags["Environment"])
@@ -143,14 +145,14 @@ func TestRouteTableWithSubnetAssociation(t *testing.T) {
 
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	// Deploy with subnet associations
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	// Validate subnet associations
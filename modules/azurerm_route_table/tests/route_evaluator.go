@@ -0,0 +1,163 @@
+package test
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v4"
+	"github.com/stretchr/testify/require"
+)
+
+// RouteDecision is the route selected for a destination and where it sends traffic
+type RouteDecision struct {
+	RouteName        string
+	AddressPrefix    string
+	NextHopType      armnetwork.RouteNextHopType
+	NextHopIPAddress string
+	SystemRoute      bool
+}
+
+// RouteEvaluator selects next hops from a fetched Route Table without calling Azure.
+// VirtualNetworkPrefixes are added as VnetLocal system routes for the subnet under test.
+type RouteEvaluator struct {
+	VirtualNetworkPrefixes []string
+}
+
+type candidateRoute struct {
+	decision RouteDecision
+	prefix   netip.Prefix
+}
+
+// NewRouteEvaluator creates an evaluator with the given VNet address space as system routes
+func NewRouteEvaluator(virtualNetworkPrefixes ...string) *RouteEvaluator {
+	return &RouteEvaluator{VirtualNetworkPrefixes: virtualNetworkPrefixes}
+}
+
+// NextHop returns the route Azure would use for the destination IP address or CIDR.
+// The longest matching prefix wins and user-defined routes override system routes with the same prefix.
+func (e *RouteEvaluator) NextHop(routeTable *armnetwork.RouteTable, destination string) (RouteDecision, error) {
+	target, err := parseDestination(destination)
+	if err != nil {
+		return RouteDecision{}, err
+	}
+
+	candidates, err := e.candidateRoutes(routeTable)
+	if err != nil {
+		return RouteDecision{}, err
+	}
+
+	var best *candidateRoute
+	for i := range candidates {
+		candidate := &candidates[i]
+		if candidate.prefix.Bits() > target.Bits() || !candidate.prefix.Contains(target.Addr()) {
+			continue
+		}
+		if best == nil || candidate.prefix.Bits() > best.prefix.Bits() ||
+			(candidate.prefix.Bits() == best.prefix.Bits() && best.decision.SystemRoute && !candidate.decision.SystemRoute) {
+			best = candidate
+		}
+	}
+	if best == nil {
+		return RouteDecision{}, fmt.Errorf("no route matches %s", destination)
+	}
+	return best.decision, nil
+}
+
+// RequireNextHop asserts the next hop type, next hop IP and deciding route for a destination
+func RequireNextHop(t *testing.T, evaluator *RouteEvaluator, routeTable *armnetwork.RouteTable, destination string, expectedType armnetwork.RouteNextHopType, expectedIP, expectedRoute string) {
+	decision, err := evaluator.NextHop(routeTable, destination)
+	require.NoError(t, err, "Failed to evaluate next hop for %s", destination)
+	require.Equal(t, expectedRoute, decision.RouteName, "Unexpected route selected for %s", destination)
+	require.Equal(t, expectedType, decision.NextHopType, "Unexpected next hop type for %s", destination)
+	require.Equal(t, expectedIP, decision.NextHopIPAddress, "Unexpected next hop IP for %s", destination)
+}
+
+// candidateRoutes combines user-defined routes with Azure's default system routes
+func (e *RouteEvaluator) candidateRoutes(routeTable *armnetwork.RouteTable) ([]candidateRoute, error) {
+	if routeTable == nil || routeTable.Properties == nil {
+		return nil, fmt.Errorf("route table has no properties")
+	}
+
+	var candidates []candidateRoute
+	for _, route := range routeTable.Properties.Routes {
+		if route == nil || route.Properties == nil || route.Properties.AddressPrefix == nil {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(*route.Properties.AddressPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("route %s uses %q, service tag prefixes cannot be evaluated offline", derefString(route.Name), *route.Properties.AddressPrefix)
+		}
+
+		decision := RouteDecision{
+			RouteName:        derefString(route.Name),
+			AddressPrefix:    prefix.String(),
+			NextHopIPAddress: derefString(route.Properties.NextHopIPAddress),
+		}
+		if route.Properties.NextHopType != nil {
+			decision.NextHopType = *route.Properties.NextHopType
+		}
+		candidates = append(candidates, candidateRoute{decision: decision, prefix: prefix.Masked()})
+	}
+
+	system := []struct {
+		name    string
+		prefix  string
+		nextHop armnetwork.RouteNextHopType
+	}{
+		{"SystemDefault", "0.0.0.0/0", armnetwork.RouteNextHopTypeInternet},
+		{"SystemRFC1918A", "10.0.0.0/8", armnetwork.RouteNextHopTypeNone},
+		{"SystemRFC1918B", "172.16.0.0/12", armnetwork.RouteNextHopTypeNone},
+		{"SystemRFC1918C", "192.168.0.0/16", armnetwork.RouteNextHopTypeNone},
+		{"SystemRFC6598", "100.64.0.0/10", armnetwork.RouteNextHopTypeNone},
+	}
+	for _, prefix := range e.VirtualNetworkPrefixes {
+		system = append(system, struct {
+			name    string
+			prefix  string
+			nextHop armnetwork.RouteNextHopType
+		}{"SystemVnetLocal", prefix, armnetwork.RouteNextHopTypeVnetLocal})
+	}
+
+	for _, route := range system {
+		prefix, err := netip.ParsePrefix(route.prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid virtual network prefix %q: %w", route.prefix, err)
+		}
+		candidates = append(candidates, candidateRoute{
+			decision: RouteDecision{
+				RouteName:     route.name,
+				AddressPrefix: prefix.Masked().String(),
+				NextHopType:   route.nextHop,
+				SystemRoute:   true,
+			},
+			prefix: prefix.Masked(),
+		})
+	}
+	return candidates, nil
+}
+
+// parseDestination accepts a single IP address or a CIDR
+func parseDestination(destination string) (netip.Prefix, error) {
+	if strings.Contains(destination, "/") {
+		prefix, err := netip.ParsePrefix(destination)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid destination %q: %w", destination, err)
+		}
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(destination)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid destination %q: %w", destination, err)
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+func derefString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,82 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRoute(name, prefix string, nextHop armnetwork.RouteNextHopType, nextHopIP string) *armnetwork.Route {
+	route := &armnetwork.Route{
+		Name: to.Ptr(name),
+		Properties: &armnetwork.RoutePropertiesFormat{
+			AddressPrefix: to.Ptr(prefix),
+			NextHopType:   to.Ptr(nextHop),
+		},
+	}
+	if nextHopIP != "" {
+		route.Properties.NextHopIPAddress = to.Ptr(nextHopIP)
+	}
+	return route
+}
+
+// secureFixtureRouteTable mirrors the routes defined by fixtures/secure
+func secureFixtureRouteTable() *armnetwork.RouteTable {
+	return &armnetwork.RouteTable{
+		Name: to.Ptr("rt-sec"),
+		Properties: &armnetwork.RouteTablePropertiesFormat{
+			Routes: []*armnetwork.Route{
+				testRoute("route-internet-via-firewall", "0.0.0.0/0", armnetwork.RouteNextHopTypeVirtualAppliance, "10.0.100.4"),
+				testRoute("route-dmz-via-firewall", "10.0.1.0/24", armnetwork.RouteNextHopTypeVirtualAppliance, "10.0.100.4"),
+				testRoute("route-block-db-internet", "10.0.3.0/24", armnetwork.RouteNextHopTypeNone, ""),
+				testRoute("route-vpn-via-firewall", "172.16.0.0/12", armnetwork.RouteNextHopTypeVirtualAppliance, "10.0.100.4"),
+				testRoute("route-block-rfc1918-a", "192.168.0.0/16", armnetwork.RouteNextHopTypeNone, ""),
+				testRoute("route-allow-local-vnet", "10.0.0.0/16", armnetwork.RouteNextHopTypeVnetLocal, ""),
+			},
+		},
+	}
+}
+
+// Test longest prefix match and user route precedence for the secure fixture routes
+func TestRouteEvaluatorSecureFixture(t *testing.T) {
+	t.Parallel()
+
+	routeTable := secureFixtureRouteTable()
+	evaluator := NewRouteEvaluator("10.0.0.0/16")
+
+	RequireNextHop(t, evaluator, routeTable, "0.0.0.0/0", armnetwork.RouteNextHopTypeVirtualAppliance, "10.0.100.4", "route-internet-via-firewall")
+	RequireNextHop(t, evaluator, routeTable, "10.0.1.4", armnetwork.RouteNextHopTypeVirtualAppliance, "10.0.100.4", "route-dmz-via-firewall")
+	RequireNextHop(t, evaluator, routeTable, "10.0.3.0/25", armnetwork.RouteNextHopTypeNone, "", "route-block-db-internet")
+	RequireNextHop(t, evaluator, routeTable, "10.0.2.5", armnetwork.RouteNextHopTypeVnetLocal, "", "route-allow-local-vnet")
+	RequireNextHop(t, evaluator, routeTable, "172.20.1.1", armnetwork.RouteNextHopTypeVirtualAppliance, "10.0.100.4", "route-vpn-via-firewall")
+}
+
+// Test system routes apply when the table has no matching user route
+func TestRouteEvaluatorSystemRoutes(t *testing.T) {
+	t.Parallel()
+
+	routeTable := &armnetwork.RouteTable{
+		Properties: &armnetwork.RouteTablePropertiesFormat{
+			Routes: []*armnetwork.Route{
+				testRoute("route-to-spoke1", "10.1.0.0/16", armnetwork.RouteNextHopTypeVirtualAppliance, "10.0.3.4"),
+			},
+		},
+	}
+	evaluator := NewRouteEvaluator("10.0.0.0/16")
+
+	decision, err := evaluator.NextHop(routeTable, "0.0.0.0/0")
+	require.NoError(t, err)
+	assert.True(t, decision.SystemRoute)
+	assert.Equal(t, armnetwork.RouteNextHopTypeInternet, decision.NextHopType)
+
+	RequireNextHop(t, evaluator, routeTable, "10.0.5.5", armnetwork.RouteNextHopTypeVnetLocal, "", "SystemVnetLocal")
+	RequireNextHop(t, evaluator, routeTable, "10.9.0.1", armnetwork.RouteNextHopTypeNone, "", "SystemRFC1918A")
+
+	routeTable.Properties.Routes = append(routeTable.Properties.Routes, testRoute("route-storage", "Storage", armnetwork.RouteNextHopTypeInternet, ""))
+	_, err = evaluator.NextHop(routeTable, "0.0.0.0/0")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "route-storage")
+}
@@ -4,6 +4,9 @@ import (
 	"testing"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v4"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/importtest"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/assert"
@@ -18,13 +21,13 @@ func TestBasicRouteTable(t *testing.T) {
 
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -40,6 +43,11 @@ func TestBasicRouteTable(t *testing.T) {
 		assert.True(t, *routeTable.Properties.DisableBgpRoutePropagation == false)
 		assert.Equal(t, 0, len(routeTable.Properties.Routes))
 	})
+
+	// Adopting the deployed module resources into an empty state must plan no changes
+	test_structure.RunTestStage(t, "import", func() {
+		importtest.RequireStateImportRoundTrip(t, test_structure.LoadTerraformOptions(t, testFolder))
+	})
 }
 
 // Test a more complete Route Table configuration
@@ -50,13 +58,13 @@ func TestCompleteRouteTable(t *testing.T) {
 
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -91,13 +99,13 @@ func TestSecureRouteTable(t *testing.T) {
 
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -120,6 +128,14 @@ func TestSecureRouteTable(t *testing.T) {
 				assert.NotNil(t, route.Properties.NextHopIPAddress)
 			}
 		}
+		// Evaluate where traffic actually goes from the associated subnets
+		applianceIP := terraform.Output(t, terraformOptions, "security_appliance_ip")
+		evaluator := NewRouteEvaluator("10.0.0.0/16")
+		RequireNextHop(t, evaluator, routeTable, "0.0.0.0/0", armnetwork.RouteNextHopTypeVirtualAppliance, applianceIP, "route-internet-via-firewall")
+		RequireNextHop(t, evaluator, routeTable, "203.0.113.10", armnetwork.RouteNextHopTypeVirtualAppliance, applianceIP, "route-internet-via-firewall")
+		RequireNextHop(t, evaluator, routeTable, "10.0.1.4", armnetwork.RouteNextHopTypeVirtualAppliance, applianceIP, "route-dmz-via-firewall")
+		RequireNextHop(t, evaluator, routeTable, "10.0.3.5", armnetwork.RouteNextHopTypeNone, "", "route-block-db-internet")
+		RequireNextHop(t, evaluator, routeTable, "10.0.2.5", armnetwork.RouteNextHopTypeVnetLocal, "", "route-allow-local-vnet")
 	})
 }
 
@@ -131,13 +147,13 @@ func TestNetworkRouteTable(t *testing.T) {
 
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -168,6 +184,19 @@ func TestNetworkRouteTable(t *testing.T) {
 			}
 		}
 		assert.True(t, hasInternetRoute || hasVirtualApplianceRoute, "Should have either Internet or Virtual Appliance routes")
+		// The hub table has no default route, so internet traffic uses the system route
+		nvaIP := terraform.Output(t, terraformOptions, "hub_nva_ip")
+		evaluator := NewRouteEvaluator("10.0.0.0/16")
+		RequireNextHop(t, evaluator, routeTable, "0.0.0.0/0", armnetwork.RouteNextHopTypeInternet, "", "SystemDefault")
+		RequireNextHop(t, evaluator, routeTable, "10.1.1.4", armnetwork.RouteNextHopTypeVirtualAppliance, nvaIP, "route-to-spoke1")
+
+		// Spokes force their default route through the hub NVA
+		spoke1 := helper.GetRouteTableProperties(t, resourceGroupName, terraform.Output(t, terraformOptions, "spoke1_route_table_name"))
+		spokeEvaluator := NewRouteEvaluator("10.1.0.0/16")
+		RequireNextHop(t, spokeEvaluator, spoke1, "0.0.0.0/0", armnetwork.RouteNextHopTypeVirtualAppliance, nvaIP, "route-default")
+		RequireNextHop(t, spokeEvaluator, spoke1, "10.2.1.4", armnetwork.RouteNextHopTypeVirtualAppliance, nvaIP, "route-to-spoke2")
+		RequireNextHop(t, spokeEvaluator, spoke1, "192.168.10.1", armnetwork.RouteNextHopTypeVirtualNetworkGateway, "", "route-to-onprem")
+		RequireNextHop(t, spokeEvaluator, spoke1, "10.1.1.4", armnetwork.RouteNextHopTypeVnetLocal, "", "SystemVnetLocal")
 	})
 }
 
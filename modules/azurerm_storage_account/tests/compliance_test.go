@@ -0,0 +1,69 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/compliance"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func findingStatuses(findings []compliance.Finding) map[string]string {
+	statuses := map[string]string{}
+	for _, finding := range findings {
+		statuses[finding.Rule.ID] = finding.Status
+	}
+	return statuses
+}
+
+// TestStorageAccountPlanCompliance evaluates a saved plan of the security fixture offline
+func TestStorageAccountPlanCompliance(t *testing.T) {
+	t.Parallel()
+
+	showJSON, err := os.ReadFile(filepath.Join("testdata", "compliance", "security_plan.json"))
+	require.NoError(t, err)
+	resources, err := compliance.ParseResourcesE(showJSON)
+	require.NoError(t, err)
+	require.Len(t, resources, 2, "deleted resources and data sources are not evaluated")
+
+	findings := compliance.Evaluate(compliance.LoadRules(t, "azurerm_storage_account"), resources)
+	assert.Equal(t, map[string]string{
+		"CKV_AZURE_CUSTOM_1":            compliance.Pass,
+		"AZURE_STORAGE_HTTPS_ONLY":      compliance.Pass,
+		"AZURE_STORAGE_MIN_TLS":         compliance.Pass,
+		"AZURE_STORAGE_NO_PUBLIC_BLOBS": compliance.Pass,
+		"AZURE_STORAGE_NETWORK_DENY":    compliance.Pass,
+	}, findingStatuses(findings))
+
+	// The security fixture passes every rule
+	compliance.AssertShowJSON(t, showJSON, "azurerm_storage_account")
+}
+
+func TestStorageAccountStateCompliance(t *testing.T) {
+	t.Parallel()
+
+	showJSON, err := os.ReadFile(filepath.Join("testdata", "compliance", "public_state.json"))
+	require.NoError(t, err)
+	resources, err := compliance.ParseResourcesE(showJSON)
+	require.NoError(t, err)
+
+	findings := compliance.Evaluate(compliance.LoadRules(t, "azurerm_storage_account"), resources)
+	assert.Equal(t, map[string]string{
+		"CKV_AZURE_CUSTOM_1":            compliance.Pass,
+		"AZURE_STORAGE_HTTPS_ONLY":      compliance.Pass,
+		"AZURE_STORAGE_MIN_TLS":         compliance.Fail,
+		"AZURE_STORAGE_NO_PUBLIC_BLOBS": compliance.Fail,
+		"AZURE_STORAGE_NETWORK_DENY":    compliance.Fail,
+	}, findingStatuses(findings))
+
+	for _, finding := range findings {
+		switch finding.Rule.ID {
+		case "AZURE_STORAGE_MIN_TLS":
+			assert.Equal(t, "min_tls_version is TLS1_0, want equals TLS1_2", finding.Detail)
+		case "AZURE_STORAGE_NETWORK_DENY":
+			assert.Equal(t, "network_rules.0 is missing, want equals Deny", finding.Detail)
+		}
+	}
+}
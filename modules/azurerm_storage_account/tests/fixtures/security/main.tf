@@ -56,9 +56,12 @@ module "storage_account" {
   }
 
   tags = {
-    Environment = "Test"
-    TestType    = "Security"
-    Compliance  = "High"
+    Environment        = "Test"
+    TestType           = "Security"
+    Compliance         = "High"
+    Owner              = "platform"
+    CostCenter         = "1234"
+    DataClassification = "Internal"
   }
 }
 
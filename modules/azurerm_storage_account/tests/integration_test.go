@@ -6,6 +6,10 @@ import (
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/storage/armstorage"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/cassette"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/compliance"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	// "github.com/gruntwork-io/terratest/modules/azure" // Commented out due to SQL import issue
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
@@ -23,16 +27,16 @@ func TestStorageAccountFullIntegration(t *testing.T) {
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/complete")
 	
 	// Setup stages
-	defer test_structure.RunTestStage(t, "cleanup", func() {
+	defer cassette.RunLiveStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	// Deploy infrastructure
-	test_structure.RunTestStage(t, "deploy", func() {
+	cassette.RunLiveStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	// Validate all components
@@ -55,12 +59,12 @@ func TestStorageAccountFullIntegration(t *testing.T) {
 
 // validateCoreFeatures validates basic storage account features
 func validateCoreFeatures(t *testing.T, testFolder string) {
-	terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+	terraformOptions := cassette.LoadTerraformOptions(t, testFolder)
 	helper := NewStorageAccountHelper(t)
 
 	// Get outputs
-	storageAccountName := terraform.Output(t, terraformOptions, "storage_account_name")
-	resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
+	storageAccountName := cassette.Output(t, terraformOptions, "storage_account_name")
+	resourceGroupName := cassette.Output(t, terraformOptions, "resource_group_name")
 	
 	// Get storage account details
 	account := helper.GetStorageAccountProperties(t, storageAccountName, resourceGroupName)
@@ -85,11 +89,11 @@ func validateCoreFeatures(t *testing.T, testFolder string) {
 
 // validateSecurityFeatures validates security configurations
 func validateSecurityFeatures(t *testing.T, testFolder string) {
-	terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+	terraformOptions := cassette.LoadTerraformOptions(t, testFolder)
 	helper := NewStorageAccountHelper(t)
 
-	storageAccountName := terraform.Output(t, terraformOptions, "storage_account_name")
-	resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
+	storageAccountName := cassette.Output(t, terraformOptions, "storage_account_name")
+	resourceGroupName := cassette.Output(t, terraformOptions, "resource_group_name")
 	
 	account := helper.GetStorageAccountProperties(t, storageAccountName, resourceGroupName)
 
@@ -108,11 +112,11 @@ func validateSecurityFeatures(t *testing.T, testFolder string) {
 
 // validateNetworkFeatures validates network configurations
 func validateNetworkFeatures(t *testing.T, testFolder string) {
-	terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+	terraformOptions := cassette.LoadTerraformOptions(t, testFolder)
 	helper := NewStorageAccountHelper(t)
 
-	storageAccountName := terraform.Output(t, terraformOptions, "storage_account_name")
-	resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
+	storageAccountName := cassette.Output(t, terraformOptions, "storage_account_name")
+	resourceGroupName := cassette.Output(t, terraformOptions, "resource_group_name")
 	
 	account := helper.GetStorageAccountProperties(t, storageAccountName, resourceGroupName)
 
@@ -127,12 +131,12 @@ func validateNetworkFeatures(t *testing.T, testFolder string) {
 
 // validateOperationalFeatures validates operational features like monitoring
 func validateOperationalFeatures(t *testing.T, testFolder string) {
-	terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+	terraformOptions := cassette.LoadTerraformOptions(t, testFolder)
 	helper := NewStorageAccountHelper(t)
 
-	storageAccountName := terraform.Output(t, terraformOptions, "storage_account_name")
-	resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
-	storageAccountID := terraform.Output(t, terraformOptions, "storage_account_id")
+	storageAccountName := cassette.Output(t, terraformOptions, "storage_account_name")
+	resourceGroupName := cassette.Output(t, terraformOptions, "resource_group_name")
+	storageAccountID := cassette.Output(t, terraformOptions, "storage_account_id")
 	
 	// Validate blob service properties
 	helper.ValidateBlobServiceProperties(t, storageAccountName, resourceGroupName)
@@ -141,13 +145,15 @@ func validateOperationalFeatures(t *testing.T, testFolder string) {
 	ValidateDiagnosticSettings(t, storageAccountID)
 	
 	// Validate containers were created
-	containerNames := terraform.OutputList(t, terraformOptions, "container_names")
+	containerNames := cassette.OutputList(t, terraformOptions, "container_names")
 	assert.Equal(t, 3, len(containerNames))
 	
 	// Validate each container exists
-	for _, containerName := range containerNames {
-		ValidateContainerExists(t, storageAccountName, resourceGroupName, containerName)
-	}
+	cassette.RunLiveStage(t, "validate_containers", func() {
+		for _, containerName := range containerNames {
+			ValidateContainerExists(t, storageAccountName, resourceGroupName, containerName)
+		}
+	})
 }
 
 // TestStorageAccountLifecycle tests the complete lifecycle of storage account
@@ -160,14 +166,18 @@ func TestStorageAccountLifecycle(t *testing.T) {
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/simple")
 	terraformOptions := getTerraformOptions(t, testFolder)
 	
-	defer terraform.Destroy(t, terraformOptions)
+	defer cassette.RunLiveStage(t, "cleanup", func() {
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
+	})
 
 	// Initial deployment
-	terraform.InitAndApply(t, terraformOptions)
+	cassette.RunLiveStage(t, "deploy", func() {
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
+	})
 	
 	// Get initial state
-	storageAccountName := terraform.Output(t, terraformOptions, "storage_account_name")
-	resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
+	storageAccountName := cassette.Output(t, terraformOptions, "storage_account_name")
+	resourceGroupName := cassette.Output(t, terraformOptions, "resource_group_name")
 	
 	// Verify initial deployment using our helper
 	helper := NewStorageAccountHelper(t)
@@ -176,13 +186,17 @@ func TestStorageAccountLifecycle(t *testing.T) {
 	
 	// Update configuration (enable blob versioning)
 	terraformOptions.Vars["enable_blob_versioning"] = true
-	terraform.Apply(t, terraformOptions)
+	cassette.RunLiveStage(t, "update", func() {
+		tfretry.ApplyWithRetry(t, terraformOptions)
+	})
 	
 	// Verify update was applied
 	helper.ValidateBlobServiceProperties(t, storageAccountName, resourceGroupName)
 	
 	// Test idempotency - apply again without changes
-	terraform.Apply(t, terraformOptions)
+	cassette.RunLiveStage(t, "idempotency", func() {
+		tfretry.ApplyWithRetry(t, terraformOptions)
+	})
 }
 
 // TestStorageAccountDisasterRecovery tests failover scenarios with RA-GRS for read access to secondary endpoints
@@ -197,13 +211,17 @@ func TestStorageAccountDisasterRecovery(t *testing.T) {
 	// Use RA-GRS for read access to secondary region
 	terraformOptions.Vars["account_replication_type"] = "RAGRS"
 	
-	defer terraform.Destroy(t, terraformOptions)
+	defer cassette.RunLiveStage(t, "cleanup", func() {
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
+	})
 	
 	// Deploy
-	terraform.InitAndApply(t, terraformOptions)
+	cassette.RunLiveStage(t, "deploy", func() {
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
+	})
 	
-	storageAccountName := terraform.Output(t, terraformOptions, "storage_account_name")
-	resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
+	storageAccountName := cassette.Output(t, terraformOptions, "storage_account_name")
+	resourceGroupName := cassette.Output(t, terraformOptions, "resource_group_name")
 	
 	// Verify GRS is configured
 	helper := NewStorageAccountHelper(t)
@@ -240,13 +258,23 @@ func TestStorageAccountCompliance(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/security")
 	terraformOptions := getTerraformOptions(t, testFolder)
+
+	// Rules from security-policies/compliance are checked on the plan and on the applied state
+	cassette.RunLiveStage(t, "plan_compliance", func() {
+		compliance.AssertPlan(t, terraformOptions, "azurerm_storage_account")
+	})
 	
-	defer terraform.Destroy(t, terraformOptions)
+	defer cassette.RunLiveStage(t, "cleanup", func() {
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
+	})
 	
-	terraform.InitAndApply(t, terraformOptions)
+	cassette.RunLiveStage(t, "deploy", func() {
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
+		compliance.AssertState(t, terraformOptions, "azurerm_storage_account")
+	})
 	
-	storageAccountName := terraform.Output(t, terraformOptions, "storage_account_name")
-	resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
+	storageAccountName := cassette.Output(t, terraformOptions, "storage_account_name")
+	resourceGroupName := cassette.Output(t, terraformOptions, "resource_group_name")
 	
 	helper := NewStorageAccountHelper(t)
 	account := helper.GetStorageAccountProperties(t, storageAccountName, resourceGroupName)
@@ -298,9 +326,9 @@ func TestStorageAccountMonitoring(t *testing.T) {
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/complete")
 	terraformOptions := getTerraformOptions(t, testFolder)
 	
-	defer terraform.Destroy(t, terraformOptions)
+	defer destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	
-	terraform.InitAndApply(t, terraformOptions)
+	tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	
 	storageAccountID := terraform.Output(t, terraformOptions, "storage_account_id")
 	
@@ -0,0 +1,36 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/naming"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateValidStorageAccountName(t *testing.T) {
+	t.Parallel()
+
+	name := GenerateValidStorageAccountName("st-test-with-a-long-prefix")
+	assert.Len(t, name, 24)
+	assert.NoError(t, naming.ValidateResourceName("azurerm_storage_account", name))
+}
+
+// TestNamingTestFileIsGenerated keeps the generated runs in unit/naming.tftest.hcl
+// in sync with the registry; set UPDATE_NAMING_TESTS=1 to regenerate them
+func TestNamingTestFileIsGenerated(t *testing.T) {
+	path := filepath.Join("unit", "naming.tftest.hcl")
+	if os.Getenv("UPDATE_NAMING_TESTS") != "" {
+		_, err := naming.UpdateTestFileE(path, "azurerm_storage_account", "azurerm_storage_account.storage_account")
+		require.NoError(t, err)
+	}
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	generated, err := naming.RenderTestRuns("azurerm_storage_account", "azurerm_storage_account.storage_account")
+	require.NoError(t, err)
+	assert.True(t, strings.Contains(string(content), generated), "%s is out of date; run with UPDATE_NAMING_TESTS=1", path)
+}
@@ -8,6 +8,11 @@ import (
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/storage/armstorage"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/cassette"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/importtest"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/upgrade"
 	// "github.com/gruntwork-io/terratest/modules/azure" // Commented out due to SQL import issue
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/terraform"
@@ -22,25 +27,25 @@ func TestBasicStorageAccount(t *testing.T) {
 
 	// Create a folder for this test
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/simple")
-	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+	defer cassette.RunLiveStage(t, "cleanup", func() {
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	// Deploy the infrastructure
-	test_structure.RunTestStage(t, "deploy", func() {
+	cassette.RunLiveStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	// Validate the infrastructure
 	test_structure.RunTestStage(t, "validate", func() {
-		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+		terraformOptions := cassette.LoadTerraformOptions(t, testFolder)
 
 		// Get outputs
-		storageAccountName := terraform.Output(t, terraformOptions, "storage_account_name")
-		resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
-		storageAccountID := terraform.Output(t, terraformOptions, "storage_account_id")
+		storageAccountName := cassette.Output(t, terraformOptions, "storage_account_name")
+		resourceGroupName := cassette.Output(t, terraformOptions, "resource_group_name")
+		storageAccountID := cassette.Output(t, terraformOptions, "storage_account_id")
 
 		// Validate outputs are not empty
 		assert.NotEmpty(t, storageAccountName)
@@ -62,6 +67,11 @@ func TestBasicStorageAccount(t *testing.T) {
 		assert.True(t, *storageAccount.Properties.EnableHTTPSTrafficOnly)
 		assert.Equal(t, armstorage.MinimumTLSVersionTLS12, *storageAccount.Properties.MinimumTLSVersion)
 	})
+
+	// Adopting the deployed module resources into an empty state must plan no changes
+	cassette.RunLiveStage(t, "import", func() {
+		importtest.RequireStateImportRoundTrip(t, test_structure.LoadTerraformOptions(t, testFolder))
+	})
 }
 
 // Test complete storage account with all features
@@ -69,24 +79,24 @@ func TestCompleteStorageAccount(t *testing.T) {
 	t.Parallel()
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/complete")
-	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+	defer cassette.RunLiveStage(t, "cleanup", func() {
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDestroyVerifier(t))
 	})
 
-	test_structure.RunTestStage(t, "deploy", func() {
+	cassette.RunLiveStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
-		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+		terraformOptions := cassette.LoadTerraformOptions(t, testFolder)
 
 		// Get outputs
-		storageAccountName := terraform.Output(t, terraformOptions, "storage_account_name")
-		resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
-		containerNames := terraform.OutputList(t, terraformOptions, "container_names")
-		primaryBlobEndpoint := terraform.Output(t, terraformOptions, "primary_blob_endpoint")
+		storageAccountName := cassette.Output(t, terraformOptions, "storage_account_name")
+		resourceGroupName := cassette.Output(t, terraformOptions, "resource_group_name")
+		containerNames := cassette.OutputList(t, terraformOptions, "container_names")
+		primaryBlobEndpoint := cassette.Output(t, terraformOptions, "primary_blob_endpoint")
 
 		// Use our helper instead of azure module
 		helper := NewStorageAccountHelper(t)
@@ -106,12 +116,14 @@ func TestCompleteStorageAccount(t *testing.T) {
 		// Validate blob endpoint is not publicly accessible
 		// Storage account should reject unauthorized requests
 		// Note: We're not testing specific error codes as they may vary
-		resp, err := http.Get(primaryBlobEndpoint)
-		if err == nil {
-			defer resp.Body.Close()
-			// Should not get 200 OK without authorization
-			assert.NotEqual(t, 200, resp.StatusCode)
-		}
+		cassette.RunLiveStage(t, "validate_blob_access", func() {
+			resp, err := http.Get(primaryBlobEndpoint)
+			if err == nil {
+				defer resp.Body.Close()
+				// Should not get 200 OK without authorization
+				assert.NotEqual(t, 200, resp.StatusCode)
+			}
+		})
 	})
 }
 
@@ -120,21 +132,21 @@ func TestStorageAccountSecurity(t *testing.T) {
 	t.Parallel()
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/security")
-	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+	defer cassette.RunLiveStage(t, "cleanup", func() {
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDestroyVerifier(t))
 	})
 
-	test_structure.RunTestStage(t, "deploy", func() {
+	cassette.RunLiveStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
-		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+		terraformOptions := cassette.LoadTerraformOptions(t, testFolder)
 
-		storageAccountName := terraform.Output(t, terraformOptions, "storage_account_name")
-		resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
+		storageAccountName := cassette.Output(t, terraformOptions, "storage_account_name")
+		resourceGroupName := cassette.Output(t, terraformOptions, "resource_group_name")
 
 		// Use our helper instead of azure module
 		helper := NewStorageAccountHelper(t)
@@ -165,21 +177,21 @@ func TestStorageAccountNetworkRules(t *testing.T) {
 	t.Parallel()
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/network")
-	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+	defer cassette.RunLiveStage(t, "cleanup", func() {
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDestroyVerifier(t))
 	})
 
-	test_structure.RunTestStage(t, "deploy", func() {
+	cassette.RunLiveStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
-		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+		terraformOptions := cassette.LoadTerraformOptions(t, testFolder)
 
-		storageAccountName := terraform.Output(t, terraformOptions, "storage_account_name")
-		resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
+		storageAccountName := cassette.Output(t, terraformOptions, "storage_account_name")
+		resourceGroupName := cassette.Output(t, terraformOptions, "resource_group_name")
 
 		// Use our helper instead of azure module
 		helper := NewStorageAccountHelper(t)
@@ -212,22 +224,22 @@ func TestStorageAccountPrivateEndpoint(t *testing.T) {
 	t.Parallel()
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/private_endpoint")
-	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+	defer cassette.RunLiveStage(t, "cleanup", func() {
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDestroyVerifier(t))
 	})
 
-	test_structure.RunTestStage(t, "deploy", func() {
+	cassette.RunLiveStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
-		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+		terraformOptions := cassette.LoadTerraformOptions(t, testFolder)
 
-		storageAccountName := terraform.Output(t, terraformOptions, "storage_account_name")
-		resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
-		privateEndpointID := terraform.Output(t, terraformOptions, "private_endpoint_id")
+		storageAccountName := cassette.Output(t, terraformOptions, "storage_account_name")
+		resourceGroupName := cassette.Output(t, terraformOptions, "resource_group_name")
+		privateEndpointID := cassette.Output(t, terraformOptions, "private_endpoint_id")
 
 		// Validate private endpoint was created
 		assert.NotEmpty(t, privateEndpointID)
@@ -244,6 +256,36 @@ func TestStorageAccountPrivateEndpoint(t *testing.T) {
 	})
 }
 
+// Test that consumers can upgrade from the last SAv release without recreating the storage account
+func TestStorageAccountUpgrade(t *testing.T) {
+	t.Parallel()
+
+	upgrade.RunUpgradeTest(t, upgrade.Test{ModuleDir: "..", FixtureFolder: "tests/fixtures/simple"}, getTerraformOptions)
+}
+
+// TestStorageAccountHelperReplay runs the helper and its assertions against a recorded cassette.
+// The name suffix and outputs come from the recording, so the requests match without Terraform.
+func TestStorageAccountHelperReplay(t *testing.T) {
+	t.Setenv(cassette.ModeEnv, cassette.Replay)
+
+	terraformOptions := getTerraformOptions(t, "fixtures/simple")
+	assert.Equal(t, "x7k2p", terraformOptions.Vars["random_suffix"])
+	storageAccountName := cassette.Output(t, terraformOptions, "storage_account_name")
+	resourceGroupName := cassette.Output(t, terraformOptions, "resource_group_name")
+
+	helper := NewStorageAccountHelper(t)
+	account := helper.GetStorageAccountProperties(t, storageAccountName, resourceGroupName)
+
+	assert.Equal(t, armstorage.SKUNameStandardLRS, *account.SKU.Name)
+	assert.Equal(t, armstorage.KindStorageV2, *account.Kind)
+	assert.True(t, *account.Properties.EnableHTTPSTrafficOnly)
+	assert.Equal(t, armstorage.MinimumTLSVersionTLS12, *account.Properties.MinimumTLSVersion)
+	helper.ValidateStorageAccountEncryption(t, account)
+	helper.ValidateNetworkRules(t, account, []string{"203.0.113.10"}, nil)
+	ValidateStorageAccountTags(t, account, map[string]string{"Environment": "Test"})
+	helper.ValidateBlobServiceProperties(t, storageAccountName, resourceGroupName)
+}
+
 // Negative test cases for validation rules
 func TestStorageAccountValidationRules(t *testing.T) {
 	t.Parallel()
@@ -312,7 +354,7 @@ func BenchmarkStorageAccountCreation(b *testing.B) {
 	terraformOptions := getTerraformOptions(b, testFolder)
 
 	// Cleanup after benchmark
-	defer terraform.Destroy(b, terraformOptions)
+	defer tfretry.DestroyWithRetry(b, terraformOptions)
 
 	// Run the benchmark
 	b.ResetTimer()
@@ -321,8 +363,8 @@ func BenchmarkStorageAccountCreation(b *testing.B) {
 		// Override the random_suffix for each iteration
 		terraformOptions.Vars["random_suffix"] = fmt.Sprintf("%d%s", i, terraformOptions.Vars["random_suffix"].(string)[:5])
 		
-		terraform.InitAndApply(b, terraformOptions)
-		terraform.Destroy(b, terraformOptions)
+		tfretry.InitAndApplyWithRetry(b, terraformOptions)
+		tfretry.DestroyWithRetry(b, terraformOptions)
 	}
 }
 
@@ -330,7 +372,8 @@ func BenchmarkStorageAccountCreation(b *testing.B) {
 func getTerraformOptions(t testing.TB, terraformDir string) *terraform.Options {
 	// Generate a unique random suffix for resource naming
 	// The suffix will be used in Terraform templates to create unique names
-	randomSuffix := strings.ToLower(random.UniqueId())
+	// Replay reuses the suffix recorded in the test's cassette
+	randomSuffix := strings.ToLower(cassette.UniqueID(t, random.UniqueId))
 	
 	return &terraform.Options{
 		TerraformDir: terraformDir,
@@ -340,13 +383,9 @@ func getTerraformOptions(t testing.TB, terraformDir string) *terraform.Options {
 		},
 		NoColor: true,
 		// Retry configuration
-		RetryableTerraformErrors: map[string]string{
-			".*timeout.*":                    "Timeout error - retrying",
-			".*ResourceGroupNotFound.*":      "Resource group not found - retrying",
-			".*StorageAccountAlreadyTaken.*": "Storage account name taken - retrying",
-		},
-		MaxRetries:         3,
-		TimeBetweenRetries: 10 * time.Second,
+		RetryableTerraformErrors: tfretry.ClassifiedRetryableErrors(),
+		MaxRetries:               3,
+		TimeBetweenRetries:       10 * time.Second,
 	}
 }
 
@@ -17,6 +17,8 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/storage/armstorage"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/cassette"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/naming"
 	"github.com/gruntwork-io/terratest/modules/azure"
 	"github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/gruntwork-io/terratest/modules/retry"
@@ -31,11 +33,31 @@ type StorageAccountHelper struct {
 	blobClient     *armstorage.BlobServicesClient
 }
 
-// NewStorageAccountHelper creates a new helper instance
+// NewStorageAccountHelper creates a new helper instance.
+// SDK traffic goes through the test's HTTP cassette when AZURE_CASSETTE_MODE is set.
 func NewStorageAccountHelper(t *testing.T) *StorageAccountHelper {
-	subscriptionID := getRequiredEnvVar(t, "AZURE_SUBSCRIPTION_ID")
+	subscriptionID, credential, options := cassette.Session(t,
+		func() string { return getRequiredEnvVar(t, "AZURE_SUBSCRIPTION_ID") },
+		func() azcore.TokenCredential { return getAzureCredential(t) })
+
+	// Create storage accounts client
+	client, err := armstorage.NewAccountsClient(subscriptionID, credential, options)
+	require.NoError(t, err, "Failed to create storage accounts client")
 	
-	// Create credential based on available credentials
+	// Create blob services client
+	blobClient, err := armstorage.NewBlobServicesClient(subscriptionID, credential, options)
+	require.NoError(t, err, "Failed to create blob services client")
+
+	return &StorageAccountHelper{
+		subscriptionID: subscriptionID,
+		credential:     credential,
+		client:         client,
+		blobClient:     blobClient,
+	}
+}
+
+// getAzureCredential creates a credential based on available credentials
+func getAzureCredential(t *testing.T) azcore.TokenCredential {
 	var credential azcore.TokenCredential
 	var err error
 	
@@ -57,21 +79,7 @@ func NewStorageAccountHelper(t *testing.T) *StorageAccountHelper {
 			require.NoError(t, err, "Failed to create credential")
 		}
 	}
-
-	// Create storage accounts client
-	client, err := armstorage.NewAccountsClient(subscriptionID, credential, nil)
-	require.NoError(t, err, "Failed to create storage accounts client")
-	
-	// Create blob services client
-	blobClient, err := armstorage.NewBlobServicesClient(subscriptionID, credential, nil)
-	require.NoError(t, err, "Failed to create blob services client")
-
-	return &StorageAccountHelper{
-		subscriptionID: subscriptionID,
-		credential:     credential,
-		client:         client,
-		blobClient:     blobClient,
-	}
+	return credential
 }
 
 // GetStorageAccountProperties retrieves detailed storage account properties
@@ -179,24 +187,9 @@ func ValidateDiagnosticSettings(t *testing.T, storageAccountID string) {
 
 // GenerateValidStorageAccountName generates a valid storage account name
 func GenerateValidStorageAccountName(prefix string) string {
-	timestamp := time.Now().Unix()
-	name := fmt.Sprintf("%s%d", prefix, timestamp)
-	
-	// Ensure name is lowercase and within length limits
-	name = strings.ToLower(name)
-	if len(name) > 24 {
-		name = name[:24]
-	}
-	
-	// Remove any invalid characters
-	validName := ""
-	for _, char := range name {
-		if (char >= 'a' && char <= 'z') || (char >= '0' && char <= '9') {
-			validName += string(char)
-		}
-	}
-	
-	return validName
+	// The registry pads and trims any prefix into a valid name, so the error is always nil
+	name, _ := naming.GenerateResourceNameE("azurerm_storage_account", prefix, fmt.Sprintf("%d", time.Now().Unix()))
+	return name
 }
 
 // ValidateContainerExists checks if a container exists in the storage account
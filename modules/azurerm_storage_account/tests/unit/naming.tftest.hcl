@@ -132,4 +132,95 @@ run "ends_with_number" {
     condition     = azurerm_storage_account.storage_account.name == "storageaccount1"
     error_message = "Storage account name ending with a number should be valid"
   }
-}
\ No newline at end of file
+}
+
+# BEGIN GENERATED NAMING RULES from shared/testkit/naming for azurerm_storage_account; regenerate with UPDATE_NAMING_TESTS=1
+
+run "naming_rule_min_length" {
+  command = plan
+
+  variables {
+    name = "abc"
+  }
+
+  assert {
+    condition     = azurerm_storage_account.storage_account.name == "abc"
+    error_message = "A name with 3 characters should be valid."
+  }
+}
+
+run "naming_rule_max_length" {
+  command = plan
+
+  variables {
+    name = "abcdefghijklmnopqrstuvwx"
+  }
+
+  assert {
+    condition     = azurerm_storage_account.storage_account.name == "abcdefghijklmnopqrstuvwx"
+    error_message = "A name with 24 characters should be valid."
+  }
+}
+
+run "naming_rule_generated" {
+  command = plan
+
+  variables {
+    name = "namingtesta1b2c3"
+  }
+
+  assert {
+    condition     = azurerm_storage_account.storage_account.name == "namingtesta1b2c3"
+    error_message = "A name with a prefix and unique ID should be valid."
+  }
+}
+
+run "naming_rule_too_short" {
+  command = plan
+
+  variables {
+    name = "ab"
+  }
+
+  expect_failures = [
+    var.name,
+  ]
+}
+
+run "naming_rule_too_long" {
+  command = plan
+
+  variables {
+    name = "abcdefghijklmnopqrstuvwxy"
+  }
+
+  expect_failures = [
+    var.name,
+  ]
+}
+
+run "naming_rule_invalid_character" {
+  command = plan
+
+  variables {
+    name = "ab!cd"
+  }
+
+  expect_failures = [
+    var.name,
+  ]
+}
+
+run "naming_rule_uppercase" {
+  command = plan
+
+  variables {
+    name = "Abcd"
+  }
+
+  expect_failures = [
+    var.name,
+  ]
+}
+
+# END GENERATED NAMING RULES
@@ -0,0 +1,25 @@
+package test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/upgrade"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadModuleConfig(t *testing.T) {
+	config, err := upgrade.ReadModuleConfigE("..")
+	require.NoError(t, err)
+	assert.Equal(t, "azurerm_storage_account", config.Name)
+	assert.Equal(t, "SAv", config.TagPrefix)
+}
+
+func TestChangelogIsNotBreaking(t *testing.T) {
+	changelog, err := os.ReadFile("../CHANGELOG.md")
+	require.NoError(t, err)
+	base, _ := upgrade.ParseModuleRelease("SAv1.2.3", "SAv")
+	// The "BREAKING CHANGE" in the example entry format is inside a code block and must not count
+	assert.False(t, upgrade.ChangelogMarksBreaking(string(changelog), "SAv", base))
+}
@@ -12,28 +12,13 @@ provider "azurerm" {
   features {}
 }
 
-resource "azurerm_resource_group" "example" {
-  name     = "rg-subnet-basic-${var.random_suffix}"
-  location = var.location
-}
-
-resource "azurerm_virtual_network" "example" {
-  name                = "vnet-subnet-basic-${var.random_suffix}"
-  resource_group_name = azurerm_resource_group.example.name
-  location            = azurerm_resource_group.example.location
-  address_space       = ["10.0.0.0/16"]
-
-  tags = {
-    Environment = var.tag_environment
-    Example     = "Basic"
-  }
-}
-
+# The resource group and virtual network come from the shared network baseline leased by the tests
 module "subnet" {
   source = "../../../"
 
   name                 = "snet-subnet-basic-${var.random_suffix}"
-  resource_group_name  = azurerm_resource_group.example.name
-  virtual_network_name = azurerm_virtual_network.example.name
-  address_prefixes     = ["10.0.1.0/24"]
+  resource_group_name  = var.baseline_resource_group_name
+  virtual_network_name = var.baseline_virtual_network_name
+  address_prefixes     = [var.baseline_subnet_address_prefix]
+  service_endpoints    = var.service_endpoints
 }
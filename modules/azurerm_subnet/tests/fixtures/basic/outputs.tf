@@ -10,5 +10,5 @@ output "subnet_name" {
 
 output "resource_group_name" {
   description = "The name of the resource group"
-  value       = azurerm_resource_group.example.name
+  value       = var.baseline_resource_group_name
 }
@@ -10,8 +10,23 @@ variable "random_suffix" {
   default     = ""
 }
 
-variable "tag_environment" {
-  description = "Environment tag for resources"
+variable "baseline_resource_group_name" {
+  description = "Resource group of the shared network baseline"
   type        = string
-  default     = "Development"
+}
+
+variable "baseline_virtual_network_name" {
+  description = "Virtual network of the shared network baseline"
+  type        = string
+}
+
+variable "baseline_subnet_address_prefix" {
+  description = "Address prefix leased to this fixture within the baseline virtual network"
+  type        = string
+}
+
+variable "service_endpoints" {
+  description = "Service endpoints for the subnet"
+  type        = list(string)
+  default     = []
 }
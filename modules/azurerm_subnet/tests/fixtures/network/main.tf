@@ -66,6 +66,16 @@ module "subnet" {
     "Microsoft.KeyVault"
   ]
 
+  delegations = {
+    aci = {
+      name = "aci"
+      service_delegation = {
+        name    = "Microsoft.ContainerInstance/containerGroups"
+        actions = ["Microsoft.Network/virtualNetworks/subnets/action"]
+      }
+    }
+  }
+
   private_endpoint_network_policies_enabled     = true
   private_link_service_network_policies_enabled = true
 }
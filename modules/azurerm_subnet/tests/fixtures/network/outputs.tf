@@ -26,3 +26,8 @@ output "resource_group_name" {
   description = "The name of the resource group"
   value       = azurerm_resource_group.test.name
 }
+
+output "network_security_group_id" {
+  description = "The ID of the externally associated network security group"
+  value       = azurerm_network_security_group.test.id
+}
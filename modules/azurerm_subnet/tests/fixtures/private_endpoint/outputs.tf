@@ -27,3 +27,8 @@ output "private_endpoint_id" {
   description = "The ID of the private endpoint"
   value       = azurerm_private_endpoint.example.id
 }
+
+output "virtual_network_name" {
+  description = "The name of the virtual network"
+  value       = azurerm_virtual_network.example.name
+}
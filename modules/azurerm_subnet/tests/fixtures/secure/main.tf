@@ -54,6 +54,52 @@ resource "azurerm_network_security_group" "secure" {
   }
 }
 
+resource "azurerm_route_table" "secure" {
+  name                = "rt-subnet-secure-${var.random_suffix}"
+  location            = azurerm_resource_group.example.location
+  resource_group_name = azurerm_resource_group.example.name
+
+  route {
+    name                   = "default-to-firewall"
+    address_prefix         = "0.0.0.0/0"
+    next_hop_type          = "VirtualAppliance"
+    next_hop_in_ip_address = "10.0.0.4"
+  }
+
+  tags = {
+    Environment   = "Production"
+    Purpose       = "Forced Tunneling"
+    SecurityLevel = "High"
+  }
+}
+
+resource "azurerm_storage_account" "allowed" {
+  name                     = "stsubsec${var.random_suffix}"
+  resource_group_name      = azurerm_resource_group.example.name
+  location                 = azurerm_resource_group.example.location
+  account_tier             = "Standard"
+  account_replication_type = "LRS"
+
+  tags = {
+    Environment   = "Production"
+    SecurityLevel = "High"
+  }
+}
+
+resource "azurerm_subnet_service_endpoint_storage_policy" "secure" {
+  name                = "sep-subnet-secure-${var.random_suffix}"
+  resource_group_name = azurerm_resource_group.example.name
+  location            = azurerm_resource_group.example.location
+
+  definition {
+    name        = "allowed-storage"
+    description = "Only allow the fixture storage account"
+    service_resources = [
+      azurerm_storage_account.allowed.id
+    ]
+  }
+}
+
 module "subnet" {
   source = "../../../"
 
@@ -68,6 +114,10 @@ module "subnet" {
     "Microsoft.AzureActiveDirectory"
   ]
 
+  service_endpoint_policy_ids = [
+    azurerm_subnet_service_endpoint_storage_policy.secure.id
+  ]
+
   private_endpoint_network_policies_enabled     = true
   private_link_service_network_policies_enabled = true
 
@@ -75,5 +125,8 @@ module "subnet" {
     network_security_group = {
       id = azurerm_network_security_group.secure.id
     }
+    route_table = {
+      id = azurerm_route_table.secure.id
+    }
   }
 }
@@ -12,3 +12,23 @@ output "resource_group_name" {
   description = "The name of the resource group"
   value       = azurerm_resource_group.example.name
 }
+
+output "virtual_network_name" {
+  description = "The name of the virtual network"
+  value       = azurerm_virtual_network.example.name
+}
+
+output "network_security_group_id" {
+  description = "The ID of the associated network security group"
+  value       = azurerm_network_security_group.secure.id
+}
+
+output "route_table_id" {
+  description = "The ID of the associated route table"
+  value       = azurerm_route_table.secure.id
+}
+
+output "service_endpoint_policy_id" {
+  description = "The ID of the associated service endpoint storage policy"
+  value       = azurerm_subnet_service_endpoint_storage_policy.secure.id
+}
@@ -1,14 +1,14 @@
 package test
 
 import (
-	// "context" // TODO: Uncomment when Azure SDK is added  
 	"fmt"
 	"testing"
-	// "time" // TODO: Uncomment when Azure SDK is added
 
-	// Azure SDK imports - add specific ones for your resource type
-	// Example: "github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/storage/armstorage"
-	
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v5"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/compliance"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/infrapool"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/assert"
@@ -27,14 +27,14 @@ func TestSubnetFullIntegration(t *testing.T) {
 	// Setup stages
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	// Deploy infrastructure
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	// Validate all components
@@ -66,36 +66,50 @@ func validateCoreFeatures(t *testing.T, testFolder string) {
 	// Validate core properties
 	assert.NotEmpty(t, resourceName, "Resource name should not be empty")
 	assert.NotEmpty(t, resourceGroupName, "Resource group name should not be empty")
-	
-	// TODO: Add subnet specific core validations using the SDK when helper is implemented
-	// Example:
-	// helper := NewSubnetHelper(t)
-	// resource := helper.GetSubnetProperties(t, resourceName, resourceGroupName)
-	// assert.Equal(t, expectedSKU, *resource.SKU.Name)
+
+	subnet := getCompleteSubnet(t, terraformOptions)
+	ValidateSubnetAddressPrefixes(t, subnet, []string{"10.0.1.0/24"})
+	ValidateSubnetDelegations(t, subnet, map[string]ExpectedDelegation{
+		"container_instances": {
+			ServiceName: "Microsoft.ContainerInstance/containerGroups",
+			Actions: []string{
+				"Microsoft.Network/virtualNetworks/subnets/join/action",
+				"Microsoft.Network/virtualNetworks/subnets/prepareNetworkPolicies/action",
+			},
+		},
+	})
 }
 
 // validateSecurityFeatures validates security configurations using SDK
 func validateSecurityFeatures(t *testing.T, testFolder string) {
-	// TODO: Implement when Azure SDK is added
-	// terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-	// helper := NewSubnetHelper(t)
-	// resourceName := terraform.Output(t, terraformOptions, "subnet_name")
-	// resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
-	// resource := helper.GetSubnetProperties(t, resourceName, resourceGroupName)
-	
-	// Security validations would go here
+	terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+
+	subnet := getCompleteSubnet(t, terraformOptions)
+	ValidateSubnetAssociations(t, subnet,
+		terraform.Output(t, terraformOptions, "network_security_group_id"),
+		terraform.Output(t, terraformOptions, "route_table_id"))
+	ValidateSubnetNetworkPolicies(t, subnet,
+		armnetwork.VirtualNetworkPrivateEndpointNetworkPoliciesEnabled,
+		armnetwork.VirtualNetworkPrivateLinkServiceNetworkPoliciesEnabled)
 }
 
 // validateNetworkFeatures validates network configurations using SDK
 func validateNetworkFeatures(t *testing.T, testFolder string) {
-	// TODO: Implement when Azure SDK is added
-	// terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-	// helper := NewSubnetHelper(t)
-	// resourceName := terraform.Output(t, terraformOptions, "subnet_name")
-	// resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
-	// resource := helper.GetSubnetProperties(t, resourceName, resourceGroupName)
-	
-	// Network validations would go here
+	terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+
+	subnet := getCompleteSubnet(t, terraformOptions)
+	ValidateSubnetServiceEndpoints(t, subnet, []string{"Microsoft.Storage", "Microsoft.KeyVault", "Microsoft.Sql"})
+	ValidateSubnetServiceEndpointPolicies(t, subnet, []string{terraform.Output(t, terraformOptions, "service_endpoint_policy_id")})
+	ValidateSubnetDefaultOutboundAccess(t, subnet, true)
+}
+
+// getCompleteSubnet reads the subnet deployed by the complete fixture
+func getCompleteSubnet(t *testing.T, terraformOptions *terraform.Options) armnetwork.Subnet {
+	helper := NewSubnetHelper(t)
+	return helper.GetSubnetProperties(t,
+		terraform.Output(t, terraformOptions, "resource_group_name"),
+		terraform.Output(t, terraformOptions, "virtual_network_name"),
+		terraform.Output(t, terraformOptions, "subnet_name"))
 }
 
 // validateOperationalFeatures validates operational features like monitoring
@@ -113,10 +127,6 @@ func validateOperationalFeatures(t *testing.T, testFolder string) {
 	
 	// Validate diagnostic settings format
 	assert.Contains(t, resourceID, "/providers/Microsoft.")
-	
-	// TODO: Add subnet specific operational validations when helper is implemented
-	// helper := NewSubnetHelper(t)
-	// helper.ValidateDiagnosticSettings(t, resourceID)
 }
 
 // TestSubnetWithNetworkRules tests network access controls
@@ -131,14 +141,14 @@ func TestSubnetWithNetworkRules(t *testing.T) {
 	// Setup stages
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	// Deploy infrastructure
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	// Validate network configuration
@@ -152,10 +162,14 @@ func TestSubnetWithNetworkRules(t *testing.T) {
 		// Validate outputs
 		assert.NotEmpty(t, resourceID)
 		assert.NotEmpty(t, resourceName)
-		
-		// TODO: Add SDK-based network rule validations when helper is implemented
-		// helper := NewSubnetHelper(t)
-		// Validate IP rules, subnet rules, etc.
+
+		helper := NewSubnetHelper(t)
+		subnet := helper.GetSubnetProperties(t,
+			terraform.Output(t, terraformOptions, "resource_group_name"),
+			terraform.Output(t, terraformOptions, "virtual_network_name"),
+			resourceName)
+		ValidateSubnetAssociations(t, subnet, terraform.Output(t, terraformOptions, "network_security_group_id"), "")
+		ValidateSubnetServiceEndpoints(t, subnet, []string{"Microsoft.Storage", "Microsoft.KeyVault"})
 	})
 }
 
@@ -171,14 +185,14 @@ func TestSubnetPrivateEndpointIntegration(t *testing.T) {
 	// Setup stages
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	// Deploy infrastructure
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	// Validate private endpoint
@@ -192,10 +206,15 @@ func TestSubnetPrivateEndpointIntegration(t *testing.T) {
 		// Validate outputs
 		assert.NotEmpty(t, resourceID)
 		assert.NotEmpty(t, privateEndpointID)
-		
-		// TODO: Add SDK-based private endpoint validations when helper is implemented
-		// helper := NewSubnetHelper(t)
-		// Validate private endpoint configuration
+
+		helper := NewSubnetHelper(t)
+		subnet := helper.GetSubnetProperties(t,
+			terraform.Output(t, terraformOptions, "resource_group_name"),
+			terraform.Output(t, terraformOptions, "virtual_network_name"),
+			terraform.Output(t, terraformOptions, "subnet_name"))
+		ValidateSubnetNetworkPolicies(t, subnet,
+			armnetwork.VirtualNetworkPrivateEndpointNetworkPoliciesDisabled,
+			armnetwork.VirtualNetworkPrivateLinkServiceNetworkPoliciesEnabled)
 	})
 }
 
@@ -211,14 +230,14 @@ func TestSubnetSecurityConfiguration(t *testing.T) {
 	// Setup stages
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	// Deploy infrastructure
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	// Validate security configuration
@@ -233,9 +252,15 @@ func TestSubnetSecurityConfiguration(t *testing.T) {
 		assert.NotEmpty(t, resourceID)
 		assert.NotEmpty(t, resourceName)
 		
-		// TODO: Add SDK-based security validations when helper is implemented
-		// helper := NewSubnetHelper(t)
-		// Validate encryption, TLS, access controls, etc.
+		helper := NewSubnetHelper(t)
+		subnet := helper.GetSubnetProperties(t,
+			terraform.Output(t, terraformOptions, "resource_group_name"),
+			terraform.Output(t, terraformOptions, "virtual_network_name"),
+			resourceName)
+		ValidateSubnetAssociations(t, subnet,
+			terraform.Output(t, terraformOptions, "network_security_group_id"),
+			terraform.Output(t, terraformOptions, "route_table_id"))
+		ValidateSubnetServiceEndpointPolicies(t, subnet, []string{terraform.Output(t, terraformOptions, "service_endpoint_policy_id")})
 	})
 }
 
@@ -304,8 +329,9 @@ func TestSubnetLifecycle(t *testing.T) {
 	// Initial deployment
 	test_structure.RunTestStage(t, "deploy_initial", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
+		useNetworkBaseline(t, infrapool.NetworkBaseline.Acquire(t), terraformOptions)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	// Update configuration (simulate changes)
@@ -313,10 +339,10 @@ func TestSubnetLifecycle(t *testing.T) {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
 		
 		// Modify some variable to trigger an update
-		terraformOptions.Vars["tag_environment"] = "Updated"
+		terraformOptions.Vars["service_endpoints"] = []string{"Microsoft.Storage"}
 		
 		// Apply the changes
-		terraform.Apply(t, terraformOptions)
+		tfretry.ApplyWithRetry(t, terraformOptions)
 		
 		// Validate the update was successful
 		resourceID := terraform.Output(t, terraformOptions, "subnet_id")
@@ -326,7 +352,7 @@ func TestSubnetLifecycle(t *testing.T) {
 	// Clean up
 	test_structure.RunTestStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 }
 
@@ -340,9 +366,13 @@ func TestSubnetCompliance(t *testing.T) {
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/complete")
 	
 	// Deploy infrastructure
-	defer terraform.Destroy(t, getTerraformOptions(t, testFolder))
+	defer destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDestroyVerifier(t))
 	terraformOptions := getTerraformOptions(t, testFolder)
-	terraform.InitAndApply(t, terraformOptions)
+
+	// Rules from security-policies/compliance are checked on the plan and on the applied state
+	compliance.AssertPlan(t, terraformOptions, "azurerm_subnet")
+	tfretry.InitAndApplyWithRetry(t, terraformOptions)
+	compliance.AssertState(t, terraformOptions, "azurerm_subnet")
 	
 	// Get outputs for compliance validation
 	resourceID := terraform.Output(t, terraformOptions, "subnet_id")
@@ -355,9 +385,12 @@ func TestSubnetCompliance(t *testing.T) {
 	assert.Contains(t, resourceID, "/subscriptions/", "Resource ID should be properly formatted")
 	assert.Contains(t, resourceID, "/resourceGroups/", "Resource ID should contain resource group")
 	
-	// TODO: Add more compliance checks when helper is implemented
-	// helper := NewSubnetHelper(t)
-	// Validate tags, encryption, network policies, etc.
+	// Subnets must not bypass private endpoint network policies unless explicitly intended
+	subnet := getCompleteSubnet(t, terraformOptions)
+	ValidateSubnetNetworkPolicies(t, subnet,
+		armnetwork.VirtualNetworkPrivateEndpointNetworkPoliciesEnabled,
+		armnetwork.VirtualNetworkPrivateLinkServiceNetworkPoliciesEnabled)
+	require.NotNil(t, subnet.Properties.NetworkSecurityGroup, "Subnet should be protected by a network security group")
 }
 
 // BenchmarkSubnetIntegrationCreation benchmarks resource creation performance
@@ -369,9 +402,10 @@ func BenchmarkSubnetIntegrationCreation(b *testing.B) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(b, "..", "tests/fixtures/basic")
 	terraformOptions := getTerraformOptions(b, testFolder)
+	useNetworkBaseline(b, infrapool.NetworkBaseline.Acquire(b), terraformOptions)
 
 	// Cleanup after benchmark
-	defer terraform.Destroy(b, terraformOptions)
+	defer tfretry.DestroyWithRetry(b, terraformOptions)
 
 	// Run the benchmark
 	b.ResetTimer()
@@ -379,7 +413,7 @@ func BenchmarkSubnetIntegrationCreation(b *testing.B) {
 		// Generate unique name for each iteration
 		terraformOptions.Vars["random_suffix"] = fmt.Sprintf("%d%s", i, terraformOptions.Vars["random_suffix"].(string)[:5])
 		
-		terraform.InitAndApply(b, terraformOptions)
-		terraform.Destroy(b, terraformOptions)
+		tfretry.InitAndApplyWithRetry(b, terraformOptions)
+		tfretry.DestroyWithRetry(b, terraformOptions)
 	}
 }
@@ -2,10 +2,16 @@ package test
 
 import (
 	"fmt"
+	"os"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v5"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/importtest"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/infrapool"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
@@ -13,6 +19,10 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+func TestMain(m *testing.M) {
+	os.Exit(infrapool.RunWithInfraPools(m, infrapool.NetworkBaseline))
+}
+
 // Test basic subnet creation
 func TestBasicSubnet(t *testing.T) {
 	t.Parallel()
@@ -20,14 +30,15 @@ func TestBasicSubnet(t *testing.T) {
 	// Create a folder for this test
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/basic")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	// Deploy the infrastructure
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
+		useNetworkBaseline(t, infrapool.NetworkBaseline.Acquire(t), terraformOptions)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	// Validate the infrastructure
@@ -46,6 +57,11 @@ func TestBasicSubnet(t *testing.T) {
 
 		// Add subnet specific validations here
 	})
+
+	// Adopting the deployed module resources into an empty state must plan no changes
+	test_structure.RunTestStage(t, "import", func() {
+		importtest.RequireStateImportRoundTrip(t, test_structure.LoadTerraformOptions(t, testFolder))
+	})
 }
 
 // Test complete subnet with all features
@@ -54,13 +70,13 @@ func TestCompleteSubnet(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/complete")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -85,13 +101,13 @@ func TestSubnetSecurity(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/secure")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -103,9 +119,22 @@ func TestSubnetSecurity(t *testing.T) {
 		// Validate security settings
 		assert.NotEmpty(t, resourceID)
 		assert.NotEmpty(t, resourceName)
-		
-		// Add security-specific validations
-		// Validate encryption, TLS settings, access controls, etc.
+
+		resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
+		virtualNetworkName := terraform.Output(t, terraformOptions, "virtual_network_name")
+
+		helper := NewSubnetHelper(t)
+		subnet := helper.GetSubnetProperties(t, resourceGroupName, virtualNetworkName, resourceName)
+		ValidateSubnet(t, subnet, ExpectedSubnet{
+			AddressPrefixes:                   []string{"10.0.1.0/24"},
+			ServiceEndpoints:                  []string{"Microsoft.Storage", "Microsoft.KeyVault", "Microsoft.AzureActiveDirectory"},
+			ServiceEndpointPolicyIDs:          []string{terraform.Output(t, terraformOptions, "service_endpoint_policy_id")},
+			NetworkSecurityGroupID:            terraform.Output(t, terraformOptions, "network_security_group_id"),
+			RouteTableID:                      terraform.Output(t, terraformOptions, "route_table_id"),
+			PrivateEndpointNetworkPolicies:    armnetwork.VirtualNetworkPrivateEndpointNetworkPoliciesEnabled,
+			PrivateLinkServiceNetworkPolicies: armnetwork.VirtualNetworkPrivateLinkServiceNetworkPoliciesEnabled,
+			DefaultOutboundAccess:             true,
+		})
 	})
 }
 
@@ -115,25 +144,43 @@ func TestSubnetNetworkRules(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/network")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
 
 		resourceID := terraform.Output(t, terraformOptions, "subnet_id")
-		
+
 		// Validate network rules
 		assert.NotEmpty(t, resourceID)
-		
-		// Add network-specific validations
-		// Validate IP rules, subnet restrictions, private endpoints, etc.
+
+		resourceName := terraform.Output(t, terraformOptions, "subnet_name")
+		resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
+		virtualNetworkName := terraform.Output(t, terraformOptions, "virtual_network_name")
+
+		helper := NewSubnetHelper(t)
+		subnet := helper.GetSubnetProperties(t, resourceGroupName, virtualNetworkName, resourceName)
+		ValidateSubnet(t, subnet, ExpectedSubnet{
+			AddressPrefixes: []string{"10.0.1.0/24"},
+			Delegations: map[string]ExpectedDelegation{
+				"aci": {
+					ServiceName: "Microsoft.ContainerInstance/containerGroups",
+					Actions:     []string{"Microsoft.Network/virtualNetworks/subnets/action"},
+				},
+			},
+			ServiceEndpoints:                  []string{"Microsoft.Storage", "Microsoft.KeyVault"},
+			NetworkSecurityGroupID:            terraform.Output(t, terraformOptions, "network_security_group_id"),
+			PrivateEndpointNetworkPolicies:    armnetwork.VirtualNetworkPrivateEndpointNetworkPoliciesEnabled,
+			PrivateLinkServiceNetworkPolicies: armnetwork.VirtualNetworkPrivateLinkServiceNetworkPoliciesEnabled,
+			DefaultOutboundAccess:             true,
+		})
 	})
 }
 
@@ -143,13 +190,13 @@ func TestSubnetPrivateEndpoint(t *testing.T) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "..", "tests/fixtures/private_endpoint")
 	defer test_structure.RunTestStage(t, "cleanup", func() {
-		terraform.Destroy(t, getTerraformOptions(t, testFolder))
+		destroyverify.DestroyAndVerify(t, getTerraformOptions(t, testFolder), destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	test_structure.RunTestStage(t, "validate", func() {
@@ -171,7 +218,21 @@ func TestSubnetPrivateEndpoint(t *testing.T) {
 		}
 		
 		// Validate subnet is configured for private endpoints
-		// Add additional private endpoint validations
+		resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
+		virtualNetworkName := terraform.Output(t, terraformOptions, "virtual_network_name")
+
+		helper := NewSubnetHelper(t)
+		subnet := helper.GetSubnetProperties(t, resourceGroupName, virtualNetworkName, subnetName)
+		ValidateSubnet(t, subnet, ExpectedSubnet{
+			AddressPrefixes:                   []string{"10.0.1.0/24"},
+			PrivateEndpointNetworkPolicies:    armnetwork.VirtualNetworkPrivateEndpointNetworkPoliciesDisabled,
+			PrivateLinkServiceNetworkPolicies: armnetwork.VirtualNetworkPrivateLinkServiceNetworkPoliciesEnabled,
+			DefaultOutboundAccess:             true,
+		})
+
+		require.NotEmpty(t, subnet.Properties.PrivateEndpoints, "Subnet should host the private endpoint")
+		require.True(t, strings.EqualFold(privateEndpointID, derefString(subnet.Properties.PrivateEndpoints[0].ID)),
+			"Subnet private endpoint should be %s", privateEndpointID)
 	})
 }
 
@@ -230,9 +291,10 @@ func BenchmarkSubnetCreation(b *testing.B) {
 
 	testFolder := test_structure.CopyTerraformFolderToTemp(b, "..", "tests/fixtures/basic")
 	terraformOptions := getTerraformOptions(b, testFolder)
+	useNetworkBaseline(b, infrapool.NetworkBaseline.Acquire(b), terraformOptions)
 
 	// Cleanup after benchmark
-	defer terraform.Destroy(b, terraformOptions)
+	defer tfretry.DestroyWithRetry(b, terraformOptions)
 
 	// Run the benchmark
 	b.ResetTimer()
@@ -240,11 +302,20 @@ func BenchmarkSubnetCreation(b *testing.B) {
 		// Generate unique name for each iteration
 		terraformOptions.Vars["random_suffix"] = fmt.Sprintf("%d%s", i, terraformOptions.Vars["random_suffix"].(string)[:5])
 		
-		terraform.InitAndApply(b, terraformOptions)
-		terraform.Destroy(b, terraformOptions)
+		tfretry.InitAndApplyWithRetry(b, terraformOptions)
+		tfretry.DestroyWithRetry(b, terraformOptions)
 	}
 }
 
+// useNetworkBaseline points the basic fixture at a lease of the shared network baseline
+func useNetworkBaseline(t testing.TB, lease *infrapool.Lease, terraformOptions *terraform.Options) {
+	t.Helper()
+
+	err := infrapool.UseNetworkBaseline(lease, terraformOptions,
+		infrapool.BaselineResourceGroupVar, infrapool.BaselineVirtualNetworkVar, infrapool.BaselineSubnetAddressPrefixVar)
+	require.NoError(t, err, "Failed to use the network baseline")
+}
+
 // Helper function to get terraform options
 func getTerraformOptions(t testing.TB, terraformDir string) *terraform.Options {
 	// Generate a unique ID for resources
@@ -260,13 +331,8 @@ func getTerraformOptions(t testing.TB, terraformDir string) *terraform.Options {
 		},
 		NoColor: true,
 		// Retry configuration
-		RetryableTerraformErrors: map[string]string{
-			".*timeout.*":                    "Timeout error - retrying",
-			".*ResourceGroupNotFound.*":      "Resource group not found - retrying",
-			".*AlreadyExists.*":              "Resource already exists - retrying",
-			".*TooManyRequests.*":            "Too many requests - retrying",
-		},
-		MaxRetries:         3,
-		TimeBetweenRetries: 10 * time.Second,
+		RetryableTerraformErrors: tfretry.ClassifiedRetryableErrors(),
+		MaxRetries:               3,
+		TimeBetweenRetries:       10 * time.Second,
 	}
 }
@@ -4,12 +4,14 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v5"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/stretchr/testify/require"
 )
@@ -87,14 +89,204 @@ func WaitForResourceDeletion(ctx context.Context, checkFunc func() (bool, error)
 	}
 }
 
-// GenerateResourceName generates a unique resource name for testing
-func GenerateResourceName(prefix string, uniqueID string) string {
-	// Ensure the name meets Azure naming requirements
-	name := fmt.Sprintf("%s%s", prefix, uniqueID)
-	// Remove any invalid characters and ensure length limits
-	name = strings.ReplaceAll(name, "-", "")
-	if len(name) > 24 {
-		name = name[:24]
+// SubnetHelper provides helper methods for subnet testing
+type SubnetHelper struct {
+	subscriptionID string
+	credential     azcore.TokenCredential
+	client         *armnetwork.SubnetsClient
+}
+
+// NewSubnetHelper creates a new helper instance
+func NewSubnetHelper(t *testing.T) *SubnetHelper {
+	subscriptionID := os.Getenv("ARM_SUBSCRIPTION_ID")
+	require.NotEmpty(t, subscriptionID, "ARM_SUBSCRIPTION_ID environment variable must be set")
+
+	credential := GetAzureCredential(t)
+
+	client, err := armnetwork.NewSubnetsClient(subscriptionID, credential, nil)
+	require.NoError(t, err, "Failed to create Subnets client")
+
+	return &SubnetHelper{
+		subscriptionID: subscriptionID,
+		credential:     credential,
+		client:         client,
+	}
+}
+
+// GetSubnetProperties retrieves subnet properties
+func (h *SubnetHelper) GetSubnetProperties(t *testing.T, resourceGroupName, virtualNetworkName, subnetName string) armnetwork.Subnet {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	resp, err := h.client.Get(ctx, resourceGroupName, virtualNetworkName, subnetName, nil)
+	require.NoError(t, err, "Failed to get subnet %s", subnetName)
+
+	return resp.Subnet
+}
+
+// ExpectedDelegation describes a subnet delegation and the actions granted to the service
+type ExpectedDelegation struct {
+	ServiceName string
+	Actions     []string
+}
+
+// ExpectedSubnet describes the subnet configuration a fixture should produce.
+// Empty ID fields mean no association is expected.
+type ExpectedSubnet struct {
+	AddressPrefixes                   []string
+	Delegations                       map[string]ExpectedDelegation
+	ServiceEndpoints                  []string
+	ServiceEndpointPolicyIDs          []string
+	NetworkSecurityGroupID            string
+	RouteTableID                      string
+	PrivateEndpointNetworkPolicies    armnetwork.VirtualNetworkPrivateEndpointNetworkPolicies
+	PrivateLinkServiceNetworkPolicies armnetwork.VirtualNetworkPrivateLinkServiceNetworkPolicies
+	DefaultOutboundAccess             bool
+}
+
+// ValidateSubnet validates every aspect of the subnet against the expected configuration
+func ValidateSubnet(t *testing.T, subnet armnetwork.Subnet, expected ExpectedSubnet) {
+	require.NotNil(t, subnet.Properties, "Subnet properties should not be nil")
+	require.NotNil(t, subnet.Properties.ProvisioningState, "Subnet provisioning state should be set")
+	require.Equal(t, armnetwork.ProvisioningStateSucceeded, *subnet.Properties.ProvisioningState, "Subnet should be successfully provisioned")
+
+	ValidateSubnetAddressPrefixes(t, subnet, expected.AddressPrefixes)
+	ValidateSubnetDelegations(t, subnet, expected.Delegations)
+	ValidateSubnetServiceEndpoints(t, subnet, expected.ServiceEndpoints)
+	ValidateSubnetServiceEndpointPolicies(t, subnet, expected.ServiceEndpointPolicyIDs)
+	ValidateSubnetAssociations(t, subnet, expected.NetworkSecurityGroupID, expected.RouteTableID)
+	ValidateSubnetNetworkPolicies(t, subnet, expected.PrivateEndpointNetworkPolicies, expected.PrivateLinkServiceNetworkPolicies)
+	ValidateSubnetDefaultOutboundAccess(t, subnet, expected.DefaultOutboundAccess)
+}
+
+// ValidateSubnetAddressPrefixes validates the exact set of subnet address prefixes
+func ValidateSubnetAddressPrefixes(t *testing.T, subnet armnetwork.Subnet, expected []string) {
+	require.ElementsMatch(t, expected, subnetAddressPrefixes(subnet), "Subnet address prefixes mismatch")
+}
+
+// ValidateSubnetDelegations validates the exact set of delegations, their services and actions
+func ValidateSubnetDelegations(t *testing.T, subnet armnetwork.Subnet, expected map[string]ExpectedDelegation) {
+	actual := make(map[string]ExpectedDelegation, len(subnet.Properties.Delegations))
+	for _, delegation := range subnet.Properties.Delegations {
+		require.NotNil(t, delegation.Name, "Delegation name should be set")
+		require.NotNil(t, delegation.Properties, "Delegation %s properties should not be nil", *delegation.Name)
+
+		actual[*delegation.Name] = ExpectedDelegation{
+			ServiceName: derefString(delegation.Properties.ServiceName),
+			Actions:     derefStrings(delegation.Properties.Actions),
+		}
+	}
+
+	require.Len(t, actual, len(expected), "Unexpected number of subnet delegations")
+	for name, want := range expected {
+		got, ok := actual[name]
+		require.True(t, ok, "Subnet delegation %s not found", name)
+		require.Equal(t, want.ServiceName, got.ServiceName, "Delegation %s service mismatch", name)
+		require.ElementsMatch(t, want.Actions, got.Actions, "Delegation %s actions mismatch", name)
+	}
+}
+
+// ValidateSubnetServiceEndpoints validates the exact set of service endpoints and that each is provisioned
+func ValidateSubnetServiceEndpoints(t *testing.T, subnet armnetwork.Subnet, expected []string) {
+	actual := make([]string, 0, len(subnet.Properties.ServiceEndpoints))
+	for _, endpoint := range subnet.Properties.ServiceEndpoints {
+		service := derefString(endpoint.Service)
+		actual = append(actual, service)
+		if endpoint.ProvisioningState != nil {
+			require.Equal(t, armnetwork.ProvisioningStateSucceeded, *endpoint.ProvisioningState,
+				"Service endpoint %s should be successfully provisioned", service)
+		}
 	}
-	return strings.ToLower(name)
-}
\ No newline at end of file
+	require.ElementsMatch(t, expected, actual, "Subnet service endpoints mismatch")
+}
+
+// ValidateSubnetServiceEndpointPolicies validates the exact set of service endpoint policies by resource ID
+func ValidateSubnetServiceEndpointPolicies(t *testing.T, subnet armnetwork.Subnet, expectedIDs []string) {
+	actual := make([]string, 0, len(subnet.Properties.ServiceEndpointPolicies))
+	for _, policy := range subnet.Properties.ServiceEndpointPolicies {
+		actual = append(actual, strings.ToLower(derefString(policy.ID)))
+	}
+	require.ElementsMatch(t, lowerAll(expectedIDs), actual, "Subnet service endpoint policies mismatch")
+}
+
+// ValidateSubnetAssociations validates the NSG and route table associations; empty IDs expect no association
+func ValidateSubnetAssociations(t *testing.T, subnet armnetwork.Subnet, networkSecurityGroupID, routeTableID string) {
+	if networkSecurityGroupID == "" {
+		require.Nil(t, subnet.Properties.NetworkSecurityGroup, "Subnet should not have a network security group")
+	} else {
+		require.NotNil(t, subnet.Properties.NetworkSecurityGroup, "Subnet should have a network security group")
+		require.True(t, strings.EqualFold(networkSecurityGroupID, derefString(subnet.Properties.NetworkSecurityGroup.ID)),
+			"Subnet NSG should be %s, got %s", networkSecurityGroupID, derefString(subnet.Properties.NetworkSecurityGroup.ID))
+	}
+
+	if routeTableID == "" {
+		require.Nil(t, subnet.Properties.RouteTable, "Subnet should not have a route table")
+	} else {
+		require.NotNil(t, subnet.Properties.RouteTable, "Subnet should have a route table")
+		require.True(t, strings.EqualFold(routeTableID, derefString(subnet.Properties.RouteTable.ID)),
+			"Subnet route table should be %s, got %s", routeTableID, derefString(subnet.Properties.RouteTable.ID))
+	}
+}
+
+// ValidateSubnetNetworkPolicies validates private endpoint and private link service network policy flags
+func ValidateSubnetNetworkPolicies(t *testing.T, subnet armnetwork.Subnet, privateEndpoint armnetwork.VirtualNetworkPrivateEndpointNetworkPolicies, privateLinkService armnetwork.VirtualNetworkPrivateLinkServiceNetworkPolicies) {
+	require.NotNil(t, subnet.Properties.PrivateEndpointNetworkPolicies, "Private endpoint network policies should be set")
+	require.Equal(t, privateEndpoint, *subnet.Properties.PrivateEndpointNetworkPolicies, "Private endpoint network policies mismatch")
+
+	require.NotNil(t, subnet.Properties.PrivateLinkServiceNetworkPolicies, "Private link service network policies should be set")
+	require.Equal(t, privateLinkService, *subnet.Properties.PrivateLinkServiceNetworkPolicies, "Private link service network policies mismatch")
+}
+
+// ValidateSubnetDefaultOutboundAccess validates default outbound access.
+// Azure omits the property when it has not been set explicitly, which means enabled.
+func ValidateSubnetDefaultOutboundAccess(t *testing.T, subnet armnetwork.Subnet, expected bool) {
+	actual := true
+	if subnet.Properties.DefaultOutboundAccess != nil {
+		actual = *subnet.Properties.DefaultOutboundAccess
+	}
+	require.Equal(t, expected, actual, "Subnet default outbound access mismatch")
+}
+
+// subnetAddressPrefixes merges the single and multi-prefix properties returned by the API
+func subnetAddressPrefixes(subnet armnetwork.Subnet) []string {
+	prefixes := derefStrings(subnet.Properties.AddressPrefixes)
+	if subnet.Properties.AddressPrefix != nil {
+		found := false
+		for _, prefix := range prefixes {
+			if prefix == *subnet.Properties.AddressPrefix {
+				found = true
+				break
+			}
+		}
+		if !found {
+			prefixes = append(prefixes, *subnet.Properties.AddressPrefix)
+		}
+	}
+	sort.Strings(prefixes)
+	return prefixes
+}
+
+func lowerAll(values []string) []string {
+	lowered := make([]string, 0, len(values))
+	for _, value := range values {
+		lowered = append(lowered, strings.ToLower(value))
+	}
+	return lowered
+}
+
+func derefString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func derefStrings(values []*string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if value != nil {
+			result = append(result, *value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,37 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/compliance"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestVirtualNetworkPlanCompliance evaluates the checkov-derived rules against a saved plan offline
+func TestVirtualNetworkPlanCompliance(t *testing.T) {
+	t.Parallel()
+
+	showJSON, err := os.ReadFile(filepath.Join("testdata", "compliance", "ddos_plan.json"))
+	require.NoError(t, err)
+	resources, err := compliance.ParseResourcesE(showJSON)
+	require.NoError(t, err)
+
+	statuses := map[string]string{}
+	for _, finding := range compliance.Evaluate(compliance.LoadRules(t, "azurerm_virtual_network"), resources) {
+		statuses[finding.Address+" "+finding.Rule.Name] = finding.Status
+	}
+	assert.Equal(t, map[string]string{
+		"module.test.azurerm_virtual_network.virtual_network VirtualNetworkMustHaveTags":              compliance.Fail,
+		"module.test.azurerm_virtual_network.virtual_network VirtualNetworkDDoSProtection":            compliance.NotApplicable,
+		"module.prod.azurerm_virtual_network.virtual_network VirtualNetworkMustHaveTags":              compliance.Pass,
+		"module.prod.azurerm_virtual_network.virtual_network VirtualNetworkDDoSProtection":            compliance.Fail,
+		"module.prod_protected.azurerm_virtual_network.virtual_network VirtualNetworkMustHaveTags":    compliance.Pass,
+		"module.prod_protected.azurerm_virtual_network.virtual_network VirtualNetworkDDoSProtection":  compliance.Pass,
+		"module.prod_pending_plan.azurerm_virtual_network.virtual_network VirtualNetworkMustHaveTags": compliance.Pass,
+		// The plan ID of a protection plan created in the same run is known only after apply
+		"module.prod_pending_plan.azurerm_virtual_network.virtual_network VirtualNetworkDDoSProtection": compliance.Unknown,
+	}, statuses)
+}
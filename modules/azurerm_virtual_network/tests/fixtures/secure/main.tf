@@ -120,6 +120,8 @@ module "virtual_network" {
     Environment = "Test"
     Module      = "azurerm_virtual_network"
     Test        = "Secure"
+    Owner       = "network"
+    CostCenter  = "1234"
   }
 
 }
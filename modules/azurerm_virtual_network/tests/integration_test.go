@@ -3,6 +3,10 @@ package test
 import (
 	"testing"
 
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/cassette"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/compliance"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/stretchr/testify/assert"
@@ -19,16 +23,16 @@ func TestVirtualNetworkFullIntegration(t *testing.T) {
 	testFolder := test_structure.CopyTerraformFolderToTemp(t, "../..", "azurerm_virtual_network/tests/fixtures/complete")
 	
 	// Setup stages
-	defer test_structure.RunTestStage(t, "cleanup", func() {
+	defer cassette.RunLiveStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	// Deploy infrastructure
-	test_structure.RunTestStage(t, "deploy", func() {
+	cassette.RunLiveStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	// Validate all components
@@ -47,12 +51,12 @@ func TestVirtualNetworkFullIntegration(t *testing.T) {
 
 // validateCoreFeatures validates core VNet features
 func validateCoreFeatures(t *testing.T, testFolder string) {
-	terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+	terraformOptions := cassette.LoadTerraformOptions(t, testFolder)
 	
 	// Core outputs
-	vnetID := terraform.Output(t, terraformOptions, "virtual_network_id")
-	vnetName := terraform.Output(t, terraformOptions, "virtual_network_name")
-	addressSpace := terraform.OutputList(t, terraformOptions, "virtual_network_address_space")
+	vnetID := cassette.Output(t, terraformOptions, "virtual_network_id")
+	vnetName := cassette.Output(t, terraformOptions, "virtual_network_name")
+	addressSpace := cassette.OutputList(t, terraformOptions, "virtual_network_address_space")
 	
 	// Assertions
 	assert.NotEmpty(t, vnetID)
@@ -62,12 +66,12 @@ func validateCoreFeatures(t *testing.T, testFolder string) {
 
 // validateSecurityFeatures validates security-related features
 func validateSecurityFeatures(t *testing.T, testFolder string) {
-	terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+	terraformOptions := cassette.LoadTerraformOptions(t, testFolder)
 	helper := NewVirtualNetworkHelper(t)
 	
 	// Get resource details from outputs
-	vnetName := terraform.Output(t, terraformOptions, "virtual_network_name")
-	resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
+	vnetName := cassette.Output(t, terraformOptions, "virtual_network_name")
+	resourceGroupName := cassette.Output(t, terraformOptions, "resource_group_name")
 	
 	// Get VNet properties from Azure
 	vnet := helper.GetVirtualNetworkProperties(t, vnetName, resourceGroupName)
@@ -84,12 +88,12 @@ func validateSecurityFeatures(t *testing.T, testFolder string) {
 
 // validateNetworkFeatures validates network-related features
 func validateNetworkFeatures(t *testing.T, testFolder string) {
-	terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+	terraformOptions := cassette.LoadTerraformOptions(t, testFolder)
 	helper := NewVirtualNetworkHelper(t)
 	
 	// Get resource details from outputs
-	vnetName := terraform.Output(t, terraformOptions, "virtual_network_name")
-	resourceGroupName := terraform.Output(t, terraformOptions, "resource_group_name")
+	vnetName := cassette.Output(t, terraformOptions, "virtual_network_name")
+	resourceGroupName := cassette.Output(t, terraformOptions, "resource_group_name")
 	
 	// Get VNet properties from Azure
 	vnet := helper.GetVirtualNetworkProperties(t, vnetName, resourceGroupName)
@@ -122,14 +126,14 @@ func TestVirtualNetworkWithPeering(t *testing.T) {
 	// Setup stages
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	// Deploy infrastructure
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	// Validate outputs
@@ -162,14 +166,14 @@ func TestVirtualNetworkPrivateEndpoint(t *testing.T) {
 	// Setup stages
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	// Deploy infrastructure
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	// Validate outputs
@@ -199,14 +203,14 @@ func TestVirtualNetworkDNS(t *testing.T) {
 	// Setup stages
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	// Deploy infrastructure
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	// Validate outputs
@@ -236,14 +240,14 @@ func TestVirtualNetworkFlowLogs(t *testing.T) {
 	// Setup stages
 	defer test_structure.RunTestStage(t, "cleanup", func() {
 		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
-		terraform.Destroy(t, terraformOptions)
+		destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
 	})
 
 	// Deploy infrastructure
 	test_structure.RunTestStage(t, "deploy", func() {
 		terraformOptions := getTerraformOptions(t, testFolder)
 		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
-		terraform.InitAndApply(t, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
 	})
 
 	// Validate outputs
@@ -315,9 +319,30 @@ func TestVirtualNetworkValidationRules(t *testing.T) {
 				require.Error(t, err)
 				assert.Contains(t, err.Error(), tc.errorContains)
 			} else {
-				defer terraform.Destroy(t, terraformOptions)
-				terraform.InitAndApply(t, terraformOptions)
+				defer destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
+				tfretry.InitAndApplyWithRetry(t, terraformOptions)
 			}
 		})
 	}
 }
+
+// TestVirtualNetworkCompliance evaluates the compliance policy rules against the secure fixture
+func TestVirtualNetworkCompliance(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+	t.Parallel()
+
+	testFolder := test_structure.CopyTerraformFolderToTemp(t, "../..", "azurerm_virtual_network/tests/fixtures/secure")
+	terraformOptions := getTerraformOptions(t, testFolder)
+
+	// Rules from security-policies/compliance are checked on the plan and on the applied state
+	compliance.AssertPlan(t, terraformOptions, "azurerm_virtual_network")
+
+	defer destroyverify.DestroyAndVerify(t, terraformOptions, destroyverify.NewAzureDestroyVerifier(t))
+
+	tfretry.InitAndApplyWithRetry(t, terraformOptions)
+	compliance.AssertState(t, terraformOptions, "azurerm_virtual_network")
+
+	assert.NotEmpty(t, terraform.Output(t, terraformOptions, "virtual_network_name"))
+}
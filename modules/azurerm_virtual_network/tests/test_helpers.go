@@ -11,6 +11,9 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v5"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/cassette"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/naming"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
@@ -90,46 +93,14 @@ func WaitForResourceDeletion(ctx context.Context, checkFunc func() (bool, error)
 	}
 }
 
-// GenerateResourceName generates a unique resource name for testing
-func GenerateResourceName(prefix string, uniqueID string) string {
-	// Ensure the name meets Azure naming requirements
-	name := fmt.Sprintf("%s-%s", prefix, uniqueID)
-	// Ensure length limits for Virtual Network (max 80 characters)
-	if len(name) > 80 {
-		name = name[:80]
-	}
-	return strings.ToLower(name)
-}
-
 // GenerateVirtualNetworkName generates a unique Virtual Network name for testing
-func GenerateVirtualNetworkName(uniqueID string) string {
-	return GenerateResourceName("vnet-test", uniqueID)
+func GenerateVirtualNetworkName(t testing.TB, uniqueID string) string {
+	return naming.GenerateResourceName(t, "azurerm_virtual_network", "vnet-test", uniqueID)
 }
 
 // ValidateVirtualNetworkName validates that a Virtual Network name meets Azure requirements
 func ValidateVirtualNetworkName(name string) error {
-	if len(name) < 2 || len(name) > 80 {
-		return fmt.Errorf("Virtual Network name must be between 2 and 80 characters")
-	}
-	
-	// Must start and end with alphanumeric character
-	if !((name[0] >= 'a' && name[0] <= 'z') || (name[0] >= 'A' && name[0] <= 'Z') || (name[0] >= '0' && name[0] <= '9')) {
-		return fmt.Errorf("Virtual Network name must start with alphanumeric character")
-	}
-	
-	lastChar := name[len(name)-1]
-	if !((lastChar >= 'a' && lastChar <= 'z') || (lastChar >= 'A' && lastChar <= 'Z') || (lastChar >= '0' && lastChar <= '9')) {
-		return fmt.Errorf("Virtual Network name must end with alphanumeric character")
-	}
-	
-	// Can contain alphanumeric characters, hyphens, periods, and underscores
-	for _, char := range name {
-		if !((char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9') || char == '-' || char == '.' || char == '_') {
-			return fmt.Errorf("Virtual Network name can only contain alphanumeric characters, hyphens, periods, and underscores")
-		}
-	}
-	
-	return nil
+	return naming.ValidateResourceName("azurerm_virtual_network", name)
 }
 
 // ValidateAddressSpace validates that address spaces are valid CIDR blocks
@@ -155,13 +126,16 @@ func ValidateAddressSpace(addressSpaces []string) error {
 
 // GetVirtualNetwork retrieves a virtual network from Azure
 func GetVirtualNetwork(t *testing.T, virtualNetworkName, resourceGroupName, subscriptionID string) *armnetwork.VirtualNetwork {
-	if subscriptionID == "" {
-		subscriptionID = os.Getenv("ARM_SUBSCRIPTION_ID")
-		require.NotEmpty(t, subscriptionID, "ARM_SUBSCRIPTION_ID must be set")
-	}
-
-	cred := GetAzureCredential(t)
-	client, err := armnetwork.NewVirtualNetworksClient(subscriptionID, cred, nil)
+	subscriptionID, cred, options := cassette.Session(t,
+		func() string {
+			if subscriptionID == "" {
+				subscriptionID = os.Getenv("ARM_SUBSCRIPTION_ID")
+				require.NotEmpty(t, subscriptionID, "ARM_SUBSCRIPTION_ID must be set")
+			}
+			return subscriptionID
+		},
+		func() azcore.TokenCredential { return GetAzureCredential(t) })
+	client, err := armnetwork.NewVirtualNetworksClient(subscriptionID, cred, options)
 	require.NoError(t, err, "Failed to create virtual networks client")
 
 	ctx := context.Background()
@@ -174,19 +148,18 @@ func GetVirtualNetwork(t *testing.T, virtualNetworkName, resourceGroupName, subs
 // getTerraformOptions returns terraform options for test runs
 func getTerraformOptions(t testing.TB, terraformDir string) *terraform.Options {
 	// Generate unique random suffix for resource naming
-	randomSuffix := generateRandomSuffix()
+	// Replay reuses the suffix recorded in the test's cassette
+	randomSuffix := cassette.UniqueID(t, generateRandomSuffix)
 	
 	return &terraform.Options{
 		TerraformDir: terraformDir,
 		Vars: map[string]interface{}{
 			"random_suffix": randomSuffix,
 		},
-		RetryableTerraformErrors: map[string]string{
-			".*": "Terraform operation failed",
-		},
-		MaxRetries:         3,
-		TimeBetweenRetries: 10 * time.Second,
-		NoColor:            true,
+		RetryableTerraformErrors: tfretry.ClassifiedRetryableErrors(),
+		MaxRetries:               3,
+		TimeBetweenRetries:       10 * time.Second,
+		NoColor:                  true,
 	}
 }
 
@@ -244,17 +217,19 @@ type VirtualNetworkHelper struct {
 	subscriptionID string
 }
 
-// NewVirtualNetworkHelper creates a new VirtualNetworkHelper instance
+// NewVirtualNetworkHelper creates a new VirtualNetworkHelper instance.
+// SDK traffic goes through the test's HTTP cassette when AZURE_CASSETTE_MODE is set.
 func NewVirtualNetworkHelper(t *testing.T) *VirtualNetworkHelper {
-	config := GetTestConfig(t)
-	cred := GetAzureCredential(t)
+	subscriptionID, cred, options := cassette.Session(t,
+		func() string { return GetTestConfig(t).SubscriptionID },
+		func() azcore.TokenCredential { return GetAzureCredential(t) })
 	
-	client, err := armnetwork.NewVirtualNetworksClient(config.SubscriptionID, cred, nil)
+	client, err := armnetwork.NewVirtualNetworksClient(subscriptionID, cred, options)
 	require.NoError(t, err, "Failed to create Virtual Networks client")
 	
 	return &VirtualNetworkHelper{
 		client:         client,
-		subscriptionID: config.SubscriptionID,
+		subscriptionID: subscriptionID,
 	}
 }
 
@@ -304,4 +279,4 @@ func (h *VirtualNetworkHelper) ValidateSubnets(t *testing.T, vnet armnetwork.Vir
 		assert.Equal(t, armnetwork.ProvisioningStateSucceeded, *subnet.Properties.ProvisioningState,
 			"Subnet %s should be successfully provisioned", *subnet.Name)
 	}
-}
\ No newline at end of file
+}
@@ -88,18 +88,6 @@ func WaitForResourceDeletion(ctx context.Context, checkFunc func() (bool, error)
 	}
 }
 
-// GenerateResourceName generates a unique resource name for testing
-func GenerateResourceName(prefix string, uniqueID string) string {
-	// Ensure the name meets Azure naming requirements
-	name := fmt.Sprintf("%s%s", prefix, uniqueID)
-	// Remove any invalid characters and ensure length limits
-	name = strings.ReplaceAll(name, "-", "")
-	if len(name) > 24 {
-		name = name[:24]
-	}
-	return strings.ToLower(name)
-}
-
 // PrepareTerraformWorkingDirs removes Terraform artifacts from copied fixtures.
 // This prevents stale module snapshots in .terraform from previous runs.
 func PrepareTerraformWorkingDirs(t testing.TB, terraformDir string) {
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTestJSON(t *testing.T) {
+	file, err := os.Open(filepath.Join("testdata", "storage_account_bench.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	results, err := ParseTestJSON(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2: %+v", len(results), results)
+	}
+
+	simple := results[0]
+	if simple.Benchmark != "BenchmarkStorageAccountCreationSimple" || simple.Iterations != 1 {
+		t.Errorf("name printed before the log output is kept for the result line: %+v", simple)
+	}
+	if simple.Metrics["creation_ms"] != 131000 || simple.Metrics["destroy_ms"] != 48000 {
+		t.Errorf("unexpected metrics %v", simple.Metrics)
+	}
+
+	containers := results[1]
+	if containers.Benchmark != "BenchmarkStorageAccountCreationWithContainers/Containers_5" {
+		t.Errorf("GOMAXPROCS suffix is stripped, got %q", containers.Benchmark)
+	}
+	if containers.Metrics["container_count"] != 5 {
+		t.Errorf("dimensions are kept as metrics, got %v", containers.Metrics)
+	}
+	if module := ModuleName(containers.Package); module != "azurerm_storage_account" {
+		t.Errorf("got module %q", module)
+	}
+}
+
+func TestModuleName(t *testing.T) {
+	for packagePath, want := range map[string]string{
+		"github.com/PatrykIti/azurerm-terraform-modules/modules/azurerm_key_vault/tests": "azurerm_key_vault",
+		"github.com/example/azurerm-storage-account/tests":                               "azurerm_storage_account",
+		"example.com/bench": "example.com/bench",
+	} {
+		if got := ModuleName(packagePath); got != want {
+			t.Errorf("ModuleName(%q) = %q, want %q", packagePath, got, want)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	stats := Summarize([]float64{100, 300, 200, 400})
+	if stats.N != 4 || stats.P50 != 250 || stats.Mean != 250 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if stats.P90 != 370 {
+		t.Errorf("p90 = %v, want 370", stats.P90)
+	}
+	if single := Summarize([]float64{42}); single.P50 != 42 || single.StdDev != 0 {
+		t.Errorf("unexpected stats %+v", single)
+	}
+}
+
+func TestCompareGatesOnP50PerRegion(t *testing.T) {
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	sample := func(run string, day int, region string, creation, destroy float64) Sample {
+		return Sample{RunID: run, Timestamp: start.AddDate(0, 0, day), Module: "azurerm_storage_account",
+			Benchmark: "BenchmarkStorageAccountCreationSimple", Region: region,
+			Metrics: map[string]float64{"creation_ms": creation, "destroy_ms": destroy}}
+	}
+	history := []Sample{
+		sample("r1", 0, "northeurope", 100000, 40000),
+		sample("r2", 1, "northeurope", 110000, 42000),
+		sample("r3", 2, "northeurope", 90000, 41000),
+		sample("r3", 2, "westeurope", 300000, 40000),
+		// Outlier before the baseline window is ignored with -baseline-runs 3
+		sample("r0", -1, "northeurope", 10000, 1000),
+		sample("r4", 3, "northeurope", 150000, 44000),
+		sample("r4", 3, "westeurope", 200000, 40000),
+		sample("r4", 3, "eastus", 50000, 20000),
+	}
+
+	comparisons := Compare(history, LatestRun(history), CompareOptions{
+		Metrics: []string{"creation_ms", "destroy_ms"}, ThresholdPct: 20, BaselineRuns: 3, MinSamples: 1,
+	})
+	status := map[string]string{}
+	for _, c := range comparisons {
+		status[c.Key.Region+"/"+c.Metric] = c.Status
+	}
+	want := map[string]string{
+		"northeurope/creation_ms": StatusRegressed, // p50 150000 vs 100000
+		"northeurope/destroy_ms":  StatusOK,        // p50 44000 vs 41000
+		"westeurope/creation_ms":  StatusOK,        // faster than the only baseline sample
+		"westeurope/destroy_ms":   StatusOK,
+		"eastus/creation_ms":      StatusNew,
+		"eastus/destroy_ms":       StatusNew,
+	}
+	for key, expected := range want {
+		if status[key] != expected {
+			t.Errorf("%s: status %q, want %q", key, status[key], expected)
+		}
+	}
+	if regressed := Regressions(comparisons); len(regressed) != 1 || regressed[0].DeltaPct != 50 {
+		t.Errorf("unexpected regressions %+v", regressed)
+	}
+
+	pinned := Compare(history, "r4", CompareOptions{Metrics: []string{"creation_ms"}, ThresholdPct: 60, BaselineRun: "r3", MinSamples: 2})
+	for _, c := range pinned {
+		if c.Key.Region == "northeurope" && c.Status != StatusTooFew {
+			t.Errorf("one baseline sample is not enough with -min-samples 2: %+v", c)
+		}
+	}
+}
+
+func TestCheckCommand(t *testing.T) {
+	store := filepath.Join(t.TempDir(), "history.jsonl")
+	input := filepath.Join("testdata", "storage_account_bench.json")
+	args := func(extra ...string) []string {
+		return append([]string{"check", "-store", store, "-input", input, "-region", "northeurope"}, extra...)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run(args("-run-id", "baseline"), nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("first run: exit %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), StatusNew) {
+		t.Errorf("first run has no baseline:\n%s", stdout.String())
+	}
+
+	// Same timings again stay within the threshold
+	stdout.Reset()
+	if code := run(args("-run-id", "same"), nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("unchanged run: exit %d: %s\n%s", code, stderr.String(), stdout.String())
+	}
+
+	history, err := Store{Path: store}.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 4 || history[0].Region != "northeurope" || history[0].Module != "azurerm_storage_account" {
+		t.Fatalf("unexpected history %+v", history)
+	}
+
+	// A slower run fails the gate
+	slower := history[len(history)-1]
+	slower.RunID, slower.Timestamp = "slower", slower.Timestamp.Add(time.Hour)
+	slower.Metrics = map[string]float64{"creation_ms": 400000, "destroy_ms": 51000}
+	if err := (Store{Path: store}).Append([]Sample{slower}); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	stderr.Reset()
+	if code := run([]string{"compare", "-store", store}, nil, &stdout, &stderr); code != exitRegression {
+		t.Fatalf("exit %d, want %d:\n%s", code, exitRegression, stdout.String())
+	}
+	if !strings.Contains(stdout.String(), StatusRegressed) || !strings.Contains(stderr.String(), "1 series regressed") {
+		t.Errorf("regression is reported:\n%s%s", stdout.String(), stderr.String())
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Stats summarizes the samples of one metric
+type Stats struct {
+	N      int
+	P50    float64
+	P90    float64
+	Mean   float64
+	StdDev float64
+}
+
+// Summarize computes percentiles (linear interpolation), mean and sample standard deviation
+func Summarize(values []float64) Stats {
+	if len(values) == 0 {
+		return Stats{}
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	stats := Stats{N: len(sorted), P50: percentile(sorted, 0.5), P90: percentile(sorted, 0.9)}
+	for _, value := range sorted {
+		stats.Mean += value
+	}
+	stats.Mean /= float64(stats.N)
+	if stats.N > 1 {
+		var squares float64
+		for _, value := range sorted {
+			squares += (value - stats.Mean) * (value - stats.Mean)
+		}
+		stats.StdDev = math.Sqrt(squares / float64(stats.N-1))
+	}
+	return stats
+}
+
+func percentile(sorted []float64, q float64) float64 {
+	position := q * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	upper := int(math.Ceil(position))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(position-float64(lower))
+}
+
+// CompareOptions controls which runs form the baseline and when a change is a regression
+type CompareOptions struct {
+	// Metrics to compare, e.g. creation_ms and destroy_ms
+	Metrics []string
+	// ThresholdPct is the p50 increase, in percent, that counts as a regression
+	ThresholdPct float64
+	// BaselineRun pins the baseline to one run; otherwise the BaselineRuns runs before the current one are used
+	BaselineRun  string
+	BaselineRuns int
+	// MinSamples is the number of baseline samples needed before a series can fail the gate
+	MinSamples int
+}
+
+// Status of a compared series
+const (
+	StatusOK        = "ok"
+	StatusRegressed = "regressed"
+	StatusNew       = "new"
+	StatusTooFew    = "insufficient-baseline"
+)
+
+// Comparison is the result for one series and metric
+type Comparison struct {
+	Key      SeriesKey
+	Metric   string
+	Baseline Stats
+	Current  Stats
+	DeltaPct float64
+	Status   string
+}
+
+// runOrder returns run IDs ordered by their first sample; runs recorded at the
+// same time keep their order in the store
+func runOrder(history []Sample) []string {
+	first := map[string]time.Time{}
+	var runs []string
+	for _, sample := range history {
+		started, ok := first[sample.RunID]
+		if !ok {
+			runs = append(runs, sample.RunID)
+		}
+		if !ok || sample.Timestamp.Before(started) {
+			first[sample.RunID] = sample.Timestamp
+		}
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return first[runs[i]].Before(first[runs[j]]) })
+	return runs
+}
+
+// LatestRun returns the ID of the most recent run in the history
+func LatestRun(history []Sample) string {
+	runs := runOrder(history)
+	if len(runs) == 0 {
+		return ""
+	}
+	return runs[len(runs)-1]
+}
+
+// baselineRuns returns the run IDs that form the baseline of currentRun
+func baselineRuns(history []Sample, currentRun string, options CompareOptions) map[string]bool {
+	if options.BaselineRun != "" {
+		return map[string]bool{options.BaselineRun: true}
+	}
+
+	runs := map[string]bool{}
+	order := runOrder(history)
+	for i := len(order) - 1; i >= 0; i-- {
+		if order[i] != currentRun {
+			continue
+		}
+		for j := i - 1; j >= 0 && (options.BaselineRuns <= 0 || len(runs) < options.BaselineRuns); j-- {
+			runs[order[j]] = true
+		}
+	}
+	return runs
+}
+
+// Compare compares every series of currentRun against its baseline, per region
+func Compare(history []Sample, currentRun string, options CompareOptions) []Comparison {
+	baseline := baselineRuns(history, currentRun, options)
+
+	type seriesMetric struct {
+		key    SeriesKey
+		metric string
+	}
+	current := map[seriesMetric][]float64{}
+	previous := map[seriesMetric][]float64{}
+	for _, sample := range history {
+		for _, metric := range options.Metrics {
+			value, ok := sample.Metrics[metric]
+			if !ok {
+				continue
+			}
+			key := seriesMetric{sample.Key(), metric}
+			switch {
+			case sample.RunID == currentRun:
+				current[key] = append(current[key], value)
+			case baseline[sample.RunID]:
+				previous[key] = append(previous[key], value)
+			}
+		}
+	}
+
+	var comparisons []Comparison
+	for key, values := range current {
+		comparison := Comparison{Key: key.key, Metric: key.metric, Current: Summarize(values), Baseline: Summarize(previous[key])}
+		switch {
+		case comparison.Baseline.N == 0:
+			comparison.Status = StatusNew
+		case comparison.Baseline.N < options.MinSamples:
+			comparison.Status = StatusTooFew
+		default:
+			comparison.Status = StatusOK
+		}
+		if comparison.Baseline.P50 > 0 {
+			comparison.DeltaPct = (comparison.Current.P50 - comparison.Baseline.P50) / comparison.Baseline.P50 * 100
+			if comparison.Status == StatusOK && comparison.DeltaPct > options.ThresholdPct {
+				comparison.Status = StatusRegressed
+			}
+		}
+		comparisons = append(comparisons, comparison)
+	}
+
+	sort.Slice(comparisons, func(i, j int) bool {
+		a, b := comparisons[i], comparisons[j]
+		if a.Key != b.Key {
+			if a.Key.Module != b.Key.Module {
+				return a.Key.Module < b.Key.Module
+			}
+			if a.Key.Benchmark != b.Key.Benchmark {
+				return a.Key.Benchmark < b.Key.Benchmark
+			}
+			return a.Key.Region < b.Key.Region
+		}
+		return a.Metric < b.Metric
+	})
+	return comparisons
+}
+
+// Regressions returns the comparisons that fail the gate
+func Regressions(comparisons []Comparison) []Comparison {
+	var regressed []Comparison
+	for _, comparison := range comparisons {
+		if comparison.Status == StatusRegressed {
+			regressed = append(regressed, comparison)
+		}
+	}
+	return regressed
+}
@@ -0,0 +1,3 @@
+module github.com/PatrykIti/azurerm-terraform-modules/scripts/benchhistory
+
+go 1.21
@@ -0,0 +1,190 @@
+// Command benchhistory keeps a history of Terratest benchmark metrics and fails
+// CI when p50 creation or destroy time regresses against earlier runs.
+//
+//	(cd scripts/benchhistory && go build -o ../../.benchmarks/benchhistory .)
+//	go test -json -run='^$' -bench=. -benchtime=1x ./... > bench.json
+//	.benchmarks/benchhistory check -store .benchmarks/history.jsonl -input bench.json
+//
+// Subcommands:
+//
+//	ingest   append the results of a `go test -bench -json` run to the store
+//	compare  compare a stored run with its baseline
+//	check    ingest, then compare the new run (exit status 1 on regression)
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	defaultStore     = ".benchmarks/history.jsonl"
+	defaultMetrics   = "creation_ms,destroy_ms"
+	defaultThreshold = 20.0
+
+	exitRegression = 1
+	exitError      = 2
+)
+
+type config struct {
+	store        string
+	input        string
+	runID        string
+	region       string
+	commit       string
+	module       string
+	metrics      string
+	threshold    float64
+	baselineRun  string
+	baselineRuns int
+	minSamples   int
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "usage: benchhistory ingest|compare|check [flags]")
+		return exitError
+	}
+	command := args[0]
+
+	var cfg config
+	flags := flag.NewFlagSet("benchhistory "+command, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&cfg.store, "store", defaultStore, "JSON lines history file")
+	flags.StringVar(&cfg.runID, "run-id", "", "run to ingest or compare (default: new timestamp for ingest, latest run for compare)")
+	switch command {
+	case "ingest", "check":
+		flags.StringVar(&cfg.input, "input", "-", "`go test -bench -json` output file, - for stdin")
+		flags.StringVar(&cfg.region, "region", firstEnv("AZURE_LOCATION", "ARM_LOCATION"), "Azure region the benchmarks ran in")
+		flags.StringVar(&cfg.commit, "commit", firstEnv("GITHUB_SHA", "BUILD_SOURCEVERSION"), "commit the benchmarks ran on")
+		flags.StringVar(&cfg.module, "module", "", "module name (default: derived from the test package path)")
+	}
+	switch command {
+	case "compare", "check":
+		flags.StringVar(&cfg.metrics, "metrics", defaultMetrics, "comma-separated metrics to gate on")
+		flags.Float64Var(&cfg.threshold, "threshold", defaultThreshold, "allowed p50 increase in percent")
+		flags.StringVar(&cfg.baselineRun, "baseline-run", "", "compare against this run only")
+		flags.IntVar(&cfg.baselineRuns, "baseline-runs", 5, "number of previous runs forming the baseline (0 for all)")
+		flags.IntVar(&cfg.minSamples, "min-samples", 1, "baseline samples needed before a series can fail")
+	case "ingest":
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n", command)
+		return exitError
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		return exitError
+	}
+
+	store := Store{Path: cfg.store}
+	if command == "ingest" || command == "check" {
+		runID, err := ingest(store, cfg, stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "ingest: %v\n", err)
+			return exitError
+		}
+		fmt.Fprintf(stdout, "Recorded run %s in %s\n", runID, cfg.store)
+		cfg.runID = runID
+	}
+	if command == "ingest" {
+		return 0
+	}
+
+	history, err := store.Load()
+	if err != nil {
+		fmt.Fprintf(stderr, "compare: %v\n", err)
+		return exitError
+	}
+	if cfg.runID == "" {
+		cfg.runID = LatestRun(history)
+	}
+	comparisons := Compare(history, cfg.runID, CompareOptions{
+		Metrics:      strings.Split(cfg.metrics, ","),
+		ThresholdPct: cfg.threshold,
+		BaselineRun:  cfg.baselineRun,
+		BaselineRuns: cfg.baselineRuns,
+		MinSamples:   cfg.minSamples,
+	})
+	printComparisons(stdout, comparisons)
+
+	if regressed := Regressions(comparisons); len(regressed) > 0 {
+		fmt.Fprintf(stderr, "%d series regressed by more than %.0f%% p50\n", len(regressed), cfg.threshold)
+		return exitRegression
+	}
+	return 0
+}
+
+// ingest parses the benchmark output and appends it to the store as a new run
+func ingest(store Store, cfg config, stdin io.Reader) (string, error) {
+	input := stdin
+	if cfg.input != "-" {
+		file, err := os.Open(cfg.input)
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+		input = file
+	}
+	results, err := ParseTestJSON(input)
+	if err != nil {
+		return "", err
+	}
+	if len(results) == 0 {
+		return "", fmt.Errorf("no benchmark results in %s", cfg.input)
+	}
+
+	now := time.Now().UTC()
+	runID := cfg.runID
+	if runID == "" {
+		runID = now.Format("20060102T150405Z")
+	}
+	region := cfg.region
+	if region == "" {
+		region = "unknown"
+	}
+	samples := make([]Sample, 0, len(results))
+	for _, result := range results {
+		module := cfg.module
+		if module == "" {
+			module = ModuleName(result.Package)
+		}
+		samples = append(samples, Sample{
+			RunID:      runID,
+			Timestamp:  now,
+			Commit:     cfg.commit,
+			Module:     module,
+			Benchmark:  result.Benchmark,
+			Region:     region,
+			Iterations: result.Iterations,
+			Metrics:    result.Metrics,
+		})
+	}
+	return runID, store.Append(samples)
+}
+
+func printComparisons(w io.Writer, comparisons []Comparison) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "MODULE\tBENCHMARK\tREGION\tMETRIC\tBASE P50\tCURRENT P50\tDELTA\tBASE N\tSTATUS")
+	for _, c := range comparisons {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%.0f\t%.0f\t%+.1f%%\t%d\t%s\n",
+			c.Key.Module, c.Key.Benchmark, c.Key.Region, c.Metric, c.Baseline.P50, c.Current.P50, c.DeltaPct, c.Baseline.N, c.Status)
+	}
+	table.Flush()
+}
+
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
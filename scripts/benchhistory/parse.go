@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// Benchmark result lines start with the benchmark name; with -v the name is
+	// printed before the run and the numbers follow after any log output.
+	benchmarkNamePattern = regexp.MustCompile(`^(Benchmark\S+)(?:\s+(.*))?$`)
+	procsSuffixPattern   = regexp.MustCompile(`-\d+$`)
+	modulePackagePattern = regexp.MustCompile(`/modules/([^/]+)/tests$`)
+)
+
+// Result is one benchmark result line from `go test -bench -json`
+type Result struct {
+	Package    string
+	Benchmark  string
+	Iterations int
+	Metrics    map[string]float64
+}
+
+// testEvent is the subset of test2json events used here
+type testEvent struct {
+	Action  string
+	Package string
+	Output  string
+}
+
+// ParseTestJSON extracts benchmark results from `go test -json` output.
+// Lines that are not JSON events (build errors, plain output) are ignored.
+func ParseTestJSON(r io.Reader) ([]Result, error) {
+	var results []Result
+	pending := map[string]string{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var event testEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil || event.Action != "output" {
+			continue
+		}
+		output := strings.TrimRight(event.Output, "\r\n")
+
+		rest := output
+		if match := benchmarkNamePattern.FindStringSubmatch(output); match != nil {
+			pending[event.Package] = procsSuffixPattern.ReplaceAllString(match[1], "")
+			rest = match[2]
+		}
+		name, ok := pending[event.Package]
+		if !ok {
+			continue
+		}
+		if iterations, metrics, ok := parseMeasurements(rest); ok {
+			results = append(results, Result{Package: event.Package, Benchmark: name, Iterations: iterations, Metrics: metrics})
+		}
+	}
+	return results, scanner.Err()
+}
+
+// parseMeasurements parses "<iterations> <value> <unit> [<value> <unit>...]"
+func parseMeasurements(text string) (int, map[string]float64, bool) {
+	fields := strings.Fields(text)
+	if len(fields) < 3 || len(fields)%2 == 0 {
+		return 0, nil, false
+	}
+	iterations, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, nil, false
+	}
+	metrics := map[string]float64{}
+	for i := 1; i < len(fields); i += 2 {
+		value, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return 0, nil, false
+		}
+		metrics[fields[i+1]] = value
+	}
+	return iterations, metrics, true
+}
+
+// ModuleName derives the module from a test package path such as
+// github.com/<org>/azurerm-terraform-modules/modules/azurerm_storage_account/tests.
+// Suites with a standalone module path fall back to the element before /tests.
+func ModuleName(packagePath string) string {
+	if match := modulePackagePattern.FindStringSubmatch(packagePath); match != nil {
+		return match[1]
+	}
+	if parent, ok := strings.CutSuffix(packagePath, "/tests"); ok {
+		return strings.ReplaceAll(parent[strings.LastIndex(parent, "/")+1:], "-", "_")
+	}
+	return packagePath
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Sample is one benchmark result of one run, as persisted in the history store
+type Sample struct {
+	RunID      string             `json:"run_id"`
+	Timestamp  time.Time          `json:"timestamp"`
+	Commit     string             `json:"commit,omitempty"`
+	Module     string             `json:"module"`
+	Benchmark  string             `json:"benchmark"`
+	Region     string             `json:"region"`
+	Iterations int                `json:"iterations"`
+	Metrics    map[string]float64 `json:"metrics"`
+}
+
+// SeriesKey identifies the history a sample is compared against
+type SeriesKey struct {
+	Module    string
+	Benchmark string
+	Region    string
+}
+
+// Key returns the series of the sample
+func (s Sample) Key() SeriesKey {
+	return SeriesKey{Module: s.Module, Benchmark: s.Benchmark, Region: s.Region}
+}
+
+// Store is a JSON lines file with one Sample per line
+type Store struct {
+	Path string
+}
+
+// Append adds samples to the store, creating it if needed
+func (s Store) Append(samples []Sample) error {
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(s.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	for _, sample := range samples {
+		if err := encoder.Encode(sample); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	return file.Close()
+}
+
+// Load reads all samples; a missing store is an empty history
+func (s Store) Load() ([]Sample, error) {
+	file, err := os.Open(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var samples []Sample
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var sample Sample
+		if err := json.Unmarshal(scanner.Bytes(), &sample); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", s.Path, line, err)
+		}
+		samples = append(samples, sample)
+	}
+	return samples, scanner.Err()
+}
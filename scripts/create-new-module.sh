@@ -347,6 +347,11 @@ find "$MODULE_DIR" -type f \( -name "*.tf" -o -name "*.md" -o -name "*.yml" -o -
     replace_placeholders "$file"
 done
 
+# Resolve the dependencies of shared/testkit for the new test suite
+if command -v go >/dev/null 2>&1; then
+    (cd "$MODULE_DIR/tests" && go mod tidy >/dev/null 2>&1) || print_warning "go mod tidy failed; run it in $MODULE_DIR/tests"
+fi
+
 # Create example files
 print_info "Creating examples..."
 
@@ -0,0 +1,228 @@
+// Package adoacl resolves effective Azure DevOps permissions from security namespace ACLs
+// and group memberships, as the azuredevops_*_permissions resources write them.
+package adoacl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/security"
+)
+
+// Security namespaces written by the azuredevops_*_permissions resources
+const (
+	SecurityNamespaceProject          = "52d39943-cb85-4d7f-8fa8-c6baac873819"
+	SecurityNamespaceGitRepositories  = "2e9eb7ed-3c0a-47d4-87c1-0ffdd275fd87"
+	SecurityNamespaceBuild            = "33344d9c-fc72-4d6f-aba5-fa317101a7e9"
+	SecurityNamespaceLibrary          = "b7e84409-6553-448a-bbb2-af228e07cbeb"
+	SecurityNamespaceServiceEndpoints = "49b48001-ca20-4adc-8111-5b60c903a50c"
+)
+
+// PermissionState matches the Allow/Deny/NotSet values used by the permission modules
+type PermissionState string
+
+const (
+	PermissionAllow  PermissionState = "Allow"
+	PermissionDeny   PermissionState = "Deny"
+	PermissionNotSet PermissionState = "NotSet"
+)
+
+// EffectivePermission is the resolved state of one action for one identity on one token
+type EffectivePermission struct {
+	Action string
+	State  PermissionState
+	// Token is the ACL that decided the state; empty when nothing is set
+	Token string
+	// Inherited is true when the deciding ACL belongs to a parent token
+	Inherited bool
+	// Descriptors lists the identities whose entries decided the state
+	Descriptors []string
+}
+
+// ACLSnapshot is everything needed to resolve permissions offline.
+// Memberships maps an identity descriptor to the descriptors of the groups it belongs to.
+type ACLSnapshot struct {
+	Namespace   security.SecurityNamespaceDescription `json:"namespace"`
+	ACLs        []security.AccessControlList          `json:"acls"`
+	Memberships map[string][]string                   `json:"memberships"`
+}
+
+// ACLResolver computes effective permissions from a snapshot
+type ACLResolver struct {
+	actions     map[string]int
+	separator   string
+	acls        map[string]security.AccessControlList
+	memberships map[string][]string
+}
+
+// ProjectPermissionToken returns the Project namespace token of a project
+func ProjectPermissionToken(projectID string) string {
+	return fmt.Sprintf("$PROJECT:vstfs:///Classification/TeamProject/%s", projectID)
+}
+
+// GitRepositoryPermissionToken returns the Git Repositories namespace token of a repository
+func GitRepositoryPermissionToken(projectID, repositoryID string) string {
+	return fmt.Sprintf("repoV2/%s/%s", projectID, repositoryID)
+}
+
+// GitBranchPermissionToken returns the Git Repositories namespace token of a branch.
+// Branch name segments are hex encoded as UTF-16LE, as Azure DevOps stores them.
+func GitBranchPermissionToken(projectID, repositoryID, branch string) string {
+	branch = strings.TrimPrefix(branch, "refs/heads/")
+	var encoded []string
+	for _, segment := range strings.Split(branch, "/") {
+		var builder strings.Builder
+		for _, r := range segment {
+			fmt.Fprintf(&builder, "%02x%02x", r&0xff, (r>>8)&0xff)
+		}
+		encoded = append(encoded, builder.String())
+	}
+	return fmt.Sprintf("%s/refs/heads/%s", GitRepositoryPermissionToken(projectID, repositoryID), strings.Join(encoded, "/"))
+}
+
+// VariableGroupPermissionToken returns the Library namespace token of a variable group
+func VariableGroupPermissionToken(projectID string, variableGroupID int) string {
+	return fmt.Sprintf("Library/%s/VariableGroup/%d", projectID, variableGroupID)
+}
+
+// BuildDefinitionPermissionToken returns the Build namespace token of a pipeline.
+// The folder path, e.g. "\" or "\Team\Nightly", becomes part of the token.
+func BuildDefinitionPermissionToken(projectID, path string, definitionID int) string {
+	path = strings.Trim(strings.ReplaceAll(path, `\`, "/"), "/")
+	if path == "" {
+		return fmt.Sprintf("%s/%d", projectID, definitionID)
+	}
+	return fmt.Sprintf("%s/%s/%d", projectID, path, definitionID)
+}
+
+// ServiceEndpointPermissionToken returns the ServiceEndpoints namespace token of a project scoped endpoint
+func ServiceEndpointPermissionToken(projectID, serviceEndpointID string) string {
+	return fmt.Sprintf("endpoints/%s/%s", projectID, serviceEndpointID)
+}
+
+// NewACLResolver indexes the snapshot for resolution
+func NewACLResolver(snapshot ACLSnapshot) (*ACLResolver, error) {
+	if snapshot.Namespace.Actions == nil || len(*snapshot.Namespace.Actions) == 0 {
+		return nil, fmt.Errorf("security namespace %s has no actions", derefString(snapshot.Namespace.Name))
+	}
+
+	resolver := &ACLResolver{
+		actions:     map[string]int{},
+		separator:   derefString(snapshot.Namespace.SeparatorValue),
+		acls:        map[string]security.AccessControlList{},
+		memberships: map[string][]string{},
+	}
+	for _, action := range *snapshot.Namespace.Actions {
+		if action.Name != nil && action.Bit != nil {
+			resolver.actions[strings.ToLower(*action.Name)] = *action.Bit
+		}
+	}
+	for _, acl := range snapshot.ACLs {
+		if acl.Token != nil {
+			resolver.acls[strings.ToLower(*acl.Token)] = acl
+		}
+	}
+	for descriptor, groups := range snapshot.Memberships {
+		resolver.memberships[strings.ToLower(descriptor)] = groups
+	}
+	return resolver, nil
+}
+
+// Resolve computes the effective state of an action for an identity.
+// The closest token with an entry for the action bit decides; at that token Deny from any
+// membership wins over Allow. Walking stops at ACLs that do not inherit permissions.
+func (r *ACLResolver) Resolve(descriptor, token, action string) (EffectivePermission, error) {
+	bit, ok := r.actions[strings.ToLower(action)]
+	if !ok {
+		return EffectivePermission{}, fmt.Errorf("unknown action %q, known actions: %s", action, strings.Join(r.actionNames(), ", "))
+	}
+
+	result := EffectivePermission{Action: action, State: PermissionNotSet}
+	identities := r.expandMemberships(descriptor)
+	for i, candidate := range r.tokenChain(token) {
+		acl, ok := r.acls[strings.ToLower(candidate)]
+		if !ok {
+			continue
+		}
+
+		var allowedBy, deniedBy []string
+		if acl.AcesDictionary != nil {
+			for aceDescriptor, ace := range *acl.AcesDictionary {
+				if !identities[strings.ToLower(aceDescriptor)] {
+					continue
+				}
+				if ace.Deny != nil && *ace.Deny&bit != 0 {
+					deniedBy = append(deniedBy, aceDescriptor)
+				} else if ace.Allow != nil && *ace.Allow&bit != 0 {
+					allowedBy = append(allowedBy, aceDescriptor)
+				}
+			}
+		}
+
+		switch {
+		case len(deniedBy) > 0:
+			result.State, result.Descriptors = PermissionDeny, deniedBy
+		case len(allowedBy) > 0:
+			result.State, result.Descriptors = PermissionAllow, allowedBy
+		}
+		if result.State != PermissionNotSet {
+			sort.Strings(result.Descriptors)
+			result.Token = candidate
+			result.Inherited = i > 0
+			return result, nil
+		}
+		if acl.InheritPermissions != nil && !*acl.InheritPermissions {
+			break
+		}
+	}
+	return result, nil
+}
+
+// tokenChain returns the token followed by its parents, closest first
+func (r *ACLResolver) tokenChain(token string) []string {
+	chain := []string{token}
+	if r.separator == "" {
+		return chain
+	}
+	for {
+		index := strings.LastIndex(token, r.separator)
+		if index <= 0 {
+			return chain
+		}
+		token = token[:index]
+		chain = append(chain, token)
+	}
+}
+
+// expandMemberships returns the identity and every group it belongs to, transitively
+func (r *ACLResolver) expandMemberships(descriptor string) map[string]bool {
+	identities := map[string]bool{}
+	pending := []string{descriptor}
+	for len(pending) > 0 {
+		current := strings.ToLower(pending[0])
+		pending = pending[1:]
+		if identities[current] {
+			continue
+		}
+		identities[current] = true
+		pending = append(pending, r.memberships[current]...)
+	}
+	return identities
+}
+
+func (r *ACLResolver) actionNames() []string {
+	names := make([]string, 0, len(r.actions))
+	for name := range r.actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func derefString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,143 @@
+package adoacl
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testACLProjectID     = "0f3c8a7e-2b7d-4c6a-9a53-1f0e6d2b8c11"
+	testACLRepositoryID  = "5febef5a-833d-4e14-b9c0-14cb638f91e6"
+	testACLIsolatedRepo  = "a1d6e1f4-7c0e-4a9b-8f3d-2e5c9b7a6d10"
+	testACLContributors  = "Microsoft.TeamFoundation.Identity;S-1-9-1551374245-1204400969-2402986413-2179408616-3-contributors"
+	testACLReaders       = "Microsoft.TeamFoundation.Identity;S-1-9-1551374245-1204400969-2402986413-2179408616-3-readers"
+	testACLCollectionAdm = "Microsoft.TeamFoundation.Identity;S-1-9-1551374245-1204400969-2402986413-2179408616-0-0-0-0-1"
+	testACLAlice         = "Microsoft.IdentityModel.Claims.ClaimsIdentity;alice@example.com"
+)
+
+func loadTestACLResolver(t *testing.T) *ACLResolver {
+	content, err := os.ReadFile(filepath.Join("testdata", "git_repository_acls.json"))
+	require.NoError(t, err)
+
+	var snapshot ACLSnapshot
+	require.NoError(t, json.Unmarshal(content, &snapshot))
+	resolver, err := NewACLResolver(snapshot)
+	require.NoError(t, err)
+	return resolver
+}
+
+func TestACLResolverResolve(t *testing.T) {
+	resolver := loadTestACLResolver(t)
+	repository := GitRepositoryPermissionToken(testACLProjectID, testACLRepositoryID)
+	mainBranch := GitBranchPermissionToken(testACLProjectID, testACLRepositoryID, "refs/heads/main")
+
+	testCases := []struct {
+		name       string
+		descriptor string
+		token      string
+		action     string
+		state      PermissionState
+		decidedAt  string
+		inherited  bool
+	}{
+		{
+			name:       "ContributorsCannotAdminister",
+			descriptor: testACLContributors,
+			token:      repository,
+			action:     "Administer",
+			state:      PermissionNotSet,
+		},
+		{
+			name:       "ContributeInheritedFromProject",
+			descriptor: testACLContributors,
+			token:      repository,
+			action:     "GenericContribute",
+			state:      PermissionAllow,
+			decidedAt:  "repoV2/" + testACLProjectID,
+			inherited:  true,
+		},
+		{
+			name:       "CollectionAdministratorsInheritFromRoot",
+			descriptor: testACLCollectionAdm,
+			token:      repository,
+			action:     "ManagePermissions",
+			state:      PermissionAllow,
+			decidedAt:  "repoV2",
+			inherited:  true,
+		},
+		{
+			name:       "GroupDenyWinsOverUserAllowOnSameToken",
+			descriptor: testACLAlice,
+			token:      repository,
+			action:     "ForcePush",
+			state:      PermissionDeny,
+			decidedAt:  repository,
+		},
+		{
+			name:       "ExplicitBranchAllowOverridesInheritedDeny",
+			descriptor: testACLAlice,
+			token:      mainBranch,
+			action:     "ForcePush",
+			state:      PermissionAllow,
+			decidedAt:  mainBranch,
+		},
+		{
+			name:       "NestedMembershipInheritsContribute",
+			descriptor: testACLAlice,
+			token:      mainBranch,
+			action:     "genericcontribute",
+			state:      PermissionAllow,
+			decidedAt:  "repoV2/" + testACLProjectID,
+			inherited:  true,
+		},
+		{
+			name:       "InheritanceBrokenOnIsolatedRepository",
+			descriptor: testACLContributors,
+			token:      GitRepositoryPermissionToken(testACLProjectID, testACLIsolatedRepo),
+			action:     "GenericRead",
+			state:      PermissionNotSet,
+		},
+		{
+			name:       "ReadersKeepExplicitReadOnIsolatedRepository",
+			descriptor: testACLReaders,
+			token:      GitRepositoryPermissionToken(testACLProjectID, testACLIsolatedRepo),
+			action:     "GenericRead",
+			state:      PermissionAllow,
+			decidedAt:  GitRepositoryPermissionToken(testACLProjectID, testACLIsolatedRepo),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			permission, err := resolver.Resolve(tc.descriptor, tc.token, tc.action)
+			require.NoError(t, err)
+			assert.Equal(t, tc.state, permission.State)
+			assert.Equal(t, tc.decidedAt, permission.Token)
+			assert.Equal(t, tc.inherited, permission.Inherited)
+		})
+	}
+}
+
+func TestACLResolverUnknownAction(t *testing.T) {
+	resolver := loadTestACLResolver(t)
+
+	_, err := resolver.Resolve(testACLContributors, GitRepositoryPermissionToken(testACLProjectID, testACLRepositoryID), "DeleteEverything")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "administer")
+}
+
+func TestPermissionTokens(t *testing.T) {
+	assert.Equal(t, "repoV2/p/r/refs/heads/6d00610069006e00", GitBranchPermissionToken("p", "r", "main"))
+	assert.Equal(t, "repoV2/p/r/refs/heads/6600650061007400750072006500/780079007a00", GitBranchPermissionToken("p", "r", "refs/heads/feature/xyz"))
+	assert.Equal(t, "$PROJECT:vstfs:///Classification/TeamProject/p", ProjectPermissionToken("p"))
+	assert.Equal(t, "Library/p/VariableGroup/7", VariableGroupPermissionToken("p", 7))
+	assert.Equal(t, "endpoints/p/e", ServiceEndpointPermissionToken("p", "e"))
+	assert.Equal(t, "p/12", BuildDefinitionPermissionToken("p", `\`, 12))
+	assert.Equal(t, "p/Team/Nightly/12", BuildDefinitionPermissionToken("p", `\Team\Nightly`, 12))
+}
@@ -0,0 +1,109 @@
+package adoacl
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/feed"
+	"github.com/stretchr/testify/require"
+)
+
+// feedRoleRank orders the Azure Artifacts feed roles; a higher role includes the lower ones
+var feedRoleRank = map[string]int{
+	"none":          0,
+	"reader":        1,
+	"collaborator":  2,
+	"contributor":   3,
+	"administrator": 4,
+}
+
+// EffectiveFeedRole is the highest feed role an identity holds directly or through its groups
+type EffectiveFeedRole struct {
+	Role string
+	// Descriptors lists the identities whose entries grant the role
+	Descriptors []string
+	// Inherited is true when the role comes only from permissions inherited by the feed
+	Inherited bool
+}
+
+// FeedSnapshot is everything needed to resolve a feed role offline: the feed permissions,
+// including inherited ones, and the expanded group memberships of the identity
+type FeedSnapshot struct {
+	Permissions []feed.FeedPermission `json:"permissions"`
+	Memberships map[string][]string   `json:"memberships"`
+}
+
+// ResolveFeedRole returns the highest role the identity holds on the feed.
+// Feed roles only add up, so there is no deny to take into account.
+func ResolveFeedRole(snapshot FeedSnapshot, descriptor string) EffectiveFeedRole {
+	resolver := &ACLResolver{memberships: map[string][]string{}}
+	for member, groups := range snapshot.Memberships {
+		resolver.memberships[strings.ToLower(member)] = groups
+	}
+	identities := resolver.expandMemberships(descriptor)
+
+	result := EffectiveFeedRole{Role: "none"}
+	for _, permission := range snapshot.Permissions {
+		if permission.Role == nil || !identities[strings.ToLower(derefString(permission.IdentityDescriptor))] {
+			continue
+		}
+		role := strings.ToLower(string(*permission.Role))
+		inherited := permission.IsInheritedRole != nil && *permission.IsInheritedRole
+		switch {
+		case feedRoleRank[role] > feedRoleRank[result.Role]:
+			result = EffectiveFeedRole{Role: role, Descriptors: []string{*permission.IdentityDescriptor}, Inherited: inherited}
+		case feedRoleRank[role] == feedRoleRank[result.Role] && role != "none":
+			result.Descriptors = append(result.Descriptors, *permission.IdentityDescriptor)
+			result.Inherited = result.Inherited && inherited
+		}
+	}
+	sort.Strings(result.Descriptors)
+	return result
+}
+
+// LoadFeedSnapshotE reads the feed permissions, including inherited ones, and the expanded group
+// memberships of the identity. projectID is empty for organization scoped feeds.
+func LoadFeedSnapshotE(connection *azuredevops.Connection, projectID, feedID, descriptor string) (*FeedSnapshot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	client, err := feed.NewClient(ctx, connection)
+	if err != nil {
+		return nil, err
+	}
+	excludeInherited := false
+	args := feed.GetFeedPermissionsArgs{FeedId: &feedID, ExcludeInheritedPermissions: &excludeInherited}
+	if projectID != "" {
+		args.Project = &projectID
+	}
+	permissions, err := client.GetFeedPermissions(ctx, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read permissions of feed %s: %w", feedID, err)
+	}
+
+	snapshot := &FeedSnapshot{}
+	if permissions != nil {
+		snapshot.Permissions = *permissions
+	}
+	snapshot.Memberships, err = loadMembershipsE(ctx, connection, descriptor)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// RequireEffectiveFeedRole asserts the highest role the identity holds on the feed
+func RequireEffectiveFeedRole(t testing.TB, connection *azuredevops.Connection, projectID, feedID, descriptor, expected string) EffectiveFeedRole {
+	t.Helper()
+
+	snapshot, err := LoadFeedSnapshotE(connection, projectID, feedID, descriptor)
+	require.NoError(t, err)
+
+	role := ResolveFeedRole(*snapshot, descriptor)
+	require.Equal(t, strings.ToLower(expected), role.Role, "Effective feed role of %s on %s was granted by %v", descriptor, feedID, role.Descriptors)
+	return role
+}
@@ -0,0 +1,28 @@
+package adoacl
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveFeedRole(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join("testdata", "feed_permissions.json"))
+	require.NoError(t, err)
+	var snapshot FeedSnapshot
+	require.NoError(t, json.Unmarshal(content, &snapshot))
+
+	readers := ResolveFeedRole(snapshot, testACLReaders)
+	assert.Equal(t, EffectiveFeedRole{Role: "reader", Descriptors: []string{testACLReaders}}, readers)
+
+	alice := ResolveFeedRole(snapshot, testACLAlice)
+	assert.Equal(t, "collaborator", alice.Role, "the highest role across group memberships wins")
+	assert.Equal(t, []string{testACLContributors}, alice.Descriptors)
+	assert.True(t, alice.Inherited)
+
+	assert.Equal(t, "none", ResolveFeedRole(snapshot, "Microsoft.IdentityModel.Claims.ClaimsIdentity;bob@example.com").Role)
+}
@@ -0,0 +1,154 @@
+package adoacl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/identity"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/security"
+	"github.com/stretchr/testify/require"
+)
+
+const requestTimeout = 2 * time.Minute
+
+// LoadACLSnapshotE reads the namespace, the ACLs on the token and its parents, and the expanded
+// group memberships of the identity
+func LoadACLSnapshotE(connection *azuredevops.Connection, namespaceID, token, descriptor string) (*ACLSnapshot, error) {
+	namespaceUUID, err := uuid.Parse(namespaceID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid security namespace %q: %w", namespaceID, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	client := security.NewClient(ctx, connection)
+	namespaces, err := client.QuerySecurityNamespaces(ctx, security.QuerySecurityNamespacesArgs{SecurityNamespaceId: &namespaceUUID})
+	if err != nil {
+		return nil, err
+	}
+	if namespaces == nil || len(*namespaces) == 0 {
+		return nil, fmt.Errorf("security namespace %s not found", namespaceID)
+	}
+
+	snapshot := &ACLSnapshot{Namespace: (*namespaces)[0]}
+	resolver := &ACLResolver{separator: derefString(snapshot.Namespace.SeparatorValue)}
+	for _, candidate := range resolver.tokenChain(token) {
+		candidate := candidate
+		acls, err := client.QueryAccessControlLists(ctx, security.QueryAccessControlListsArgs{
+			SecurityNamespaceId: &namespaceUUID,
+			Token:               &candidate,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query ACLs for %s: %w", candidate, err)
+		}
+		if acls != nil {
+			snapshot.ACLs = append(snapshot.ACLs, *acls...)
+		}
+	}
+
+	snapshot.Memberships, err = loadMembershipsE(ctx, connection, descriptor)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// loadMembershipsE reads the expanded group memberships of an identity descriptor
+func loadMembershipsE(ctx context.Context, connection *azuredevops.Connection, descriptor string) (map[string][]string, error) {
+	client, err := identity.NewClient(ctx, connection)
+	if err != nil {
+		return nil, err
+	}
+	identities, err := client.ReadIdentities(ctx, identity.ReadIdentitiesArgs{
+		Descriptors:     &descriptor,
+		QueryMembership: &identity.QueryMembershipValues.Expanded,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read memberships of %s: %w", descriptor, err)
+	}
+
+	memberships := map[string][]string{}
+	if identities != nil {
+		for _, resolved := range *identities {
+			if resolved.Descriptor != nil && resolved.MemberOf != nil {
+				memberships[*resolved.Descriptor] = *resolved.MemberOf
+			}
+		}
+	}
+	return memberships, nil
+}
+
+// GetIdentityDescriptorE resolves a subject descriptor (as used by Terraform principals) or a
+// group name such as "[Project]\Contributors" to the identity descriptor used in ACLs
+func GetIdentityDescriptorE(connection *azuredevops.Connection, subjectOrName string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	client, err := identity.NewClient(ctx, connection)
+	if err != nil {
+		return "", err
+	}
+	args := identity.ReadIdentitiesArgs{}
+	if strings.HasPrefix(subjectOrName, "vss") || strings.HasPrefix(subjectOrName, "aad") {
+		args.SubjectDescriptors = &subjectOrName
+	} else {
+		searchFilter := "General"
+		args.SearchFilter = &searchFilter
+		args.FilterValue = &subjectOrName
+	}
+	identities, err := client.ReadIdentities(ctx, args)
+	if err != nil {
+		return "", err
+	}
+	if identities == nil || len(*identities) == 0 || (*identities)[0].Descriptor == nil {
+		return "", fmt.Errorf("identity %q not found", subjectOrName)
+	}
+	return *(*identities)[0].Descriptor, nil
+}
+
+// RequireIdentityDescriptor is GetIdentityDescriptorE failing the test on error
+func RequireIdentityDescriptor(t testing.TB, connection *azuredevops.Connection, subjectOrName string) string {
+	t.Helper()
+
+	descriptor, err := GetIdentityDescriptorE(connection, subjectOrName)
+	require.NoError(t, err, "Failed to resolve identity %s", subjectOrName)
+	return descriptor
+}
+
+// ResolveEffectivePermissionE loads the ACLs for the token and resolves the action for the identity
+func ResolveEffectivePermissionE(connection *azuredevops.Connection, namespaceID, token, descriptor, action string) (EffectivePermission, error) {
+	snapshot, err := LoadACLSnapshotE(connection, namespaceID, token, descriptor)
+	if err != nil {
+		return EffectivePermission{}, fmt.Errorf("failed to load ACLs for %s: %w", token, err)
+	}
+	resolver, err := NewACLResolver(*snapshot)
+	if err != nil {
+		return EffectivePermission{}, err
+	}
+	return resolver.Resolve(descriptor, token, action)
+}
+
+// RequireEffectivePermission loads the ACLs for the token and asserts the resolved state of the action
+func RequireEffectivePermission(t testing.TB, connection *azuredevops.Connection, namespaceID, token, descriptor, action string, expected PermissionState) EffectivePermission {
+	t.Helper()
+
+	permission, err := ResolveEffectivePermissionE(connection, namespaceID, token, descriptor, action)
+	require.NoError(t, err)
+	require.Equal(t, expected, permission.State, "Effective %s for %s on %s was decided at %q by %v", action, descriptor, token, permission.Token, permission.Descriptors)
+	return permission
+}
+
+// RequirePermissionNotAllowed asserts the action is not effectively allowed (Deny or NotSet)
+func RequirePermissionNotAllowed(t testing.TB, connection *azuredevops.Connection, namespaceID, token, descriptor, action string) {
+	t.Helper()
+
+	permission, err := ResolveEffectivePermissionE(connection, namespaceID, token, descriptor, action)
+	require.NoError(t, err)
+	require.NotEqual(t, PermissionAllow, permission.State, "%s is allowed %s on %s through %q by %v", descriptor, action, token, permission.Token, permission.Descriptors)
+}
@@ -0,0 +1,271 @@
+// Package adoentitlement reads Azure DevOps user, group (group rule) and service principal
+// entitlements back and compares them with the entitlement a fixture configures.
+package adoentitlement
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/licensing"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/licensingrule"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/memberentitlementmanagement"
+	"github.com/stretchr/testify/require"
+)
+
+const requestTimeout = 2 * time.Minute
+
+// ExpectedEntitlement is the entitlement input the REST representation must reflect
+type ExpectedEntitlement struct {
+	// AccountLicenseType uses the module values; "basic" is an alias of "express"
+	AccountLicenseType string
+	LicensingSource    string
+	// MsdnLicenseType is only checked when set, e.g. "enterprise" for VS Enterprise subscribers
+	MsdnLicenseType string
+	// OriginID and PrincipalName identify the graph subject; empty values are not checked
+	OriginID      string
+	PrincipalName string
+	// ProjectEntitlements maps project IDs to the project group type (projectContributor, ...).
+	// nil skips the check, an empty map requires no project entitlements.
+	ProjectEntitlements map[string]string
+	// AllowOtherProjects ignores entitlements for projects not in ProjectEntitlements, for
+	// principals that may already belong to other projects of the organization
+	AllowOtherProjects bool
+}
+
+// GetUserEntitlementE retrieves a user entitlement by ID
+func GetUserEntitlementE(connection *azuredevops.Connection, entitlementID string) (*memberentitlementmanagement.UserEntitlement, error) {
+	id, err := uuid.Parse(entitlementID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user entitlement ID %q: %w", entitlementID, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	client, err := memberentitlementmanagement.NewClient(ctx, connection)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetUserEntitlement(ctx, memberentitlementmanagement.GetUserEntitlementArgs{UserId: &id})
+}
+
+// GetGroupEntitlementE retrieves a group entitlement (group rule) by ID
+func GetGroupEntitlementE(connection *azuredevops.Connection, entitlementID string) (*memberentitlementmanagement.GroupEntitlement, error) {
+	id, err := uuid.Parse(entitlementID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid group entitlement ID %q: %w", entitlementID, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	client, err := memberentitlementmanagement.NewClient(ctx, connection)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetGroupEntitlement(ctx, memberentitlementmanagement.GetGroupEntitlementArgs{GroupId: &id})
+}
+
+// GetServicePrincipalEntitlementE retrieves a service principal entitlement by ID
+func GetServicePrincipalEntitlementE(connection *azuredevops.Connection, entitlementID string) (*memberentitlementmanagement.ServicePrincipalEntitlement, error) {
+	id, err := uuid.Parse(entitlementID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service principal entitlement ID %q: %w", entitlementID, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	client, err := memberentitlementmanagement.NewClient(ctx, connection)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetServicePrincipalEntitlement(ctx, memberentitlementmanagement.GetServicePrincipalEntitlementArgs{ServicePrincipalId: &id})
+}
+
+// RequireUserEntitlement reads the user entitlement back and compares it with the input
+func RequireUserEntitlement(t testing.TB, connection *azuredevops.Connection, entitlementID string, expected ExpectedEntitlement) *memberentitlementmanagement.UserEntitlement {
+	t.Helper()
+
+	entitlement, err := GetUserEntitlementE(connection, entitlementID)
+	require.NoError(t, err, "Failed to get user entitlement %s", entitlementID)
+	require.NotNil(t, entitlement, "User entitlement %s not found", entitlementID)
+
+	problems := CompareAccessLevel(entitlement.AccessLevel, expected)
+	problems = append(problems, CompareProjectEntitlements(entitlement.ProjectEntitlements, expected.ProjectEntitlements, expected.AllowOtherProjects)...)
+	if entitlement.User == nil {
+		problems = append(problems, "user is not set")
+	} else {
+		problems = append(problems, compareSubject(entitlement.User.OriginId, entitlement.User.PrincipalName, expected)...)
+	}
+	requireNoEntitlementProblems(t, "user", entitlementID, problems)
+	return entitlement
+}
+
+// RequireGroupEntitlement reads the group rule back and compares its license rule and project entitlements with the input
+func RequireGroupEntitlement(t testing.TB, connection *azuredevops.Connection, entitlementID string, expected ExpectedEntitlement) *memberentitlementmanagement.GroupEntitlement {
+	t.Helper()
+
+	entitlement, err := GetGroupEntitlementE(connection, entitlementID)
+	require.NoError(t, err, "Failed to get group entitlement %s", entitlementID)
+	require.NotNil(t, entitlement, "Group entitlement %s not found", entitlementID)
+
+	requireNoEntitlementProblems(t, "group", entitlementID, CompareGroupRule(entitlement, expected))
+	return entitlement
+}
+
+// RequireServicePrincipalEntitlement reads the service principal entitlement back and compares it with the input
+func RequireServicePrincipalEntitlement(t testing.TB, connection *azuredevops.Connection, entitlementID string, expected ExpectedEntitlement) *memberentitlementmanagement.ServicePrincipalEntitlement {
+	t.Helper()
+
+	entitlement, err := GetServicePrincipalEntitlementE(connection, entitlementID)
+	require.NoError(t, err, "Failed to get service principal entitlement %s", entitlementID)
+	require.NotNil(t, entitlement, "Service principal entitlement %s not found", entitlementID)
+
+	problems := CompareAccessLevel(entitlement.AccessLevel, expected)
+	problems = append(problems, CompareProjectEntitlements(entitlement.ProjectEntitlements, expected.ProjectEntitlements, expected.AllowOtherProjects)...)
+	if entitlement.ServicePrincipal == nil {
+		problems = append(problems, "service principal is not set")
+	} else {
+		problems = append(problems, compareSubject(entitlement.ServicePrincipal.OriginId, entitlement.ServicePrincipal.PrincipalName, expected)...)
+	}
+	requireNoEntitlementProblems(t, "service principal", entitlementID, problems)
+	return entitlement
+}
+
+// CompareAccessLevel reports differences in license type, licensing source and MSDN license
+func CompareAccessLevel(level *licensing.AccessLevel, expected ExpectedEntitlement) []string {
+	if level == nil {
+		return []string{"access level is not set"}
+	}
+
+	var problems []string
+	actualType := ""
+	if level.AccountLicenseType != nil {
+		actualType = string(*level.AccountLicenseType)
+	}
+	if !strings.EqualFold(actualType, NormalizeAccountLicenseType(expected.AccountLicenseType)) {
+		problems = append(problems, fmt.Sprintf("account license type is %q, expected %q", actualType, NormalizeAccountLicenseType(expected.AccountLicenseType)))
+	}
+	if expected.LicensingSource != "" {
+		actualSource := ""
+		if level.LicensingSource != nil {
+			actualSource = string(*level.LicensingSource)
+		}
+		if !strings.EqualFold(actualSource, expected.LicensingSource) {
+			problems = append(problems, fmt.Sprintf("licensing source is %q, expected %q", actualSource, expected.LicensingSource))
+		}
+	}
+	if expected.MsdnLicenseType != "" {
+		actualMsdn := ""
+		if level.MsdnLicenseType != nil {
+			actualMsdn = string(*level.MsdnLicenseType)
+		}
+		if !strings.EqualFold(actualMsdn, expected.MsdnLicenseType) {
+			problems = append(problems, fmt.Sprintf("msdn license type is %q, expected %q", actualMsdn, expected.MsdnLicenseType))
+		}
+	}
+	return problems
+}
+
+// CompareProjectEntitlements reports projects missing, assigned to another project group or,
+// unless allowOthers is set, not expected
+func CompareProjectEntitlements(entitlements *[]memberentitlementmanagement.ProjectEntitlement, expected map[string]string, allowOthers bool) []string {
+	if expected == nil {
+		return nil
+	}
+
+	actual := map[string]string{}
+	if entitlements != nil {
+		for _, entitlement := range *entitlements {
+			if entitlement.ProjectRef == nil || entitlement.ProjectRef.Id == nil {
+				continue
+			}
+			groupType := ""
+			if entitlement.Group != nil && entitlement.Group.GroupType != nil {
+				groupType = string(*entitlement.Group.GroupType)
+			}
+			actual[strings.ToLower(entitlement.ProjectRef.Id.String())] = groupType
+		}
+	}
+
+	var problems []string
+	for projectID, groupType := range expected {
+		actualType, ok := actual[strings.ToLower(projectID)]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("project entitlement for %s is missing", projectID))
+		case !strings.EqualFold(actualType, groupType):
+			problems = append(problems, fmt.Sprintf("project %s group is %q, expected %q", projectID, actualType, groupType))
+		}
+		delete(actual, strings.ToLower(projectID))
+	}
+	if allowOthers {
+		actual = nil
+	}
+	for projectID, groupType := range actual {
+		problems = append(problems, fmt.Sprintf("unexpected project entitlement for %s (%s)", projectID, groupType))
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// CompareGroupRule reports differences in the group rule license, status, subject and project entitlements
+func CompareGroupRule(entitlement *memberentitlementmanagement.GroupEntitlement, expected ExpectedEntitlement) []string {
+	var problems []string
+	if entitlement.LicenseRule == nil {
+		problems = append(problems, "license rule is not set")
+	} else {
+		problems = append(problems, CompareAccessLevel(entitlement.LicenseRule, expected)...)
+	}
+	if entitlement.Status != nil && *entitlement.Status != licensingrule.GroupLicensingRuleStatusValues.Applied && *entitlement.Status != licensingrule.GroupLicensingRuleStatusValues.ApplyPending {
+		problems = append(problems, fmt.Sprintf("group rule status is %q", *entitlement.Status))
+	}
+	problems = append(problems, CompareProjectEntitlements(entitlement.ProjectEntitlements, expected.ProjectEntitlements, expected.AllowOtherProjects)...)
+	if entitlement.Group == nil {
+		problems = append(problems, "group is not set")
+	} else {
+		problems = append(problems, compareSubject(entitlement.Group.OriginId, entitlement.Group.PrincipalName, expected)...)
+	}
+	return problems
+}
+
+// NormalizeAccountLicenseType maps module license values to the values returned by the API
+func NormalizeAccountLicenseType(licenseType string) string {
+	if strings.EqualFold(licenseType, "basic") {
+		return string(licensing.AccountLicenseTypeValues.Express)
+	}
+	return licenseType
+}
+
+func compareSubject(originID, principalName *string, expected ExpectedEntitlement) []string {
+	var problems []string
+	if expected.OriginID != "" && (originID == nil || !strings.EqualFold(*originID, expected.OriginID)) {
+		problems = append(problems, fmt.Sprintf("origin ID is %q, expected %q", stringValue(originID), expected.OriginID))
+	}
+	if expected.PrincipalName != "" && (principalName == nil || !strings.EqualFold(*principalName, expected.PrincipalName)) {
+		problems = append(problems, fmt.Sprintf("principal name is %q, expected %q", stringValue(principalName), expected.PrincipalName))
+	}
+	return problems
+}
+
+func requireNoEntitlementProblems(t testing.TB, kind, entitlementID string, problems []string) {
+	t.Helper()
+
+	if len(problems) > 0 {
+		require.FailNow(t, fmt.Sprintf("The %s entitlement does not match the fixture", kind), "entitlement %s:\n  %s", entitlementID, strings.Join(problems, "\n  "))
+	}
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
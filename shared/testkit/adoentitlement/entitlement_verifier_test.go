@@ -0,0 +1,101 @@
+package adoentitlement
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/licensing"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/licensingrule"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/memberentitlementmanagement"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testPlatformProjectID = "0b6a2c47-8f3e-4d1a-b5c9-7e2f1a3d4c58"
+	testSharedProjectID   = "9d4e3b21-6a7c-4f58-8e1d-3c2b5a6f7e90"
+)
+
+func loadTestUserEntitlement(t *testing.T) *memberentitlementmanagement.UserEntitlement {
+	content, err := os.ReadFile(filepath.Join("testdata", "user_entitlement.json"))
+	require.NoError(t, err)
+
+	var entitlement memberentitlementmanagement.UserEntitlement
+	require.NoError(t, json.Unmarshal(content, &entitlement))
+	return &entitlement
+}
+
+func TestCompareAccessLevelTreatsBasicAsExpress(t *testing.T) {
+	entitlement := loadTestUserEntitlement(t)
+
+	assert.Empty(t, CompareAccessLevel(entitlement.AccessLevel, ExpectedEntitlement{AccountLicenseType: "basic", LicensingSource: "account"}))
+	assert.Empty(t, CompareAccessLevel(entitlement.AccessLevel, ExpectedEntitlement{AccountLicenseType: "express", MsdnLicenseType: "none"}))
+}
+
+func TestCompareAccessLevelDifferences(t *testing.T) {
+	entitlement := loadTestUserEntitlement(t)
+
+	problems := CompareAccessLevel(entitlement.AccessLevel, ExpectedEntitlement{
+		AccountLicenseType: "stakeholder",
+		LicensingSource:    "msdn",
+		MsdnLicenseType:    "enterprise",
+	})
+	assert.ElementsMatch(t, []string{
+		`account license type is "express", expected "stakeholder"`,
+		`licensing source is "account", expected "msdn"`,
+		`msdn license type is "none", expected "enterprise"`,
+	}, problems)
+	assert.Equal(t, []string{"access level is not set"}, CompareAccessLevel(nil, ExpectedEntitlement{AccountLicenseType: "basic"}))
+}
+
+func TestCompareProjectEntitlements(t *testing.T) {
+	entitlements := loadTestUserEntitlement(t).ProjectEntitlements
+
+	assert.Empty(t, CompareProjectEntitlements(entitlements, nil, false))
+	assert.Empty(t, CompareProjectEntitlements(entitlements, map[string]string{
+		testPlatformProjectID: "projectContributor",
+		testSharedProjectID:   "projectReader",
+	}, false))
+
+	problems := CompareProjectEntitlements(entitlements, map[string]string{
+		testPlatformProjectID:                  "projectAdministrator",
+		"4c7d1e2f-0a3b-4c5d-8e9f-1a2b3c4d5e6f": "projectReader",
+	}, false)
+	assert.ElementsMatch(t, []string{
+		"project entitlement for 4c7d1e2f-0a3b-4c5d-8e9f-1a2b3c4d5e6f is missing",
+		`project 0b6a2c47-8f3e-4d1a-b5c9-7e2f1a3d4c58 group is "projectContributor", expected "projectAdministrator"`,
+		"unexpected project entitlement for 9d4e3b21-6a7c-4f58-8e1d-3c2b5a6f7e90 (projectReader)",
+	}, problems)
+
+	// Principals that already belong to other projects only need the fixture's project
+	assert.Empty(t, CompareProjectEntitlements(entitlements, map[string]string{testPlatformProjectID: "projectContributor"}, true))
+	assert.Equal(t, []string{"project entitlement for 4c7d1e2f-0a3b-4c5d-8e9f-1a2b3c4d5e6f is missing"},
+		CompareProjectEntitlements(entitlements, map[string]string{"4c7d1e2f-0a3b-4c5d-8e9f-1a2b3c4d5e6f": "projectReader"}, true))
+}
+
+func TestCompareGroupRule(t *testing.T) {
+	licenseType := licensing.AccountLicenseTypeValues.Professional
+	source := licensing.LicensingSourceValues.Account
+	status := licensingrule.GroupLicensingRuleStatusValues.ApplyPending
+	entitlement := &memberentitlementmanagement.GroupEntitlement{
+		LicenseRule: &licensing.AccessLevel{AccountLicenseType: &licenseType, LicensingSource: &source},
+		Status:      &status,
+	}
+
+	problems := CompareGroupRule(entitlement, ExpectedEntitlement{AccountLicenseType: "professional", LicensingSource: "account"})
+	assert.Equal(t, []string{"group is not set"}, problems)
+
+	entitlement.LicenseRule = nil
+	problems = CompareGroupRule(entitlement, ExpectedEntitlement{AccountLicenseType: "professional"})
+	assert.Contains(t, problems, "license rule is not set")
+}
+
+func TestCompareSubject(t *testing.T) {
+	user := loadTestUserEntitlement(t).User
+
+	assert.Empty(t, compareSubject(user.OriginId, user.PrincipalName, ExpectedEntitlement{PrincipalName: "Jane.Doe@contoso.com"}))
+	assert.Equal(t, []string{`origin ID is "e2b7c1a4-5d3f-4e8a-9b6c-1f0a2d3e4c5b", expected "00000000-0000-0000-0000-000000000000"`},
+		compareSubject(user.OriginId, user.PrincipalName, ExpectedEntitlement{OriginID: "00000000-0000-0000-0000-000000000000"}))
+}
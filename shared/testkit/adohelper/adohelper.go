@@ -0,0 +1,369 @@
+// Package adohelper reads Azure DevOps state through the REST API so validate stages can
+// compare what was applied with the fixture inputs.
+package adohelper
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/build"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/core"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/feed"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/serviceendpoint"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/taskagent"
+	"github.com/stretchr/testify/require"
+)
+
+// RequestTimeout bounds a single Azure DevOps REST call
+const RequestTimeout = 2 * time.Minute
+
+// Helper wraps an authenticated connection and the clients created from it. It is safe
+// for concurrent use, so parallel subtests can share one helper.
+type Helper struct {
+	connection *azuredevops.Connection
+
+	mu                    sync.Mutex
+	coreClient            core.Client
+	gitClient             git.Client
+	buildClient           build.Client
+	taskAgentClient       taskagent.Client
+	serviceEndpointClient serviceendpoint.Client
+	feedClient            feed.Client
+}
+
+// New creates a helper authenticated with AZDO_ORG_SERVICE_URL and AZDO_PERSONAL_ACCESS_TOKEN
+func New(t testing.TB) *Helper {
+	t.Helper()
+
+	organizationURL := os.Getenv("AZDO_ORG_SERVICE_URL")
+	require.NotEmpty(t, organizationURL, "AZDO_ORG_SERVICE_URL environment variable must be set")
+
+	token := os.Getenv("AZDO_PERSONAL_ACCESS_TOKEN")
+	require.NotEmpty(t, token, "AZDO_PERSONAL_ACCESS_TOKEN environment variable must be set")
+
+	return &Helper{
+		connection: azuredevops.NewPatConnection(strings.TrimRight(organizationURL, "/"), token),
+	}
+}
+
+// Connection exposes the authenticated connection for clients the helper does not wrap
+func (h *Helper) Connection() *azuredevops.Connection {
+	return h.connection
+}
+
+// GetProjectE retrieves a project by ID or name
+func (h *Helper) GetProjectE(projectID string) (*core.TeamProject, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+	defer cancel()
+
+	client, err := h.Core(ctx)
+	if err != nil {
+		return nil, err
+	}
+	includeCapabilities := true
+	return client.GetProject(ctx, core.GetProjectArgs{
+		ProjectId:           &projectID,
+		IncludeCapabilities: &includeCapabilities,
+	})
+}
+
+// GetProject retrieves a project by ID or name
+func (h *Helper) GetProject(t testing.TB, projectID string) *core.TeamProject {
+	t.Helper()
+
+	project, err := h.GetProjectE(projectID)
+	require.NoError(t, err, "Failed to get Azure DevOps project %s", projectID)
+	return project
+}
+
+// GetTeamE retrieves a team by ID or name
+func (h *Helper) GetTeamE(projectID, teamID string) (*core.WebApiTeam, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+	defer cancel()
+
+	client, err := h.Core(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetTeam(ctx, core.GetTeamArgs{ProjectId: &projectID, TeamId: &teamID})
+}
+
+// GetTeam retrieves a team by ID or name
+func (h *Helper) GetTeam(t testing.TB, projectID, teamID string) *core.WebApiTeam {
+	t.Helper()
+
+	team, err := h.GetTeamE(projectID, teamID)
+	require.NoError(t, err, "Failed to get Azure DevOps team %s", teamID)
+	return team
+}
+
+// GetRepositoryE retrieves a Git repository by ID or name
+func (h *Helper) GetRepositoryE(projectID, repositoryID string) (*git.GitRepository, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+	defer cancel()
+
+	client, err := h.Git(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetRepository(ctx, git.GetRepositoryArgs{Project: &projectID, RepositoryId: &repositoryID})
+}
+
+// GetRepository retrieves a Git repository by ID or name
+func (h *Helper) GetRepository(t testing.TB, projectID, repositoryID string) *git.GitRepository {
+	t.Helper()
+
+	repository, err := h.GetRepositoryE(projectID, repositoryID)
+	require.NoError(t, err, "Failed to get Azure DevOps repository %s", repositoryID)
+	return repository
+}
+
+// GetBuildDefinitionE retrieves a build (pipeline) definition
+func (h *Helper) GetBuildDefinitionE(projectID string, definitionID int) (*build.BuildDefinition, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+	defer cancel()
+
+	client, err := h.Build(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetDefinition(ctx, build.GetDefinitionArgs{Project: &projectID, DefinitionId: &definitionID})
+}
+
+// GetBuildDefinition retrieves a build (pipeline) definition
+func (h *Helper) GetBuildDefinition(t testing.TB, projectID string, definitionID int) *build.BuildDefinition {
+	t.Helper()
+
+	definition, err := h.GetBuildDefinitionE(projectID, definitionID)
+	require.NoError(t, err, "Failed to get Azure DevOps build definition %d", definitionID)
+	return definition
+}
+
+// GetVariableGroupE retrieves a variable group
+func (h *Helper) GetVariableGroupE(projectID string, groupID int) (*taskagent.VariableGroup, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+	defer cancel()
+
+	client, err := h.TaskAgent(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetVariableGroup(ctx, taskagent.GetVariableGroupArgs{Project: &projectID, GroupId: &groupID})
+}
+
+// GetVariableGroup retrieves a variable group
+func (h *Helper) GetVariableGroup(t testing.TB, projectID string, groupID int) *taskagent.VariableGroup {
+	t.Helper()
+
+	group, err := h.GetVariableGroupE(projectID, groupID)
+	require.NoError(t, err, "Failed to get Azure DevOps variable group %d", groupID)
+	require.NotNil(t, group, "Variable group %d not found", groupID)
+	return group
+}
+
+// GetEnvironmentE retrieves a pipeline environment
+func (h *Helper) GetEnvironmentE(projectID string, environmentID int) (*taskagent.EnvironmentInstance, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+	defer cancel()
+
+	client, err := h.TaskAgent(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetEnvironmentById(ctx, taskagent.GetEnvironmentByIdArgs{
+		Project:       &projectID,
+		EnvironmentId: &environmentID,
+		Expands:       &taskagent.EnvironmentExpandsValues.ResourceReferences,
+	})
+}
+
+// GetEnvironment retrieves a pipeline environment
+func (h *Helper) GetEnvironment(t testing.TB, projectID string, environmentID int) *taskagent.EnvironmentInstance {
+	t.Helper()
+
+	environment, err := h.GetEnvironmentE(projectID, environmentID)
+	require.NoError(t, err, "Failed to get Azure DevOps environment %d", environmentID)
+	return environment
+}
+
+// GetServiceEndpointE retrieves a service endpoint (service connection)
+func (h *Helper) GetServiceEndpointE(projectID, endpointID string) (*serviceendpoint.ServiceEndpoint, error) {
+	id, err := uuid.Parse(endpointID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service endpoint ID %q: %w", endpointID, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+	defer cancel()
+
+	client, err := h.ServiceEndpoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetServiceEndpointDetails(ctx, serviceendpoint.GetServiceEndpointDetailsArgs{Project: &projectID, EndpointId: &id})
+}
+
+// GetServiceEndpoint retrieves a service endpoint (service connection)
+func (h *Helper) GetServiceEndpoint(t testing.TB, projectID, endpointID string) *serviceendpoint.ServiceEndpoint {
+	t.Helper()
+
+	endpoint, err := h.GetServiceEndpointE(projectID, endpointID)
+	require.NoError(t, err, "Failed to get Azure DevOps service endpoint %s", endpointID)
+	require.NotNil(t, endpoint, "Service endpoint %s not found", endpointID)
+	return endpoint
+}
+
+// GetFeedE retrieves an Artifacts feed; projectID may be empty for organization-scoped feeds
+func (h *Helper) GetFeedE(projectID, feedID string) (*feed.Feed, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), RequestTimeout)
+	defer cancel()
+
+	client, err := h.Feed(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	args := feed.GetFeedArgs{FeedId: &feedID}
+	if projectID != "" {
+		args.Project = &projectID
+	}
+	return client.GetFeed(ctx, args)
+}
+
+// GetFeed retrieves an Artifacts feed; projectID may be empty for organization-scoped feeds
+func (h *Helper) GetFeed(t testing.TB, projectID, feedID string) *feed.Feed {
+	t.Helper()
+
+	result, err := h.GetFeedE(projectID, feedID)
+	require.NoError(t, err, "Failed to get Azure DevOps feed %s", feedID)
+	return result
+}
+
+// VariableGroupValue returns a variable's value and whether it is secret; secret values are never returned by the API
+func VariableGroupValue(group *taskagent.VariableGroup, name string) (value string, isSecret bool, found bool) {
+	if group == nil || group.Variables == nil {
+		return "", false, false
+	}
+	raw, ok := (*group.Variables)[name]
+	if !ok {
+		return "", false, false
+	}
+	fields, ok := raw.(map[string]interface{})
+	if !ok {
+		return "", false, true
+	}
+	value, _ = fields["value"].(string)
+	isSecret, _ = fields["isSecret"].(bool)
+	return value, isSecret, true
+}
+
+// ParseIntID converts a numeric Terraform ID output (definitions, groups, environments) to int
+func ParseIntID(t testing.TB, value string) int {
+	t.Helper()
+
+	id, err := strconv.Atoi(strings.TrimSpace(value))
+	require.NoError(t, err, "Failed to parse Azure DevOps ID %q as int", value)
+	return id
+}
+
+// Clients are created on first use because each one resolves its resource area over the network.
+// Suites use them for API calls the helper does not wrap.
+
+// Core returns the core client
+func (h *Helper) Core(ctx context.Context) (core.Client, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.coreClient == nil {
+		client, err := core.NewClient(ctx, h.connection)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Azure DevOps core client: %w", err)
+		}
+		h.coreClient = client
+	}
+	return h.coreClient, nil
+}
+
+// Git returns the git client
+func (h *Helper) Git(ctx context.Context) (git.Client, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.gitClient == nil {
+		client, err := git.NewClient(ctx, h.connection)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Azure DevOps git client: %w", err)
+		}
+		h.gitClient = client
+	}
+	return h.gitClient, nil
+}
+
+// Build returns the build client
+func (h *Helper) Build(ctx context.Context) (build.Client, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.buildClient == nil {
+		client, err := build.NewClient(ctx, h.connection)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Azure DevOps build client: %w", err)
+		}
+		h.buildClient = client
+	}
+	return h.buildClient, nil
+}
+
+// TaskAgent returns the task agent client
+func (h *Helper) TaskAgent(ctx context.Context) (taskagent.Client, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.taskAgentClient == nil {
+		client, err := taskagent.NewClient(ctx, h.connection)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Azure DevOps task agent client: %w", err)
+		}
+		h.taskAgentClient = client
+	}
+	return h.taskAgentClient, nil
+}
+
+// ServiceEndpoint returns the service endpoint client
+func (h *Helper) ServiceEndpoint(ctx context.Context) (serviceendpoint.Client, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.serviceEndpointClient == nil {
+		client, err := serviceendpoint.NewClient(ctx, h.connection)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Azure DevOps service endpoint client: %w", err)
+		}
+		h.serviceEndpointClient = client
+	}
+	return h.serviceEndpointClient, nil
+}
+
+// Feed returns the feed client
+func (h *Helper) Feed(ctx context.Context) (feed.Client, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.feedClient == nil {
+		client, err := feed.NewClient(ctx, h.connection)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Azure DevOps feed client: %w", err)
+		}
+		h.feedClient = client
+	}
+	return h.feedClient, nil
+}
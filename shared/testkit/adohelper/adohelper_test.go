@@ -0,0 +1,90 @@
+package adohelper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/taskagent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClientsAreCreatedOnce shares one helper between parallel callers, as parallel subtests do
+func TestClientsAreCreatedOnce(t *testing.T) {
+	t.Parallel()
+
+	var resourceAreaLookups atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodOptions {
+			_, _ = w.Write([]byte(`{"count":1,"value":[{"id":"e81700f7-3be2-46de-8624-2eb35882fcaa","area":"Location","resourceName":"ResourceAreas",
+				"routeTemplate":"_apis/{resource}/{areaId}","resourceVersion":1,"minVersion":"1.0","maxVersion":"7.1","releasedVersion":"0.0"}]}`))
+			return
+		}
+		if strings.EqualFold(r.URL.Path, "/_apis/ResourceAreas") {
+			resourceAreaLookups.Add(1)
+		}
+		// An empty list makes the clients use the organization URL, as on premises servers do
+		_, _ = w.Write([]byte(`{"count":0,"value":[]}`))
+	}))
+	defer server.Close()
+
+	helper := &Helper{connection: azuredevops.NewPatConnection(server.URL, "fake-token")}
+	clients := make([]git.Client, 16)
+	var wg sync.WaitGroup
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			client, err := helper.Git(context.Background())
+			assert.NoError(t, err)
+			clients[i] = client
+		}(i)
+	}
+	wg.Wait()
+
+	for _, client := range clients {
+		assert.Same(t, clients[0], client)
+	}
+	assert.EqualValues(t, 1, resourceAreaLookups.Load(), "the git client resolves its resource area once")
+}
+
+func TestVariableGroupValue(t *testing.T) {
+	t.Parallel()
+
+	group := &taskagent.VariableGroup{Variables: &map[string]interface{}{
+		"plain":  map[string]interface{}{"value": "value-1"},
+		"secret": map[string]interface{}{"isSecret": true},
+		"odd":    "not an object",
+	}}
+
+	value, isSecret, found := VariableGroupValue(group, "plain")
+	assert.Equal(t, "value-1", value)
+	assert.False(t, isSecret)
+	assert.True(t, found)
+
+	value, isSecret, found = VariableGroupValue(group, "secret")
+	assert.Empty(t, value, "secret values are never returned")
+	assert.True(t, isSecret)
+	assert.True(t, found)
+
+	_, _, found = VariableGroupValue(group, "odd")
+	assert.True(t, found)
+	_, _, found = VariableGroupValue(group, "missing")
+	assert.False(t, found)
+	_, _, found = VariableGroupValue(nil, "plain")
+	assert.False(t, found)
+}
+
+func TestParseIntID(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, 42, ParseIntID(t, " 42\n"))
+}
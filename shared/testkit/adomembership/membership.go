@@ -0,0 +1,148 @@
+// Package adomembership reads Azure DevOps group and team memberships back and compares them
+// with the members a fixture configures.
+package adomembership
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/core"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/graph"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/webapi"
+	"github.com/stretchr/testify/require"
+)
+
+const requestTimeout = 2 * time.Minute
+
+// ListGroupMemberDescriptorsE returns the descriptors of the direct members of a group
+func ListGroupMemberDescriptorsE(connection *azuredevops.Connection, groupDescriptor string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	client, err := graph.NewClient(ctx, connection)
+	if err != nil {
+		return nil, err
+	}
+	depth := 1
+	memberships, err := client.ListMemberships(ctx, graph.ListMembershipsArgs{
+		SubjectDescriptor: &groupDescriptor,
+		Direction:         &graph.GraphTraversalDirectionValues.Down,
+		Depth:             &depth,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var descriptors []string
+	if memberships != nil {
+		for _, membership := range *memberships {
+			if membership.MemberDescriptor != nil {
+				descriptors = append(descriptors, *membership.MemberDescriptor)
+			}
+		}
+	}
+	return descriptors, nil
+}
+
+// GetTeamMembersE returns the members of a team with their administrator flag
+func GetTeamMembersE(connection *azuredevops.Connection, projectID, teamID string) ([]webapi.TeamMember, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	client, err := core.NewClient(ctx, connection)
+	if err != nil {
+		return nil, err
+	}
+	members, err := client.GetTeamMembersWithExtendedProperties(ctx, core.GetTeamMembersWithExtendedPropertiesArgs{
+		ProjectId: &projectID,
+		TeamId:    &teamID,
+	})
+	if err != nil || members == nil {
+		return nil, err
+	}
+	return *members, nil
+}
+
+// RequireGroupMembers asserts the group's direct members. With exact set, members that are
+// not expected are reported as leftovers.
+func RequireGroupMembers(t testing.TB, connection *azuredevops.Connection, groupDescriptor string, expected []string, exact bool) {
+	t.Helper()
+
+	actual, err := ListGroupMemberDescriptorsE(connection, groupDescriptor)
+	require.NoError(t, err, "Failed to list members of %s", groupDescriptor)
+	if problems := CompareMembers("member", actual, expected, exact); len(problems) > 0 {
+		require.FailNow(t, "Group membership does not match the fixture", "group %s:\n  %s", groupDescriptor, strings.Join(problems, "\n  "))
+	}
+}
+
+// RequireTeamMembership asserts that team administrators and the remaining team members match the inputs exactly
+func RequireTeamMembership(t testing.TB, connection *azuredevops.Connection, projectID, teamID string, expectedMembers, expectedAdministrators []string) {
+	t.Helper()
+
+	members, err := GetTeamMembersE(connection, projectID, teamID)
+	require.NoError(t, err, "Failed to list members of team %s", teamID)
+	if problems := CompareTeamMembership(members, expectedMembers, expectedAdministrators); len(problems) > 0 {
+		require.FailNow(t, "Team membership does not match the fixture", "team %s:\n  %s", teamID, strings.Join(problems, "\n  "))
+	}
+}
+
+// CompareMembers reports expected descriptors that are missing and, when exact, leftovers that were not expected
+func CompareMembers(kind string, actual, expected []string, exact bool) []string {
+	present := map[string]bool{}
+	for _, descriptor := range actual {
+		present[descriptor] = true
+	}
+
+	var problems []string
+	wanted := map[string]bool{}
+	for _, descriptor := range expected {
+		wanted[descriptor] = true
+		if !present[descriptor] {
+			problems = append(problems, fmt.Sprintf("%s %s is missing", kind, descriptor))
+		}
+	}
+	if exact {
+		for _, descriptor := range actual {
+			if !wanted[descriptor] {
+				problems = append(problems, fmt.Sprintf("%s %s is a leftover", kind, descriptor))
+			}
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// CompareTeamMembership compares administrators and non-administrator members separately, both exactly
+func CompareTeamMembership(members []webapi.TeamMember, expectedMembers, expectedAdministrators []string) []string {
+	var administrators, regular []string
+	for _, member := range members {
+		if member.Identity == nil || member.Identity.Descriptor == nil {
+			continue
+		}
+		if member.IsTeamAdmin != nil && *member.IsTeamAdmin {
+			administrators = append(administrators, *member.Identity.Descriptor)
+		} else {
+			regular = append(regular, *member.Identity.Descriptor)
+		}
+	}
+
+	// Administrators are team members as well, so they may be listed in team_members without a finding
+	isAdministrator := map[string]bool{}
+	for _, descriptor := range administrators {
+		isAdministrator[descriptor] = true
+	}
+	var expectedRegular []string
+	for _, descriptor := range expectedMembers {
+		if !isAdministrator[descriptor] {
+			expectedRegular = append(expectedRegular, descriptor)
+		}
+	}
+
+	problems := CompareMembers("administrator", administrators, expectedAdministrators, true)
+	return append(problems, CompareMembers("member", regular, expectedRegular, true)...)
+}
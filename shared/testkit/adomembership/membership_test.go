@@ -0,0 +1,54 @@
+package adomembership
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/webapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testProjectAdministrators = "vssgp.Uy0xLTktMTU1MTM3NDI0NS0xMDAwMDAwMDAx"
+	testReaders               = "vssgp.Uy0xLTktMTU1MTM3NDI0NS0xMDAwMDAwMDAz"
+	testContributors          = "vssgp.Uy0xLTktMTU1MTM3NDI0NS0xMDAwMDAwMDA0"
+	testUserDescriptor        = "aad.ZTJiN2MxYTQtNWQzZi00ZThhLTliNmMtMWYwYTJkM2U0YzVi"
+)
+
+func loadTestTeamMembers(t *testing.T) []webapi.TeamMember {
+	content, err := os.ReadFile(filepath.Join("testdata", "team_members.json"))
+	require.NoError(t, err)
+
+	var response struct {
+		Value []webapi.TeamMember `json:"value"`
+	}
+	require.NoError(t, json.Unmarshal(content, &response))
+	return response.Value
+}
+
+func TestCompareMembersReportsLeftovers(t *testing.T) {
+	actual := []string{testReaders, testContributors}
+
+	assert.Empty(t, CompareMembers("member", actual, []string{testReaders}, false))
+	assert.Equal(t, []string{"member " + testContributors + " is a leftover"}, CompareMembers("member", actual, []string{testReaders}, true))
+	assert.Equal(t, []string{"member " + testProjectAdministrators + " is missing"}, CompareMembers("member", actual, []string{testProjectAdministrators, testReaders, testContributors}, true))
+}
+
+func TestCompareTeamMembership(t *testing.T) {
+	members := loadTestTeamMembers(t)
+
+	// The team creator stays an administrator until team_administrators overwrites it
+	problems := CompareTeamMembership(members, []string{testReaders, testContributors}, []string{testProjectAdministrators})
+	assert.Equal(t, []string{"administrator " + testUserDescriptor + " is a leftover"}, problems)
+
+	// Administrators may also be listed as members
+	assert.Empty(t, CompareTeamMembership(members,
+		[]string{testReaders, testContributors, testProjectAdministrators},
+		[]string{testProjectAdministrators, testUserDescriptor}))
+
+	problems = CompareTeamMembership(members, []string{testReaders}, []string{testProjectAdministrators, testUserDescriptor})
+	assert.Equal(t, []string{"member " + testContributors + " is a leftover"}, problems)
+}
@@ -0,0 +1,569 @@
+// Package cassette records the Azure SDK traffic, Terraform outputs and unique name suffixes of a test and replays
+// them offline, so the SDK assertions of a suite run without Azure or Terraform.
+package cassette
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ModeEnv selects how tests talk to Azure: record, replay or live (default)
+	ModeEnv = "AZURE_CASSETTE_MODE"
+	Record  = "record"
+	Replay  = "replay"
+	Live    = "live"
+
+	// Dir holds one cassette per test, named after the test
+	Dir = "testdata/cassettes"
+
+	// SubscriptionID replaces real subscription IDs in recorded traffic
+	SubscriptionID = "00000000-0000-0000-0000-000000000000"
+	// TenantID replaces tenant IDs in recorded bodies
+	TenantID = "00000000-0000-0000-0000-000000000000"
+
+	redactedValue = "REDACTED"
+)
+
+var (
+	subscriptionPathPattern = regexp.MustCompile(`(?i)(/subscriptions/)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+	sasSignaturePattern     = regexp.MustCompile(`(?i)([?&]sig=)[^&"\s]+`)
+	sensitiveFieldPattern   = regexp.MustCompile(`(?i)^(keys|kubeconfigs?|.*(password|secret|token|connectionstring|accesskey|primarykey|secondarykey|sharedkey|privatekey|signature).*)$`)
+	tenantFieldPattern      = regexp.MustCompile(`(?i)^tenantid$`)
+	cassetteNamePattern     = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+	// Response headers kept in cassettes; everything else, including request headers, is dropped
+	cassetteResponseHeaders = []string{"Content-Type", "Location", "Azure-AsyncOperation", "Operation-Location"}
+
+	activeCassettes   = map[string]*Cassette{}
+	activeCassettesMu sync.Mutex
+)
+
+// Interaction is one recorded request and its response
+type Interaction struct {
+	Request  Request  `yaml:"request"`
+	Response Response `yaml:"response"`
+}
+
+// Request is the sanitized part of a request used for matching
+type Request struct {
+	Method string `yaml:"method"`
+	URL    string `yaml:"url"`
+	Body   string `yaml:"body,omitempty"`
+}
+
+// Response is a sanitized response served in replay mode
+type Response struct {
+	Status  int               `yaml:"status"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Body    string            `yaml:"body,omitempty"`
+}
+
+// RecordedOutput is one Terraform output read by the test, a string or a list of strings
+type RecordedOutput struct {
+	Name  string      `yaml:"name"`
+	Value interface{} `yaml:"value"`
+}
+
+// Cassette records or replays the traffic of one test. It implements policy.Transporter so it plugs into the
+// arm.ClientOptions of SDK clients. Names derived from random.UniqueId and Terraform outputs are recorded next to
+// the interactions, so replayed requests address the same resources as the recording.
+type Cassette struct {
+	Path         string            `yaml:"-"`
+	Mode         string            `yaml:"-"`
+	UniqueIDs    []string          `yaml:"unique_ids,omitempty"`
+	Outputs      []*RecordedOutput `yaml:"outputs,omitempty"`
+	Interactions []*Interaction    `yaml:"interactions"`
+
+	inner        policy.Transporter
+	mu           sync.Mutex
+	used         []bool
+	usedOutputs  []bool
+	nextUniqueID int
+	unexpected   []string
+}
+
+// Mode returns the mode selected by AZURE_CASSETTE_MODE
+func Mode() string {
+	switch mode := strings.ToLower(os.Getenv(ModeEnv)); mode {
+	case Record, Replay:
+		return mode
+	default:
+		return Live
+	}
+}
+
+// Path returns the cassette file of the named test
+func Path(testName string) string {
+	return filepath.Join(Dir, cassetteNamePattern.ReplaceAllString(testName, "_")+".yaml")
+}
+
+// NewRecorder returns a cassette that forwards requests to inner and records them
+func NewRecorder(path string, inner policy.Transporter) *Cassette {
+	return &Cassette{Path: path, Mode: Record, inner: inner}
+}
+
+// LoadE reads a cassette for replay
+func LoadE(path string) (*Cassette, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cassette := &Cassette{Path: path, Mode: Replay}
+	if err := yaml.Unmarshal(content, cassette); err != nil {
+		return nil, fmt.Errorf("parsing cassette %s: %w", path, err)
+	}
+	cassette.used = make([]bool, len(cassette.Interactions))
+	cassette.usedOutputs = make([]bool, len(cassette.Outputs))
+	return cassette, nil
+}
+
+// Do records or replays a single request
+func (c *Cassette) Do(req *http.Request) (*http.Response, error) {
+	body, err := readAndRestore(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	request := Request{Method: req.Method, URL: ScrubURL(req.URL.String()), Body: string(ScrubBody(body))}
+
+	if c.Mode == Replay {
+		return c.replay(req, request), nil
+	}
+
+	resp, err := c.inner.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := readAndRestore(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	headers := map[string]string{}
+	for _, name := range cassetteResponseHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			headers[name] = ScrubURL(value)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Interactions = append(c.Interactions, &Interaction{
+		Request:  request,
+		Response: Response{Status: resp.StatusCode, Headers: headers, Body: string(ScrubBody(respBody))},
+	})
+	return resp, nil
+}
+
+// replay serves the first unused interaction with the same method and URL.
+// Unexpected requests get a 501 so the SDK fails fast instead of retrying.
+func (c *Cassette) replay(req *http.Request, request Request) *http.Response {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, interaction := range c.Interactions {
+		if c.used[i] || interaction.Request.Method != request.Method || normalizeURL(interaction.Request.URL) != normalizeURL(request.URL) {
+			continue
+		}
+		c.used[i] = true
+		header := http.Header{}
+		for name, value := range interaction.Response.Headers {
+			header.Set(name, value)
+		}
+		return newResponse(req, interaction.Response.Status, header, interaction.Response.Body)
+	}
+
+	c.unexpected = append(c.unexpected, request.Method+" "+request.URL)
+	body := fmt.Sprintf(`{"error":{"code":"CassetteMismatch","message":"no recorded interaction for %s %s in %s"}}`, request.Method, request.URL, c.Path)
+	return newResponse(req, http.StatusNotImplemented, http.Header{"Content-Type": {"application/json"}}, body)
+}
+
+// NextUniqueID records the generated ID, or returns the next recorded one in replay mode
+func (c *Cassette) NextUniqueID(generate func() string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.Mode != Replay {
+		id := generate()
+		c.UniqueIDs = append(c.UniqueIDs, id)
+		return id, nil
+	}
+	if c.nextUniqueID >= len(c.UniqueIDs) {
+		return "", fmt.Errorf("cassette %s holds %d unique IDs; the test asked for more", c.Path, len(c.UniqueIDs))
+	}
+	c.nextUniqueID++
+	return c.UniqueIDs[c.nextUniqueID-1], nil
+}
+
+// RecordOutput stores an output value with subscription IDs and SAS signatures scrubbed
+func (c *Cassette) RecordOutput(name string, value interface{}) {
+	switch typed := value.(type) {
+	case string:
+		value = ScrubURL(typed)
+	case []string:
+		scrubbed := make([]string, len(typed))
+		for i, item := range typed {
+			scrubbed[i] = ScrubURL(item)
+		}
+		value = scrubbed
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Outputs = append(c.Outputs, &RecordedOutput{Name: name, Value: value})
+}
+
+// ReplayOutput serves the first unused recording of the named output
+func (c *Cassette) ReplayOutput(name string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, output := range c.Outputs {
+		if c.usedOutputs[i] || output.Name != name {
+			continue
+		}
+		c.usedOutputs[i] = true
+		return output.Value, nil
+	}
+	return nil, fmt.Errorf("no recorded output %q in %s", name, c.Path)
+}
+
+// Mismatches lists requests that were not recorded and recorded requests that were never made
+func (c *Cassette) Mismatches() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var mismatches []string
+	for _, request := range c.unexpected {
+		mismatches = append(mismatches, "unexpected request "+request)
+	}
+	for i, interaction := range c.Interactions {
+		if c.Mode == Replay && !c.used[i] {
+			mismatches = append(mismatches, "missing request "+interaction.Request.Method+" "+interaction.Request.URL)
+		}
+	}
+	return mismatches
+}
+
+// Empty reports whether the cassette holds neither interactions nor outputs
+func (c *Cassette) Empty() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.Interactions) == 0 && len(c.Outputs) == 0
+}
+
+// SaveE writes the recording to the cassette file
+func (c *Cassette) SaveE() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	content, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.Path, content, 0o644)
+}
+
+// Use returns the cassette of the running test, or nil in live mode.
+// All helpers of one test share the cassette; it is saved (record) or checked
+// for mismatches (replay) when the test finishes. Tests without a recorded
+// cassette are skipped in replay mode. Tests that made no SDK requests and read
+// no outputs through the cassette save none, so they skip on replay too.
+func Use(t testing.TB) *Cassette {
+	mode := Mode()
+	if mode == Live {
+		return nil
+	}
+
+	activeCassettesMu.Lock()
+	defer activeCassettesMu.Unlock()
+	if cassette, ok := activeCassettes[t.Name()]; ok {
+		return cassette
+	}
+
+	path := Path(t.Name())
+	var cassette *Cassette
+	if mode == Record {
+		cassette = NewRecorder(path, http.DefaultClient)
+	} else {
+		loaded, err := LoadE(path)
+		if os.IsNotExist(err) {
+			t.Skipf("No cassette recorded at %s; run with %s=%s against Azure first", path, ModeEnv, Record)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		cassette = loaded
+	}
+	activeCassettes[t.Name()] = cassette
+
+	t.Cleanup(func() {
+		activeCassettesMu.Lock()
+		delete(activeCassettes, t.Name())
+		activeCassettesMu.Unlock()
+
+		if cassette.Mode == Replay {
+			for _, mismatch := range cassette.Mismatches() {
+				t.Errorf("cassette %s: %s", cassette.Path, mismatch)
+			}
+			return
+		}
+		if t.Failed() {
+			t.Logf("Test failed; not saving cassette %s", cassette.Path)
+			return
+		}
+		if cassette.Empty() {
+			t.Logf("Nothing to replay; not saving cassette %s", cassette.Path)
+			return
+		}
+		if err := cassette.SaveE(); err != nil {
+			t.Errorf("saving cassette %s: %v", cassette.Path, err)
+		}
+	})
+	return cassette
+}
+
+// UniqueID returns generate() in live mode. Record mode stores every generated
+// ID in the test's cassette and replay mode hands them out again in the same
+// order, so names built from random.UniqueId match the recorded requests.
+func UniqueID(t testing.TB, generate func() string) string {
+	cassette := Use(t)
+	if cassette == nil {
+		return generate()
+	}
+	id, err := cassette.NextUniqueID(generate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// RunLiveStage runs a test_structure stage that needs Terraform or the deployed
+// resources themselves, such as deploy, cleanup, import or data plane checks.
+// Replay mode skips it; the SDK assertions of the other stages run against the
+// cassette instead.
+func RunLiveStage(t testing.TB, stageName string, stage func()) {
+	t.Helper()
+	if Mode() == Replay {
+		t.Logf("Replaying from cassette; skipping stage %s", stageName)
+		return
+	}
+	test_structure.RunTestStage(t, stageName, stage)
+}
+
+// LoadTerraformOptions loads the options saved by the deploy stage. The deploy
+// stage does not run on replay, so replay returns bare options for the folder.
+func LoadTerraformOptions(t testing.TB, testFolder string) *terraform.Options {
+	if Mode() == Replay {
+		return &terraform.Options{TerraformDir: testFolder, Vars: map[string]interface{}{}, NoColor: true}
+	}
+	return test_structure.LoadTerraformOptions(t, testFolder)
+}
+
+// Output reads a Terraform output, recording it in record mode and serving the
+// recorded value in replay mode
+func Output(t testing.TB, terraformOptions *terraform.Options, name string) string {
+	cassette := Use(t)
+	if cassette == nil {
+		return terraform.Output(t, terraformOptions, name)
+	}
+	if cassette.Mode == Record {
+		value := terraform.Output(t, terraformOptions, name)
+		cassette.RecordOutput(name, value)
+		return value
+	}
+
+	value, err := cassette.ReplayOutput(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, ok := value.(string)
+	if !ok {
+		t.Fatalf("recorded output %q in %s is not a string", name, cassette.Path)
+	}
+	return text
+}
+
+// OutputList is Output for list outputs
+func OutputList(t testing.TB, terraformOptions *terraform.Options, name string) []string {
+	cassette := Use(t)
+	if cassette == nil {
+		return terraform.OutputList(t, terraformOptions, name)
+	}
+	if cassette.Mode == Record {
+		value := terraform.OutputList(t, terraformOptions, name)
+		cassette.RecordOutput(name, value)
+		return value
+	}
+
+	value, err := cassette.ReplayOutput(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		t.Fatalf("recorded output %q in %s is not a list", name, cassette.Path)
+	}
+	list := make([]string, len(items))
+	for i, item := range items {
+		list[i] = fmt.Sprint(item)
+	}
+	return list
+}
+
+// ClientOptions returns client options routing SDK traffic through the
+// test's cassette, or nil (SDK defaults) in live mode
+func ClientOptions(t testing.TB) *arm.ClientOptions {
+	cassette := Use(t)
+	if cassette == nil {
+		return nil
+	}
+	options := &arm.ClientOptions{ClientOptions: policy.ClientOptions{Transport: cassette}}
+	if cassette.Mode == Replay {
+		// Recorded throttling and server errors replay without waiting
+		options.Retry = policy.RetryOptions{RetryDelay: time.Millisecond, MaxRetryDelay: time.Millisecond}
+	}
+	return options
+}
+
+// Session returns the subscription, credential and client options for an SDK
+// helper. Replay needs no Azure environment, so the live values are only
+// resolved outside replay mode.
+func Session(t testing.TB, subscriptionID func() string, credential func() azcore.TokenCredential) (string, azcore.TokenCredential, *arm.ClientOptions) {
+	options := ClientOptions(t)
+	if Mode() == Replay {
+		return SubscriptionID, replayCredential{}, options
+	}
+	return subscriptionID(), credential(), options
+}
+
+// ScrubURL replaces subscription IDs and SAS signatures in a URL
+func ScrubURL(rawURL string) string {
+	scrubbed := subscriptionPathPattern.ReplaceAllString(rawURL, "${1}"+SubscriptionID)
+	return sasSignaturePattern.ReplaceAllString(scrubbed, "${1}"+redactedValue)
+}
+
+// ScrubBody redacts keys, secrets, tokens and tenant IDs in JSON bodies and
+// subscription IDs and SAS signatures in any body
+func ScrubBody(body []byte) []byte {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err == nil {
+		var scrubbed bytes.Buffer
+		encoder := json.NewEncoder(&scrubbed)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(scrubJSON(document)); err == nil {
+			body = bytes.TrimSuffix(scrubbed.Bytes(), []byte("\n"))
+		}
+	}
+	return []byte(ScrubURL(string(body)))
+}
+
+func scrubJSON(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, field := range typed {
+			switch {
+			case sensitiveFieldPattern.MatchString(key):
+				typed[key] = redactJSON(field)
+			case tenantFieldPattern.MatchString(key):
+				if _, ok := field.(string); ok {
+					typed[key] = TenantID
+				}
+			default:
+				typed[key] = scrubJSON(field)
+			}
+		}
+	case []interface{}:
+		for i, item := range typed {
+			typed[i] = scrubJSON(item)
+		}
+	}
+	return value
+}
+
+func redactJSON(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case string:
+		return redactedValue
+	case map[string]interface{}:
+		for key, field := range typed {
+			typed[key] = redactJSON(field)
+		}
+	case []interface{}:
+		for i, item := range typed {
+			typed[i] = redactJSON(item)
+		}
+	}
+	return value
+}
+
+// normalizeURL sorts query parameters so their order does not affect matching
+func normalizeURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	parsed.RawQuery = parsed.Query().Encode()
+	return strings.ToLower(parsed.Scheme+"://"+parsed.Host+parsed.EscapedPath()) + "?" + parsed.RawQuery
+}
+
+func readAndRestore(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+	content, err := io.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, err
+	}
+	*body = io.NopCloser(bytes.NewReader(content))
+	return content, nil
+}
+
+func newResponse(req *http.Request, status int, header http.Header, body string) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// replayCredential satisfies the bearer token policy in replay mode
+type replayCredential struct{}
+
+func (replayCredential) GetToken(context.Context, policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: redactedValue, ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
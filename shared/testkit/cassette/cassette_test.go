@@ -0,0 +1,175 @@
+package cassette
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/storage/armstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const fakeSubscriptionID = "0b1f6471-1bf0-4dda-aec3-cb9272f09590"
+
+// TestCassetteRecordAndReplay records traffic from a fake ARM endpoint and replays it offline
+func TestCassetteRecordAndReplay(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Ms-Correlation-Request-Id", "dropped-header")
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = w.Write([]byte(`{"name":"sttest","identity":{"type":"SystemAssigned","tenantId":"72f988bf-86f1-41af-91ab-2d7cd011db47"},
+				"properties":{"provisioningState":"Succeeded","primaryEndpoints":{"blob":"https://sttest.blob.core.windows.net/?sv=2022&sig=c2VjcmV0"}}}`))
+		case http.MethodPost:
+			_, _ = w.Write([]byte(`{"keys":[{"keyName":"key1","value":"c2VjcmV0LWtleS0x","permissions":"FULL"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), Path(t.Name()))
+	recorder := NewRecorder(path, server.Client())
+	client := newFakeAccountsClient(t, server.URL, recorder)
+
+	account, err := client.GetProperties(context.Background(), "rg-test", "sttest", nil)
+	require.NoError(t, err)
+	keys, err := client.ListKeys(context.Background(), "rg-test", "sttest", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "c2VjcmV0LWtleS0x", *keys.Keys[0].Value, "recording does not alter live responses")
+	require.NoError(t, recorder.SaveE())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	for _, secret := range []string{fakeSubscriptionID, "c2VjcmV0LWtleS0x", "c2VjcmV0", "72f988bf", "Authorization", "dropped-header"} {
+		assert.NotContains(t, string(content), secret)
+	}
+	assert.Contains(t, string(content), "/subscriptions/"+SubscriptionID+"/resourceGroups/rg-test")
+
+	// Replay needs neither the server nor the real subscription
+	server.Close()
+	player, err := LoadE(path)
+	require.NoError(t, err)
+	client = newFakeAccountsClient(t, server.URL, player)
+
+	replayed, err := client.GetProperties(context.Background(), "rg-test", "sttest", nil)
+	require.NoError(t, err)
+	assert.Equal(t, *account.Name, *replayed.Name)
+	assert.Equal(t, armstorage.ProvisioningStateSucceeded, *replayed.Properties.ProvisioningState)
+	assert.Equal(t, TenantID, *replayed.Identity.TenantID)
+	keys, err = client.ListKeys(context.Background(), "rg-test", "sttest", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "REDACTED", *keys.Keys[0].Value)
+	assert.Empty(t, player.Mismatches())
+}
+
+func TestCassetteMismatches(t *testing.T) {
+	t.Parallel()
+
+	player, err := LoadE(Path(t.Name()))
+	require.NoError(t, err)
+	client := newFakeAccountsClient(t, "https://management.azure.com", player)
+
+	_, err = client.GetProperties(context.Background(), "rg-test-sa-x7k2p", "sttestx7k2p", nil)
+	require.NoError(t, err)
+	_, err = client.GetProperties(context.Background(), "rg-test-sa-x7k2p", "sttestx7k2p", nil)
+	require.Error(t, err, "each recorded interaction is served once")
+	assert.Contains(t, err.Error(), "CassetteMismatch")
+
+	mismatches := player.Mismatches()
+	require.Len(t, mismatches, 2)
+	assert.Contains(t, mismatches[0], "unexpected request GET https://management.azure.com/subscriptions/"+SubscriptionID)
+	assert.Contains(t, mismatches[1], "missing request GET")
+	assert.Contains(t, mismatches[1], "/blobServices/default")
+}
+
+// TestUniqueIDsAndOutputsReplay replays the name suffixes and outputs of a recording in order
+func TestUniqueIDsAndOutputsReplay(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), Path(t.Name()))
+	recorder := NewRecorder(path, http.DefaultClient)
+	assert.True(t, recorder.Empty())
+
+	generated := []string{"x7k2p", "q9w3z"}
+	for _, want := range generated {
+		want := want
+		id, err := recorder.NextUniqueID(func() string { return want })
+		require.NoError(t, err)
+		assert.Equal(t, want, id)
+	}
+	assert.True(t, recorder.Empty(), "unique IDs alone are not worth replaying")
+	recorder.RecordOutput("storage_account_id", "/subscriptions/"+fakeSubscriptionID+"/resourceGroups/rg-test-sa-x7k2p")
+	recorder.RecordOutput("container_names", []string{"data", "logs"})
+	recorder.RecordOutput("storage_account_id", "/subscriptions/"+fakeSubscriptionID+"/resourceGroups/rg-test-sa-q9w3z")
+	require.NoError(t, recorder.SaveE())
+
+	player, err := LoadE(path)
+	require.NoError(t, err)
+	for _, want := range generated {
+		id, err := player.NextUniqueID(func() string { return "not-recorded" })
+		require.NoError(t, err)
+		assert.Equal(t, want, id)
+	}
+	_, err = player.NextUniqueID(func() string { return "not-recorded" })
+	assert.Error(t, err, "replay never generates new names")
+
+	first, err := player.ReplayOutput("storage_account_id")
+	require.NoError(t, err)
+	assert.Equal(t, "/subscriptions/"+SubscriptionID+"/resourceGroups/rg-test-sa-x7k2p", first)
+	second, err := player.ReplayOutput("storage_account_id")
+	require.NoError(t, err)
+	assert.Equal(t, "/subscriptions/"+SubscriptionID+"/resourceGroups/rg-test-sa-q9w3z", second, "outputs read after an update replay in order")
+	containers, err := player.ReplayOutput("container_names")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"data", "logs"}, containers)
+	_, err = player.ReplayOutput("storage_account_id")
+	assert.Error(t, err)
+}
+
+func TestRunLiveStageSkipsOnReplay(t *testing.T) {
+	ran := map[string]bool{}
+	for _, mode := range []string{Live, Record, Replay} {
+		t.Setenv(ModeEnv, mode)
+		RunLiveStage(t, "deploy", func() { ran[mode] = true })
+	}
+	assert.Equal(t, map[string]bool{Live: true, Record: true}, ran)
+
+	t.Setenv("SKIP_deploy", "true")
+	t.Setenv(ModeEnv, Live)
+	RunLiveStage(t, "deploy", func() { t.Fatal("SKIP_<stage> still skips the stage") })
+}
+
+func TestScrubBody(t *testing.T) {
+	t.Parallel()
+
+	scrubbed := string(ScrubBody([]byte(`{"properties":{"administratorLoginPassword":"P@ssw0rd!","encryption":{"keySource":"Microsoft.Storage"},
+		"primaryConnectionString":"DefaultEndpointsProtocol=https;AccountKey=abc==","kubeconfigs":[{"name":"clusterUser","value":"YXBpVmVyc2lvbjogdjE="}]}}`)))
+	for _, secret := range []string{"P@ssw0rd!", "AccountKey=abc==", "YXBpVmVyc2lvbjogdjE="} {
+		assert.NotContains(t, scrubbed, secret)
+	}
+	assert.Contains(t, scrubbed, `"keySource": "Microsoft.Storage"`, "non-secret fields are kept")
+	assert.Equal(t, "not json /subscriptions/"+SubscriptionID+"/x", string(ScrubBody([]byte("not json /subscriptions/"+fakeSubscriptionID+"/x"))))
+	assert.Nil(t, ScrubBody(nil))
+}
+
+func newFakeAccountsClient(t *testing.T, endpoint string, transport policy.Transporter) *armstorage.AccountsClient {
+	t.Helper()
+	options := &arm.ClientOptions{ClientOptions: policy.ClientOptions{
+		Cloud: cloud.Configuration{Services: map[cloud.ServiceName]cloud.ServiceConfiguration{
+			cloud.ResourceManager: {Audience: "https://management.core.windows.net/", Endpoint: endpoint},
+		}},
+		Transport: transport,
+		Retry:     policy.RetryOptions{MaxRetries: -1},
+	}}
+	client, err := armstorage.NewAccountsClient(fakeSubscriptionID, replayCredential{}, options)
+	require.NoError(t, err)
+	return client
+}
@@ -0,0 +1,526 @@
+// Package compliance evaluates the declarative rules under security-policies/compliance against
+// `terraform show -json` output of saved plans and of the state.
+package compliance
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// PolicyDirEnv overrides the directory the policy rules are loaded from
+	PolicyDirEnv = "COMPLIANCE_POLICY_DIR"
+	// FailSeverityEnv sets the lowest severity that fails a compliance test
+	FailSeverityEnv = "COMPLIANCE_FAIL_SEVERITY"
+	// DefaultPolicyDir is relative to a module's tests directory and to this package
+	DefaultPolicyDir = "../../../security-policies/compliance"
+)
+
+// Severity ranks rule violations
+type Severity string
+
+const (
+	SeverityLow      Severity = "low"
+	SeverityMedium   Severity = "medium"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+)
+
+var severityRanks = map[Severity]int{SeverityLow: 1, SeverityMedium: 2, SeverityHigh: 3, SeverityCritical: 4}
+
+// AtLeast reports whether s is as severe as other
+func (s Severity) AtLeast(other Severity) bool {
+	return severityRanks[s] >= severityRanks[other]
+}
+
+// Policy operators
+const (
+	OperatorEquals    = "equals"
+	OperatorNotEquals = "not_equals"
+	OperatorIn        = "in"
+	OperatorNotIn     = "not_in"
+	OperatorExists    = "exists"
+	OperatorAbsent    = "absent"
+	OperatorNotEmpty  = "not_empty"
+	OperatorHasKeys   = "has_keys"
+	OperatorMatches   = "matches"
+	OperatorGTE       = "gte"
+	OperatorLTE       = "lte"
+)
+
+var operators = map[string]bool{
+	OperatorEquals: true, OperatorNotEquals: true, OperatorIn: true, OperatorNotIn: true,
+	OperatorExists: true, OperatorAbsent: true, OperatorNotEmpty: true, OperatorHasKeys: true,
+	OperatorMatches: true, OperatorGTE: true, OperatorLTE: true,
+}
+
+// Condition compares the attribute at Path with Value. Paths are dot
+// separated; numbers index lists and * applies the condition to every element.
+type Condition struct {
+	Path       string      `yaml:"path"`
+	Operator   string      `yaml:"operator"`
+	Value      interface{} `yaml:"value,omitempty"`
+	IgnoreCase bool        `yaml:"ignore_case,omitempty"`
+}
+
+// Rule checks one attribute of every resource of ResourceType, and the attributes
+// of And. The rule only applies to resources matching all When conditions.
+type Rule struct {
+	ID           string   `yaml:"id"`
+	Name         string   `yaml:"name"`
+	Description  string   `yaml:"description,omitempty"`
+	ResourceType string   `yaml:"resource_type"`
+	Severity     Severity `yaml:"severity"`
+	Condition    `yaml:",inline"`
+	And          []Condition `yaml:"and,omitempty"`
+	When         []Condition `yaml:"when,omitempty"`
+}
+
+// Finding statuses
+const (
+	Pass          = "pass"
+	Fail          = "fail"
+	Unknown       = "unknown"
+	NotApplicable = "not_applicable"
+)
+
+// Finding is the result of one rule for one resource
+type Finding struct {
+	Rule    Rule
+	Address string
+	Status  string
+	Detail  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("[%s] %s %s on %s: %s", f.Rule.Severity, f.Rule.ID, f.Rule.Name, f.Address, f.Detail)
+}
+
+// Resource is a managed resource from a plan or state in `terraform show -json` format
+type Resource struct {
+	Address string
+	Type    string
+	Values  map[string]interface{}
+	// Unknown mirrors Values with true for attributes only known after apply
+	Unknown interface{}
+}
+
+// LoadRulesE reads every *.yaml rule file in dir
+func LoadRulesE(dir string) ([]Rule, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no policy rules in %s", dir)
+	}
+	sort.Strings(paths)
+
+	var rules []Rule
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var file struct {
+			Rules []Rule `yaml:"rules"`
+		}
+		if err := yaml.Unmarshal(content, &file); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, rule := range file.Rules {
+			if err := rule.validate(); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+		rules = append(rules, file.Rules...)
+	}
+	return rules, nil
+}
+
+func (r Rule) validate() error {
+	if r.ID == "" || r.ResourceType == "" || r.Path == "" {
+		return fmt.Errorf("rule %q needs id, resource_type and path", r.Name)
+	}
+	if _, ok := severityRanks[r.Severity]; !ok {
+		return fmt.Errorf("rule %s: unknown severity %q", r.ID, r.Severity)
+	}
+	for _, condition := range append(append([]Condition{r.Condition}, r.And...), r.When...) {
+		if !operators[condition.Operator] {
+			return fmt.Errorf("rule %s: unknown operator %q", r.ID, condition.Operator)
+		}
+		if condition.Operator == OperatorMatches {
+			if _, err := regexp.Compile(fmt.Sprint(condition.Value)); err != nil {
+				return fmt.Errorf("rule %s: %w", r.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// RulesFor returns the rules for the given resource types
+func RulesFor(rules []Rule, resourceTypes ...string) []Rule {
+	var selected []Rule
+	for _, rule := range rules {
+		for _, resourceType := range resourceTypes {
+			if rule.ResourceType == resourceType {
+				selected = append(selected, rule)
+			}
+		}
+	}
+	return selected
+}
+
+// ParseResourcesE extracts managed resources from `terraform show -json`
+// output of a saved plan (planned values, deletions excluded) or of the state
+func ParseResourcesE(showJSON []byte) ([]Resource, error) {
+	var show struct {
+		PlannedValues   json.RawMessage `json:"planned_values"`
+		ResourceChanges []struct {
+			Address string `json:"address"`
+			Mode    string `json:"mode"`
+			Type    string `json:"type"`
+			Change  struct {
+				After        map[string]interface{} `json:"after"`
+				AfterUnknown interface{}            `json:"after_unknown"`
+			} `json:"change"`
+		} `json:"resource_changes"`
+		Values *struct {
+			RootModule stateModule `json:"root_module"`
+		} `json:"values"`
+	}
+	if err := json.Unmarshal(showJSON, &show); err != nil {
+		return nil, err
+	}
+
+	var resources []Resource
+	if show.PlannedValues != nil {
+		for _, change := range show.ResourceChanges {
+			if change.Mode != "managed" || change.Change.After == nil {
+				continue
+			}
+			resources = append(resources, Resource{Address: change.Address, Type: change.Type, Values: change.Change.After, Unknown: change.Change.AfterUnknown})
+		}
+		return resources, nil
+	}
+	if show.Values == nil {
+		return nil, nil
+	}
+
+	var walk func(module stateModule)
+	walk = func(module stateModule) {
+		for _, resource := range module.Resources {
+			if resource.Mode == "managed" {
+				resources = append(resources, Resource{Address: resource.Address, Type: resource.Type, Values: resource.Values})
+			}
+		}
+		for _, child := range module.ChildModules {
+			walk(child)
+		}
+	}
+	walk(show.Values.RootModule)
+	return resources, nil
+}
+
+type stateModule struct {
+	Resources []struct {
+		Address string                 `json:"address"`
+		Mode    string                 `json:"mode"`
+		Type    string                 `json:"type"`
+		Values  map[string]interface{} `json:"values"`
+	} `json:"resources"`
+	ChildModules []stateModule `json:"child_modules"`
+}
+
+// Evaluate evaluates every rule against the resources of its type
+func Evaluate(rules []Rule, resources []Resource) []Finding {
+	var findings []Finding
+	for _, resource := range resources {
+		for _, rule := range rules {
+			if rule.ResourceType == resource.Type {
+				findings = append(findings, rule.Evaluate(resource))
+			}
+		}
+	}
+	return findings
+}
+
+// Evaluate applies the rule to one resource
+func (r Rule) Evaluate(resource Resource) Finding {
+	finding := Finding{Rule: r, Address: resource.Address}
+	for _, condition := range r.When {
+		status, detail := condition.evaluate(resource)
+		if status != Pass {
+			finding.Status, finding.Detail = NotApplicable, "when: "+detail
+			if status == Unknown {
+				finding.Status = Unknown
+			}
+			return finding
+		}
+	}
+	for _, condition := range append([]Condition{r.Condition}, r.And...) {
+		finding.Status, finding.Detail = condition.evaluate(resource)
+		if finding.Status != Pass {
+			return finding
+		}
+	}
+	return finding
+}
+
+type pathValue struct {
+	path    string
+	value   interface{}
+	missing bool
+	unknown bool
+}
+
+// resolve follows path through value and the matching after_unknown structure
+func resolve(path string, segments []string, value, unknown interface{}) []pathValue {
+	if known, ok := unknown.(bool); ok && known {
+		return []pathValue{{path: path, unknown: true}}
+	}
+	if len(segments) == 0 {
+		return []pathValue{{path: path, value: value, missing: value == nil}}
+	}
+
+	segment, rest := segments[0], segments[1:]
+	join := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + "." + key
+	}
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		unknownMap, _ := unknown.(map[string]interface{})
+		if segment == "*" {
+			keys := make([]string, 0, len(typed))
+			for key := range typed {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			var values []pathValue
+			for _, key := range keys {
+				values = append(values, resolve(join(key), rest, typed[key], unknownMap[key])...)
+			}
+			return values
+		}
+		return resolve(join(segment), rest, typed[segment], unknownMap[segment])
+	case []interface{}:
+		unknownList, _ := unknown.([]interface{})
+		unknownAt := func(index int) interface{} {
+			if index < len(unknownList) {
+				return unknownList[index]
+			}
+			return nil
+		}
+		if segment == "*" {
+			var values []pathValue
+			for index, element := range typed {
+				values = append(values, resolve(join(strconv.Itoa(index)), rest, element, unknownAt(index))...)
+			}
+			return values
+		}
+		index, err := strconv.Atoi(segment)
+		if err != nil || index < 0 || index >= len(typed) {
+			return []pathValue{{path: join(segment), missing: true}}
+		}
+		return resolve(join(segment), rest, typed[index], unknownAt(index))
+	default:
+		return []pathValue{{path: join(strings.Join(segments, ".")), missing: true}}
+	}
+}
+
+// evaluate returns pass, fail or unknown; a wildcard path passes when every element passes
+func (c Condition) evaluate(resource Resource) (string, string) {
+	for _, value := range resolve("", strings.Split(c.Path, "."), resource.Values, resource.Unknown) {
+		if value.unknown {
+			return Unknown, fmt.Sprintf("%s is known only after apply", value.path)
+		}
+		if ok, detail := c.check(value); !ok {
+			return Fail, detail
+		}
+	}
+	return Pass, fmt.Sprintf("%s %s", c.Path, c.Operator)
+}
+
+func (c Condition) check(value pathValue) (bool, string) {
+	switch c.Operator {
+	case OperatorExists:
+		return !value.missing, fmt.Sprintf("%s is missing", value.path)
+	case OperatorAbsent:
+		return value.missing, fmt.Sprintf("%s is set to %v", value.path, value.value)
+	}
+	if value.missing {
+		return false, fmt.Sprintf("%s is missing, want %s %v", value.path, c.Operator, c.Value)
+	}
+
+	failed := fmt.Sprintf("%s is %v, want %s %v", value.path, value.value, c.Operator, c.Value)
+	switch c.Operator {
+	case OperatorEquals:
+		return c.equal(value.value, c.Value), failed
+	case OperatorNotEquals:
+		return !c.equal(value.value, c.Value), failed
+	case OperatorIn, OperatorNotIn:
+		found := false
+		for _, expected := range toList(c.Value) {
+			found = found || c.equal(value.value, expected)
+		}
+		return found == (c.Operator == OperatorIn), failed
+	case OperatorNotEmpty:
+		return !isEmpty(value.value), fmt.Sprintf("%s is empty", value.path)
+	case OperatorHasKeys:
+		object, _ := value.value.(map[string]interface{})
+		var missing []string
+		for _, key := range toList(c.Value) {
+			if _, ok := object[fmt.Sprint(key)]; !ok {
+				missing = append(missing, fmt.Sprint(key))
+			}
+		}
+		return len(missing) == 0, fmt.Sprintf("%s is missing keys: %s", value.path, strings.Join(missing, ", "))
+	case OperatorMatches:
+		pattern := fmt.Sprint(c.Value)
+		if c.IgnoreCase {
+			pattern = "(?i)" + pattern
+		}
+		return regexp.MustCompile(pattern).MatchString(fmt.Sprint(value.value)), failed
+	case OperatorGTE, OperatorLTE:
+		actual, ok := toNumber(value.value)
+		expected, expectedOK := toNumber(c.Value)
+		if !ok || !expectedOK {
+			return false, failed
+		}
+		if c.Operator == OperatorGTE {
+			return actual >= expected, failed
+		}
+		return actual <= expected, failed
+	}
+	return false, fmt.Sprintf("unknown operator %q", c.Operator)
+}
+
+func (c Condition) equal(actual, expected interface{}) bool {
+	if actualNumber, ok := toNumber(actual); ok {
+		expectedNumber, expectedOK := toNumber(expected)
+		return expectedOK && actualNumber == expectedNumber
+	}
+	actualString, actualOK := actual.(string)
+	expectedString, expectedOK := expected.(string)
+	if actualOK && expectedOK {
+		if c.IgnoreCase {
+			return strings.EqualFold(actualString, expectedString)
+		}
+		return actualString == expectedString
+	}
+	return reflect.DeepEqual(actual, expected)
+}
+
+func toNumber(value interface{}) (float64, bool) {
+	switch number := value.(type) {
+	case int:
+		return float64(number), true
+	case int64:
+		return float64(number), true
+	case float64:
+		return number, true
+	}
+	return 0, false
+}
+
+func toList(value interface{}) []interface{} {
+	if list, ok := value.([]interface{}); ok {
+		return list
+	}
+	return []interface{}{value}
+}
+
+func isEmpty(value interface{}) bool {
+	switch typed := value.(type) {
+	case nil:
+		return true
+	case string:
+		return typed == ""
+	case []interface{}:
+		return len(typed) == 0
+	case map[string]interface{}:
+		return len(typed) == 0
+	}
+	return false
+}
+
+// FailSeverity returns COMPLIANCE_FAIL_SEVERITY or high
+func FailSeverity(t testing.TB) Severity {
+	t.Helper()
+
+	severity := Severity(strings.ToLower(os.Getenv(FailSeverityEnv)))
+	if severity == "" {
+		return SeverityHigh
+	}
+	_, ok := severityRanks[severity]
+	require.True(t, ok, "%s must be one of low, medium, high or critical", FailSeverityEnv)
+	return severity
+}
+
+// LoadRules loads the rules for the given resource types from COMPLIANCE_POLICY_DIR or security-policies/compliance
+func LoadRules(t testing.TB, resourceTypes ...string) []Rule {
+	t.Helper()
+
+	dir := os.Getenv(PolicyDirEnv)
+	if dir == "" {
+		dir = DefaultPolicyDir
+	}
+	rules, err := LoadRulesE(dir)
+	require.NoError(t, err, "Failed to load policy rules")
+	return RulesFor(rules, resourceTypes...)
+}
+
+// AssertShowJSON evaluates `terraform show -json` output and fails the
+// test for failed rules at or above COMPLIANCE_FAIL_SEVERITY; other findings are logged
+func AssertShowJSON(t testing.TB, showJSON []byte, resourceTypes ...string) []Finding {
+	t.Helper()
+
+	resources, err := ParseResourcesE(showJSON)
+	require.NoError(t, err, "Failed to parse terraform show output")
+	findings := Evaluate(LoadRules(t, resourceTypes...), resources)
+
+	failSeverity := FailSeverity(t)
+	for _, finding := range findings {
+		switch {
+		case finding.Status == Fail && finding.Rule.Severity.AtLeast(failSeverity):
+			t.Errorf("Policy violation %s", finding)
+		case finding.Status == Fail, finding.Status == Unknown:
+			t.Logf("Policy %s %s", finding.Status, finding)
+		}
+	}
+	return findings
+}
+
+// AssertPlan plans the fixture and evaluates the saved plan; nothing is applied
+func AssertPlan(t testing.TB, terraformOptions *terraform.Options, resourceTypes ...string) []Finding {
+	t.Helper()
+
+	planOptions := *terraformOptions
+	planOptions.PlanFilePath = filepath.Join(terraformOptions.TerraformDir, "compliance.tfplan")
+	showJSON := terraform.InitAndPlanAndShow(t, &planOptions)
+	return AssertShowJSON(t, []byte(showJSON), resourceTypes...)
+}
+
+// AssertState evaluates the applied state of the fixture
+func AssertState(t testing.TB, terraformOptions *terraform.Options, resourceTypes ...string) []Finding {
+	t.Helper()
+
+	showJSON, err := terraform.ShowE(t, terraformOptions)
+	require.NoError(t, err, "Failed to run terraform show")
+	return AssertShowJSON(t, []byte(showJSON), resourceTypes...)
+}
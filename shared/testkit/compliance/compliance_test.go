@@ -0,0 +1,93 @@
+package compliance
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRulesLoad(t *testing.T) {
+	t.Parallel()
+
+	rules, err := LoadRulesE(DefaultPolicyDir)
+	require.NoError(t, err)
+
+	ids := map[string]Rule{}
+	for _, rule := range rules {
+		_, duplicate := ids[rule.ID]
+		assert.False(t, duplicate, "duplicate rule ID %s", rule.ID)
+		ids[rule.ID] = rule
+	}
+	for _, checkov := range []string{"CKV_AZURE_CUSTOM_1", "CKV_AZURE_CUSTOM_2", "CKV_AZURE_CUSTOM_3"} {
+		require.Contains(t, ids, checkov, "checkov policy %s has a rule", checkov)
+		// Lower severities would only be logged at the default COMPLIANCE_FAIL_SEVERITY
+		assert.Equal(t, SeverityHigh, ids[checkov].Severity, "checkov policy %s fails compliance tests", checkov)
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bad.yaml"), []byte("rules:\n  - id: X\n    resource_type: azurerm_x\n    severity: high\n    path: a\n    operator: approximately\n"), 0o644))
+	_, err = LoadRulesE(dir)
+	assert.ErrorContains(t, err, `unknown operator "approximately"`)
+}
+
+func TestConditions(t *testing.T) {
+	t.Parallel()
+
+	resource := Resource{
+		Address: "azurerm_example.test",
+		Type:    "azurerm_example",
+		Values: map[string]interface{}{
+			"sku":      "Premium",
+			"capacity": float64(2),
+			"tags":     map[string]interface{}{"Environment": "PROD"},
+			"rules": []interface{}{
+				map[string]interface{}{"action": "Allow", "port": float64(443)},
+				map[string]interface{}{"action": "Allow", "port": float64(8443)},
+			},
+		},
+		Unknown: map[string]interface{}{"id": true, "identity": true},
+	}
+
+	for _, testCase := range []struct {
+		condition Condition
+		want      string
+	}{
+		{Condition{Path: "sku", Operator: OperatorEquals, Value: "Premium"}, Pass},
+		{Condition{Path: "sku", Operator: OperatorEquals, Value: "premium"}, Fail},
+		{Condition{Path: "sku", Operator: OperatorEquals, Value: "premium", IgnoreCase: true}, Pass},
+		{Condition{Path: "sku", Operator: OperatorNotIn, Value: []interface{}{"Basic", "Standard"}}, Pass},
+		{Condition{Path: "capacity", Operator: OperatorGTE, Value: 2}, Pass},
+		{Condition{Path: "capacity", Operator: OperatorLTE, Value: 1}, Fail},
+		{Condition{Path: "tags.Environment", Operator: OperatorMatches, Value: "^prod", IgnoreCase: true}, Pass},
+		{Condition{Path: "tags.Owner", Operator: OperatorExists}, Fail},
+		{Condition{Path: "tags.Owner", Operator: OperatorAbsent}, Pass},
+		{Condition{Path: "tags", Operator: OperatorHasKeys, Value: []interface{}{"Environment"}}, Pass},
+		{Condition{Path: "rules.*.action", Operator: OperatorEquals, Value: "Allow"}, Pass},
+		{Condition{Path: "rules.*.port", Operator: OperatorEquals, Value: 443}, Fail},
+		{Condition{Path: "rules.2.port", Operator: OperatorExists}, Fail},
+		{Condition{Path: "identity.0.type", Operator: OperatorEquals, Value: "SystemAssigned"}, Unknown},
+		{Condition{Path: "name", Operator: OperatorNotEmpty}, Fail},
+	} {
+		status, detail := testCase.condition.evaluate(resource)
+		assert.Equal(t, testCase.want, status, "%+v: %s", testCase.condition, detail)
+	}
+
+	rule := Rule{
+		ID: "EXAMPLE", ResourceType: "azurerm_example", Severity: SeverityMedium,
+		Condition: Condition{Path: "sku", Operator: OperatorEquals, Value: "Premium"},
+		When:      []Condition{{Path: "tags.Environment", Operator: OperatorIn, Value: []interface{}{"dev"}, IgnoreCase: true}},
+	}
+	assert.Equal(t, NotApplicable, rule.Evaluate(resource).Status)
+
+	rule.When = nil
+	assert.Equal(t, Pass, rule.Evaluate(resource).Status)
+	rule.And = []Condition{{Path: "tags.Owner", Operator: OperatorNotEmpty}}
+	finding := rule.Evaluate(resource)
+	assert.Equal(t, Fail, finding.Status, "every and condition must pass")
+	assert.Contains(t, finding.Detail, "tags.Owner is missing")
+	rule.And = []Condition{{Path: "identity.0.type", Operator: OperatorExists}}
+	assert.Equal(t, Unknown, rule.Evaluate(resource).Status)
+}
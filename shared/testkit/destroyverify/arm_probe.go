@@ -0,0 +1,267 @@
+package destroyverify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	providersAPIVersion      = "2021-04-01"
+	resourceGroupsAPIVersion = "2021-04-01"
+)
+
+// retryableStatusCodes are throttling and server errors; the pipeline retries them honouring Retry-After,
+// and a lookup that still gets one is retried at the next poll instead of failing the verification
+var retryableStatusCodes = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// armRetryOptions is the retry policy of the probe pipeline
+var armRetryOptions = policy.RetryOptions{
+	MaxRetries:    5,
+	RetryDelay:    2 * time.Second,
+	MaxRetryDelay: time.Minute,
+	StatusCodes:   retryableStatusCodes,
+}
+
+// softDeleteLookups point at the "deleted" collection of resource types that are retained after delete
+var softDeleteLookups = map[string]struct {
+	pathFormat string
+	apiVersion string
+}{
+	// /subscriptions/{sub}/providers/Microsoft.KeyVault/locations/{location}/deletedVaults/{name}
+	"microsoft.keyvault/vaults":      {"/subscriptions/%[1]s/providers/Microsoft.KeyVault/locations/%[2]s/deletedVaults/%[4]s", "2022-07-01"},
+	"microsoft.keyvault/managedhsms": {"/subscriptions/%[1]s/providers/Microsoft.KeyVault/locations/%[2]s/deletedManagedHSMs/%[4]s", "2023-07-01"},
+	// /subscriptions/{sub}/providers/Microsoft.CognitiveServices/locations/{location}/resourceGroups/{rg}/deletedAccounts/{name}
+	"microsoft.cognitiveservices/accounts": {"/subscriptions/%[1]s/providers/Microsoft.CognitiveServices/locations/%[2]s/resourceGroups/%[3]s/deletedAccounts/%[4]s", "2023-05-01"},
+}
+
+// ARMDeletionProbe looks resources up by ID with a generic ARM GET, resolving API versions from the resource provider
+type ARMDeletionProbe struct {
+	pipeline runtime.Pipeline
+	endpoint string
+
+	mu          sync.Mutex
+	apiVersions map[string]string
+}
+
+// NewAzureDestroyVerifier returns a DestroyVerifier that probes every ARM ID in the state with the default Azure credential
+func NewAzureDestroyVerifier(t testing.TB) *DestroyVerifier {
+	t.Helper()
+
+	credential, err := azidentity.NewDefaultAzureCredential(nil)
+	require.NoError(t, err, "Failed to create Azure credential for destroy verification")
+	probe, err := NewARMDeletionProbe(credential)
+	require.NoError(t, err, "Failed to create ARM client for destroy verification")
+
+	return &DestroyVerifier{DefaultProbe: probe.Probe, Timeout: DestroyVerifyTimeout(t)}
+}
+
+// NewARMDeletionProbe creates a probe for the public cloud
+func NewARMDeletionProbe(credential azcore.TokenCredential) (*ARMDeletionProbe, error) {
+	client, err := arm.NewClient("destroyverify", "v1.0.0", credential, &arm.ClientOptions{
+		ClientOptions: policy.ClientOptions{Retry: armRetryOptions},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newARMDeletionProbe(client.Pipeline(), client.Endpoint()), nil
+}
+
+func newARMDeletionProbe(pipeline runtime.Pipeline, endpoint string) *ARMDeletionProbe {
+	return &ARMDeletionProbe{pipeline: pipeline, endpoint: endpoint, apiVersions: map[string]string{}}
+}
+
+// Probe implements DeletionProbe: live resources and soft-deleted resources pending purge both count as existing
+func (p *ARMDeletionProbe) Probe(ctx context.Context, resource StateResource) (bool, string, error) {
+	if !IsARMResourceID(resource.ID) {
+		return false, "", ErrProbeNotApplicable
+	}
+	parsed, err := arm.ParseResourceID(resource.ID)
+	if err != nil {
+		return false, "", ErrProbeNotApplicable
+	}
+
+	apiVersion, err := p.apiVersion(ctx, parsed)
+	if err != nil {
+		return false, "", err
+	}
+	status, err := p.get(ctx, resource.ID, apiVersion)
+	if err != nil {
+		return false, "", err
+	}
+	if status != http.StatusNotFound {
+		return true, fmt.Sprintf("still exists (HTTP %d)", status), nil
+	}
+
+	path, softDeleteVersion, ok := SoftDeletedResourcePath(parsed, stateString(resource, "location"))
+	if !ok {
+		return false, "", nil
+	}
+	status, err = p.get(ctx, path, softDeleteVersion)
+	if err != nil {
+		return false, "", err
+	}
+	if status != http.StatusNotFound {
+		return true, fmt.Sprintf("soft-deleted and pending purge at %s", path), nil
+	}
+	return false, "", nil
+}
+
+// IsARMResourceID reports whether the ID is an ARM resource ID rather than a data-plane URL or a
+// provider-composed ID such as "<target>|<diagnostic setting name>"
+func IsARMResourceID(id string) bool {
+	return strings.HasPrefix(strings.ToLower(id), "/subscriptions/") && !strings.Contains(id, "|")
+}
+
+// SoftDeletedResourcePath returns the "deleted" lookup for resource types with soft delete
+func SoftDeletedResourcePath(id *arm.ResourceID, location string) (string, string, bool) {
+	lookup, ok := softDeleteLookups[strings.ToLower(id.ResourceType.String())]
+	if !ok || location == "" {
+		return "", "", false
+	}
+	return fmt.Sprintf(lookup.pathFormat, id.SubscriptionID, NormalizeLocation(location), id.ResourceGroupName, id.Name), lookup.apiVersion, true
+}
+
+// NormalizeLocation converts display names such as "West Europe" to ARM location names
+func NormalizeLocation(location string) string {
+	return strings.ToLower(strings.ReplaceAll(location, " ", ""))
+}
+
+// LatestStableAPIVersion picks the newest non-preview version, falling back to the newest preview
+func LatestStableAPIVersion(versions []string) string {
+	latest, latestPreview := "", ""
+	for _, version := range versions {
+		if strings.Contains(strings.ToLower(version), "preview") {
+			if version > latestPreview {
+				latestPreview = version
+			}
+			continue
+		}
+		if version > latest {
+			latest = version
+		}
+	}
+	if latest == "" {
+		return latestPreview
+	}
+	return latest
+}
+
+func (p *ARMDeletionProbe) apiVersion(ctx context.Context, id *arm.ResourceID) (string, error) {
+	namespace := id.ResourceType.Namespace
+	resourceType := strings.Join(id.ResourceType.Types, "/")
+	if strings.EqualFold(namespace, "Microsoft.Resources") {
+		return resourceGroupsAPIVersion, nil
+	}
+
+	key := strings.ToLower(namespace + "/" + resourceType)
+	p.mu.Lock()
+	version, ok := p.apiVersions[key]
+	p.mu.Unlock()
+	if ok {
+		return version, nil
+	}
+
+	response, err := p.do(ctx, "/subscriptions/"+id.SubscriptionID+"/providers/"+namespace, providersAPIVersion)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", statusError(response, "providers/"+namespace)
+	}
+	var provider struct {
+		ResourceTypes []struct {
+			ResourceType string   `json:"resourceType"`
+			APIVersions  []string `json:"apiVersions"`
+		} `json:"resourceTypes"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&provider); err != nil {
+		return "", err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, candidate := range provider.ResourceTypes {
+		p.apiVersions[strings.ToLower(namespace+"/"+candidate.ResourceType)] = LatestStableAPIVersion(candidate.APIVersions)
+	}
+	version, ok = p.apiVersions[key]
+	if !ok || version == "" {
+		return "", fmt.Errorf("no API version for %s/%s", namespace, resourceType)
+	}
+	return version, nil
+}
+
+// get returns the HTTP status of a GET on the path; 404 and 410 both mean the resource is gone
+func (p *ARMDeletionProbe) get(ctx context.Context, path, apiVersion string) (int, error) {
+	response, err := p.do(ctx, path, apiVersion)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode == http.StatusNotFound || response.StatusCode == http.StatusGone:
+		return http.StatusNotFound, nil
+	case response.StatusCode < 300:
+		return response.StatusCode, nil
+	default:
+		return 0, statusError(response, path)
+	}
+}
+
+func (p *ARMDeletionProbe) do(ctx context.Context, path, apiVersion string) (*http.Response, error) {
+	request, err := runtime.NewRequest(ctx, http.MethodGet, p.endpoint+path)
+	if err != nil {
+		return nil, err
+	}
+	query := request.Raw().URL.Query()
+	query.Set("api-version", apiVersion)
+	request.Raw().URL.RawQuery = query.Encode()
+
+	response, err := p.pipeline.Do(request)
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		// Network failures are retried by the pipeline first; whatever is left is retried at the next poll
+		return nil, &TransientProbeError{Err: err}
+	}
+	return response, err
+}
+
+// statusError describes a failed GET; throttling and server errors are transient
+func statusError(response *http.Response, path string) error {
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	_ = json.NewDecoder(response.Body).Decode(&body)
+	err := fmt.Errorf("GET %s returned %d %s", path, response.StatusCode, body.Error.Code)
+	for _, code := range retryableStatusCodes {
+		if response.StatusCode == code {
+			return &TransientProbeError{Err: err}
+		}
+	}
+	return err
+}
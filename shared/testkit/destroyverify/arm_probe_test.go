@@ -0,0 +1,113 @@
+package destroyverify
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeARM answers with the statuses queued for the longest matching path prefix; the last one repeats
+type fakeARM struct {
+	responses map[string][]int
+	requests  []string
+}
+
+func (f *fakeARM) Do(request *http.Request) (*http.Response, error) {
+	path := request.URL.Path
+	f.requests = append(f.requests, path)
+
+	match := ""
+	for prefix := range f.responses {
+		if strings.HasPrefix(path, prefix) && len(prefix) > len(match) {
+			match = prefix
+		}
+	}
+	status := http.StatusNotFound
+	if statuses := f.responses[match]; len(statuses) > 0 {
+		status = statuses[0]
+		if len(statuses) > 1 {
+			f.responses[match] = statuses[1:]
+		}
+	}
+
+	body := `{"error":{"code":"Throttled"}}`
+	if strings.HasSuffix(path, "/providers/Microsoft.KeyVault") {
+		body = `{"resourceTypes":[{"resourceType":"vaults","apiVersions":["2024-04-01-preview","2023-07-01"]}]}`
+	}
+	header := http.Header{}
+	if status == http.StatusTooManyRequests {
+		header.Set("Retry-After", "0")
+	}
+	return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader(body)), Request: request}, nil
+}
+
+func newFakeARMProbe(transport *fakeARM, maxRetries int32) *ARMDeletionProbe {
+	retry := armRetryOptions
+	retry.MaxRetries = maxRetries
+	retry.RetryDelay = time.Millisecond
+	retry.MaxRetryDelay = time.Millisecond
+	pipeline := runtime.NewPipeline("destroyverify", "v1.0.0", runtime.PipelineOptions{}, &policy.ClientOptions{Retry: retry, Transport: transport})
+	return newARMDeletionProbe(pipeline, "https://management.azure.com")
+}
+
+var vaultResource = StateResource{
+	Address: "module.key_vault.azurerm_key_vault.key_vault",
+	ID:      "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv1",
+	Values:  map[string]interface{}{"location": "West Europe"},
+}
+
+func TestARMProbeRetriesThrottling(t *testing.T) {
+	transport := &fakeARM{responses: map[string][]int{
+		"/subscriptions/sub/providers/Microsoft.KeyVault":           {http.StatusOK},
+		"/subscriptions/sub/resourceGroups/rg":                      {http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusNotFound},
+		"/subscriptions/sub/providers/Microsoft.KeyVault/locations": {http.StatusNotFound},
+	}}
+
+	exists, _, err := newFakeARMProbe(transport, 3).Probe(context.Background(), vaultResource)
+	require.NoError(t, err)
+	assert.False(t, exists)
+	assert.Len(t, transport.requests, 5, "providers lookup, two retried GETs, the final 404 and the deleted vault lookup")
+}
+
+func TestARMProbeExhaustedRetriesAreTransient(t *testing.T) {
+	transport := &fakeARM{responses: map[string][]int{
+		"/subscriptions/sub/providers/Microsoft.KeyVault": {http.StatusOK},
+		"/subscriptions/sub/resourceGroups/rg":            {http.StatusServiceUnavailable},
+	}}
+
+	_, _, err := newFakeARMProbe(transport, 1).Probe(context.Background(), vaultResource)
+	require.Error(t, err)
+	assert.True(t, IsTransientProbeError(err))
+	assert.Contains(t, err.Error(), "503")
+}
+
+func TestARMProbeReportsSoftDeletedVault(t *testing.T) {
+	transport := &fakeARM{responses: map[string][]int{
+		"/subscriptions/sub/providers/Microsoft.KeyVault/locations": {http.StatusOK},
+		"/subscriptions/sub/providers/Microsoft.KeyVault":           {http.StatusOK},
+	}}
+
+	exists, detail, err := newFakeARMProbe(transport, 0).Probe(context.Background(), vaultResource)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, "soft-deleted and pending purge at /subscriptions/sub/providers/Microsoft.KeyVault/locations/westeurope/deletedVaults/kv1", detail)
+}
+
+func TestARMProbeAuthorizationFailureIsFatal(t *testing.T) {
+	transport := &fakeARM{responses: map[string][]int{
+		"/subscriptions/sub/providers/Microsoft.KeyVault": {http.StatusForbidden},
+	}}
+
+	_, _, err := newFakeARMProbe(transport, 3).Probe(context.Background(), vaultResource)
+	require.Error(t, err)
+	assert.False(t, IsTransientProbeError(err))
+	assert.Len(t, transport.requests, 1)
+}
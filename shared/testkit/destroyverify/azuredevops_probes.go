@@ -0,0 +1,175 @@
+package destroyverify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/core"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/feed"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/stretchr/testify/require"
+)
+
+const adoRequestTimeout = 2 * time.Minute
+
+// azureDevOpsProbes looks up the Azure DevOps objects that own other resources
+type azureDevOpsProbes struct {
+	connection *azuredevops.Connection
+}
+
+// NewAzureDevOpsDestroyVerifier returns a DestroyVerifier authenticated with AZDO_ORG_SERVICE_URL and AZDO_PERSONAL_ACCESS_TOKEN.
+// It has lookups for projects, repositories and feeds; permissions, policies and other child settings disappear
+// with their parent and are logged as skipped.
+func NewAzureDevOpsDestroyVerifier(t testing.TB) *DestroyVerifier {
+	t.Helper()
+
+	organizationURL := os.Getenv("AZDO_ORG_SERVICE_URL")
+	require.NotEmpty(t, organizationURL, "AZDO_ORG_SERVICE_URL environment variable must be set")
+	token := os.Getenv("AZDO_PERSONAL_ACCESS_TOKEN")
+	require.NotEmpty(t, token, "AZDO_PERSONAL_ACCESS_TOKEN environment variable must be set")
+
+	probes := &azureDevOpsProbes{connection: azuredevops.NewPatConnection(strings.TrimRight(organizationURL, "/"), token)}
+	return &DestroyVerifier{
+		Probes: map[string]DeletionProbe{
+			"azuredevops_project":        probes.project,
+			"azuredevops_git_repository": probes.repository,
+			"azuredevops_feed":           probes.feed,
+		},
+		Timeout: DestroyVerifyTimeout(t),
+	}
+}
+
+// IsADONotFound reports whether an Azure DevOps API error is a 404
+func IsADONotFound(err error) bool {
+	return adoStatusCode(err) == http.StatusNotFound
+}
+
+// FeedInRecycleBin reports whether a feed with the ID is listed in the recycle bin
+func FeedInRecycleBin(feeds []feed.Feed, feedID string) bool {
+	for _, deleted := range feeds {
+		if deleted.Id != nil && strings.EqualFold(deleted.Id.String(), feedID) {
+			return true
+		}
+	}
+	return false
+}
+
+// FeedPermanentDelete returns features.permanent_delete from an azuredevops_feed state snapshot
+func FeedPermanentDelete(resource StateResource) bool {
+	features, _ := resource.Values["features"].([]interface{})
+	for _, item := range features {
+		values, _ := item.(map[string]interface{})
+		if enabled, _ := values["permanent_delete"].(bool); enabled {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *azureDevOpsProbes) project(ctx context.Context, resource StateResource) (bool, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, adoRequestTimeout)
+	defer cancel()
+
+	client, err := core.NewClient(ctx, p.connection)
+	if err != nil {
+		return false, "", adoProbeError(err)
+	}
+	project, err := client.GetProject(ctx, core.GetProjectArgs{ProjectId: &resource.ID})
+	if IsADONotFound(err) {
+		return false, "", nil
+	}
+	if err != nil {
+		return false, "", adoProbeError(err)
+	}
+	state := ""
+	if project.State != nil {
+		state = string(*project.State)
+	}
+	return true, fmt.Sprintf("project still exists (state %s)", state), nil
+}
+
+func (p *azureDevOpsProbes) repository(ctx context.Context, resource StateResource) (bool, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, adoRequestTimeout)
+	defer cancel()
+
+	client, err := git.NewClient(ctx, p.connection)
+	if err != nil {
+		return false, "", adoProbeError(err)
+	}
+	projectID := stateString(resource, "project_id")
+	_, err = client.GetRepository(ctx, git.GetRepositoryArgs{Project: &projectID, RepositoryId: &resource.ID})
+	if IsADONotFound(err) {
+		return false, "", nil
+	}
+	if err != nil {
+		return false, "", adoProbeError(err)
+	}
+	return true, "repository still exists", nil
+}
+
+// feed also fails on feeds left in the recycle bin when the state asked for permanent deletion
+func (p *azureDevOpsProbes) feed(ctx context.Context, resource StateResource) (bool, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, adoRequestTimeout)
+	defer cancel()
+
+	client, err := feed.NewClient(ctx, p.connection)
+	if err != nil {
+		return false, "", adoProbeError(err)
+	}
+	projectID := stateString(resource, "project_id")
+	args := feed.GetFeedArgs{FeedId: &resource.ID}
+	if projectID != "" {
+		args.Project = &projectID
+	}
+	_, err = client.GetFeed(ctx, args)
+	if err == nil {
+		return true, "feed still exists", nil
+	}
+	if !IsADONotFound(err) {
+		return false, "", adoProbeError(err)
+	}
+	if !FeedPermanentDelete(resource) {
+		return false, "", nil
+	}
+
+	recycleBinArgs := feed.GetFeedsFromRecycleBinArgs{}
+	if projectID != "" {
+		recycleBinArgs.Project = &projectID
+	}
+	deleted, err := client.GetFeedsFromRecycleBin(ctx, recycleBinArgs)
+	if err != nil {
+		return false, "", adoProbeError(err)
+	}
+	if deleted != nil && FeedInRecycleBin(*deleted, resource.ID) {
+		return true, "feed is in the recycle bin although features.permanent_delete is true", nil
+	}
+	return false, "", nil
+}
+
+// adoProbeError marks throttling and server errors as transient so the lookup is retried at the next poll
+func adoProbeError(err error) error {
+	status := adoStatusCode(err)
+	if status == http.StatusTooManyRequests || status >= http.StatusInternalServerError {
+		return &TransientProbeError{Err: err}
+	}
+	return err
+}
+
+func adoStatusCode(err error) int {
+	var wrapped azuredevops.WrappedError
+	if errors.As(err, &wrapped) && wrapped.StatusCode != nil {
+		return *wrapped.StatusCode
+	}
+	var wrappedPointer *azuredevops.WrappedError
+	if errors.As(err, &wrappedPointer) && wrappedPointer.StatusCode != nil {
+		return *wrappedPointer.StatusCode
+	}
+	return 0
+}
@@ -0,0 +1,56 @@
+package destroyverify
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/feed"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsADONotFound(t *testing.T) {
+	notFound := http.StatusNotFound
+	forbidden := http.StatusForbidden
+
+	assert.True(t, IsADONotFound(azuredevops.WrappedError{StatusCode: &notFound}))
+	assert.True(t, IsADONotFound(fmt.Errorf("get feed: %w", &azuredevops.WrappedError{StatusCode: &notFound})))
+	assert.False(t, IsADONotFound(&azuredevops.WrappedError{StatusCode: &forbidden}))
+	assert.False(t, IsADONotFound(errors.New("connection reset")))
+	assert.False(t, IsADONotFound(nil))
+}
+
+func TestFeedPermanentDelete(t *testing.T) {
+	enabled := StateResource{Values: map[string]interface{}{
+		"features": []interface{}{map[string]interface{}{"permanent_delete": true, "restore": false}},
+	}}
+	disabled := StateResource{Values: map[string]interface{}{
+		"features": []interface{}{map[string]interface{}{"permanent_delete": false}},
+	}}
+
+	assert.True(t, FeedPermanentDelete(enabled))
+	assert.False(t, FeedPermanentDelete(disabled))
+	assert.False(t, FeedPermanentDelete(StateResource{Values: map[string]interface{}{"features": []interface{}{}}}))
+}
+
+func TestFeedInRecycleBin(t *testing.T) {
+	id := uuid.MustParse("0b1a6c1e-5c4e-4b0f-9d7c-3f2f6b8e1a01")
+	deleted := []feed.Feed{{Id: &id}, {}}
+
+	assert.True(t, FeedInRecycleBin(deleted, "0B1A6C1E-5C4E-4B0F-9D7C-3F2F6B8E1A01"))
+	assert.False(t, FeedInRecycleBin(deleted, uuid.NewString()))
+}
+
+func TestADOProbeErrorMarksThrottlingTransient(t *testing.T) {
+	throttled := http.StatusTooManyRequests
+	unavailable := http.StatusServiceUnavailable
+	unauthorized := http.StatusUnauthorized
+
+	assert.True(t, IsTransientProbeError(adoProbeError(azuredevops.WrappedError{StatusCode: &throttled})))
+	assert.True(t, IsTransientProbeError(adoProbeError(&azuredevops.WrappedError{StatusCode: &unavailable})))
+	assert.False(t, IsTransientProbeError(adoProbeError(&azuredevops.WrappedError{StatusCode: &unauthorized})))
+	assert.False(t, IsTransientProbeError(adoProbeError(errors.New("invalid organization URL"))))
+}
@@ -0,0 +1,283 @@
+// Package destroyverify checks that terraform destroy really removed what a fixture deployed by looking
+// every resource captured from the state up again until it is gone.
+package destroyverify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+// pollInterval is the wait between lookups of resources that still exist
+var pollInterval = 30 * time.Second
+
+// ErrProbeNotApplicable is returned by a DeletionProbe that cannot look up the resource
+var ErrProbeNotApplicable = errors.New("deletion probe not applicable")
+
+// TransientProbeError is a lookup that failed for a reason worth retrying, such as throttling or a 5xx.
+// The resource stays on the list and is looked up again at the next poll.
+type TransientProbeError struct {
+	Err error
+}
+
+func (e *TransientProbeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TransientProbeError) Unwrap() error {
+	return e.Err
+}
+
+// IsTransientProbeError reports whether the lookup error should be retried
+func IsTransientProbeError(err error) bool {
+	var transient *TransientProbeError
+	return errors.As(err, &transient)
+}
+
+// StateResource is a managed resource captured from `terraform show -json` before destroy
+type StateResource struct {
+	Address string
+	Type    string
+	ID      string
+	Values  map[string]interface{}
+}
+
+// DeletionProbe reports whether a destroyed resource still exists, with a short reason when it does
+type DeletionProbe func(ctx context.Context, resource StateResource) (exists bool, detail string, err error)
+
+// DestroySurvivor is a resource that was still reachable after destroy
+type DestroySurvivor struct {
+	Address string
+	ID      string
+	Detail  string
+}
+
+// DestroyVerifier polls every resource captured before destroy until its lookup returns 404
+type DestroyVerifier struct {
+	// Probes are keyed by Terraform resource type and take precedence over DefaultProbe
+	Probes       map[string]DeletionProbe
+	DefaultProbe DeletionProbe
+	// IgnoreTypes lists resource types that legitimately outlive destroy (e.g. registrations)
+	IgnoreTypes []string
+	Timeout     time.Duration
+}
+
+// DestroyVerifyTimeout returns DESTROY_VERIFY_TIMEOUT (e.g. "20m") or the 15 minute default
+func DestroyVerifyTimeout(t testing.TB) time.Duration {
+	t.Helper()
+
+	value := os.Getenv("DESTROY_VERIFY_TIMEOUT")
+	if value == "" {
+		return 15 * time.Minute
+	}
+	timeout, err := time.ParseDuration(value)
+	require.NoError(t, err, "DESTROY_VERIFY_TIMEOUT must be a duration such as 20m")
+	return timeout
+}
+
+// SnapshotStateResourcesE captures all managed resources from the current state
+func SnapshotStateResourcesE(t testing.TB, terraformOptions *terraform.Options) ([]StateResource, error) {
+	t.Helper()
+
+	stateOptions := *terraformOptions
+	stateOptions.PlanFilePath = ""
+	output, err := terraform.ShowE(t, &stateOptions)
+	if err != nil {
+		return nil, fmt.Errorf("terraform show: %w", err)
+	}
+	resources, err := ParseStateResources([]byte(output))
+	if err != nil {
+		return nil, fmt.Errorf("parse terraform show output: %w", err)
+	}
+	return resources, nil
+}
+
+// ParseStateResources extracts managed resources with an ID from `terraform show -json` output, including child modules
+func ParseStateResources(showJSON []byte) ([]StateResource, error) {
+	var state struct {
+		Values *struct {
+			RootModule stateModule `json:"root_module"`
+		} `json:"values"`
+	}
+	if err := json.Unmarshal(showJSON, &state); err != nil {
+		return nil, err
+	}
+	if state.Values == nil {
+		return nil, nil
+	}
+
+	var resources []StateResource
+	var walk func(module stateModule)
+	walk = func(module stateModule) {
+		for _, resource := range module.Resources {
+			if resource.Mode != "managed" {
+				continue
+			}
+			id, _ := resource.Values["id"].(string)
+			if id == "" {
+				continue
+			}
+			resources = append(resources, StateResource{Address: resource.Address, Type: resource.Type, ID: id, Values: resource.Values})
+		}
+		for _, child := range module.ChildModules {
+			walk(child)
+		}
+	}
+	walk(state.Values.RootModule)
+	return resources, nil
+}
+
+type stateModule struct {
+	Resources []struct {
+		Address string                 `json:"address"`
+		Mode    string                 `json:"mode"`
+		Type    string                 `json:"type"`
+		Values  map[string]interface{} `json:"values"`
+	} `json:"resources"`
+	ChildModules []stateModule `json:"child_modules"`
+}
+
+// DestroyAndVerify snapshots the state, destroys it and fails the test for every resource that is still reachable.
+// Destroy always runs; when the state cannot be captured the failure is logged and only verification is skipped.
+func DestroyAndVerify(t testing.TB, terraformOptions *terraform.Options, verifier *DestroyVerifier) {
+	t.Helper()
+
+	resources, snapshotErr := SnapshotStateResourcesE(t, terraformOptions)
+	if snapshotErr != nil {
+		t.Logf("Failed to capture the state before destroy, destroy verification is skipped: %v", snapshotErr)
+	}
+	tfretry.DestroyWithRetry(t, terraformOptions)
+	if snapshotErr != nil {
+		return
+	}
+
+	survivors, skipped, err := verifier.WaitForDeletionE(context.Background(), resources)
+	for _, resource := range skipped {
+		t.Logf("Destroy verification skipped %s (%s): no lookup for this ID", resource.Address, resource.ID)
+	}
+	require.NoError(t, err, "Failed to verify destroyed resources")
+	if len(survivors) > 0 {
+		lines := make([]string, 0, len(survivors))
+		for _, survivor := range survivors {
+			lines = append(lines, fmt.Sprintf("%s %s: %s", survivor.Address, survivor.ID, survivor.Detail))
+		}
+		require.FailNow(t, "Resources still exist after terraform destroy", strings.Join(lines, "\n"))
+	}
+}
+
+// WaitForDeletionE probes every resource until it is gone or the timeout expires and returns the survivors.
+// Resources without an applicable probe are returned as skipped. Transient lookup failures are retried at
+// the next poll; a resource whose lookup never succeeds is reported as a survivor with the last error.
+func (v *DestroyVerifier) WaitForDeletionE(ctx context.Context, resources []StateResource) ([]DestroySurvivor, []StateResource, error) {
+	remaining := map[string]DestroySurvivor{}
+	var skipped []StateResource
+	probes := map[string]DeletionProbe{}
+	byID := map[string]StateResource{}
+
+	for _, resource := range resources {
+		if v.ignored(resource.Type) {
+			continue
+		}
+		key := strings.ToLower(resource.ID)
+		if _, seen := byID[key]; seen {
+			// Association resources often reuse the ID of the resource they attach to
+			continue
+		}
+		probe := v.probeFor(resource.Type)
+		if probe == nil {
+			skipped = append(skipped, resource)
+			continue
+		}
+		byID[key] = resource
+		probes[key] = probe
+		remaining[key] = DestroySurvivor{Address: resource.Address, ID: resource.ID}
+	}
+
+	check := func() error {
+		for key, survivor := range remaining {
+			exists, detail, err := probes[key](ctx, byID[key])
+			switch {
+			case errors.Is(err, ErrProbeNotApplicable):
+				skipped = append(skipped, byID[key])
+				delete(remaining, key)
+				continue
+			case IsTransientProbeError(err):
+				exists, detail = true, fmt.Sprintf("lookup failed: %v", err)
+			case err != nil:
+				return fmt.Errorf("%s: %w", survivor.Address, err)
+			}
+			if !exists {
+				delete(remaining, key)
+				continue
+			}
+			survivor.Detail = detail
+			remaining[key] = survivor
+		}
+		return nil
+	}
+
+	timeout := v.Timeout
+	if timeout == 0 {
+		timeout = 15 * time.Minute
+	}
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	// Most resources are gone as soon as destroy returns, so check once before waiting for the first poll interval
+	probeErr := check()
+	for probeErr == nil && len(remaining) > 0 {
+		select {
+		case <-ctx.Done():
+			probeErr = ctx.Err()
+		case <-deadline:
+			// A timeout is how survivors surface; they are reported from remaining below
+			return v.result(remaining, skipped, nil)
+		case <-ticker.C:
+			probeErr = check()
+		}
+	}
+	return v.result(remaining, skipped, probeErr)
+}
+
+func (v *DestroyVerifier) result(remaining map[string]DestroySurvivor, skipped []StateResource, err error) ([]DestroySurvivor, []StateResource, error) {
+	survivors := make([]DestroySurvivor, 0, len(remaining))
+	for _, survivor := range remaining {
+		survivors = append(survivors, survivor)
+	}
+	sort.Slice(survivors, func(i, j int) bool { return survivors[i].Address < survivors[j].Address })
+	sort.Slice(skipped, func(i, j int) bool { return skipped[i].Address < skipped[j].Address })
+	return survivors, skipped, err
+}
+
+func (v *DestroyVerifier) probeFor(resourceType string) DeletionProbe {
+	if probe, ok := v.Probes[resourceType]; ok {
+		return probe
+	}
+	return v.DefaultProbe
+}
+
+func (v *DestroyVerifier) ignored(resourceType string) bool {
+	for _, ignored := range v.IgnoreTypes {
+		if ignored == resourceType {
+			return true
+		}
+	}
+	return false
+}
+
+// stateString returns a string attribute captured in the state snapshot
+func stateString(resource StateResource, name string) string {
+	value, _ := resource.Values[name].(string)
+	return value
+}
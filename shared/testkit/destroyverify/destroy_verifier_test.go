@@ -0,0 +1,180 @@
+package destroyverify
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadStateResources(t *testing.T) []StateResource {
+	t.Helper()
+
+	content, err := os.ReadFile("testdata/terraform_show.json")
+	require.NoError(t, err)
+	resources, err := ParseStateResources(content)
+	require.NoError(t, err)
+	return resources
+}
+
+func TestParseStateResources(t *testing.T) {
+	resources := loadStateResources(t)
+
+	addresses := make([]string, 0, len(resources))
+	for _, resource := range resources {
+		addresses = append(addresses, resource.Address)
+	}
+	// Data sources and resources without an ID are left out; child modules are walked recursively
+	assert.Equal(t, []string{
+		"azurerm_resource_group.example",
+		"random_string.suffix",
+		"module.key_vault.azurerm_key_vault.key_vault",
+		`module.key_vault.azurerm_key_vault_secret.secrets["app"]`,
+		"module.key_vault.module.diagnostics.azurerm_monitor_diagnostic_setting.this",
+		"module.key_vault.module.diagnostics.azurerm_key_vault_access_policy.this",
+	}, addresses)
+	assert.Equal(t, "West Europe", stateString(resources[2], "location"))
+
+	empty, err := ParseStateResources([]byte(`{"format_version":"1.0"}`))
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+// armOnlyProbe mimics the ARM probe: it only handles ARM IDs and reports the remaining ones as existing
+func armOnlyProbe(existing map[string]string) DeletionProbe {
+	return func(ctx context.Context, resource StateResource) (bool, string, error) {
+		if !IsARMResourceID(resource.ID) {
+			return false, "", ErrProbeNotApplicable
+		}
+		detail, ok := existing[resource.Address]
+		return ok, detail, nil
+	}
+}
+
+func TestWaitForDeletionAllGone(t *testing.T) {
+	verifier := &DestroyVerifier{DefaultProbe: armOnlyProbe(nil), IgnoreTypes: []string{"random_string"}}
+
+	survivors, skipped, err := verifier.WaitForDeletionE(context.Background(), loadStateResources(t))
+	require.NoError(t, err)
+	assert.Empty(t, survivors)
+
+	skippedAddresses := make([]string, 0, len(skipped))
+	for _, resource := range skipped {
+		skippedAddresses = append(skippedAddresses, resource.Address)
+	}
+	assert.Equal(t, []string{
+		`module.key_vault.azurerm_key_vault_secret.secrets["app"]`,
+		"module.key_vault.module.diagnostics.azurerm_monitor_diagnostic_setting.this",
+	}, skippedAddresses)
+}
+
+func TestWaitForDeletionReportsSurvivors(t *testing.T) {
+	calls := 0
+	verifier := &DestroyVerifier{
+		DefaultProbe: armOnlyProbe(map[string]string{
+			"module.key_vault.azurerm_key_vault.key_vault":                             "soft-deleted and pending purge",
+			"module.key_vault.module.diagnostics.azurerm_key_vault_access_policy.this": "duplicate of the vault ID",
+		}),
+		Probes: map[string]DeletionProbe{
+			"azurerm_resource_group": func(ctx context.Context, resource StateResource) (bool, string, error) {
+				calls++
+				return false, "", nil
+			},
+		},
+		IgnoreTypes: []string{"random_string"},
+		Timeout:     10 * time.Millisecond,
+	}
+
+	survivors, _, err := verifier.WaitForDeletionE(context.Background(), loadStateResources(t))
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls, "the type-specific probe takes precedence and gone resources are not probed again")
+
+	// The access policy shares the vault ID and is only probed once
+	require.Len(t, survivors, 1)
+	assert.Equal(t, "module.key_vault.azurerm_key_vault.key_vault", survivors[0].Address)
+	assert.True(t, strings.HasSuffix(survivors[0].ID, "/vaults/kvcompleteabc123"))
+	assert.Equal(t, "soft-deleted and pending purge", survivors[0].Detail)
+}
+
+func TestWaitForDeletionProbeError(t *testing.T) {
+	verifier := &DestroyVerifier{
+		DefaultProbe: func(ctx context.Context, resource StateResource) (bool, string, error) {
+			return false, "", errors.New("GET returned 403 AuthorizationFailed")
+		},
+		Timeout: 10 * time.Millisecond,
+	}
+
+	_, _, err := verifier.WaitForDeletionE(context.Background(), loadStateResources(t)[:1])
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "azurerm_resource_group.example")
+	assert.Contains(t, err.Error(), "AuthorizationFailed")
+}
+
+func TestWaitForDeletionRetriesTransientErrors(t *testing.T) {
+	calls := 0
+	verifier := &DestroyVerifier{
+		DefaultProbe: func(ctx context.Context, resource StateResource) (bool, string, error) {
+			calls++
+			if calls == 1 {
+				return false, "", &TransientProbeError{Err: errors.New("GET returned 429 TooManyRequests")}
+			}
+			return false, "", nil
+		},
+		Timeout: time.Second,
+	}
+	pollInterval = 10 * time.Millisecond
+	defer func() { pollInterval = 30 * time.Second }()
+
+	survivors, _, err := verifier.WaitForDeletionE(context.Background(), loadStateResources(t)[:1])
+	require.NoError(t, err)
+	assert.Empty(t, survivors)
+	assert.Equal(t, 2, calls)
+}
+
+func TestWaitForDeletionReportsPersistentTransientErrors(t *testing.T) {
+	verifier := &DestroyVerifier{
+		DefaultProbe: func(ctx context.Context, resource StateResource) (bool, string, error) {
+			return false, "", &TransientProbeError{Err: errors.New("GET returned 503 ServiceUnavailable")}
+		},
+		Timeout: 10 * time.Millisecond,
+	}
+
+	survivors, _, err := verifier.WaitForDeletionE(context.Background(), loadStateResources(t)[:1])
+	require.NoError(t, err)
+	require.Len(t, survivors, 1)
+	assert.Equal(t, "lookup failed: GET returned 503 ServiceUnavailable", survivors[0].Detail)
+}
+
+func TestSoftDeletedResourcePath(t *testing.T) {
+	vault, err := arm.ParseResourceID("/subscriptions/sub/resourceGroups/rg/providers/Microsoft.KeyVault/vaults/kv1")
+	require.NoError(t, err)
+	path, apiVersion, ok := SoftDeletedResourcePath(vault, "West Europe")
+	require.True(t, ok)
+	assert.Equal(t, "/subscriptions/sub/providers/Microsoft.KeyVault/locations/westeurope/deletedVaults/kv1", path)
+	assert.Equal(t, "2022-07-01", apiVersion)
+
+	account, err := arm.ParseResourceID("/subscriptions/sub/resourceGroups/rg/providers/Microsoft.CognitiveServices/accounts/cog1")
+	require.NoError(t, err)
+	path, _, ok = SoftDeletedResourcePath(account, "eastus")
+	require.True(t, ok)
+	assert.Equal(t, "/subscriptions/sub/providers/Microsoft.CognitiveServices/locations/eastus/resourceGroups/rg/deletedAccounts/cog1", path)
+
+	nic, err := arm.ParseResourceID("/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/networkInterfaces/nic1")
+	require.NoError(t, err)
+	_, _, ok = SoftDeletedResourcePath(nic, "eastus")
+	assert.False(t, ok)
+	_, _, ok = SoftDeletedResourcePath(vault, "")
+	assert.False(t, ok, "the location is required to look up a deleted vault")
+}
+
+func TestLatestStableAPIVersion(t *testing.T) {
+	assert.Equal(t, "2023-07-01", LatestStableAPIVersion([]string{"2024-04-01-preview", "2023-07-01", "2022-07-01"}))
+	assert.Equal(t, "2024-04-01-preview", LatestStableAPIVersion([]string{"2023-10-01-preview", "2024-04-01-preview"}))
+	assert.Equal(t, "", LatestStableAPIVersion(nil))
+}
@@ -0,0 +1,167 @@
+// Package importtest adopts existing objects into a fixture's state with `terraform import` or
+// import blocks and asserts that the configuration plans no changes afterwards.
+package importtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+// ImportBlocksFile is written into the fixture when importing with import blocks
+const ImportBlocksFile = "imports.tf"
+
+// ImportTarget maps a resource address in the fixture to the provider import ID of an existing object
+type ImportTarget struct {
+	Address string
+	ID      string
+}
+
+// ImportMode selects how existing objects are adopted
+type ImportMode int
+
+const (
+	// ImportWithCLI runs `terraform import` once per target
+	ImportWithCLI ImportMode = iota
+	// ImportWithBlocks writes `import {}` blocks and applies them
+	ImportWithBlocks
+)
+
+func (m ImportMode) String() string {
+	if m == ImportWithBlocks {
+		return "import blocks"
+	}
+	return "terraform import"
+}
+
+// ModuleResourceAddress returns the address of a resource inside a module call, e.g.
+// module.azuredevops_repository.azuredevops_git_repository.git_repository
+func ModuleResourceAddress(moduleName, resourceType, resourceName string) string {
+	return fmt.Sprintf("module.%s.%s.%s", moduleName, resourceType, resourceName)
+}
+
+// InstanceAddress appends a for_each key (string) or count index (int) to a module or resource address
+func InstanceAddress(address string, key interface{}) string {
+	if index, ok := key.(int); ok {
+		return fmt.Sprintf("%s[%d]", address, index)
+	}
+	return fmt.Sprintf("%s[%q]", address, fmt.Sprint(key))
+}
+
+// ImportRoundTrip adopts existing objects into the fixture state and requires the following plan to be empty
+func ImportRoundTrip(t testing.TB, options *terraform.Options, targets []ImportTarget, mode ImportMode) {
+	t.Helper()
+	require.NotEmpty(t, targets, "Import round trip needs at least one target")
+
+	switch mode {
+	case ImportWithBlocks:
+		require.NoError(t, WriteImportBlocksE(options.TerraformDir, targets), "Failed to write import blocks")
+		// Import blocks show up as no-op changes; anything else would be applied together with the import
+		changes := PlannedChanges(planStruct(t, options, "import.tfplan"))
+		require.Empty(t, changes, "Plan with import blocks changes the imported objects")
+		tfretry.ApplyWithRetry(t, options)
+	default:
+		terraform.Init(t, options)
+		for _, target := range targets {
+			require.NoError(t, ImportResourceE(t, options, target), "Failed to import %s", target.Address)
+		}
+	}
+
+	RequireEmptyPlan(t, options)
+}
+
+// RequireEmptyPlan fails with the planned resource changes when the configuration does not match the state
+func RequireEmptyPlan(t testing.TB, options *terraform.Options) {
+	t.Helper()
+
+	if changes := PlannedChanges(planStruct(t, options, "empty.tfplan")); len(changes) > 0 {
+		require.FailNow(t, "Plan after import is not empty", strings.Join(changes, "\n"))
+	}
+}
+
+// PlannedChanges lists resource changes other than no-op and data source reads, sorted by address
+func PlannedChanges(plan *terraform.PlanStruct) []string {
+	var changes []string
+	for address, change := range plan.ResourceChangesMap {
+		if change == nil || change.Change == nil || change.Change.Actions.NoOp() || change.Change.Actions.Read() {
+			continue
+		}
+		actions := make([]string, 0, len(change.Change.Actions))
+		for _, action := range change.Change.Actions {
+			actions = append(actions, string(action))
+		}
+		changes = append(changes, fmt.Sprintf("%s %v", address, actions))
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+// ImportResourceE runs `terraform import` for one target with the options' variables
+func ImportResourceE(t testing.TB, options *terraform.Options, target ImportTarget) error {
+	t.Helper()
+
+	args := BuildImportArgs(options, target.Address, target.ID)
+	_, err := terraform.RunTerraformCommandE(t, options, args...)
+	return err
+}
+
+// BuildImportArgs builds `terraform import` arguments honouring lock, var and var-file options
+func BuildImportArgs(options *terraform.Options, address, id string) []string {
+	args := []string{"import", "-input=false"}
+
+	if options.NoColor {
+		args = append(args, "-no-color")
+	}
+
+	args = append(args, terraform.FormatTerraformLockAsArgs(options.Lock, options.LockTimeout)...)
+
+	if options.SetVarsAfterVarFiles {
+		args = append(args, terraform.FormatTerraformArgs("-var-file", options.VarFiles)...)
+		args = append(args, terraform.FormatTerraformVarsAsArgs(options.Vars)...)
+	} else {
+		args = append(args, terraform.FormatTerraformVarsAsArgs(options.Vars)...)
+		args = append(args, terraform.FormatTerraformArgs("-var-file", options.VarFiles)...)
+	}
+
+	args = append(args, address, id)
+	return args
+}
+
+// RenderImportBlocks renders one `import {}` block per target
+func RenderImportBlocks(targets []ImportTarget) string {
+	var builder strings.Builder
+	for i, target := range targets {
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+		fmt.Fprintf(&builder, "import {\n  to = %s\n  id = %s\n}\n", target.Address, hclString(target.ID))
+	}
+	return builder.String()
+}
+
+// WriteImportBlocksE writes the import blocks into the Terraform directory
+func WriteImportBlocksE(terraformDir string, targets []ImportTarget) error {
+	return os.WriteFile(filepath.Join(terraformDir, ImportBlocksFile), []byte(RenderImportBlocks(targets)), 0o644)
+}
+
+// hclString quotes a value as an HCL string literal without template interpolation
+func hclString(value string) string {
+	quoted := fmt.Sprintf("%q", value)
+	quoted = strings.ReplaceAll(quoted, "${", "$${")
+	return strings.ReplaceAll(quoted, "%{", "%%{")
+}
+
+func planStruct(t testing.TB, options *terraform.Options, planFile string) *terraform.PlanStruct {
+	t.Helper()
+
+	planOptions := *options
+	planOptions.PlanFilePath = filepath.Join(options.TerraformDir, planFile)
+	return terraform.InitAndPlanAndShowWithStruct(t, &planOptions)
+}
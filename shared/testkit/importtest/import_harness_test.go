@@ -0,0 +1,59 @@
+package importtest
+
+import (
+	"os"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstanceAddress(t *testing.T) {
+	address := ModuleResourceAddress("azuredevops_repository", "azuredevops_git_repository_file", "git_repository_file")
+	assert.Equal(t, `module.azuredevops_repository.azuredevops_git_repository_file.git_repository_file["README.md:default"]`, InstanceAddress(address, "README.md:default"))
+	assert.Equal(t, `module.azuredevops_extension["ms/extension"].azuredevops_extension.extension`,
+		InstanceAddress("module.azuredevops_extension", "ms/extension")+".azuredevops_extension.extension")
+	assert.Equal(t, "azuredevops_git_repository.this[0]", InstanceAddress("azuredevops_git_repository.this", 0))
+}
+
+func TestRenderImportBlocks(t *testing.T) {
+	rendered := RenderImportBlocks([]ImportTarget{
+		{Address: "module.azuredevops_repository.azuredevops_git_repository.git_repository", ID: "project/repository"},
+		{Address: `module.x.y.z["a"]`, ID: `odd "id" ${var.x}`},
+	})
+
+	assert.Equal(t, `import {
+  to = module.azuredevops_repository.azuredevops_git_repository.git_repository
+  id = "project/repository"
+}
+
+import {
+  to = module.x.y.z["a"]
+  id = "odd \"id\" $${var.x}"
+}
+`, rendered)
+}
+
+func TestBuildImportArgs(t *testing.T) {
+	options := &terraform.Options{
+		NoColor:  true,
+		Vars:     map[string]interface{}{"project_id": "p1"},
+		VarFiles: []string{"extra.tfvars"},
+	}
+
+	args := BuildImportArgs(options, "module.a.b.c", "id-1")
+	assert.Equal(t, []string{"import", "-input=false", "-no-color", "-lock=false", "-var", "project_id=p1", "-var-file", "extra.tfvars", "module.a.b.c", "id-1"}, args)
+}
+
+func TestPlannedChangesIgnoresImportsAndReads(t *testing.T) {
+	content, err := os.ReadFile("testdata/import_plan.json")
+	require.NoError(t, err)
+	plan, err := terraform.ParsePlanJSON(string(content))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		`module.azuredevops_repository.azuredevops_git_repository_branch.git_repository_branch["develop"] [delete create]`,
+		`module.azuredevops_repository.azuredevops_git_repository_file.git_repository_file["README.md:default"] [update]`,
+	}, PlannedChanges(plan))
+}
@@ -0,0 +1,181 @@
+package importtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+// StateImportTargets returns an import target for every managed resource inside a module call.
+// Resources of the fixture itself stay in state; unimportable ones are returned as skipped addresses.
+func StateImportTargets(state *tfjson.State) (targets []ImportTarget, skipped []string, err error) {
+	if state == nil || state.Values == nil || state.Values.RootModule == nil {
+		return nil, nil, nil
+	}
+
+	var walk func(module *tfjson.StateModule) error
+	walk = func(module *tfjson.StateModule) error {
+		for _, resource := range module.Resources {
+			if resource.Mode != tfjson.ManagedResourceMode || !strings.HasPrefix(resource.Address, "module.") {
+				continue
+			}
+			if !IsImportable(resource.Type) {
+				skipped = append(skipped, resource.Address)
+				continue
+			}
+			id, err := ImportID(resource.Type, resource.AttributeValues)
+			if err != nil {
+				return fmt.Errorf("%s: %w", resource.Address, err)
+			}
+			targets = append(targets, ImportTarget{Address: resource.Address, ID: id})
+		}
+		for _, child := range module.ChildModules {
+			if err := walk(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(state.Values.RootModule); err != nil {
+		return nil, nil, err
+	}
+
+	sort.Slice(targets, func(i, j int) bool { return targets[i].Address < targets[j].Address })
+	sort.Strings(skipped)
+	return targets, skipped, nil
+}
+
+// UnexpectedImportChanges lists planned changes that an import must not cause: the targets have to
+// be imported, and anything besides write-only attributes of an imported object counts as a change
+func UnexpectedImportChanges(plan *terraform.PlanStruct, targets []ImportTarget) []string {
+	var problems []string
+	for _, target := range targets {
+		change, ok := plan.ResourceChangesMap[target.Address]
+		if !ok || change.Change == nil || change.Change.Importing == nil {
+			problems = append(problems, fmt.Sprintf("%s is not imported", target.Address))
+		}
+	}
+
+	for address, change := range plan.ResourceChangesMap {
+		if change == nil || change.Change == nil || change.Change.Actions.NoOp() || change.Change.Actions.Read() {
+			continue
+		}
+		attributes := ChangedAttributes(change.Change)
+		if change.Change.Importing != nil {
+			attributes = withoutAttributes(attributes, WriteOnlyAttributes(change.Type))
+			if len(attributes) == 0 {
+				continue
+			}
+		}
+		actions := make([]string, 0, len(change.Change.Actions))
+		for _, action := range change.Change.Actions {
+			actions = append(actions, string(action))
+		}
+		problems = append(problems, fmt.Sprintf("%s %v %s", address, actions, strings.Join(attributes, ", ")))
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// ChangedAttributes returns the top level attributes that differ between the prior and planned values
+func ChangedAttributes(change *tfjson.Change) []string {
+	before, _ := change.Before.(map[string]interface{})
+	after, _ := change.After.(map[string]interface{})
+	unknown, _ := change.AfterUnknown.(map[string]interface{})
+
+	names := map[string]bool{}
+	for name := range before {
+		names[name] = true
+	}
+	for name := range after {
+		names[name] = true
+	}
+	for name := range unknown {
+		names[name] = true
+	}
+
+	var changed []string
+	for name := range names {
+		if isUnknown(unknown[name]) || !jsonEqual(before[name], after[name]) {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// RequireStateImportRoundTrip adopts the module resources of an applied fixture into an empty state and
+// requires the plan to import them without changes. It works on a copy of the fixture and never applies,
+// so the deployed objects and the original state stay untouched for destroy.
+func RequireStateImportRoundTrip(t testing.TB, options *terraform.Options) {
+	t.Helper()
+
+	stateOptions := *options
+	stateOptions.PlanFilePath = ""
+	var state tfjson.State
+	require.NoError(t, json.Unmarshal([]byte(terraform.Show(t, &stateOptions)), &state), "Failed to parse the fixture state")
+
+	targets, skipped, err := StateImportTargets(&state)
+	require.NoError(t, err)
+	require.NotEmpty(t, targets, "The fixture state has no importable module resources")
+	if len(skipped) > 0 {
+		t.Logf("Not importable, kept in state: %s", strings.Join(skipped, ", "))
+	}
+
+	importDir := options.TerraformDir + "-import"
+	require.NoError(t, os.MkdirAll(importDir, 0o755))
+	defer os.RemoveAll(importDir)
+	// The copy stays next to the fixture so relative module sources still resolve
+	require.NoError(t, files.CopyFolderContentsWithFilter(options.TerraformDir, importDir, func(path string) bool {
+		name := filepath.Base(path)
+		return name != ".terraform" && name != ".test-data" && filepath.Ext(name) != ".tfplan"
+	}))
+
+	importOptions := stateOptions
+	importOptions.TerraformDir = importDir
+	terraform.Init(t, &importOptions)
+	addresses := make([]string, 0, len(targets))
+	for _, target := range targets {
+		addresses = append(addresses, target.Address)
+	}
+	terraform.RunTerraformCommand(t, &importOptions, append([]string{"state", "rm"}, addresses...)...)
+	require.NoError(t, WriteImportBlocksE(importDir, targets), "Failed to write import blocks")
+
+	if problems := UnexpectedImportChanges(planStruct(t, &importOptions, "import.tfplan"), targets); len(problems) > 0 {
+		require.FailNow(t, "Importing the deployed module resources does not yield an empty plan", strings.Join(problems, "\n"))
+	}
+}
+
+func withoutAttributes(attributes, ignored []string) []string {
+	skip := map[string]bool{}
+	for _, name := range ignored {
+		skip[name] = true
+	}
+	var kept []string
+	for _, name := range attributes {
+		if !skip[name] {
+			kept = append(kept, name)
+		}
+	}
+	return kept
+}
+
+func isUnknown(value interface{}) bool {
+	unknown, ok := value.(bool)
+	return ok && unknown
+}
+
+func jsonEqual(a, b interface{}) bool {
+	left, errLeft := json.Marshal(a)
+	right, errRight := json.Marshal(b)
+	return errLeft == nil && errRight == nil && string(left) == string(right)
+}
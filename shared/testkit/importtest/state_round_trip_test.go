@@ -0,0 +1,60 @@
+package importtest
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var roundTripTargets = []ImportTarget{
+	{Address: "module.azuredevops_repository.azuredevops_git_repository.git_repository", ID: "11111111-1111-1111-1111-111111111111/22222222-2222-2222-2222-222222222222"},
+	{Address: `module.azuredevops_repository.azuredevops_git_repository_file.git_repository_file["README.md:default"]`, ID: "22222222-2222-2222-2222-222222222222/README.md:refs/heads/main"},
+	{Address: "module.azuredevops_repository.module.agent_pool[0].azuredevops_agent_pool.agent_pool", ID: "1234567"},
+}
+
+func TestStateImportTargets(t *testing.T) {
+	content, err := os.ReadFile("testdata/module_state.json")
+	require.NoError(t, err)
+	var state tfjson.State
+	require.NoError(t, json.Unmarshal(content, &state))
+
+	targets, skipped, err := StateImportTargets(&state)
+	require.NoError(t, err)
+	assert.Equal(t, roundTripTargets, targets)
+	assert.Equal(t, []string{`module.azuredevops_repository.azuredevops_git_permissions.git_permissions["readers"]`}, skipped)
+}
+
+func TestImportID(t *testing.T) {
+	id, err := ImportID("azurerm_storage_account", map[string]interface{}{"id": "/subscriptions/s/resourceGroups/rg/providers/Microsoft.Storage/storageAccounts/st"})
+	require.NoError(t, err)
+	assert.Equal(t, "/subscriptions/s/resourceGroups/rg/providers/Microsoft.Storage/storageAccounts/st", id)
+
+	id, err = ImportID("azuredevops_extension", map[string]interface{}{"id": "x", "publisher_id": "ms", "extension_id": "vss-code-search"})
+	require.NoError(t, err)
+	assert.Equal(t, "ms/vss-code-search", id)
+
+	id, err = ImportID("azuredevops_feed", map[string]interface{}{"id": "feed", "project_id": ""})
+	require.NoError(t, err)
+	assert.Equal(t, "feed", id)
+
+	_, err = ImportID("azurerm_subnet", map[string]interface{}{})
+	assert.Error(t, err)
+}
+
+func TestUnexpectedImportChanges(t *testing.T) {
+	content, err := os.ReadFile("testdata/import_round_trip_plan.json")
+	require.NoError(t, err)
+	plan, err := terraform.ParsePlanJSON(string(content))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		`module.azuredevops_repository.azuredevops_git_repository_file.git_repository_file["README.md:default"] [update] content`,
+		"module.azuredevops_repository.module.agent_pool[0].azuredevops_agent_pool.agent_pool [create] id, name",
+		"module.azuredevops_repository.module.agent_pool[0].azuredevops_agent_pool.agent_pool is not imported",
+	}, UnexpectedImportChanges(plan, roundTripTargets))
+}
@@ -0,0 +1,51 @@
+# Shared resource group, virtual network and Log Analytics workspace leased to the basic fixtures of several suites
+
+terraform {
+  required_version = ">= 1.12.2"
+
+  required_providers {
+    azurerm = {
+      source  = "hashicorp/azurerm"
+      version = "4.57.0"
+    }
+  }
+}
+
+provider "azurerm" {
+  features {
+    resource_group {
+      # Teardown runs after every lease is released; anything a crashed test left behind goes with the group
+      prevent_deletion_if_contains_resources = false
+    }
+  }
+}
+
+resource "azurerm_resource_group" "baseline" {
+  name     = "rg-baseline-pool-${var.random_suffix}"
+  location = var.location
+}
+
+resource "azurerm_virtual_network" "baseline" {
+  name                = "vnet-baseline-pool-${var.random_suffix}"
+  location            = azurerm_resource_group.baseline.location
+  resource_group_name = azurerm_resource_group.baseline.name
+  address_space       = [var.address_space]
+
+  tags = {
+    Environment = "Test"
+    Example     = "Pool"
+  }
+}
+
+resource "azurerm_log_analytics_workspace" "baseline" {
+  name                = "law-baseline-pool-${var.random_suffix}"
+  location            = azurerm_resource_group.baseline.location
+  resource_group_name = azurerm_resource_group.baseline.name
+  sku                 = "PerGB2018"
+  retention_in_days   = 30
+
+  tags = {
+    Environment = "Test"
+    Example     = "Pool"
+  }
+}
@@ -0,0 +1,29 @@
+output "location" {
+  description = "Pool location"
+  value       = azurerm_resource_group.baseline.location
+}
+
+output "resource_group_name" {
+  description = "Pool resource group name"
+  value       = azurerm_resource_group.baseline.name
+}
+
+output "virtual_network_name" {
+  description = "Pool virtual network name"
+  value       = azurerm_virtual_network.baseline.name
+}
+
+output "virtual_network_id" {
+  description = "Pool virtual network ID"
+  value       = azurerm_virtual_network.baseline.id
+}
+
+output "address_space" {
+  description = "Pool virtual network address space"
+  value       = var.address_space
+}
+
+output "log_analytics_workspace_id" {
+  description = "Pool Log Analytics workspace ID"
+  value       = azurerm_log_analytics_workspace.baseline.id
+}
@@ -0,0 +1,16 @@
+variable "location" {
+  description = "Azure region for resources"
+  type        = string
+  default     = "northeurope"
+}
+
+variable "random_suffix" {
+  description = "Random suffix for unique resource naming"
+  type        = string
+}
+
+variable "address_space" {
+  description = "Virtual network address space; every lease gets one /24 of it"
+  type        = string
+  default     = "10.40.0.0/16"
+}
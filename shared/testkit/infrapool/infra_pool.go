@@ -0,0 +1,631 @@
+// Package infrapool provisions shared test infrastructure once per run and leases slots of it to parallel tests,
+// also across test binaries of different suites.
+package infrapool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// InfraPoolDirEnv overrides the directory holding pool state, leases and Terraform working directories
+	InfraPoolDirEnv = "INFRA_POOL_DIR"
+	// InfraPoolKeepEnv keeps pools after the run so the next run can reuse them
+	InfraPoolKeepEnv = "INFRA_POOL_KEEP"
+	// InfraPoolLeaseTimeoutEnv bounds how long Acquire waits for the pool lock or a free slot
+	InfraPoolLeaseTimeoutEnv = "INFRA_POOL_LEASE_TIMEOUT"
+
+	// LeaseVarsFile is written into the fixture so lease values never appear on the command line
+	LeaseVarsFile = "infra_pool.auto.tfvars.json"
+
+	poolStatusProvisioning = "provisioning"
+	poolStatusReady        = "ready"
+	poolStatusDirty        = "dirty"
+
+	poolPollInterval = 2 * time.Second
+	slotPollInterval = 30 * time.Second
+	// staleRecordAge expires records of other hosts, whose processes cannot be checked
+	staleRecordAge = 12 * time.Hour
+)
+
+// InfraPool provisions a fixture once per test run and leases it to parallel tests. State lives on disk so
+// several test binaries share one pool, and records of crashed processes are reclaimed.
+type InfraPool struct {
+	// Name is the pool directory name; it must be unique per fixture
+	Name string
+	// RootFolder and FixtureFolder are passed to test_structure like for test fixtures
+	RootFolder    string
+	FixtureFolder string
+	// Slots is the number of concurrent leases
+	Slots int
+	// Options returns the Terraform options for the pool fixture
+	Options func(t testing.TB, terraformDir string) *terraform.Options
+	// LeaseVars maps pool outputs to fixture variables for one lease
+	LeaseVars func(lease *Lease) (map[string]interface{}, error)
+
+	// joined is set once this process holds the pool; Close leaves pools of other processes alone
+	joined atomic.Bool
+}
+
+// PoolState is persisted in state.json
+type PoolState struct {
+	Status       string                 `json:"status"`
+	TerraformDir string                 `json:"terraform_dir"`
+	Outputs      map[string]interface{} `json:"outputs,omitempty"`
+	Owner        PoolRecord             `json:"owner"`
+}
+
+// PoolRecord identifies the process that owns a lock, a lease or a holder file
+type PoolRecord struct {
+	Test      string    `json:"test,omitempty"`
+	Host      string    `json:"host"`
+	PID       int       `json:"pid"`
+	Slot      int       `json:"slot"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Lease is one slot of a pool held by a test
+type Lease struct {
+	Pool    *InfraPool
+	Slot    int
+	Outputs map[string]interface{}
+	// Dir holds files written for the lease, such as kubeconfigs; it is removed on release
+	Dir  string
+	Vars map[string]interface{}
+
+	file string
+}
+
+// RunWithInfraPools runs the tests and tears down the pools this process used and no other process still holds; use it from TestMain
+func RunWithInfraPools(m *testing.M, pools ...*InfraPool) int {
+	code := m.Run()
+	for _, pool := range pools {
+		if err := pool.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "infra pool %s: %v\n", pool.Name, err)
+			if code == 0 {
+				code = 1
+			}
+		}
+	}
+	return code
+}
+
+// Dir returns the pool directory
+func (p *InfraPool) Dir() string {
+	root := os.Getenv(InfraPoolDirEnv)
+	if root == "" {
+		root = filepath.Join(os.TempDir(), "terratest-infra-pools")
+	}
+	return filepath.Join(root, p.Name)
+}
+
+// Acquire provisions the pool if needed and leases a free slot until the test ends
+func (p *InfraPool) Acquire(t testing.TB) *Lease {
+	t.Helper()
+
+	lease, err := p.AcquireE(t)
+	require.NoError(t, err, "Failed to lease from infra pool %s", p.Name)
+	t.Cleanup(func() {
+		if err := lease.Release(); err != nil {
+			t.Logf("Failed to release %s slot %d: %v", p.Name, lease.Slot, err)
+		}
+	})
+	return lease
+}
+
+// AcquireE is Acquire without registering the release; the caller must call Release
+func (p *InfraPool) AcquireE(t testing.TB) (*Lease, error) {
+	t.Helper()
+
+	timeout := leaseTimeout()
+	if err := p.join(); err != nil {
+		return nil, err
+	}
+	outputs, err := p.ensureReady(t, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		record := newPoolRecord(t.Name())
+		slot, file, err := ClaimSlotE(p.Dir(), p.Slots, record)
+		if err == nil {
+			lease := &Lease{Pool: p, Slot: slot, Outputs: outputs, Dir: filepath.Join(p.Dir(), "leases", strconv.Itoa(slot)), file: file}
+			if err := lease.init(); err != nil {
+				_ = lease.Release()
+				return nil, err
+			}
+			t.Logf("Leased %s slot %d", p.Name, slot)
+			return lease, nil
+		}
+		if !errors.Is(err, errNoFreeSlot) {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("no free slot in %s after %s", p.Name, timeout)
+		}
+		t.Logf("All %d slots of %s are leased; waiting", p.Slots, p.Name)
+		time.Sleep(slotPollInterval)
+	}
+}
+
+// Close releases this process's leases and holder record and destroys the pool when no other process holds it.
+// Pools this process never acquired are left alone, whoever provisioned them.
+func (p *InfraPool) Close() error {
+	if !p.joined.Load() {
+		return nil
+	}
+	dir := p.Dir()
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+
+	unlock, err := lockPool(dir, leaseTimeout())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	own := newPoolRecord("")
+	ownRecord := func(file recordFile) bool { return file.record.Host == own.Host && file.record.PID == own.PID }
+	for _, kind := range []string{"leases", "holders"} {
+		if err := removeRecords(filepath.Join(dir, kind), ownRecord); err != nil {
+			return err
+		}
+	}
+	refs, err := RefCount(dir)
+	if err != nil {
+		return err
+	}
+	if refs > 0 {
+		fmt.Fprintf(os.Stderr, "infra pool %s: %d references left, skipping teardown\n", p.Name, refs)
+		return nil
+	}
+	if strings.EqualFold(os.Getenv(InfraPoolKeepEnv), "true") {
+		fmt.Fprintf(os.Stderr, "infra pool %s: kept in %s (%s=true)\n", p.Name, dir, InfraPoolKeepEnv)
+		return nil
+	}
+
+	state, err := readPoolState(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return os.RemoveAll(dir)
+		}
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(state.TerraformDir, "terraform.tfstate")); os.IsNotExist(err) {
+		// Provisioning failed before apply wrote any state, so there is nothing to destroy
+		return os.RemoveAll(dir)
+	}
+	if err := destroyPool(state.TerraformDir); err != nil {
+		state.Status = poolStatusDirty
+		_ = writePoolState(dir, state)
+		return fmt.Errorf("destroy failed, state kept in %s: %w", dir, err)
+	}
+	return os.RemoveAll(dir)
+}
+
+// Output returns a string output of the pool fixture
+func (l *Lease) Output(name string) (string, error) {
+	value, ok := l.Outputs[name]
+	if !ok {
+		return "", fmt.Errorf("pool %s has no output %q", l.Pool.Name, name)
+	}
+	return fmt.Sprint(value), nil
+}
+
+// WriteFileE writes a private file into the lease directory and returns its path
+func (l *Lease) WriteFileE(name, content string) (string, error) {
+	path := filepath.Join(l.Dir, name)
+	return path, os.WriteFile(path, []byte(content), 0o600)
+}
+
+// WriteVarsFileE writes the lease variables, or only the named ones, into the fixture as an auto-loaded tfvars file
+func (l *Lease) WriteVarsFileE(terraformDir string, names ...string) error {
+	vars := l.Vars
+	if len(names) > 0 {
+		vars = make(map[string]interface{}, len(names))
+		for _, name := range names {
+			value, ok := l.Vars[name]
+			if !ok {
+				return fmt.Errorf("lease of %s has no variable %q", l.Pool.Name, name)
+			}
+			vars[name] = value
+		}
+	}
+	content, err := json.MarshalIndent(vars, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(terraformDir, LeaseVarsFile), content, 0o600)
+}
+
+// Release removes the lease directory and record, making the slot available
+func (l *Lease) Release() error {
+	if err := os.RemoveAll(l.Dir); err != nil {
+		return err
+	}
+	if err := os.Remove(l.file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (l *Lease) init() error {
+	// A crashed holder of the same slot may have left files behind
+	if err := os.RemoveAll(l.Dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(l.Dir, 0o700); err != nil {
+		return err
+	}
+	if l.Pool.LeaseVars == nil {
+		return nil
+	}
+	vars, err := l.Pool.LeaseVars(l)
+	l.Vars = vars
+	return err
+}
+
+// SubnetSlice returns the index-th subnet of prefix extended by newBits, like Terraform's cidrsubnet()
+func SubnetSlice(prefix string, newBits, index int) (string, error) {
+	_, network, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return "", err
+	}
+	ones, bits := network.Mask.Size()
+	if newBits <= 0 || ones+newBits > bits {
+		return "", fmt.Errorf("cannot extend %s by %d bits", prefix, newBits)
+	}
+	if index < 0 || index >= 1<<newBits {
+		return "", fmt.Errorf("%s has no subnet %d of /%d", prefix, index, ones+newBits)
+	}
+
+	base := new(big.Int).SetBytes(network.IP)
+	offset := new(big.Int).Lsh(big.NewInt(int64(index)), uint(bits-ones-newBits))
+	ip := base.Add(base, offset).FillBytes(make([]byte, len(network.IP)))
+	return fmt.Sprintf("%s/%d", net.IP(ip), ones+newBits), nil
+}
+
+var errNoFreeSlot = errors.New("no free slot")
+
+// ClaimSlotE reclaims stale leases and writes a lease record for the first free slot
+func ClaimSlotE(dir string, slots int, record PoolRecord) (int, string, error) {
+	leasesDir := filepath.Join(dir, "leases")
+	if err := os.MkdirAll(leasesDir, 0o700); err != nil {
+		return 0, "", err
+	}
+	if err := removeRecords(leasesDir, recordFile.stale); err != nil {
+		return 0, "", err
+	}
+	for slot := 0; slot < slots; slot++ {
+		record.Slot = slot
+		file := filepath.Join(leasesDir, fmt.Sprintf("%d.json", slot))
+		err := writeRecordExclusive(file, record)
+		if err == nil {
+			return slot, file, nil
+		}
+		if !os.IsExist(err) {
+			return 0, "", err
+		}
+	}
+	return 0, "", errNoFreeSlot
+}
+
+// RefCount reclaims stale records and counts the live leases and holders of a pool
+func RefCount(dir string) (int, error) {
+	count := 0
+	for _, kind := range []string{"leases", "holders"} {
+		recordsDir := filepath.Join(dir, kind)
+		if err := removeRecords(recordsDir, recordFile.stale); err != nil {
+			return 0, err
+		}
+		records, err := readRecords(recordsDir)
+		if err != nil {
+			return 0, err
+		}
+		count += len(records)
+	}
+	return count, nil
+}
+
+// IsStaleRecord reports whether the process behind a record is gone. Processes on other hosts, or on
+// platforms without signal 0, are assumed alive until the record is older than staleRecordAge.
+func IsStaleRecord(record PoolRecord) bool {
+	if time.Since(record.CreatedAt) > staleRecordAge {
+		return true
+	}
+	own := newPoolRecord("")
+	if record.Host != own.Host || runtime.GOOS == "windows" {
+		return false
+	}
+	if record.PID == own.PID {
+		return false
+	}
+	process, err := os.FindProcess(record.PID)
+	if err != nil {
+		return true
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err != nil && !errors.Is(err, os.ErrPermission)
+}
+
+// ensureReady returns the pool outputs, provisioning the fixture under the pool lock when it is not ready
+func (p *InfraPool) ensureReady(t testing.TB, timeout time.Duration) (map[string]interface{}, error) {
+	dir := p.Dir()
+	unlock, err := lockPool(dir, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	state, err := readPoolState(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil && state.Status == poolStatusReady {
+		return state.Outputs, nil
+	}
+
+	// Missing, interrupted or dirty pools are (re)applied in the same working directory
+	if state.TerraformDir == "" {
+		terraformDir, err := filepath.Abs(test_structure.CopyTerraformFolderToDest(t, p.RootFolder, p.FixtureFolder, dir))
+		if err != nil {
+			return nil, err
+		}
+		state.TerraformDir = terraformDir
+		test_structure.SaveTerraformOptions(t, state.TerraformDir, p.Options(t, state.TerraformDir))
+	}
+	state.Status = poolStatusProvisioning
+	state.Owner = newPoolRecord(t.Name())
+	if err := writePoolState(dir, state); err != nil {
+		return nil, err
+	}
+
+	t.Logf("Provisioning infra pool %s in %s", p.Name, state.TerraformDir)
+	options := test_structure.LoadTerraformOptions(t, state.TerraformDir)
+	if _, err := tfretry.InitAndApplyWithRetryE(t, options); err != nil {
+		return nil, fmt.Errorf("provisioning %s: %w", p.Name, err)
+	}
+	outputs, err := terraform.OutputAllE(t, options)
+	if err != nil {
+		return nil, err
+	}
+
+	state.Status = poolStatusReady
+	state.Outputs = outputs
+	return outputs, writePoolState(dir, state)
+}
+
+func destroyPool(terraformDir string) (err error) {
+	t := &poolT{name: "infra-pool-teardown"}
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("%v", recovered)
+		}
+	}()
+	options := test_structure.LoadTerraformOptions(t, terraformDir)
+	_, err = tfretry.DestroyWithRetryE(t, options)
+	return err
+}
+
+// lockPool takes the pool lock file, reclaiming it from crashed processes
+func lockPool(dir string, timeout time.Duration) (func(), error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	lockFile := filepath.Join(dir, "lock")
+	deadline := time.Now().Add(timeout)
+	for {
+		err := writeRecordExclusive(lockFile, newPoolRecord(""))
+		if err == nil {
+			return func() { _ = os.Remove(lockFile) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if file, readErr := readRecordFile(lockFile); readErr == nil && file.stale() {
+			_ = os.Remove(lockFile)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out after %s waiting for %s", timeout, lockFile)
+		}
+		time.Sleep(poolPollInterval)
+	}
+}
+
+// join registers this process as a holder of the pool
+func (p *InfraPool) join() error {
+	if err := registerHolder(p.Dir()); err != nil {
+		return err
+	}
+	p.joined.Store(true)
+	return nil
+}
+
+func registerHolder(dir string) error {
+	holdersDir := filepath.Join(dir, "holders")
+	if err := os.MkdirAll(holdersDir, 0o700); err != nil {
+		return err
+	}
+	record := newPoolRecord("")
+	err := writeRecordExclusive(filepath.Join(holdersDir, fmt.Sprintf("%s-%d.json", record.Host, record.PID)), record)
+	if os.IsExist(err) {
+		return nil
+	}
+	return err
+}
+
+func newPoolRecord(test string) PoolRecord {
+	host, _ := os.Hostname()
+	return PoolRecord{Test: test, Host: host, PID: os.Getpid(), CreatedAt: time.Now().UTC()}
+}
+
+func writeRecordExclusive(path string, record PoolRecord) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return json.NewEncoder(file).Encode(record)
+}
+
+func readRecord(path string) (PoolRecord, error) {
+	var record PoolRecord
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return record, err
+	}
+	return record, json.Unmarshal(content, &record)
+}
+
+// recordFile is a record on disk; a record that cannot be parsed is being written or was interrupted
+type recordFile struct {
+	record  PoolRecord
+	corrupt bool
+	modTime time.Time
+}
+
+func (f recordFile) stale() bool {
+	if f.corrupt {
+		return time.Since(f.modTime) > time.Minute
+	}
+	return IsStaleRecord(f.record)
+}
+
+func readRecordFile(path string) (recordFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return recordFile{}, err
+	}
+	record, err := readRecord(path)
+	if os.IsNotExist(err) {
+		return recordFile{}, err
+	}
+	return recordFile{record: record, corrupt: err != nil, modTime: info.ModTime()}, nil
+}
+
+func readRecords(dir string) (map[string]recordFile, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	records := make(map[string]recordFile, len(files))
+	for _, file := range files {
+		record, err := readRecordFile(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		records[file] = record
+	}
+	return records, nil
+}
+
+func removeRecords(dir string, remove func(recordFile) bool) error {
+	records, err := readRecords(dir)
+	if err != nil {
+		return err
+	}
+	for file, record := range records {
+		if !remove(record) {
+			continue
+		}
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.RemoveAll(strings.TrimSuffix(file, ".json")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readPoolState(dir string) (PoolState, error) {
+	var state PoolState
+	content, err := os.ReadFile(filepath.Join(dir, "state.json"))
+	if err != nil {
+		return state, err
+	}
+	return state, json.Unmarshal(content, &state)
+}
+
+// writePoolState replaces state.json atomically so a crash never leaves a partial file
+func writePoolState(dir string, state PoolState) error {
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	temp := filepath.Join(dir, "state.json.tmp")
+	if err := os.WriteFile(temp, content, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(temp, filepath.Join(dir, "state.json"))
+}
+
+func leaseTimeout() time.Duration {
+	if value := os.Getenv(InfraPoolLeaseTimeoutEnv); value != "" {
+		if timeout, err := time.ParseDuration(value); err == nil {
+			return timeout
+		}
+	}
+	return 60 * time.Minute
+}
+
+// poolT satisfies tfretry.TestingT outside of a test, for teardown from TestMain
+type poolT struct {
+	name string
+}
+
+func (t *poolT) Fail() {}
+
+func (t *poolT) FailNow() {
+	panic(t.name + " failed")
+}
+
+func (t *poolT) Fatal(args ...interface{}) {
+	panic(fmt.Sprint(args...))
+}
+
+func (t *poolT) Fatalf(format string, args ...interface{}) {
+	panic(fmt.Sprintf(format, args...))
+}
+
+func (t *poolT) Error(args ...interface{}) {
+	fmt.Fprintln(os.Stderr, args...)
+}
+
+func (t *poolT) Errorf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+}
+
+func (t *poolT) Name() string {
+	return t.name
+}
+
+func (t *poolT) Helper() {}
+
+func (t *poolT) Logf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+}
@@ -0,0 +1,178 @@
+package infrapool
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubnetSlice(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		prefix   string
+		newBits  int
+		index    int
+		expected string
+	}{
+		{"10.30.0.0/16", 8, 0, "10.30.0.0/24"},
+		{"10.30.0.0/16", 8, 5, "10.30.5.0/24"},
+		{"10.30.0.0/16", 4, 3, "10.30.48.0/20"},
+		{"10.30.4.0/22", 2, 3, "10.30.7.0/24"},
+	}
+	for _, tc := range testCases {
+		subnet, err := SubnetSlice(tc.prefix, tc.newBits, tc.index)
+		require.NoError(t, err)
+		assert.Equal(t, tc.expected, subnet, "%s +%d bits, index %d", tc.prefix, tc.newBits, tc.index)
+	}
+
+	_, err := SubnetSlice("10.30.0.0/16", 8, 256)
+	assert.Error(t, err, "index outside the prefix")
+	_, err = SubnetSlice("10.30.0.0/30", 4, 0)
+	assert.Error(t, err, "prefix too small")
+}
+
+func TestClaimSlotReclaimsStaleLeases(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	own := newPoolRecord(t.Name())
+
+	slot, first, err := ClaimSlotE(dir, 2, own)
+	require.NoError(t, err)
+	assert.Equal(t, 0, slot)
+	slot, _, err = ClaimSlotE(dir, 2, own)
+	require.NoError(t, err)
+	assert.Equal(t, 1, slot)
+	_, _, err = ClaimSlotE(dir, 2, own)
+	assert.ErrorIs(t, err, errNoFreeSlot, "leases of a live process are kept")
+
+	// Simulate a crashed test binary holding slot 0
+	require.NoError(t, os.Remove(first))
+	crashed := own
+	crashed.PID = exitedPID(t)
+	writeRecord(t, first, crashed)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "leases", "0"), 0o700))
+
+	slot, _, err = ClaimSlotE(dir, 2, own)
+	require.NoError(t, err)
+	assert.Equal(t, 0, slot, "slot of the crashed process is reclaimed")
+	assert.NoDirExists(t, filepath.Join(dir, "leases", "0"), "files of the crashed lease are removed")
+}
+
+func TestIsStaleRecord(t *testing.T) {
+	t.Parallel()
+
+	own := newPoolRecord(t.Name())
+	assert.False(t, IsStaleRecord(own))
+
+	otherHost := own
+	otherHost.Host = own.Host + "-ci-runner"
+	otherHost.PID = exitedPID(t)
+	assert.False(t, IsStaleRecord(otherHost), "processes on other hosts cannot be checked")
+	otherHost.CreatedAt = time.Now().Add(-2 * staleRecordAge)
+	assert.True(t, IsStaleRecord(otherHost), "old records expire")
+}
+
+func TestRefCountAndClose(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(InfraPoolDirEnv, dir)
+	pool := &InfraPool{Name: "refcount", Slots: 2}
+
+	require.NoError(t, pool.join())
+	require.NoError(t, pool.join(), "registering twice keeps one holder record")
+	_, _, err := ClaimSlotE(pool.Dir(), pool.Slots, newPoolRecord(t.Name()))
+	require.NoError(t, err)
+	refs, err := RefCount(pool.Dir())
+	require.NoError(t, err)
+	assert.Equal(t, 2, refs)
+
+	// Another live process still holds the pool
+	other := newPoolRecord("")
+	other.PID = os.Getppid()
+	otherHolder := filepath.Join(pool.Dir(), "holders", "other.json")
+	writeRecord(t, otherHolder, other)
+	// A record interrupted while being written long ago
+	corrupt := filepath.Join(pool.Dir(), "holders", "corrupt.json")
+	require.NoError(t, os.WriteFile(corrupt, nil, 0o600))
+	require.NoError(t, os.Chtimes(corrupt, time.Now().Add(-time.Hour), time.Now().Add(-time.Hour)))
+
+	require.NoError(t, pool.Close())
+	assert.DirExists(t, pool.Dir(), "pool held by another process is not torn down")
+	assert.NoFileExists(t, corrupt)
+	refs, err = RefCount(pool.Dir())
+	require.NoError(t, err)
+	assert.Equal(t, 1, refs, "only the other process's holder record is left")
+
+	require.NoError(t, os.Remove(otherHolder))
+	require.NoError(t, pool.Close())
+	assert.NoDirExists(t, pool.Dir(), "last reference removes a pool without state")
+}
+
+func TestCloseLeavesPoolsThisProcessNeverAcquired(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(InfraPoolDirEnv, dir)
+	pool := &InfraPool{Name: "foreign", Slots: 2}
+
+	// A pool kept by an earlier run, or provisioned by another suite's test binary
+	require.NoError(t, os.MkdirAll(pool.Dir(), 0o700))
+	require.NoError(t, writePoolState(pool.Dir(), PoolState{Status: poolStatusReady, TerraformDir: filepath.Join(dir, "missing")}))
+
+	require.NoError(t, pool.Close(), "no teardown, so no destroy of a fixture this process never used")
+	assert.FileExists(t, filepath.Join(pool.Dir(), "state.json"))
+
+	// Once joined, a pool whose provisioning never got to write state is removed without a destroy
+	require.NoError(t, pool.join())
+	require.NoError(t, pool.Close())
+	assert.NoDirExists(t, pool.Dir())
+}
+
+func TestLeaseWriteVarsFile(t *testing.T) {
+	t.Parallel()
+
+	lease := &Lease{
+		Pool: &InfraPool{Name: "vars"},
+		Dir:  t.TempDir(),
+		Vars: map[string]interface{}{"kubeconfig_path": "/tmp/kubeconfig", "secrets_provider_client_id": "client"},
+	}
+	fixtureDir := t.TempDir()
+
+	require.NoError(t, lease.WriteVarsFileE(fixtureDir, "kubeconfig_path"))
+	content, err := os.ReadFile(filepath.Join(fixtureDir, LeaseVarsFile))
+	require.NoError(t, err)
+	var vars map[string]interface{}
+	require.NoError(t, json.Unmarshal(content, &vars))
+	assert.Equal(t, map[string]interface{}{"kubeconfig_path": "/tmp/kubeconfig"}, vars)
+
+	info, err := os.Stat(filepath.Join(fixtureDir, LeaseVarsFile))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	assert.Error(t, lease.WriteVarsFileE(fixtureDir, "missing"))
+
+	path, err := lease.WriteFileE("kubeconfig", "apiVersion: v1")
+	require.NoError(t, err)
+	info, err = os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+// exitedPID returns the PID of a process that has already exited
+func exitedPID(t *testing.T) int {
+	t.Helper()
+	command := exec.Command("true")
+	require.NoError(t, command.Run())
+	return command.Process.Pid
+}
+
+func writeRecord(t *testing.T, path string, record PoolRecord) {
+	t.Helper()
+	content, err := json.Marshal(record)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, content, 0o600))
+}
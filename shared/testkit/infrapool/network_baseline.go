@@ -0,0 +1,99 @@
+package infrapool
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// Variables a fixture declares to run inside the network baseline
+const (
+	BaselineResourceGroupVar         = "baseline_resource_group_name"
+	BaselineVirtualNetworkVar        = "baseline_virtual_network_name"
+	BaselineSubnetAddressPrefixVar   = "baseline_subnet_address_prefix"
+	BaselineLogAnalyticsWorkspaceVar = "baseline_log_analytics_workspace_id"
+)
+
+// networkBaselineSubnetBits carves one /24 per slot out of the /16 address space
+const networkBaselineSubnetBits = 8
+
+// NetworkBaseline is a resource group, virtual network and Log Analytics workspace shared by the basic fixtures
+// of several suites. The pool state lives outside the suites, so test binaries of different modules lease the
+// same baseline and the last one to finish tears it down. Paths are relative to modules/<module>/tests.
+var NetworkBaseline = &InfraPool{
+	Name:          "network-baseline",
+	RootFolder:    "../../../shared/testkit/infrapool/fixtures",
+	FixtureFolder: "network_baseline",
+	Slots:         16,
+	Options:       networkBaselineOptions,
+	LeaseVars:     NetworkBaselineLeaseVars,
+}
+
+// NetworkBaselineLeaseVars passes the shared resources and the slot's own subnet address prefix to a fixture
+func NetworkBaselineLeaseVars(lease *Lease) (map[string]interface{}, error) {
+	vars := map[string]interface{}{}
+	for name, output := range map[string]string{
+		BaselineResourceGroupVar:         "resource_group_name",
+		BaselineVirtualNetworkVar:        "virtual_network_name",
+		BaselineLogAnalyticsWorkspaceVar: "log_analytics_workspace_id",
+	} {
+		value, err := lease.Output(output)
+		if err != nil {
+			return nil, err
+		}
+		vars[name] = value
+	}
+
+	addressSpace, err := lease.Output("address_space")
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := SubnetSlice(addressSpace, networkBaselineSubnetBits, lease.Slot)
+	if err != nil {
+		return nil, err
+	}
+	vars[BaselineSubnetAddressPrefixVar] = prefix
+	return vars, nil
+}
+
+// UseNetworkBaseline writes the named baseline variables into the fixture and deploys it to the baseline location
+func UseNetworkBaseline(lease *Lease, terraformOptions *terraform.Options, names ...string) error {
+	location, err := lease.Output("location")
+	if err != nil {
+		return err
+	}
+	if err := lease.WriteVarsFileE(terraformOptions.TerraformDir, names...); err != nil {
+		return err
+	}
+	if terraformOptions.Vars == nil {
+		terraformOptions.Vars = map[string]interface{}{}
+	}
+	// -var takes precedence over the vars file, so the location is overridden on the options instead
+	terraformOptions.Vars["location"] = location
+	return nil
+}
+
+func networkBaselineOptions(t testing.TB, terraformDir string) *terraform.Options {
+	location := os.Getenv("ARM_LOCATION")
+	if location == "" {
+		location = "northeurope"
+	}
+
+	return &terraform.Options{
+		TerraformDir: terraformDir,
+		Vars: map[string]interface{}{
+			"random_suffix": fmt.Sprintf("%s%03d", strings.ToLower(random.UniqueId())[:5], time.Now().UnixNano()%1000),
+			"location":      location,
+		},
+		NoColor:                  true,
+		RetryableTerraformErrors: tfretry.ClassifiedRetryableErrors(),
+		MaxRetries:               3,
+		TimeBetweenRetries:       10 * time.Second,
+	}
+}
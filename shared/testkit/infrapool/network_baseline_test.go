@@ -0,0 +1,61 @@
+package infrapool
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var baselineOutputs = map[string]interface{}{
+	"location":                   "northeurope",
+	"resource_group_name":        "rg-baseline-pool-abcde123",
+	"virtual_network_name":       "vnet-baseline-pool-abcde123",
+	"virtual_network_id":         "/subscriptions/s/resourceGroups/rg-baseline-pool-abcde123/providers/Microsoft.Network/virtualNetworks/vnet-baseline-pool-abcde123",
+	"address_space":              "10.40.0.0/16",
+	"log_analytics_workspace_id": "/subscriptions/s/resourceGroups/rg-baseline-pool-abcde123/providers/Microsoft.OperationalInsights/workspaces/law-baseline-pool-abcde123",
+}
+
+func TestNetworkBaselineLeaseVars(t *testing.T) {
+	t.Parallel()
+
+	vars, err := NetworkBaselineLeaseVars(&Lease{Pool: NetworkBaseline, Slot: 7, Outputs: baselineOutputs})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		BaselineResourceGroupVar:         "rg-baseline-pool-abcde123",
+		BaselineVirtualNetworkVar:        "vnet-baseline-pool-abcde123",
+		BaselineSubnetAddressPrefixVar:   "10.40.7.0/24",
+		BaselineLogAnalyticsWorkspaceVar: baselineOutputs["log_analytics_workspace_id"],
+	}, vars)
+
+	_, err = SubnetSlice("10.40.0.0/16", networkBaselineSubnetBits, NetworkBaseline.Slots-1)
+	assert.NoError(t, err, "every slot gets its own subnet")
+	_, err = NetworkBaselineLeaseVars(&Lease{Pool: NetworkBaseline, Outputs: map[string]interface{}{}})
+	assert.Error(t, err, "outputs missing")
+}
+
+func TestUseNetworkBaseline(t *testing.T) {
+	t.Parallel()
+
+	lease := &Lease{Pool: NetworkBaseline, Slot: 2, Outputs: baselineOutputs}
+	vars, err := NetworkBaselineLeaseVars(lease)
+	require.NoError(t, err)
+	lease.Vars = vars
+	options := &terraform.Options{TerraformDir: t.TempDir(), Vars: map[string]interface{}{"location": "westeurope", "random_suffix": "x"}}
+
+	require.NoError(t, UseNetworkBaseline(lease, options, BaselineResourceGroupVar, BaselineSubnetAddressPrefixVar))
+	assert.Equal(t, "northeurope", options.Vars["location"], "fixtures deploy next to the baseline")
+
+	content, err := os.ReadFile(filepath.Join(options.TerraformDir, LeaseVarsFile))
+	require.NoError(t, err)
+	var written map[string]interface{}
+	require.NoError(t, json.Unmarshal(content, &written))
+	assert.Equal(t, map[string]interface{}{
+		BaselineResourceGroupVar:       "rg-baseline-pool-abcde123",
+		BaselineSubnetAddressPrefixVar: "10.40.2.0/24",
+	}, written, "only the variables the fixture declares, since undeclared ones in a vars file produce warnings")
+}
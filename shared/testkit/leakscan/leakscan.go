@@ -0,0 +1,334 @@
+// Package leakscan fails tests whose credentials leak into Terraform outputs not marked
+// sensitive, into the Terratest log or into credential files left on disk.
+package leakscan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	terratesting "github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// SecretPattern is a named regular expression for one credential format. When the
+// pattern has a capture group, the first group is the secret value.
+type SecretPattern struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// SecretPatterns are matched against outputs, captured logs and sensitive values
+var SecretPatterns = []SecretPattern{
+	{"connection_string_key", regexp.MustCompile(`(?i)\b(?:AccountKey|SharedAccessKey|SharedAccessSignature|Password|Pwd)=([^;,"'\s]{8,})`)},
+	{"sas_token", regexp.MustCompile(`(?i)\bsig=([A-Za-z0-9%+/=]{20,})`)},
+	{"pem_private_key", regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )*PRIVATE KEY-----`)},
+	{"kubeconfig_credential", regexp.MustCompile(`\b(?:client-key-data|token):\s+"?([A-Za-z0-9+/=._-]{20,})`)},
+	{"azure_access_key", regexp.MustCompile(`(?:^|[^A-Za-z0-9+/])([A-Za-z0-9+/]{86}==)(?:$|[^A-Za-z0-9+/=])`)},
+	{"azure_devops_pat", regexp.MustCompile(`\b(?:[a-z2-7]{52}|[A-Za-z0-9]{76}AZDO[A-Za-z0-9]{4})\b`)},
+	{"github_token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+}
+
+// secretNamePattern matches output and attribute names that hold credentials, e.g.
+// administrator_password, api_key or APPLICATIONINSIGHTS_CONNECTION_STRING
+var secretNamePattern = regexp.MustCompile(`(?i)(?:^|_)(?:password|passwd|secret|access_?key|api_?key|primary_key|secondary_key|shared_key|instrumentation_?key|connection_?string|sas|sas_token|sas_url|token|kube_config|kube_config_raw|kube_admin_config_raw|client_key|private_key)s?$`)
+
+// minSecretLength keeps short sensitive values, which would match unrelated log text, out of the log scan
+const minSecretLength = 8
+
+// Finding is a credential found where it must not be; Match is redacted
+type Finding struct {
+	Source   string
+	Location string
+	Pattern  string
+	Match    string
+}
+
+func (f Finding) String() string {
+	location := f.Source
+	if f.Location != "" {
+		location += " (" + f.Location + ")"
+	}
+	return fmt.Sprintf("%s: %s %s", location, f.Pattern, f.Match)
+}
+
+// RedactSecret keeps the first characters of a secret so a finding can be located without printing it
+func RedactSecret(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) <= minSecretLength {
+		return fmt.Sprintf("[%d chars]", len(value))
+	}
+	return fmt.Sprintf("%s…[%d chars]", value[:4], len(value))
+}
+
+type secretMatch struct {
+	pattern string
+	value   string
+}
+
+func secretMatches(text string) []secretMatch {
+	var matches []secretMatch
+	for _, secret := range SecretPatterns {
+		for _, groups := range secret.Pattern.FindAllStringSubmatch(text, -1) {
+			value := groups[0]
+			if len(groups) > 1 {
+				value = groups[1]
+			}
+			matches = append(matches, secretMatch{secret.Name, value})
+		}
+	}
+	return matches
+}
+
+// FindSecrets returns the SecretPatterns matches in text, redacted
+func FindSecrets(text string) []Finding {
+	var findings []Finding
+	for _, match := range secretMatches(text) {
+		findings = append(findings, Finding{Pattern: match.pattern, Match: RedactSecret(match.value)})
+	}
+	return findings
+}
+
+// OutputScan is the result of scanning terraform output -json
+type OutputScan struct {
+	// Findings are credentials in outputs that are not marked sensitive
+	Findings []Finding
+	// Secrets are values of sensitive outputs that must not appear in logs
+	Secrets []string
+}
+
+// ScanOutputsE flags outputs not marked sensitive whose name or value looks like a
+// credential, and collects the credential values of sensitive outputs
+func ScanOutputsE(outputJSON []byte) (OutputScan, error) {
+	var outputs map[string]struct {
+		Sensitive bool        `json:"sensitive"`
+		Value     interface{} `json:"value"`
+	}
+	if err := json.Unmarshal(outputJSON, &outputs); err != nil {
+		return OutputScan{}, fmt.Errorf("failed to parse terraform output -json: %w", err)
+	}
+
+	var scan OutputScan
+	for _, name := range sortedKeys(outputs) {
+		output := outputs[name]
+		if value, ok := output.Value.(string); ok && output.Sensitive && !strings.Contains(value, "\n") && len(value) >= minSecretLength {
+			scan.Secrets = append(scan.Secrets, value)
+			continue
+		}
+
+		walkOutputStrings(name, name, output.Value, func(path, key, value string) {
+			namedSecret := secretNameLike(key) && value != ""
+			if output.Sensitive {
+				if namedSecret && len(value) >= minSecretLength {
+					scan.Secrets = append(scan.Secrets, value)
+				}
+				for _, match := range secretMatches(value) {
+					scan.Secrets = append(scan.Secrets, match.value)
+				}
+				return
+			}
+
+			if namedSecret {
+				scan.Findings = append(scan.Findings, Finding{Source: "output " + name, Location: path, Pattern: "credential_name", Match: RedactSecret(value)})
+			}
+			for _, finding := range FindSecrets(value) {
+				finding.Source, finding.Location = "output "+name, path
+				scan.Findings = append(scan.Findings, finding)
+			}
+		})
+	}
+	return scan, nil
+}
+
+func secretNameLike(name string) bool {
+	return secretNamePattern.MatchString(strings.ReplaceAll(name, "-", "_"))
+}
+
+// walkOutputStrings visits every string in an output value with its path and the
+// name of the attribute holding it; list elements keep the name of their list
+func walkOutputStrings(path, key string, value interface{}, visit func(path, key, value string)) {
+	switch typed := value.(type) {
+	case string:
+		visit(path, key, typed)
+	case map[string]interface{}:
+		for _, name := range sortedKeys(typed) {
+			walkOutputStrings(path+"."+name, name, typed[name], visit)
+		}
+	case []interface{}:
+		for i, item := range typed {
+			walkOutputStrings(path+"."+strconv.Itoa(i), key, item, visit)
+		}
+	}
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ScanText flags lines of text that match SecretPatterns or contain one of the secrets
+func ScanText(source, text string, secrets []string) []Finding {
+	return scanLines(source, text, secrets, 1)
+}
+
+func scanLines(source, text string, secrets []string, firstLine int) []Finding {
+	var findings []Finding
+	for i, line := range strings.Split(text, "\n") {
+		location := fmt.Sprintf("line %d", firstLine+i)
+		for _, finding := range FindSecrets(line) {
+			finding.Source, finding.Location = source, location
+			findings = append(findings, finding)
+		}
+		for _, secret := range secrets {
+			if len(secret) >= minSecretLength && strings.Contains(line, secret) {
+				findings = append(findings, Finding{Source: source, Location: location, Pattern: "sensitive_output_value", Match: RedactSecret(secret)})
+			}
+		}
+	}
+	return findings
+}
+
+// CheckCredentialFileE checks a file holding credentials: while it exists it must be
+// readable by the owner only, and once the test is done it must have been removed
+func CheckCredentialFileE(path string, mustBeRemoved bool) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if mustBeRemoved {
+		return fmt.Errorf("credential file %s was not removed", path)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		return fmt.Errorf("credential file %s has permissions %#o, want 0600", path, info.Mode().Perm())
+	}
+	return nil
+}
+
+// SensitiveOutput reads an output without writing its value to the test log
+func SensitiveOutput(t testing.TB, terraformOptions *terraform.Options, name string) string {
+	quiet := *terraformOptions
+	quiet.Logger = logger.Discard
+	return terraform.Output(t, &quiet, name)
+}
+
+// Scanner fails a test when credentials leak into outputs not marked sensitive,
+// into the Terratest log of the options it is attached to, or into credential files
+// left behind by the test
+type Scanner struct {
+	t       testing.TB
+	logger  *logger.Logger
+	mu      sync.Mutex
+	log     strings.Builder
+	scanned int
+	secrets []string
+	files   []string
+}
+
+// NewScanner creates a scanner that checks the logs and tracked files again when
+// the test ends. Create it before acquiring pooled infrastructure so that check runs
+// after the lease is released.
+func NewScanner(t testing.TB) *Scanner {
+	scanner := &Scanner{t: t}
+	scanner.logger = logger.New(scanner)
+	t.Cleanup(scanner.finish)
+	return scanner
+}
+
+// Logf captures a log line and forwards it to the default Terratest logger
+func (s *Scanner) Logf(t terratesting.TestingT, format string, args ...interface{}) {
+	line := fmt.Sprintf(format, args...)
+	s.mu.Lock()
+	s.log.WriteString(line)
+	s.log.WriteByte('\n')
+	s.mu.Unlock()
+	logger.Default.Logf(t, "%s", line)
+}
+
+// Attach captures the logs of terraform commands run with the options; options
+// loaded from a test stage need to be attached again
+func (s *Scanner) Attach(terraformOptions *terraform.Options) *terraform.Options {
+	terraformOptions.Logger = s.logger
+	return terraformOptions
+}
+
+// TrackCredentialFile registers a file holding credentials written for the test
+func (s *Scanner) TrackCredentialFile(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files = append(s.files, path)
+}
+
+// ScanAfterApply scans the outputs, the logs captured so far and the tracked files
+func (s *Scanner) ScanAfterApply(terraformOptions *terraform.Options) {
+	s.t.Helper()
+
+	quiet := *terraformOptions
+	quiet.Logger = logger.Discard
+	outputJSON, err := terraform.OutputJsonE(s.t, &quiet, "")
+	require.NoError(s.t, err, "Failed to read outputs for the leak scan")
+	scan, err := ScanOutputsE([]byte(outputJSON))
+	require.NoError(s.t, err)
+
+	s.mu.Lock()
+	s.secrets = append(s.secrets, scan.Secrets...)
+	s.mu.Unlock()
+
+	s.report(scan.Findings)
+	s.report(s.scanLog(true))
+	s.checkFiles(false)
+}
+
+func (s *Scanner) finish() {
+	s.report(s.scanLog(false))
+	s.checkFiles(true)
+}
+
+// scanLog scans the captured log, or only the part not scanned yet
+func (s *Scanner) scanLog(all bool) []Finding {
+	s.mu.Lock()
+	text := s.log.String()
+	start := s.scanned
+	if all {
+		start = 0
+	}
+	s.scanned = len(text)
+	secrets := append([]string(nil), s.secrets...)
+	s.mu.Unlock()
+
+	return scanLines("test log", text[start:], secrets, strings.Count(text[:start], "\n")+1)
+}
+
+func (s *Scanner) checkFiles(mustBeRemoved bool) {
+	s.mu.Lock()
+	files := append([]string(nil), s.files...)
+	s.mu.Unlock()
+
+	for _, path := range files {
+		if err := CheckCredentialFileE(path, mustBeRemoved); err != nil {
+			s.t.Errorf("credential leak: %v", err)
+		}
+	}
+}
+
+func (s *Scanner) report(findings []Finding) {
+	for _, finding := range findings {
+		s.t.Errorf("credential leak: %s", finding)
+	}
+}
@@ -0,0 +1,406 @@
+// Package naming keeps the Azure naming rules of the resource types the modules create, and generates and
+// validates test resource names and the naming runs of unit/naming.tftest.hcl from them.
+package naming
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/stretchr/testify/require"
+)
+
+// Scope is the scope in which a resource name must be unique
+type Scope string
+
+const (
+	ScopeGlobal        Scope = "global"
+	ScopeSubscription  Scope = "subscription"
+	ScopeResourceGroup Scope = "resource_group"
+	ScopeParent        Scope = "parent"
+)
+
+// Rule describes the Azure naming constraints of one resource type.
+// Character sets are regexp character class bodies, e.g. "a-z0-9-".
+type Rule struct {
+	ResourceType string `json:"resource_type"`
+	Abbreviation string `json:"abbreviation,omitempty"`
+	MinLength    int    `json:"min_length"`
+	MaxLength    int    `json:"max_length"`
+	Charset      string `json:"charset"`
+	Start        string `json:"start,omitempty"`
+	End          string `json:"end,omitempty"`
+	Lowercase    bool   `json:"lowercase,omitempty"`
+	NoDoubleDash bool   `json:"no_consecutive_hyphens,omitempty"`
+	Scope        Scope  `json:"scope"`
+}
+
+const (
+	alphanumeric      = "a-zA-Z0-9"
+	lowerAlphanumeric = "a-z0-9"
+	networkCharset    = "a-zA-Z0-9._-"
+	networkEnd        = "a-zA-Z0-9_"
+)
+
+// Rules is the registry of naming constraints for the resource types the
+// modules and their fixtures create. Resources named by Azure (role
+// assignments, workbooks) are not listed.
+var Rules = []Rule{
+	{ResourceType: "azurerm_resource_group", Abbreviation: "rg", MinLength: 1, MaxLength: 90, Charset: `a-zA-Z0-9._()-`, End: `a-zA-Z0-9_()-`, Scope: ScopeSubscription},
+
+	{ResourceType: "azurerm_storage_account", Abbreviation: "st", MinLength: 3, MaxLength: 24, Charset: lowerAlphanumeric, Lowercase: true, Scope: ScopeGlobal},
+	{ResourceType: "azurerm_storage_container", MinLength: 3, MaxLength: 63, Charset: "a-z0-9-", Start: lowerAlphanumeric, End: lowerAlphanumeric, Lowercase: true, NoDoubleDash: true, Scope: ScopeParent},
+	{ResourceType: "azurerm_storage_queue", MinLength: 3, MaxLength: 63, Charset: "a-z0-9-", Start: lowerAlphanumeric, End: lowerAlphanumeric, Lowercase: true, NoDoubleDash: true, Scope: ScopeParent},
+	{ResourceType: "azurerm_storage_share", MinLength: 3, MaxLength: 63, Charset: "a-z0-9-", Start: lowerAlphanumeric, End: lowerAlphanumeric, Lowercase: true, NoDoubleDash: true, Scope: ScopeParent},
+	{ResourceType: "azurerm_storage_table", MinLength: 3, MaxLength: 63, Charset: alphanumeric, Start: "a-zA-Z", Scope: ScopeParent},
+
+	{ResourceType: "azurerm_virtual_network", Abbreviation: "vnet", MinLength: 2, MaxLength: 64, Charset: networkCharset, Start: alphanumeric, End: networkEnd, Scope: ScopeResourceGroup},
+	{ResourceType: "azurerm_subnet", Abbreviation: "snet", MinLength: 1, MaxLength: 80, Charset: networkCharset, Start: alphanumeric, End: networkEnd, Scope: ScopeParent},
+	{ResourceType: "azurerm_network_security_group", Abbreviation: "nsg", MinLength: 1, MaxLength: 80, Charset: networkCharset, Start: alphanumeric, End: networkEnd, Scope: ScopeResourceGroup},
+	{ResourceType: "azurerm_network_security_rule", Abbreviation: "nsgsr", MinLength: 1, MaxLength: 80, Charset: networkCharset, Start: alphanumeric, End: networkEnd, Scope: ScopeParent},
+	{ResourceType: "azurerm_route_table", Abbreviation: "rt", MinLength: 1, MaxLength: 80, Charset: networkCharset, Start: alphanumeric, End: networkEnd, Scope: ScopeResourceGroup},
+	{ResourceType: "azurerm_route", Abbreviation: "udr", MinLength: 1, MaxLength: 80, Charset: networkCharset, Start: alphanumeric, End: networkEnd, Scope: ScopeParent},
+	{ResourceType: "azurerm_bastion_host", Abbreviation: "bas", MinLength: 1, MaxLength: 80, Charset: networkCharset, Start: alphanumeric, End: networkEnd, Scope: ScopeResourceGroup},
+	{ResourceType: "azurerm_private_endpoint", Abbreviation: "pe", MinLength: 2, MaxLength: 64, Charset: networkCharset, Start: alphanumeric, End: networkEnd, Scope: ScopeResourceGroup},
+	{ResourceType: "azurerm_private_dns_zone", MinLength: 1, MaxLength: 253, Charset: "a-z0-9.-", Start: lowerAlphanumeric, End: lowerAlphanumeric, Lowercase: true, Scope: ScopeResourceGroup},
+	{ResourceType: "azurerm_private_dns_zone_virtual_network_link", Abbreviation: "pdnslink", MinLength: 1, MaxLength: 80, Charset: networkCharset, Start: alphanumeric, End: networkEnd, Scope: ScopeParent},
+
+	{ResourceType: "azurerm_key_vault", Abbreviation: "kv", MinLength: 3, MaxLength: 24, Charset: "a-zA-Z0-9-", Start: "a-zA-Z", End: alphanumeric, NoDoubleDash: true, Scope: ScopeGlobal},
+	{ResourceType: "azurerm_key_vault_secret", MinLength: 1, MaxLength: 127, Charset: "a-zA-Z0-9-", Scope: ScopeParent},
+	{ResourceType: "azurerm_key_vault_key", MinLength: 1, MaxLength: 127, Charset: "a-zA-Z0-9-", Scope: ScopeParent},
+	{ResourceType: "azurerm_key_vault_certificate", MinLength: 1, MaxLength: 127, Charset: "a-zA-Z0-9-", Scope: ScopeParent},
+
+	{ResourceType: "azurerm_kubernetes_cluster", Abbreviation: "aks", MinLength: 1, MaxLength: 63, Charset: "a-zA-Z0-9_-", Start: alphanumeric, End: alphanumeric, Scope: ScopeResourceGroup},
+	{ResourceType: "azurerm_kubernetes_cluster_node_pool", Abbreviation: "np", MinLength: 1, MaxLength: 12, Charset: lowerAlphanumeric, Start: "a-z", Lowercase: true, Scope: ScopeParent},
+	{ResourceType: "azurerm_user_assigned_identity", Abbreviation: "id", MinLength: 3, MaxLength: 128, Charset: "a-zA-Z0-9_-", Start: alphanumeric, Scope: ScopeResourceGroup},
+	{ResourceType: "azurerm_federated_identity_credential", MinLength: 3, MaxLength: 120, Charset: "a-zA-Z0-9_-", Start: alphanumeric, Scope: ScopeParent},
+
+	{ResourceType: "azurerm_linux_virtual_machine", Abbreviation: "vm", MinLength: 1, MaxLength: 64, Charset: "a-zA-Z0-9-", Start: alphanumeric, End: alphanumeric, Scope: ScopeResourceGroup},
+	{ResourceType: "azurerm_windows_virtual_machine", Abbreviation: "vm", MinLength: 1, MaxLength: 15, Charset: "a-zA-Z0-9-", Start: alphanumeric, End: alphanumeric, Scope: ScopeResourceGroup},
+	{ResourceType: "azurerm_managed_disk", Abbreviation: "disk", MinLength: 1, MaxLength: 80, Charset: networkCharset, Start: alphanumeric, End: networkEnd, Scope: ScopeResourceGroup},
+	{ResourceType: "azurerm_linux_function_app", Abbreviation: "func", MinLength: 2, MaxLength: 60, Charset: "a-zA-Z0-9-", Start: alphanumeric, End: alphanumeric, Scope: ScopeGlobal},
+	{ResourceType: "azurerm_windows_function_app", Abbreviation: "func", MinLength: 2, MaxLength: 60, Charset: "a-zA-Z0-9-", Start: alphanumeric, End: alphanumeric, Scope: ScopeGlobal},
+	{ResourceType: "azurerm_linux_function_app_slot", MinLength: 2, MaxLength: 59, Charset: "a-zA-Z0-9-", Start: alphanumeric, End: alphanumeric, Scope: ScopeParent},
+	{ResourceType: "azurerm_windows_function_app_slot", MinLength: 2, MaxLength: 59, Charset: "a-zA-Z0-9-", Start: alphanumeric, End: alphanumeric, Scope: ScopeParent},
+
+	{ResourceType: "azurerm_eventhub_namespace", Abbreviation: "evhns", MinLength: 6, MaxLength: 50, Charset: "a-zA-Z0-9-", Start: "a-zA-Z", End: alphanumeric, Scope: ScopeGlobal},
+	{ResourceType: "azurerm_eventhub", Abbreviation: "evh", MinLength: 1, MaxLength: 256, Charset: networkCharset, Start: alphanumeric, End: alphanumeric, Scope: ScopeParent},
+	{ResourceType: "azurerm_eventhub_consumer_group", MinLength: 1, MaxLength: 50, Charset: networkCharset, Start: alphanumeric, End: alphanumeric, Scope: ScopeParent},
+	{ResourceType: "azurerm_eventhub_authorization_rule", MinLength: 1, MaxLength: 256, Charset: networkCharset, Start: alphanumeric, End: alphanumeric, Scope: ScopeParent},
+	{ResourceType: "azurerm_eventhub_namespace_authorization_rule", MinLength: 1, MaxLength: 256, Charset: networkCharset, Start: alphanumeric, End: alphanumeric, Scope: ScopeParent},
+
+	{ResourceType: "azurerm_cognitive_account", Abbreviation: "cog", MinLength: 2, MaxLength: 64, Charset: "a-zA-Z0-9-", Start: alphanumeric, Scope: ScopeResourceGroup},
+	{ResourceType: "azurerm_ai_services", Abbreviation: "ais", MinLength: 2, MaxLength: 64, Charset: "a-zA-Z0-9-", Start: alphanumeric, Scope: ScopeResourceGroup},
+	{ResourceType: "azurerm_cognitive_deployment", MinLength: 2, MaxLength: 64, Charset: networkCharset, Start: alphanumeric, Scope: ScopeParent},
+
+	{ResourceType: "azurerm_log_analytics_workspace", Abbreviation: "log", MinLength: 4, MaxLength: 63, Charset: "a-zA-Z0-9-", Start: alphanumeric, End: alphanumeric, Scope: ScopeResourceGroup},
+	{ResourceType: "azurerm_log_analytics_cluster", Abbreviation: "logc", MinLength: 4, MaxLength: 63, Charset: "a-zA-Z0-9-", Start: alphanumeric, End: alphanumeric, Scope: ScopeResourceGroup},
+	{ResourceType: "azurerm_application_insights", Abbreviation: "appi", MinLength: 1, MaxLength: 260, Charset: `^%&\\?/\x00-\x1f`, End: `^%&\\?/\x00-\x20.`, Scope: ScopeResourceGroup},
+	{ResourceType: "azurerm_monitor_data_collection_endpoint", Abbreviation: "dce", MinLength: 3, MaxLength: 44, Charset: "a-zA-Z0-9-", Start: alphanumeric, End: alphanumeric, Scope: ScopeResourceGroup},
+	{ResourceType: "azurerm_monitor_data_collection_rule", Abbreviation: "dcr", MinLength: 1, MaxLength: 64, Charset: networkCharset, Start: alphanumeric, End: alphanumeric, Scope: ScopeResourceGroup},
+	{ResourceType: "azurerm_monitor_private_link_scope", Abbreviation: "ampls", MinLength: 1, MaxLength: 255, Charset: `a-zA-Z0-9._()-`, End: `a-zA-Z0-9_()-`, Scope: ScopeResourceGroup},
+
+	{ResourceType: "azurerm_postgresql_flexible_server", Abbreviation: "psql", MinLength: 3, MaxLength: 63, Charset: "a-z0-9-", Start: lowerAlphanumeric, End: lowerAlphanumeric, Lowercase: true, Scope: ScopeGlobal},
+	{ResourceType: "azurerm_postgresql_flexible_server_database", MinLength: 1, MaxLength: 63, Charset: "a-zA-Z0-9_-", Start: "a-zA-Z_", Scope: ScopeParent},
+	{ResourceType: "azurerm_postgresql_flexible_server_firewall_rule", MinLength: 1, MaxLength: 128, Charset: "a-zA-Z0-9_-", Scope: ScopeParent},
+	{ResourceType: "azurerm_redis_cache", Abbreviation: "redis", MinLength: 1, MaxLength: 63, Charset: "a-zA-Z0-9-", Start: alphanumeric, End: alphanumeric, NoDoubleDash: true, Scope: ScopeGlobal},
+	{ResourceType: "azurerm_managed_redis", Abbreviation: "amr", MinLength: 1, MaxLength: 60, Charset: "a-zA-Z0-9-", Start: alphanumeric, End: alphanumeric, NoDoubleDash: true, Scope: ScopeGlobal},
+}
+
+// nameFiller supplies characters for generated sample names
+const nameFiller = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// LookupRule returns the rule for a Terraform resource type
+func LookupRule(resourceType string) (Rule, bool) {
+	for _, rule := range Rules {
+		if rule.ResourceType == resourceType {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+func mustRule(resourceType string) (Rule, error) {
+	rule, ok := LookupRule(resourceType)
+	if !ok {
+		return Rule{}, fmt.Errorf("no naming rule for %s", resourceType)
+	}
+	return rule, nil
+}
+
+// Regex returns a regular expression for Terraform validations; it does not
+// cover the Lowercase and NoDoubleDash checks
+func (r Rule) Regex() string {
+	start, end := r.startClass(), r.endClass()
+	switch {
+	case r.MaxLength == 1:
+		return fmt.Sprintf("^[%s]$", start)
+	case r.MinLength <= 1:
+		return fmt.Sprintf("^[%s]([%s]{0,%d}[%s])?$", start, r.Charset, r.MaxLength-2, end)
+	default:
+		return fmt.Sprintf("^[%s][%s]{%d,%d}[%s]$", start, r.Charset, r.MinLength-2, r.MaxLength-2, end)
+	}
+}
+
+func (r Rule) startClass() string {
+	if r.Start == "" {
+		return r.Charset
+	}
+	return r.Start
+}
+
+func (r Rule) endClass() string {
+	if r.End == "" {
+		return r.Charset
+	}
+	return r.End
+}
+
+func classMatches(class string, char rune) bool {
+	return regexp.MustCompile("^[" + class + "]$").MatchString(string(char))
+}
+
+// Validate returns an error describing the first rule the name breaks
+func (r Rule) Validate(name string) error {
+	runes := []rune(name)
+	switch {
+	case len(runes) < r.MinLength || len(runes) > r.MaxLength:
+		return fmt.Errorf("%s name %q must be %d-%d characters long, got %d", r.ResourceType, name, r.MinLength, r.MaxLength, len(runes))
+	case r.Lowercase && strings.ToLower(name) != name:
+		return fmt.Errorf("%s name %q must be lower case", r.ResourceType, name)
+	case !classMatches(r.startClass(), runes[0]):
+		return fmt.Errorf("%s name %q cannot start with %q", r.ResourceType, name, runes[0])
+	case !classMatches(r.endClass(), runes[len(runes)-1]):
+		return fmt.Errorf("%s name %q cannot end with %q", r.ResourceType, name, runes[len(runes)-1])
+	case r.NoDoubleDash && strings.Contains(name, "--"):
+		return fmt.Errorf("%s name %q cannot contain consecutive hyphens", r.ResourceType, name)
+	}
+	for _, char := range runes {
+		if !classMatches(r.Charset, char) {
+			return fmt.Errorf("%s name %q cannot contain %q", r.ResourceType, name, char)
+		}
+	}
+	return nil
+}
+
+// ValidateResourceName checks a name against the rule of its resource type
+func ValidateResourceName(resourceType, name string) error {
+	rule, err := mustRule(resourceType)
+	if err != nil {
+		return err
+	}
+	return rule.Validate(name)
+}
+
+// GenerateResourceNameE builds a valid name from a prefix and unique ID. Invalid
+// characters are dropped and the prefix, not the unique ID, is shortened to fit.
+func GenerateResourceNameE(resourceType, prefix, uniqueID string) (string, error) {
+	rule, err := mustRule(resourceType)
+	if err != nil {
+		return "", err
+	}
+
+	keep := func(value string) string {
+		var kept strings.Builder
+		for _, char := range strings.ToLower(value) {
+			if classMatches(rule.Charset, char) {
+				kept.WriteRune(char)
+			}
+		}
+		return kept.String()
+	}
+	prefix, uniqueID = keep(prefix), keep(uniqueID)
+
+	separator := ""
+	if prefix != "" && uniqueID != "" && classMatches(rule.Charset, '-') {
+		separator = "-"
+	}
+	if len(uniqueID) > rule.MaxLength {
+		uniqueID = uniqueID[len(uniqueID)-rule.MaxLength:]
+	}
+	if room := rule.MaxLength - len(uniqueID) - len(separator); len(prefix) > room {
+		prefix = strings.TrimRight(prefix[:max(room, 0)], "-._")
+		if prefix == "" {
+			separator = ""
+		}
+	}
+	name := prefix + separator + uniqueID
+	if rule.NoDoubleDash {
+		for strings.Contains(name, "--") {
+			name = strings.ReplaceAll(name, "--", "-")
+		}
+	}
+
+	name = strings.TrimLeftFunc(name, func(char rune) bool { return !classMatches(rule.startClass(), char) })
+	name = strings.TrimRightFunc(name, func(char rune) bool { return !classMatches(rule.endClass(), char) })
+	if name == "" || len(name) < rule.MinLength {
+		name = sampleName(rule, rule.MinLength-len(name)) + name
+		name = strings.TrimRightFunc(name, func(char rune) bool { return !classMatches(rule.endClass(), char) })
+	}
+	if len(name) > rule.MaxLength {
+		name = name[:rule.MaxLength]
+	}
+	return name, rule.Validate(name)
+}
+
+// GenerateResourceName builds a valid name from a prefix and unique ID
+func GenerateResourceName(t testing.TB, resourceType, prefix, uniqueID string) string {
+	t.Helper()
+	name, err := GenerateResourceNameE(resourceType, prefix, uniqueID)
+	require.NoError(t, err)
+	return name
+}
+
+// GenerateUniqueResourceName builds a valid name from a prefix and a random unique ID
+func GenerateUniqueResourceName(t testing.TB, resourceType, prefix string) string {
+	t.Helper()
+	return GenerateResourceName(t, resourceType, prefix, strings.ToLower(random.UniqueId()))
+}
+
+// sampleName returns a valid name of the given length, or the shortest valid name
+func sampleName(rule Rule, length int) string {
+	length = max(length, 1)
+	var filler []rune
+	for _, char := range nameFiller {
+		if classMatches(rule.Charset, char) {
+			filler = append(filler, char)
+		}
+	}
+	pick := func(class string, offset int) rune {
+		for i := range filler {
+			if char := filler[(offset+i)%len(filler)]; classMatches(class, char) {
+				return char
+			}
+		}
+		return filler[0]
+	}
+
+	name := []rune{pick(rule.startClass(), 0)}
+	for i := 1; i < length-1; i++ {
+		name = append(name, filler[i%len(filler)])
+	}
+	if length > 1 {
+		name = append(name, pick(rule.endClass(), length-1))
+	}
+	return string(name)
+}
+
+// TestCase is a name a module's validation must accept or reject
+type TestCase struct {
+	Run         string
+	Name        string
+	Valid       bool
+	Description string
+}
+
+// TestCases derives accepted and rejected names from the rule
+func (r Rule) TestCases() []TestCase {
+	cases := []TestCase{
+		{Run: "naming_rule_min_length", Name: sampleName(r, r.MinLength), Valid: true, Description: fmt.Sprintf("%d characters", r.MinLength)},
+		{Run: "naming_rule_max_length", Name: sampleName(r, r.MaxLength), Valid: true, Description: fmt.Sprintf("%d characters", r.MaxLength)},
+	}
+	if generated, err := GenerateResourceNameE(r.ResourceType, "naming-test", "a1b2c3"); err == nil {
+		cases = append(cases, TestCase{Run: "naming_rule_generated", Name: generated, Valid: true, Description: "a prefix and unique ID"})
+	}
+	if r.MinLength > 1 {
+		cases = append(cases, TestCase{Run: "naming_rule_too_short", Name: sampleName(r, r.MinLength-1), Description: fmt.Sprintf("%d characters", r.MinLength-1)})
+	}
+	cases = append(cases, TestCase{Run: "naming_rule_too_long", Name: sampleName(r, r.MaxLength+1), Description: fmt.Sprintf("%d characters", r.MaxLength+1)})
+
+	base := sampleName(r, max(r.MinLength, 4))
+	middle := len(base) / 2
+	for _, char := range "!_-.A" {
+		if !classMatches(r.Charset, char) && (char != 'A' || !r.Lowercase) {
+			cases = append(cases, TestCase{Run: "naming_rule_invalid_character", Name: base[:middle] + string(char) + base[middle:], Description: fmt.Sprintf("the character %q", char)})
+			break
+		}
+	}
+	if r.Lowercase {
+		cases = append(cases, TestCase{Run: "naming_rule_uppercase", Name: strings.ToUpper(base[:1]) + base[1:], Description: "upper case letters"})
+	}
+	for _, char := range "-._0" {
+		if classMatches(r.Charset, char) && !classMatches(r.startClass(), char) {
+			cases = append(cases, TestCase{Run: "naming_rule_invalid_start", Name: string(char) + base[1:], Description: fmt.Sprintf("a leading %q", char)})
+			break
+		}
+	}
+	for _, char := range "-._" {
+		if classMatches(r.Charset, char) && !classMatches(r.endClass(), char) {
+			cases = append(cases, TestCase{Run: "naming_rule_invalid_end", Name: base[:len(base)-1] + string(char), Description: fmt.Sprintf("a trailing %q", char)})
+			break
+		}
+	}
+	if r.NoDoubleDash {
+		cases = append(cases, TestCase{Run: "naming_rule_consecutive_hyphens", Name: base[:middle] + "--" + base[middle:], Description: "consecutive hyphens"})
+	}
+	return cases
+}
+
+const (
+	namingTestsBegin = "# BEGIN GENERATED NAMING RULES"
+	namingTestsEnd   = "# END GENERATED NAMING RULES"
+)
+
+// RenderTestRuns renders the test cases of a resource type as
+// naming.tftest.hcl run blocks asserting on resourceAddress and var.name
+func RenderTestRuns(resourceType, resourceAddress string) (string, error) {
+	rule, err := mustRule(resourceType)
+	if err != nil {
+		return "", err
+	}
+
+	var hcl strings.Builder
+	fmt.Fprintf(&hcl, "%s from shared/testkit/naming for %s; regenerate with UPDATE_NAMING_TESTS=1\n", namingTestsBegin, resourceType)
+	for _, testCase := range rule.TestCases() {
+		fmt.Fprintf(&hcl, "\nrun %q {\n  command = plan\n\n  variables {\n    name = %q\n  }\n\n", testCase.Run, testCase.Name)
+		if testCase.Valid {
+			fmt.Fprintf(&hcl, "  assert {\n    condition     = %s.name == %q\n    error_message = %q\n  }\n}\n",
+				resourceAddress, testCase.Name, fmt.Sprintf("A name with %s should be valid.", testCase.Description))
+		} else {
+			hcl.WriteString("  expect_failures = [\n    var.name,\n  ]\n}\n")
+		}
+	}
+	hcl.WriteString("\n" + namingTestsEnd + "\n")
+	return hcl.String(), nil
+}
+
+// UpdateTestFileE replaces the generated section of a naming.tftest.hcl
+// file, appending it when missing. It reports whether the file changed.
+func UpdateTestFileE(path, resourceType, resourceAddress string) (bool, error) {
+	generated, err := RenderTestRuns(resourceType, resourceAddress)
+	if err != nil {
+		return false, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	current := string(content)
+	updated := strings.TrimRight(current, "\n") + "\n\n" + generated
+	if begin := strings.Index(current, namingTestsBegin); begin >= 0 {
+		end := strings.Index(current, namingTestsEnd)
+		if end < begin {
+			return false, fmt.Errorf("%s: %q without %q", path, namingTestsBegin, namingTestsEnd)
+		}
+		updated = current[:begin] + generated + strings.TrimPrefix(current[end+len(namingTestsEnd):], "\n")
+	}
+	if updated == current {
+		return false, nil
+	}
+	return true, os.WriteFile(path, []byte(updated), 0o644)
+}
+
+// ResourceTypes lists the resource types in the registry
+func ResourceTypes() []string {
+	types := make([]string, 0, len(Rules))
+	for _, rule := range Rules {
+		types = append(types, rule.ResourceType)
+	}
+	sort.Strings(types)
+	return types
+}
@@ -0,0 +1,94 @@
+package naming
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRules checks every rule against its own generated test cases
+func TestRules(t *testing.T) {
+	t.Parallel()
+
+	for _, rule := range Rules {
+		rule := rule
+		t.Run(rule.ResourceType, func(t *testing.T) {
+			require.LessOrEqual(t, 1, rule.MinLength)
+			require.LessOrEqual(t, rule.MinLength, rule.MaxLength)
+			pattern := regexp.MustCompile(rule.Regex())
+
+			for _, testCase := range rule.TestCases() {
+				err := rule.Validate(testCase.Name)
+				if testCase.Valid {
+					assert.NoError(t, err, testCase.Run)
+				} else {
+					assert.Error(t, err, "%s: %q", testCase.Run, testCase.Name)
+				}
+				// Regex cannot express the lower case and consecutive hyphen checks
+				if testCase.Run != "naming_rule_consecutive_hyphens" && testCase.Run != "naming_rule_uppercase" {
+					assert.Equal(t, testCase.Valid, pattern.MatchString(testCase.Name), "%s: %q against %s", testCase.Run, testCase.Name, rule.Regex())
+				}
+			}
+
+			for i := 0; i < 20; i++ {
+				name, err := GenerateResourceNameE(rule.ResourceType, "Test_Prefix--With.Characters", fmt.Sprintf("u%dx", i))
+				require.NoError(t, err)
+				assert.Regexp(t, pattern, name)
+			}
+		})
+	}
+}
+
+func TestGenerateResourceName(t *testing.T) {
+	t.Parallel()
+
+	for _, testCase := range []struct {
+		resourceType, prefix, uniqueID, want string
+	}{
+		{"azurerm_storage_account", "st-test", "abc123", "sttestabc123"},
+		{"azurerm_storage_account", "storage-account-with-a-long-prefix", "abc123", "storageaccountwithabc123"},
+		{"azurerm_key_vault", "kv-test", "AbC123", "kv-test-abc123"},
+		{"azurerm_key_vault", "1-kv", "abc", "kv-abc"},
+		{"azurerm_kubernetes_cluster_node_pool", "user", "abc123def456", "abc123def456"},
+		{"azurerm_windows_virtual_machine", "vm-windows-test", "abc123", "vm-windo-abc123"},
+		{"azurerm_eventhub_namespace", "", "ab", "abcdab"},
+	} {
+		name, err := GenerateResourceNameE(testCase.resourceType, testCase.prefix, testCase.uniqueID)
+		require.NoError(t, err)
+		assert.Equal(t, testCase.want, name, "%s %q %q", testCase.resourceType, testCase.prefix, testCase.uniqueID)
+	}
+
+	_, err := GenerateResourceNameE("azurerm_unknown", "x", "y")
+	assert.ErrorContains(t, err, "no naming rule")
+	assert.ErrorContains(t, ValidateResourceName("azurerm_storage_account", "Invalid-Name"), "must be lower case")
+}
+
+// TestUpdateTestFileE appends the generated runs once and then replaces them in place
+func TestUpdateTestFileE(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "naming.tftest.hcl")
+	require.NoError(t, os.WriteFile(path, []byte("run \"handwritten\" {\n  command = plan\n}\n"), 0o644))
+
+	changed, err := UpdateTestFileE(path, "azurerm_key_vault", "azurerm_key_vault.key_vault")
+	require.NoError(t, err)
+	assert.True(t, changed)
+	changed, err = UpdateTestFileE(path, "azurerm_key_vault", "azurerm_key_vault.key_vault")
+	require.NoError(t, err)
+	assert.False(t, changed, "a current file is left alone")
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(content), "run \"handwritten\""), "runs outside the generated section are kept")
+	assert.Equal(t, 1, strings.Count(string(content), namingTestsBegin))
+	assert.Contains(t, string(content), "azurerm_key_vault.key_vault.name")
+
+	_, err = UpdateTestFileE(path, "azurerm_unknown", "x.y")
+	assert.ErrorContains(t, err, "no naming rule")
+}
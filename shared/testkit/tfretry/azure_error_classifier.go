@@ -0,0 +1,510 @@
+// Package tfretry classifies azurerm and azuredevops provider errors and retries Terraform commands
+// only on errors the decision table marks as transient.
+package tfretry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	terratesting "github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// RetryMetricsFileEnv names a file that receives one JSON line per classified retry
+const RetryMetricsFileEnv = "RETRY_METRICS_FILE"
+
+// maxRetryAfter caps server-provided Retry-After values
+const maxRetryAfter = 10 * time.Minute
+
+// TestingT is the part of testing.TB the retries use, so TestMain helpers can retry without a *testing.T
+type TestingT interface {
+	terratesting.TestingT
+	Helper()
+	Logf(format string, args ...interface{})
+}
+
+// ProviderError is one "Error:" diagnostic from the azurerm or azuredevops provider
+type ProviderError struct {
+	Summary    string
+	Address    string
+	Code       string
+	StatusCode int
+	RequestID  string
+	ResourceID string
+	RetryAfter time.Duration
+	Text       string
+}
+
+// RetryRule is one row of the decision table; a rule matches on a code, an HTTP status or a text pattern
+type RetryRule struct {
+	Name      string
+	Codes     []string
+	Statuses  []int
+	Pattern   string
+	Retryable bool
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// MaxAttempts caps retries for this rule below Options.MaxRetries; zero means no extra cap
+	MaxAttempts int
+	Reason      string
+}
+
+// RetryRules is the decision table, evaluated top to bottom. Specific rules come before the generic
+// status rules so that, for example, a 409 caused by a concurrent operation is retried while other conflicts are not.
+var RetryRules = []RetryRule{
+	{Name: "ResourceAlreadyManaged", Pattern: `already exists - to be managed via Terraform`, Reason: "resource exists outside the state and must be imported"},
+	{Name: "QuotaExceeded", Codes: []string{"QuotaExceeded", "SkuNotAvailable", "ZonalAllocationFailed"}, Pattern: `(?i)\bquota\b`, Reason: "quota or capacity is exhausted"},
+	{Name: "TooManyRequests", Codes: []string{"TooManyRequests", "SubscriptionRequestsThrottled", "RateLimitExceeded"}, Statuses: []int{429}, Pattern: `(?i)too many requests`,
+		Retryable: true, BaseDelay: 30 * time.Second, MaxDelay: 5 * time.Minute, Reason: "request was throttled"},
+	{Name: "AnotherOperationInProgress", Codes: []string{"AnotherOperationInProgress", "OperationInProgress", "ServerIsBusy", "ServerBusy", "RetryableError"},
+		Pattern:   `(?i)(another operation is in progress|operation.*in progress|busy processing another operation)`,
+		Retryable: true, BaseDelay: 30 * time.Second, MaxDelay: 5 * time.Minute, Reason: "another operation is running on the resource"},
+	{Name: "ResourceGroupNotFound", Codes: []string{"ResourceGroupNotFound"},
+		Retryable: true, BaseDelay: 15 * time.Second, MaxDelay: time.Minute, MaxAttempts: 4, Reason: "resource group has not replicated yet"},
+	{Name: "ProviderRegistration", Pattern: `Error ensuring Resource Providers are registered`,
+		Retryable: true, BaseDelay: 30 * time.Second, MaxDelay: 2 * time.Minute, MaxAttempts: 3, Reason: "resource provider registration race"},
+	{Name: "StillProvisioning", Codes: []string{"AccountProvisioningStateInvalid", "ServerDropping"}, Pattern: `in state Accepted`,
+		Retryable: true, BaseDelay: 30 * time.Second, MaxDelay: 3 * time.Minute, Reason: "resource is still provisioning or being dropped"},
+	{Name: "StorageAccountAlreadyTaken", Codes: []string{"StorageAccountAlreadyTaken"},
+		Retryable: true, BaseDelay: 30 * time.Second, MaxDelay: 2 * time.Minute, MaxAttempts: 3, Reason: "storage account name is still held after a delete"},
+	{Name: "AlreadyExists", Codes: []string{"AlreadyExists"}, Pattern: `\bAlreadyExists\b`,
+		Retryable: true, BaseDelay: 30 * time.Second, MaxDelay: time.Minute, MaxAttempts: 2, Reason: "resource already exists"},
+	{Name: "OperationNotAllowed", Codes: []string{"OperationNotAllowed"},
+		Retryable: true, BaseDelay: 30 * time.Second, MaxDelay: 2 * time.Minute, MaxAttempts: 3, Reason: "operation temporarily not allowed"},
+	{Name: "AppServicePropagation", Pattern: `Cannot find user\.`,
+		Retryable: true, BaseDelay: 30 * time.Second, MaxDelay: 2 * time.Minute, Reason: "App Service backend propagation delay"},
+	{Name: "ServerError", Codes: []string{"InternalServerError", "InternalError", "ServiceUnavailable", "BadGateway", "GatewayTimeout", "OperationTimedOut"},
+		Statuses:  []int{500, 502, 503, 504},
+		Retryable: true, BaseDelay: 20 * time.Second, MaxDelay: 3 * time.Minute, Reason: "Azure service error"},
+	{Name: "AzureDevOpsServiceError", Codes: []string{"TF400898"}, Pattern: `(?i)unexpected error occur+ed`,
+		Retryable: true, BaseDelay: 15 * time.Second, MaxDelay: time.Minute, Reason: "Azure DevOps service error"},
+	{Name: "NonJSONResponse", Pattern: `invalid character '<' looking for beginning of value`,
+		Retryable: true, BaseDelay: 15 * time.Second, MaxDelay: time.Minute, Reason: "service returned HTML instead of JSON"},
+	{Name: "ConnectionReset", Pattern: `(?i)(connection reset by peer|transport is closing|unexpected EOF)`,
+		Retryable: true, BaseDelay: 10 * time.Second, MaxDelay: time.Minute, Reason: "connection was reset"},
+	{Name: "Timeout", Pattern: `(?i)(context deadline exceeded|\btime(d)? ?out\b)`,
+		Retryable: true, BaseDelay: 20 * time.Second, MaxDelay: 2 * time.Minute, Reason: "operation timed out"},
+	{Name: "Conflict", Codes: []string{"Conflict"}, Statuses: []int{409}, Reason: "conflicting resource state"},
+	{Name: "ClientError", Statuses: []int{400, 401, 403, 404}, Reason: "request was rejected"},
+}
+
+var (
+	logPrefixPattern  = regexp.MustCompile(`^\S+ \d{4}-\d{2}-\d{2}T\S+ \S+\.go:\d+: `)
+	errorLinePattern  = regexp.MustCompile(`(?m)^Error: `)
+	addressPattern    = regexp.MustCompile(`(?m)^\s*with ([^\s,]+),`)
+	resourceIDPattern = regexp.MustCompile(`(?i)/subscriptions/[0-9a-f-]{36}(?:/[^\s"',)/]+)+`)
+	requestIDPattern  = regexp.MustCompile(`(?i)(?:x-ms-(?:correlation-)?request-id|request ?id|activity ?id|correlation ?id)"?\s*[:=]\s*"?([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})`)
+	retryAfterPattern = regexp.MustCompile(`(?i)(?:retry-after"?\s*[:=]\s*"?|retry after )(\d+)`)
+	codePatterns      = []*regexp.Regexp{
+		// go-azure-sdk polling errors: Code: "InternalServerError"
+		regexp.MustCompile(`(?m)^\s*Code:\s*"([^"]+)"`),
+		// autorest: Code="TooManyRequests"
+		regexp.MustCompile(`Code="([^"]+)"`),
+		// raw response bodies: {"error":{"code":"Conflict",...}}
+		regexp.MustCompile(`"code"\s*:\s*"([^"]+)"`),
+		// go-azure-sdk: unexpected status 409 (409 Conflict) with error: AnotherOperationInProgress: ...
+		regexp.MustCompile(`with error: ([A-Za-z]+):`),
+		// Azure DevOps: TF401019: The Git repository ... / VS403403: ...
+		regexp.MustCompile(`\b((?:TF|VS)\d{5,6}):`),
+	}
+	statusPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`unexpected status (\d{3})`),
+		regexp.MustCompile(`StatusCode=(\d{3})`),
+		regexp.MustCompile(`Status=(\d{3})`),
+		regexp.MustCompile(`(?i)status code:? (\d{3})`),
+		regexp.MustCompile(`(?i)\bHTTP (\d{3})\b`),
+	}
+	compiledRulePatterns = compileRulePatterns(RetryRules)
+)
+
+// ParseProviderErrors splits Terraform output into provider error diagnostics. Output without an
+// "Error:" line, such as a bare transport error, is returned as a single error.
+func ParseProviderErrors(output string) []ProviderError {
+	text := stripDiagnosticFrame(output)
+	starts := errorLinePattern.FindAllStringIndex(text, -1)
+	if len(starts) == 0 {
+		if strings.TrimSpace(text) == "" {
+			return nil
+		}
+		return []ProviderError{parseProviderError(text)}
+	}
+
+	errors := make([]ProviderError, 0, len(starts))
+	for i, start := range starts {
+		end := len(text)
+		if i+1 < len(starts) {
+			end = starts[i+1][0]
+		}
+		errors = append(errors, parseProviderError(text[start[0]:end]))
+	}
+	return errors
+}
+
+// Decision is the classification of a failed Terraform command
+type Decision struct {
+	Retryable bool
+	Rule      RetryRule
+	Error     ProviderError
+	Errors    []ProviderError
+}
+
+// Key identifies the decision in retry metrics: the ARM or Azure DevOps code, or the rule name when no code was reported
+func (d Decision) Key() string {
+	if d.Error.Code != "" {
+		return d.Error.Code
+	}
+	return d.Rule.Name
+}
+
+// Backoff returns the wait before retry number attempt (1-based): exponential from the rule's base delay up to
+// its maximum, or the server's Retry-After when that is longer
+func (d Decision) Backoff(attempt int) time.Duration {
+	delay := d.Rule.BaseDelay
+	for i := 1; i < attempt && delay < d.Rule.MaxDelay; i++ {
+		delay *= 2
+	}
+	if d.Rule.MaxDelay > 0 && delay > d.Rule.MaxDelay {
+		delay = d.Rule.MaxDelay
+	}
+	if retryAfter := d.Error.RetryAfter; retryAfter > delay {
+		delay = retryAfter
+		if delay > maxRetryAfter {
+			delay = maxRetryAfter
+		}
+	}
+	return delay
+}
+
+// Classify decides whether Terraform output is worth retrying. A command is retried only when every error in it
+// is retryable, because a permanent error would fail the next attempt again; the decision then carries the
+// error with the longest backoff.
+func Classify(output string) Decision {
+	errors := ParseProviderErrors(output)
+	if len(errors) == 0 {
+		return Decision{Rule: RetryRule{Name: "Unclassified", Reason: "no error output"}}
+	}
+
+	var decision Decision
+	for i, providerError := range errors {
+		rule := matchRule(providerError)
+		candidate := Decision{Retryable: rule.Retryable, Rule: rule, Error: providerError}
+		switch {
+		case i == 0:
+			decision = candidate
+		case !candidate.Retryable:
+			if decision.Retryable {
+				decision = candidate
+			}
+		case decision.Retryable && candidate.Backoff(1) > decision.Backoff(1):
+			decision = candidate
+		}
+	}
+	decision.Errors = errors
+	return decision
+}
+
+// ClassifiedRetryableErrors renders the retryable rules as Terratest RetryableTerraformErrors. Terratest matches
+// any regex, so non-retryable rules and per-rule delays only apply through RunWithClassifiedRetryE.
+func ClassifiedRetryableErrors() map[string]string {
+	retryable := map[string]string{}
+	for _, rule := range RetryRules {
+		if !rule.Retryable {
+			continue
+		}
+		message := fmt.Sprintf("%s: %s - retrying", rule.Name, rule.Reason)
+		for _, code := range rule.Codes {
+			retryable[`\b`+regexp.QuoteMeta(code)+`\b`] = message
+		}
+		for _, status := range rule.Statuses {
+			retryable[fmt.Sprintf(`(unexpected status|StatusCode=|Status=)\s*%d\b`, status)] = message
+		}
+		if rule.Pattern != "" {
+			retryable[rule.Pattern] = message
+		}
+	}
+	return retryable
+}
+
+// RunWithClassifiedRetryE runs a Terraform action and retries it while Classify allows, waiting the per-rule
+// backoff between attempts. Options.MaxRetries bounds the total; Terratest's own retry loop is disabled.
+func RunWithClassifiedRetryE(t TestingT, options *terraform.Options, description string, action func(*terraform.Options) (string, error)) (string, error) {
+	t.Helper()
+
+	attemptOptions := *options
+	attemptOptions.RetryableTerraformErrors = nil
+	attemptOptions.MaxRetries = 0
+
+	retriesPerRule := map[string]int{}
+	for attempt := 1; ; attempt++ {
+		output, err := action(&attemptOptions)
+		if err == nil {
+			return output, nil
+		}
+
+		decision := Classify(output + "\n" + err.Error())
+		if !decision.Retryable {
+			return output, err
+		}
+		retriesPerRule[decision.Rule.Name]++
+		if attempt > options.MaxRetries || (decision.Rule.MaxAttempts > 0 && retriesPerRule[decision.Rule.Name] > decision.Rule.MaxAttempts) {
+			DefaultRetryMetrics.RecordExhausted(t, decision)
+			return output, fmt.Errorf("%s: giving up after %d attempts on %s (%s): %w", description, attempt, decision.Key(), decision.Rule.Reason, err)
+		}
+
+		delay := decision.Backoff(retriesPerRule[decision.Rule.Name])
+		DefaultRetryMetrics.RecordRetry(t, decision, delay)
+		t.Logf("%s failed with %s (HTTP %d, request %s): %s; retry %d in %s", description, decision.Key(), decision.Error.StatusCode, decision.Error.RequestID, decision.Rule.Reason, attempt, delay)
+		time.Sleep(delay)
+	}
+}
+
+// InitAndApplyWithRetry runs terraform init and apply with classified retries
+func InitAndApplyWithRetry(t TestingT, options *terraform.Options) string {
+	t.Helper()
+	output, err := InitAndApplyWithRetryE(t, options)
+	require.NoError(t, err)
+	return output
+}
+
+// InitAndApplyWithRetryE runs terraform init and apply with classified retries and returns the last error
+func InitAndApplyWithRetryE(t TestingT, options *terraform.Options) (string, error) {
+	t.Helper()
+	return RunWithClassifiedRetryE(t, options, "terraform init and apply", func(attemptOptions *terraform.Options) (string, error) {
+		return terraform.InitAndApplyE(t, attemptOptions)
+	})
+}
+
+// ApplyWithRetry runs terraform apply with classified retries
+func ApplyWithRetry(t TestingT, options *terraform.Options) string {
+	t.Helper()
+	output, err := ApplyWithRetryE(t, options)
+	require.NoError(t, err)
+	return output
+}
+
+// ApplyWithRetryE runs terraform apply with classified retries and returns the last error
+func ApplyWithRetryE(t TestingT, options *terraform.Options) (string, error) {
+	t.Helper()
+	return RunWithClassifiedRetryE(t, options, "terraform apply", func(attemptOptions *terraform.Options) (string, error) {
+		return terraform.ApplyE(t, attemptOptions)
+	})
+}
+
+// DestroyWithRetry runs terraform destroy with classified retries
+func DestroyWithRetry(t TestingT, options *terraform.Options) string {
+	t.Helper()
+	output, err := DestroyWithRetryE(t, options)
+	require.NoError(t, err)
+	return output
+}
+
+// DestroyWithRetryE runs terraform destroy with classified retries and returns the last error
+func DestroyWithRetryE(t TestingT, options *terraform.Options) (string, error) {
+	t.Helper()
+	return RunWithClassifiedRetryE(t, options, "terraform destroy", func(attemptOptions *terraform.Options) (string, error) {
+		return terraform.DestroyE(t, attemptOptions)
+	})
+}
+
+// RetryStat counts retries of one code
+type RetryStat struct {
+	Retries   int           `json:"retries"`
+	Exhausted int           `json:"exhausted"`
+	Waited    time.Duration `json:"waited_ns"`
+}
+
+// RetryEvent is written to RETRY_METRICS_FILE for every retry and every exhausted retry budget
+type RetryEvent struct {
+	Test       string        `json:"test"`
+	Key        string        `json:"key"`
+	Rule       string        `json:"rule"`
+	StatusCode int           `json:"status_code,omitempty"`
+	RequestID  string        `json:"request_id,omitempty"`
+	ResourceID string        `json:"resource_id,omitempty"`
+	Delay      time.Duration `json:"delay_ns"`
+	Exhausted  bool          `json:"exhausted,omitempty"`
+}
+
+// RetryMetrics counts how often each code was retried in this test binary
+type RetryMetrics struct {
+	mu    sync.Mutex
+	stats map[string]*RetryStat
+}
+
+// DefaultRetryMetrics is shared by all classified retries in the test binary
+var DefaultRetryMetrics = &RetryMetrics{stats: map[string]*RetryStat{}}
+
+// RecordRetry counts a retry and appends it to RETRY_METRICS_FILE when set
+func (m *RetryMetrics) RecordRetry(t TestingT, decision Decision, delay time.Duration) {
+	m.mu.Lock()
+	stat := m.stat(decision.Key())
+	stat.Retries++
+	stat.Waited += delay
+	m.mu.Unlock()
+	m.write(t, newRetryEvent(t, decision, delay, false))
+}
+
+// RecordExhausted counts a retryable error that ran out of attempts
+func (m *RetryMetrics) RecordExhausted(t TestingT, decision Decision) {
+	m.mu.Lock()
+	m.stat(decision.Key()).Exhausted++
+	m.mu.Unlock()
+	m.write(t, newRetryEvent(t, decision, 0, true))
+}
+
+// Snapshot returns a copy of the counters keyed by code
+func (m *RetryMetrics) Snapshot() map[string]RetryStat {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := make(map[string]RetryStat, len(m.stats))
+	for key, stat := range m.stats {
+		snapshot[key] = *stat
+	}
+	return snapshot
+}
+
+// Summary renders the counters one code per line, most retried first
+func (m *RetryMetrics) Summary() string {
+	snapshot := m.Snapshot()
+	keys := make([]string, 0, len(snapshot))
+	for key := range snapshot {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if snapshot[keys[i]].Retries != snapshot[keys[j]].Retries {
+			return snapshot[keys[i]].Retries > snapshot[keys[j]].Retries
+		}
+		return keys[i] < keys[j]
+	})
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		stat := snapshot[key]
+		lines = append(lines, fmt.Sprintf("%s: %d retries, %d exhausted, waited %s", key, stat.Retries, stat.Exhausted, stat.Waited))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m *RetryMetrics) stat(key string) *RetryStat {
+	stat, ok := m.stats[key]
+	if !ok {
+		stat = &RetryStat{}
+		m.stats[key] = stat
+	}
+	return stat
+}
+
+func (m *RetryMetrics) write(t TestingT, event RetryEvent) {
+	path := os.Getenv(RetryMetricsFileEnv)
+	if path == "" {
+		return
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		t.Logf("Failed to encode retry event: %v", err)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Logf("Failed to open %s: %v", path, err)
+		return
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		t.Logf("Failed to write %s: %v", path, err)
+	}
+}
+
+func newRetryEvent(t TestingT, decision Decision, delay time.Duration, exhausted bool) RetryEvent {
+	return RetryEvent{
+		Test:       t.Name(),
+		Key:        decision.Key(),
+		Rule:       decision.Rule.Name,
+		StatusCode: decision.Error.StatusCode,
+		RequestID:  decision.Error.RequestID,
+		ResourceID: decision.Error.ResourceID,
+		Delay:      delay,
+		Exhausted:  exhausted,
+	}
+}
+
+func matchRule(providerError ProviderError) RetryRule {
+	for i, rule := range RetryRules {
+		for _, code := range rule.Codes {
+			if strings.EqualFold(code, providerError.Code) {
+				return rule
+			}
+		}
+		for _, status := range rule.Statuses {
+			if status == providerError.StatusCode {
+				return rule
+			}
+		}
+		if pattern := compiledRulePatterns[i]; pattern != nil && pattern.MatchString(providerError.Text) {
+			return rule
+		}
+	}
+	return RetryRule{Name: "Unclassified", Reason: "error is not in the decision table"}
+}
+
+func parseProviderError(block string) ProviderError {
+	providerError := ProviderError{Text: strings.TrimSpace(block)}
+	providerError.Summary = strings.TrimPrefix(strings.SplitN(providerError.Text, "\n", 2)[0], "Error: ")
+
+	if match := addressPattern.FindStringSubmatch(block); match != nil {
+		providerError.Address = match[1]
+	}
+	for _, pattern := range codePatterns {
+		if match := pattern.FindStringSubmatch(block); match != nil {
+			providerError.Code = match[1]
+			break
+		}
+	}
+	for _, pattern := range statusPatterns {
+		if match := pattern.FindStringSubmatch(block); match != nil {
+			providerError.StatusCode, _ = strconv.Atoi(match[1])
+			break
+		}
+	}
+	if match := requestIDPattern.FindStringSubmatch(block); match != nil {
+		providerError.RequestID = strings.ToLower(match[1])
+	}
+	providerError.ResourceID = resourceIDPattern.FindString(block)
+	if match := retryAfterPattern.FindStringSubmatch(block); match != nil {
+		seconds, _ := strconv.Atoi(match[1])
+		providerError.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return providerError
+}
+
+// stripDiagnosticFrame removes Terratest log prefixes and the box drawing around Terraform diagnostics
+func stripDiagnosticFrame(output string) string {
+	lines := strings.Split(output, "\n")
+	for i, line := range lines {
+		line = logPrefixPattern.ReplaceAllString(line, "")
+		line = strings.TrimLeft(line, "╷╵")
+		line = strings.TrimPrefix(line, "│")
+		lines[i] = strings.TrimPrefix(line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func compileRulePatterns(rules []RetryRule) []*regexp.Regexp {
+	patterns := make([]*regexp.Regexp, len(rules))
+	for i, rule := range rules {
+		if rule.Pattern != "" {
+			patterns[i] = regexp.MustCompile(rule.Pattern)
+		}
+	}
+	return patterns
+}
@@ -0,0 +1,139 @@
+package tfretry
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClassifyProviderErrorFixtures checks the decision table against captured provider output
+func TestClassifyProviderErrorFixtures(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		fixture    string
+		retryable  bool
+		rule       string
+		code       string
+		statusCode int
+		requestID  string
+		resourceID string
+		address    string
+		retryAfter time.Duration
+	}{
+		{fixture: "redis_operation_in_progress.txt", retryable: true, rule: "AnotherOperationInProgress", code: "Conflict", statusCode: 409,
+			requestID: "5f0c8a3e-9d21-4c1b-8f7e-2a6b3c4d5e6f", address: "module.managed_redis.azurerm_managed_redis.managed_redis"},
+		{fixture: "conflict_name_in_use.txt", retryable: false, rule: "Conflict", code: "Conflict", statusCode: 409,
+			address: "module.managed_redis.azurerm_managed_redis.managed_redis"},
+		{fixture: "throttled_retry_after.txt", retryable: true, rule: "TooManyRequests", code: "TooManyRequests", statusCode: 429,
+			requestID: "9a1b2c3d-4e5f-4a6b-8c7d-0e1f2a3b4c5d", address: "azurerm_resource_group.test", retryAfter: 120 * time.Second},
+		{fixture: "polling_internal_server_error.txt", retryable: true, rule: "ServerError", code: "InternalServerError",
+			requestID:  "c3d4e5f6-a7b8-4c9d-8e0f-1a2b3c4d5e6f",
+			resourceID: "/subscriptions/0b1f6471-1bf0-4dda-aec3-cb9272f09590/providers/Microsoft.Cache/locations/northeurope/operationsStatus/7d8e9f00",
+			address:    "module.managed_redis.azurerm_managed_redis.managed_redis"},
+		{fixture: "resource_already_managed.txt", retryable: false, rule: "ResourceAlreadyManaged",
+			resourceID: "/subscriptions/0b1f6471-1bf0-4dda-aec3-cb9272f09590/resourceGroups/rg-test-managed-redis-x7k2p", address: "azurerm_resource_group.test"},
+		{fixture: "quota_exceeded.txt", retryable: false, rule: "QuotaExceeded", code: "OperationNotAllowed", statusCode: 409,
+			address: "module.linux_virtual_machine.azurerm_linux_virtual_machine.linux_virtual_machine"},
+		{fixture: "resource_group_not_found.txt", retryable: true, rule: "ResourceGroupNotFound", code: "ResourceGroupNotFound", statusCode: 404,
+			address: "module.virtual_network.azurerm_virtual_network.virtual_network"},
+		{fixture: "ado_html_response.txt", retryable: true, rule: "NonJSONResponse",
+			address: "module.azuredevops_repository.azuredevops_git_repository.git_repository"},
+		{fixture: "ado_repository_not_found.txt", retryable: false, rule: "Unclassified", code: "TF401019",
+			address: "module.azuredevops_repository.azuredevops_git_repository.git_repository"},
+		{fixture: "mixed_timeout_and_forbidden.txt", retryable: false, rule: "ClientError", code: "AuthorizationFailed", statusCode: 403,
+			resourceID: "/subscriptions/0b1f6471-1bf0-4dda-aec3-cb9272f09590/resourceGroups/rg-test-sa-x7k2p", address: "azurerm_role_assignment.test"},
+		{fixture: "connection_reset.txt", retryable: true, rule: "ConnectionReset"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.fixture, func(t *testing.T) {
+			t.Parallel()
+
+			output, err := os.ReadFile(filepath.Join("testdata", "provider_errors", tc.fixture))
+			require.NoError(t, err)
+
+			decision := Classify(string(output))
+			assert.Equal(t, tc.retryable, decision.Retryable)
+			assert.Equal(t, tc.rule, decision.Rule.Name)
+			assert.Equal(t, tc.code, decision.Error.Code)
+			assert.Equal(t, tc.statusCode, decision.Error.StatusCode)
+			assert.Equal(t, tc.requestID, decision.Error.RequestID)
+			assert.Equal(t, tc.resourceID, decision.Error.ResourceID)
+			assert.Equal(t, tc.address, decision.Error.Address)
+			assert.Equal(t, tc.retryAfter, decision.Error.RetryAfter)
+		})
+	}
+}
+
+func TestParseProviderErrorsSplitsDiagnostics(t *testing.T) {
+	t.Parallel()
+
+	output, err := os.ReadFile(filepath.Join("testdata", "provider_errors", "mixed_timeout_and_forbidden.txt"))
+	require.NoError(t, err)
+
+	providerErrors := ParseProviderErrors(string(output))
+	require.Len(t, providerErrors, 2)
+	assert.Equal(t, "module.storage_account.azurerm_storage_account.storage_account", providerErrors[0].Address)
+	assert.Equal(t, "Timeout", matchRule(providerErrors[0]).Name)
+	assert.Equal(t, "azurerm_role_assignment.test", providerErrors[1].Address)
+	assert.Empty(t, ParseProviderErrors(" \n"))
+}
+
+func TestDecisionBackoff(t *testing.T) {
+	t.Parallel()
+
+	decision := Decision{Retryable: true, Rule: RetryRule{BaseDelay: 20 * time.Second, MaxDelay: time.Minute}}
+	assert.Equal(t, 20*time.Second, decision.Backoff(1))
+	assert.Equal(t, 40*time.Second, decision.Backoff(2))
+	assert.Equal(t, time.Minute, decision.Backoff(3))
+	assert.Equal(t, time.Minute, decision.Backoff(10))
+
+	decision.Error.RetryAfter = 90 * time.Second
+	assert.Equal(t, 90*time.Second, decision.Backoff(1), "Retry-After longer than the backoff wins")
+	decision.Error.RetryAfter = time.Hour
+	assert.Equal(t, maxRetryAfter, decision.Backoff(1), "Retry-After is capped")
+}
+
+func TestClassifiedRetryableErrors(t *testing.T) {
+	t.Parallel()
+
+	retryable := ClassifiedRetryableErrors()
+	for pattern := range retryable {
+		_, err := regexp.Compile(pattern)
+		require.NoError(t, err, "pattern %q", pattern)
+	}
+	assert.NotContains(t, retryable, `\bConflict\b`, "plain conflicts are not retried")
+	assert.Contains(t, retryable, `\bTooManyRequests\b`)
+	assert.Contains(t, retryable, `invalid character '<' looking for beginning of value`)
+}
+
+func TestRunWithClassifiedRetryE(t *testing.T) {
+	t.Parallel()
+
+	options := &terraform.Options{MaxRetries: 0, RetryableTerraformErrors: map[string]string{".*": "any"}}
+	calls := 0
+	_, err := RunWithClassifiedRetryE(t, options, "terraform apply", func(attemptOptions *terraform.Options) (string, error) {
+		calls++
+		assert.Nil(t, attemptOptions.RetryableTerraformErrors, "Terratest retries are disabled inside the classified loop")
+		return "Error: creating Role Assignment: unexpected status 403 (403 Forbidden) with error: AuthorizationFailed: denied", errors.New("exit status 1")
+	})
+	require.Error(t, err)
+	assert.Equal(t, 1, calls, "non-retryable errors are not retried")
+
+	metrics := DefaultRetryMetrics.Snapshot()["TestRetryBudgetCode"]
+	_, err = RunWithClassifiedRetryE(t, options, "terraform apply", func(*terraform.Options) (string, error) {
+		return "Error: polling failed\nCode: \"TestRetryBudgetCode\"\nStatus=503", errors.New("exit status 1")
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "giving up after 1 attempts on TestRetryBudgetCode")
+	assert.Equal(t, metrics.Exhausted+1, DefaultRetryMetrics.Snapshot()["TestRetryBudgetCode"].Exhausted)
+}
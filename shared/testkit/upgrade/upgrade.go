@@ -0,0 +1,329 @@
+// Package upgrade applies a fixture with the last released module version and plans it against the working tree,
+// so a release that would recreate stateful resources without a breaking change note fails before it ships.
+package upgrade
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/destroyverify"
+	"github.com/PatrykIti/azurerm-terraform-modules/shared/testkit/tfretry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	test_structure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/stretchr/testify/require"
+)
+
+// LocalModuleSource is the module source every fixture uses for the working tree
+const LocalModuleSource = "../../../"
+
+// DefaultStatefulResourceTypes hold data or identities that are lost when the resource is recreated
+var DefaultStatefulResourceTypes = []string{
+	"azurerm_cognitive_account",
+	"azurerm_eventhub",
+	"azurerm_eventhub_namespace",
+	"azurerm_key_vault",
+	"azurerm_key_vault_certificate",
+	"azurerm_key_vault_key",
+	"azurerm_key_vault_secret",
+	"azurerm_kubernetes_cluster",
+	"azurerm_linux_virtual_machine",
+	"azurerm_log_analytics_workspace",
+	"azurerm_managed_disk",
+	"azurerm_managed_redis",
+	"azurerm_postgresql_flexible_server",
+	"azurerm_postgresql_flexible_server_database",
+	"azurerm_redis_cache",
+	"azurerm_storage_account",
+	"azurerm_storage_container",
+	"azurerm_storage_data_lake_gen2_filesystem",
+	"azurerm_storage_queue",
+	"azurerm_storage_share",
+	"azurerm_storage_table",
+	"azurerm_user_assigned_identity",
+	"azurerm_windows_virtual_machine",
+}
+
+// ModuleConfig is the subset of module.json used to resolve release tags
+type ModuleConfig struct {
+	Name      string `json:"name"`
+	TagPrefix string `json:"tag_prefix"`
+}
+
+// ModuleRelease is a release tag such as SAv1.2.3
+type ModuleRelease struct {
+	Tag     string
+	Version [3]int
+}
+
+// String returns the version without the tag prefix
+func (r ModuleRelease) String() string {
+	return fmt.Sprintf("%d.%d.%d", r.Version[0], r.Version[1], r.Version[2])
+}
+
+// Test applies a fixture with the last released module version and plans it against the working tree
+type Test struct {
+	// ModuleDir is the module root relative to the tests directory
+	ModuleDir string
+	// FixtureFolder is the fixture path relative to ModuleDir, e.g. tests/fixtures/simple
+	FixtureFolder string
+	// StatefulTypes defaults to DefaultStatefulResourceTypes
+	StatefulTypes []string
+}
+
+// Violation is a planned delete or replace of a stateful resource
+type Violation struct {
+	Address string
+	Actions []string
+}
+
+// RunUpgradeTest deploys the fixture from the previous release tag, switches the module source to the working tree
+// and fails when the plan deletes or replaces stateful resources, unless the CHANGELOG marks the release as breaking
+func RunUpgradeTest(t *testing.T, upgrade Test, getOptions func(t testing.TB, terraformDir string) *terraform.Options) {
+	t.Helper()
+
+	moduleDir, err := filepath.Abs(upgrade.ModuleDir)
+	require.NoError(t, err)
+	config, err := ReadModuleConfigE(moduleDir)
+	require.NoError(t, err, "Failed to read module.json")
+	require.NotEmpty(t, config.TagPrefix, "module.json must define tag_prefix")
+
+	repoRoot, err := gitOutput(moduleDir, "rev-parse", "--show-toplevel")
+	require.NoError(t, err, "Upgrade tests need the module inside a git checkout")
+	tags, err := gitOutput(repoRoot, "tag", "--list", config.TagPrefix+"*")
+	require.NoError(t, err, "Failed to list release tags")
+	release, ok := LatestModuleRelease(strings.Fields(tags), config.TagPrefix)
+	if !ok {
+		t.Skipf("Skipping upgrade test: no %s* release tag in the local checkout (run git fetch --tags)", config.TagPrefix)
+	}
+	modulePath, err := filepath.Rel(repoRoot, moduleDir)
+	require.NoError(t, err)
+	releaseSource := PinnedModuleSource(repoRoot, filepath.ToSlash(modulePath), release.Tag)
+
+	testFolder := test_structure.CopyTerraformFolderToTemp(t, upgrade.ModuleDir, upgrade.FixtureFolder)
+	defer test_structure.RunTestStage(t, "cleanup", func() {
+		destroyverify.DestroyAndVerify(t, test_structure.LoadTerraformOptions(t, testFolder), destroyverify.NewAzureDestroyVerifier(t))
+	})
+
+	test_structure.RunTestStage(t, "deploy_release", func() {
+		t.Logf("Deploying %s from %s", upgrade.FixtureFolder, release.Tag)
+		require.NoError(t, SetModuleSourceE(testFolder, LocalModuleSource, releaseSource))
+
+		terraformOptions := getOptions(t, testFolder)
+		test_structure.SaveTerraformOptions(t, testFolder, terraformOptions)
+		tfretry.InitAndApplyWithRetry(t, terraformOptions)
+	})
+
+	test_structure.RunTestStage(t, "upgrade", func() {
+		require.NoError(t, SetModuleSourceE(testFolder, releaseSource, LocalModuleSource))
+
+		terraformOptions := test_structure.LoadTerraformOptions(t, testFolder)
+		planOptions := *terraformOptions
+		planOptions.PlanFilePath = filepath.Join(testFolder, "upgrade.tfplan")
+		plan := terraform.InitAndPlanAndShowWithStruct(t, &planOptions)
+
+		statefulTypes := upgrade.StatefulTypes
+		if statefulTypes == nil {
+			statefulTypes = DefaultStatefulResourceTypes
+		}
+		violations := StatefulReplacements(plan, statefulTypes)
+		if len(violations) == 0 {
+			return
+		}
+
+		lines := make([]string, 0, len(violations))
+		for _, violation := range violations {
+			lines = append(lines, fmt.Sprintf("%s %v", violation.Address, violation.Actions))
+		}
+		changelog, err := os.ReadFile(filepath.Join(moduleDir, "CHANGELOG.md"))
+		require.NoError(t, err, "Failed to read CHANGELOG.md")
+		if ChangelogMarksBreaking(string(changelog), config.TagPrefix, release) {
+			t.Logf("Upgrade from %s recreates stateful resources; allowed because the CHANGELOG marks a breaking change:\n%s", release.Tag, strings.Join(lines, "\n"))
+			return
+		}
+		require.FailNow(t, fmt.Sprintf("Upgrade from %s deletes or replaces stateful resources and the CHANGELOG does not mark it as breaking", release.Tag), strings.Join(lines, "\n"))
+	})
+}
+
+// ReadModuleConfigE reads module.json from the module root
+func ReadModuleConfigE(moduleDir string) (ModuleConfig, error) {
+	var config ModuleConfig
+	content, err := os.ReadFile(filepath.Join(moduleDir, "module.json"))
+	if err != nil {
+		return config, err
+	}
+	err = json.Unmarshal(content, &config)
+	return config, err
+}
+
+// ParseModuleRelease parses a tag such as SAv1.2.3; pre-release and other tags are rejected
+func ParseModuleRelease(tag, tagPrefix string) (ModuleRelease, bool) {
+	if !strings.HasPrefix(tag, tagPrefix) {
+		return ModuleRelease{}, false
+	}
+	version, ok := parseVersion(strings.TrimPrefix(tag, tagPrefix))
+	return ModuleRelease{Tag: tag, Version: version}, ok
+}
+
+// LatestModuleRelease returns the highest release among the tags
+func LatestModuleRelease(tags []string, tagPrefix string) (ModuleRelease, bool) {
+	var releases []ModuleRelease
+	for _, tag := range tags {
+		if release, ok := ParseModuleRelease(tag, tagPrefix); ok {
+			releases = append(releases, release)
+		}
+	}
+	if len(releases) == 0 {
+		return ModuleRelease{}, false
+	}
+	sort.Slice(releases, func(i, j int) bool { return compareVersions(releases[i].Version, releases[j].Version) < 0 })
+	return releases[len(releases)-1], true
+}
+
+// PinnedModuleSource returns a git source that makes Terraform clone the tag from the local repository
+func PinnedModuleSource(repoRoot, modulePath, tag string) string {
+	return fmt.Sprintf("git::file://%s//%s?ref=%s", filepath.ToSlash(repoRoot), modulePath, tag)
+}
+
+// SetModuleSourceE rewrites `source = "<from>"` to `source = "<to>"` in the fixture's .tf files
+func SetModuleSourceE(fixtureDir, from, to string) error {
+	files, err := filepath.Glob(filepath.Join(fixtureDir, "*.tf"))
+	if err != nil {
+		return err
+	}
+	pattern := regexp.MustCompile(`(?m)^(\s*source\s*=\s*)"` + regexp.QuoteMeta(from) + `"`)
+	replaced := 0
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		matches := len(pattern.FindAllIndex(content, -1))
+		if matches == 0 {
+			continue
+		}
+		replaced += matches
+		updated := pattern.ReplaceAll(content, []byte(`${1}"`+strings.ReplaceAll(to, "$", "$$")+`"`))
+		if err := os.WriteFile(file, updated, 0o644); err != nil {
+			return err
+		}
+	}
+	if replaced == 0 {
+		return fmt.Errorf("no module block with source %q in %s", from, fixtureDir)
+	}
+	return nil
+}
+
+// StatefulReplacements lists planned delete or replace actions on stateful resource types, sorted by address
+func StatefulReplacements(plan *terraform.PlanStruct, statefulTypes []string) []Violation {
+	stateful := make(map[string]bool, len(statefulTypes))
+	for _, resourceType := range statefulTypes {
+		stateful[resourceType] = true
+	}
+
+	var violations []Violation
+	for address, change := range plan.ResourceChangesMap {
+		if change == nil || change.Change == nil || !stateful[change.Type] || change.Mode != "managed" {
+			continue
+		}
+		actions := make([]string, 0, len(change.Change.Actions))
+		deletes := false
+		for _, action := range change.Change.Actions {
+			actions = append(actions, string(action))
+			deletes = deletes || action == "delete"
+		}
+		if deletes {
+			violations = append(violations, Violation{Address: address, Actions: actions})
+		}
+	}
+	sort.Slice(violations, func(i, j int) bool { return violations[i].Address < violations[j].Address })
+	return violations
+}
+
+var changelogVersionPattern = regexp.MustCompile(`^\[?(?:[A-Za-z]+v|v)?(\d+\.\d+\.\d+)\b`)
+
+// ChangelogMarksBreaking reports whether a section released after the base release, or the Unreleased section,
+// mentions a breaking change or bumps the major version. Fenced code blocks (format examples) are ignored.
+func ChangelogMarksBreaking(changelog, tagPrefix string, base ModuleRelease) bool {
+	scanner := bufio.NewScanner(strings.NewReader(changelog))
+	inFence := false
+	inNewerSection := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		if strings.HasPrefix(line, "## ") {
+			heading := strings.TrimSpace(strings.TrimPrefix(line, "## "))
+			if strings.HasPrefix(strings.ToLower(heading), "[unreleased]") {
+				inNewerSection = true
+				continue
+			}
+			match := changelogVersionPattern.FindStringSubmatch(strings.TrimPrefix(heading, "["+tagPrefix))
+			if match == nil {
+				inNewerSection = false
+				continue
+			}
+			version, _ := parseVersion(match[1])
+			inNewerSection = compareVersions(version, base.Version) > 0
+			if inNewerSection && version[0] > base.Version[0] {
+				return true
+			}
+			continue
+		}
+		if inNewerSection && strings.Contains(line, "BREAKING") {
+			return true
+		}
+	}
+	return false
+}
+
+func parseVersion(value string) ([3]int, bool) {
+	var version [3]int
+	parts := strings.Split(value, ".")
+	if len(parts) != 3 {
+		return version, false
+	}
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return version, false
+		}
+		version[i] = number
+	}
+	return version, true
+}
+
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func gitOutput(dir string, args ...string) (string, error) {
+	command := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	output, err := command.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
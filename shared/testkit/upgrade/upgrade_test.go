@@ -0,0 +1,97 @@
+package upgrade
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatestModuleRelease(t *testing.T) {
+	release, ok := LatestModuleRelease([]string{"SAv1.2.3", "SAv1.10.0", "SAv1.9.9", "SAv2.0.0-rc.1", "SAVv3.0.0", "NSGv9.0.0"}, "SAv")
+	require.True(t, ok)
+	assert.Equal(t, "SAv1.10.0", release.Tag)
+	assert.Equal(t, "1.10.0", release.String())
+
+	_, ok = LatestModuleRelease(nil, "SAv")
+	assert.False(t, ok)
+}
+
+func TestReadModuleConfig(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "module.json"), []byte(`{"name": "azurerm_storage_account", "tag_prefix": "SAv"}`), 0o644))
+
+	config, err := ReadModuleConfigE(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "azurerm_storage_account", config.Name)
+	assert.Equal(t, "SAv", config.TagPrefix)
+
+	_, err = ReadModuleConfigE(t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestSetModuleSource(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.tf")
+	require.NoError(t, os.WriteFile(main, []byte("module \"storage_account\" {\n  source = \"../../../\"\n\n  name = \"example\"\n}\n"), 0o644))
+
+	pinned := PinnedModuleSource("/src/repo", "modules/azurerm_storage_account", "SAv1.2.3")
+	assert.Equal(t, "git::file:///src/repo//modules/azurerm_storage_account?ref=SAv1.2.3", pinned)
+
+	require.NoError(t, SetModuleSourceE(dir, LocalModuleSource, pinned))
+	content, err := os.ReadFile(main)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `source = "`+pinned+`"`)
+
+	require.NoError(t, SetModuleSourceE(dir, pinned, LocalModuleSource))
+	content, err = os.ReadFile(main)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `source = "../../../"`)
+
+	assert.Error(t, SetModuleSourceE(dir, pinned, LocalModuleSource), "a fixture without the module block must not pass silently")
+}
+
+func TestStatefulReplacements(t *testing.T) {
+	content, err := os.ReadFile("testdata/upgrade_plan.json")
+	require.NoError(t, err)
+	plan, err := terraform.ParsePlanJSON(string(content))
+	require.NoError(t, err)
+
+	violations := StatefulReplacements(plan, DefaultStatefulResourceTypes)
+	assert.Equal(t, []Violation{
+		{Address: "module.storage_account.azurerm_storage_account.storage_account", Actions: []string{"delete", "create"}},
+		{Address: `module.storage_account.azurerm_storage_container.containers["logs"]`, Actions: []string{"delete"}},
+	}, violations)
+}
+
+func TestChangelogMarksBreaking(t *testing.T) {
+	base, _ := ParseModuleRelease("SAv1.2.3", "SAv")
+	// The "BREAKING CHANGE" in the example entry format is inside a code block and must not count
+	entryFormat := "# Changelog\n\n## Format\n\n```\n## [SAv3.0.0]\n\n### BREAKING CHANGE\n```\n\n## [SAv1.2.3] - 2025-09-22\n\n### Fixed\n- Typo\n"
+	assert.False(t, ChangelogMarksBreaking(entryFormat, "SAv", base))
+
+	breakingFix := `# Changelog
+
+## [1.3.0](https://github.com/org/repo/compare/SAv1.2.3...SAv1.3.0) (2025-10-01)
+
+### ⚠ BREAKING CHANGES
+
+* **storage-account:** containers are keyed by name
+
+## [1.2.3](https://github.com/org/repo/compare/SAv1.2.2...SAv1.2.3) (2025-09-22)
+
+### Bug Fixes
+
+* **storage-account:** fix
+`
+	assert.True(t, ChangelogMarksBreaking(breakingFix, "SAv", base))
+	newer, _ := ParseModuleRelease("SAv1.3.0", "SAv")
+	assert.False(t, ChangelogMarksBreaking(breakingFix, "SAv", newer), "breaking changes up to the base release are already deployed")
+
+	assert.True(t, ChangelogMarksBreaking("## [SAv2.0.0] - 2025-11-01\n\n### Changed\n- Renamed outputs\n", "SAv", base), "a major bump is breaking")
+	assert.True(t, ChangelogMarksBreaking("## [Unreleased]\n\n### Changed\n- BREAKING: removed subnet associations\n", "SAv", base))
+	assert.False(t, ChangelogMarksBreaking("## [Unreleased]\n\n### Added\n- New input\n", "SAv", base))
+}